@@ -26,6 +26,15 @@ var DefaultBuiltins = v1.DefaultBuiltins
 // built-in definitions.
 var BuiltinMap = v1.BuiltinMap
 
+// Categories of built-in functions that capabilities can deny, regardless of
+// their names. See Capabilities.DenyCategories.
+const (
+	BuiltinCategoryNetwork = v1.BuiltinCategoryNetwork
+	BuiltinCategoryCrypto  = v1.BuiltinCategoryCrypto
+	BuiltinCategoryIO      = v1.BuiltinCategoryIO
+	BuiltinCategoryTime    = v1.BuiltinCategoryTime
+)
+
 // Deprecated: Builtins can now be directly annotated with the
 // Nondeterministic property, and when set to true, will be ignored
 // for partial evaluation.
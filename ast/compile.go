@@ -40,6 +40,22 @@ type CompilerStageDefinition = v1.CompilerStageDefinition
 // compiler.
 type RulesOptions = v1.RulesOptions
 
+// Cardinality describes how many rule results make up a virtual document.
+type Cardinality = v1.Cardinality
+
+const (
+	// CardinalityUnknown indicates that the document is not produced by rules,
+	// or that it cannot be told from the rules.
+	CardinalityUnknown = v1.CardinalityUnknown
+
+	// CardinalityOne indicates that the document is a single rule result.
+	CardinalityOne = v1.CardinalityOne
+
+	// CardinalityMany indicates that the document is made up of many rule
+	// results.
+	CardinalityMany = v1.CardinalityMany
+)
+
 // QueryContext contains contextual information for running an ad-hoc query.
 //
 // Ad-hoc queries can be run in the context of a package and imports may be
@@ -125,3 +141,10 @@ func OutputVarsFromBody(c *Compiler, body Body, safe VarSet) VarSet {
 func OutputVarsFromExpr(c *Compiler, expr *Expr, safe VarSet) VarSet {
 	return v1.OutputVarsFromExpr(c, expr, safe)
 }
+
+// RewriteRegoMetadataCalls rewrites the rego.metadata.chain and
+// rego.metadata.rule calls in the rules of mod like the compiler does. If as
+// is nil, it is built from the annotations of mod.
+func RewriteRegoMetadataCalls(as *AnnotationSet, mod *Module) Errors {
+	return v1.RewriteRegoMetadataCalls(as, mod)
+}
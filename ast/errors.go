@@ -37,6 +37,9 @@ func IsError(code string, err error) bool {
 // ErrorDetails defines the interface for detailed error messages.
 type ErrorDetails = v1.ErrorDetails
 
+// ErrorDetail represents a location related to an error.
+type ErrorDetail = v1.ErrorDetail
+
 // Error represents a single error caught during parsing, compiling, etc.
 type Error = v1.Error
 
@@ -39,6 +39,18 @@ func ValueFromReader(r io.Reader) (Value, error) {
 	return v1.ValueFromReader(r)
 }
 
+// ValueFromJSONReader returns an AST value from the first JSON serialized value
+// in the reader, without decoding it into native Go values first.
+func ValueFromJSONReader(r io.Reader) (Value, error) {
+	return v1.ValueFromJSONReader(r)
+}
+
+// ValueToJSONWriter writes the JSON representation of v to w, without
+// converting v into native Go values first.
+func ValueToJSONWriter(w io.Writer, v Value) error {
+	return v1.ValueToJSONWriter(w, v)
+}
+
 // As converts v into a Go native type referred to by x.
 func As(v Value, x any) error {
 	return v1.As(v, x)
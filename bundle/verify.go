@@ -6,6 +6,8 @@
 package bundle
 
 import (
+	"io"
+
 	v1 "github.com/open-policy-agent/opa/v1/bundle"
 )
 
@@ -21,6 +23,13 @@ func VerifyBundleSignature(sc SignaturesConfig, bvc *VerificationConfig) (map[st
 	return v1.VerifyBundleSignature(sc, bvc)
 }
 
+// NewVerifyingReader returns a reader of the file at path that verifies the
+// raw digest of the file against the files in the bundle signature payload
+// while the file is read from r.
+func NewVerifyingReader(path string, r io.Reader, files map[string]FileInfo) (io.Reader, error) {
+	return v1.NewVerifyingReader(path, r, files)
+}
+
 // DefaultVerifier is the default bundle verification implementation. It verifies bundles by checking
 // the JWT signature using a locally-accessible public key.
 type DefaultVerifier = v1.DefaultVerifier
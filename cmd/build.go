@@ -30,6 +30,7 @@ type buildParams struct {
 	target             *util.EnumFlag
 	bundleMode         bool
 	pruneUnused        bool
+	pruneUnusedData    bool
 	optimizationLevel  int
 	entrypoints        repeatedStringFlag
 	outputFile         string
@@ -44,6 +45,7 @@ type buildParams struct {
 	claimsFile         string
 	excludeVerifyFiles []string
 	plugin             string
+	rawDigests         bool
 	ns                 string
 	v0Compatible       bool
 	v1Compatible       bool
@@ -147,6 +149,11 @@ ensure that document is not eliminated by the optimizer.
 Note: Unless the --prune-unused flag is used, any rule transitively referring to a 
 package or rule declared as an entrypoint will also be enumerated as an entrypoint.
 
+The --prune-unused-data flag excludes data from the output bundle that no rule or
+entrypoint refers to. A reference with a dynamic part, e.g., data.users[x].name,
+keeps all of the data below its constant prefix, e.g., data.users. Data that is only
+queried directly must be declared as an entrypoint to be kept.
+
 Signing
 -------
 
@@ -189,7 +196,8 @@ to the output bundle. It will use the key specified by the --signing-key flag to
 the token in the ".signatures.json" file.
 
 To include additional claims in the payload use the --claims-file flag to provide a JSON file
-containing optional claims.
+containing optional claims. The --raw-digests flag signs the digests of the raw bytes of the
+bundle files, so that they can be verified while the bundle is read.
 
 For more information on the format of the ".signatures.json" file
 see https://www.openpolicyagent.org/docs/latest/management-bundles/#signature-format.
@@ -252,6 +260,7 @@ against ` + brand + ` v0.22.0:
 
 	buildCommand.Flags().VarP(buildParams.target, "target", "t", "set the output bundle target type")
 	buildCommand.Flags().BoolVar(&buildParams.pruneUnused, "prune-unused", false, "exclude dependents of entrypoints")
+	buildCommand.Flags().BoolVar(&buildParams.pruneUnusedData, "prune-unused-data", false, "exclude data that no rule or entrypoint refers to")
 	buildCommand.Flags().BoolVar(&buildParams.debug, "debug", false, "enable debug output")
 	buildCommand.Flags().IntVarP(&buildParams.optimizationLevel, "optimize", "O", 0, "set optimization level")
 	buildCommand.Flags().VarP(&buildParams.entrypoints, "entrypoint", "e", "set slash separated entrypoint path")
@@ -276,6 +285,7 @@ against ` + brand + ` v0.22.0:
 	addSigningKeyFlag(buildCommand.Flags(), &buildParams.key)
 	addSigningPluginFlag(buildCommand.Flags(), &buildParams.plugin)
 	addClaimsFileFlag(buildCommand.Flags(), &buildParams.claimsFile)
+	addRawDigestsFlag(buildCommand.Flags(), &buildParams.rawDigests)
 
 	addV0CompatibleFlag(buildCommand.Flags(), &buildParams.v0Compatible, false)
 	addV1CompatibleFlag(buildCommand.Flags(), &buildParams.v1Compatible, false)
@@ -298,6 +308,10 @@ func dobuild(params buildParams, args []string) error {
 		return err
 	}
 
+	if bsc != nil {
+		bsc = bsc.WithRawDigests(params.rawDigests)
+	}
+
 	if (bvc != nil || bsc != nil) && !params.bundleMode {
 		return errors.New("enable bundle mode (ie. --bundle) to verify or sign bundle files or directories")
 	}
@@ -313,6 +327,7 @@ func dobuild(params buildParams, args []string) error {
 		WithTarget(params.target.String()).
 		WithAsBundle(params.bundleMode).
 		WithPruneUnused(params.pruneUnused).
+		WithPruneUnusedData(params.pruneUnusedData).
 		WithOptimizationLevel(params.optimizationLevel).
 		WithOutput(buf).
 		WithEntrypoints(params.entrypoints.v...).
@@ -23,16 +23,17 @@ import (
 )
 
 type checkParams struct {
-	format       *util.EnumFlag
-	errLimit     int
-	ignore       []string
-	bundleMode   bool
-	capabilities *capabilitiesFlag
-	schema       *schemaFlags
-	strict       bool
-	regoV1       bool
-	v0Compatible bool
-	v1Compatible bool
+	format         *util.EnumFlag
+	errLimit       int
+	ignore         []string
+	bundleMode     bool
+	capabilities   *capabilitiesFlag
+	schema         *schemaFlags
+	strict         bool
+	contradictions bool
+	regoV1         bool
+	v0Compatible   bool
+	v1Compatible   bool
 }
 
 func newCheckParams() checkParams {
@@ -82,6 +83,7 @@ func checkModules(params checkParams, args []string) error {
 		WithSchemas(ss).
 		WithEnablePrintStatements(true).
 		WithStrict(params.strict).
+		WithContradictionCheck(params.contradictions).
 		WithUseTypeCheckAnnotations(true)
 
 	var modules map[string]*ast.Module
@@ -117,6 +119,12 @@ func checkModules(params checkParams, args []string) error {
 		return compiler.Errors
 	}
 
+	if params.format.String() != formats.JSON {
+		for _, w := range compiler.Warnings {
+			fmt.Fprintln(os.Stderr, "warning:", w)
+		}
+	}
+
 	return nil
 }
 
@@ -211,6 +219,7 @@ and exit with a non-zero exit code.`,
 	addCapabilitiesFlag(checkCommand.Flags(), checkParams.capabilities)
 	addSchemaFlags(checkCommand.Flags(), checkParams.schema)
 	addStrictFlag(checkCommand.Flags(), &checkParams.strict, false)
+	checkCommand.Flags().BoolVar(&checkParams.contradictions, "contradictions", false, "warn about expressions that contradict earlier expressions and can never be true")
 	addRegoV0V1FlagWithDescription(checkCommand.Flags(), &checkParams.regoV1, false,
 		"check for Rego v0 and v1 compatibility (policies must be compatible with both Rego versions)")
 	addV0CompatibleFlag(checkCommand.Flags(), &checkParams.v0Compatible, false)
@@ -41,6 +41,7 @@ func Command(rootCommand *cobra.Command, brand string) *cobra.Command {
 	initOracle(rootCommand, brand)
 	initParse(rootCommand, brand)
 	initRefactor(rootCommand, brand)
+	initReplay(rootCommand, brand)
 	initRun(rootCommand, brand)
 	initSign(rootCommand, brand)
 	initTest(rootCommand, brand)
@@ -62,6 +62,10 @@ func initDeps(root *cobra.Command, _ string) {
 
 Dependencies are categorized as either base documents, which is any data loaded
 from the outside world, or virtual documents, i.e values that are computed from rules.
+
+The requirements of the query are printed as well: the built-in functions,
+future keywords and features required by the policies and the query, the data
+roots the query reads from, and the minimum OPA version providing all of them.
 `,
 
 		Example: `
@@ -161,9 +165,15 @@ func deps(args []string, params depsCommandParams, w io.Writer) error {
 		return err
 	}
 
+	req, err := dependencies.Required(compiler, query)
+	if err != nil {
+		return err
+	}
+
 	output := presentation.DepAnalysisOutput{
-		Base:    brs,
-		Virtual: vrs,
+		Base:         brs,
+		Virtual:      vrs,
+		Requirements: req,
 	}
 
 	switch params.outputFormat.String() {
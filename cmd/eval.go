@@ -49,6 +49,7 @@ type evalCommandParams struct {
 	disableIndexing           bool
 	disableEarlyExit          bool
 	strictBuiltinErrors       bool
+	sortedIteration           bool
 	showBuiltinErrors         bool
 	dataPaths                 repeatedStringFlag
 	inputPath                 string
@@ -333,6 +334,7 @@ access.
 	evalCommand.Flags().BoolVar(&params.disableIndexing, "disable-indexing", false, "disable indexing optimizations")
 	evalCommand.Flags().BoolVar(&params.disableEarlyExit, "disable-early-exit", false, "disable 'early exit' optimizations")
 	evalCommand.Flags().BoolVarP(&params.strictBuiltinErrors, "strict-builtin-errors", "", false, "treat the first built-in function error encountered as fatal")
+	evalCommand.Flags().BoolVar(&params.sortedIteration, "sorted-iteration", false, "iterate over documents defined by both data and rules in sorted key order")
 	evalCommand.Flags().BoolVarP(&params.showBuiltinErrors, "show-builtin-errors", "", false, "collect and return all encountered built-in errors, built in errors are not fatal")
 	evalCommand.Flags().BoolVarP(&params.instrument, "instrument", "", false, "enable query instrumentation metrics (implies --metrics)")
 	evalCommand.Flags().BoolVarP(&params.profile, "profile", "", false, "perform expression profiling")
@@ -367,7 +369,7 @@ access.
 	addStrictFlag(evalCommand.Flags(), &params.strict, false)
 	addV0CompatibleFlag(evalCommand.Flags(), &params.v0Compatible, false)
 	addV1CompatibleFlag(evalCommand.Flags(), &params.v1Compatible, false)
-	addReadAstValuesFromStoreFlag(evalCommand.Flags(), &params.ReadAstValuesFromStore, false)
+	addReadAstValuesFromStoreFlag(evalCommand.Flags(), &params.ReadAstValuesFromStore, true)
 
 	root.AddCommand(evalCommand)
 }
@@ -692,6 +694,14 @@ func setupEval(args []string, params evalCommandParams) (*evalContext, error) {
 		evalArgs = append(evalArgs, rego.EvalQueryTracer(c))
 	}
 
+	if params.sortedIteration {
+		regoArgs = append(regoArgs, rego.SortedIteration(true))
+
+		if params.target.String() == compile.TargetWasm {
+			fmt.Fprintln(os.Stderr, "warning: --sorted-iteration is not supported with wasm target")
+		}
+	}
+
 	if params.strictBuiltinErrors {
 		regoArgs = append(regoArgs, rego.StrictBuiltinErrors(true))
 		if params.showBuiltinErrors {
@@ -1153,6 +1153,31 @@ func TestEvalWithStrictBuiltinErrors(t *testing.T) {
 	}
 }
 
+func TestEvalWithSortedIteration(t *testing.T) {
+	files := map[string]string{
+		"data.json": `{"x": {"b": 1, "d": 2}}`,
+		"a.rego":    "package x.a\nv := 3",
+		"c.rego":    "package x.c\nv := 4",
+	}
+
+	test.WithTempFS(files, func(path string) {
+		params := newEvalCommandParams()
+		params.dataPaths = newrepeatedStringFlag([]string{path})
+		params.sortedIteration = true
+		_ = params.outputFormat.Set(formats.Raw)
+
+		var buf bytes.Buffer
+		defined, err := eval([]string{"[k | data.x[k]]"}, params, &buf)
+		if !defined || err != nil {
+			t.Fatalf("Unexpected undefined or error: %v", err)
+		}
+
+		if exp, act := `["a","b","c","d"]`, strings.Join(strings.Fields(buf.String()), ""); act != exp {
+			t.Fatalf("expected %v, got %v", exp, act)
+		}
+	})
+}
+
 func assertResultSet(t *testing.T, rs rego.ResultSet, expected string) {
 	t.Helper()
 	result := []any{}
@@ -115,6 +115,10 @@ func addSigningPluginFlag(fs *pflag.FlagSet, plugin *string) {
 	fs.StringVarP(plugin, "signing-plugin", "", "", "name of the plugin to use for signing/verification (see https://www.openpolicyagent.org/docs/latest/management-bundles/#signature-plugin)")
 }
 
+func addRawDigestsFlag(fs *pflag.FlagSet, rawDigests *bool) {
+	fs.BoolVarP(rawDigests, "raw-digests", "", false, "sign the digests of the raw bytes of bundle files, so that they can be verified while the bundle is read")
+}
+
 func addVerificationKeyFlag(fs *pflag.FlagSet, key *string) {
 	fs.StringVarP(key, "verification-key", "", "", "set the secret (HMAC) or path of the PEM file containing the public key (RSA and ECDSA)")
 }
@@ -181,7 +185,7 @@ func addV1CompatibleFlag(fs *pflag.FlagSet, v1Compatible *bool, value bool) {
 }
 
 func addReadAstValuesFromStoreFlag(fs *pflag.FlagSet, readAstValuesFromStore *bool, value bool) {
-	fs.BoolVar(readAstValuesFromStore, "optimize-store-for-read-speed", value, "optimize default in-memory store for read speed. Has possible negative impact on memory footprint and write speed. See https://www.openpolicyagent.org/docs/latest/policy-performance/#storage-optimization for more details.")
+	fs.BoolVar(readAstValuesFromStore, "optimize-store-for-read-speed", value, "optimize default in-memory store for read speed. Has possible negative impact on memory footprint and write speed, set to false to disable. See https://www.openpolicyagent.org/docs/latest/policy-performance/#storage-optimization for more details.")
 }
 
 func addE2EFlag(fs *pflag.FlagSet, e2e *bool, value bool, brand string) {
@@ -0,0 +1,236 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/open-policy-agent/opa/cmd/formats"
+	"github.com/open-policy-agent/opa/cmd/internal/env"
+	"github.com/open-policy-agent/opa/internal/presentation"
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/plugins/logs"
+	"github.com/open-policy-agent/opa/v1/rego"
+	"github.com/open-policy-agent/opa/v1/storage"
+	"github.com/open-policy-agent/opa/v1/storage/inmem"
+	"github.com/open-policy-agent/opa/v1/util"
+)
+
+type replayCommandParams struct {
+	dataPaths    repeatedStringFlag
+	bundlePaths  repeatedStringFlag
+	ignore       []string
+	outputFormat *util.EnumFlag
+	fail         bool
+	v0Compatible bool
+}
+
+func newReplayCommandParams() replayCommandParams {
+	return replayCommandParams{
+		outputFormat: formats.Flag(formats.Pretty, formats.JSON),
+	}
+}
+
+func (p *replayCommandParams) regoVersion() ast.RegoVersion {
+	if p.v0Compatible {
+		return ast.RegoV0
+	}
+	return ast.DefaultRegoVersion
+}
+
+type replaySummary struct {
+	Decisions int `json:"decisions"`
+	Changed   int `json:"changed"`
+	Failed    int `json:"failed"`
+	Skipped   int `json:"skipped"`
+}
+
+type replayOutput struct {
+	Results []logs.ReplayResult `json:"results"`
+	Summary replaySummary       `json:"summary"`
+}
+
+func initReplay(root *cobra.Command, brand string) {
+	executable := root.Name()
+
+	params := newReplayCommandParams()
+
+	replayCommand := &cobra.Command{
+		Use:   "replay <decision log file> [<decision log file> [...]]",
+		Short: "Replay logged decisions against policies",
+		Long: `Replay logged decisions against policies and data, and report the decisions
+whose result changed.
+
+The decision log files contain decision log events, either newline-delimited as
+written to the console by ` + brand + `, or as JSON arrays as uploaded to a decision log
+service, optionally gzip compressed. Use '-' to read the events from stdin.
+
+The non-deterministic builtin results recorded with a decision (see the
+'nd_builtin_cache' option) are reused in the replay. Decisions that failed,
+or whose input or result were erased, are skipped. Decisions whose input was masked
+are replayed with the masked input.
+`,
+		Example: `
+Replay the decisions of a production log against a policy change:
+
+	$ ` + executable + ` replay --bundle ./policy decisions.ndjson
+	decision 0b5c0f5e-2b6b-4ab0-9c2b-0a3f0d1c1a7e (path: authz/allow) changed
+	  expected: true
+	  actual:   undefined
+	3 decisions, 1 changed, 0 failed, 0 skipped
+`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errors.New("specify at least one decision log file")
+			}
+			return env.CmdFlags.CheckEnvironmentVariables(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceErrors = true
+			cmd.SilenceUsage = true
+
+			changed, err := replay(context.Background(), args, params, os.Stdout)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return newExitErrorWrap(2, err)
+			}
+
+			if params.fail && changed {
+				return newExitError(1)
+			}
+			return nil
+		},
+	}
+
+	addDataFlag(replayCommand.Flags(), &params.dataPaths)
+	addBundleFlag(replayCommand.Flags(), &params.bundlePaths)
+	addIgnoreFlag(replayCommand.Flags(), &params.ignore)
+	addOutputFormat(replayCommand.Flags(), params.outputFormat)
+	addV0CompatibleFlag(replayCommand.Flags(), &params.v0Compatible, false)
+	replayCommand.Flags().BoolVarP(&params.fail, "fail", "", false, "exits with non-zero exit code if a decision changed or failed to replay")
+
+	root.AddCommand(replayCommand)
+}
+
+// replay replays the decisions in the decision log files against the
+// policies and data in params, and reports if any decision changed or failed
+// to replay.
+func replay(ctx context.Context, args []string, params replayCommandParams, w io.Writer) (bool, error) {
+	compiler := ast.NewCompiler().WithDefaultRegoVersion(params.regoVersion())
+	store := inmem.New()
+
+	opts := []func(*rego.Rego){
+		rego.Compiler(compiler),
+		rego.Store(store),
+		rego.Query("data"),
+		rego.SetRegoVersion(params.regoVersion()),
+	}
+
+	if len(params.dataPaths.v) > 0 {
+		opts = append(opts, rego.Load(params.dataPaths.v, ignored(params.ignore).Apply))
+	}
+
+	for _, path := range params.bundlePaths.v {
+		opts = append(opts, rego.LoadBundle(path))
+	}
+
+	// Preparing the query loads the policies and data into the compiler and
+	// store used for the replay.
+	err := storage.Txn(ctx, store, storage.WriteParams, func(txn storage.Transaction) error {
+		_, err := rego.New(append(opts, rego.Transaction(txn))...).PrepareForEval(ctx)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	var output replayOutput
+
+	for _, path := range args {
+		err := replayFile(ctx, path, logs.ReplayOptions{Compiler: compiler, Store: store}, func(r logs.ReplayResult) error {
+			output.Results = append(output.Results, r)
+			output.Summary.Decisions++
+			switch {
+			case r.Skipped != "":
+				output.Summary.Skipped++
+			case r.Error != "":
+				output.Summary.Failed++
+			case r.Changed:
+				output.Summary.Changed++
+			}
+			return nil
+		})
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	changed := output.Summary.Changed > 0 || output.Summary.Failed > 0
+
+	switch params.outputFormat.String() {
+	case formats.JSON:
+		return changed, presentation.JSON(w, output)
+	default:
+		return changed, output.pretty(w)
+	}
+}
+
+func replayFile(ctx context.Context, path string, opts logs.ReplayOptions, f func(logs.ReplayResult) error) error {
+	if path == "-" {
+		return logs.Replay(ctx, os.Stdin, opts, f)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return logs.Replay(ctx, file, opts, f)
+}
+
+func (o replayOutput) pretty(w io.Writer) error {
+	for _, r := range o.Results {
+		if !r.Changed && r.Error == "" {
+			continue
+		}
+
+		name := "path: " + r.Path
+		if r.Path == "" {
+			name = "query: " + r.Query
+		}
+
+		if r.Error != "" {
+			fmt.Fprintf(w, "decision %v (%v) failed: %v\n", r.DecisionID, name, r.Error)
+			continue
+		}
+
+		fmt.Fprintf(w, "decision %v (%v) changed\n", r.DecisionID, name)
+		fmt.Fprintf(w, "  expected: %v\n", replayValue(r.Expected))
+		fmt.Fprintf(w, "  actual:   %v\n", replayValue(r.Actual))
+	}
+
+	_, err := fmt.Fprintf(w, "%d decisions, %d changed, %d failed, %d skipped\n",
+		o.Summary.Decisions, o.Summary.Changed, o.Summary.Failed, o.Summary.Skipped)
+	return err
+}
+
+func replayValue(x *any) string {
+	if x == nil {
+		return "undefined"
+	}
+	bs, err := json.Marshal(*x)
+	if err != nil {
+		return fmt.Sprint(*x)
+	}
+	return string(bs)
+}
@@ -0,0 +1,87 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/open-policy-agent/opa/cmd/formats"
+	"github.com/open-policy-agent/opa/v1/util"
+	"github.com/open-policy-agent/opa/v1/util/test"
+)
+
+func TestReplay(t *testing.T) {
+	files := map[string]string{
+		"policy/authz.rego": `package authz
+
+allow if input.user in data.admins`,
+		"policy/data.json": `{"admins": ["alice"]}`,
+		"decisions.ndjson": `{"decision_id": "1", "path": "authz/allow", "input": {"user": "alice"}, "result": true}
+{"decision_id": "2", "path": "authz/allow", "input": {"user": "bob"}, "result": true}
+{"decision_id": "3", "path": "authz/allow", "erased": ["/input"], "result": true}
+`,
+	}
+
+	test.WithTempFS(files, func(rootPath string) {
+		t.Run("pretty", func(t *testing.T) {
+			params := newReplayCommandParams()
+			_ = params.bundlePaths.Set(filepath.Join(rootPath, "policy"))
+
+			var buf bytes.Buffer
+			changed, err := replay(context.Background(), []string{filepath.Join(rootPath, "decisions.ndjson")}, params, &buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !changed {
+				t.Fatal("expected decisions to have changed")
+			}
+
+			exp := `decision 2 (path: authz/allow) changed
+  expected: true
+  actual:   undefined
+3 decisions, 1 changed, 0 failed, 1 skipped
+`
+			if buf.String() != exp {
+				t.Fatalf("expected output:\n\n%s\n\ngot:\n\n%s", exp, buf.String())
+			}
+		})
+
+		t.Run("json", func(t *testing.T) {
+			params := newReplayCommandParams()
+			_ = params.outputFormat.Set(formats.JSON)
+			_ = params.dataPaths.Set(filepath.Join(rootPath, "policy"))
+
+			var buf bytes.Buffer
+			if _, err := replay(context.Background(), []string{filepath.Join(rootPath, "decisions.ndjson")}, params, &buf); err != nil {
+				t.Fatal(err)
+			}
+
+			var output replayOutput
+			if err := util.UnmarshalJSON(buf.Bytes(), &output); err != nil {
+				t.Fatal(err)
+			}
+
+			if exp := (replaySummary{Decisions: 3, Changed: 1, Skipped: 1}); output.Summary != exp {
+				t.Fatalf("expected summary %+v, got %+v", exp, output.Summary)
+			}
+
+			if len(output.Results) != 3 || output.Results[2].Skipped != "input erased" {
+				t.Fatalf("unexpected results: %+v", output.Results)
+			}
+		})
+	})
+}
+
+func TestReplayMissingFile(t *testing.T) {
+	params := newReplayCommandParams()
+
+	if _, err := replay(context.Background(), []string{"does-not-exist.ndjson"}, params, &bytes.Buffer{}); err == nil {
+		t.Fatal("expected error for missing decision log file")
+	}
+}
@@ -47,6 +47,7 @@ type runCmdParams struct {
 	logLevel             *util.EnumFlag
 	logFormat            *util.EnumFlag
 	logTimestampFormat   string
+	logPrintLevels       bool
 	algorithm            string
 	scope                string
 	pubKey               string
@@ -60,7 +61,7 @@ type runCmdParams struct {
 func newRunParams() runCmdParams {
 	return runCmdParams{
 		rt:             runtime.NewParams(),
-		authentication: util.NewEnumFlag("off", []string{"token", "tls", "off"}),
+		authentication: util.NewEnumFlag("off", []string{"token", "tls", "plugin", "off"}),
 		authorization:  util.NewEnumFlag("off", []string{"basic", "off"}),
 		minTLSVersion:  util.NewEnumFlag("1.2", []string{"1.0", "1.1", "1.2", "1.3"}),
 		logLevel:       util.NewEnumFlag("info", []string{"debug", "info", "error"}),
@@ -233,16 +234,25 @@ See https://godoc.org/crypto/tls#pkg-constants for more information.
 	addV1CompatibleFlag(runCommand.Flags(), &cmdParams.rt.V1Compatible, false)
 	addMaxErrorsFlag(runCommand.Flags(), &cmdParams.rt.ErrorLimit)
 	runCommand.Flags().BoolVar(&cmdParams.rt.PprofEnabled, "pprof", false, "enables pprof endpoints")
+	runCommand.Flags().BoolVar(&cmdParams.rt.REPLEnabled, "repl-api", false, "enables the REPL API for interactive sessions over WebSocket connections")
+	runCommand.Flags().IntVar(&cmdParams.rt.REPLMaxSessions, "repl-api-max-sessions", 0, "set maximum number of concurrent REPL API sessions (0 means the default of 8, negative means no limit)")
+	runCommand.Flags().DurationVar(&cmdParams.rt.REPLIdleTimeout, "repl-api-idle-timeout", 0, "set time after which idle REPL API sessions are closed (0 means the default of 10m, negative means no timeout)")
+	runCommand.Flags().BoolVar(&cmdParams.rt.TenantsEnabled, "tenants", false, "enables the Tenant API for serving isolated tenants from a single server")
+	runCommand.Flags().IntVar(&cmdParams.rt.MaxTenants, "max-tenants", 0, "set maximum number of tenants when the Tenant API is enabled (0 means no limit)")
 	runCommand.Flags().StringVar(&cmdParams.tlsCertFile, "tls-cert-file", "", "set path of TLS certificate file")
 	runCommand.Flags().StringVar(&cmdParams.tlsPrivateKeyFile, "tls-private-key-file", "", "set path of TLS private key file")
 	runCommand.Flags().StringVar(&cmdParams.tlsCACertFile, "tls-ca-cert-file", "", "set path of TLS CA cert file")
 	runCommand.Flags().DurationVar(&cmdParams.tlsCertRefresh, "tls-cert-refresh-period", 0, "set certificate refresh period")
 	runCommand.Flags().Var(cmdParams.authentication, "authentication", "set authentication scheme")
+	runCommand.Flags().StringVar(&cmdParams.rt.Authenticator, "authenticator", "", "set name of the registered authenticator used by the plugin authentication scheme")
 	runCommand.Flags().Var(cmdParams.authorization, "authorization", "set authorization scheme")
 	runCommand.Flags().Var(cmdParams.minTLSVersion, "min-tls-version", "set minimum TLS version to be used by "+brand+"'s server")
 	runCommand.Flags().VarP(cmdParams.logLevel, "log-level", "l", "set log level")
 	runCommand.Flags().Var(cmdParams.logFormat, "log-format", "set log format")
 	runCommand.Flags().StringVar(&cmdParams.logTimestampFormat, "log-timestamp-format", "", "set log timestamp format (OPA_LOG_TIMESTAMP_FORMAT environment variable)")
+	runCommand.Flags().BoolVar(&cmdParams.logPrintLevels, "log-print-levels", false, "log outputs of print calls at the level set by their first argument, e.g., \"warn:\"")
+	runCommand.Flags().IntVar(&cmdParams.rt.SharedVirtualCacheSize, "shared-virtual-cache-size", 0, "set maximum number of values of virtual documents that only depend on data shared across evaluations until policies or data change (0 disables the cache, negative means no limit)")
+	runCommand.Flags().IntVar(&cmdParams.rt.QueryCacheSize, "query-cache-size", 0, "set maximum number of compiled queries cached until policies change (0 disables the cache)")
 	runCommand.Flags().IntVar(&cmdParams.rt.GracefulShutdownPeriod, "shutdown-grace-period", 10, "set the time (in seconds) that the server will wait to gracefully shut down")
 	runCommand.Flags().IntVar(&cmdParams.rt.ShutdownWaitPeriod, "shutdown-wait-period", 0, "set the time (in seconds) that the server will wait before initiating shutdown")
 	runCommand.Flags().BoolVar(&cmdParams.skipKnownSchemaCheck, "skip-known-schema-check", false, "disables type checking on known input schemas")
@@ -250,7 +260,7 @@ See https://godoc.org/crypto/tls#pkg-constants for more information.
 	addConfigOverrides(runCommand.Flags(), &cmdParams.rt.ConfigOverrides)
 	addConfigOverrideFiles(runCommand.Flags(), &cmdParams.rt.ConfigOverrideFiles)
 	addBundleModeFlag(runCommand.Flags(), &cmdParams.rt.BundleMode, false)
-	addReadAstValuesFromStoreFlag(runCommand.Flags(), &cmdParams.rt.ReadAstValuesFromStore, false)
+	addReadAstValuesFromStoreFlag(runCommand.Flags(), &cmdParams.rt.ReadAstValuesFromStore, true)
 
 	runCommand.Flags().BoolVar(&cmdParams.skipVersionCheck, "skip-version-check", false, "disables anonymous version reporting (see: https://www.openpolicyagent.org/docs/latest/privacy)")
 	err := runCommand.Flags().MarkDeprecated("skip-version-check", "\"skip-version-check\" is deprecated. Use \"disable-telemetry\" instead")
@@ -284,9 +294,10 @@ Flags:
 
 func initRuntime(ctx context.Context, params runCmdParams, args []string, addrSetByUser bool) (*runtime.Runtime, error) {
 	authenticationSchemes := map[string]server.AuthenticationScheme{
-		"token": server.AuthenticationToken,
-		"tls":   server.AuthenticationTLS,
-		"off":   server.AuthenticationOff,
+		"token":  server.AuthenticationToken,
+		"tls":    server.AuthenticationTLS,
+		"plugin": server.AuthenticationPlugin,
+		"off":    server.AuthenticationOff,
 	}
 
 	authorizationScheme := map[string]server.AuthorizationScheme{
@@ -345,6 +356,7 @@ func initRuntime(ctx context.Context, params runCmdParams, args []string, addrSe
 		Level:           params.logLevel.String(),
 		Format:          params.logFormat.String(),
 		TimestampFormat: timestampFormat,
+		PrintLevels:     params.logPrintLevels,
 	}
 	params.rt.Paths = args
 	params.rt.Filter = ignored(params.ignore).Apply
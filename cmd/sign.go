@@ -30,6 +30,7 @@ type signCmdParams struct {
 	outputFilePath string
 	bundleMode     bool
 	plugin         string
+	rawDigests     bool
 }
 
 const (
@@ -130,6 +131,11 @@ exists in the actual bundle. The file content is hashed using SHA256.
 To include additional claims in the payload use the --claims-file flag to provide
 a JSON file containing optional claims.
 
+With the --raw-digests flag, structured files are hashed like other files, i.e.,
+their byte stream is hashed, and the files in the "files" field are marked as
+"raw". Raw digests can be verified while the bundle is read, but they depend on
+the formatting of the files.
+
 For more information on the format of the ".signatures.json" file see
 https://www.openpolicyagent.org/docs/latest/management-bundles/#signature-format.
 `,
@@ -159,6 +165,7 @@ https://www.openpolicyagent.org/docs/latest/management-bundles/#signature-format
 	addClaimsFileFlag(signCommand.Flags(), &cmdParams.claimsFile)
 	addSigningAlgFlag(signCommand.Flags(), &cmdParams.algorithm, defaultTokenSigningAlg)
 	addSigningPluginFlag(signCommand.Flags(), &cmdParams.plugin)
+	addRawDigestsFlag(signCommand.Flags(), &cmdParams.rawDigests)
 
 	signCommand.Flags().StringVarP(&cmdParams.outputFilePath, "output-file-path", "o", ".", "set the location for the .signatures.json file")
 
@@ -176,7 +183,7 @@ func doSign(args []string, params signCmdParams) error {
 		return err
 	}
 
-	files, err := readBundleFiles(load.BundlesLoader, hash)
+	files, err := readBundleFiles(load.BundlesLoader, hash, params.rawDigests)
 	if err != nil {
 		return err
 	}
@@ -194,7 +201,7 @@ func doSign(args []string, params signCmdParams) error {
 	return writeTokenToFile(token, params.outputFilePath)
 }
 
-func readBundleFiles(loaders []initload.BundleLoader, h bundle.SignatureHasher) ([]bundle.FileInfo, error) {
+func readBundleFiles(loaders []initload.BundleLoader, h bundle.SignatureHasher, raw bool) ([]bundle.FileInfo, error) {
 	files := []bundle.FileInfo{}
 
 	for _, bl := range loaders {
@@ -229,7 +236,7 @@ func readBundleFiles(loaders []initload.BundleLoader, h bundle.SignatureHasher)
 			}
 
 			// hash the file content
-			fi, err := hashFileContent(h, buf.Bytes(), path)
+			fi, err := hashFileContent(h, buf.Bytes(), path, raw)
 			if err != nil {
 				return files, err
 			}
@@ -239,12 +246,12 @@ func readBundleFiles(loaders []initload.BundleLoader, h bundle.SignatureHasher)
 	return files, nil
 }
 
-func hashFileContent(h bundle.SignatureHasher, data []byte, path string) (bundle.FileInfo, error) {
+func hashFileContent(h bundle.SignatureHasher, data []byte, path string, raw bool) (bundle.FileInfo, error) {
 
 	var fileInfo bundle.FileInfo
 	var value any
 
-	if bundle.IsStructuredDoc(path) {
+	if bundle.IsStructuredDoc(path) && !raw {
 		err := util.Unmarshal(data, &value)
 		if err != nil {
 			return fileInfo, err
@@ -258,7 +265,10 @@ func hashFileContent(h bundle.SignatureHasher, data []byte, path string) (bundle
 		return fileInfo, err
 	}
 
-	return bundle.NewFile(strings.TrimPrefix(path, "/"), hex.EncodeToString(bytes), defaultHashingAlg), nil
+	fileInfo = bundle.NewFile(strings.TrimPrefix(path, "/"), hex.EncodeToString(bytes), defaultHashingAlg)
+	fileInfo.Raw = raw
+
+	return fileInfo, nil
 }
 
 func writeTokenToFile(token, fileLoc string) error {
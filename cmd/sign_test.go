@@ -128,6 +128,46 @@ func TestBundleSignVerification(t *testing.T) {
 	})
 }
 
+func TestBundleSignVerificationRawDigests(t *testing.T) {
+
+	// files to be included in the bundle
+	files := map[string]string{
+		"/.manifest":            `{"revision": "quickbrownfaux"}`,
+		"/a/b/c/data.json":      "[1,2,3]",
+		"/a/b/y/data.yaml":      `foo: 1`,
+		"/example/example.rego": `package example`,
+		"/data.json":            `{"x": {"y": true}}`,
+	}
+
+	test.WithTempFS(files, func(rootDir string) {
+		params := signCmdParams{
+			algorithm:      "HS256",
+			key:            "mysecret",
+			outputFilePath: rootDir,
+			bundleMode:     true,
+			rawDigests:     true,
+		}
+
+		err := doSign([]string{rootDir}, params)
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+
+		kc := keys.Config{
+			Key:       "mysecret",
+			Algorithm: "HS256",
+		}
+
+		bvc := bundle.NewVerificationConfig(map[string]*keys.Config{"foo": &kc}, "foo", "", nil)
+		reader := bundle.NewCustomReader(bundle.NewDirectoryLoader(rootDir)).WithBundleVerificationConfig(bvc).WithBaseDir(rootDir)
+
+		_, err = reader.Read()
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+	})
+}
+
 func TestValidateSignParams(t *testing.T) {
 
 	tests := map[string]struct {
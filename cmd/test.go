@@ -64,6 +64,7 @@ type testCommandParams struct {
 	v1Compatible bool
 	varValues    bool
 	parallel     int
+	updateSnaps  bool
 }
 
 func newTestCommandParams() testCommandParams {
@@ -411,7 +412,8 @@ func compileAndSetupTests(ctx context.Context, testParams testCommandParams, sto
 		SetBundles(bundles).
 		SetTimeout(timeout).
 		Filter(testParams.runRegex).
-		SetParallel(testParams.parallel)
+		SetParallel(testParams.parallel).
+		SetUpdateSnapshots(testParams.updateSnaps)
 
 	if testParams.target.IsSet() {
 		runner = runner.Target(testParams.target.String())
@@ -566,6 +568,7 @@ recommended as some updates might cause them to be dropped by OPA.
 	testCommand.Flags().StringVarP(&testParams.runRegex, "run", "r", "", "run only test cases matching the regular expression")
 	testCommand.Flags().BoolVarP(&testParams.watch, "watch", "w", false, "watch command line files for changes")
 	testCommand.Flags().BoolVar(&testParams.varValues, "var-values", false, "show local variable values in test output")
+	testCommand.Flags().BoolVar(&testParams.updateSnaps, "update-snapshots", false, "write the values of snapshot tests to their golden files")
 	testCommand.Flags().IntVarP(&testParams.parallel, "parallel", "p", goRuntime.NumCPU(), "the number of tests that can run in parallel, defaulting to the number of CPUs (explicitly set with 0). Benchmarks are always run sequentially.")
 
 	// Shared flags
@@ -40,3 +40,17 @@ func Base(compiler *ast.Compiler, x any) ([]ast.Ref, error) {
 func Virtual(compiler *ast.Compiler, x any) ([]ast.Ref, error) {
 	return v1.Virtual(compiler, x)
 }
+
+// Requirements describes what the OPA instance evaluating an AST element must
+// provide.
+type Requirements = v1.Requirements
+
+// BuiltinRequirement is a built-in function required by an AST element, and
+// the oldest OPA version that provides it.
+type BuiltinRequirement = v1.BuiltinRequirement
+
+// Required returns the requirements of the given AST element, e.g., a query,
+// evaluated against the modules compiled by compiler.
+func Required(compiler *ast.Compiler, x any) (*Requirements, error) {
+	return v1.Required(compiler, x)
+}
@@ -0,0 +1,13 @@
+package graph_shortest_path_example
+
+member_of := {
+	"alice": {"engineering"},
+	"bob": {"interns"},
+	"engineering": {"staff"},
+	"interns": {"engineering"},
+	"staff": set(),
+}
+
+bob_is_staff_because := graph.shortest_path(member_of, "bob", "staff")
+
+groups := graph.transitive_closure(member_of, {"alice", "bob"})
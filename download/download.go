@@ -27,3 +27,20 @@ func New(config Config, client rest.Client, path string) *Downloader {
 }
 
 type HTTPError = v1.HTTPError
+
+// Transport sends the bundle requests of a downloader to the service it
+// downloads bundles from. Transports are selected by the type of the service.
+type Transport = v1.Transport
+
+// Service types of the object storage transports.
+const (
+	ServiceTypeS3        = v1.ServiceTypeS3
+	ServiceTypeGCS       = v1.ServiceTypeGCS
+	ServiceTypeAzureBlob = v1.ServiceTypeAzureBlob
+)
+
+// RegisterTransport registers t as the transport of services of type typ,
+// replacing the transport registered before, if any.
+func RegisterTransport(typ string, t Transport) {
+	v1.RegisterTransport(typ, t)
+}
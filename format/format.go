@@ -84,3 +84,12 @@ type ArityFormatErrDetail = v1.ArityFormatErrDetail
 func ArityFormatMismatchError(operands []*ast.Term, operator string, loc *ast.Location, f *types.Function) *ast.Error {
 	return v1.ArityFormatMismatchError(operands, operator, loc, f)
 }
+
+// Note describes a construct that MigrateToRegoV1 could not rewrite.
+type Note = v1.Note
+
+// MigrateToRegoV1 rewrites the v0 module to Rego v1 syntax.
+// See v1.MigrateToRegoV1 for details.
+func MigrateToRegoV1(module *ast.Module) (*ast.Module, []Note, error) {
+	return v1.MigrateToRegoV1(module)
+}
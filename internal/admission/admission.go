@@ -0,0 +1,96 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+// Package admission implements policy admission: a policy that is evaluated
+// against the AST of every policy before it is stored, and that can deny the
+// update.
+package admission
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/rego"
+	"github.com/open-policy-agent/opa/v1/storage"
+)
+
+// Error is returned when the admission policy denies a policy update.
+type Error struct {
+	ID         string
+	Violations []string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("policy %q rejected by admission policy: %v", e.ID, strings.Join(e.Violations, "; "))
+}
+
+// Checker evaluates an admission decision against policies.
+type Checker struct {
+	Decision ast.Ref
+	Compiler *ast.Compiler
+	Store    storage.Store
+	Txn      storage.Transaction
+}
+
+// Check evaluates the admission decision with the policy id and module as
+// input:
+//
+//	{"id": <id>, "module": <module AST>}
+//
+// The decision is expected to produce a set or array of violation messages.
+// If it produces any, an *Error containing the messages is returned. An
+// undefined decision admits the policy, so that policies can be loaded before
+// the admission policy itself.
+func (c *Checker) Check(ctx context.Context, id string, module *ast.Module) error {
+	if c == nil || c.Decision == nil || c.Compiler == nil {
+		return nil
+	}
+
+	moduleValue, err := ast.InterfaceToValue(module)
+	if err != nil {
+		return err
+	}
+
+	input := ast.NewObject(
+		ast.Item(ast.StringTerm("id"), ast.StringTerm(id)),
+		ast.Item(ast.StringTerm("module"), ast.NewTerm(moduleValue)),
+	)
+
+	rs, err := rego.New(
+		rego.Query(c.Decision.String()),
+		rego.Compiler(c.Compiler),
+		rego.Store(c.Store),
+		rego.Transaction(c.Txn),
+		rego.ParsedInput(input),
+	).Eval(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(rs) == 0 {
+		return nil
+	}
+
+	violations, ok := rs[0].Expressions[0].Value.([]any)
+	if !ok {
+		return fmt.Errorf("admission decision %v must be a set or array of messages", c.Decision)
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+
+	denied := &Error{ID: id, Violations: make([]string, len(violations))}
+	for i, v := range violations {
+		if s, ok := v.(string); ok {
+			denied.Violations[i] = s
+		} else {
+			denied.Violations[i] = fmt.Sprint(v)
+		}
+	}
+
+	return denied
+}
@@ -0,0 +1,71 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package bundle
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+
+	"github.com/open-policy-agent/opa/v1/storage"
+)
+
+// encryptedBundleHeader starts the files of encrypted bundles, followed by the
+// nonce and the AES-GCM sealed bundle. Files of unencrypted bundles are gzipped
+// tarballs, which start with the gzip magic number instead.
+var encryptedBundleHeader = []byte("opa.encrypted-bundle.v1\n")
+
+func encryptBundle(ctx context.Context, kp storage.KeyProvider, plaintext []byte) ([]byte, error) {
+	aead, err := bundleAEAD(ctx, kp)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, len(encryptedBundleHeader)+aead.NonceSize(), len(encryptedBundleHeader)+aead.NonceSize()+len(plaintext)+aead.Overhead())
+	copy(out, encryptedBundleHeader)
+	nonce := out[len(encryptedBundleHeader):]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(out, nonce, plaintext, encryptedBundleHeader), nil
+}
+
+func decryptBundle(ctx context.Context, kp storage.KeyProvider, bs []byte) ([]byte, error) {
+	if kp == nil {
+		return nil, errors.New("bundle is encrypted but no encryption key is configured")
+	}
+
+	aead, err := bundleAEAD(ctx, kp)
+	if err != nil {
+		return nil, err
+	}
+
+	bs = bs[len(encryptedBundleHeader):]
+	if len(bs) < aead.NonceSize() {
+		return nil, errors.New("encrypted bundle is truncated")
+	}
+
+	plaintext, err := aead.Open(nil, bs[:aead.NonceSize()], bs[aead.NonceSize():], encryptedBundleHeader)
+	if err != nil {
+		return nil, errors.New("encrypted bundle could not be decrypted, it is corrupted or the encryption key changed")
+	}
+	return plaintext, nil
+}
+
+func bundleAEAD(ctx context.Context, kp storage.KeyProvider) (cipher.AEAD, error) {
+	key, err := storage.EncryptionKey(ctx, kp)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
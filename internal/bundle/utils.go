@@ -5,6 +5,8 @@
 package bundle
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -72,7 +74,7 @@ func LoadWasmResolversFromStore(ctx context.Context, store storage.Store, txn st
 	var resolvers []*wasm.Resolver
 	if len(resolversToLoad) > 0 {
 		// Get a full snapshot of the current data (including any from "outside" the bundles)
-		data, err := store.Read(ctx, txn, storage.Path{})
+		data, err := storage.ReadJSON(ctx, store, txn, storage.Path{})
 		if err != nil {
 			return nil, fmt.Errorf("failed to initialize wasm runtime: %s", err)
 		}
@@ -94,37 +96,60 @@ func LoadBundleFromDisk(path, name string, bvc *bundle.VerificationConfig) (*bun
 }
 
 func LoadBundleFromDiskForRegoVersion(regoVersion ast.RegoVersion, path, name string, bvc *bundle.VerificationConfig) (*bundle.Bundle, error) {
+	return LoadEncryptedBundleFromDisk(context.Background(), regoVersion, path, name, bvc, nil)
+}
+
+// LoadEncryptedBundleFromDisk loads a previously persisted activated bundle
+// from disk, decrypting it with the key of kp if it was persisted encrypted.
+// Unencrypted bundles are loaded regardless of kp, so that bundles persisted
+// before encryption was enabled remain available until they are replaced.
+func LoadEncryptedBundleFromDisk(ctx context.Context, regoVersion ast.RegoVersion, path, name string, bvc *bundle.VerificationConfig, kp storage.KeyProvider) (*bundle.Bundle, error) {
 	bundlePath := filepath.Join(path, name, "bundle.tar.gz")
 
-	_, err := os.Stat(bundlePath)
-	if err == nil {
-		f, err := os.Open(bundlePath)
+	f, err := os.Open(bundlePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	var src io.Reader = br
+	if header, err := br.Peek(len(encryptedBundleHeader)); err == nil && bytes.Equal(header, encryptedBundleHeader) {
+		bs, err := io.ReadAll(br)
 		if err != nil {
 			return nil, err
 		}
-		defer f.Close()
-
-		r := bundle.NewCustomReader(bundle.NewTarballLoaderWithBaseURL(f, "")).
-			WithRegoVersion(regoVersion)
-
-		if bvc != nil {
-			r = r.WithBundleVerificationConfig(bvc)
-		}
-
-		b, err := r.Read()
+		bs, err = decryptBundle(ctx, kp, bs)
 		if err != nil {
 			return nil, err
 		}
-		return &b, nil
-	} else if os.IsNotExist(err) {
-		return nil, nil
+		src = bytes.NewReader(bs)
+	}
+
+	r := bundle.NewCustomReader(bundle.NewTarballLoaderWithBaseURL(src, "")).
+		WithRegoVersion(regoVersion)
+
+	if bvc != nil {
+		r = r.WithBundleVerificationConfig(bvc)
 	}
 
-	return nil, err
+	b, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
 }
 
 // SaveBundleToDisk saves the given raw bytes representing the bundle's content to disk
 func SaveBundleToDisk(path string, raw io.Reader) (string, error) {
+	return SaveEncryptedBundleToDisk(context.Background(), path, raw, nil)
+}
+
+// SaveEncryptedBundleToDisk saves the given raw bytes representing the
+// bundle's content to disk, encrypted with the key of kp, if set.
+func SaveEncryptedBundleToDisk(ctx context.Context, path string, raw io.Reader, kp storage.KeyProvider) (string, error) {
 	if _, err := os.Stat(path); os.IsNotExist(err) {
 		err = os.MkdirAll(path, os.ModePerm)
 		if err != nil {
@@ -136,6 +161,18 @@ func SaveBundleToDisk(path string, raw io.Reader) (string, error) {
 		return "", errors.New("no raw bundle bytes to persist to disk")
 	}
 
+	if kp != nil {
+		bs, err := io.ReadAll(raw)
+		if err != nil {
+			return "", err
+		}
+		bs, err = encryptBundle(ctx, kp, bs)
+		if err != nil {
+			return "", err
+		}
+		raw = bytes.NewReader(bs)
+	}
+
 	dest, err := os.CreateTemp(path, ".bundle.tar.gz.*.tmp")
 	if err != nil {
 		return "", err
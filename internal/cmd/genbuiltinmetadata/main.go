@@ -77,6 +77,9 @@ func main() {
 		if latest.IsDeprecated() {
 			md["deprecated"] = true
 		}
+		if len(latest.Tags) > 0 {
+			md["tags"] = latest.Tags
+		}
 		mdata[bi.Name] = md
 	}
 
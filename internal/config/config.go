@@ -12,6 +12,7 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"sigs.k8s.io/yaml"
 
 	"github.com/open-policy-agent/opa/internal/strvals"
@@ -29,6 +30,8 @@ type ServiceOptions struct {
 	Keys                  map[string]*keys.Config
 	Logger                logging.Logger
 	DistributedTacingOpts tracing.Options
+	RetryMetrics          *prometheus.CounterVec
+	SharedCache           *rest.SharedCache
 }
 
 // ParseServicesConfig returns a set of named service clients. The service
@@ -44,7 +47,7 @@ func ParseServicesConfig(opts ServiceOptions) (map[string]rest.Client, error) {
 
 	if err := util.Unmarshal(opts.Raw, &arr); err == nil {
 		for _, s := range arr {
-			client, err := rest.New(s, opts.Keys, rest.AuthPluginLookup(opts.AuthPlugin), rest.Logger(opts.Logger), rest.DistributedTracingOpts(opts.DistributedTacingOpts))
+			client, err := rest.New(s, opts.Keys, rest.AuthPluginLookup(opts.AuthPlugin), rest.Logger(opts.Logger), rest.DistributedTracingOpts(opts.DistributedTacingOpts), rest.RetryMetrics(opts.RetryMetrics), rest.UseSharedCache(opts.SharedCache))
 			if err != nil {
 				return nil, err
 			}
@@ -52,7 +55,7 @@ func ParseServicesConfig(opts ServiceOptions) (map[string]rest.Client, error) {
 		}
 	} else if util.Unmarshal(opts.Raw, &obj) == nil {
 		for k := range obj {
-			client, err := rest.New(obj[k], opts.Keys, rest.Name(k), rest.AuthPluginLookup(opts.AuthPlugin), rest.Logger(opts.Logger), rest.DistributedTracingOpts(opts.DistributedTacingOpts))
+			client, err := rest.New(obj[k], opts.Keys, rest.Name(k), rest.AuthPluginLookup(opts.AuthPlugin), rest.Logger(opts.Logger), rest.DistributedTracingOpts(opts.DistributedTacingOpts), rest.RetryMetrics(opts.RetryMetrics), rest.UseSharedCache(opts.SharedCache))
 			if err != nil {
 				return nil, err
 			}
@@ -145,29 +145,7 @@ func Init(ctx context.Context, raw []byte, id string) (*otlptrace.Exporter, *tra
 		)
 	}
 
-	var resourceAttributes []attribute.KeyValue
-	if distributedTracingConfig.Resource.ServiceVersion != "" {
-		resourceAttributes = append(resourceAttributes, semconv.ServiceVersionKey.String(distributedTracingConfig.Resource.ServiceVersion))
-	}
-	if distributedTracingConfig.Resource.ServiceInstanceID != "" {
-		resourceAttributes = append(resourceAttributes, semconv.ServiceInstanceIDKey.String(distributedTracingConfig.Resource.ServiceInstanceID))
-	}
-	if distributedTracingConfig.Resource.ServiceNamespace != "" {
-		resourceAttributes = append(resourceAttributes, semconv.ServiceNamespaceKey.String(distributedTracingConfig.Resource.ServiceNamespace))
-	}
-
-	// NOTE: this is currently using the `deployment.environment` setting which is being deprecated
-	// in favour of `deployment.environment.name` in future versions of the OpenTelemetry schema.
-	// This will need to be taken into account when upgrading the library version in the future.
-	if distributedTracingConfig.Resource.DeploymentEnvironment != "" {
-		resourceAttributes = append(resourceAttributes, semconv.DeploymentEnvironmentKey.String(distributedTracingConfig.Resource.DeploymentEnvironment))
-	}
-	res, err := resource.New(ctx,
-		resource.WithAttributes(
-			semconv.ServiceNameKey.String(distributedTracingConfig.ServiceName),
-		),
-		resource.WithAttributes(resourceAttributes...),
-	)
+	res, err := resource.New(ctx, resource.WithAttributes(distributedTracingConfig.resourceAttributes()...))
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -198,6 +176,29 @@ func Init(ctx context.Context, raw []byte, id string) (*otlptrace.Exporter, *tra
 	return traceExporter, traceProvider, res, nil
 }
 
+// resourceAttributes returns the attributes of the resource emitting
+// telemetry, i.e. the OPA instance.
+func (c *distributedTracingConfig) resourceAttributes() []attribute.KeyValue {
+	resourceAttributes := []attribute.KeyValue{semconv.ServiceNameKey.String(c.ServiceName)}
+	if c.Resource.ServiceVersion != "" {
+		resourceAttributes = append(resourceAttributes, semconv.ServiceVersionKey.String(c.Resource.ServiceVersion))
+	}
+	if c.Resource.ServiceInstanceID != "" {
+		resourceAttributes = append(resourceAttributes, semconv.ServiceInstanceIDKey.String(c.Resource.ServiceInstanceID))
+	}
+	if c.Resource.ServiceNamespace != "" {
+		resourceAttributes = append(resourceAttributes, semconv.ServiceNamespaceKey.String(c.Resource.ServiceNamespace))
+	}
+
+	// NOTE: this is currently using the `deployment.environment` setting which is being deprecated
+	// in favour of `deployment.environment.name` in future versions of the OpenTelemetry schema.
+	// This will need to be taken into account when upgrading the library version in the future.
+	if c.Resource.DeploymentEnvironment != "" {
+		resourceAttributes = append(resourceAttributes, semconv.DeploymentEnvironmentKey.String(c.Resource.DeploymentEnvironment))
+	}
+	return resourceAttributes
+}
+
 func SetupLogging(logger logging.Logger) {
 	otel.SetErrorHandler(&errorHandler{logger: logger})
 	otel.SetLogger(logr.New(&sink{logger: logger}))
@@ -334,10 +335,7 @@ func loadCertPool(tlsCACertFile string) (*x509.CertPool, error) {
 	return pool, nil
 }
 
-func grpcTLSOption(encryptionScheme string, encryptionSkipVerify bool, cert *tls.Certificate, certPool *x509.CertPool) (otlptracegrpc.Option, error) {
-	if encryptionScheme == "off" {
-		return otlptracegrpc.WithInsecure(), nil
-	}
+func newTLSConfig(encryptionScheme string, encryptionSkipVerify bool, cert *tls.Certificate, certPool *x509.CertPool) (*tls.Config, error) {
 	tlsConfig := &tls.Config{
 		RootCAs:            certPool,
 		InsecureSkipVerify: encryptionSkipVerify,
@@ -348,6 +346,17 @@ func grpcTLSOption(encryptionScheme string, encryptionSkipVerify bool, cert *tls
 		}
 		tlsConfig.Certificates = []tls.Certificate{*cert}
 	}
+	return tlsConfig, nil
+}
+
+func grpcTLSOption(encryptionScheme string, encryptionSkipVerify bool, cert *tls.Certificate, certPool *x509.CertPool) (otlptracegrpc.Option, error) {
+	if encryptionScheme == "off" {
+		return otlptracegrpc.WithInsecure(), nil
+	}
+	tlsConfig, err := newTLSConfig(encryptionScheme, encryptionSkipVerify, cert, certPool)
+	if err != nil {
+		return nil, err
+	}
 	return otlptracegrpc.WithTLSCredentials(credentials.NewTLS(tlsConfig)), nil
 }
 
@@ -355,15 +364,9 @@ func httpTLSOption(encryptionScheme string, encryptionSkipVerify bool, cert *tls
 	if encryptionScheme == "off" {
 		return otlptracehttp.WithInsecure(), nil
 	}
-	tlsConfig := &tls.Config{
-		RootCAs:            certPool,
-		InsecureSkipVerify: encryptionSkipVerify,
-	}
-	if encryptionScheme == "mtls" {
-		if cert == nil {
-			return nil, errors.New("distributed_tracing.tls_cert_file required but not supplied")
-		}
-		tlsConfig.Certificates = []tls.Certificate{*cert}
+	tlsConfig, err := newTLSConfig(encryptionScheme, encryptionSkipVerify, cert, certPool)
+	if err != nil {
+		return nil, err
 	}
 	return otlptracehttp.WithTLSClientConfig(tlsConfig), nil
 }
@@ -0,0 +1,371 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package distributedtracing
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/proto"
+)
+
+// default path of the logs endpoint defined in https://opentelemetry.io/docs/specs/otlp/#otlphttp-request
+const logsHTTPPath = "/v1/logs"
+
+// Severity numbers of log records, as defined in
+// https://opentelemetry.io/docs/specs/otel/logs/data-model/#field-severitynumber
+const (
+	SeverityInfo  = int(logspb.SeverityNumber_SEVERITY_NUMBER_INFO)
+	SeverityError = int(logspb.SeverityNumber_SEVERITY_NUMBER_ERROR)
+)
+
+// LogRecord is a log record exported by a LogExporter.
+type LogRecord struct {
+	Timestamp  time.Time
+	Severity   int
+	Body       any               // JSON-compatible value, e.g. as returned by util.UnmarshalJSON
+	Attributes map[string]string // attributes of the record
+	TraceID    string            // hex-encoded trace ID, ignored if invalid
+	SpanID     string            // hex-encoded span ID, ignored if invalid
+}
+
+// LogExporter exports log records to the OTLP endpoint configured for
+// distributed tracing, so that they end up in the same backend as the spans.
+// Records are queued, and exported in batches according to the batch span
+// processor options of the distributed tracing configuration.
+type LogExporter struct {
+	send          func(context.Context, *collogspb.ExportLogsServiceRequest) error
+	close         func() error
+	resource      *resourcepb.Resource
+	scope         *commonpb.InstrumentationScope
+	records       chan LogRecord
+	stop          chan chan struct{}
+	maxBatchSize  int
+	batchTimeout  time.Duration
+	exportTimeout time.Duration
+}
+
+// NewLogExporter returns a LogExporter for the raw distributed tracing
+// configuration, attributing the records to the instrumentation scope with
+// the given name. If distributed tracing is not enabled, NewLogExporter
+// returns nil.
+func NewLogExporter(raw []byte, scope string) (*LogExporter, error) {
+	c, err := parseDistributedTracingConfig(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.EqualFold(c.Type, "grpc") && !strings.EqualFold(c.Type, "http") {
+		return nil, nil
+	}
+
+	certificate, err := loadCertificate(c.TLSCertFile, c.TLSCertPrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	certPool, err := loadCertPool(c.TLSCACertFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var tlsConfig *tls.Config
+	if c.EncryptionScheme != "off" {
+		tlsConfig, err = newTLSConfig(c.EncryptionScheme, *c.EncryptionSkipVerify, certificate, certPool)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	e := &LogExporter{
+		records:       make(chan LogRecord, *c.BatchSpanProcessorOptions.MaxQueueSize),
+		stop:          make(chan chan struct{}),
+		maxBatchSize:  *c.BatchSpanProcessorOptions.MaxExportBatchSize,
+		batchTimeout:  time.Duration(*c.BatchSpanProcessorOptions.BatchTimeoutMs) * time.Millisecond,
+		exportTimeout: time.Duration(*c.BatchSpanProcessorOptions.ExportTimeoutMs) * time.Millisecond,
+	}
+
+	if strings.EqualFold(c.Type, "grpc") {
+		creds := insecure.NewCredentials()
+		if tlsConfig != nil {
+			creds = credentials.NewTLS(tlsConfig)
+		}
+		conn, err := grpc.NewClient(c.Address, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			return nil, err
+		}
+		client := collogspb.NewLogsServiceClient(conn)
+		e.send = func(ctx context.Context, req *collogspb.ExportLogsServiceRequest) error {
+			_, err := client.Export(ctx, req)
+			return err
+		}
+		e.close = conn.Close
+	} else {
+		scheme := "https://"
+		if tlsConfig == nil {
+			scheme = "http://"
+		}
+		url := scheme + c.Address + logsHTTPPath
+
+		tr := http.DefaultTransport.(*http.Transport).Clone()
+		tr.TLSClientConfig = tlsConfig
+		client := &http.Client{Transport: tr}
+
+		e.send = func(ctx context.Context, req *collogspb.ExportLogsServiceRequest) error {
+			return sendHTTP(ctx, client, url, req)
+		}
+		e.close = func() error {
+			client.CloseIdleConnections()
+			return nil
+		}
+	}
+
+	attrs := c.resourceAttributes()
+	e.resource = &resourcepb.Resource{Attributes: make([]*commonpb.KeyValue, 0, len(attrs))}
+	for _, attr := range attrs {
+		e.resource.Attributes = append(e.resource.Attributes, stringKeyValue(string(attr.Key), attr.Value.Emit()))
+	}
+	e.scope = &commonpb.InstrumentationScope{Name: scope}
+
+	return e, nil
+}
+
+// Start starts exporting the emitted records in the background.
+func (e *LogExporter) Start() {
+	go e.loop()
+}
+
+// Emit queues r for export. If the queue is full, r is dropped, and Emit
+// returns false.
+func (e *LogExporter) Emit(r LogRecord) bool {
+	select {
+	case e.records <- r:
+		return true
+	default:
+		return false
+	}
+}
+
+// Shutdown exports the queued records, and stops the exporter. Records
+// emitted after Shutdown are never exported.
+func (e *LogExporter) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	select {
+	case e.stop <- done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	return e.close()
+}
+
+func (e *LogExporter) loop() {
+	ticker := time.NewTicker(e.batchTimeout)
+	defer ticker.Stop()
+
+	batch := make([]LogRecord, 0, e.maxBatchSize)
+
+	for {
+		select {
+		case r := <-e.records:
+			batch = append(batch, r)
+			if len(batch) >= e.maxBatchSize {
+				e.export(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				e.export(batch)
+				batch = batch[:0]
+			}
+		case done := <-e.stop:
+			for len(e.records) > 0 {
+				batch = append(batch, <-e.records)
+				if len(batch) >= e.maxBatchSize {
+					e.export(batch)
+					batch = batch[:0]
+				}
+			}
+			if len(batch) > 0 {
+				e.export(batch)
+			}
+			close(done)
+			return
+		}
+	}
+}
+
+// export sends a batch of records. Errors are reported to the OpenTelemetry
+// error handler, like the errors of the span exporter.
+func (e *LogExporter) export(batch []LogRecord) {
+	req := e.request(batch, time.Now())
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.exportTimeout)
+	defer cancel()
+
+	if err := e.send(ctx, req); err != nil {
+		otel.Handle(fmt.Errorf("failed to export %d log records: %w", len(batch), err))
+	}
+}
+
+// request returns the ExportLogsServiceRequest message holding the records.
+func (e *LogExporter) request(records []LogRecord, observed time.Time) *collogspb.ExportLogsServiceRequest {
+	scopeLogs := &logspb.ScopeLogs{
+		Scope:      e.scope,
+		LogRecords: make([]*logspb.LogRecord, len(records)),
+	}
+	for i, r := range records {
+		scopeLogs.LogRecords[i] = logRecord(r, observed)
+	}
+
+	return &collogspb.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{{
+			Resource:  e.resource,
+			ScopeLogs: []*logspb.ScopeLogs{scopeLogs},
+		}},
+	}
+}
+
+func logRecord(r LogRecord, observed time.Time) *logspb.LogRecord {
+	severity := logspb.SeverityNumber(r.Severity)
+	record := &logspb.LogRecord{
+		TimeUnixNano:         uint64(r.Timestamp.UnixNano()),
+		ObservedTimeUnixNano: uint64(observed.UnixNano()),
+		SeverityNumber:       severity,
+		SeverityText:         severityText(r.Severity),
+		Body:                 anyValue(r.Body),
+		Attributes:           make([]*commonpb.KeyValue, 0, len(r.Attributes)),
+	}
+
+	keys := make([]string, 0, len(r.Attributes))
+	for k := range r.Attributes {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	for _, k := range keys {
+		record.Attributes = append(record.Attributes, stringKeyValue(k, r.Attributes[k]))
+	}
+
+	if id, err := hex.DecodeString(r.TraceID); err == nil && len(id) == 16 {
+		record.TraceId = id
+	}
+	if id, err := hex.DecodeString(r.SpanID); err == nil && len(id) == 8 {
+		record.SpanId = id
+	}
+
+	return record
+}
+
+func severityText(severity int) string {
+	switch severity {
+	case SeverityInfo:
+		return "INFO"
+	case SeverityError:
+		return "ERROR"
+	}
+	return ""
+}
+
+func stringKeyValue(k, v string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   k,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v}},
+	}
+}
+
+// anyValue converts the JSON-compatible value x to its OTLP representation.
+func anyValue(x any) *commonpb.AnyValue {
+	switch x := x.(type) {
+	case nil:
+		return &commonpb.AnyValue{}
+	case string:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: x}}
+	case bool:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: x}}
+	case json.Number:
+		if i, err := x.Int64(); err == nil {
+			return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: i}}
+		}
+		if f, err := x.Float64(); err == nil {
+			return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: f}}
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: x.String()}}
+	case int:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(x)}}
+	case int64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: x}}
+	case float64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: x}}
+	case []any:
+		values := make([]*commonpb.AnyValue, len(x))
+		for i := range x {
+			values[i] = anyValue(x[i])
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{Values: values}}}
+	case map[string]any:
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		values := make([]*commonpb.KeyValue, len(keys))
+		for i, k := range keys {
+			values[i] = &commonpb.KeyValue{Key: k, Value: anyValue(x[k])}
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{Values: values}}}
+	default:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: fmt.Sprint(x)}}
+	}
+}
+
+func sendHTTP(ctx context.Context, client *http.Client, url string, msg *collogspb.ExportLogsServiceRequest) error {
+	body, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.New("unexpected response status: " + resp.Status)
+	}
+	return nil
+}
@@ -8,4 +8,6 @@ const (
 	P256 EllipticCurveAlgorithm = "P-256"
 	P384 EllipticCurveAlgorithm = "P-384"
 	P521 EllipticCurveAlgorithm = "P-521"
+
+	Ed25519 EllipticCurveAlgorithm = "Ed25519"
 )
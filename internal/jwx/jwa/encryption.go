@@ -0,0 +1,49 @@
+package jwa
+
+// KeyEncryptionAlgorithm represents the algorithms used to encrypt or determine the
+// Content Encryption Key (CEK) of a JWE, as described in https://tools.ietf.org/html/rfc7518#section-4.1
+type KeyEncryptionAlgorithm string
+
+// Supported values for KeyEncryptionAlgorithm
+const (
+	Direct     KeyEncryptionAlgorithm = "dir"          // Direct use of a shared symmetric key as the CEK
+	ECDHES     KeyEncryptionAlgorithm = "ECDH-ES"      // Elliptic Curve Diffie-Hellman Ephemeral Static key agreement using Concat KDF
+	RSAOAEP    KeyEncryptionAlgorithm = "RSA-OAEP"     // RSAES OAEP using default parameters
+	RSAOAEP256 KeyEncryptionAlgorithm = "RSA-OAEP-256" // RSAES OAEP using SHA-256 and MGF1 with SHA-256
+)
+
+// String returns the string representation of a KeyEncryptionAlgorithm
+func (alg KeyEncryptionAlgorithm) String() string {
+	return string(alg)
+}
+
+// ContentEncryptionAlgorithm represents the algorithms used to encrypt the payload of a
+// JWE, as described in https://tools.ietf.org/html/rfc7518#section-5.1
+type ContentEncryptionAlgorithm string
+
+// Supported values for ContentEncryptionAlgorithm
+const (
+	A128GCM ContentEncryptionAlgorithm = "A128GCM" // AES GCM using 128-bit key
+	A192GCM ContentEncryptionAlgorithm = "A192GCM" // AES GCM using 192-bit key
+	A256GCM ContentEncryptionAlgorithm = "A256GCM" // AES GCM using 256-bit key
+)
+
+// KeySize returns the size of the content encryption key in bytes, or 0 if the
+// algorithm is not supported.
+func (enc ContentEncryptionAlgorithm) KeySize() int {
+	switch enc {
+	case A128GCM:
+		return 16
+	case A192GCM:
+		return 24
+	case A256GCM:
+		return 32
+	default:
+		return 0
+	}
+}
+
+// String returns the string representation of a ContentEncryptionAlgorithm
+func (enc ContentEncryptionAlgorithm) String() string {
+	return string(enc)
+}
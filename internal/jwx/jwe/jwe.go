@@ -0,0 +1,196 @@
+// Package jwe implements the compact serialization of JSON Web Encryption
+// as described in https://tools.ietf.org/html/rfc7516
+//
+// Only a subset of the algorithms defined in https://tools.ietf.org/html/rfc7518
+// is supported: RSA-OAEP, RSA-OAEP-256, ECDH-ES and dir for key management, and
+// A128GCM, A192GCM and A256GCM for content encryption.
+package jwe
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/open-policy-agent/opa/internal/jwx/jwa"
+)
+
+// Constants for JWE parameters
+const (
+	AlgorithmKey           = "alg"
+	EncryptionKey          = "enc"
+	EphemeralPublicKeyKey  = "epk"
+	AgreementPartyUInfoKey = "apu"
+	AgreementPartyVInfoKey = "apv"
+	CompressionKey         = "zip"
+)
+
+// Headers are the JWE protected header parameters used while decrypting.
+type Headers struct {
+	Algorithm           jwa.KeyEncryptionAlgorithm     `json:"alg"`
+	Encryption          jwa.ContentEncryptionAlgorithm `json:"enc"`
+	EphemeralPublicKey  json.RawMessage                `json:"epk,omitempty"`
+	AgreementPartyUInfo string                         `json:"apu,omitempty"`
+	AgreementPartyVInfo string                         `json:"apv,omitempty"`
+	Compression         string                         `json:"zip,omitempty"`
+}
+
+// Encrypt encrypts payload using the `alg` and `enc` algorithms found in the
+// headers, and serializes the result in compact serialization format. For RSA
+// key management, key must be a *rsa.PublicKey (or *rsa.PrivateKey); for
+// ECDH-ES, a *ecdsa.PublicKey (or *ecdsa.PrivateKey); for dir, the []byte
+// content encryption key. Randomness is drawn from rnd.
+//
+// The headers are serialized as the JWE protected header. If ECDH-ES is used,
+// the ephemeral public key is added to the headers as `epk`.
+func Encrypt(payload []byte, headers map[string]any, key any, rnd io.Reader) ([]byte, error) {
+	alg, ok := headers[AlgorithmKey].(string)
+	if !ok {
+		return nil, errors.New("missing or invalid alg header")
+	}
+	enc, ok := headers[EncryptionKey].(string)
+	if !ok {
+		return nil, errors.New("missing or invalid enc header")
+	}
+	if _, ok := headers[CompressionKey]; ok {
+		return nil, errors.New("unsupported zip header")
+	}
+	contentAlg := jwa.ContentEncryptionAlgorithm(enc)
+	if contentAlg.KeySize() == 0 {
+		return nil, fmt.Errorf("unsupported content encryption algorithm: %s", enc)
+	}
+
+	apu, apv, err := agreementPartyInfo(headers[AgreementPartyUInfoKey], headers[AgreementPartyVInfoKey])
+	if err != nil {
+		return nil, err
+	}
+
+	cek, encryptedKey, epk, err := wrapKey(jwa.KeyEncryptionAlgorithm(alg), contentAlg, key, apu, apv, rnd)
+	if err != nil {
+		return nil, err
+	}
+
+	if epk != nil {
+		hdrs := make(map[string]any, len(headers)+1)
+		for k, v := range headers {
+			hdrs[k] = v
+		}
+		hdrs[EphemeralPublicKeyKey] = epk
+		headers = hdrs
+	}
+
+	hdrBuf, err := json.Marshal(headers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal Headers: %w", err)
+	}
+	encodedHdr := base64.RawURLEncoding.EncodeToString(hdrBuf)
+
+	gcm, err := newGCM(cek)
+	if err != nil {
+		return nil, err
+	}
+	iv := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rnd, iv); err != nil {
+		return nil, fmt.Errorf("failed to generate initialization vector: %w", err)
+	}
+
+	sealed := gcm.Seal(nil, iv, payload, []byte(encodedHdr))
+	ciphertext, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+
+	compactSerialization := strings.Join(
+		[]string{
+			encodedHdr,
+			base64.RawURLEncoding.EncodeToString(encryptedKey),
+			base64.RawURLEncoding.EncodeToString(iv),
+			base64.RawURLEncoding.EncodeToString(ciphertext),
+			base64.RawURLEncoding.EncodeToString(tag),
+		}, ".",
+	)
+	return []byte(compactSerialization), nil
+}
+
+// Decrypt decrypts a JWE serialized in compact serialization format using key,
+// which must be a *rsa.PrivateKey for RSA key management, a *ecdsa.PrivateKey
+// for ECDH-ES, or the []byte content encryption key for dir. On success, the
+// decoded protected header and the plaintext are returned.
+func Decrypt(buf []byte, key any) (header []byte, payload []byte, err error) {
+	parts := strings.Split(strings.TrimSpace(string(buf)), ".")
+	if len(parts) != 5 {
+		return nil, nil, errors.New("failed to split compact serialization")
+	}
+
+	decoded := make([][]byte, len(parts))
+	for i, part := range parts {
+		if decoded[i], err = base64.RawURLEncoding.DecodeString(part); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode compact serialization part %d: %w", i+1, err)
+		}
+	}
+	header, encryptedKey, iv, ciphertext, tag := decoded[0], decoded[1], decoded[2], decoded[3], decoded[4]
+
+	var hdr Headers
+	if err := json.Unmarshal(header, &hdr); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse JOSE Headers: %w", err)
+	}
+	if hdr.Compression != "" {
+		return nil, nil, fmt.Errorf("unsupported zip header: %s", hdr.Compression)
+	}
+	if hdr.Encryption.KeySize() == 0 {
+		return nil, nil, fmt.Errorf("unsupported content encryption algorithm: %s", hdr.Encryption)
+	}
+
+	apu, apv, err := agreementPartyInfo(hdr.AgreementPartyUInfo, hdr.AgreementPartyVInfo)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cek, err := unwrapKey(hdr.Algorithm, hdr.Encryption, key, encryptedKey, hdr.EphemeralPublicKey, apu, apv)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	gcm, err := newGCM(cek)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(iv) != gcm.NonceSize() || len(tag) != gcm.Overhead() {
+		return nil, nil, errors.New("invalid initialization vector or authentication tag")
+	}
+
+	payload, err = gcm.Open(nil, iv, append(ciphertext, tag...), []byte(parts[0]))
+	if err != nil {
+		return nil, nil, errors.New("failed to decrypt payload")
+	}
+	return header, payload, nil
+}
+
+func newGCM(cek []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AES cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// agreementPartyInfo decodes the base64url encoded `apu` and `apv` header
+// values, if present.
+func agreementPartyInfo(apu, apv any) ([]byte, []byte, error) {
+	var out [2][]byte
+	for i, v := range []any{apu, apv} {
+		switch v := v.(type) {
+		case nil:
+		case string:
+			b, err := base64.RawURLEncoding.DecodeString(v)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to decode agreement party info: %w", err)
+			}
+			out[i] = b
+		default:
+			return nil, nil, errors.New("invalid agreement party info header")
+		}
+	}
+	return out[0], out[1], nil
+}
@@ -0,0 +1,152 @@
+package jwe
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/open-policy-agent/opa/internal/jwx/jwk"
+)
+
+func TestConcatKDF(t *testing.T) {
+	// https://tools.ietf.org/html/rfc7518#appendix-C
+	alice := mustParseKey(t, `{"kty":"EC","crv":"P-256","x":"gI0GAILBdu7T53akrFmMyGcsF3n5dO7MmwNBHKW5SV0","y":"SLW_xSffzlPWrHEVI30DHM_4egVwt3NQqeUD7nMFpps","d":"0_NxaRPUMQoAJt50Gz8YiTr8gRTwyEaCumd-MToTmIo"}`).(*ecdsa.PrivateKey)
+	bob := mustParseKey(t, `{"kty":"EC","crv":"P-256","x":"weNJy2HscCSM6AEDTDg04biOvhFhyyWvOHQfeF_PxMQ","y":"e8lnCO-AlStT-NJVX-crhB7QRYhiix03illJOVAOyck","d":"VEmDZpDXXK8p8N0Cndsxs924q6nS1RXFASRl6BfUqdw"}`).(*ecdsa.PrivateKey)
+
+	a, err := alice.ECDH()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := bob.PublicKey.ECDH()
+	if err != nil {
+		t.Fatal(err)
+	}
+	z, err := a.ECDH(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := concatKDF(z, "A128GCM", []byte("Alice"), []byte("Bob"), 16)
+	if exp, act := "VqqN6vgjbSBcIijNcacQGg", base64.RawURLEncoding.EncodeToString(key); exp != act {
+		t.Fatalf("expected derived key %s, got %s", exp, act)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	symmetricKey := make([]byte, 24)
+	if _, err := rand.Read(symmetricKey); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		alg     string
+		enc     string
+		public  any
+		private any
+	}{
+		{"RSA-OAEP", "A128GCM", &rsaKey.PublicKey, rsaKey},
+		{"RSA-OAEP-256", "A256GCM", &rsaKey.PublicKey, rsaKey},
+		{"ECDH-ES", "A128GCM", &ecKey.PublicKey, ecKey},
+		{"ECDH-ES", "A256GCM", ecKey, ecKey},
+		{"dir", "A192GCM", symmetricKey, symmetricKey},
+	}
+
+	payload := []byte("The true sign of intelligence is not knowledge but imagination.")
+	for _, tc := range tests {
+		t.Run(tc.alg+" "+tc.enc, func(t *testing.T) {
+			headers := map[string]any{"alg": tc.alg, "enc": tc.enc, "kid": "test", "apu": "QWxpY2U"}
+			buf, err := Encrypt(payload, headers, tc.public, rand.Reader)
+			if err != nil {
+				t.Fatalf("Failed to encrypt payload: %v", err)
+			}
+			if _, ok := headers["epk"]; ok {
+				t.Fatal("Encrypt must not modify the headers passed in")
+			}
+
+			header, decrypted, err := Decrypt(buf, tc.private)
+			if err != nil {
+				t.Fatalf("Failed to decrypt payload: %v", err)
+			}
+			if !bytes.Equal(payload, decrypted) {
+				t.Fatalf("Mismatched payload (%s):(%s)", payload, decrypted)
+			}
+
+			var hdr map[string]any
+			if err := json.Unmarshal(header, &hdr); err != nil {
+				t.Fatal(err)
+			}
+			if hdr["kid"] != "test" {
+				t.Fatalf("Expected kid header to be preserved, got %v", hdr)
+			}
+
+			// tampering with the protected header must fail authentication
+			parts := strings.Split(string(buf), ".")
+			hdr["kid"] = "other"
+			tampered, _ := json.Marshal(hdr)
+			parts[0] = base64.RawURLEncoding.EncodeToString(tampered)
+			if _, _, err := Decrypt([]byte(strings.Join(parts, ".")), tc.private); err == nil {
+				t.Fatal("Expected decryption of tampered JWE to fail")
+			}
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	symmetricKey := make([]byte, 16)
+
+	for _, headers := range []map[string]any{
+		{"enc": "A128GCM"},
+		{"alg": "dir"},
+		{"alg": "dir", "enc": "A128CBC-HS256"},
+		{"alg": "A128KW", "enc": "A128GCM"},
+		{"alg": "dir", "enc": "A256GCM"},
+		{"alg": "dir", "enc": "A128GCM", "zip": "DEF"},
+		{"alg": "RSA-OAEP", "enc": "A128GCM"},
+	} {
+		if _, err := Encrypt([]byte("payload"), headers, symmetricKey, rand.Reader); err == nil {
+			t.Errorf("Expected error for headers %v", headers)
+		}
+	}
+
+	buf, err := Encrypt([]byte("payload"), map[string]any{"alg": "dir", "enc": "A128GCM"}, symmetricKey, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := Decrypt(buf, make([]byte, 16)[:15]); err == nil {
+		t.Error("Expected error for wrong key size")
+	}
+	otherKey := bytes.Repeat([]byte{1}, 16)
+	if _, _, err := Decrypt(buf, otherKey); err == nil {
+		t.Error("Expected error for wrong key")
+	}
+	if _, _, err := Decrypt([]byte("a.b.c"), symmetricKey); err == nil {
+		t.Error("Expected error for malformed JWE")
+	}
+}
+
+func mustParseKey(t *testing.T, src string) any {
+	t.Helper()
+	set, err := jwk.ParseString(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := set.Keys[0].Materialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
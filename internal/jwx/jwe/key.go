@@ -0,0 +1,188 @@
+package jwe
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	_ "crypto/sha1" // RSA-OAEP is defined using SHA-1
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/open-policy-agent/opa/internal/jwx/jwa"
+	"github.com/open-policy-agent/opa/internal/jwx/jwk"
+)
+
+// wrapKey determines the content encryption key for enc and, depending on alg,
+// returns it in encrypted form, or the ephemeral public key to be included in the
+// JWE header.
+func wrapKey(alg jwa.KeyEncryptionAlgorithm, enc jwa.ContentEncryptionAlgorithm, key any, apu, apv []byte, rnd io.Reader) (cek []byte, encryptedKey []byte, epk map[string]any, err error) {
+	switch alg {
+	case jwa.RSAOAEP, jwa.RSAOAEP256:
+		var pub *rsa.PublicKey
+		switch k := key.(type) {
+		case *rsa.PublicKey:
+			pub = k
+		case *rsa.PrivateKey:
+			pub = &k.PublicKey
+		default:
+			return nil, nil, nil, fmt.Errorf("invalid key type %T. *rsa.PublicKey is required", key)
+		}
+		cek = make([]byte, enc.KeySize())
+		if _, err := io.ReadFull(rnd, cek); err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to generate content encryption key: %w", err)
+		}
+		encryptedKey, err = rsa.EncryptOAEP(oaepHash(alg).New(), rnd, pub, cek, nil)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to encrypt content encryption key: %w", err)
+		}
+		return cek, encryptedKey, nil, nil
+	case jwa.ECDHES:
+		var pub *ecdsa.PublicKey
+		switch k := key.(type) {
+		case *ecdsa.PublicKey:
+			pub = k
+		case *ecdsa.PrivateKey:
+			pub = &k.PublicKey
+		default:
+			return nil, nil, nil, fmt.Errorf("invalid key type %T. *ecdsa.PublicKey is required", key)
+		}
+		recipient, err := pub.ECDH()
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid public key: %w", err)
+		}
+		ephemeral, err := recipient.Curve().GenerateKey(rnd)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to generate ephemeral key: %w", err)
+		}
+		z, err := ephemeral.ECDH(recipient)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to compute shared secret: %w", err)
+		}
+
+		// uncompressed point encoding: 0x04 || X || Y
+		point := ephemeral.PublicKey().Bytes()[1:]
+		epk = map[string]any{
+			"kty": jwa.EC.String(),
+			"crv": pub.Curve.Params().Name,
+			"x":   base64.RawURLEncoding.EncodeToString(point[:len(point)/2]),
+			"y":   base64.RawURLEncoding.EncodeToString(point[len(point)/2:]),
+		}
+		return concatKDF(z, enc.String(), apu, apv, enc.KeySize()), nil, epk, nil
+	case jwa.Direct:
+		cek, ok := key.([]byte)
+		if !ok {
+			return nil, nil, nil, fmt.Errorf("invalid key type %T. []byte is required", key)
+		}
+		if len(cek) != enc.KeySize() {
+			return nil, nil, nil, fmt.Errorf("invalid key size for %s", enc)
+		}
+		return cek, nil, nil, nil
+	default:
+		return nil, nil, nil, fmt.Errorf("unsupported key encryption algorithm: %s", alg)
+	}
+}
+
+// unwrapKey recovers the content encryption key for enc.
+func unwrapKey(alg jwa.KeyEncryptionAlgorithm, enc jwa.ContentEncryptionAlgorithm, key any, encryptedKey []byte, epk []byte, apu, apv []byte) ([]byte, error) {
+	switch alg {
+	case jwa.RSAOAEP, jwa.RSAOAEP256:
+		priv, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("invalid key type %T. *rsa.PrivateKey is required", key)
+		}
+		cek, err := rsa.DecryptOAEP(oaepHash(alg).New(), nil, priv, encryptedKey, nil)
+		if err != nil || len(cek) != enc.KeySize() {
+			return nil, errors.New("failed to decrypt content encryption key")
+		}
+		return cek, nil
+	case jwa.ECDHES:
+		priv, ok := key.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("invalid key type %T. *ecdsa.PrivateKey is required", key)
+		}
+		if len(encryptedKey) != 0 {
+			return nil, errors.New("unexpected encrypted key for ECDH-ES")
+		}
+		recipient, err := priv.ECDH()
+		if err != nil {
+			return nil, fmt.Errorf("invalid private key: %w", err)
+		}
+		ephemeral, err := ephemeralPublicKey(epk, priv)
+		if err != nil {
+			return nil, err
+		}
+		z, err := recipient.ECDH(ephemeral)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute shared secret: %w", err)
+		}
+		return concatKDF(z, enc.String(), apu, apv, enc.KeySize()), nil
+	case jwa.Direct:
+		cek, ok := key.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("invalid key type %T. []byte is required", key)
+		}
+		if len(encryptedKey) != 0 {
+			return nil, errors.New("unexpected encrypted key for dir")
+		}
+		if len(cek) != enc.KeySize() {
+			return nil, fmt.Errorf("invalid key size for %s", enc)
+		}
+		return cek, nil
+	default:
+		return nil, fmt.Errorf("unsupported key encryption algorithm: %s", alg)
+	}
+}
+
+func oaepHash(alg jwa.KeyEncryptionAlgorithm) crypto.Hash {
+	if alg == jwa.RSAOAEP256 {
+		return crypto.SHA256
+	}
+	return crypto.SHA1
+}
+
+// ephemeralPublicKey parses the `epk` header value, which must be a key on the
+// same curve as priv.
+func ephemeralPublicKey(epk []byte, priv *ecdsa.PrivateKey) (*ecdh.PublicKey, error) {
+	if len(epk) == 0 {
+		return nil, errors.New("missing epk header")
+	}
+	set, err := jwk.ParseBytes(epk)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse epk header: %w", err)
+	}
+	key, err := set.Keys[0].Materialize()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse epk header: %w", err)
+	}
+	pub, ok := key.(*ecdsa.PublicKey)
+	if !ok || pub.Curve != priv.Curve {
+		return nil, errors.New("invalid epk header: key must be on the recipient's curve")
+	}
+	return pub.ECDH()
+}
+
+// concatKDF derives a key of keyLen bytes from the shared secret z using the
+// Concat KDF as described in https://tools.ietf.org/html/rfc7518#section-4.6.2
+func concatKDF(z []byte, algID string, apu, apv []byte, keyLen int) []byte {
+	var otherInfo []byte
+	for _, v := range [][]byte{[]byte(algID), apu, apv} {
+		otherInfo = binary.BigEndian.AppendUint32(otherInfo, uint32(len(v)))
+		otherInfo = append(otherInfo, v...)
+	}
+	otherInfo = binary.BigEndian.AppendUint32(otherInfo, uint32(keyLen*8))
+
+	var out []byte
+	for counter := uint32(1); len(out) < keyLen; counter++ {
+		h := sha256.New()
+		h.Write(binary.BigEndian.AppendUint32(nil, counter))
+		h.Write(z)
+		h.Write(otherInfo)
+		out = h.Sum(out)
+	}
+	return out[:keyLen]
+}
@@ -2,6 +2,7 @@ package jwk
 
 import (
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 
 	"github.com/open-policy-agent/opa/internal/jwx/jwa"
@@ -23,6 +24,7 @@ type Key interface {
 	// Materialize creates the corresponding key. For example,
 	// RSA types would create *rsa.PublicKey or *rsa.PrivateKey,
 	// EC types would create *ecdsa.PublicKey or *ecdsa.PrivateKey,
+	// OKP types would create ed25519.PublicKey or ed25519.PrivateKey,
 	// and OctetSeq types create a []byte key.
 	Materialize() (any, error)
 	GenerateKey(*RawKeyJSON) error
@@ -69,3 +71,15 @@ type ECDSAPrivateKey struct {
 	*StandardHeaders
 	key *ecdsa.PrivateKey
 }
+
+// OKPPublicKey is a type of JWK generated from Ed25519 public keys
+type OKPPublicKey struct {
+	*StandardHeaders
+	key ed25519.PublicKey
+}
+
+// OKPPrivateKey is a type of JWK generated from Ed25519 private keys
+type OKPPrivateKey struct {
+	*StandardHeaders
+	key ed25519.PrivateKey
+}
@@ -3,6 +3,7 @@ package jwk
 
 import (
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"encoding/json"
 	"errors"
@@ -29,6 +30,8 @@ func GetPublicKey(key any) (any, error) {
 		return v.Public(), nil
 	case *ecdsa.PrivateKey:
 		return v.Public(), nil
+	case ed25519.PrivateKey:
+		return v.Public(), nil
 	case []byte:
 		return v, nil
 	default:
@@ -44,6 +47,8 @@ func GetKeyTypeFromKey(key any) jwa.KeyType {
 		return jwa.RSA
 	case *ecdsa.PrivateKey, *ecdsa.PublicKey:
 		return jwa.EC
+	case ed25519.PrivateKey, ed25519.PublicKey:
+		return jwa.OKP
 	case []byte:
 		return jwa.OctetSeq
 	default:
@@ -66,6 +71,10 @@ func New(key any) (Key, error) {
 		return newECDSAPrivateKey(v)
 	case *ecdsa.PublicKey:
 		return newECDSAPublicKey(v)
+	case ed25519.PrivateKey:
+		return newOKPPrivateKey(v)
+	case ed25519.PublicKey:
+		return newOKPPublicKey(v)
 	case []byte:
 		return newSymmetricKey(v)
 	default:
@@ -140,6 +149,12 @@ func (r *RawKeyJSON) GenerateKey() (Key, error) {
 		} else {
 			key = &ECDSAPublicKey{}
 		}
+	case jwa.OKP:
+		if r.D != nil {
+			key = &OKPPrivateKey{}
+		} else {
+			key = &OKPPublicKey{}
+		}
 	case jwa.OctetSeq:
 		key = &SymmetricKey{}
 	default:
@@ -0,0 +1,57 @@
+package jwk_test
+
+import (
+	"crypto/ed25519"
+	"testing"
+
+	"github.com/open-policy-agent/opa/internal/jwx/jwa"
+	"github.com/open-policy-agent/opa/internal/jwx/jwk"
+)
+
+func TestOKP(t *testing.T) {
+
+	// https://tools.ietf.org/html/rfc8037#appendix-A.1
+	jwkSrc := `{
+  "kty": "OKP",
+  "crv": "Ed25519",
+  "d": "nWGxne_9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A",
+  "x": "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"
+}`
+
+	t.Run("Parse private key", func(t *testing.T) {
+		set, err := jwk.ParseString(jwkSrc)
+		if err != nil {
+			t.Fatalf("Failed to parse JWK: %v", err)
+		}
+		key, err := set.Keys[0].Materialize()
+		if err != nil {
+			t.Fatalf("Failed to materialize key: %v", err)
+		}
+		privateKey, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			t.Fatalf("Expected ed25519.PrivateKey, got %T", key)
+		}
+		if jwk.GetKeyTypeFromKey(privateKey) != jwa.OKP {
+			t.Fatalf("Expected key type %s", jwa.OKP)
+		}
+		publicKey, err := jwk.GetPublicKey(privateKey)
+		if err != nil {
+			t.Fatalf("Failed to get public key: %v", err)
+		}
+		if _, ok := publicKey.(ed25519.PublicKey); !ok {
+			t.Fatalf("Expected ed25519.PublicKey, got %T", publicKey)
+		}
+	})
+
+	t.Run("Key Generation Errors", func(t *testing.T) {
+		for _, src := range []string{
+			`{"kty": "OKP", "crv": "X25519", "x": "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`,
+			`{"kty": "OKP", "crv": "Ed25519", "x": "11qYAYKxCrfVS_7TyWQHOg"}`,
+			`{"kty": "OKP", "crv": "Ed25519", "x": "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo", "d": "mWGxne_9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A"}`,
+		} {
+			if _, err := jwk.ParseString(src); err == nil {
+				t.Fatalf("Key generation should fail for %s", src)
+			}
+		}
+	})
+}
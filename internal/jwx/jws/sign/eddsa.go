@@ -0,0 +1,30 @@
+package sign
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+
+	"github.com/open-policy-agent/opa/internal/jwx/jwa"
+)
+
+// Algorithm returns the signer algorithm
+func (s EdDSASigner) Algorithm() jwa.SignatureAlgorithm {
+	return jwa.EdDSA
+}
+
+// Sign signs payload with an Ed25519 private key
+func (s EdDSASigner) Sign(payload []byte, key any) ([]byte, error) {
+	if key == nil {
+		return nil, errors.New("missing private key while signing payload")
+	}
+
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("invalid key type %T. ed25519.PrivateKey is required", key)
+	}
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, errors.New("invalid ed25519 private key size")
+	}
+	return ed25519.Sign(privateKey, payload), nil
+}
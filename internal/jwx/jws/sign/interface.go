@@ -44,3 +44,6 @@ type HMACSigner struct {
 	alg  jwa.SignatureAlgorithm
 	sign hmacSignFunc
 }
+
+// EdDSASigner uses crypto/ed25519 to sign the payloads.
+type EdDSASigner struct{}
@@ -0,0 +1,26 @@
+package verify
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+)
+
+// Verify checks whether the signature for a given input and key is correct
+func (v EdDSAVerifier) Verify(payload []byte, signature []byte, key any) error {
+	if key == nil {
+		return errors.New(`missing public key while verifying payload`)
+	}
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return fmt.Errorf(`invalid key type %T. ed25519.PublicKey is required`, key)
+	}
+	if len(publicKey) != ed25519.PublicKeySize {
+		return errors.New(`invalid ed25519 public key size`)
+	}
+
+	if !ed25519.Verify(publicKey, payload, signature) {
+		return errors.New(`failed to verify signature using ed25519`)
+	}
+	return nil
+}
@@ -37,3 +37,6 @@ type ECDSAVerifier struct {
 type HMACVerifier struct {
 	signer sign.Signer
 }
+
+// EdDSAVerifier implements the Verifier interface
+type EdDSAVerifier struct{}
@@ -2,6 +2,7 @@ package verify
 
 import (
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
@@ -19,6 +20,8 @@ func New(alg jwa.SignatureAlgorithm) (Verifier, error) {
 		return newRSA(alg)
 	case jwa.ES256, jwa.ES384, jwa.ES512:
 		return newECDSA(alg)
+	case jwa.EdDSA:
+		return EdDSAVerifier{}, nil
 	case jwa.HS256, jwa.HS384, jwa.HS512:
 		return newHMAC(alg)
 	default:
@@ -26,12 +29,12 @@ func New(alg jwa.SignatureAlgorithm) (Verifier, error) {
 	}
 }
 
-// GetSigningKey returns a *rsa.PublicKey or *ecdsa.PublicKey typically encoded in PEM blocks of type "PUBLIC KEY",
-// for RSA and ECDSA family of algorithms.
+// GetSigningKey returns a *rsa.PublicKey, *ecdsa.PublicKey or ed25519.PublicKey typically encoded in PEM blocks of
+// type "PUBLIC KEY", for RSA, ECDSA and EdDSA family of algorithms.
 // For HMAC family, it return a []byte value
 func GetSigningKey(key string, alg jwa.SignatureAlgorithm) (any, error) {
 	switch alg {
-	case jwa.RS256, jwa.RS384, jwa.RS512, jwa.PS256, jwa.PS384, jwa.PS512, jwa.ES256, jwa.ES384, jwa.ES512:
+	case jwa.RS256, jwa.RS384, jwa.RS512, jwa.PS256, jwa.PS384, jwa.PS512, jwa.ES256, jwa.ES384, jwa.ES512, jwa.EdDSA:
 		block, _ := pem.Decode([]byte(key))
 		if block == nil {
 			return nil, errors.New("failed to parse PEM block containing the key")
@@ -43,7 +46,7 @@ func GetSigningKey(key string, alg jwa.SignatureAlgorithm) (any, error) {
 		}
 
 		switch pub := pub.(type) {
-		case *rsa.PublicKey, *ecdsa.PublicKey:
+		case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
 			return pub, nil
 		default:
 			return nil, fmt.Errorf("invalid key type %T", pub)
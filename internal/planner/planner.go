@@ -47,6 +47,9 @@ type Planner struct {
 	lnext   ir.Local                // next variable to use
 	loc     *location.Location      // location currently "being planned"
 	debug   debug.Debug             // debug information produced during planning
+	cse     bool                    // eliminate common subexpressions
+	shared  map[ast.Value]string    // comprehensions planned as shared functions
+	nshared int                     // next shared function number
 }
 
 // debugf prepends the planner location. We're passing callstack depth 2 because
@@ -105,6 +108,16 @@ func (p *Planner) WithModules(modules []*ast.Module) *Planner {
 	return p
 }
 
+// WithCommonSubexpressionElimination tells the planner to plan the closed
+// comprehensions that occur more than once in the modules and queries, e.g.,
+// in several rules sharing parts of their bodies, as functions that are
+// planned once and called by every occurrence. This reduces the size of the
+// plan, and evaluation memoizes the result of the functions.
+func (p *Planner) WithCommonSubexpressionElimination(yes bool) *Planner {
+	p.cse = yes
+	return p
+}
+
 // WithDebug sets where debug messages are written to.
 func (p *Planner) WithDebug(sink io.Writer) *Planner {
 	if sink != nil {
@@ -120,6 +133,10 @@ func (p *Planner) Plan() (*ir.Policy, error) {
 		return nil, err
 	}
 
+	if p.cse {
+		p.findSharedComprehensions()
+	}
+
 	if err := p.planQueries(); err != nil {
 		return nil, err
 	}
@@ -1526,14 +1543,24 @@ func (p *Planner) planValue(t ast.Value, loc *ast.Location, iter planiter) error
 		return p.planObject(v, iter)
 	case ast.Set:
 		return p.planSet(v, iter)
-	case *ast.SetComprehension:
+	case *ast.SetComprehension, *ast.ArrayComprehension, *ast.ObjectComprehension:
 		p.loc = loc
+		if key, ok := p.shared[v]; ok {
+			return p.planSharedComprehension(key, v, iter)
+		}
+		return p.planComprehensionValue(v, iter)
+	default:
+		return fmt.Errorf("%v term not implemented", ast.ValueName(v))
+	}
+}
+
+func (p *Planner) planComprehensionValue(t ast.Value, iter planiter) error {
+	switch v := t.(type) {
+	case *ast.SetComprehension:
 		return p.planSetComprehension(v, iter)
 	case *ast.ArrayComprehension:
-		p.loc = loc
 		return p.planArrayComprehension(v, iter)
 	case *ast.ObjectComprehension:
-		p.loc = loc
 		return p.planObjectComprehension(v, iter)
 	default:
 		return fmt.Errorf("%v term not implemented", ast.ValueName(v))
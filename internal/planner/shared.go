@@ -0,0 +1,269 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package planner
+
+import (
+	"fmt"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/ir"
+)
+
+// sharedFuncPrefix prefixes the keys of shared comprehension functions in the
+// funcstack, so that they cannot clash with rule paths.
+const sharedFuncPrefix = "shared:"
+
+// findSharedComprehensions eliminates common subexpressions: it finds the
+// comprehensions that occur more than once in the modules and queries and that
+// are closed, i.e., that do not refer to variables of their enclosing scopes.
+// Since closed comprehensions only depend on the input and data documents,
+// each of them is planned once as a function that all occurrences call.
+func (p *Planner) findSharedComprehensions() {
+	keys := map[ast.Value]string{}
+	counts := map[string]int{}
+
+	visit := func(x any) {
+		visitComprehensions(x, ast.NewVarSet(), func(c ast.Value) {
+			key := canonicalComprehension(c)
+			keys[c] = key
+			counts[key]++
+		})
+	}
+
+	for _, module := range p.modules {
+		for _, rule := range module.Rules {
+			visit(rule)
+		}
+	}
+
+	for _, qs := range p.queries {
+		for _, q := range qs.Queries {
+			visit(q)
+		}
+	}
+
+	p.shared = make(map[ast.Value]string, len(keys))
+	for c, key := range keys {
+		if counts[key] > 1 {
+			p.shared[c] = key
+		}
+	}
+}
+
+// visitComprehensions calls f on the closed comprehensions of x, given the
+// variables of the scopes enclosing x.
+func visitComprehensions(x any, outer ast.VarSet, f func(ast.Value)) {
+	vars, comprehensions := scopeOf(x)
+	visible := outer.Copy()
+	visible.Update(vars)
+
+	for _, c := range comprehensions {
+		if closedComprehension(c, visible) {
+			f(c)
+		}
+		visitComprehensions(c, visible, f)
+	}
+}
+
+// scopeOf returns the variables of x outside of the comprehensions it contains,
+// and the outermost comprehensions it contains.
+func scopeOf(x any) (ast.VarSet, []ast.Value) {
+	vars := ast.NewVarSet()
+	var comprehensions []ast.Value
+
+	walkScope(x, func(y any) bool {
+		switch y := y.(type) {
+		case *ast.Term:
+			switch c := y.Value.(type) {
+			case *ast.ArrayComprehension, *ast.SetComprehension, *ast.ObjectComprehension:
+				comprehensions = append(comprehensions, c)
+				return true
+			}
+		case ast.Var:
+			vars.Add(y)
+		}
+		return false
+	})
+
+	return vars, comprehensions
+}
+
+// closedComprehension returns true if c does not refer to the variables of its
+// enclosing scopes, and does not print, which would be observable if c was
+// shared.
+func closedComprehension(c ast.Value, visible ast.VarSet) bool {
+	closed := true
+
+	walkScope(c, func(x any) bool {
+		if !closed {
+			return true
+		}
+		switch x := x.(type) {
+		case ast.Var:
+			if visible.Contains(x) && !ast.ReservedVars.Contains(x) {
+				closed = false
+			}
+		case *ast.Expr:
+			if x.IsCall() {
+				switch x.Operator().String() {
+				case ast.Print.Name, ast.InternalPrint.Name:
+					closed = false
+				}
+			}
+		}
+		return !closed
+	})
+
+	return closed
+}
+
+// walkScope walks x like ast.NewGenericVisitor(f).Walk(x), but skips the
+// operators of calls, which are not variables of the scope.
+func walkScope(x any, f func(any) bool) {
+	var vis *ast.GenericVisitor
+	vis = ast.NewGenericVisitor(func(y any) bool {
+		if f(y) {
+			return true
+		}
+		switch y := y.(type) {
+		case *ast.Expr:
+			if y.IsCall() {
+				for _, t := range y.Operands() {
+					vis.Walk(t)
+				}
+				for _, w := range y.With {
+					vis.Walk(w)
+				}
+				return true
+			}
+		case ast.Call:
+			for _, t := range y[1:] {
+				vis.Walk(t)
+			}
+			return true
+		}
+		return false
+	})
+	vis.Walk(x)
+}
+
+// canonicalComprehension returns a string that is the same for comprehensions
+// that only differ in the names of their variables. The heads of references,
+// e.g., built-in function names and root documents, are not renamed.
+func canonicalComprehension(c ast.Value) string {
+	heads := ast.NewVarSet()
+	ast.WalkRefs(c, func(r ast.Ref) bool {
+		if v, ok := r[0].Value.(ast.Var); ok {
+			heads.Add(v)
+		}
+		return false
+	})
+
+	cpy := ast.NewTerm(c).Copy().Value
+	names := map[ast.Var]ast.Var{}
+	x, _ := ast.TransformVars(cpy, func(v ast.Var) (ast.Value, error) {
+		if heads.Contains(v) {
+			return v, nil
+		}
+		name, ok := names[v]
+		if !ok {
+			name = ast.Var(fmt.Sprintf("shared$%d", len(names)))
+			names[v] = name
+		}
+		return name, nil
+	})
+
+	return x.(ast.Value).String()
+}
+
+// planSharedComprehension plans a call to the function computing the shared
+// comprehension c, planning the function first if necessary.
+func (p *Planner) planSharedComprehension(key string, c ast.Value, iter planiter) error {
+	key = sharedFuncPrefix + key
+
+	name, ok := p.funcs.Get(key)
+	if !ok {
+		var err error
+		name, err = p.planSharedFunc(key, c)
+		if err != nil {
+			return err
+		}
+	}
+
+	p.ltarget = p.newOperand()
+	p.appendStmt(&ir.CallStmt{
+		Func:   name,
+		Args:   p.defaultOperands(),
+		Result: p.ltarget.Value.(ir.Local),
+	})
+
+	return iter()
+}
+
+func (p *Planner) planSharedFunc(key string, c ast.Value) (string, error) {
+
+	// Save current state of planner.
+	pvars := p.vars
+	pcurr := p.curr
+	pltarget := p.ltarget
+	plnext := p.lnext
+	ploc := p.loc
+
+	// Reset the variable counter for the function plan.
+	p.lnext = ir.Input
+
+	pcount := p.funcs.argVars()
+	params := make([]ir.Local, 0, pcount)
+	for range pcount {
+		params = append(params, p.newLocal())
+	}
+
+	// Shared functions are named like rule functions, but cannot clash with
+	// them as their paths are not valid Rego references.
+	gen := fmt.Sprintf("g%d", p.funcs.gen())
+	shared := fmt.Sprintf("$shared%d", p.nshared)
+	p.nshared++
+
+	fn := &ir.Func{
+		Name:   gen + "." + shared,
+		Params: params,
+		Return: p.newLocal(),
+		Path:   []string{gen, shared},
+	}
+
+	vs := make(map[ast.Var]ir.Local, pcount)
+	for i, v := range p.funcs.vars() {
+		vs[v] = fn.Params[i]
+	}
+	p.vars = newVarstack(vs)
+
+	p.curr = &ir.Block{}
+	fn.Blocks = append(fn.Blocks, p.curr)
+
+	err := p.planComprehensionValue(c, func() error {
+		p.appendStmt(&ir.AssignVarStmt{
+			Source: p.ltarget,
+			Target: fn.Return,
+		})
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	fn.Blocks = append(fn.Blocks, p.blockWithStmt(&ir.ReturnLocalStmt{Source: fn.Return}))
+
+	p.appendFunc(fn)
+	p.funcs.Add(key, fn.Name)
+
+	// Restore the state of the planner.
+	p.lnext = plnext
+	p.ltarget = pltarget
+	p.vars = pvars
+	p.curr = pcurr
+	p.loc = ploc
+
+	return fn.Name, nil
+}
@@ -0,0 +1,131 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package planner
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/ir"
+)
+
+func TestPlannerCommonSubexpressionElimination(t *testing.T) {
+	tests := []struct {
+		note   string
+		module string
+		shared int
+	}{
+		{
+			note: "identical comprehensions across rules",
+			module: `package test
+
+			p := count([x | some x in input.xs; x > 1])
+			q := sum([y | some y in input.xs; y > 1])
+			r := {z | some z in input.xs}`,
+			shared: 1,
+		},
+		{
+			note: "nested comprehensions",
+			module: `package test
+
+			p := [x | some x in input.xs; x > count({y | some y in input.ys})]
+			q := [z | some z in input.xs; z > count({w | some w in input.ys})]`,
+			shared: 2,
+		},
+		{
+			note: "comprehensions with local variables",
+			module: `package test
+
+			p := [x | some x in input.xs; x > n] if n := 1
+			q := [x | some x in input.xs; x > n] if n := 1`,
+			shared: 0,
+		},
+		{
+			note: "comprehensions printing",
+			module: `package test
+
+			p := [x | some x in input.xs; print(x)]
+			q := [x | some x in input.xs; print(x)]`,
+			shared: 0,
+		},
+		{
+			note: "comprehensions with different built-in functions",
+			module: `package test
+
+			p := [count(x) | some x in input.xs]
+			q := [sum(x) | some x in input.xs]`,
+			shared: 0,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			compiler := ast.NewCompiler().WithEnablePrintStatements(true)
+			compiler.Compile(map[string]*ast.Module{
+				"test.rego": ast.MustParseModule(tc.module),
+			})
+			if compiler.Failed() {
+				t.Fatal(compiler.Errors)
+			}
+
+			modules := make([]*ast.Module, 0, len(compiler.Modules))
+			for _, module := range compiler.Modules {
+				modules = append(modules, module)
+			}
+
+			policy, err := New().
+				WithQueries([]QuerySet{{Name: "test", Queries: []ast.Body{ast.MustParseBody("data.test = x")}}}).
+				WithModules(modules).
+				WithBuiltinDecls(ast.BuiltinMap).
+				WithCommonSubexpressionElimination(true).
+				Plan()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if testing.Verbose() {
+				if err := ir.Pretty(os.Stderr, policy); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var shared []string
+			for _, fn := range policy.Funcs.Funcs {
+				if strings.HasPrefix(fn.Name, "g0.$shared") {
+					shared = append(shared, fn.Name)
+				}
+			}
+
+			if len(shared) != tc.shared {
+				t.Fatalf("expected %d shared functions, got %v", tc.shared, shared)
+			}
+
+			w := &sharedCallWalker{}
+			if err := ir.Walk(w, policy); err != nil {
+				t.Fatal(err)
+			}
+
+			if tc.shared > 0 && w.calls < 2 {
+				t.Fatalf("expected shared functions to be called at least twice, got %d calls", w.calls)
+			}
+		})
+	}
+}
+
+type sharedCallWalker struct {
+	calls int
+}
+
+func (*sharedCallWalker) Before(any) {}
+func (*sharedCallWalker) After(any)  {}
+
+func (w *sharedCallWalker) Visit(x any) (ir.Visitor, error) {
+	if call, ok := x.(*ir.CallStmt); ok && strings.HasPrefix(call.Func, "g0.$shared") {
+		w.calls++
+	}
+	return w, nil
+}
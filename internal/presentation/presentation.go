@@ -21,6 +21,7 @@ import (
 
 	"github.com/open-policy-agent/opa/v1/ast"
 	"github.com/open-policy-agent/opa/v1/cover"
+	"github.com/open-policy-agent/opa/v1/dependencies"
 	"github.com/open-policy-agent/opa/v1/format"
 	"github.com/open-policy-agent/opa/v1/loader"
 	"github.com/open-policy-agent/opa/v1/metrics"
@@ -37,6 +38,7 @@ var DefaultProfileSortOrder = []string{"total_time_ns", "num_eval", "num_redo",
 type DepAnalysisOutput struct {
 	Base    []ast.Ref `json:"base,omitempty"`
 	Virtual []ast.Ref `json:"virtual,omitempty"`
+	*dependencies.Requirements
 }
 
 // JSON outputs o to w as JSON.
@@ -79,12 +81,44 @@ func (o DepAnalysisOutput) Pretty(w io.Writer) error {
 		}
 	}
 
+	if len(rows) > 0 {
+		table := tablewriter.NewWriter(w)
+		table.SetHeader(headers)
+		table.SetAutoWrapText(false)
+		for i := range rows {
+			table.Append(rows[i])
+		}
+
+		table.Render()
+	}
+
+	if o.Requirements == nil {
+		return nil
+	}
+
+	rows = nil
+	if o.MinimumVersion != "" {
+		rows = append(rows, []string{"minimum opa version", o.MinimumVersion})
+	}
+	for _, bi := range o.Builtins {
+		rows = append(rows, []string{"builtin", bi.Name})
+	}
+	for _, root := range o.DataRoots {
+		rows = append(rows, []string{"data root", strconv.Quote(root)})
+	}
+	for _, kw := range o.FutureKeywords {
+		rows = append(rows, []string{"future keyword", kw})
+	}
+	for _, feat := range o.Features {
+		rows = append(rows, []string{"feature", feat})
+	}
+
 	if len(rows) == 0 {
 		return nil
 	}
 
 	table := tablewriter.NewWriter(w)
-	table.SetHeader(headers)
+	table.SetHeader([]string{"Requirement", "Value"})
 	table.SetAutoWrapText(false)
 	for i := range rows {
 		table.Append(rows[i])
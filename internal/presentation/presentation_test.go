@@ -15,6 +15,7 @@ import (
 	"testing"
 
 	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/dependencies"
 	"github.com/open-policy-agent/opa/v1/loader"
 	"github.com/open-policy-agent/opa/v1/rego"
 	"github.com/open-policy-agent/opa/v1/storage"
@@ -562,6 +563,33 @@ func TestDepsAnalysisPrettyOutput(t *testing.T) {
 				"+----------------+-------------------+",
 			},
 		},
+		{
+			note: "requirements",
+			output: DepAnalysisOutput{
+				Base: []ast.Ref{ast.MustParseRef("data.users")},
+				Requirements: &dependencies.Requirements{
+					Builtins:       []dependencies.BuiltinRequirement{{Name: "count", MinimumVersion: "0.17.0"}},
+					DataRoots:      []string{"users"},
+					Features:       []string{"rego_v1"},
+					MinimumVersion: "1.0.0",
+				},
+			},
+			want: []string{
+				"+----------------+",
+				"| BASE DOCUMENTS |",
+				"+----------------+",
+				"| data.users     |",
+				"+----------------+",
+				"+---------------------+---------+",
+				"|     REQUIREMENT     |  VALUE  |",
+				"+---------------------+---------+",
+				"| minimum opa version | 1.0.0   |",
+				"| builtin             | count   |",
+				"| data root           | \"users\" |",
+				"| feature             | rego_v1 |",
+				"+---------------------+---------+",
+			},
+		},
 	}
 
 	for _, tc := range tests {
@@ -37,6 +37,7 @@ type EvalEngine interface {
 	Entrypoints(context.Context) (map[string]int32, error)
 	WithPolicyBytes([]byte) EvalEngine
 	WithDataJSON(any) EvalEngine
+	WithMemoryLimit(uint32) EvalEngine
 	Eval(context.Context, EvalOpts) (*Result, error)
 	SetData(context.Context, any) error
 	SetDataPath(context.Context, []string, any) error
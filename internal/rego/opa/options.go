@@ -28,4 +28,5 @@ type EvalOpts struct {
 	NDBuiltinCache              builtins.NDBCache
 	PrintHook                   print.Hook
 	Capabilities                *ast.Capabilities
+	MemoryLimit                 uint32
 }
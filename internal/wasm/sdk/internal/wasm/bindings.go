@@ -52,9 +52,19 @@ func opaAbort(caller *wasmtime.Caller, args []wasmtime.Val) ([]wasmtime.Val, *wa
 		panic("invalid abort argument")
 	}
 
+	// The heap can only fail to grow if the memory of the instance reached its
+	// maximum size.
+	if msg := string(data[:n]); msg == mallocFailedMessage {
+		panic(memoryLimitError{message: msg})
+	}
+
 	panic(abortError{message: string(data[:n])})
 }
 
+// mallocFailedMessage is the message opa_malloc aborts with if the memory
+// cannot be grown.
+const mallocFailedMessage = "opa_malloc: failed"
+
 func opaPrintln(caller *wasmtime.Caller, args []wasmtime.Val) ([]wasmtime.Val, *wasmtime.Trap) {
 	data := caller.GetExport("memory").Memory().UnsafeData(caller)[args[0].I32():]
 
@@ -68,8 +78,10 @@ func opaPrintln(caller *wasmtime.Caller, args []wasmtime.Val) ([]wasmtime.Val, *
 }
 
 type builtinDispatcher struct {
-	ctx      *topdown.BuiltinContext
-	builtins map[int32]topdown.BuiltinFunc
+	ctx         *topdown.BuiltinContext
+	builtins    map[int32]topdown.BuiltinFunc
+	heapStart   int32
+	memoryLimit uint32
 }
 
 func newBuiltinDispatcher() *builtinDispatcher {
@@ -115,6 +127,14 @@ func (d *builtinDispatcher) Reset(ctx context.Context,
 
 }
 
+// SetMemoryLimit is called in Eval before using the builtinDispatcher. The
+// calls of built-in functions abort the evaluation if the heap grew by more
+// than limit bytes beyond start. A limit of 0 means no limit.
+func (d *builtinDispatcher) SetMemoryLimit(start int32, limit uint32) {
+	d.heapStart = start
+	d.memoryLimit = limit
+}
+
 func (d *builtinDispatcher) Call(caller *wasmtime.Caller, args []wasmtime.Val) (result []wasmtime.Val, trap *wasmtime.Trap) {
 
 	if d.ctx == nil {
@@ -125,6 +145,19 @@ func (d *builtinDispatcher) Call(caller *wasmtime.Caller, args []wasmtime.Val) (
 		panic("unreachable: uninitialized built-in dispatcher index")
 	}
 
+	// Built-in function calls are the points where control returns to the
+	// host during evaluation, so this is where the memory limit is checked
+	// before the evaluation completes.
+	if d.memoryLimit > 0 {
+		ptr, err := caller.GetExport("opa_heap_ptr_get").Func().Call(caller)
+		if err != nil {
+			panic(builtinError{err: err})
+		}
+		if err := checkMemoryLimit(d.heapStart, ptr.(int32), d.memoryLimit); err != nil {
+			panic(*err)
+		}
+	}
+
 	// Bridge ctx <-> topdown.Cancel
 	//
 	// If the ctx is cancelled (deadline expired, or manually cancelled), this will
@@ -290,9 +290,10 @@ func (i *VM) Eval(ctx context.Context,
 	iqbCache cache.InterQueryCache,
 	ndbCache builtins.NDBCache,
 	ph print.Hook,
-	capabilities *ast.Capabilities) ([]byte, error) {
+	capabilities *ast.Capabilities,
+	memoryLimit uint32) ([]byte, error) {
 	if i.abiMinorVersion < int32(2) {
-		return i.evalCompat(ctx, entrypoint, input, metrics, seed, ns, iqbCache, ndbCache, ph, capabilities)
+		return i.evalCompat(ctx, entrypoint, input, metrics, seed, ns, iqbCache, ndbCache, ph, capabilities, memoryLimit)
 	}
 
 	metrics.Timer("wasm_vm_eval").Start()
@@ -344,6 +345,7 @@ func (i *VM) Eval(ctx context.Context,
 	// cancelling the builtins that use topdown.Cancel, when the context is
 	// cancelled.
 	i.dispatcher.Reset(ctx, seed, ns, iqbCache, ndbCache, ph, capabilities)
+	i.dispatcher.SetMemoryLimit(heapPtr, memoryLimit)
 
 	metrics.Timer("wasm_vm_eval_call").Start()
 	resultAddr, err := i.evalOneOff(ctx, entrypoint, i.dataAddr, inputAddr, inputLen, heapPtr)
@@ -352,6 +354,10 @@ func (i *VM) Eval(ctx context.Context,
 	}
 	metrics.Timer("wasm_vm_eval_call").Stop()
 
+	if err := i.accountMemory(ctx, heapPtr, memoryLimit, metrics); err != nil {
+		return nil, err
+	}
+
 	data := i.memory.UnsafeData(i.store)[resultAddr:]
 	n := max(bytes.IndexByte(data, 0), 0)
 
@@ -371,7 +377,8 @@ func (i *VM) evalCompat(ctx context.Context,
 	iqbCache cache.InterQueryCache,
 	ndbCache builtins.NDBCache,
 	ph print.Hook,
-	capabilities *ast.Capabilities) ([]byte, error) {
+	capabilities *ast.Capabilities,
+	memoryLimit uint32) ([]byte, error) {
 	metrics.Timer("wasm_vm_eval").Start()
 	defer metrics.Timer("wasm_vm_eval").Stop()
 
@@ -382,6 +389,7 @@ func (i *VM) evalCompat(ctx context.Context,
 	// cancelling the builtins that use topdown.Cancel, when the context is
 	// cancelled.
 	i.dispatcher.Reset(ctx, seed, ns, iqbCache, ndbCache, ph, capabilities)
+	i.dispatcher.SetMemoryLimit(i.evalHeapPtr, memoryLimit)
 
 	err := i.setHeapState(ctx, i.evalHeapPtr)
 	if err != nil {
@@ -424,6 +432,10 @@ func (i *VM) evalCompat(ctx context.Context,
 		return nil, err
 	}
 
+	if err := i.accountMemory(ctx, i.evalHeapPtr, memoryLimit, metrics); err != nil {
+		return nil, err
+	}
+
 	metrics.Timer("wasm_vm_eval_prepare_result").Start()
 	resultAddr, err := i.evalCtxGetResult(ctx, ctxAddr)
 	if err != nil {
@@ -509,10 +521,43 @@ func (i *VM) SetPolicyData(ctx context.Context, opts vmOpts) error {
 	return nil
 }
 
+// accountMemory records the number of bytes the heap grew by during an
+// evaluation, which started with the heap pointer at start, in the
+// wasm_vm_eval_heap_bytes counter, and returns an error if they exceed limit.
+// A limit of 0 means no limit.
+func (i *VM) accountMemory(ctx context.Context, start int32, limit uint32, metrics metrics.Metrics) error {
+	ptr, err := i.getHeapState(ctx)
+	if err != nil {
+		return err
+	}
+
+	if ptr > start {
+		metrics.Counter("wasm_vm_eval_heap_bytes").Add(uint64(ptr - start))
+	}
+
+	if err := checkMemoryLimit(start, ptr, limit); err != nil {
+		return sdk_errors.New(sdk_errors.MemoryLimitErr, err.message)
+	}
+	return nil
+}
+
+// checkMemoryLimit returns an error if the heap pointer ptr is more than limit
+// bytes beyond start. A limit of 0 means no limit.
+func checkMemoryLimit(start, ptr int32, limit uint32) *memoryLimitError {
+	if limit == 0 || ptr <= start || uint32(ptr-start) <= limit {
+		return nil
+	}
+	return &memoryLimitError{message: fmt.Sprintf("heap grew by more than %d bytes", limit)}
+}
+
 type abortError struct {
 	message string
 }
 
+type memoryLimitError struct {
+	message string
+}
+
 type cancelledError struct {
 	message string
 }
@@ -780,6 +825,8 @@ func callOrCancel(ctx context.Context, vm *VM, name string, args ...int32) (any,
 					err = sdk_errors.New(sdk_errors.InternalErr, e.message)
 				case cancelledError:
 					err = sdk_errors.New(sdk_errors.CancelledErr, e.message)
+				case memoryLimitError:
+					err = sdk_errors.New(sdk_errors.MemoryLimitErr, e.message)
 				case builtinError:
 					err = sdk_errors.New(sdk_errors.InternalErr, e.err.Error())
 				default:
@@ -81,6 +81,19 @@ func (o *OPA) WithMemoryLimits(min, max uint32) *OPA {
 	return o
 }
 
+// WithMemoryMax configures the maximum memory (in bytes) of the instances
+// evaluating the policy. The memory is not grown beyond it, and evaluations
+// that need more memory are aborted with an error with the MemoryLimitErr code.
+func (o *OPA) WithMemoryMax(max uint32) *OPA {
+	if util.Pages(max) < o.memoryMinPages {
+		o.configErr = errors.New(errors.InvalidConfigErr, "too low maximum memory limit")
+		return o
+	}
+
+	o.memoryMaxPages = util.Pages(max)
+	return o
+}
+
 // WithPoolSize configures the maximum number of simultaneous policy
 // evaluations, i.e., the maximum number of underlying WASM instances
 // active at any time. The default is the number of logical CPUs
@@ -27,6 +27,10 @@ const (
 
 	// CancelledErr is the error code returned if the evaluation is cancelled.
 	CancelledErr string = "cancelled"
+
+	// MemoryLimitErr is the error code returned if the evaluation is aborted
+	// because it exceeded its memory limit.
+	MemoryLimitErr string = "memory_limit_exceeded"
 )
 
 // Error is the error code type returned by the SDK functions when an error occurs.
@@ -38,7 +42,7 @@ type Error struct {
 // New returns a new error with the passed code
 func New(code, msg string) error {
 	switch code {
-	case InvalidConfigErr, InvalidPolicyOrDataErr, InvalidBundleErr, NotReadyErr, InternalErr, CancelledErr:
+	case InvalidConfigErr, InvalidPolicyOrDataErr, InvalidBundleErr, NotReadyErr, InternalErr, CancelledErr, MemoryLimitErr:
 		return &Error{Code: code, Message: msg}
 	default:
 		panic("unknown error code: " + code)
@@ -59,6 +63,12 @@ func IsCancel(err error) bool {
 	return errorHasCode(err, CancelledErr)
 }
 
+// IsMemoryLimit returns true if err was caused by the evaluation exceeding its
+// memory limit.
+func IsMemoryLimit(err error) bool {
+	return errorHasCode(err, MemoryLimitErr)
+}
+
 // Is allows matching error types using errors.Is (see IsCancel).
 func (e *Error) Is(target error) bool {
 	var t *Error
@@ -168,6 +168,11 @@ type EvalOpts struct {
 	NDBuiltinCache         builtins.NDBCache
 	PrintHook              print.Hook
 	Capabilities           *ast.Capabilities
+
+	// MemoryLimit is the maximum number of bytes the heap of the policy may
+	// grow by during the evaluation. If it is exceeded, the evaluation is
+	// aborted with an error with the MemoryLimitErr code. 0 means no limit.
+	MemoryLimit uint32
 }
 
 // Eval evaluates the policy with the given input, returning the
@@ -191,7 +196,7 @@ func (o *OPA) Eval(ctx context.Context, opts EvalOpts) (*Result, error) {
 
 	defer o.pool.Release(instance, m)
 
-	result, err := instance.Eval(ctx, opts.Entrypoint, opts.Input, m, opts.Seed, opts.Time, opts.InterQueryBuiltinCache, opts.NDBuiltinCache, opts.PrintHook, opts.Capabilities)
+	result, err := instance.Eval(ctx, opts.Entrypoint, opts.Input, m, opts.Seed, opts.Time, opts.InterQueryBuiltinCache, opts.NDBuiltinCache, opts.PrintHook, opts.Capabilities, opts.MemoryLimit)
 	if err != nil {
 		return nil, err
 	}
@@ -15,10 +15,12 @@ import (
 	"testing"
 
 	"github.com/open-policy-agent/opa/internal/wasm/sdk/opa"
+	"github.com/open-policy-agent/opa/internal/wasm/sdk/opa/errors"
 	wasm_util "github.com/open-policy-agent/opa/internal/wasm/util"
 	"github.com/open-policy-agent/opa/v1/ast"
 	"github.com/open-policy-agent/opa/v1/bundle"
 	"github.com/open-policy-agent/opa/v1/compile"
+	"github.com/open-policy-agent/opa/v1/metrics"
 	"github.com/open-policy-agent/opa/v1/rego"
 	"github.com/open-policy-agent/opa/v1/util"
 )
@@ -250,7 +252,7 @@ a = "c" if { input > 2 }`,
 			Evals: []Eval{
 				{Input: largeInput},
 			},
-			WantErr: "internal_error: opa_malloc: failed",
+			WantErr: "memory_limit_exceeded: opa_malloc: failed",
 		},
 		{
 			Description: "input exceeds available memory, grows successfully",
@@ -402,6 +404,145 @@ func TestNamedEntrypoint(t *testing.T) {
 	}
 }
 
+func TestCommonSubexpressionElimination(t *testing.T) {
+	module := `package test
+
+	p := count([x | some x in input.xs; x > 1])
+	q := sum([y | some y in input.xs; y > 1])
+	w := x if x := [z | some z in input.xs; z > 1] with input.xs as [5, 6]
+	r := {"p": p, "q": q, "w": w, "v": [a | some a in input.xs; a > 1]}
+	`
+
+	ctx := context.Background()
+
+	// Optimization enables common subexpression elimination, which must not
+	// change the result.
+	for _, level := range []int{0, 1} {
+		compiler := compile.New().
+			WithTarget(compile.TargetWasm).
+			WithEntrypoints("test/r").
+			WithOptimizationLevel(level).
+			WithBundle(&bundle.Bundle{
+				Manifest: bundle.Manifest{Roots: &[]string{""}},
+				Modules: []bundle.ModuleFile{
+					{
+						Path:   "policy.rego",
+						URL:    "policy.rego",
+						Raw:    []byte(module),
+						Parsed: ast.MustParseModule(module),
+					},
+				},
+			})
+
+		if err := compiler.Build(ctx); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		instance, err := opa.New().
+			WithPolicyBytes(compiler.Bundle().WasmModules[0].Raw).
+			WithPoolSize(1).
+			Init()
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		var input any = map[string]any{"xs": []any{1, 2, 3}}
+		result, err := instance.Eval(ctx, opa.EvalOpts{Input: &input})
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		exp := ast.MustParseTerm(`{{"result": {"p": 2, "q": 5, "w": [5, 6], "v": [2, 3]}}}`)
+		actual := ast.MustParseTerm(string(result.Result))
+		if !actual.Equal(exp) {
+			t.Fatalf("Expected result at optimization level %d to be %s, got: %s", level, exp, actual)
+		}
+
+		instance.Close()
+	}
+}
+
+func TestMemoryLimit(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		note   string
+		module string
+	}{
+		{
+			note:   "exceeded in policy",
+			module: `x := [y | some y in numbers.range(1, input.n)]`,
+		},
+		{
+			note: "exceeded before built-in function call",
+			module: `x := sprintf("%d", [count(ys)]) if {
+				ys := [y | some y in numbers.range(1, input.n)]
+			}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			policy := compileRegoToWasm(tc.module, "data.p.x", dump)
+			instance, err := opa.New().WithPolicyBytes(policy).WithPoolSize(1).Init()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer instance.Close()
+
+			var small any = map[string]any{"n": 1}
+			var large any = map[string]any{"n": 100000}
+
+			m := metrics.New()
+			if _, err := instance.Eval(ctx, opa.EvalOpts{Input: &small, Metrics: m, MemoryLimit: 64 * 1024}); err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if m.Counter("wasm_vm_eval_heap_bytes").Value().(uint64) == 0 {
+				t.Fatal("Expected heap growth to be recorded")
+			}
+
+			_, err = instance.Eval(ctx, opa.EvalOpts{Input: &large, MemoryLimit: 64 * 1024})
+			if !errors.IsMemoryLimit(err) {
+				t.Fatalf("Expected memory limit error, got: %v", err)
+			}
+
+			// The instance can be reused after an aborted evaluation.
+			if _, err := instance.Eval(ctx, opa.EvalOpts{Input: &large}); err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+		})
+	}
+}
+
+func TestMemoryMax(t *testing.T) {
+	ctx := context.Background()
+
+	policy := compileRegoToWasm(`x := [y | some y in numbers.range(1, input.n)]`, "data.p.x", dump)
+	instance, err := opa.New().WithPolicyBytes(policy).WithPoolSize(1).WithMemoryMax(2 * 1024 * 1024).Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer instance.Close()
+
+	var small any = map[string]any{"n": 1}
+	var large any = map[string]any{"n": 1000000}
+
+	if _, err := instance.Eval(ctx, opa.EvalOpts{Input: &small}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	// The memory cannot grow beyond the maximum, no matter where the heap
+	// grows during the evaluation.
+	_, err = instance.Eval(ctx, opa.EvalOpts{Input: &large})
+	if !errors.IsMemoryLimit(err) {
+		t.Fatalf("Expected memory limit error, got: %v", err)
+	}
+
+	if _, err := instance.Eval(ctx, opa.EvalOpts{Input: &small}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+}
+
 // compileRegoToWasm is shared with the benchmarking functions in opa_bench_test.go;
 // those function use helpers shared with topdown_bench_test.go, and they all use
 // `package test` -- whereas the callers in this file don't provide the package at
@@ -14,3 +14,8 @@ import (
 func Pretty(w io.Writer, x any) error {
 	return v1.Pretty(w, x)
 }
+
+// Parse reads a policy in the text format written by Pretty.
+func Parse(r io.Reader) (*Policy, error) {
+	return v1.Parse(r)
+}
@@ -40,3 +40,9 @@ type Config = v1.Config
 
 // Source is a configured bundle source to download bundles from
 type Source = v1.Source
+
+// CandidateSource is a configured source of candidate revisions of a bundle.
+type CandidateSource = v1.CandidateSource
+
+// SmokeTest is a query evaluated against a candidate bundle.
+type SmokeTest = v1.SmokeTest
@@ -10,3 +10,7 @@ import (
 
 // Status represents the status of processing a bundle.
 type Status = v1.Status
+
+// CandidateStatus represents the status of verifying the candidate revision of
+// a bundle.
+type CandidateStatus = v1.CandidateStatus
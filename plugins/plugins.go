@@ -138,6 +138,14 @@ type StatusListener v1.StatusListener
 // to engine-wide components like storage.
 type Manager = v1.Manager
 
+// StopTimeoutError is returned by Manager.StopWithTimeout if plugins did not
+// stop before the deadline.
+type StopTimeoutError = v1.StopTimeoutError
+
+// PluginMetrics creates the Prometheus metrics of a plugin, which are
+// exported under the opa_plugin_<name>_ prefix. See Manager.MetricsFor.
+type PluginMetrics = v1.PluginMetrics
+
 // SetCompilerOnContext puts the compiler into the storage context. Calling this
 // function before committing updated policies to storage allows the manager to
 // skip parsing and compiling of modules. Instead, the manager will use the
@@ -164,6 +172,13 @@ func ValidateAndInjectDefaultsForTriggerMode(a, b *TriggerMode) (*TriggerMode, e
 	return v1.ValidateAndInjectDefaultsForTriggerMode(a, b)
 }
 
+// DecodeConfig decodes the raw configuration of the plugin named name into
+// the value pointed to by v, applying the defaults and validation rules
+// declared in the default and validate struct tags of its fields.
+func DecodeConfig(name string, raw []byte, v any) error {
+	return v1.DecodeConfig(name, raw, v)
+}
+
 // Info sets the runtime information on the manager. The runtime information is
 // propagated to opa.runtime() built-in function calls.
 func Info(term *ast.Term) func(*Manager) {
@@ -224,6 +239,12 @@ func WithTracerProvider(tracerProvider *trace.TracerProvider) func(*Manager) {
 	return v1.WithTracerProvider(tracerProvider)
 }
 
+// WithPersistenceKeyProvider sets the storage.KeyProvider of the key that
+// encrypts the bundles persisted to disk by plugins.
+func WithPersistenceKeyProvider(kp storage.KeyProvider) func(*Manager) {
+	return v1.WithPersistenceKeyProvider(kp)
+}
+
 // WithDistributedTracingOpts sets the options to be used by distributed tracing.
 func WithDistributedTracingOpts(tr tracing.Options) func(*Manager) {
 	return v1.WithDistributedTracingOpts(tr)
@@ -26,6 +26,13 @@ type Config = v1.Config
 // BundleLoadDurationNanoseconds represents the configuration for the status.prometheus_config.bundle_loading_duration_ns settings
 type BundleLoadDurationNanoseconds = v1.BundleLoadDurationNanoseconds
 
+// BundleStalenessConfig configures the service level objective (SLO) for the
+// staleness of bundles, i.e., the time since their last successful activation.
+type BundleStalenessConfig = v1.BundleStalenessConfig
+
+// BundleStaleness describes the staleness of a bundle against its SLO.
+type BundleStaleness = v1.BundleStaleness
+
 // ParseConfig validates the config and injects default values.
 func ParseConfig(config []byte, services []string, pluginsList []string) (*Config, error) {
 	return v1.ParseConfig(config, services, pluginsList)
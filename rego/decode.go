@@ -0,0 +1,31 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package rego
+
+import (
+	v1 "github.com/open-policy-agent/opa/v1/rego"
+	"github.com/open-policy-agent/opa/v1/types"
+)
+
+// ErrUndefinedResult is returned by DecodeResult if the result set is empty,
+// i.e., if the query is undefined.
+var ErrUndefinedResult = v1.ErrUndefinedResult
+
+// DecodeOption configures DecodeResult.
+type DecodeOption = v1.DecodeOption
+
+// DecodeType makes DecodeResult validate the result against tpe before
+// decoding it.
+func DecodeType(tpe types.Type) DecodeOption {
+	return v1.DecodeType(tpe)
+}
+
+// DecodeResult decodes the value of the single expression of the single
+// result in rs into out, which must be a pointer, like encoding/json decodes
+// the JSON representation of the value. If rs is empty, ErrUndefinedResult is
+// returned.
+func DecodeResult(rs ResultSet, out any, opts ...DecodeOption) error {
+	return v1.DecodeResult(rs, out, opts...)
+}
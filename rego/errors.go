@@ -15,3 +15,10 @@ func NewHaltError(err error) error {
 // ErrorDetails interface is satisfied by an error that provides further
 // details.
 type ErrorDetails = v1.ErrorDetails
+
+// IsWasmMemoryLimit returns true if err was caused by the evaluation of a
+// policy compiled to Wasm exceeding its memory limit, see WasmMemoryLimit and
+// EvalWasmMemoryLimit.
+func IsWasmMemoryLimit(err error) bool {
+	return v1.IsWasmMemoryLimit(err)
+}
@@ -127,6 +127,52 @@ func EvalSeed(r io.Reader) EvalOption {
 	return v1.EvalSeed(r)
 }
 
+// EvalRandSeed makes the randomization required by built-in functions
+// deterministic for the given seed.
+func EvalRandSeed(seed int64) EvalOption {
+	return v1.EvalRandSeed(seed)
+}
+
+// EvalDecisionID sets the ID of the decision made by the evaluation into the
+// DecisionID field of each Result.
+func EvalDecisionID(id string) EvalOption {
+	return v1.EvalDecisionID(id)
+}
+
+// EvalRecordBundleRevisions records the revisions of the bundles activated in
+// the store into the BundleRevisions field of each Result.
+func EvalRecordBundleRevisions(yes bool) EvalOption {
+	return v1.EvalRecordBundleRevisions(yes)
+}
+
+// EvalRequestMetadata sets the metadata of the request the policy is evaluated
+// for, which policies can read with the runtime.request built-in function.
+func EvalRequestMetadata(metadata map[string]string) EvalOption {
+	return v1.EvalRequestMetadata(metadata)
+}
+
+// EvalCheckpoint sets a function to call every interval operations of the
+// evaluation, which can pause evaluation by blocking, and abort it by
+// returning an error.
+func EvalCheckpoint(interval uint64, f topdown.CheckpointFunc) EvalOption {
+	return v1.EvalCheckpoint(interval, f)
+}
+
+// EvalDataSnapshot causes the query to be evaluated as if it was evaluated
+// `with data as <data>`, i.e., with rules disabled and base documents read from
+// data. The data is loaded into a transient in-memory store rather than
+// converted into a term. Partial evaluation does not support this option.
+func EvalDataSnapshot(data map[string]any) EvalOption {
+	return v1.EvalDataSnapshot(data)
+}
+
+// EvalWasmMemoryLimit sets the maximum number of bytes the heap of a policy
+// compiled to Wasm may grow by during the evaluation. It only applies to the
+// Wasm target.
+func EvalWasmMemoryLimit(limit uint32) EvalOption {
+	return v1.EvalWasmMemoryLimit(limit)
+}
+
 // EvalInterQueryBuiltinCache sets the inter-query cache that built-in functions can utilize
 // during evaluation.
 func EvalInterQueryBuiltinCache(c cache.InterQueryCache) EvalOption {
@@ -171,6 +217,12 @@ func EvalVirtualCache(vc topdown.VirtualCache) EvalOption {
 	return v1.EvalVirtualCache(vc)
 }
 
+// EvalSharedVirtualCache sets the topdown.SharedVirtualCache that stores the
+// values of virtual documents that only depend on data across evaluations.
+func EvalSharedVirtualCache(c *topdown.SharedVirtualCache, key string) EvalOption {
+	return v1.EvalSharedVirtualCache(c, key)
+}
+
 // PreparedEvalQuery holds the prepared Rego state that has been pre-processed
 // for subsequent evaluations.
 type PreparedEvalQuery = v1.PreparedEvalQuery
@@ -470,6 +522,31 @@ func Seed(r io.Reader) func(*Rego) {
 	return v1.Seed(r)
 }
 
+// RandSeed makes the randomization required by built-in functions
+// deterministic for the given seed. See EvalRandSeed.
+func RandSeed(seed int64) func(*Rego) {
+	return v1.RandSeed(seed)
+}
+
+// RecordBundleRevisions records the revisions of the bundles activated in the
+// store into the BundleRevisions field of each Result. See
+// EvalRecordBundleRevisions.
+func RecordBundleRevisions(yes bool) func(r *Rego) {
+	return v1.RecordBundleRevisions(yes)
+}
+
+// RequestMetadata sets the metadata of the request the policy is evaluated
+// for. See EvalRequestMetadata.
+func RequestMetadata(metadata map[string]string) func(*Rego) {
+	return v1.RequestMetadata(metadata)
+}
+
+// Checkpoint sets a function to call every interval operations of the
+// evaluation. See EvalCheckpoint.
+func Checkpoint(interval uint64, f topdown.CheckpointFunc) func(*Rego) {
+	return v1.Checkpoint(interval, f)
+}
+
 // PrintTrace is a helper function to write a human-readable version of the
 // trace to the writer w.
 func PrintTrace(w io.Writer, r *Rego) {
@@ -517,6 +594,14 @@ func StrictBuiltinErrors(yes bool) func(r *Rego) {
 	return v1.StrictBuiltinErrors(yes)
 }
 
+// SortedIteration tells the evaluator to iterate over documents defined by both
+// base documents and rules in sorted key order, so that results and traces do
+// not depend on where documents are defined. This has a performance cost, and
+// is not supported by the Wasm target.
+func SortedIteration(yes bool) func(r *Rego) {
+	return v1.SortedIteration(yes)
+}
+
 // BuiltinErrorList supplies an error slice to store built-in function errors.
 func BuiltinErrorList(list *[]topdown.Error) func(r *Rego) {
 	return v1.BuiltinErrorList(list)
@@ -539,6 +624,13 @@ func Capabilities(c *ast.Capabilities) func(r *Rego) {
 	return v1.Capabilities(c)
 }
 
+// WasmMemoryLimit sets the maximum number of bytes of memory of the instances
+// evaluating a policy compiled to Wasm. The memory is not grown beyond the
+// limit. It only applies to the Wasm target.
+func WasmMemoryLimit(limit uint32) func(r *Rego) {
+	return v1.WasmMemoryLimit(limit)
+}
+
 // Target sets the runtime to exercise.
 func Target(t string) func(r *Rego) {
 	return v1.Target(t)
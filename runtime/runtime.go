@@ -8,6 +8,7 @@ import (
 	"context"
 
 	"github.com/open-policy-agent/opa/plugins"
+	"github.com/open-policy-agent/opa/server/identifier"
 	v1 "github.com/open-policy-agent/opa/v1/runtime"
 )
 
@@ -19,6 +20,14 @@ func RegisterPlugin(name string, factory plugins.Factory) {
 	v1.RegisterPlugin(name, factory)
 }
 
+// RegisterAuthenticator registers an authenticator with the runtime package.
+// The authenticator is used by the server if the authentication scheme is
+// server.AuthenticationPlugin and it is selected by name, see
+// Params.Authenticator. This function is idempotent.
+func RegisterAuthenticator(name string, authenticator identifier.Authenticator) {
+	v1.RegisterAuthenticator(name, authenticator)
+}
+
 // Params stores the configuration for an OPA instance.
 type Params = v1.Params
 
@@ -36,6 +36,17 @@ func New(ctx context.Context, opts Options) (*OPA, error) {
 	return v1.New(ctx, opts)
 }
 
+type Pool = v1.Pool
+
+type PoolOptions = v1.PoolOptions
+
+func NewPool(opts PoolOptions) *Pool {
+	if opts.RegoVersion == ast.RegoUndefined {
+		opts.RegoVersion = ast.DefaultRegoVersion
+	}
+	return v1.NewPool(opts)
+}
+
 func IsUndefinedErr(err error) bool {
 	return v1.IsUndefinedErr(err)
 }
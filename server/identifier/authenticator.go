@@ -0,0 +1,37 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package identifier
+
+import (
+	"net/http"
+
+	v1 "github.com/open-policy-agent/opa/v1/server/identifier"
+)
+
+// Authenticator establishes the identity of the caller of a request, e.g., by
+// verifying an OIDC token or the SPIFFE ID of a client certificate.
+type Authenticator = v1.Authenticator
+
+// AuthenticatorFunc is an adapter to allow the use of ordinary functions as
+// Authenticators.
+type AuthenticatorFunc = v1.AuthenticatorFunc
+
+// AuthenticatorBased delegates authentication of requests to an Authenticator.
+type AuthenticatorBased = v1.AuthenticatorBased
+
+// NewAuthenticatorBased returns a new AuthenticatorBased object.
+func NewAuthenticatorBased(inner http.Handler, authenticator Authenticator) *AuthenticatorBased {
+	return v1.NewAuthenticatorBased(inner, authenticator)
+}
+
+// IdentityClaims returns the claims made about the caller associated with ctx.
+func IdentityClaims(r *http.Request) (map[string]any, bool) {
+	return v1.IdentityClaims(r)
+}
+
+// SetIdentityClaims returns a new http.Request with the identity claims set to v.
+func SetIdentityClaims(r *http.Request, v map[string]any) *http.Request {
+	return v1.SetIdentityClaims(r, v)
+}
@@ -14,9 +14,10 @@ type AuthenticationScheme = v1.AuthenticationScheme
 
 // Set of supported authentication schemes.
 const (
-	AuthenticationOff   = v1.AuthenticationOff
-	AuthenticationToken = v1.AuthenticationToken
-	AuthenticationTLS   = v1.AuthenticationTLS
+	AuthenticationOff    = v1.AuthenticationOff
+	AuthenticationToken  = v1.AuthenticationToken
+	AuthenticationTLS    = v1.AuthenticationTLS
+	AuthenticationPlugin = v1.AuthenticationPlugin
 )
 
 // AuthorizationScheme enumerates the supported authorization schemes. The authorization
@@ -44,6 +45,14 @@ const (
 	PromHandlerAPIAuthz   = v1.PromHandlerAPIAuthz
 )
 
+// Set of response headers that correlate Data API responses with decision logs
+// and bundle revisions.
+const (
+	DecisionIDHeader      = v1.DecisionIDHeader
+	BundleRevisionsHeader = v1.BundleRevisionsHeader
+	BundleRevisionHeader  = v1.BundleRevisionHeader
+)
+
 // Server represents an instance of OPA running in server mode.
 type Server = v1.Server
 
@@ -21,6 +21,7 @@ const (
 	CodeResourceNotFound  = v1.CodeResourceNotFound
 	CodeResourceConflict  = v1.CodeResourceConflict
 	CodeUndefinedDocument = v1.CodeUndefinedDocument
+	CodeTooManyRequests   = v1.CodeTooManyRequests
 )
 
 // ErrorV1 models an error response sent to the client.
@@ -45,6 +46,7 @@ const (
 	MsgPluginConfigError          = v1.MsgPluginConfigError
 	MsgDecodingLimitError         = v1.MsgDecodingLimitError
 	MsgDecodingGzipLimitError     = v1.MsgDecodingGzipLimitError
+	MsgTooManyRequestsError       = v1.MsgTooManyRequestsError
 )
 
 // PatchV1 models a single patch operation against a document.
@@ -0,0 +1,36 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"context"
+
+	v1 "github.com/open-policy-agent/opa/v1/storage"
+)
+
+// KeyProvider provides the key that encrypts data at rest, e.g., the data of
+// the disk store and the bundles persisted to disk. Keys are AES keys, i.e.,
+// they are 16, 24, or 32 bytes long.
+type KeyProvider = v1.KeyProvider
+
+// KeyProviderFunc adapts a function, e.g., one that fetches the key from a key
+// management service, to a KeyProvider.
+type KeyProviderFunc = v1.KeyProviderFunc
+
+// EncryptionConfig represents the configuration of an encryption key, either
+// inline and base64-encoded, e.g., from an environment variable, or in a file
+// containing the raw key.
+type EncryptionConfig = v1.EncryptionConfig
+
+// StaticKey returns a KeyProvider that always provides key.
+func StaticKey(key []byte) KeyProvider {
+	return v1.StaticKey(key)
+}
+
+// EncryptionKey returns the key of the provider, or an error if the provider
+// fails or the key is not a valid AES key.
+func EncryptionKey(ctx context.Context, kp KeyProvider) ([]byte, error) {
+	return v1.EncryptionKey(ctx, kp)
+}
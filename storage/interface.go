@@ -66,6 +66,17 @@ type DataEvent = v1.DataEvent
 // TriggerEvent describes the changes that caused the trigger to be invoked.
 type TriggerEvent = v1.TriggerEvent
 
+// TriggerKind identifies the kinds of changes a trigger is invoked for.
+type TriggerKind = v1.TriggerKind
+
+const (
+	// TriggerPolicy selects policy changes.
+	TriggerPolicy = v1.TriggerPolicy
+
+	// TriggerData selects base data document changes.
+	TriggerData = v1.TriggerData
+)
+
 // TriggerConfig contains the trigger registration configuration.
 type TriggerConfig = v1.TriggerConfig
 
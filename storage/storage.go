@@ -7,6 +7,7 @@ package storage
 import (
 	"context"
 
+	"github.com/open-policy-agent/opa/ast"
 	v1 "github.com/open-policy-agent/opa/v1/storage"
 )
 
@@ -24,6 +25,18 @@ func ReadOne(ctx context.Context, store Store, path Path) (any, error) {
 	return v1.ReadOne(ctx, store, path)
 }
 
+// ReadValue reads the document at path as an AST value. Values of stores that
+// return AST values on read are returned without conversion.
+func ReadValue(ctx context.Context, store Store, txn Transaction, path Path) (ast.Value, error) {
+	return v1.ReadValue(ctx, store, txn, path)
+}
+
+// ReadJSON reads the document at path as a JSON-compatible Go value,
+// converting AST values returned by the store.
+func ReadJSON(ctx context.Context, store Store, txn Transaction, path Path) (any, error) {
+	return v1.ReadJSON(ctx, store, txn, path)
+}
+
 // WriteOne is a convenience function to write a single value to the provided Store. It
 // will create a new Transaction to perform the write with, and clean up after itself
 // should an error occur.
@@ -17,3 +17,14 @@ type VirtualCache = v1.VirtualCache
 func NewVirtualCache() VirtualCache {
 	return v1.NewVirtualCache()
 }
+
+// SharedVirtualCache stores the values of virtual documents that only depend
+// on data, so that they are computed once and shared by subsequent
+// evaluations.
+type SharedVirtualCache = v1.SharedVirtualCache
+
+// NewSharedVirtualCache returns a new SharedVirtualCache that holds at most
+// maxEntries values.
+func NewSharedVirtualCache(maxEntries int) *SharedVirtualCache {
+	return v1.NewSharedVirtualCache(maxEntries)
+}
@@ -0,0 +1,17 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package topdown
+
+import (
+	v1 "github.com/open-policy-agent/opa/v1/topdown"
+)
+
+// CheckpointStats describes the evaluation of a query at a checkpoint.
+type CheckpointStats = v1.CheckpointStats
+
+// CheckpointFunc is called at evaluation checkpoints. Evaluation is paused
+// until the function returns, and resumes if it returns nil. If it returns an
+// error, evaluation is aborted with a cancellation error wrapping it.
+type CheckpointFunc = v1.CheckpointFunc
@@ -1,6 +1,7 @@
 package print
 
 import (
+	"github.com/open-policy-agent/opa/v1/logging"
 	v1 "github.com/open-policy-agent/opa/v1/topdown/print"
 )
 
@@ -12,3 +13,47 @@ type Context = v1.Context
 // strict builtin error checking is enabled (otherwise, it will not halt
 // execution.)
 type Hook = v1.Hook
+
+// Level is the level of print statement outputs.
+type Level = v1.Level
+
+const (
+	LevelInfo  = v1.LevelInfo
+	LevelDebug = v1.LevelDebug
+	LevelWarn  = v1.LevelWarn
+	LevelError = v1.LevelError
+)
+
+// ParseLevel returns the level set by the first argument of a print() call.
+func ParseLevel(arg string) (Level, bool) {
+	return v1.ParseLevel(arg)
+}
+
+// Multi returns a hook that passes print statement outputs to each of hooks.
+func Multi(hooks ...Hook) Hook {
+	return v1.Multi(hooks...)
+}
+
+// TrimLevelPrefix returns msg without the prefix that set its level.
+func TrimLevelPrefix(pctx Context, msg string) string {
+	return v1.TrimLevelPrefix(pctx, msg)
+}
+
+// NewLoggingHook returns a hook that logs print statement outputs with logger.
+func NewLoggingHook(logger logging.Logger, opts ...LoggingHookOption) Hook {
+	return v1.NewLoggingHook(logger, opts...)
+}
+
+// LoggingHookOption configures the hook returned by NewLoggingHook.
+type LoggingHookOption = v1.LoggingHookOption
+
+// WithLevels makes the hook log print statement outputs at their level.
+func WithLevels(yes bool) LoggingHookOption {
+	return v1.WithLevels(yes)
+}
+
+// NewSpanEventHook returns a hook that records print statement outputs as
+// events of the span of the request context.
+func NewSpanEventHook() Hook {
+	return v1.NewSpanEventHook()
+}
@@ -10,8 +10,10 @@ import (
 	"net/url"
 	"slices"
 	"strings"
+	"sync"
 
 	"github.com/open-policy-agent/opa/internal/deepcopy"
+	"github.com/open-policy-agent/opa/internal/gojsonschema"
 	astJSON "github.com/open-policy-agent/opa/v1/ast/json"
 	"github.com/open-policy-agent/opa/v1/util"
 )
@@ -617,6 +619,71 @@ func validateAnnotationEntrypointAttachment(a *Annotations) *Error {
 	return nil
 }
 
+var (
+	annotationSchemasMtx sync.RWMutex
+	annotationSchemas    = map[string]*gojsonschema.Schema{}
+)
+
+// RegisterAnnotationSchema registers the JSON schema of the custom annotation
+// with the given name, i.e. of the value of the name key in the custom
+// annotations. The compiler validates the custom annotations of the compiled
+// modules against the registered schemas. Registering a nil schema removes the
+// schema of the custom annotation.
+func RegisterAnnotationSchema(name string, schema any) error {
+	annotationSchemasMtx.Lock()
+	defer annotationSchemasMtx.Unlock()
+
+	if schema == nil {
+		delete(annotationSchemas, name)
+		return nil
+	}
+
+	compiled, err := compileSchema(schema, nil)
+	if err != nil {
+		return fmt.Errorf("custom annotation %q: %w", name, err)
+	}
+	annotationSchemas[name] = compiled
+	return nil
+}
+
+func hasAnnotationSchemas() bool {
+	annotationSchemasMtx.RLock()
+	defer annotationSchemasMtx.RUnlock()
+	return len(annotationSchemas) > 0
+}
+
+// validateCustomAnnotations validates the custom annotations of a against the
+// registered schemas.
+func validateCustomAnnotations(a *Annotations) Errors {
+	annotationSchemasMtx.RLock()
+	defer annotationSchemasMtx.RUnlock()
+
+	var errs Errors
+
+	for _, name := range util.KeysSorted(a.Custom) {
+		schema, ok := annotationSchemas[name]
+		if !ok {
+			continue
+		}
+
+		result, err := schema.Validate(gojsonschema.NewGoLoader(a.Custom[name]))
+		if err != nil {
+			errs = append(errs, NewError(CompileErr, a.Location, "invalid custom annotation %q: %v", name, err))
+			continue
+		}
+
+		for _, desc := range result.Errors() {
+			if field := desc.Field(); field != gojsonschema.StringRootSchemaProperty {
+				errs = append(errs, NewError(CompileErr, a.Location, "invalid custom annotation %q: %v: %v", name, field, desc.Description()))
+			} else {
+				errs = append(errs, NewError(CompileErr, a.Location, "invalid custom annotation %q: %v", name, desc.Description()))
+			}
+		}
+	}
+
+	return errs
+}
+
 // Copy returns a deep copy of a.
 func (a *AuthorAnnotation) Copy() *AuthorAnnotation {
 	cpy := *a
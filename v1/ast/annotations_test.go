@@ -1195,3 +1195,86 @@ func schemaAnnotationFromMap(path string, def map[string]any) *SchemaAnnotation
 	var p any = def
 	return &SchemaAnnotation{Path: MustParseRef(path), Definition: &p}
 }
+
+func TestRegisterAnnotationSchema(t *testing.T) {
+	schema := map[string]any{
+		"type":                 "object",
+		"required":             []any{"team"},
+		"properties":           map[string]any{"team": map[string]any{"type": "string"}},
+		"additionalProperties": false,
+	}
+	if err := RegisterAnnotationSchema("test_owner", schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = RegisterAnnotationSchema("test_owner", nil)
+	})
+
+	if err := RegisterAnnotationSchema("test_invalid", map[string]any{"type": 42}); err == nil {
+		t.Fatal("expected error for invalid schema")
+	}
+
+	tests := []struct {
+		note      string
+		module    string
+		processed bool
+		expErrs   []string
+	}{
+		{
+			note: "valid",
+			module: `# METADATA
+# custom:
+#   test_owner:
+#     team: payments
+package test`,
+			processed: true,
+		},
+		{
+			note: "invalid",
+			module: `package test
+
+# METADATA
+# custom:
+#   test_owner:
+#     name: payments
+#   other: anything
+p := 1`,
+			processed: true,
+			expErrs: []string{
+				`3:1: rego_compile_error: invalid custom annotation "test_owner": team is required`,
+				`3:1: rego_compile_error: invalid custom annotation "test_owner": Additional property name is not allowed`,
+			},
+		},
+		{
+			note: "unprocessed annotations",
+			module: `package test
+
+# METADATA
+# custom:
+#   test_owner:
+#     team: 42
+p := 1`,
+			expErrs: []string{
+				`3:1: rego_compile_error: invalid custom annotation "test_owner": team: Invalid type. Expected: string, given: integer`,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			mod := MustParseModuleWithOpts(tc.module, ParserOptions{ProcessAnnotation: tc.processed})
+
+			c := NewCompiler()
+			c.Compile(map[string]*Module{"test.rego": mod})
+
+			if len(c.Errors) != len(tc.expErrs) {
+				t.Fatalf("expected errors %v, got %v", tc.expErrs, c.Errors)
+			}
+			for i := range tc.expErrs {
+				if act := c.Errors[i].Error(); act != tc.expErrs[i] {
+					t.Errorf("expected error %q, got %q", tc.expErrs[i], act)
+				}
+			}
+		})
+	}
+}
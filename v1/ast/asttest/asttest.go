@@ -0,0 +1,570 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+// Package asttest generates random but valid Rego modules and query bodies
+// for property-based testing of the compiler, the stores and other consumers
+// of the AST.
+//
+// The generated modules parse, compile and evaluate without errors. A
+// Generator is either seeded, or driven by the bytes of a fuzz input, so that
+// the Go fuzzing engine can mutate the generated modules structurally:
+//
+//	func FuzzCompile(f *testing.F) {
+//		f.Fuzz(func(t *testing.T, data []byte) {
+//			g := asttest.NewGeneratorFromBytes(data, asttest.Options{Every: true})
+//			compiler := ast.NewCompiler()
+//			compiler.Compile(map[string]*ast.Module{"m.rego": g.Module()})
+//			...
+//		})
+//	}
+package asttest
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+)
+
+// Options configures the features used in generated modules and bodies.
+type Options struct {
+	RefHeads       bool   // generate rules with ref heads, e.g. p.q.r := 1
+	Every          bool   // generate every expressions
+	Comprehensions bool   // generate array, set and object comprehensions
+	Functions      bool   // generate functions and calls to them
+	MaxRules       int    // maximum number of rules per module (default: 5)
+	MaxBodyLen     int    // maximum number of expressions per body (default: 4)
+	Package        string // root package of the generated modules (default: gen)
+}
+
+// AllFeatures returns options that enable all features.
+func AllFeatures() Options {
+	return Options{RefHeads: true, Every: true, Comprehensions: true, Functions: true}
+}
+
+type intner interface {
+	Intn(int) int
+}
+
+// byteSource draws choices from the bytes of a fuzz input. Once the bytes are
+// exhausted, the first option of every choice is taken, which keeps the
+// generated modules small.
+type byteSource struct {
+	data []byte
+}
+
+func (s *byteSource) Intn(n int) int {
+	if n <= 1 || len(s.data) == 0 {
+		return 0
+	}
+	b := s.data[0]
+	s.data = s.data[1:]
+	return int(b) % n
+}
+
+// Generator generates random but valid modules and bodies. Modules generated
+// by the same Generator are placed in distinct packages, and may refer to
+// the rules of the modules generated before them. A Generator is not safe for
+// concurrent use.
+type Generator struct {
+	src     intner
+	opts    Options
+	modules int
+	rules   []rule // rules of the generated modules that can be referred to
+}
+
+// NewGenerator returns a Generator that draws its choices from a random
+// source seeded with seed.
+func NewGenerator(seed int64, opts Options) *Generator {
+	return newGenerator(rand.New(rand.NewSource(seed)), opts)
+}
+
+// NewGeneratorFromBytes returns a Generator that draws its choices from data,
+// e.g. the input of a fuzz test.
+func NewGeneratorFromBytes(data []byte, opts Options) *Generator {
+	return newGenerator(&byteSource{data: data}, opts)
+}
+
+func newGenerator(src intner, opts Options) *Generator {
+	if opts.MaxRules <= 0 {
+		opts.MaxRules = 5
+	}
+	if opts.MaxBodyLen <= 0 {
+		opts.MaxBodyLen = 4
+	}
+	if opts.Package == "" {
+		opts.Package = "gen"
+	}
+	return &Generator{src: src, opts: opts}
+}
+
+// Module returns a new module.
+func (g *Generator) Module() *ast.Module {
+	return ast.MustParseModuleWithOpts(g.ModuleSource(), ast.ParserOptions{RegoVersion: ast.RegoV1})
+}
+
+// ModuleSource returns the source of a new module.
+func (g *Generator) ModuleSource() string {
+	pkg := g.opts.Package + ".m" + strconv.Itoa(g.modules)
+	g.modules++
+
+	var buf strings.Builder
+	buf.WriteString("package " + pkg + "\n")
+
+	n := 1 + g.intn(g.opts.MaxRules)
+	for i := range n {
+		buf.WriteString("\n")
+		buf.WriteString(g.rule("data."+pkg, "r"+strconv.Itoa(i)))
+	}
+
+	return buf.String()
+}
+
+// Body returns a new query body. Bodies only refer to the input document and
+// the rules of the modules generated before.
+func (g *Generator) Body() ast.Body {
+	return ast.MustParseBodyWithOpts(g.BodySource(), ast.ParserOptions{RegoVersion: ast.RegoV1})
+}
+
+// BodySource returns the source of a new query body.
+func (g *Generator) BodySource() string {
+	b := &body{g: g}
+	b.literals(1 + g.intn(g.opts.MaxBodyLen))
+	return strings.Join(b.exprs, "; ")
+}
+
+func (g *Generator) intn(n int) int {
+	return g.src.Intn(n)
+}
+
+func (g *Generator) chance(n int) bool {
+	return g.intn(n) == 0
+}
+
+// typ is the type of a generated value.
+type typ struct {
+	coll byte   // 0 for scalars, 'a' for arrays, 's' for sets, 'o' for objects
+	elem scalar // type of the scalar or of the elements of the collection
+}
+
+type scalar int
+
+const (
+	number scalar = iota
+	str
+	boolean
+	anyScalar
+)
+
+var (
+	numberType  = typ{elem: number}
+	stringType  = typ{elem: str}
+	booleanType = typ{elem: boolean}
+	anyType     = typ{elem: anyScalar}
+)
+
+func (t typ) isCollection() bool {
+	return t.coll != 0
+}
+
+// rule is a rule that can be referred to, or a function that can be called.
+type rule struct {
+	ref string
+	typ typ
+	arg bool // the rule is a function of a number
+}
+
+// randomScalar returns the type of a scalar literal. Values of anyScalar type
+// are only obtained from the input document and from rules with ref heads.
+func (g *Generator) randomScalar() scalar {
+	return scalar(g.intn(int(anyScalar)))
+}
+
+func (g *Generator) randomType() typ {
+	t := typ{elem: g.randomScalar()}
+	switch g.intn(5) {
+	case 1:
+		t.coll = 'a'
+	case 2:
+		t.coll = 's'
+	case 3:
+		t.coll = 'o'
+	}
+	return t
+}
+
+// literal returns a literal value of type t.
+func (g *Generator) literal(t typ) string {
+	switch t.coll {
+	case 'a', 's':
+		n := 1 + g.intn(3)
+		elems := make([]string, n)
+		for i := range elems {
+			elems[i] = g.literal(typ{elem: t.elem})
+		}
+		if t.coll == 'a' {
+			return "[" + strings.Join(elems, ", ") + "]"
+		}
+		return "{" + strings.Join(elems, ", ") + "}"
+	case 'o':
+		n := 1 + g.intn(3)
+		items := make([]string, n)
+		for i := range items {
+			items[i] = fmt.Sprintf("%q: %s", string(rune('a'+i)), g.literal(typ{elem: t.elem}))
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	}
+
+	switch t.elem {
+	case number:
+		return strconv.Itoa(g.intn(10))
+	case str:
+		return strconv.Quote(string(rune('a' + g.intn(5))))
+	case boolean:
+		if g.chance(2) {
+			return "true"
+		}
+		return "false"
+	default:
+		return g.literal(typ{elem: g.randomScalar()})
+	}
+}
+
+func (g *Generator) rule(pkg, name string) string {
+	b := &body{g: g}
+
+	// Each kind of rule returns its source and the type of its value. The
+	// rule is only added to the rules that can be referred to afterwards, so
+	// that rules never refer to themselves.
+	kinds := []func() (string, typ, bool){
+		// Constant.
+		func() (string, typ, bool) {
+			t := g.randomType()
+			return name + " := " + g.literal(t) + "\n", t, false
+		},
+		// Boolean rule with default.
+		func() (string, typ, bool) {
+			return "default " + name + " := false\n\n" + name + " if " + b.block() + "\n", booleanType, false
+		},
+		// Complete rule with a body.
+		func() (string, typ, bool) {
+			t := typ{elem: g.randomScalar()}
+			value := g.literal(t)
+			return name + " := " + value + " if " + b.block() + "\n", t, false
+		},
+		// Partial set.
+		func() (string, typ, bool) {
+			b.literals(g.intn(g.opts.MaxBodyLen))
+			t := typ{elem: g.randomScalar()}
+			v := b.fresh()
+			b.exprs = append(b.exprs, v+" := "+b.term(t))
+			return name + " contains " + v + " if " + b.String() + "\n", typ{coll: 's', elem: t.elem}, false
+		},
+		// Partial object.
+		func() (string, typ, bool) {
+			b.literals(g.intn(g.opts.MaxBodyLen))
+			k := b.fresh()
+			b.exprs = append(b.exprs, k+" := "+b.term(stringType))
+			return name + "[" + k + "] := true if " + b.String() + "\n", typ{coll: 'o', elem: boolean}, false
+		},
+	}
+
+	if g.opts.Functions {
+		kinds = append(kinds, func() (string, typ, bool) {
+			value := "x + " + g.literal(numberType)
+			if g.chance(2) {
+				value = "abs(x)"
+			}
+			return name + "(x) := y if {\n\ty := " + value + "\n}\n", numberType, true
+		})
+	}
+
+	if g.opts.RefHeads {
+		kinds = append(kinds, func() (string, typ, bool) {
+			var buf strings.Builder
+			for i := range 1 + g.intn(3) {
+				key := string(rune('a' + i))
+				if g.chance(2) {
+					fmt.Fprintf(&buf, "%s.%s.v := %s\n", name, key, g.literal(g.randomType()))
+					continue
+				}
+				b := &body{g: g}
+				b.literals(g.intn(g.opts.MaxBodyLen))
+				k := b.fresh()
+				b.exprs = append(b.exprs, k+" := "+b.term(stringType))
+				fmt.Fprintf(&buf, "%s.%s[%s] := true if %s\n", name, key, k, b.String())
+			}
+			return buf.String(), typ{coll: 'o', elem: anyScalar}, false
+		})
+	}
+
+	src, t, arg := kinds[g.intn(len(kinds))]()
+	g.rules = append(g.rules, rule{ref: pkg + "." + name, typ: t, arg: arg})
+
+	return src
+}
+
+// binding is a variable bound in a body.
+type binding struct {
+	name string
+	typ  typ
+}
+
+// body generates the expressions of a body, keeping track of the bound
+// variables so that every generated body is safe.
+type body struct {
+	g      *Generator
+	scope  []binding
+	exprs  []string
+	parent *body // enclosing body of comprehensions and every expressions
+	vars   *int
+}
+
+func (b *body) String() string {
+	if len(b.exprs) == 0 {
+		return "{\n\ttrue\n}"
+	}
+	return "{\n\t" + strings.Join(b.exprs, "\n\t") + "\n}"
+}
+
+func (b *body) block() string {
+	b.literals(1 + b.g.intn(b.g.opts.MaxBodyLen))
+	return b.String()
+}
+
+func (b *body) fresh() string {
+	if b.vars == nil {
+		b.vars = new(int)
+	}
+	name := "x" + strconv.Itoa(*b.vars)
+	*b.vars++
+	return name
+}
+
+// nested returns a body for a comprehension or every expression, which can
+// refer to the variables bound in b.
+func (b *body) nested() *body {
+	if b.vars == nil {
+		b.vars = new(int)
+	}
+	return &body{g: b.g, parent: b, vars: b.vars}
+}
+
+func (b *body) bindings(f func(t typ) bool) []binding {
+	var result []binding
+	for x := b; x != nil; x = x.parent {
+		for _, v := range x.scope {
+			if f(v.typ) {
+				result = append(result, v)
+			}
+		}
+	}
+	return result
+}
+
+// term returns a term of type t, which is either a bound variable or a
+// literal.
+func (b *body) term(t typ) string {
+	if vars := b.bindings(func(u typ) bool { return u == t }); len(vars) > 0 && !b.g.chance(3) {
+		return vars[b.g.intn(len(vars))].name
+	}
+	return b.g.literal(t)
+}
+
+// anyTerm returns a term of any type.
+func (b *body) anyTerm() (string, typ) {
+	if vars := b.bindings(func(typ) bool { return true }); len(vars) > 0 && !b.g.chance(3) {
+		v := vars[b.g.intn(len(vars))]
+		return v.name, v.typ
+	}
+	if b.g.chance(3) {
+		return "input." + string(rune('a'+b.g.intn(5))), anyType
+	}
+	t := b.g.randomType()
+	return b.g.literal(t), t
+}
+
+// collection returns a term of a collection type.
+func (b *body) collection() (string, typ) {
+	if vars := b.bindings(typ.isCollection); len(vars) > 0 && !b.g.chance(3) {
+		v := vars[b.g.intn(len(vars))]
+		return v.name, v.typ
+	}
+	t := b.g.randomType()
+	if !t.isCollection() {
+		t.coll = 'a'
+	}
+	return b.g.literal(t), t
+}
+
+func (b *body) literals(n int) {
+	for range n {
+		b.literal()
+	}
+}
+
+func (b *body) literal() {
+	g := b.g
+
+	kinds := []func(){
+		b.assignment,
+		b.assignment,
+		b.condition,
+		b.condition,
+		func() {
+			c, t := b.collection()
+			v := b.fresh()
+			if t.coll == 'o' {
+				k := b.fresh()
+				b.exprs = append(b.exprs, "some "+k+", "+v+" in "+c)
+				b.scope = append(b.scope, binding{k, stringType}, binding{v, typ{elem: t.elem}})
+				b.use(b.scope[len(b.scope)-1])
+				return
+			}
+			b.exprs = append(b.exprs, "some "+v+" in "+c)
+			b.scope = append(b.scope, binding{v, typ{elem: t.elem}})
+		},
+	}
+
+	if g.opts.Every {
+		kinds = append(kinds, func() {
+			c, t := b.collection()
+			inner := b.nested()
+			v := inner.fresh()
+			inner.scope = append(inner.scope, binding{v, typ{elem: t.elem}})
+			inner.use(inner.scope[0])
+			for range g.intn(2) {
+				inner.condition()
+			}
+			b.exprs = append(b.exprs, "every "+v+" in "+c+" { "+strings.Join(inner.exprs, "; ")+" }")
+		})
+	}
+
+	kinds[g.intn(len(kinds))]()
+}
+
+// assignment binds a new variable to a value.
+func (b *body) assignment() {
+	g := b.g
+	x := b.fresh()
+
+	values := []func() (string, typ){
+		func() (string, typ) {
+			t := g.randomType()
+			return b.term(t), t
+		},
+		func() (string, typ) {
+			return b.term(numberType) + " + " + b.term(numberType), numberType
+		},
+		func() (string, typ) {
+			c, _ := b.collection()
+			return "count(" + c + ")", numberType
+		},
+		func() (string, typ) {
+			return "upper(" + b.term(stringType) + ")", stringType
+		},
+		func() (string, typ) {
+			return `concat("-", ` + b.term(typ{coll: 'a', elem: str}) + ")", stringType
+		},
+	}
+
+	if rules := g.rules; len(rules) > 0 {
+		values = append(values, func() (string, typ) {
+			r := rules[g.intn(len(rules))]
+			if r.arg {
+				return r.ref + "(" + b.term(numberType) + ")", r.typ
+			}
+			return r.ref, r.typ
+		})
+	}
+
+	if g.opts.Comprehensions {
+		values = append(values, func() (string, typ) {
+			c, t := b.collection()
+			inner := b.nested()
+			v := inner.fresh()
+			elem := typ{elem: t.elem}
+
+			var head, open, closing string
+			var result typ
+			if t.coll == 'o' {
+				k := inner.fresh()
+				inner.exprs = append(inner.exprs, "some "+k+", "+v+" in "+c)
+				inner.scope = append(inner.scope, binding{k, stringType})
+				head, open, closing, result = k+": "+v, "{", "}", typ{coll: 'o', elem: t.elem}
+			} else {
+				inner.exprs = append(inner.exprs, "some "+v+" in "+c)
+				if g.chance(2) {
+					head, open, closing, result = v, "[", "]", typ{coll: 'a', elem: t.elem}
+				} else {
+					head, open, closing, result = v, "{", "}", typ{coll: 's', elem: t.elem}
+				}
+			}
+			inner.scope = append(inner.scope, binding{v, elem})
+			inner.condition()
+
+			return open + head + " | " + strings.Join(inner.exprs, "; ") + closing, result
+		})
+	}
+
+	value, t := values[g.intn(len(values))]()
+	b.exprs = append(b.exprs, x+" := "+value)
+	b.scope = append(b.scope, binding{x, t})
+}
+
+// comparable returns two terms that the type checker accepts in a
+// comparison. Collections and terms of unknown type are only compared with
+// themselves or with the input document, as the types of collections include
+// their size.
+func (b *body) comparable() (string, string) {
+	l, t := b.anyTerm()
+	return l, b.comparableWith(l, t)
+}
+
+// comparableWith returns a term that can be compared with l of type t.
+func (b *body) comparableWith(l string, t typ) string {
+	if !t.isCollection() && t.elem != anyScalar {
+		return b.term(t)
+	}
+	if b.g.chance(2) {
+		return l
+	}
+	return "input." + string(rune('a'+b.g.intn(5)))
+}
+
+// use adds a condition on v, as the compiler rejects variables declared by
+// some and every that are never used.
+func (b *body) use(v binding) {
+	b.exprs = append(b.exprs, v.name+" != "+b.comparableWith(v.name, v.typ))
+}
+
+// condition adds an expression that does not bind variables.
+func (b *body) condition() {
+	g := b.g
+
+	conds := []func() string{
+		func() string {
+			l, r := b.comparable()
+			return l + " == " + r
+		},
+		func() string {
+			l, r := b.comparable()
+			return l + " != " + r
+		},
+		func() string {
+			return b.term(numberType) + " < " + b.term(numberType)
+		},
+		func() string {
+			return "startswith(" + b.term(stringType) + ", " + g.literal(stringType) + ")"
+		},
+		func() string {
+			t, _ := b.anyTerm()
+			return "not " + t
+		},
+	}
+
+	b.exprs = append(b.exprs, conds[g.intn(len(conds))]())
+}
@@ -0,0 +1,115 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package asttest_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/ast/asttest"
+	"github.com/open-policy-agent/opa/v1/rego"
+)
+
+var input = map[string]any{"a": 1, "b": "c", "c": []any{1, 2}, "d": map[string]any{"e": true}}
+
+// checkGenerated compiles n modules of g and evaluates them with a body of g.
+func checkGenerated(t *testing.T, g *asttest.Generator, n int) {
+	t.Helper()
+
+	modules := map[string]*ast.Module{}
+	sources := make([]string, n)
+	for i := range n {
+		sources[i] = g.ModuleSource()
+		module, err := ast.ParseModuleWithOpts(fmt.Sprintf("m%d.rego", i), sources[i], ast.ParserOptions{RegoVersion: ast.RegoV1})
+		if err != nil {
+			t.Fatalf("failed to parse generated module: %v\n\n%s", err, sources[i])
+		}
+		modules[fmt.Sprintf("m%d.rego", i)] = module
+	}
+
+	compiler := ast.NewCompiler()
+	if compiler.Compile(modules); compiler.Failed() {
+		t.Fatalf("failed to compile generated modules: %v\n\n%s", compiler.Errors, strings.Join(sources, "\n"))
+	}
+
+	body := g.BodySource()
+
+	for _, query := range []string{"data", body} {
+		_, err := rego.New(rego.Compiler(compiler), rego.Query(query), rego.Input(input)).Eval(context.Background())
+		if err != nil {
+			t.Fatalf("failed to evaluate %v: %v\n\n%s", query, err, strings.Join(sources, "\n"))
+		}
+	}
+}
+
+func TestGenerator(t *testing.T) {
+	for _, tc := range []struct {
+		note string
+		opts asttest.Options
+	}{
+		{note: "default", opts: asttest.Options{}},
+		{note: "all features", opts: asttest.AllFeatures()},
+		{note: "large", opts: asttest.Options{Every: true, Comprehensions: true, MaxRules: 20, MaxBodyLen: 10}},
+	} {
+		t.Run(tc.note, func(t *testing.T) {
+			for seed := range int64(200) {
+				checkGenerated(t, asttest.NewGenerator(seed, tc.opts), 3)
+			}
+		})
+	}
+}
+
+func TestGeneratorFeatures(t *testing.T) {
+	g := asttest.NewGenerator(0, asttest.AllFeatures())
+
+	var src strings.Builder
+	for range 50 {
+		src.WriteString(g.ModuleSource())
+	}
+
+	for _, feature := range []string{"every ", " | ", "(x) := y", ".v := ", " contains ", "default "} {
+		if !strings.Contains(src.String(), feature) {
+			t.Errorf("expected generated modules to contain %q", feature)
+		}
+	}
+
+	g = asttest.NewGenerator(0, asttest.Options{})
+	src.Reset()
+	for range 50 {
+		src.WriteString(g.ModuleSource())
+	}
+
+	for _, feature := range []string{"every ", " | ", "(x) := y", ".v := "} {
+		if strings.Contains(src.String(), feature) {
+			t.Errorf("expected generated modules not to contain %q", feature)
+		}
+	}
+}
+
+func TestGeneratorDeterministic(t *testing.T) {
+	a, b := asttest.NewGenerator(42, asttest.AllFeatures()), asttest.NewGenerator(42, asttest.AllFeatures())
+	if a.ModuleSource() != b.ModuleSource() || a.BodySource() != b.BodySource() {
+		t.Fatal("expected generators with equal seeds to generate equal modules")
+	}
+
+	data := []byte{3, 1, 4, 1, 5, 9, 2, 6}
+	x, y := asttest.NewGeneratorFromBytes(data, asttest.Options{}), asttest.NewGeneratorFromBytes(data, asttest.Options{})
+	if !x.Module().Equal(y.Module()) {
+		t.Fatal("expected generators with equal bytes to generate equal modules")
+	}
+}
+
+func FuzzGenerator(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{1, 2, 3, 4, 5, 6, 7, 8, 9})
+	f.Add([]byte("every comprehension ref head"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		checkGenerated(t, asttest.NewGeneratorFromBytes(data, asttest.AllFeatures()), 2)
+	})
+}
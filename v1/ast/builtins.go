@@ -87,6 +87,9 @@ var DefaultBuiltins = [...]*Builtin{
 	Min,
 	Any,
 	All,
+	AggregateGroupBy,
+	AggregateGroupCount,
+	AggregateGroupSum,
 
 	// Arrays
 	ArrayConcat,
@@ -155,6 +158,8 @@ var DefaultBuiltins = [...]*Builtin{
 	// Encoding
 	JSONMarshal,
 	JSONMarshalWithOptions,
+	JSONCanonicalize,
+	JSONCanonicalHash,
 	JSONUnmarshal,
 	JSONIsValid,
 	Base64Encode,
@@ -186,6 +191,7 @@ var DefaultBuiltins = [...]*Builtin{
 	JSONFilter,
 	JSONRemove,
 	JSONPatch,
+	JSONPatchDiff,
 
 	// Tokens
 	JWTDecode,
@@ -198,12 +204,15 @@ var DefaultBuiltins = [...]*Builtin{
 	JWTVerifyES256,
 	JWTVerifyES384,
 	JWTVerifyES512,
+	JWTVerifyEdDSA,
 	JWTVerifyHS256,
 	JWTVerifyHS384,
 	JWTVerifyHS512,
 	JWTDecodeVerify,
 	JWTEncodeSignRaw,
 	JWTEncodeSign,
+	JWTEncodeEncrypt,
+	JWTDecodeDecrypt,
 
 	// Time
 	NowNanos,
@@ -221,6 +230,7 @@ var DefaultBuiltins = [...]*Builtin{
 	CryptoX509ParseCertificates,
 	CryptoX509ParseAndVerifyCertificates,
 	CryptoX509ParseAndVerifyCertificatesWithOptions,
+	CryptoX509VerifyChain,
 	CryptoMd5,
 	CryptoSha1,
 	CryptoSha256,
@@ -238,6 +248,8 @@ var DefaultBuiltins = [...]*Builtin{
 	WalkBuiltin,
 	ReachableBuiltin,
 	ReachablePathsBuiltin,
+	ShortestPathBuiltin,
+	TransitiveClosureBuiltin,
 
 	// Sort
 	Sort,
@@ -277,6 +289,7 @@ var DefaultBuiltins = [...]*Builtin{
 
 	// OPA
 	OPARuntime,
+	RuntimeRequest,
 
 	// Tracing
 	Trace,
@@ -286,6 +299,7 @@ var DefaultBuiltins = [...]*Builtin{
 	NetCIDRIntersects,
 	NetCIDRContains,
 	NetCIDRContainsMatches,
+	NetCIDRMatchIndex,
 	NetCIDRExpand,
 	NetCIDRMerge,
 	NetLookupIPAddr,
@@ -298,6 +312,10 @@ var DefaultBuiltins = [...]*Builtin{
 	// Units
 	UnitsParse,
 	UnitsParseBytes,
+	UnitsParseDuration,
+	UnitsAddDurations,
+	UnitsCompareDurations,
+	UnitsNormalizeDuration,
 
 	// UUIDs
 	UUIDRFC4122,
@@ -328,6 +346,7 @@ var IgnoreDuringPartialEval = []*Builtin{
 	JWTDecodeVerify,
 	JWTEncodeSignRaw,
 	JWTEncodeSign,
+	JWTEncodeEncrypt,
 	NowNanos,
 	HTTPSend,
 	OPARuntime,
@@ -849,6 +868,61 @@ var Min = &Builtin{
 	canSkipBctx: true,
 }
 
+var groupedCollection = types.NewAny(
+	types.SetOfAny,
+	types.NewArray(nil, types.A),
+	types.NewObject(nil, types.NewDynamicProperty(types.A, types.A)),
+)
+
+var AggregateGroupBy = &Builtin{
+	Name: "aggregate.group_by",
+	Description: "Groups the elements of a collection by the value at a key or path in each element. " +
+		"If `key` is an `array`, it is used as a path into nested objects and arrays, like with `object.get`. " +
+		"Elements lacking the key are left out. The values of objects are grouped, not their keys. " +
+		"For example: `aggregate.group_by([{\"t\": \"a\", \"n\": 1}, {\"t\": \"b\", \"n\": 2}], \"t\")` results in " +
+		"`{\"a\": [{\"t\": \"a\", \"n\": 1}], \"b\": [{\"t\": \"b\", \"n\": 2}]}`.",
+	Decl: types.NewFunction(
+		types.Args(
+			types.Named("collection", groupedCollection).Description("the set, array or object of elements to group"),
+			types.Named("key", types.A).Description("the key or path of the values to group the elements by"),
+		),
+		types.Named("groups", types.NewObject(nil, types.NewDynamicProperty(types.A, types.NewArray(nil, types.A)))).Description("the elements of `collection` grouped by the values at `key`"),
+	),
+	Categories:  aggregates,
+	canSkipBctx: true,
+}
+
+var AggregateGroupCount = &Builtin{
+	Name: "aggregate.group_count",
+	Description: "Counts the elements of a collection by the value at a key or path in each element, " +
+		"i.e., `aggregate.group_count(xs, key)` equals `{k: count(g) | some k, g in aggregate.group_by(xs, key)}`.",
+	Decl: types.NewFunction(
+		types.Args(
+			types.Named("collection", groupedCollection).Description("the set, array or object of elements to count"),
+			types.Named("key", types.A).Description("the key or path of the values to count the elements by"),
+		),
+		types.Named("counts", types.NewObject(nil, types.NewDynamicProperty(types.A, types.N))).Description("the number of elements of `collection` by the values at `key`"),
+	),
+	Categories:  aggregates,
+	canSkipBctx: true,
+}
+
+var AggregateGroupSum = &Builtin{
+	Name: "aggregate.group_sum",
+	Description: "Sums the numbers at a key or path in the elements of a collection, grouped by the value at another key or path. " +
+		"Elements lacking either key are left out.",
+	Decl: types.NewFunction(
+		types.Args(
+			types.Named("collection", groupedCollection).Description("the set, array or object of elements to sum"),
+			types.Named("key", types.A).Description("the key or path of the values to group the elements by"),
+			types.Named("value", types.A).Description("the key or path of the numbers to sum"),
+		),
+		types.Named("sums", types.NewObject(nil, types.NewDynamicProperty(types.A, types.N))).Description("the sums of the numbers at `value` by the values at `key`"),
+	),
+	Categories:  aggregates,
+	canSkipBctx: true,
+}
+
 /**
  * Sorting
  */
@@ -1529,6 +1603,69 @@ and "MiB" are equivalent).`,
 	canSkipBctx: true,
 }
 
+var duration = types.NewAny(types.S, types.N)
+
+var UnitsParseDuration = &Builtin{
+	Name: "units.parse_duration",
+	Tags: category(BuiltinCategoryTime),
+	Description: `Converts strings like "90s", "1h30m", "1.5d", or "-2w" into an integer number of nanoseconds.
+
+Supports the units of ` + "`time.parse_duration_ns`" + ` (ns, us/µs, ms, s, m, h), and d and w for days and weeks,
+which are always 24 and 168 hours long, respectively.`,
+	Decl: types.NewFunction(
+		types.Args(
+			types.Named("x", types.S).Description("the duration to parse"),
+		),
+		types.Named("y", types.N).Description("the duration in nanoseconds"),
+	),
+	canSkipBctx: true,
+}
+
+var UnitsAddDurations = &Builtin{
+	Name: "units.add_durations",
+	Tags: category(BuiltinCategoryTime),
+	Description: "Adds two durations, each given as a string like accepted by `units.parse_duration`, " +
+		"or as a number of nanoseconds. Sums that do not fit into 64 bits are errors.",
+	Decl: types.NewFunction(
+		types.Args(
+			types.Named("a", duration).Description("the first duration"),
+			types.Named("b", duration).Description("the second duration"),
+		),
+		types.Named("sum", types.N).Description("the sum of the durations in nanoseconds"),
+	),
+	canSkipBctx: true,
+}
+
+var UnitsCompareDurations = &Builtin{
+	Name: "units.compare_durations",
+	Tags: category(BuiltinCategoryTime),
+	Description: "Compares two durations, each given as a string like accepted by `units.parse_duration`, " +
+		"or as a number of nanoseconds.",
+	Decl: types.NewFunction(
+		types.Args(
+			types.Named("a", duration).Description("the first duration"),
+			types.Named("b", duration).Description("the second duration"),
+		),
+		types.Named("result", types.N).Description("-1 if `a` is shorter than `b`, 1 if it is longer, and 0 if they are equal"),
+	),
+	canSkipBctx: true,
+}
+
+var UnitsNormalizeDuration = &Builtin{
+	Name: "units.normalize_duration",
+	Tags: category(BuiltinCategoryTime),
+	Description: "Converts a duration, given as a string like accepted by `units.parse_duration`, " +
+		"or as a number of nanoseconds, into its canonical string, " +
+		`which uses the largest units first and leaves out zero amounts, e.g., "36h" and "1d720m" both become "1d12h".`,
+	Decl: types.NewFunction(
+		types.Args(
+			types.Named("x", duration).Description("the duration to normalize"),
+		),
+		types.Named("y", types.S).Description("the canonical duration"),
+	),
+	canSkipBctx: true,
+}
+
 //
 /**
  * Type
@@ -1673,6 +1810,31 @@ var JSONPatch = &Builtin{
 	canSkipBctx: true,
 }
 
+var JSONPatchDiff = &Builtin{
+	Name: "json.patch_diff",
+	Description: "Computes the JSON patch (RFC6902) transforming `a` into `b`, i.e. `json.patch(a, json.patch_diff(a, b)) == b`. " +
+		"For example: `json.patch_diff({\"a\": 1, \"b\": 2}, {\"a\": 1, \"c\": 3})` results in `[{\"op\": \"remove\", \"path\": \"/b\"}, {\"op\": \"add\", \"path\": \"/c\", \"value\": 3}]`. " +
+		"Paths are JSON pointers unless they contain non-string object keys or set elements, in which case they are arrays of path segments.",
+	Decl: types.NewFunction(
+		types.Args(
+			types.Named("a", types.A).Description("the source document"),
+			types.Named("b", types.A).Description("the target document"),
+		),
+		types.Named("output", types.NewArray(
+			nil,
+			types.NewObject(
+				[]*types.StaticProperty{
+					{Key: "op", Value: types.S},
+					{Key: "path", Value: types.A},
+				},
+				types.NewDynamicProperty(types.A, types.A),
+			),
+		)).Description("JSON patch operations transforming `a` into `b`"),
+	),
+	Categories:  objectCat,
+	canSkipBctx: true,
+}
+
 var ObjectSubset = &Builtin{
 	Name: "object.subset",
 	Description: "Determines if an object `sub` is a subset of another object `super`." +
@@ -1853,6 +2015,36 @@ var JSONMarshalWithOptions = &Builtin{
 	canSkipBctx: true,
 }
 
+var JSONCanonicalize = &Builtin{
+	Name: "json.canonicalize",
+	Description: "Serializes the input term to canonical JSON, as defined by the JSON Canonicalization Scheme (RFC 8785): " +
+		"object keys are sorted, insignificant whitespace is omitted, and numbers and strings have a unique representation. " +
+		"Sets are serialized as arrays. Numbers must be representable as IEEE 754 double precision values.",
+	Decl: types.NewFunction(
+		types.Args(
+			types.Named("x", types.A).Description("the term to serialize"),
+		),
+		types.Named("y", types.S).Description("the canonical JSON string representation of `x`"),
+	),
+	Categories:  encoding,
+	canSkipBctx: true,
+}
+
+var JSONCanonicalHash = &Builtin{
+	Name: "json.canonical_hash",
+	Description: "Returns the hex-encoded digest of the canonical JSON serialization of the input term, " +
+		"i.e., `json.canonical_hash(x, \"sha256\") == crypto.sha256(json.canonicalize(x))`.",
+	Decl: types.NewFunction(
+		types.Args(
+			types.Named("x", types.A).Description("the term to hash"),
+			types.Named("alg", types.S).Description("the hash algorithm, one of `sha256`, `sha384` or `sha512`"),
+		),
+		types.Named("y", types.S).Description("the hex-encoded digest of `json.canonicalize(x)`"),
+	),
+	Categories:  encoding,
+	canSkipBctx: true,
+}
+
 var JSONUnmarshal = &Builtin{
 	Name:        "json.unmarshal",
 	Description: "Deserializes the input string.",
@@ -2112,6 +2304,7 @@ var JWTDecode = &Builtin{
 
 var JWTVerifyRS256 = &Builtin{
 	Name:        "io.jwt.verify_rs256",
+	Tags:        category(BuiltinCategoryCrypto),
 	Description: "Verifies if a RS256 JWT signature is valid.",
 	Decl: types.NewFunction(
 		types.Args(
@@ -2126,6 +2319,7 @@ var JWTVerifyRS256 = &Builtin{
 
 var JWTVerifyRS384 = &Builtin{
 	Name:        "io.jwt.verify_rs384",
+	Tags:        category(BuiltinCategoryCrypto),
 	Description: "Verifies if a RS384 JWT signature is valid.",
 	Decl: types.NewFunction(
 		types.Args(
@@ -2140,6 +2334,7 @@ var JWTVerifyRS384 = &Builtin{
 
 var JWTVerifyRS512 = &Builtin{
 	Name:        "io.jwt.verify_rs512",
+	Tags:        category(BuiltinCategoryCrypto),
 	Description: "Verifies if a RS512 JWT signature is valid.",
 	Decl: types.NewFunction(
 		types.Args(
@@ -2154,6 +2349,7 @@ var JWTVerifyRS512 = &Builtin{
 
 var JWTVerifyPS256 = &Builtin{
 	Name:        "io.jwt.verify_ps256",
+	Tags:        category(BuiltinCategoryCrypto),
 	Description: "Verifies if a PS256 JWT signature is valid.",
 	Decl: types.NewFunction(
 		types.Args(
@@ -2168,6 +2364,7 @@ var JWTVerifyPS256 = &Builtin{
 
 var JWTVerifyPS384 = &Builtin{
 	Name:        "io.jwt.verify_ps384",
+	Tags:        category(BuiltinCategoryCrypto),
 	Description: "Verifies if a PS384 JWT signature is valid.",
 	Decl: types.NewFunction(
 		types.Args(
@@ -2182,6 +2379,7 @@ var JWTVerifyPS384 = &Builtin{
 
 var JWTVerifyPS512 = &Builtin{
 	Name:        "io.jwt.verify_ps512",
+	Tags:        category(BuiltinCategoryCrypto),
 	Description: "Verifies if a PS512 JWT signature is valid.",
 	Decl: types.NewFunction(
 		types.Args(
@@ -2196,6 +2394,7 @@ var JWTVerifyPS512 = &Builtin{
 
 var JWTVerifyES256 = &Builtin{
 	Name:        "io.jwt.verify_es256",
+	Tags:        category(BuiltinCategoryCrypto),
 	Description: "Verifies if a ES256 JWT signature is valid.",
 	Decl: types.NewFunction(
 		types.Args(
@@ -2210,6 +2409,7 @@ var JWTVerifyES256 = &Builtin{
 
 var JWTVerifyES384 = &Builtin{
 	Name:        "io.jwt.verify_es384",
+	Tags:        category(BuiltinCategoryCrypto),
 	Description: "Verifies if a ES384 JWT signature is valid.",
 	Decl: types.NewFunction(
 		types.Args(
@@ -2224,6 +2424,7 @@ var JWTVerifyES384 = &Builtin{
 
 var JWTVerifyES512 = &Builtin{
 	Name:        "io.jwt.verify_es512",
+	Tags:        category(BuiltinCategoryCrypto),
 	Description: "Verifies if a ES512 JWT signature is valid.",
 	Decl: types.NewFunction(
 		types.Args(
@@ -2236,8 +2437,24 @@ var JWTVerifyES512 = &Builtin{
 	canSkipBctx: false,
 }
 
+var JWTVerifyEdDSA = &Builtin{
+	Name:        "io.jwt.verify_eddsa",
+	Tags:        category(BuiltinCategoryCrypto),
+	Description: "Verifies if a EdDSA (Ed25519) JWT signature is valid.",
+	Decl: types.NewFunction(
+		types.Args(
+			types.Named("jwt", types.S).Description("JWT token whose signature is to be verified"),
+			types.Named("certificate", types.S).Description("PEM encoded certificate, PEM encoded public key, or the JWK key (set) used to verify the signature"),
+		),
+		types.Named("result", types.B).Description("`true` if the signature is valid, `false` otherwise"),
+	),
+	Categories:  tokensCat,
+	canSkipBctx: false,
+}
+
 var JWTVerifyHS256 = &Builtin{
 	Name:        "io.jwt.verify_hs256",
+	Tags:        category(BuiltinCategoryCrypto),
 	Description: "Verifies if a HS256 (secret) JWT signature is valid.",
 	Decl: types.NewFunction(
 		types.Args(
@@ -2252,6 +2469,7 @@ var JWTVerifyHS256 = &Builtin{
 
 var JWTVerifyHS384 = &Builtin{
 	Name:        "io.jwt.verify_hs384",
+	Tags:        category(BuiltinCategoryCrypto),
 	Description: "Verifies if a HS384 (secret) JWT signature is valid.",
 	Decl: types.NewFunction(
 		types.Args(
@@ -2266,6 +2484,7 @@ var JWTVerifyHS384 = &Builtin{
 
 var JWTVerifyHS512 = &Builtin{
 	Name:        "io.jwt.verify_hs512",
+	Tags:        category(BuiltinCategoryCrypto),
 	Description: "Verifies if a HS512 (secret) JWT signature is valid.",
 	Decl: types.NewFunction(
 		types.Args(
@@ -2281,8 +2500,9 @@ var JWTVerifyHS512 = &Builtin{
 // Marked non-deterministic because it relies on time internally.
 var JWTDecodeVerify = &Builtin{
 	Name: "io.jwt.decode_verify",
+	Tags: category(BuiltinCategoryCrypto),
 	Description: `Verifies a JWT signature under parameterized constraints and decodes the claims if it is valid.
-Supports the following algorithms: HS256, HS384, HS512, RS256, RS384, RS512, ES256, ES384, ES512, PS256, PS384 and PS512.`,
+Supports the following algorithms: HS256, HS384, HS512, RS256, RS384, RS512, ES256, ES384, ES512, PS256, PS384, PS512 and EdDSA.`,
 	Decl: types.NewFunction(
 		types.Args(
 			types.Named("jwt", types.S).Description("JWT token whose signature is to be verified and whose claims are to be checked"),
@@ -2304,12 +2524,13 @@ var tokenSign = category("tokensign")
 // Marked non-deterministic because it relies on RNG internally.
 var JWTEncodeSignRaw = &Builtin{
 	Name:        "io.jwt.encode_sign_raw",
+	Tags:        category(BuiltinCategoryCrypto),
 	Description: "Encodes and optionally signs a JSON Web Token.",
 	Decl: types.NewFunction(
 		types.Args(
 			types.Named("headers", types.S).Description("JWS Protected Header"),
 			types.Named("payload", types.S).Description("JWS Payload"),
-			types.Named("key", types.S).Description("JSON Web Key (RFC7517)"),
+			types.Named("key", types.S).Description("JSON Web Key (RFC7517) or PEM encoded private key"),
 		),
 		types.Named("output", types.S).Description("signed JWT"),
 	),
@@ -2321,12 +2542,16 @@ var JWTEncodeSignRaw = &Builtin{
 // Marked non-deterministic because it relies on RNG internally.
 var JWTEncodeSign = &Builtin{
 	Name:        "io.jwt.encode_sign",
+	Tags:        category(BuiltinCategoryCrypto),
 	Description: "Encodes and optionally signs a JSON Web Token. Inputs are taken as objects, not encoded strings (see `io.jwt.encode_sign_raw`).",
 	Decl: types.NewFunction(
 		types.Args(
 			types.Named("headers", types.NewObject(nil, types.NewDynamicProperty(types.S, types.A))).Description("JWS Protected Header"),
 			types.Named("payload", types.NewObject(nil, types.NewDynamicProperty(types.S, types.A))).Description("JWS Payload"),
-			types.Named("key", types.NewObject(nil, types.NewDynamicProperty(types.S, types.A))).Description("JSON Web Key (RFC7517)"),
+			types.Named("key", types.NewAny(
+				types.NewObject(nil, types.NewDynamicProperty(types.S, types.A)),
+				types.S,
+			)).Description("JSON Web Key (RFC7517) or PEM encoded private key"),
 		),
 		types.Named("output", types.S).Description("signed JWT"),
 	),
@@ -2335,6 +2560,49 @@ var JWTEncodeSign = &Builtin{
 	canSkipBctx:      false,
 }
 
+// Marked non-deterministic because it relies on RNG internally.
+var JWTEncodeEncrypt = &Builtin{
+	Name: "io.jwt.encode_encrypt",
+	Tags: category(BuiltinCategoryCrypto),
+	Description: `Encrypts a payload as a JSON Web Encryption (RFC7516) token in compact serialization.
+Supports the key management algorithms RSA-OAEP, RSA-OAEP-256, ECDH-ES and dir, and the content encryption algorithms A128GCM, A192GCM and A256GCM.`,
+	Decl: types.NewFunction(
+		types.Args(
+			types.Named("headers", types.NewObject(nil, types.NewDynamicProperty(types.S, types.A))).Description("JWE Protected Header, must contain `alg` and `enc`"),
+			types.Named("payload", types.A).Description("JWE Payload; strings are encrypted as-is, other values are serialized to JSON"),
+			types.Named("key", types.NewAny(
+				types.NewObject(nil, types.NewDynamicProperty(types.S, types.A)),
+				types.S,
+			)).Description("JSON Web Key (RFC7517), PEM encoded public key or certificate of the recipient"),
+		),
+		types.Named("output", types.S).Description("encrypted JWT"),
+	),
+	Categories:       tokenSign,
+	Nondeterministic: true,
+	canSkipBctx:      false,
+}
+
+var JWTDecodeDecrypt = &Builtin{
+	Name:        "io.jwt.decode_decrypt",
+	Tags:        category(BuiltinCategoryCrypto),
+	Description: "Decrypts a JSON Web Encryption (RFC7516) token in compact serialization. See `io.jwt.encode_encrypt` for the supported algorithms.",
+	Decl: types.NewFunction(
+		types.Args(
+			types.Named("jwe", types.S).Description("encrypted JWT"),
+			types.Named("key", types.NewAny(
+				types.NewObject(nil, types.NewDynamicProperty(types.S, types.A)),
+				types.S,
+			)).Description("JSON Web Key (RFC7517) or PEM encoded private key of the recipient"),
+		),
+		types.Named("output", types.NewArray([]types.Type{
+			types.NewObject(nil, types.NewDynamicProperty(types.S, types.A)),
+			types.S,
+		}, nil)).Description("`[header, payload]`: the JWE Protected Header and the decrypted payload"),
+	),
+	Categories:  tokenSign,
+	canSkipBctx: true,
+}
+
 /**
  * Time
  */
@@ -2342,6 +2610,7 @@ var JWTEncodeSign = &Builtin{
 // Marked non-deterministic because it relies on time directly.
 var NowNanos = &Builtin{
 	Name:        "time.now_ns",
+	Tags:        category(BuiltinCategoryTime),
 	Description: "Returns the current time since epoch in nanoseconds.",
 	Decl: types.NewFunction(
 		nil,
@@ -2353,6 +2622,7 @@ var NowNanos = &Builtin{
 
 var ParseNanos = &Builtin{
 	Name:        "time.parse_ns",
+	Tags:        category(BuiltinCategoryTime),
 	Description: "Returns the time in nanoseconds parsed from the string in the given format. `undefined` if the result would be outside the valid time range that can fit within an `int64`.",
 	Decl: types.NewFunction(
 		types.Args(
@@ -2366,6 +2636,7 @@ var ParseNanos = &Builtin{
 
 var ParseRFC3339Nanos = &Builtin{
 	Name:        "time.parse_rfc3339_ns",
+	Tags:        category(BuiltinCategoryTime),
 	Description: "Returns the time in nanoseconds parsed from the string in RFC3339 format. `undefined` if the result would be outside the valid time range that can fit within an `int64`.",
 	Decl: types.NewFunction(
 		types.Args(
@@ -2378,6 +2649,7 @@ var ParseRFC3339Nanos = &Builtin{
 
 var ParseDurationNanos = &Builtin{
 	Name:        "time.parse_duration_ns",
+	Tags:        category(BuiltinCategoryTime),
 	Description: "Returns the duration in nanoseconds represented by a string.",
 	Decl: types.NewFunction(
 		types.Args(
@@ -2390,6 +2662,7 @@ var ParseDurationNanos = &Builtin{
 
 var Format = &Builtin{
 	Name:        "time.format",
+	Tags:        category(BuiltinCategoryTime),
 	Description: "Returns the formatted timestamp for the nanoseconds since epoch.",
 	Decl: types.NewFunction(
 		types.Args(
@@ -2406,6 +2679,7 @@ var Format = &Builtin{
 
 var Date = &Builtin{
 	Name:        "time.date",
+	Tags:        category(BuiltinCategoryTime),
 	Description: "Returns the `[year, month, day]` for the nanoseconds since epoch.",
 	Decl: types.NewFunction(
 		types.Args(
@@ -2421,6 +2695,7 @@ var Date = &Builtin{
 
 var Clock = &Builtin{
 	Name:        "time.clock",
+	Tags:        category(BuiltinCategoryTime),
 	Description: "Returns the `[hour, minute, second]` of the day for the nanoseconds since epoch.",
 	Decl: types.NewFunction(
 		types.Args(
@@ -2437,6 +2712,7 @@ var Clock = &Builtin{
 
 var Weekday = &Builtin{
 	Name:        "time.weekday",
+	Tags:        category(BuiltinCategoryTime),
 	Description: "Returns the day of the week (Monday, Tuesday, ...) for the nanoseconds since epoch.",
 	Decl: types.NewFunction(
 		types.Args(
@@ -2452,6 +2728,7 @@ var Weekday = &Builtin{
 
 var AddDate = &Builtin{
 	Name:        "time.add_date",
+	Tags:        category(BuiltinCategoryTime),
 	Description: "Returns the nanoseconds since epoch after adding years, months and days to nanoseconds. Month & day values outside their usual ranges after the operation and will be normalized - for example, October 32 would become November 1. `undefined` if the result would be outside the valid time range that can fit within an `int64`.",
 	Decl: types.NewFunction(
 		types.Args(
@@ -2467,6 +2744,7 @@ var AddDate = &Builtin{
 
 var Diff = &Builtin{
 	Name:        "time.diff",
+	Tags:        category(BuiltinCategoryTime),
 	Description: "Returns the difference between two unix timestamps in nanoseconds (with optional timezone strings).",
 	Decl: types.NewFunction(
 		types.Args(
@@ -2490,11 +2768,16 @@ var Diff = &Builtin{
 
 var CryptoX509ParseCertificates = &Builtin{
 	Name: "crypto.x509.parse_certificates",
+	Tags: category(BuiltinCategoryCrypto),
 	Description: `Returns zero or more certificates from the given encoded string containing
 DER certificate data.
 
 If the input is empty, the function will return null. The input string should be a list of one or more
-concatenated PEM blocks. The whole input of concatenated PEM blocks can optionally be Base64 encoded.`,
+concatenated PEM blocks. The whole input of concatenated PEM blocks can optionally be Base64 encoded.
+
+Besides the fields of Go's x509.Certificate, certificates have the fields ` + "`URIStrings`" + `,
+` + "`PolicyOIDs`" + ` (certificate policies in dotted notation) and ` + "`SCTs`" + ` (embedded signed
+certificate timestamps).`,
 	Decl: types.NewFunction(
 		types.Args(
 			types.Named("certs", types.S).Description("base64 encoded DER or PEM data containing one or more certificates or a PEM string of one or more certificates"),
@@ -2506,6 +2789,7 @@ concatenated PEM blocks. The whole input of concatenated PEM blocks can optional
 
 var CryptoX509ParseAndVerifyCertificates = &Builtin{
 	Name: "crypto.x509.parse_and_verify_certificates",
+	Tags: category(BuiltinCategoryCrypto),
 	Description: `Returns one or more certificates from the given string containing PEM
 or base64 encoded DER certificates after verifying the supplied certificates form a complete
 certificate chain back to a trusted root.
@@ -2526,6 +2810,7 @@ with all others being treated as intermediates.`,
 
 var CryptoX509ParseAndVerifyCertificatesWithOptions = &Builtin{
 	Name: "crypto.x509.parse_and_verify_certificates_with_options",
+	Tags: category(BuiltinCategoryCrypto),
 	Description: `Returns one or more certificates from the given string containing PEM
 or base64 encoded DER certificates after verifying the supplied certificates form a complete
 certificate chain back to a trusted root. A config option passed as the second argument can
@@ -2549,8 +2834,37 @@ with all others being treated as intermediates.`,
 	canSkipBctx: true,
 }
 
+var CryptoX509VerifyChain = &Builtin{
+	Name: "crypto.x509.verify_chain",
+	Tags: category(BuiltinCategoryCrypto),
+	Description: `Builds and verifies a certificate chain from the leaf certificate to one of the trusted roots
+supplied in the options, optionally checking that no certificate in the chain was revoked.
+
+The first certificate is treated as the leaf, all others are treated as untrusted intermediates
+that may be used to build the chain, in any order. Revocation is checked against the supplied CRLs
+only: CRLs that are not signed by the issuer of a certificate, or not current, are ignored.`,
+	Decl: types.NewFunction(
+		types.Args(
+			types.Named("certs", types.S).Description("base64 encoded DER or PEM data containing the leaf certificate followed by zero or more intermediate CAs"),
+			types.Named("options", types.NewObject(
+				nil,
+				types.NewDynamicProperty(types.S, types.A),
+			)).Description("object containing the trust store and configs to verify the validity of certificates. `Roots` (required) is base64 encoded DER or PEM data containing the trusted root CAs. `CRLs` is an array of base64 encoded DER or PEM CRLs to check revocation against. If `RequireRevocationCheck` is `true`, every certificate but the root must be covered by a current CRL. `DNSName`, `CurrentTime`, `MaxConstraintComparisons` and `KeyUsages` are supported like by `crypto.x509.parse_and_verify_certificates_with_options`."),
+		),
+		types.Named("output", types.NewObject(
+			[]*types.StaticProperty{
+				types.NewStaticProperty("valid", types.B),
+				types.NewStaticProperty("chain", types.NewArray(nil, types.NewObject(nil, types.NewDynamicProperty(types.S, types.A)))),
+			},
+			types.NewDynamicProperty(types.S, types.S),
+		)).Description("object with `valid` set to `true` and `chain` to the verified chain of X.509 certificates represented as objects, from the leaf to the root, if the chain could be verified; otherwise, `valid` is `false`, `chain` is `[]` and `error` describes why verification failed"),
+	),
+	canSkipBctx: true,
+}
+
 var CryptoX509ParseCertificateRequest = &Builtin{
 	Name:        "crypto.x509.parse_certificate_request",
+	Tags:        category(BuiltinCategoryCrypto),
 	Description: "Returns a PKCS #10 certificate signing request from the given PEM-encoded PKCS#10 certificate signing request.",
 	Decl: types.NewFunction(
 		types.Args(
@@ -2563,6 +2877,7 @@ var CryptoX509ParseCertificateRequest = &Builtin{
 
 var CryptoX509ParseKeyPair = &Builtin{
 	Name:        "crypto.x509.parse_keypair",
+	Tags:        category(BuiltinCategoryCrypto),
 	Description: "Returns a valid key pair",
 	Decl: types.NewFunction(
 		types.Args(
@@ -2575,6 +2890,7 @@ var CryptoX509ParseKeyPair = &Builtin{
 }
 var CryptoX509ParseRSAPrivateKey = &Builtin{
 	Name:        "crypto.x509.parse_rsa_private_key",
+	Tags:        category(BuiltinCategoryCrypto),
 	Description: "Returns a JWK for signing a JWT from the given PEM-encoded RSA private key.",
 	Decl: types.NewFunction(
 		types.Args(
@@ -2587,6 +2903,7 @@ var CryptoX509ParseRSAPrivateKey = &Builtin{
 
 var CryptoParsePrivateKeys = &Builtin{
 	Name: "crypto.parse_private_keys",
+	Tags: category(BuiltinCategoryCrypto),
 	Description: `Returns zero or more private keys from the given encoded string containing DER certificate data.
 
 If the input is empty, the function will return null. The input string should be a list of one or more concatenated PEM blocks. The whole input of concatenated PEM blocks can optionally be Base64 encoded.`,
@@ -2601,6 +2918,7 @@ If the input is empty, the function will return null. The input string should be
 
 var CryptoMd5 = &Builtin{
 	Name:        "crypto.md5",
+	Tags:        category(BuiltinCategoryCrypto),
 	Description: "Returns a string representing the input string hashed with the MD5 function",
 	Decl: types.NewFunction(
 		types.Args(
@@ -2613,6 +2931,7 @@ var CryptoMd5 = &Builtin{
 
 var CryptoSha1 = &Builtin{
 	Name:        "crypto.sha1",
+	Tags:        category(BuiltinCategoryCrypto),
 	Description: "Returns a string representing the input string hashed with the SHA1 function",
 	Decl: types.NewFunction(
 		types.Args(
@@ -2625,6 +2944,7 @@ var CryptoSha1 = &Builtin{
 
 var CryptoSha256 = &Builtin{
 	Name:        "crypto.sha256",
+	Tags:        category(BuiltinCategoryCrypto),
 	Description: "Returns a string representing the input string hashed with the SHA256 function",
 	Decl: types.NewFunction(
 		types.Args(
@@ -2637,6 +2957,7 @@ var CryptoSha256 = &Builtin{
 
 var CryptoHmacMd5 = &Builtin{
 	Name:        "crypto.hmac.md5",
+	Tags:        category(BuiltinCategoryCrypto),
 	Description: "Returns a string representing the MD5 HMAC of the input message using the input key.",
 	Decl: types.NewFunction(
 		types.Args(
@@ -2650,6 +2971,7 @@ var CryptoHmacMd5 = &Builtin{
 
 var CryptoHmacSha1 = &Builtin{
 	Name:        "crypto.hmac.sha1",
+	Tags:        category(BuiltinCategoryCrypto),
 	Description: "Returns a string representing the SHA1 HMAC of the input message using the input key.",
 	Decl: types.NewFunction(
 		types.Args(
@@ -2663,6 +2985,7 @@ var CryptoHmacSha1 = &Builtin{
 
 var CryptoHmacSha256 = &Builtin{
 	Name:        "crypto.hmac.sha256",
+	Tags:        category(BuiltinCategoryCrypto),
 	Description: "Returns a string representing the SHA256 HMAC of the input message using the input key.",
 	Decl: types.NewFunction(
 		types.Args(
@@ -2676,6 +2999,7 @@ var CryptoHmacSha256 = &Builtin{
 
 var CryptoHmacSha512 = &Builtin{
 	Name:        "crypto.hmac.sha512",
+	Tags:        category(BuiltinCategoryCrypto),
 	Description: "Returns a string representing the SHA512 HMAC of the input message using the input key.",
 	Decl: types.NewFunction(
 		types.Args(
@@ -2689,6 +3013,7 @@ var CryptoHmacSha512 = &Builtin{
 
 var CryptoHmacEqual = &Builtin{
 	Name:        "crypto.hmac.equal",
+	Tags:        category(BuiltinCategoryCrypto),
 	Description: "Returns a boolean representing the result of comparing two MACs for equality without leaking timing information.",
 	Decl: types.NewFunction(
 		types.Args(
@@ -2767,6 +3092,49 @@ var ReachablePathsBuiltin = &Builtin{
 	canSkipBctx: true,
 }
 
+var ShortestPathBuiltin = &Builtin{
+	Name:        "graph.shortest_path",
+	Description: "Computes a shortest path between two nodes in the graph. If several shortest paths exist, the first one found when visiting neighbors in order is returned.",
+	Decl: types.NewFunction(
+		types.Args(
+			types.Named("graph", types.NewObject(
+				nil,
+				types.NewDynamicProperty(
+					types.A,
+					types.NewAny(
+						types.SetOfAny,
+						types.NewArray(nil, types.A)),
+				)),
+			).Description("object containing a set or array of neighboring vertices"),
+			types.Named("from", types.A).Description("start vertex"),
+			types.Named("to", types.A).Description("end vertex"),
+		),
+		types.Named("output", types.NewArray(nil, types.A)).Description("vertices on the path from `from` to `to` (both included) in the directed `graph`, or an empty array if `to` is not reachable"),
+	),
+	canSkipBctx: true,
+}
+
+var TransitiveClosureBuiltin = &Builtin{
+	Name:        "graph.transitive_closure",
+	Description: "Computes the transitive closure of the graph for a set of nodes, i.e. all vertices reachable from each node by following one or more edges.",
+	Decl: types.NewFunction(
+		types.Args(
+			types.Named("graph", types.NewObject(
+				nil,
+				types.NewDynamicProperty(
+					types.A,
+					types.NewAny(
+						types.SetOfAny,
+						types.NewArray(nil, types.A)),
+				)),
+			).Description("object containing a set or array of neighboring vertices"),
+			types.Named("nodes", types.NewAny(types.SetOfAny, types.NewArray(nil, types.A))).Description("set or array of vertices to compute the closure for"),
+		),
+		types.Named("output", types.NewObject(nil, types.NewDynamicProperty(types.A, types.SetOfAny))).Description("object mapping each of the `nodes` to the set of vertices reachable from it in the directed `graph`; a node is only contained in its own set if it is part of a cycle"),
+	),
+	canSkipBctx: true,
+}
+
 /**
  * Type
  */
@@ -2888,6 +3256,7 @@ var TypeNameBuiltin = &Builtin{
 // Marked non-deterministic because HTTP request results can be non-deterministic.
 var HTTPSend = &Builtin{
 	Name:        "http.send",
+	Tags:        category(BuiltinCategoryNetwork),
 	Description: "Returns a HTTP response to the given HTTP request.",
 	Decl: types.NewFunction(
 		types.Args(
@@ -3067,6 +3436,7 @@ var providersAWSCat = category("providers.aws")
 
 var ProvidersAWSSignReqObj = &Builtin{
 	Name:        "providers.aws.sign_req",
+	Tags:        category(BuiltinCategoryCrypto),
 	Description: "Signs an HTTP request object for Amazon Web Services. Currently implements [AWS Signature Version 4 request signing](https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-authenticating-requests.html) by the `Authorization` header method.",
 	Decl: types.NewFunction(
 		types.Args(
@@ -3132,6 +3502,7 @@ var RegoMetadataRule = &Builtin{
 // Marked non-deterministic because of unpredictable config/environment-dependent results.
 var OPARuntime = &Builtin{
 	Name:        "opa.runtime",
+	Tags:        category(BuiltinCategoryIO),
 	Description: "Returns an object that describes the runtime environment where OPA is deployed.",
 	Decl: types.NewFunction(
 		nil,
@@ -3142,6 +3513,19 @@ var OPARuntime = &Builtin{
 	canSkipBctx:      false,
 }
 
+var RuntimeRequest = &Builtin{
+	Name: "runtime.request",
+	Description: "Returns the metadata of the request the policy is evaluated for, e.g., the calling service or trace ID, " +
+		"as provided by the caller of the evaluation. Returns an empty object if no metadata was provided.",
+	Decl: types.NewFunction(
+		nil,
+		types.Named("output", types.NewObject(nil, types.NewDynamicProperty(types.S, types.S))).
+			Description("the request metadata"),
+	),
+	Nondeterministic: true,
+	canSkipBctx:      false,
+}
+
 /**
  * Trace
  */
@@ -3250,6 +3634,25 @@ var NetCIDRContainsMatches = &Builtin{
 	canSkipBctx: true,
 }
 
+var NetCIDRMatchIndex = &Builtin{
+	Name: "net.cidr_match_index",
+	Description: "Returns the CIDRs of a collection that contain an IP address. " +
+		"Unlike calling `net.cidr_contains` for every element of `cidrs`, the lookup uses a prefix trie built from `cidrs` " +
+		"which is cached for the duration of the query (and across queries, if the `net_cidr_match_index` inter-query value cache is enabled), " +
+		"making repeated lookups against large allowlists cheap.",
+	Decl: types.NewFunction(
+		types.Args(
+			types.Named("cidrs", types.NewAny(
+				types.NewArray(nil, types.S),
+				types.SetOfStr,
+			)).Description("CIDRs or IP addresses to match against"),
+			types.Named("ip", types.S).Description("IP address to look up"),
+		),
+		types.Named("output", types.SetOfStr).Description("elements of `cidrs` containing `ip`"),
+	),
+	canSkipBctx: false,
+}
+
 var NetCIDRMerge = &Builtin{
 	Name: "net.cidr_merge",
 	Description: "Merges IP addresses and subnets into the smallest possible list of CIDRs (e.g., `net.cidr_merge([\"192.0.128.0/24\", \"192.0.129.0/24\"])` generates `{\"192.0.128.0/23\"}`." +
@@ -3301,6 +3704,7 @@ var netCidrContainsMatchesOperandType = types.NewAny(
 // Marked non-deterministic because DNS resolution results can be non-deterministic.
 var NetLookupIPAddr = &Builtin{
 	Name:        "net.lookup_ip_addr",
+	Tags:        category(BuiltinCategoryNetwork),
 	Description: "Returns the set of IP addresses (both v4 and v6) that the passed-in `name` resolves to using the standard name resolution mechanisms available.",
 	Decl: types.NewFunction(
 		types.Args(
@@ -3352,6 +3756,7 @@ var SemVerCompare = &Builtin{
 // evaluation.
 var Print = &Builtin{
 	Name: "print",
+	Tags: category(BuiltinCategoryIO),
 	Decl: types.NewVariadicFunction(nil, types.A, nil),
 }
 
@@ -3359,6 +3764,7 @@ var Print = &Builtin{
 // The compiler rewrites print() calls to refer to the internal implementation.
 var InternalPrint = &Builtin{
 	Name: "internal.print",
+	Tags: category(BuiltinCategoryIO),
 	Decl: types.NewFunction([]types.Type{types.NewArray(nil, types.SetOfAny)}, nil),
 }
 
@@ -3529,13 +3935,34 @@ type Builtin struct {
 	// "minus" for example, is part of two categories: numbers and sets. (NOTE(sr): aspirational)
 	Categories []string `json:"categories,omitempty"`
 
+	// Tags are the categories of the built-in function that capabilities can
+	// deny, e.g., BuiltinCategoryNetwork. Custom built-in functions can be
+	// tagged too. See Capabilities.DenyCategories.
+	Tags []string `json:"tags,omitempty"`
+
 	Decl             *types.Function `json:"decl"`               // Built-in function type declaration.
 	Infix            string          `json:"infix,omitempty"`    // Unique name of infix operator. Default should be unset.
 	Relation         bool            `json:"relation,omitempty"` // Indicates if the built-in acts as a relation.
 	deprecated       bool            // Indicates if the built-in has been deprecated.
 	canSkipBctx      bool            // Built-in needs no data from the built-in context.
 	Nondeterministic bool            `json:"nondeterministic,omitempty"` // Indicates if the built-in returns non-deterministic results.
-}
+
+	// OutputArgs holds the zero-based positions of the arguments that the
+	// built-in function binds, like a relation. Variables in those arguments
+	// need not be safe and are safe after the call. The implementation calls
+	// its iterator with an array of the values of the output arguments, in
+	// order, and has no other result.
+	OutputArgs []int `json:"output_args,omitempty"`
+}
+
+// Categories of built-in functions that capabilities can deny, regardless of
+// their names. See Capabilities.DenyCategories.
+const (
+	BuiltinCategoryNetwork = "network" // built-ins connecting to other hosts, e.g., http.send
+	BuiltinCategoryCrypto  = "crypto"  // built-ins hashing, signing, verifying or parsing keys and certificates
+	BuiltinCategoryIO      = "io"      // built-ins reading from or writing to the environment, e.g., opa.runtime
+	BuiltinCategoryTime    = "time"    // built-ins reading or computing with time, e.g., time.now_ns
+)
 
 // category is a helper for specifying a Builtin's Categories
 func category(cs ...string) []string {
@@ -3557,6 +3984,18 @@ func (b *Builtin) Minimal() *Builtin {
 	return &cpy
 }
 
+// deniableTags returns the Tags of the Builtin function. Built-in functions
+// loaded from capabilities files of earlier versions, which do not include
+// them, are looked up by name among the built-in functions of this version.
+func (b *Builtin) deniableTags() []string {
+	if b.Tags == nil {
+		if bi, ok := BuiltinMap[b.Name]; ok {
+			return bi.Tags
+		}
+	}
+	return b.Tags
+}
+
 // IsDeprecated returns true if the Builtin function is deprecated and will be removed in a future release.
 func (b *Builtin) IsDeprecated() bool {
 	return b.deprecated
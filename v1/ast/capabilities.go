@@ -57,6 +57,11 @@ const FeatureRegoV1 = "rego_v1"
 const FeatureRegoV1Import = "rego_v1_import"
 const FeatureKeywordsInRefs = "keywords_in_refs"
 
+// FeatureIncludeDirective enables `import include["<file>"]` directives, which
+// inline the rules of another module into the including module. It is not
+// enabled by default: use RegisterFeatures or custom capabilities to enable it.
+const FeatureIncludeDirective = "include_directive"
+
 // Features carries the default features supported by this version of OPA.
 // Use RegisterFeatures to add to them.
 var Features = []string{
@@ -90,11 +95,24 @@ type Capabilities struct {
 	// allow_net is an array of hostnames or IP addresses, that an OPA instance is
 	// allowed to connect to.
 	// If omitted, ANY host can be connected to. If empty, NO host can be connected to.
-	// As of now, this only controls fetching remote refs for using JSON Schemas in
-	// the type checker.
+	// This controls fetching remote refs for using JSON Schemas in the type
+	// checker, and the hosts `http.send` and `net.lookup_ip_addr` can connect to
+	// at evaluation time.
 	// TODO(sr): support ports to further restrict connection peers
-	// TODO(sr): support restricting `http.send` using the same mechanism (see https://github.com/open-policy-agent/opa/issues/3665)
 	AllowNet []string `json:"allow_net,omitempty"`
+
+	// allow_env is an array of names of environment variables that `opa.runtime`
+	// returns at evaluation time.
+	// If omitted, ALL environment variables are returned. If empty, NO environment
+	// variables are returned.
+	AllowEnv []string `json:"allow_env,omitempty"`
+
+	// deny_categories is an array of categories of built-in functions, e.g.,
+	// "network", that are not available even if they are listed in builtins.
+	// Unlike removing built-in functions by name, this keeps covering the
+	// built-in functions that later versions of OPA add to the categories.
+	// See Builtin.Tags for the categories of built-in functions.
+	DenyCategories []string `json:"deny_categories,omitempty"`
 }
 
 // WasmABIVersion captures the Wasm ABI version. Its `Minor` version is indicating
@@ -269,6 +287,17 @@ func (c *Capabilities) ContainsFeature(feature string) bool {
 	return slices.Contains(c.Features, feature)
 }
 
+// DeniesBuiltin returns true if one of the categories of bi is denied by
+// DenyCategories.
+func (c *Capabilities) DeniesBuiltin(bi *Builtin) bool {
+	if len(c.DenyCategories) == 0 {
+		return false
+	}
+	return slices.ContainsFunc(bi.deniableTags(), func(tag string) bool {
+		return slices.Contains(c.DenyCategories, tag)
+	})
+}
+
 // addBuiltinSorted inserts a built-in into c in sorted order. An existing built-in with the same name
 // will be overwritten.
 func (c *Capabilities) addBuiltinSorted(bi *Builtin) {
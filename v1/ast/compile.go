@@ -5,10 +5,12 @@
 package ast
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
 	"maps"
+	"path/filepath"
 	"slices"
 	"sort"
 	"strconv"
@@ -40,6 +42,10 @@ type Compiler struct {
 	// "failed".
 	Errors Errors
 
+	// Warnings contains problems found during the compilation process that
+	// do not fail it, e.g., functions that are never called in strict mode.
+	Warnings Errors
+
 	// Modules contains the compiled modules. The compiled modules are the
 	// output of the compilation process. If the compilation process failed,
 	// there is no guarantee about the state of the modules.
@@ -128,6 +134,8 @@ type Compiler struct {
 
 	localvargen                *localVarGenerator
 	moduleLoader               ModuleLoader
+	includes                   bool                    // true if include directives were resolved
+	requiredBy                 map[*Rule]*Capabilities // capabilities required by each rule, see RequiredBy
 	ruleIndices                *util.HasherMap[Ref, RuleIndex]
 	stages                     []stage
 	maxErrs                    int
@@ -140,11 +148,14 @@ type Compiler struct {
 	imports                    map[string][]*Import          // saved imports from stripping
 	builtins                   map[string]*Builtin           // universe of built-in functions
 	customBuiltins             map[string]*Builtin           // user-supplied custom built-in functions (deprecated: use capabilities)
+	builtinOverrides           map[string]*types.Function    // user-supplied type declarations replacing those of built-in functions
+	outputArgs                 map[string][]int              // output argument positions of built-in functions that bind their arguments
 	unsafeBuiltinsMap          map[string]struct{}           // user-supplied set of unsafe built-ins functions to block (deprecated: use capabilities)
 	deprecatedBuiltinsMap      map[string]struct{}           // set of deprecated, but not removed, built-in functions
 	enablePrintStatements      bool                          // indicates if print statements should be elided (default)
 	comprehensionIndices       map[*Term]*ComprehensionIndex // comprehension key index
 	initialized                bool                          // indicates if init() has been called
+	initErrors                 Errors                        // errors found by init(), reported when compiling
 	debug                      debug.Debug                   // emits debug information produced during compilation
 	schemaSet                  *SchemaSet                    // user-supplied schemas for input and data documents
 	inputType                  types.Type                    // global input type retrieved from schema set
@@ -156,7 +167,12 @@ type Compiler struct {
 	allowUndefinedFuncCalls    bool                          // don't error on calls to unknown functions.
 	evalMode                   CompilerEvalMode              //
 	rewriteTestRulesForTracing bool                          // rewrite test rules to capture dynamic values for tracing.
+	constantFolding            bool                          // evaluate constant calls to deterministic built-in functions at compile-time.
 	defaultRegoVersion         RegoVersion
+	moduleFilter               func(string, *Module) bool // user-supplied filter of the modules to compile
+	queryCache                 *queryCache                // cache of compiled queries, if enabled
+	lenient                    bool                       // drop rules that fail to compile instead of failing
+	checkContradictions        bool                       // warn about expressions that contradict earlier ones
 }
 
 func (c *Compiler) DefaultRegoVersion() RegoVersion {
@@ -310,6 +326,7 @@ func NewCompiler() *Compiler {
 		Modules:               map[string]*Module{},
 		RewrittenVars:         map[Var]Var{},
 		Required:              &Capabilities{},
+		requiredBy:            map[*Rule]*Capabilities{},
 		ruleIndices:           util.NewHasherMap[Ref, RuleIndex](RefEqual),
 		maxErrs:               CompileErrorLimitDefault,
 		after:                 map[string][]CompilerStageDefinition{},
@@ -324,6 +341,9 @@ func NewCompiler() *Compiler {
 	c.RuleTree = NewRuleTree(c.ModuleTree)
 
 	c.stages = []stage{
+		// Include directives must be resolved before references, as the
+		// included rules are resolved in the context of the including module.
+		{"ResolveIncludes", "compile_stage_resolve_includes", c.resolveIncludes},
 		// Reference resolution should run first as it may be used to lazily
 		// load additional modules. If any stages run before resolution, they
 		// need to be re-run after resolution.
@@ -333,6 +353,7 @@ func NewCompiler() *Compiler {
 		// stages that need to generate variables.
 		{"InitLocalVarGen", "compile_stage_init_local_var_gen", c.initLocalVarGen},
 		{"RewriteRuleHeadRefs", "compile_stage_rewrite_rule_head_refs", c.rewriteRuleHeadRefs},
+		{"FoldConstants", "compile_stage_fold_constants", c.foldConstants}, // must run before RewriteExprTerms
 		{"CheckKeywordOverrides", "compile_stage_check_keyword_overrides", c.checkKeywordOverrides},
 		{"CheckDuplicateImports", "compile_stage_check_imports", c.checkImports},
 		{"RemoveImports", "compile_stage_remove_imports", c.removeImports},
@@ -357,7 +378,9 @@ func NewCompiler() *Compiler {
 		{"RewriteDynamicTerms", "compile_stage_rewrite_dynamic_terms", c.rewriteDynamicTerms},
 		{"RewriteTestRulesForTracing", "compile_stage_rewrite_test_rules_for_tracing", c.rewriteTestRuleEqualities}, // must run after RewriteDynamicTerms
 		{"CheckRecursion", "compile_stage_check_recursion", c.checkRecursion},
+		{"CheckUnusedFunctions", "compile_stage_check_unused_functions", c.checkUnusedFunctions},
 		{"CheckTypes", "compile_stage_check_types", c.checkTypes}, // must be run after CheckRecursion
+		{"CheckContradictions", "compile_stage_check_contradictions", c.checkContradictionsInBodies},
 		{"CheckUnsafeBuiltins", "compile_state_check_unsafe_builtins", c.checkUnsafeBuiltins},
 		{"CheckDeprecatedBuiltins", "compile_state_check_deprecated_builtins", c.checkDeprecatedBuiltins},
 		{"BuildRuleIndices", "compile_stage_rebuild_indices", c.buildRuleIndices},
@@ -383,6 +406,25 @@ func (c *Compiler) WithEnablePrintStatements(yes bool) *Compiler {
 	return c
 }
 
+// WithConstantFolding enables evaluation of calls to deterministic built-in
+// functions with ground operands at compile-time, e.g., lower("ADMIN") is
+// replaced by "admin". Folded built-in functions are still recorded in the
+// required capabilities. Calls to built-in functions that are replaced with
+// the with keyword anywhere in the compiled modules are not folded, but
+// replacing a folded built-in function in a query has no effect.
+func (c *Compiler) WithConstantFolding(enabled bool) *Compiler {
+	c.constantFolding = enabled
+	return c
+}
+
+// WithContradictionCheck enables warnings about expressions that can never be
+// true because they contradict earlier expressions of the same body, e.g.,
+// x == 2 after x == 1, or is_number(x) after is_string(x).
+func (c *Compiler) WithContradictionCheck(enabled bool) *Compiler {
+	c.checkContradictions = enabled
+	return c
+}
+
 // WithPathConflictsCheck enables base-virtual document conflict
 // detection. The compiler will check that rules don't overlap with
 // paths that exist as determined by the provided callable.
@@ -446,6 +488,18 @@ func (c *Compiler) WithBuiltins(builtins map[string]*Builtin) *Compiler {
 	return c
 }
 
+// WithBuiltinOverride replaces the type declaration of the named built-in
+// function for this compiler only, e.g., to narrow the result type of
+// http.send. The global built-in registry and capabilities are not modified.
+// Compilation fails if the built-in function is not available.
+func (c *Compiler) WithBuiltinOverride(name string, decl *types.Function) *Compiler {
+	if c.builtinOverrides == nil {
+		c.builtinOverrides = map[string]*types.Function{}
+	}
+	c.builtinOverrides[name] = decl
+	return c
+}
+
 // WithUnsafeBuiltins is deprecated.
 // Deprecated: Use WithCapabilities instead.
 func (c *Compiler) WithUnsafeBuiltins(unsafeBuiltins map[string]struct{}) *Compiler {
@@ -459,6 +513,16 @@ func (c *Compiler) WithStrict(strict bool) *Compiler {
 	return c
 }
 
+// WithLenientCompilation toggles lenient compilation. When enabled, rules that
+// fail to compile are dropped, their errors are recorded as warnings, and the
+// remaining rules are compiled, e.g., for best-effort analysis of policies
+// that do not compile. Compilation still fails if errors cannot be attributed
+// to rules, e.g., errors in imports.
+func (c *Compiler) WithLenientCompilation(yes bool) *Compiler {
+	c.lenient = yes
+	return c
+}
+
 // WithKeepModules enables retaining unprocessed modules in the compiler.
 // Note that the modules aren't copied on the way in or out -- so when
 // accessing them via ParsedModules(), mutations will occur in the module
@@ -468,6 +532,16 @@ func (c *Compiler) WithKeepModules(y bool) *Compiler {
 	return c
 }
 
+// WithModuleFilter sets f as the filter of the modules to compile. Modules
+// passed to Compile, or returned by the ModuleLoader, for which f returns false
+// are excluded from the compilation, e.g. to skip test modules or modules
+// annotated as development only. f is called with the name of the module and
+// the unprocessed module, which must not be modified.
+func (c *Compiler) WithModuleFilter(f func(name string, m *Module) bool) *Compiler {
+	c.moduleFilter = f
+	return c
+}
+
 // WithUseTypeCheckAnnotations use schema annotations during type checking
 func (c *Compiler) WithUseTypeCheckAnnotations(enabled bool) *Compiler {
 	c.useTypeCheckAnnotations = enabled
@@ -510,9 +584,22 @@ func (c *Compiler) QueryCompiler() QueryCompiler {
 // compiler. If the compilation process fails for any reason, the compiler will
 // contain a slice of errors.
 func (c *Compiler) Compile(modules map[string]*Module) {
+	if c.lenient {
+		c.compileLenient(modules)
+		return
+	}
+
+	c.compileModules(modules)
+}
+
+func (c *Compiler) compileModules(modules map[string]*Module) {
 
 	c.init()
 
+	if c.queryCache != nil {
+		c.queryCache.clear()
+	}
+
 	c.Modules = make(map[string]*Module, len(modules))
 	c.sorted = make([]string, 0, len(modules))
 
@@ -523,6 +610,9 @@ func (c *Compiler) Compile(modules map[string]*Module) {
 	}
 
 	for k, v := range modules {
+		if c.moduleFilter != nil && !c.moduleFilter(k, v) {
+			continue
+		}
 		c.Modules[k] = v.Copy()
 		c.sorted = append(c.sorted, k)
 		if c.parsedModules != nil {
@@ -535,6 +625,116 @@ func (c *Compiler) Compile(modules map[string]*Module) {
 	c.compile()
 }
 
+// compileLenient compiles the modules, dropping the rules that errors are
+// attributed to and compiling the remaining rules again, until compilation
+// succeeds or errors cannot be attributed to rules.
+func (c *Compiler) compileLenient(modules map[string]*Module) {
+	var dropped Errors
+
+	for {
+		c.compileModules(modules)
+		if !c.Failed() {
+			break
+		}
+
+		remaining, ok := dropFailedRules(modules, c.Errors)
+		if !ok {
+			break
+		}
+
+		for _, err := range c.Errors {
+			if err != errLimitReached {
+				dropped = append(dropped, err)
+			}
+		}
+
+		modules = remaining
+		c.reset()
+	}
+
+	c.Warnings = append(c.Warnings, dropped...)
+	c.Warnings.Sort()
+}
+
+// dropFailedRules returns copies of the modules without the rules that errs
+// are located in. It returns false if an error is not located in a rule.
+func dropFailedRules(modules map[string]*Module, errs Errors) (map[string]*Module, bool) {
+	failed := map[*Rule]struct{}{}
+
+	for _, err := range errs {
+		if err == errLimitReached {
+			continue
+		}
+		if err.Location == nil {
+			return nil, false
+		}
+
+		var found bool
+		for _, mod := range modules {
+			for _, rule := range mod.Rules {
+				if ruleContains(rule, err.Location) {
+					failed[rule] = struct{}{}
+					found = true
+				}
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+
+	result := make(map[string]*Module, len(modules))
+	for name, mod := range modules {
+		rules := make([]*Rule, 0, len(mod.Rules))
+		for _, rule := range mod.Rules {
+			if _, ok := failed[rule]; !ok {
+				rules = append(rules, rule)
+			}
+		}
+		if len(rules) == len(mod.Rules) {
+			result[name] = mod
+			continue
+		}
+		cpy := *mod
+		cpy.Rules = rules
+		result[name] = &cpy
+	}
+
+	return result, true
+}
+
+// ruleContains returns true if loc is located in the text of rule.
+func ruleContains(rule *Rule, loc *Location) bool {
+	if rule.Location == nil || rule.Location.File != loc.File {
+		return false
+	}
+	first := rule.Location.Row
+	last := first + bytes.Count(rule.Location.Text, []byte("\n"))
+	return loc.Row >= first && loc.Row <= last
+}
+
+// reset discards the state of the previous compilation.
+func (c *Compiler) reset() {
+	c.Errors = nil
+	c.Warnings = nil
+	c.ModuleTree = NewModuleTree(nil)
+	c.RuleTree = NewRuleTree(c.ModuleTree)
+	c.Graph = nil
+	c.TypeEnv = newTypeChecker().
+		WithSchemaSet(c.schemaSet).
+		WithInputType(c.inputType).
+		Env(c.builtins)
+	c.RewrittenVars = map[Var]Var{}
+	c.Required = &Capabilities{}
+	c.requiredBy = map[*Rule]*Capabilities{}
+	c.localvargen = nil
+	c.includes = false
+	c.ruleIndices = util.NewHasherMap[Ref, RuleIndex](RefEqual)
+	c.imports = nil
+	c.comprehensionIndices = map[*Term]*ComprehensionIndex{}
+	c.annotationSet = nil
+}
+
 // WithSchemas sets a schemaSet to the compiler
 func (c *Compiler) WithSchemas(schemas *SchemaSet) *Compiler {
 	c.schemaSet = schemas
@@ -674,6 +874,29 @@ func extractRules(s []any) []*Rule {
 	return rules
 }
 
+// SortedRules returns the rules of the compiled modules sorted by their
+// dependencies, i.e., every rule comes after the rules it refers to. This is
+// the order the type checker visits rules in. Ties are broken by module name
+// and by the position of the rules in their modules, so that code generators
+// emit the same output for the same modules across builds. Else branches are
+// included, after the rules they depend on. SortedRules returns nil if the
+// modules have not been compiled.
+func (c *Compiler) SortedRules() []*Rule {
+	if c.Graph == nil {
+		return nil
+	}
+
+	// Recursion is caught in earlier step, so this cannot fail.
+	sorted, _ := c.Graph.Sort()
+
+	rules := make([]*Rule, 0, len(sorted))
+	for _, x := range sorted {
+		rules = append(rules, x.(*Rule))
+	}
+
+	return rules
+}
+
 // GetRules returns a slice of rules that are referred to by ref.
 //
 // E.g., given the following module:
@@ -709,6 +932,114 @@ func (c *Compiler) GetRules(ref Ref) (rules []*Rule) {
 	return rules
 }
 
+// Cardinality describes how many rule results make up a virtual document.
+type Cardinality int
+
+const (
+	// CardinalityUnknown indicates that the document is not produced by rules,
+	// e.g., it is a base document, or that it cannot be told from the rules,
+	// e.g., it is a value inside the result of a rule.
+	CardinalityUnknown Cardinality = iota
+
+	// CardinalityOne indicates that the document is a single rule result,
+	// e.g., the value of a complete rule, or of one key of a partial object.
+	CardinalityOne
+
+	// CardinalityMany indicates that the document is made up of many rule
+	// results, e.g., a partial set, or a prefix of several rules.
+	CardinalityMany
+)
+
+func (c Cardinality) String() string {
+	switch c {
+	case CardinalityOne:
+		return "one"
+	case CardinalityMany:
+		return "many"
+	}
+	return "unknown"
+}
+
+// RuleCardinality returns the cardinality of the virtual document referred to
+// by ref, derived from the kinds and head refs of the rules producing it.
+//
+// E.g., given the following module:
+//
+//	package a
+//
+//	p := 1 if { ... }                 # rule1
+//	q contains x if { ... }           # rule2
+//	r[x] := y if { ... }              # rule3
+//	f(x) := y if { ... }              # rule4
+//
+// The following calls yield the cardinalities on the right.
+//
+//	RuleCardinality("data.a.p")    => CardinalityOne
+//	RuleCardinality("data.a.q")    => CardinalityMany
+//	RuleCardinality("data.a.q.x")  => CardinalityOne
+//	RuleCardinality("data.a.r")    => CardinalityMany
+//	RuleCardinality("data.a.r.x")  => CardinalityOne
+//	RuleCardinality("data.a.r[x]") => CardinalityMany
+//	RuleCardinality("data.a")      => CardinalityMany
+//	RuleCardinality("data.a.f")    => CardinalityUnknown
+//	RuleCardinality("data.a.p.x")  => CardinalityUnknown
+func (c *Compiler) RuleCardinality(ref Ref) Cardinality {
+	var result Cardinality
+	for _, rule := range c.GetRules(ref) {
+		card, ok := ruleCardinality(rule, ref)
+		switch {
+		case !ok:
+			continue
+		case card == CardinalityUnknown:
+			return CardinalityUnknown
+		case card == CardinalityMany:
+			result = CardinalityMany
+		case result == CardinalityUnknown:
+			result = CardinalityOne
+		}
+	}
+	return result
+}
+
+// ruleCardinality returns the cardinality of the results of rule making up the
+// document referred to by ref, and false if rule does not contribute to it,
+// e.g., the rule a.b[x].c when ref is data.a.b.y.d.
+func ruleCardinality(rule *Rule, ref Ref) (Cardinality, bool) {
+	path := rule.Ref()
+
+	// Functions are not part of documents, so their cardinality is unknown
+	// only when referred to directly.
+	if len(rule.Head.Args) > 0 {
+		return CardinalityUnknown, len(ref) >= len(path)
+	}
+
+	for i := 1; i < len(path) && i < len(ref); i++ {
+		switch {
+		case !IsConstant(ref[i].Value):
+			return CardinalityMany, true
+		case path[i].IsGround() && !path[i].Equal(ref[i]):
+			return CardinalityUnknown, false
+		}
+	}
+
+	multi := rule.Head.RuleKind() == MultiValue
+
+	switch {
+	case len(ref) < len(path):
+		return CardinalityMany, true
+	case len(ref) == len(path) && multi:
+		return CardinalityMany, true
+	case len(ref) == len(path):
+		return CardinalityOne, true
+	case len(ref) == len(path)+1 && multi && !IsConstant(ref[len(path)].Value):
+		return CardinalityMany, true
+	case len(ref) == len(path)+1 && multi:
+		return CardinalityOne, true
+	}
+
+	return CardinalityUnknown, true
+}
+
 // GetRulesDynamic returns a slice of rules that could be referred to by a ref.
 //
 // Deprecated: use GetRulesDynamicWithOpts
@@ -875,10 +1206,18 @@ func (c *Compiler) PassesTypeCheckRules(rules []*Rule) Errors {
 		c.builtins = make(map[string]*Builtin, len(c.capabilities.Builtins)+len(c.customBuiltins))
 
 		for _, bi := range c.capabilities.Builtins {
+			if c.capabilities.DeniesBuiltin(bi) {
+				continue
+			}
 			c.builtins[bi.Name] = bi
 		}
 
 		maps.Copy(c.builtins, c.customBuiltins)
+		errs := c.overrideBuiltinDecls()
+		errs = append(errs, c.initOutputArgs()...)
+		if len(errs) > 0 {
+			return errs
+		}
 
 		c.TypeEnv = checker.Env(c.builtins)
 	}
@@ -963,7 +1302,7 @@ func (c *Compiler) buildComprehensionIndices() {
 			if len(r.Head.Args) > 0 {
 				candidates.Update(r.Head.Args.Vars())
 			}
-			n := buildComprehensionIndices(c.debug, c.GetArity, candidates, c.RewrittenVars, r.Body, c.comprehensionIndices)
+			n := buildComprehensionIndices(c.debug, c.GetArity, c.outputArgs, candidates, c.RewrittenVars, r.Body, c.comprehensionIndices)
 			c.counterAdd(compileStageComprehensionIndexBuild, n)
 			return false
 		})
@@ -979,29 +1318,37 @@ var futureKeywordsPrefix = Ref{FutureRootDocument, InternedTerm("keywords")}
 func (c *Compiler) buildRequiredCapabilities() {
 
 	features := map[string]struct{}{}
-
-	// extract required keywords from modules
-
 	keywords := map[string]struct{}{}
 
+	if c.includes {
+		features[FeatureIncludeDirective] = struct{}{}
+	}
+
 	for _, name := range c.sorted {
+		mod := c.Modules[name]
+
+		// extract required keywords and features from modules, which are
+		// required by all of their rules
+
+		modKeywords := map[string]struct{}{}
+		modFeatures := map[string]struct{}{}
+
 		for _, imp := range c.imports[name] {
-			mod := c.Modules[name]
 			path := imp.Path.Value.(Ref)
 			switch {
 			case path.Equal(RegoV1CompatibleRef):
 				if !c.moduleIsRegoV1(mod) {
-					features[FeatureRegoV1Import] = struct{}{}
+					modFeatures[FeatureRegoV1Import] = struct{}{}
 				}
 			case path.HasPrefix(futureKeywordsPrefix):
 				if len(path) == 2 {
 					if c.moduleIsRegoV1(mod) {
 						for kw := range futureKeywords {
-							keywords[kw] = struct{}{}
+							modKeywords[kw] = struct{}{}
 						}
 					} else {
 						for kw := range allFutureKeywords {
-							keywords[kw] = struct{}{}
+							modKeywords[kw] = struct{}{}
 						}
 					}
 				} else {
@@ -1009,14 +1356,14 @@ func (c *Compiler) buildRequiredCapabilities() {
 					if c.moduleIsRegoV1(mod) {
 						for allowedKw := range futureKeywords {
 							if kw == allowedKw {
-								keywords[kw] = struct{}{}
+								modKeywords[kw] = struct{}{}
 								break
 							}
 						}
 					} else {
 						for allowedKw := range allFutureKeywords {
 							if kw == allowedKw {
-								keywords[kw] = struct{}{}
+								modKeywords[kw] = struct{}{}
 								break
 							}
 						}
@@ -1024,31 +1371,66 @@ func (c *Compiler) buildRequiredCapabilities() {
 				}
 			}
 		}
-	}
 
-	c.Required.FutureKeywords = util.KeysSorted(keywords)
+		regoV1 := c.moduleIsRegoV1(mod)
+		if regoV1 {
+			modFeatures[FeatureRegoV1] = struct{}{}
+		}
 
-	// extract required features from modules
+		// extract required features and built-ins from rules
 
-	for _, name := range c.sorted {
-		mod := c.Modules[name]
+		for _, rule := range mod.Rules {
+			req := c.requiredFor(rule)
 
-		if c.moduleIsRegoV1(mod) {
-			features[FeatureRegoV1] = struct{}{}
-		} else {
-			for _, rule := range mod.Rules {
-				refLen := len(rule.Head.Reference)
-				if refLen >= 3 {
-					if refLen > len(rule.Head.Reference.ConstantPrefix()) {
-						features[FeatureRefHeads] = struct{}{}
-					} else {
-						features[FeatureRefHeadStringPrefixes] = struct{}{}
+			for kw := range modKeywords {
+				req.FutureKeywords = append(req.FutureKeywords, kw)
+			}
+
+			for f := range modFeatures {
+				req.Features = append(req.Features, f)
+			}
+
+			if refLen := len(rule.Head.Reference); !regoV1 && refLen >= 3 {
+				if refLen > len(rule.Head.Reference.ConstantPrefix()) {
+					req.Features = append(req.Features, FeatureRefHeads)
+				} else {
+					req.Features = append(req.Features, FeatureRefHeadStringPrefixes)
+				}
+			}
+
+			for _, f := range req.Features {
+				features[f] = struct{}{}
+			}
+
+			slices.Sort(req.FutureKeywords)
+			req.FutureKeywords = slices.Compact(req.FutureKeywords)
+			slices.Sort(req.Features)
+			req.Features = slices.Compact(req.Features)
+
+			// built-ins called by the rule, like the type checker records them
+			WalkExprs(rule, func(expr *Expr) bool {
+				if expr.IsCall() {
+					if bi, ok := c.builtins[expr.Operator().String()]; ok {
+						req.addBuiltinSorted(bi)
 					}
 				}
+				return false
+			})
+
+			for i, bi := range req.Builtins {
+				req.Builtins[i] = bi.Minimal()
 			}
 		}
+
+		for f := range modFeatures {
+			features[f] = struct{}{}
+		}
+		for kw := range modKeywords {
+			keywords[kw] = struct{}{}
+		}
 	}
 
+	c.Required.FutureKeywords = util.KeysSorted(keywords)
 	c.Required.Features = util.KeysSorted(features)
 
 	for i, bi := range c.Required.Builtins {
@@ -1056,6 +1438,54 @@ func (c *Compiler) buildRequiredCapabilities() {
 	}
 }
 
+// requiredFor returns the capabilities required by rule, see RequiredBy.
+func (c *Compiler) requiredFor(rule *Rule) *Capabilities {
+	req, ok := c.requiredBy[rule]
+	if !ok {
+		req = &Capabilities{}
+		c.requiredBy[rule] = req
+	}
+	return req
+}
+
+// RequiredBy returns the capabilities required by the rules ref refers to, see
+// GetRules, or nil if there are none. Like Required, it is set by compilation:
+// the features and future keywords required by a module are required by each
+// of its rules, and the built-in functions required by a rule are those it
+// calls. This tells which rules keep a policy from running on older versions
+// of OPA.
+func (c *Compiler) RequiredBy(ref Ref) *Capabilities {
+	rules := c.GetRules(ref)
+	if len(rules) == 0 {
+		return nil
+	}
+
+	features := map[string]struct{}{}
+	keywords := map[string]struct{}{}
+	required := &Capabilities{}
+
+	for _, rule := range rules {
+		req, ok := c.requiredBy[rule]
+		if !ok {
+			continue
+		}
+		for _, f := range req.Features {
+			features[f] = struct{}{}
+		}
+		for _, kw := range req.FutureKeywords {
+			keywords[kw] = struct{}{}
+		}
+		for _, bi := range req.Builtins {
+			required.addBuiltinSorted(bi)
+		}
+	}
+
+	required.Features = util.KeysSorted(features)
+	required.FutureKeywords = util.KeysSorted(keywords)
+
+	return required
+}
+
 // checkRecursion ensures that there are no recursive definitions, i.e., there are
 // no cycles in the Graph.
 func (c *Compiler) checkRecursion() {
@@ -1073,6 +1503,79 @@ func (c *Compiler) checkRecursion() {
 	})
 }
 
+// checkContradictionsInBodies warns about expressions of rule bodies that can
+// never be true, if enabled.
+func (c *Compiler) checkContradictionsInBodies() {
+	if !c.checkContradictions {
+		return
+	}
+
+	cc := newContradictionChecker(c.RewrittenVars)
+	for _, name := range c.sorted {
+		WalkBodies(c.Modules[name], func(body Body) bool {
+			c.Warnings = append(c.Warnings, cc.Check(body)...)
+			return false
+		})
+	}
+
+	c.Warnings.Sort()
+}
+
+// checkUnusedFunctions warns about functions that are not called by any rule
+// in strict mode. Dynamic references, e.g., data.a[x], count as calls to all
+// functions they may refer to.
+func (c *Compiler) checkUnusedFunctions() {
+	if !c.strict {
+		return
+	}
+
+	c.RuleTree.DepthFirst(func(node *TreeNode) bool {
+		var rules []*Rule
+		for _, x := range node.Values {
+			if rule := x.(*Rule); len(rule.Head.Args) > 0 {
+				rules = append(rules, rule)
+			}
+		}
+
+		if len(rules) == 0 || c.isCalled(rules) {
+			return false
+		}
+
+		loc := rules[0].Loc()
+		for _, rule := range rules[1:] {
+			if rule.Loc().Compare(loc) < 0 {
+				loc = rule.Loc()
+			}
+		}
+
+		c.Warnings = append(c.Warnings, NewError(CompileErr, loc, "function %v unused", rules[0].Ref()))
+		return false
+	})
+
+	c.Warnings.Sort()
+}
+
+// isCalled returns true if any rule other than the function definitions in
+// rules depends on them.
+func (c *Compiler) isCalled(rules []*Rule) bool {
+	self := map[util.T]struct{}{}
+	for _, rule := range rules {
+		for node := rule; node != nil; node = node.Else {
+			self[node] = struct{}{}
+		}
+	}
+
+	for x := range self {
+		for dep := range c.Graph.Dependents(x) {
+			if _, ok := self[dep]; !ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 func (c *Compiler) checkSelfPath(loc *Location, eq func(a, b util.T) bool, a, b util.T) {
 	tr := NewGraphTraversal(c.Graph)
 	if p := util.DFSPath(tr, eq, a, b); len(p) > 0 {
@@ -1163,10 +1666,25 @@ func (c *Compiler) checkRuleConflicts() {
 
 		switch {
 		case conflicts != nil:
-			c.err(NewError(TypeErr, node.Values[0].(*Rule).Loc(), "rule %v conflicts with %v", name, conflicts))
+			err := NewError(TypeErr, node.Values[0].(*Rule).Loc(), "rule %v conflicts with %v", name, conflicts).
+				WithSuggestion("rename %v or the rules within its extent", name)
+			for _, sub := range node.Children {
+				sub.DepthFirst(func(x *TreeNode) bool {
+					for _, r := range x.Values {
+						err.WithRelated(r.(*Rule).Loc(), "conflicting rule %v", r.(*Rule).Ref())
+					}
+					return false
+				})
+			}
+			c.err(err)
 
 		case len(kinds) > 1 || len(arities) > 1 || (completeRules >= 1 && partialRules >= 1):
-			c.err(NewError(TypeErr, node.Values[0].(*Rule).Loc(), "conflicting rules %v found", name))
+			err := NewError(TypeErr, node.Values[0].(*Rule).Loc(), "conflicting rules %v found", name).
+				WithSuggestion("define all rules %v of the same kind and with the same number of arguments", name)
+			for _, r := range node.Values[1:] {
+				err.WithRelated(r.(*Rule).Loc(), "conflicting rule %v", name)
+			}
+			c.err(err)
 
 		case len(defaultRules) > 1:
 
@@ -1177,17 +1695,26 @@ func (c *Compiler) checkRuleConflicts() {
 				defaultRuleLocations.WriteString(defaultRules[i].Loc().String())
 			}
 
-			c.err(NewError(
+			err := NewError(
 				TypeErr,
 				defaultRules[0].Module.Package.Loc(),
 				"multiple default rules %s found at %s",
-				name, defaultRuleLocations.String()),
-			)
+				name, defaultRuleLocations.String()).
+				WithSuggestion("remove all but one default rule %s", name)
+			for _, r := range defaultRules {
+				err.WithRelated(r.Loc(), "default rule %s", name)
+			}
+			c.err(err)
+
+		case len(defaultRules) == 1:
+			c.checkDefaultOfMultiValueRules(defaultRules[0], rw)
 		}
 
 		return false
 	})
 
+	c.Warnings.Sort()
+
 	if c.pathExists != nil {
 		for _, err := range CheckPathConflicts(c, c.pathExists) {
 			c.err(err)
@@ -1222,6 +1749,21 @@ func (c *Compiler) checkRuleConflicts() {
 	})
 }
 
+// checkDefaultOfMultiValueRules warns about the default rule r providing a
+// single value of a document made up of many rule results, e.g., default p.q
+// next to p[x], which is easily mistaken for a default of the whole document.
+func (c *Compiler) checkDefaultOfMultiValueRules(r *Rule, rw varRewriter) {
+	for _, other := range c.GetRulesForVirtualDocument(r.Ref()) {
+		ref := other.Ref()
+		if len(ref.GroundPrefix()) >= len(r.Ref()) || c.RuleCardinality(ref.GroundPrefix()) != CardinalityMany {
+			continue
+		}
+		c.Warnings = append(c.Warnings, NewError(CompileErr, r.Loc(), "default rule %v only applies to a single value of multi-value rule %v", r.Ref(), rw(ref.CopyNonGround())).
+			WithRelated(other.Loc(), "multi-value rule %v", rw(ref.CopyNonGround())))
+		return
+	}
+}
+
 func (c *Compiler) checkUndefinedFuncs() {
 	for _, name := range c.sorted {
 		m := c.Modules[name]
@@ -1235,7 +1777,7 @@ func checkUndefinedFuncs(env *TypeEnv, x any, arity func(Ref) int, rwVars map[Va
 
 	var errs Errors
 
-	WalkExprs(x, func(expr *Expr) bool {
+	WalkNodes(x, func(expr *Expr) bool {
 		if !expr.IsCall() {
 			return false
 		}
@@ -1297,7 +1839,7 @@ func (c *Compiler) checkSafetyRuleBodies() {
 }
 
 func (c *Compiler) checkBodySafety(safe VarSet, b Body) Body {
-	reordered, unsafe := reorderBodyForSafety(c.builtins, c.GetArity, safe, b)
+	reordered, unsafe := reorderBodyForSafety(c.builtins, c.GetArity, c.outputArgs, safe, b)
 	if errs := safetyErrorSlice(unsafe, c.RewrittenVars); len(errs) > 0 {
 		for _, err := range errs {
 			c.err(err)
@@ -1333,7 +1875,8 @@ func (c *Compiler) checkSafetyRuleHeads() {
 							v = w
 						}
 						if !v.IsGenerated() {
-							c.err(NewError(UnsafeVarErr, r.Loc(), "var %v is unsafe", v))
+							c.err(NewError(UnsafeVarErr, r.Loc(), "var %v is unsafe", v).
+								WithSuggestion("assign a value to %v in the rule body", v))
 						}
 					}
 				}
@@ -1663,6 +2206,13 @@ func (c *Compiler) compile() {
 		}
 	}()
 
+	for _, err := range c.initErrors {
+		c.err(err)
+	}
+	if c.Failed() {
+		return
+	}
+
 	for _, s := range c.stages {
 		if c.evalMode == EvalModeIR {
 			switch s.name {
@@ -1726,20 +2276,27 @@ func (c *Compiler) init() {
 	c.builtins = make(map[string]*Builtin, len(c.capabilities.Builtins)+len(c.customBuiltins))
 
 	for _, bi := range c.capabilities.Builtins {
+		if c.capabilities.DeniesBuiltin(bi) {
+			continue
+		}
 		c.builtins[bi.Name] = bi
 		if bi.IsDeprecated() {
 			c.deprecatedBuiltinsMap[bi.Name] = struct{}{}
 		}
 	}
 
+	// Errors cannot be reported here, as init() is called outside of the
+	// compilation stages: they are reported when compiling.
 	maps.Copy(c.builtins, c.customBuiltins)
+	c.initErrors = c.overrideBuiltinDecls()
+	c.initErrors = append(c.initErrors, c.initOutputArgs()...)
 
 	// Load the global input schema if one was provided.
 	if c.schemaSet != nil {
 		if schema := c.schemaSet.Get(SchemaRootRef); schema != nil {
 			tpe, err := loadSchema(schema, c.capabilities.AllowNet)
 			if err != nil {
-				c.err(NewError(TypeErr, nil, err.Error())) //nolint:govet
+				c.initErrors = append(c.initErrors, NewError(TypeErr, nil, err.Error())) //nolint:govet
 			} else {
 				c.inputType = tpe
 			}
@@ -1754,6 +2311,74 @@ func (c *Compiler) init() {
 	c.initialized = true
 }
 
+// overrideBuiltinDecls replaces the type declarations of the built-in
+// functions registered via WithBuiltinOverride. The built-ins are copied so
+// that the capabilities and the global registry are left untouched. Overrides
+// must declare the same number of arguments as the built-in function.
+func (c *Compiler) overrideBuiltinDecls() Errors {
+	var errs Errors
+	for _, name := range util.KeysSorted(c.builtinOverrides) {
+		bi, ok := c.builtins[name]
+		if !ok {
+			errs = append(errs, NewError(CompileErr, nil, "cannot override type declaration of unknown built-in function %v", name))
+			continue
+		}
+		decl := c.builtinOverrides[name]
+		if exp, act := bi.Decl.FuncArgs(), decl.FuncArgs(); len(exp.Args) != len(act.Args) || (exp.Variadic == nil) != (act.Variadic == nil) {
+			errs = append(errs, NewError(CompileErr, nil, "cannot override type declaration of built-in function %v: arguments %v do not match %v", name, act, exp))
+			continue
+		}
+		cpy := *bi
+		cpy.Decl = decl
+		c.builtins[name] = &cpy
+	}
+	return errs
+}
+
+// initOutputArgs records the output arguments of the built-in functions that
+// bind their arguments, so that the safety checks need not look up every
+// called built-in function. Output arguments must be distinct positions of
+// declared arguments.
+func (c *Compiler) initOutputArgs() Errors {
+	var errs Errors
+	c.outputArgs = nil
+	for _, name := range util.KeysSorted(c.builtins) {
+		bi := c.builtins[name]
+		if len(bi.OutputArgs) == 0 {
+			continue
+		}
+		if err := checkOutputArgs(bi); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if c.outputArgs == nil {
+			c.outputArgs = map[string][]int{}
+		}
+		c.outputArgs[name] = bi.OutputArgs
+	}
+	return errs
+}
+
+// checkOutputArgs returns an error if the output arguments of bi do not refer
+// to distinct arguments of its declaration.
+func checkOutputArgs(bi *Builtin) *Error {
+	var n int
+	if bi.Decl != nil {
+		n = len(bi.Decl.FuncArgs().Args)
+	}
+	seen := make(map[int]struct{}, len(bi.OutputArgs))
+	for _, i := range bi.OutputArgs {
+		if i < 0 || i >= n {
+			return NewError(CompileErr, nil, "built-in function %v: output argument %d out of range, function has %d arguments", bi.Name, i, n)
+		}
+		if _, ok := seen[i]; ok {
+			return NewError(CompileErr, nil, "built-in function %v: duplicate output argument %d", bi.Name, i)
+		}
+		seen[i] = struct{}{}
+	}
+	return nil
+}
+
 func (c *Compiler) err(err *Error) {
 	if c.maxErrs > 0 && len(c.Errors) >= c.maxErrs {
 		c.Errors = append(c.Errors, errLimitReached)
@@ -1904,6 +2529,12 @@ func (c *Compiler) resolveAllRefs() {
 
 		globals := getGlobals(mod.Package, ruleExports, mod.Imports)
 
+		if c.strict {
+			for _, err := range checkShadowedGlobals(mod) {
+				c.err(err)
+			}
+		}
+
 		WalkRules(mod, func(rule *Rule) bool {
 			err := resolveRefsInRule(globals, rule)
 			if err != nil {
@@ -1930,29 +2561,187 @@ func (c *Compiler) resolveAllRefs() {
 
 	if c.moduleLoader != nil {
 
-		parsed, err := c.moduleLoader(c.Modules)
+		n, err := c.loadModules()
 		if err != nil {
 			c.err(NewError(CompileErr, nil, err.Error())) //nolint:govet
 			return
 		}
 
-		if len(parsed) == 0 {
+		// Stop when no module is left to load, including when all of the loaded
+		// modules are excluded.
+		if n == 0 {
 			return
 		}
 
-		for id, module := range parsed {
-			c.Modules[id] = module.Copy()
-			c.sorted = append(c.sorted, id)
-			if c.parsedModules != nil {
-				c.parsedModules[id] = module
-			}
+		// The loaded modules may contain include directives themselves.
+		c.resolveIncludes()
+		if c.Failed() {
+			return
 		}
 
-		sort.Strings(c.sorted)
 		c.resolveAllRefs()
 	}
 }
 
+// loadModules invokes the ModuleLoader and adds the modules it returns to the
+// compiler. It returns the number of modules added.
+func (c *Compiler) loadModules() (int, error) {
+	parsed, err := c.moduleLoader(c.Modules)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for id, module := range parsed {
+		if c.moduleFilter != nil && !c.moduleFilter(id, module) {
+			continue
+		}
+		n++
+		c.Modules[id] = module.Copy()
+		c.sorted = append(c.sorted, id)
+		if c.parsedModules != nil {
+			c.parsedModules[id] = module
+		}
+	}
+
+	sort.Strings(c.sorted)
+
+	return n, nil
+}
+
+// resolveIncludes inlines the rules of the modules named by include directives,
+// e.g., import include["helpers.rego"], into the including modules. Included
+// modules are looked up by ID, relative to the directory of the including
+// module first. Missing modules are requested from the ModuleLoader, if one is
+// set. The inlined rules keep their locations, so errors, traces and coverage
+// reports point at the included files.
+func (c *Compiler) resolveIncludes() {
+	if !slices.ContainsFunc(c.sorted, func(name string) bool { return hasIncludes(c.Modules[name]) }) {
+		return
+	}
+
+	if !c.capabilities.ContainsFeature(FeatureIncludeDirective) {
+		for _, name := range c.sorted {
+			for _, imp := range c.Modules[name].Imports {
+				if isInclude(imp) {
+					c.err(NewError(CompileErr, imp.Location, "include directives are not supported"))
+				}
+			}
+		}
+		return
+	}
+
+	c.includes = true
+
+	if c.moduleLoader != nil {
+		for c.hasMissingIncludes() {
+			n, err := c.loadModules()
+			if err != nil {
+				c.err(NewError(CompileErr, nil, err.Error())) //nolint:govet
+				return
+			}
+			if n == 0 {
+				break
+			}
+		}
+	}
+
+	done := make(map[string]struct{}, len(c.sorted))
+	for _, name := range c.sorted {
+		c.includeModules(name, done, nil)
+	}
+}
+
+func (c *Compiler) hasMissingIncludes() bool {
+	for _, name := range c.sorted {
+		for _, imp := range c.Modules[name].Imports {
+			if isInclude(imp) && c.includeTarget(name, imp) == "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// includeTarget returns the ID of the module included by imp in the module
+// identified by name, or an empty string if the module is missing.
+func (c *Compiler) includeTarget(name string, imp *Import) string {
+	file := string(imp.Path.Value.(Ref)[1].Value.(String))
+	if !filepath.IsAbs(file) {
+		if id := filepath.Join(filepath.Dir(name), file); c.Modules[id] != nil {
+			return id
+		}
+	}
+	if c.Modules[file] != nil {
+		return file
+	}
+	return ""
+}
+
+func (c *Compiler) includeModules(name string, done map[string]struct{}, stack []string) {
+	if _, ok := done[name]; ok {
+		return
+	}
+
+	mod := c.Modules[name]
+	stack = append(stack, name)
+
+	imports := make([]*Import, 0, len(mod.Imports))
+	var includes []*Import
+	for _, imp := range mod.Imports {
+		if isInclude(imp) {
+			includes = append(includes, imp)
+		} else {
+			imports = append(imports, imp)
+		}
+	}
+
+	for _, imp := range includes {
+		target := c.includeTarget(name, imp)
+		if target == "" {
+			c.err(NewError(CompileErr, imp.Location, "%v: module not found", imp.Path))
+			continue
+		}
+
+		if slices.Contains(stack, target) {
+			c.err(NewError(CompileErr, imp.Location, "%v: include cycle: %v", imp.Path, strings.Join(append(stack, target), " -> ")))
+			continue
+		}
+
+		// Nested includes are inlined into the included module first.
+		c.includeModules(target, done, stack)
+
+		included := c.Modules[target]
+		for _, other := range included.Imports {
+			if !slices.ContainsFunc(imports, other.Equal) {
+				imports = append(imports, other.Copy())
+			}
+		}
+
+		for _, rule := range included.Rules {
+			cpy := rule.Copy()
+			for r := cpy; r != nil; r = r.Else {
+				r.Module = mod
+			}
+			req := c.requiredFor(cpy)
+			req.Features = append(req.Features, FeatureIncludeDirective)
+			mod.Rules = append(mod.Rules, cpy)
+			mod.Annotations = append(mod.Annotations, cpy.Annotations...)
+		}
+	}
+
+	mod.Imports = imports
+	done[name] = struct{}{}
+}
+
+func hasIncludes(mod *Module) bool {
+	return slices.ContainsFunc(mod.Imports, isInclude)
+}
+
+func isInclude(imp *Import) bool {
+	return IncludeRootDocument.Equal(imp.Path.Value.(Ref)[0])
+}
+
 func (c *Compiler) removeImports() {
 	c.imports = make(map[string][]*Import, len(c.Modules))
 	for name := range c.Modules {
@@ -2056,8 +2845,11 @@ func (c *Compiler) rewritePrintCalls() {
 	var modified bool
 	if !c.enablePrintStatements {
 		for _, name := range c.sorted {
-			if erasePrintCalls(c.Modules[name]) {
-				modified = true
+			for _, rule := range c.Modules[name].Rules {
+				if erasePrintCalls(rule) {
+					c.requiredFor(rule).addBuiltinSorted(Print)
+					modified = true
+				}
 			}
 		}
 	} else {
@@ -2067,8 +2859,9 @@ func (c *Compiler) rewritePrintCalls() {
 				safe := r.Head.Args.Vars()
 				safe.Update(ReservedVars)
 				vis := func(b Body) bool {
-					modrec, errs := rewritePrintCalls(c.localvargen, c.GetArity, safe, b)
+					modrec, errs := rewritePrintCalls(c.localvargen, c.GetArity, c.outputArgs, safe, b)
 					if modrec {
+						c.requiredFor(r).addBuiltinSorted(Print)
 						modified = true
 					}
 					for _, err := range errs {
@@ -2076,8 +2869,8 @@ func (c *Compiler) rewritePrintCalls() {
 					}
 					return false
 				}
-				WalkBodies(r.Head, vis)
-				WalkBodies(r.Body, vis)
+				WalkNodes(r.Head, vis)
+				WalkNodes(r.Body, vis)
 				return false
 			})
 		}
@@ -2092,7 +2885,7 @@ func (c *Compiler) rewritePrintCalls() {
 // print().
 func checkVoidCalls(env *TypeEnv, x any) Errors {
 	var errs Errors
-	WalkTerms(x, func(x *Term) bool {
+	WalkNodes(x, func(x *Term) bool {
 		if call, ok := x.Value.(Call); ok {
 			if tpe, ok := env.Get(call[0]).(*types.Function); ok && tpe.Result() == nil {
 				errs = append(errs, NewError(TypeErr, x.Loc(), "%v used as value", call))
@@ -2115,7 +2908,7 @@ func checkVoidCalls(env *TypeEnv, x any) Errors {
 // The expression would be rewritten to:
 //
 //	print({__local0__ | __local0__ = "the value of x is:"}, {__local1__ | __local1__ = input.x})
-func rewritePrintCalls(gen *localVarGenerator, getArity func(Ref) int, globals VarSet, body Body) (bool, Errors) {
+func rewritePrintCalls(gen *localVarGenerator, getArity func(Ref) int, outputArgs map[string][]int, globals VarSet, body Body) (bool, Errors) {
 
 	var errs Errors
 	var modified bool
@@ -2124,21 +2917,21 @@ func rewritePrintCalls(gen *localVarGenerator, getArity func(Ref) int, globals V
 	// those bodies only close over variables that are safe.
 	for i := range body {
 		if ContainsClosures(body[i]) {
-			safe := outputVarsForBody(body[:i], getArity, globals)
+			safe := outputVarsForBody(body[:i], getArity, outputArgs, globals)
 			safe.Update(globals)
 			WalkClosures(body[i], func(x any) bool {
 				var modrec bool
 				var errsrec Errors
 				switch x := x.(type) {
 				case *SetComprehension:
-					modrec, errsrec = rewritePrintCalls(gen, getArity, safe, x.Body)
+					modrec, errsrec = rewritePrintCalls(gen, getArity, outputArgs, safe, x.Body)
 				case *ArrayComprehension:
-					modrec, errsrec = rewritePrintCalls(gen, getArity, safe, x.Body)
+					modrec, errsrec = rewritePrintCalls(gen, getArity, outputArgs, safe, x.Body)
 				case *ObjectComprehension:
-					modrec, errsrec = rewritePrintCalls(gen, getArity, safe, x.Body)
+					modrec, errsrec = rewritePrintCalls(gen, getArity, outputArgs, safe, x.Body)
 				case *Every:
 					safe.Update(x.KeyValueVars())
-					modrec, errsrec = rewritePrintCalls(gen, getArity, safe, x.Body)
+					modrec, errsrec = rewritePrintCalls(gen, getArity, outputArgs, safe, x.Body)
 				}
 				if modrec {
 					modified = true
@@ -2161,7 +2954,7 @@ func rewritePrintCalls(gen *localVarGenerator, getArity func(Ref) int, globals V
 		modified = true
 
 		var errs Errors
-		safe := outputVarsForBody(body[:i], getArity, globals)
+		safe := outputVarsForBody(body[:i], getArity, outputArgs, globals)
 		safe.Update(globals)
 		args := body[i].Operands()
 
@@ -2252,7 +3045,7 @@ func erasePrintCallsInBody(x Body) (bool, Body) {
 
 func containsPrintCall(x any) bool {
 	var found bool
-	WalkExprs(x, func(expr *Expr) bool {
+	WalkNodes(x, func(expr *Expr) bool {
 		if !found {
 			if isPrintCall(expr) {
 				found = true
@@ -2371,7 +3164,7 @@ func (c *Compiler) parseMetadataBlocks() {
 	regoMetadataCalled := false
 	for _, name := range c.sorted {
 		mod := c.Modules[name]
-		WalkExprs(mod, func(expr *Expr) bool {
+		WalkNodes(mod, func(expr *Expr) bool {
 			if isRegoMetadataChainCall(expr) || isRegoMetadataRuleCall(expr) {
 				regoMetadataCalled = true
 			}
@@ -2400,6 +3193,24 @@ func (c *Compiler) parseMetadataBlocks() {
 			}
 		}
 	}
+
+	if hasAnnotationSchemas() {
+		for _, name := range c.sorted {
+			mod := c.Modules[name]
+
+			annotations := mod.Annotations
+			if len(annotations) == 0 {
+				// Errors are reported when annotations are processed.
+				annotations, _ = parseAnnotations(mod.Comments)
+			}
+
+			for _, a := range annotations {
+				for _, err := range validateCustomAnnotations(a) {
+					c.err(err)
+				}
+			}
+		}
+	}
 }
 
 func (c *Compiler) rewriteRegoMetadataCalls() {
@@ -2409,86 +3220,119 @@ func (c *Compiler) rewriteRegoMetadataCalls() {
 	_, ruleFuncAllowed := c.builtins[RegoMetadataRule.Name]
 
 	for _, name := range c.sorted {
-		mod := c.Modules[name]
+		for _, err := range rewriteRegoMetadataCallsInModule(c.annotationSet, c.Modules[name], eqFactory, chainFuncAllowed, ruleFuncAllowed) {
+			c.err(err)
+		}
+	}
+}
 
-		WalkRules(mod, func(rule *Rule) bool {
-			var firstChainCall *Expr
-			var firstRuleCall *Expr
+// RewriteRegoMetadataCalls rewrites the rego.metadata.chain and
+// rego.metadata.rule calls in the rules of mod like the compiler does: the
+// metadata of each calling rule is assigned to a generated variable at the
+// start of the rule body, and the calls are replaced by that variable. This
+// allows callers that construct modules programmatically to inject the
+// metadata identically to the compiler.
+//
+// Like in the compiler, only calls that are expressions of their own are
+// rewritten, e.g., `rego.metadata.rule(x)`, but not `x := rego.metadata.rule()`,
+// which the compiler rewrites into the former in an earlier stage.
+//
+// The metadata is looked up in as. If as is nil, it is built from the
+// annotations of mod, which must have been parsed, e.g., by parsing mod with
+// ParserOptions.ProcessAnnotation set.
+func RewriteRegoMetadataCalls(as *AnnotationSet, mod *Module) Errors {
+	if as == nil {
+		var errs Errors
+		as, errs = BuildAnnotationSet([]*Module{mod})
+		if len(errs) > 0 {
+			return errs
+		}
+	}
 
-			WalkExprs(rule, func(expr *Expr) bool {
-				if chainFuncAllowed && firstChainCall == nil && isRegoMetadataChainCall(expr) {
-					firstChainCall = expr
-				} else if ruleFuncAllowed && firstRuleCall == nil && isRegoMetadataRuleCall(expr) {
-					firstRuleCall = expr
-				}
-				return firstChainCall != nil && firstRuleCall != nil
-			})
+	eqFactory := newEqualityFactory(newLocalVarGenerator("", mod))
+	return rewriteRegoMetadataCallsInModule(as, mod, eqFactory, true, true)
+}
 
-			chainCalled := firstChainCall != nil
-			ruleCalled := firstRuleCall != nil
+func rewriteRegoMetadataCallsInModule(as *AnnotationSet, mod *Module, eqFactory *equalityFactory, chainFuncAllowed, ruleFuncAllowed bool) Errors {
+	var errs Errors
 
-			if chainCalled || ruleCalled {
-				body := make(Body, 0, len(rule.Body)+2)
+	WalkRules(mod, func(rule *Rule) bool {
+		var firstChainCall *Expr
+		var firstRuleCall *Expr
 
-				var metadataChainVar Var
-				if chainCalled {
-					// Create and inject metadata chain for rule
+		WalkNodes(rule, func(expr *Expr) bool {
+			if chainFuncAllowed && firstChainCall == nil && isRegoMetadataChainCall(expr) {
+				firstChainCall = expr
+			} else if ruleFuncAllowed && firstRuleCall == nil && isRegoMetadataRuleCall(expr) {
+				firstRuleCall = expr
+			}
+			return firstChainCall != nil && firstRuleCall != nil
+		})
 
-					chain, err := createMetadataChain(c.annotationSet.Chain(rule))
-					if err != nil {
-						c.err(err)
-						return false
-					}
+		chainCalled := firstChainCall != nil
+		ruleCalled := firstRuleCall != nil
 
-					chain.Location = firstChainCall.Location
-					eq := eqFactory.Generate(chain)
-					metadataChainVar = eq.Operands()[0].Value.(Var)
-					body.Append(eq)
-				}
+		if chainCalled || ruleCalled {
+			body := make(Body, 0, len(rule.Body)+2)
 
-				var metadataRuleVar Var
-				if ruleCalled {
-					// Create and inject metadata for rule
+			var metadataChainVar Var
+			if chainCalled {
+				// Create and inject metadata chain for rule
 
-					var metadataRuleTerm *Term
+				chain, err := createMetadataChain(as.Chain(rule))
+				if err != nil {
+					errs = append(errs, err)
+					return false
+				}
 
-					a := getPrimaryRuleAnnotations(c.annotationSet, rule)
-					if a != nil {
-						annotObj, err := a.toObject()
-						if err != nil {
-							c.err(err)
-							return false
-						}
-						metadataRuleTerm = NewTerm(*annotObj)
-					} else {
-						// If rule has no annotations, assign an empty object
-						metadataRuleTerm = ObjectTerm()
-					}
+				chain.Location = firstChainCall.Location
+				eq := eqFactory.Generate(chain)
+				metadataChainVar = eq.Operands()[0].Value.(Var)
+				body.Append(eq)
+			}
 
-					metadataRuleTerm.Location = firstRuleCall.Location
-					eq := eqFactory.Generate(metadataRuleTerm)
-					metadataRuleVar = eq.Operands()[0].Value.(Var)
-					body.Append(eq)
-				}
+			var metadataRuleVar Var
+			if ruleCalled {
+				// Create and inject metadata for rule
 
-				for _, expr := range rule.Body {
-					body.Append(expr)
-				}
-				rule.Body = body
+				var metadataRuleTerm *Term
 
-				vis := func(b Body) bool {
-					for _, err := range rewriteRegoMetadataCalls(&metadataChainVar, &metadataRuleVar, b, &c.RewrittenVars) {
-						c.err(err)
+				a := getPrimaryRuleAnnotations(as, rule)
+				if a != nil {
+					annotObj, err := a.toObject()
+					if err != nil {
+						errs = append(errs, err)
+						return false
 					}
-					return false
+					metadataRuleTerm = NewTerm(*annotObj)
+				} else {
+					// If rule has no annotations, assign an empty object
+					metadataRuleTerm = ObjectTerm()
 				}
-				WalkBodies(rule.Head, vis)
-				WalkBodies(rule.Body, vis)
+
+				metadataRuleTerm.Location = firstRuleCall.Location
+				eq := eqFactory.Generate(metadataRuleTerm)
+				metadataRuleVar = eq.Operands()[0].Value.(Var)
+				body.Append(eq)
 			}
 
-			return false
-		})
-	}
+			for _, expr := range rule.Body {
+				body.Append(expr)
+			}
+			rule.Body = body
+
+			vis := func(b Body) bool {
+				errs = append(errs, rewriteRegoMetadataCalls(&metadataChainVar, &metadataRuleVar, b)...)
+				return false
+			}
+			WalkNodes(rule.Head, vis)
+			WalkNodes(rule.Body, vis)
+		}
+
+		return false
+	})
+
+	return errs
 }
 
 func getPrimaryRuleAnnotations(as *AnnotationSet, rule *Rule) *Annotations {
@@ -2506,19 +3350,19 @@ func getPrimaryRuleAnnotations(as *AnnotationSet, rule *Rule) *Annotations {
 	return annots[0]
 }
 
-func rewriteRegoMetadataCalls(metadataChainVar *Var, metadataRuleVar *Var, body Body, rewrittenVars *map[Var]Var) Errors {
+func rewriteRegoMetadataCalls(metadataChainVar *Var, metadataRuleVar *Var, body Body) Errors {
 	var errs Errors
 
 	WalkClosures(body, func(x any) bool {
 		switch x := x.(type) {
 		case *ArrayComprehension:
-			errs = rewriteRegoMetadataCalls(metadataChainVar, metadataRuleVar, x.Body, rewrittenVars)
+			errs = rewriteRegoMetadataCalls(metadataChainVar, metadataRuleVar, x.Body)
 		case *SetComprehension:
-			errs = rewriteRegoMetadataCalls(metadataChainVar, metadataRuleVar, x.Body, rewrittenVars)
+			errs = rewriteRegoMetadataCalls(metadataChainVar, metadataRuleVar, x.Body)
 		case *ObjectComprehension:
-			errs = rewriteRegoMetadataCalls(metadataChainVar, metadataRuleVar, x.Body, rewrittenVars)
+			errs = rewriteRegoMetadataCalls(metadataChainVar, metadataRuleVar, x.Body)
 		case *Every:
-			errs = rewriteRegoMetadataCalls(metadataChainVar, metadataRuleVar, x.Body, rewrittenVars)
+			errs = rewriteRegoMetadataCalls(metadataChainVar, metadataRuleVar, x.Body)
 		}
 		return true
 	})
@@ -2605,6 +3449,7 @@ func (c *Compiler) rewriteLocalVars() {
 			unusedArgs := args.Vars()
 
 			c.rewriteLocalArgVars(gen, argsStack, rule)
+			top := rule
 
 			// Rewrite local vars in each else-branch of the rule.
 			// Note: this is done instead of a walk so that we can capture any unused function arguments
@@ -2613,6 +3458,7 @@ func (c *Compiler) rewriteLocalVars() {
 				stack, errs := c.rewriteLocalVarsInRule(rule, unusedArgs, argsStack, gen)
 				if stack.assignment {
 					assignment = true
+					c.requiredFor(top).addBuiltinSorted(Assign)
 				}
 
 				for arg := range unusedArgs {
@@ -3001,6 +3847,17 @@ func (qc *queryCompiler) Compile(query Body) (Body, error) {
 		return nil, Errors{NewError(CompileErr, nil, "empty query cannot be compiled")}
 	}
 
+	var key string
+	if qc.compiler.queryCache != nil && len(qc.after) == 0 {
+		key = qc.cacheKey(query)
+		if entry, ok := qc.compiler.queryCache.get(key); ok {
+			qc.typeEnv = entry.typeEnv
+			qc.rewritten = entry.rewritten
+			qc.comprehensionIndices = entry.comprehensionIndices
+			return entry.query, nil
+		}
+	}
+
 	query = query.Copy()
 
 	stages := []queryStage{
@@ -3039,6 +3896,16 @@ func (qc *queryCompiler) Compile(query Body) (Body, error) {
 		}
 	}
 
+	if key != "" {
+		qc.compiler.queryCache.put(&queryCacheEntry{
+			key:                  key,
+			query:                query,
+			typeEnv:              qc.typeEnv,
+			rewritten:            qc.rewritten,
+			comprehensionIndices: qc.comprehensionIndices,
+		})
+	}
+
 	return query, nil
 }
 
@@ -3136,7 +4003,7 @@ func (qc *queryCompiler) rewritePrintCalls(_ *QueryContext, body Body) (Body, er
 		return cpy, nil
 	}
 	gen := newLocalVarGenerator("q", body)
-	if _, errs := rewritePrintCalls(gen, qc.compiler.GetArity, ReservedVars, body); len(errs) > 0 {
+	if _, errs := rewritePrintCalls(gen, qc.compiler.GetArity, qc.compiler.outputArgs, ReservedVars, body); len(errs) > 0 {
 		return nil, errs
 	}
 	return body, nil
@@ -3158,7 +4025,7 @@ func (qc *queryCompiler) checkUndefinedFuncs(_ *QueryContext, body Body) (Body,
 
 func (qc *queryCompiler) checkSafety(_ *QueryContext, body Body) (Body, error) {
 	safe := ReservedVars.Copy()
-	reordered, unsafe := reorderBodyForSafety(qc.compiler.builtins, qc.compiler.GetArity, safe, body)
+	reordered, unsafe := reorderBodyForSafety(qc.compiler.builtins, qc.compiler.GetArity, qc.compiler.outputArgs, safe, body)
 	if errs := safetyErrorSlice(unsafe, qc.RewrittenVars()); len(errs) > 0 {
 		return nil, errs
 	}
@@ -3216,7 +4083,7 @@ func (qc *queryCompiler) rewriteWithModifiers(_ *QueryContext, body Body) (Body,
 func (qc *queryCompiler) buildComprehensionIndices(_ *QueryContext, body Body) (Body, error) {
 	// NOTE(tsandall): The query compiler does not have a metrics object so we
 	// cannot record index metrics currently.
-	_ = buildComprehensionIndices(qc.compiler.debug, qc.compiler.GetArity, ReservedVars, qc.RewrittenVars(), body, qc.comprehensionIndices)
+	_ = buildComprehensionIndices(qc.compiler.debug, qc.compiler.GetArity, qc.compiler.outputArgs, ReservedVars, qc.RewrittenVars(), body, qc.comprehensionIndices)
 	return body, nil
 }
 
@@ -3237,12 +4104,12 @@ func (ci *ComprehensionIndex) String() string {
 	return fmt.Sprintf("<keys: %v>", NewArray(ci.Keys...))
 }
 
-func buildComprehensionIndices(dbg debug.Debug, arity func(Ref) int, candidates VarSet, rwVars map[Var]Var, node Body, result map[*Term]*ComprehensionIndex) uint64 {
+func buildComprehensionIndices(dbg debug.Debug, arity func(Ref) int, outputArgs map[string][]int, candidates VarSet, rwVars map[Var]Var, node Body, result map[*Term]*ComprehensionIndex) uint64 {
 	var n uint64
 	cpy := candidates.Copy()
-	WalkBodies(node, func(b Body) bool {
+	WalkNodes(node, func(b Body) bool {
 		for _, expr := range b {
-			index := getComprehensionIndex(dbg, arity, cpy, rwVars, expr)
+			index := getComprehensionIndex(dbg, arity, outputArgs, cpy, rwVars, expr)
 			if index != nil {
 				result[index.Term] = index
 				n++
@@ -3256,7 +4123,7 @@ func buildComprehensionIndices(dbg debug.Debug, arity func(Ref) int, candidates
 	return n
 }
 
-func getComprehensionIndex(dbg debug.Debug, arity func(Ref) int, candidates VarSet, rwVars map[Var]Var, expr *Expr) *ComprehensionIndex {
+func getComprehensionIndex(dbg debug.Debug, arity func(Ref) int, outputArgs map[string][]int, candidates VarSet, rwVars map[Var]Var, expr *Expr) *ComprehensionIndex {
 
 	// Ignore everything except <var> = <comprehension> expressions. Extract
 	// the comprehension term from the expression.
@@ -3310,7 +4177,7 @@ func getComprehensionIndex(dbg debug.Debug, arity func(Ref) int, candidates VarS
 		body = x.Body
 	}
 
-	outputs := outputVarsForBody(body, arity, ReservedVars)
+	outputs := outputVarsForBody(body, arity, outputArgs, ReservedVars)
 	unsafe := body.Vars(SafetyCheckVisitorParams).Diff(outputs).Diff(ReservedVars)
 
 	if len(unsafe) > 0 {
@@ -3720,6 +4587,7 @@ type Graph struct {
 	adj    map[util.T]map[util.T]struct{}
 	radj   map[util.T]map[util.T]struct{}
 	nodes  map[util.T]struct{}
+	order  map[util.T]int // position of nodes, for breaking ties when sorting
 	sorted []util.T
 }
 
@@ -3731,9 +4599,20 @@ func NewGraph(modules map[string]*Module, list func(Ref) []*Rule) *Graph {
 		adj:    map[util.T]map[util.T]struct{}{},
 		radj:   map[util.T]map[util.T]struct{}{},
 		nodes:  map[util.T]struct{}{},
+		order:  map[util.T]int{},
 		sorted: nil,
 	}
 
+	// Number the rules by module name and position first, so that the order of
+	// the nodes does not depend on the order dependencies are found in.
+	names := util.KeysSorted(modules)
+	for _, name := range names {
+		WalkRules(modules[name], func(a *Rule) bool {
+			graph.addNode(a)
+			return false
+		})
+	}
+
 	// Create visitor to walk a rule AST and add edges to the rule graph for
 	// each dependency.
 	vis := func(a *Rule) *GenericVisitor {
@@ -3758,10 +4637,9 @@ func NewGraph(modules map[string]*Module, list func(Ref) []*Rule) *Graph {
 		})
 	}
 
-	// Walk over all rules, add them to graph, and build adjacency lists.
-	for _, module := range modules {
-		WalkRules(module, func(a *Rule) bool {
-			graph.addNode(a)
+	// Walk over all rules and build adjacency lists.
+	for _, name := range names {
+		WalkRules(modules[name], func(a *Rule) bool {
 			vis(a).Walk(a)
 			return false
 		})
@@ -3781,7 +4659,9 @@ func (g *Graph) Dependents(x util.T) map[util.T]struct{} {
 }
 
 // Sort returns a slice of rules sorted by dependencies. If a cycle is found,
-// ok is set to false.
+// ok is set to false. The order is deterministic: ties are broken by the
+// names of the modules the rules are defined in, and by their positions in
+// the modules.
 func (g *Graph) Sort() (sorted []util.T, ok bool) {
 	if g.sorted != nil {
 		return g.sorted, true
@@ -3789,12 +4669,12 @@ func (g *Graph) Sort() (sorted []util.T, ok bool) {
 
 	sorter := &graphSort{
 		sorted: make([]util.T, 0, len(g.nodes)),
-		deps:   g.Dependencies,
+		deps:   g.sortedDependencies,
 		marked: map[util.T]struct{}{},
 		temp:   map[util.T]struct{}{},
 	}
 
-	for node := range g.nodes {
+	for _, node := range g.inOrder(g.nodes) {
 		if !sorter.Visit(node) {
 			return nil, false
 		}
@@ -3832,12 +4712,32 @@ func (g *Graph) addDependency(u util.T, v util.T) {
 }
 
 func (g *Graph) addNode(n util.T) {
+	if _, ok := g.nodes[n]; !ok {
+		g.order[n] = len(g.order)
+	}
 	g.nodes[n] = struct{}{}
 }
 
+// sortedDependencies returns the rules that x depends on, in node order.
+func (g *Graph) sortedDependencies(x util.T) []util.T {
+	return g.inOrder(g.adj[x])
+}
+
+// inOrder returns the nodes of set in the order they were added to g.
+func (g *Graph) inOrder(set map[util.T]struct{}) []util.T {
+	nodes := make([]util.T, 0, len(set))
+	for node := range set {
+		nodes = append(nodes, node)
+	}
+	slices.SortFunc(nodes, func(a, b util.T) int {
+		return g.order[a] - g.order[b]
+	})
+	return nodes
+}
+
 type graphSort struct {
 	sorted []util.T
-	deps   func(util.T) map[util.T]struct{}
+	deps   func(util.T) []util.T
 	marked map[util.T]struct{}
 	temp   map[util.T]struct{}
 }
@@ -3855,7 +4755,7 @@ func (sort *graphSort) Visit(node util.T) (ok bool) {
 		return true
 	}
 	sort.temp[node] = struct{}{}
-	for other := range sort.deps(node) {
+	for _, other := range sort.deps(node) {
 		if !sort.Visit(other) {
 			return false
 		}
@@ -3976,7 +4876,7 @@ func (vs unsafeVars) Slice() (result []unsafePair) {
 //
 // If the body cannot be reordered to ensure safety, the second return value
 // contains a mapping of expressions to unsafe variables in those expressions.
-func reorderBodyForSafety(builtins map[string]*Builtin, arity func(Ref) int, globals VarSet, body Body) (Body, unsafeVars) {
+func reorderBodyForSafety(builtins map[string]*Builtin, arity func(Ref) int, outputArgs map[string][]int, globals VarSet, body Body) (Body, unsafeVars) {
 	vis := varVisitorPool.Get().WithParams(SafetyCheckVisitorParams)
 	vis.WalkBody(body)
 
@@ -4006,13 +4906,13 @@ func reorderBodyForSafety(builtins map[string]*Builtin, arity func(Ref) int, glo
 				continue
 			}
 
-			ovs := outputVarsForExpr(e, arity, safe, output)
+			ovs := outputVarsForExpr(e, arity, outputArgs, safe, output)
 
 			// check closures: is this expression closing over variables that
 			// haven't been made safe by what's already included in `reordered`?
 			vs := unsafeVarsInClosures(e)
 			cv := vs.Intersect(bodyVars).Diff(globals)
-			ob := outputVarsForBody(reordered, arity, safe)
+			ob := outputVarsForBody(reordered, arity, outputArgs, safe)
 
 			if cv.DiffCount(ob) > 0 {
 				uv := cv.Diff(ob)
@@ -4045,8 +4945,9 @@ func reorderBodyForSafety(builtins map[string]*Builtin, arity func(Ref) int, glo
 	// be closed over.
 	g := globals.Copy()
 	xform := &bodySafetyTransformer{
-		builtins: builtins,
-		arity:    arity,
+		builtins:   builtins,
+		arity:      arity,
+		outputArgs: outputArgs,
 	}
 	gvis := &GenericVisitor{}
 	for i, e := range reordered {
@@ -4066,11 +4967,12 @@ func reorderBodyForSafety(builtins map[string]*Builtin, arity func(Ref) int, glo
 }
 
 type bodySafetyTransformer struct {
-	builtins map[string]*Builtin
-	arity    func(Ref) int
-	current  *Expr
-	globals  VarSet
-	unsafe   unsafeVars
+	builtins   map[string]*Builtin
+	arity      func(Ref) int
+	outputArgs map[string][]int
+	current    *Expr
+	globals    VarSet
+	unsafe     unsafeVars
 }
 
 func (xform *bodySafetyTransformer) Visit(x any) bool {
@@ -4126,7 +5028,7 @@ func (xform *bodySafetyTransformer) reorderComprehensionSafety(tv VarSet, body B
 		}
 	}
 
-	r, u := reorderBodyForSafety(xform.builtins, xform.arity, xform.globals, body)
+	r, u := reorderBodyForSafety(xform.builtins, xform.arity, xform.outputArgs, xform.globals, body)
 	if len(u) == 0 {
 		return r
 	}
@@ -4169,14 +5071,14 @@ func unsafeVarsInClosures(e *Expr) VarSet {
 // the given body. For safety checks this means that they would be
 // made safe by the body.
 func OutputVarsFromBody(c *Compiler, body Body, safe VarSet) VarSet {
-	return outputVarsForBody(body, c.GetArity, safe)
+	return outputVarsForBody(body, c.GetArity, c.outputArgs, safe)
 }
 
-func outputVarsForBody(body Body, arity func(Ref) int, safe VarSet) VarSet {
+func outputVarsForBody(body Body, arity func(Ref) int, outputArgs map[string][]int, safe VarSet) VarSet {
 	o := safe.Copy()
 	output := VarSet{}
 	for _, e := range body {
-		o.Update(outputVarsForExpr(e, arity, o, output))
+		o.Update(outputVarsForExpr(e, arity, outputArgs, o, output))
 	}
 	return o.Diff(safe)
 }
@@ -4185,10 +5087,10 @@ func outputVarsForBody(body Body, arity func(Ref) int, safe VarSet) VarSet {
 // the given expression. For safety checks this means that they would be
 // made safe by the expr.
 func OutputVarsFromExpr(c *Compiler, expr *Expr, safe VarSet) VarSet {
-	return outputVarsForExpr(expr, c.GetArity, safe, VarSet{})
+	return outputVarsForExpr(expr, c.GetArity, c.outputArgs, safe, VarSet{})
 }
 
-func outputVarsForExpr(expr *Expr, arity func(Ref) int, safe VarSet, output VarSet) VarSet {
+func outputVarsForExpr(expr *Expr, arity func(Ref) int, outputArgs map[string][]int, safe VarSet, output VarSet) VarSet {
 	// Negated expressions must be safe.
 	if expr.Negated {
 		return VarSet{}
@@ -4223,7 +5125,12 @@ func outputVarsForExpr(expr *Expr, arity func(Ref) int, safe VarSet, output VarS
 			return VarSet{}
 		}
 
-		return outputVarsForExprCall(expr, ar, safe, terms, vis, output)
+		var args []int
+		if len(outputArgs) > 0 {
+			args = outputArgs[operator.String()]
+		}
+
+		return outputVarsForExprCall(expr, ar, args, safe, terms, vis, output)
 	case *Every:
 		return outputVarsForTerms(terms.Domain, safe)
 	default:
@@ -4247,16 +5154,31 @@ func outputVarsForExprEq(expr *Expr, safe VarSet, output VarSet) VarSet {
 	return diff
 }
 
-func outputVarsForExprCall(expr *Expr, arity int, safe VarSet, terms []*Term, vis *VarVisitor, output VarSet) VarSet {
+// outputVarsForExprCall returns the output variables of a call. The variables
+// in the arguments at the positions in outputArgs are outputs, like those in
+// the captured result, and need not be safe.
+func outputVarsForExprCall(expr *Expr, arity int, outputArgs []int, safe VarSet, terms []*Term, vis *VarVisitor, output VarSet) VarSet {
 	clear(output)
 
 	output.Update(outputVarsForTerms(expr, safe))
 
 	numInputTerms := arity + 1
-	if numInputTerms >= len(terms) {
+	if numInputTerms > len(terms) || numInputTerms == len(terms) && len(outputArgs) == 0 {
 		return output
 	}
 
+	inputs, outputs := Args(terms[:numInputTerms]), Args(terms[numInputTerms:])
+	if len(outputArgs) > 0 {
+		inputs, outputs = make(Args, 0, numInputTerms), append(Args{}, outputs...)
+		for i, t := range terms[:numInputTerms] {
+			if i > 0 && slices.Contains(outputArgs, i-1) {
+				outputs = append(outputs, t)
+			} else {
+				inputs = append(inputs, t)
+			}
+		}
+	}
+
 	params := VarVisitorParams{
 		SkipClosures:   true,
 		SkipSets:       true,
@@ -4264,7 +5186,7 @@ func outputVarsForExprCall(expr *Expr, arity int, safe VarSet, terms []*Term, vi
 		SkipRefHead:    true,
 	}
 	vis = vis.ClearOrNew().WithParams(params)
-	vis.WalkArgs(Args(terms[:numInputTerms]))
+	vis.WalkArgs(inputs)
 
 	unsafe := vis.Vars().Diff(output).DiffCount(safe)
 	if unsafe > 0 {
@@ -4272,14 +5194,14 @@ func outputVarsForExprCall(expr *Expr, arity int, safe VarSet, terms []*Term, vi
 	}
 
 	vis = vis.Clear().WithParams(params)
-	vis.WalkArgs(Args(terms[numInputTerms:]))
+	vis.WalkArgs(outputs)
 	output.Update(vis.vars)
 	return output
 }
 
 func outputVarsForTerms(expr any, safe VarSet) VarSet {
 	output := VarSet{}
-	WalkTerms(expr, func(x *Term) bool {
+	WalkNodes(expr, func(x *Term) bool {
 		switch r := x.Value.(type) {
 		case *SetComprehension, *ArrayComprehension, *ObjectComprehension:
 			return true
@@ -4405,6 +5327,94 @@ func resolveRef(globals map[Var]*usedRef, ignore *declaredVarStack, ref Ref) Ref
 	return r
 }
 
+// checkShadowedGlobals returns an error for each local variable declared in
+// the rules of mod, by assignment, some or every, that shadows an import or a
+// rule of the module, since references to the variable no longer refer to the
+// import or rule.
+func checkShadowedGlobals(mod *Module) Errors {
+	type global struct {
+		kind string
+		name string
+		loc  *Location
+	}
+
+	globals := map[Var]global{}
+	for _, rule := range mod.Rules {
+		v, ok := rule.Head.Ref()[0].Value.(Var)
+		if _, exists := globals[v]; ok && !exists {
+			globals[v] = global{kind: "rule", name: v.String(), loc: rule.Loc()}
+		}
+	}
+	for _, imp := range mod.Imports {
+		path := imp.Path.Value.(Ref)
+		if FutureRootDocument.Equal(path[0]) || RegoRootDocument.Equal(path[0]) {
+			continue
+		}
+		globals[imp.Name()] = global{kind: "import", name: imp.Path.String(), loc: imp.Location}
+	}
+
+	if len(globals) == 0 {
+		return nil
+	}
+
+	var errs Errors
+	check := func(x *Term) {
+		WalkTerms(x, func(t *Term) bool {
+			v, ok := t.Value.(Var)
+			if !ok {
+				return false
+			}
+			if g, ok := globals[v]; ok {
+				err := NewError(CompileErr, t.Location, "var %v shadows %v %v", v, g.kind, g.name)
+				if g.loc != nil {
+					// Like the prefix of Error(), so that both locations are
+					// part of the message.
+					if g.loc.File != "" {
+						err.Message += fmt.Sprintf(" declared at %v:%v", g.loc.File, g.loc.Row)
+					} else {
+						err.Message += fmt.Sprintf(" declared at %v:%v", g.loc.Row, g.loc.Col)
+					}
+				}
+				errs = append(errs, err.WithRelated(g.loc, "shadowed %v %v", g.kind, g.name).
+					WithSuggestion("rename var %v", v))
+			}
+			return false
+		})
+	}
+
+	for _, rule := range mod.Rules {
+		WalkExprs(rule, func(expr *Expr) bool {
+			switch x := expr.Terms.(type) {
+			case *SomeDecl:
+				for _, symbol := range x.Symbols {
+					switch val := symbol.Value.(type) {
+					case Var:
+						check(symbol)
+					case Call:
+						args := val[1:]
+						if len(args) == 3 { // some x, y in xs
+							check(args[1])
+						}
+						check(args[0])
+					}
+				}
+			case *Every:
+				if x.Key != nil {
+					check(x.Key)
+				}
+				check(x.Value)
+			default:
+				if expr.IsAssignment() && validEqAssignArgCount(expr) {
+					check(expr.Operand(0))
+				}
+			}
+			return false
+		})
+	}
+
+	return errs
+}
+
 type usedRef struct {
 	ref  Ref
 	used bool
@@ -5507,12 +6517,30 @@ func rewriteEveryStatement(g *localVarGenerator, stack *localDeclaredVars, expr
 	}
 
 	// value is always present
-	if v := every.Value.Value.(Var); !v.IsWildcard() {
-		gv, err := rewriteDeclaredVar(g, stack, v, declaredVar)
-		if err != nil {
-			return nil, append(errs, NewError(CompileErr, every.Loc(), err.Error())) //nolint:govet
+	switch v := every.Value.Value.(type) {
+	case Var:
+		if !v.IsWildcard() {
+			gv, err := rewriteDeclaredVar(g, stack, v, declaredVar)
+			if err != nil {
+				return nil, append(errs, NewError(CompileErr, every.Loc(), err.Error())) //nolint:govet
+			}
+			every.Value.Value = gv
+		}
+	default:
+		// Destructuring patterns are replaced by a generated var, which the
+		// pattern is matched against at the start of the body. Every element
+		// of the domain has to match the pattern.
+		pattern := every.Value
+		every.Value = NewTerm(g.Generate()).SetLocation(pattern.Location)
+
+		match := Equality.Expr(pattern, every.Value.Copy()).SetLocation(pattern.Location)
+		for _, v0 := range outputVarsForExprEq(match, every.Value.Vars(), VarSet{}).Sorted() {
+			if _, err := rewriteDeclaredVar(g, stack, v0, declaredVar); err != nil {
+				return nil, append(errs, NewError(CompileErr, every.Loc(), err.Error())) //nolint:govet
+			}
 		}
-		every.Value.Value = gv
+
+		every.Body = NewBody(append([]*Expr{match}, every.Body...)...)
 	}
 
 	used := NewVarSet()
@@ -5568,7 +6596,7 @@ func rewriteSomeDeclStatement(g *localVarGenerator, stack *localDeclaredVars, ex
 }
 
 func rewriteDeclaredVarsInExpr(g *localVarGenerator, stack *localDeclaredVars, expr *Expr, errs Errors, strict bool) (*Expr, Errors) {
-	vis := NewGenericVisitor(func(x any) bool {
+	WalkNodes(expr, func(x Node) bool {
 		var stop bool
 		switch x := x.(type) {
 		case *Term:
@@ -5578,7 +6606,6 @@ func rewriteDeclaredVarsInExpr(g *localVarGenerator, stack *localDeclaredVars, e
 		}
 		return stop
 	})
-	vis.Walk(expr)
 	return expr, errs
 }
 
@@ -5621,7 +6648,7 @@ func rewriteDeclaredAssignment(g *localVarGenerator, stack *localDeclaredVars, e
 			return false
 		case *object:
 			v.Foreach(func(_, v *Term) {
-				WalkTerms(v, vis)
+				WalkNodes(v, vis)
 			})
 			return true
 		case Ref:
@@ -5638,7 +6665,7 @@ func rewriteDeclaredAssignment(g *localVarGenerator, stack *localDeclaredVars, e
 		return true
 	}
 
-	WalkTerms(expr.Operand(0), vis)
+	WalkNodes(expr.Operand(0), vis)
 
 	if len(errs) == numErrsBefore {
 		loc := expr.Operator()[0].Location
@@ -5707,7 +6734,7 @@ func rewriteDeclaredVarsInTerm(g *localVarGenerator, stack *localDeclaredVars, t
 }
 
 func rewriteDeclaredVarsInTermRecursive(g *localVarGenerator, stack *localDeclaredVars, term *Term, errs Errors, strict bool) Errors {
-	WalkTerms(term, func(t *Term) bool {
+	WalkNodes(term, func(t *Term) bool {
 		var stop bool
 		stop, errs = rewriteDeclaredVarsInTerm(g, stack, t, errs, strict)
 		return stop
@@ -6006,10 +7033,12 @@ func safetyErrorSlice(unsafe unsafeVars, rewritten map[Var]Var) (result Errors)
 		if !v.IsGenerated() {
 			if _, ok := allFutureKeywords[string(v)]; ok {
 				result = append(result, NewError(UnsafeVarErr, pair.Loc,
-					"var %[1]v is unsafe (hint: `import future.keywords.%[1]v` to import a future keyword)", v))
+					"var %[1]v is unsafe (hint: `import future.keywords.%[1]v` to import a future keyword)", v).
+					WithSuggestion("import future.keywords.%v", v))
 				continue
 			}
-			result = append(result, NewError(UnsafeVarErr, pair.Loc, "var %v is unsafe", v))
+			result = append(result, NewError(UnsafeVarErr, pair.Loc, "var %v is unsafe", v).
+				WithSuggestion("assign a value to %[1]v, e.g., with `%[1]v := ...` or `some %[1]v in ...`, before it is used", v))
 		}
 	}
 
@@ -6046,7 +7075,7 @@ func safetyErrorSlice(unsafe unsafeVars, rewritten map[Var]Var) (result Errors)
 
 func checkUnsafeBuiltins(unsafeBuiltinsMap map[string]struct{}, node any) Errors {
 	var errs Errors
-	WalkExprs(node, func(x *Expr) bool {
+	WalkNodes(node, func(x *Expr) bool {
 		if x.IsCall() {
 			operator := x.Operator().String()
 			if _, ok := unsafeBuiltinsMap[operator]; ok {
@@ -250,7 +250,7 @@ func TestOutputVarsForNode(t *testing.T) {
 
 			vs := NewSet()
 
-			for v := range outputVarsForBody(body, arity, safe) {
+			for v := range outputVarsForBody(body, arity, nil, safe) {
 				vs.Add(NewTerm(v))
 			}
 
@@ -1290,6 +1290,134 @@ q if {
 	})
 }
 
+func TestCompilerWithLenientCompilation(t *testing.T) {
+	module := MustParseModule(`package a
+
+allow if input.x == 1
+
+broken if undefined_fn(1)
+
+f(x) := y if {
+	y := x + z
+}
+
+g := f(1)
+
+type_error if {
+	x := 1 + "a"
+}
+
+ok := 1
+`)
+	modules := map[string]*Module{"a.rego": module}
+
+	c := NewCompiler().WithLenientCompilation(true)
+	c.Compile(modules)
+	assertNotFailed(t, c)
+
+	var names []string
+	for _, rule := range c.Modules["a.rego"].Rules {
+		names = append(names, rule.Head.Name.String())
+	}
+	if exp := []string{"allow", "ok"}; !slices.Equal(names, exp) {
+		t.Fatalf("expected rules %v, got %v", exp, names)
+	}
+
+	var warnings []string
+	for _, w := range c.Warnings {
+		warnings = append(warnings, fmt.Sprintf("%d: %v", w.Location.Row, w.Message))
+	}
+	exp := []string{
+		"5: undefined function undefined_fn",
+		"8: var y is unsafe",
+		"8: var z is unsafe",
+		"11: undefined function f",
+		"14: plus: invalid argument(s)",
+	}
+	if len(warnings) != len(exp) {
+		t.Fatalf("expected warnings %v, got %v", exp, warnings)
+	}
+	for i := range exp {
+		if !strings.HasPrefix(warnings[i], exp[i]) {
+			t.Fatalf("expected warnings %v, got %v", exp, warnings)
+		}
+	}
+
+	if len(module.Rules) != 6 {
+		t.Fatal("expected input modules to be left unmodified")
+	}
+
+	t.Run("errors outside of rules", func(t *testing.T) {
+		c := NewCompiler().WithLenientCompilation(true)
+		c.Compile(map[string]*Module{"b.rego": MustParseModule(`package b
+
+import data.x as y
+import data.z as y
+
+p if undefined_fn(1)
+`)})
+		if !c.Failed() {
+			t.Fatal("expected compilation to fail")
+		}
+	})
+}
+
+func TestCompilerWithModuleFilter(t *testing.T) {
+	modules := map[string]*Module{
+		"authz.rego": MustParseModule(`package authz
+
+allow if input.user == "alice"`),
+		"authz_test.rego": MustParseModule(`package authz_test
+
+test_allow if data.authz.allow with input.user as "alice"`),
+		"dev.rego": MustParseModuleWithOpts(`# METADATA
+# custom:
+#   scope: dev
+package dev
+
+allow := true`, ParserOptions{ProcessAnnotation: true}),
+	}
+
+	c := NewCompiler().WithModuleFilter(func(name string, m *Module) bool {
+		if strings.HasSuffix(name, "_test.rego") {
+			return false
+		}
+		for _, a := range m.Annotations {
+			if a.Scope == "package" && a.Custom["scope"] == "dev" {
+				return false
+			}
+		}
+		return true
+	})
+	c.Compile(modules)
+	assertNotFailed(t, c)
+
+	if len(c.Modules) != 1 || c.Modules["authz.rego"] == nil {
+		t.Fatalf("expected only authz.rego to be compiled, got %v", slices.Sorted(maps.Keys(c.Modules)))
+	}
+	if len(modules) != 3 {
+		t.Fatal("expected input modules to be left unmodified")
+	}
+
+	t.Run("module loader", func(t *testing.T) {
+		calls := 0
+		c := NewCompiler().
+			WithModuleFilter(func(name string, _ *Module) bool {
+				return name != "excluded.rego"
+			}).
+			WithModuleLoader(func(map[string]*Module) (map[string]*Module, error) {
+				calls++
+				return map[string]*Module{"excluded.rego": MustParseModule(`package excluded`)}, nil
+			})
+		c.Compile(map[string]*Module{"authz.rego": modules["authz.rego"]})
+		assertNotFailed(t, c)
+
+		if len(c.Modules) != 1 || calls != 1 {
+			t.Fatalf("expected loaded module to be excluded, got %v after %d calls", slices.Sorted(maps.Keys(c.Modules)), calls)
+		}
+	})
+}
+
 func TestCompilerFunctions(t *testing.T) {
 	tests := []struct {
 		note    string
@@ -1841,6 +1969,10 @@ p if {
 	if !strings.Contains(errs[1].Message, "var y is unsafe") || errs[1].Location.Row != 6 {
 		t.Fatal("expected y is unsafe on row 6 but got:", err)
 	}
+
+	if exp := "assign a value to y, e.g., with `y := ...` or `some y in ...`, before it is used"; errs[1].Suggestion != exp {
+		t.Fatalf("expected suggestion %q but got %q", exp, errs[1].Suggestion)
+	}
 }
 
 func TestCompilerCheckSafetyFunctionAndContainsKeyword(t *testing.T) {
@@ -2021,6 +2153,73 @@ p if { true }`,
 	assertCompilerErrorStrings(t, c, expected)
 }
 
+func TestCompilerCheckRuleConflictsRelatedAndSuggestion(t *testing.T) {
+	tests := []struct {
+		note          string
+		module        string
+		expSuggestion string
+		expRelated    []ErrorDetail
+	}{
+		{
+			note: "rules in extent",
+			module: `package pkg
+p := 1
+p.q := 2`,
+			expSuggestion: "rename data.pkg.p or the rules within its extent",
+			expRelated: []ErrorDetail{
+				{Message: "conflicting rule data.pkg.p.q", Location: &Location{Row: 3}},
+			},
+		},
+		{
+			note: "kinds",
+			module: `package pkg
+p := 1
+p contains 2`,
+			expSuggestion: "define all rules data.pkg.p of the same kind and with the same number of arguments",
+			expRelated: []ErrorDetail{
+				{Message: "conflicting rule data.pkg.p", Location: &Location{Row: 3}},
+			},
+		},
+		{
+			note: "default rules",
+			module: `package pkg
+default p := 1
+default p := 2`,
+			expSuggestion: "remove all but one default rule data.pkg.p",
+			expRelated: []ErrorDetail{
+				{Message: "default rule data.pkg.p", Location: &Location{Row: 2}},
+				{Message: "default rule data.pkg.p", Location: &Location{Row: 3}},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			c := NewCompiler()
+			c.Modules = map[string]*Module{"test.rego": MustParseModule(tc.module)}
+			compileStages(c, c.checkRuleConflicts)
+
+			if len(c.Errors) != 1 {
+				t.Fatalf("expected one error, got %v", c.Errors)
+			}
+
+			err := c.Errors[0]
+			if err.Suggestion != tc.expSuggestion {
+				t.Errorf("expected suggestion %q, got %q", tc.expSuggestion, err.Suggestion)
+			}
+
+			if len(err.Related) != len(tc.expRelated) {
+				t.Fatalf("expected related %v, got %v", tc.expRelated, err.Related)
+			}
+			for i, exp := range tc.expRelated {
+				if act := err.Related[i]; act.Message != exp.Message || act.Location.Row != exp.Location.Row {
+					t.Errorf("expected related %v at row %d, got %v at row %d", exp.Message, exp.Location.Row, act.Message, act.Location.Row)
+				}
+			}
+		})
+	}
+}
+
 func TestCompilerCheckRuleConflictsDefaultFunction(t *testing.T) {
 	tests := []struct {
 		note    string
@@ -3113,6 +3312,10 @@ func TestCompilerCheckUnusedImports(t *testing.T) {
 			r { foo := true; foo }
 			`,
 			expectedErrors: Errors{
+				&Error{
+					Location: NewLocation([]byte("foo"), "", 4, 8),
+					Message:  "var foo shadows import data.foo declared at 2:4",
+				},
 				&Error{
 					Location: NewLocation([]byte("import"), "", 2, 4),
 					Message:  "import data.foo unused",
@@ -3158,6 +3361,303 @@ func TestCompilerCheckUnusedImports(t *testing.T) {
 	runStrictnessTestCase(t, cases, true)
 }
 
+func TestCompilerCheckShadowedGlobals(t *testing.T) {
+	cases := []strictnessTestCase{
+		{
+			note: "assignment shadows import",
+			module: `package p
+			import data.users
+
+			r { users := ["alice"]; count(users) > 0 }
+			s { users[_] == "bob" }
+			`,
+			expectedErrors: Errors{
+				&Error{
+					Location: NewLocation([]byte("users"), "", 4, 8),
+					Message:  "var users shadows import data.users declared at 2:4",
+				},
+			},
+		},
+		{
+			note: "assignment shadows rule",
+			module: `package p
+			roles := {"admin"}
+
+			r { roles := {"guest"}; roles["guest"] }
+			`,
+			expectedErrors: Errors{
+				&Error{
+					Location: NewLocation([]byte("roles"), "", 4, 8),
+					Message:  "var roles shadows rule roles declared at 2:4",
+				},
+			},
+		},
+		{
+			note: "some and every declarations",
+			module: `package p
+			import future.keywords.every
+			import future.keywords.in
+			roles := {"admin"}
+			users := ["alice"]
+
+			r { some roles in input.roles; roles == "x" }
+			s { every i, users in input.users { users != "x"; i >= 0 } }
+			t = [roles | some roles; input.roles[roles]]
+			`,
+			expectedErrors: Errors{
+				&Error{
+					Location: NewLocation([]byte("roles"), "", 7, 13),
+					Message:  "var roles shadows rule roles declared at 4:4",
+				},
+				&Error{
+					Location: NewLocation([]byte("users"), "", 8, 17),
+					Message:  "var users shadows rule users declared at 5:4",
+				},
+				&Error{
+					Location: NewLocation([]byte("roles"), "", 9, 22),
+					Message:  "var roles shadows rule roles declared at 4:4",
+				},
+			},
+		},
+		{
+			note: "no shadowing",
+			module: `package p
+			import data.users
+			import future.keywords.in
+			roles := {"admin"}
+
+			r { some role in roles; x := role; users[x] }
+			`,
+		},
+	}
+
+	runStrictnessTestCase(t, cases, true)
+}
+
+func TestCompilerCheckUnusedFunctions(t *testing.T) {
+	tests := []struct {
+		note     string
+		modules  map[string]string
+		strict   bool
+		expected []string
+	}{
+		{
+			note: "called in same module",
+			modules: map[string]string{
+				"a.rego": `package a
+				p if f(1)
+				f(x) if x == 1`,
+			},
+			strict: true,
+		},
+		{
+			note: "called in other module",
+			modules: map[string]string{
+				"a.rego": `package a
+				f(x) := x + 1`,
+				"b.rego": `package b
+				p := data.a.f(1)`,
+			},
+			strict: true,
+		},
+		{
+			note: "called through import",
+			modules: map[string]string{
+				"a.rego": `package a
+				f(x) := x + 1`,
+				"b.rego": `package b
+				import data.a
+				p := a.f(1)`,
+			},
+			strict: true,
+		},
+		{
+			note: "called by function",
+			modules: map[string]string{
+				"a.rego": `package a
+				p := f(1)
+				f(x) := g(x)
+				g(x) := x`,
+			},
+			strict: true,
+		},
+		{
+			note: "called by test",
+			modules: map[string]string{
+				"a.rego": `package a
+				f(x) := x + 1`,
+				"a_test.rego": `package a
+				test_f if f(1) == 2`,
+			},
+			strict: true,
+		},
+		{
+			note: "replaced with",
+			modules: map[string]string{
+				"a.rego": `package a
+				p if count([]) == 1 with count as f
+				f(_) := 1`,
+			},
+			strict: true,
+		},
+		{
+			note: "dynamic ref",
+			modules: map[string]string{
+				"a.rego": `package a
+				f(x) := x`,
+				"b.rego": `package b
+				p if data.a[input.x]`,
+			},
+			strict: true,
+		},
+		{
+			note: "not strict",
+			modules: map[string]string{
+				"a.rego": `package a
+				f(x) := x`,
+			},
+		},
+		{
+			note: "unused",
+			modules: map[string]string{
+				"a.rego": `package a
+				p := 1
+				f(x) := x
+				f(x) := x if x > 1
+				g(x) := y if { y := x } else := 1`,
+				"b.rego": `package b
+				h(x) := x`,
+			},
+			strict: true,
+			expected: []string{
+				"a.rego:3: rego_compile_error: function data.a.f unused",
+				"a.rego:5: rego_compile_error: function data.a.g unused",
+				"b.rego:2: rego_compile_error: function data.b.h unused",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			c := getCompilerWithParsedModules(tc.modules).WithStrict(tc.strict)
+			compileStages(c, nil)
+			if c.Failed() {
+				t.Fatalf("unexpected errors: %v", c.Errors)
+			}
+
+			var actual []string
+			for _, w := range c.Warnings {
+				actual = append(actual, w.Error())
+			}
+
+			if !slices.Equal(actual, tc.expected) {
+				t.Fatalf("expected warnings %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestCompilerCheckContradictions(t *testing.T) {
+	tests := []struct {
+		note     string
+		module   string
+		disabled bool
+		expected []string
+	}{
+		{
+			note: "disabled",
+			module: `package a
+			p if { input.x == 1; input.x == 2 }`,
+			disabled: true,
+		},
+		{
+			note: "no contradictions",
+			module: `package a
+			p if { input.x == 1; input.x == 1.0; input.y != 1; input.y == 2; is_string(input.z); input.z == "a" }
+			q if { input.x == 1 } else if { input.x == 2 }
+			r if { input.x == 1; not input.x == 2 }
+			s if { input.x[_] == 1; input.x[_] == 2 }
+			t if { input.x == 1; input.x == 2 with input as {} }`,
+		},
+		{
+			note: "equal constants",
+			module: `package a
+			p if { input.x == 1; input.x = 2 }`,
+			expected: []string{
+				"a.rego:2: rego_compile_error: expression is always false, input.x cannot be 1 and 2",
+			},
+		},
+		{
+			note: "local vars",
+			module: `package a
+			p if { x := input.x; x == "a"; input.x == "b" }
+			q if { y = 1; y != 1 }`,
+			expected: []string{
+				`a.rego:2: rego_compile_error: expression is always false, input.x cannot be "a" and "b"`,
+				"a.rego:3: rego_compile_error: expression is always false, y cannot be 1 and not 1",
+			},
+		},
+		{
+			note: "type tests",
+			module: `package a
+			p if { is_string(input.x); is_number(input.x) }
+			q if { input.y == {"a": 1}; is_array(input.y) }`,
+			expected: []string{
+				"a.rego:2: rego_compile_error: expression is always false, input.x cannot be a string and a number",
+				`a.rego:3: rego_compile_error: expression is always false, input.y cannot be {"a": 1} and an array`,
+			},
+		},
+		{
+			note: "nested bodies",
+			module: `package a
+			p := [x | some x in input.xs; x == 1; x == 2]`,
+			expected: []string{
+				"a.rego:2: rego_compile_error: expression is always false, x cannot be 1 and 2",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			c := getCompilerWithParsedModules(map[string]string{"a.rego": tc.module}).
+				WithContradictionCheck(!tc.disabled)
+			compileStages(c, nil)
+			if c.Failed() {
+				t.Fatalf("unexpected errors: %v", c.Errors)
+			}
+
+			var actual []string
+			for _, w := range c.Warnings {
+				actual = append(actual, w.Error())
+			}
+
+			if !slices.Equal(actual, tc.expected) {
+				t.Fatalf("expected warnings %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestCompilerCheckContradictionsRelated(t *testing.T) {
+	c := getCompilerWithParsedModules(map[string]string{"a.rego": `package a
+p if {
+	input.x == 1
+	input.x == 2
+}`}).WithContradictionCheck(true)
+	compileStages(c, nil)
+
+	if len(c.Warnings) != 1 {
+		t.Fatalf("expected one warning, got %v", c.Warnings)
+	}
+	if exp, act := 4, c.Warnings[0].Location.Row; exp != act {
+		t.Fatalf("expected warning on row %d, got %d", exp, act)
+	}
+	related := c.Warnings[0].Related
+	if len(related) != 1 || related[0].Location.Row != 3 || related[0].Message != "conflicting expression" {
+		t.Fatalf("expected conflicting expression on row 3, got %v", related)
+	}
+}
+
 func TestCompilerCheckDuplicateImports(t *testing.T) {
 	cases := []strictnessTestCase{
 		{
@@ -4997,23 +5497,68 @@ p = true if {
 	}
 }
 
-func TestCompilerOverridingSelfCalls(t *testing.T) {
-	c := NewCompiler()
-	c.Modules = map[string]*Module{
-		"self.rego": MustParseModule(`package self.metadata
+func TestRewriteRegoMetadataCalls(t *testing.T) {
+	mod := MustParseModuleWithOpts(`# METADATA
+# description: A test package
+package test
 
-chain(x) = "foo"
-rule := "bar"`),
-		"test.rego": MustParseModule(`package test
-import data.self
+# METADATA
+# title: My P Rule
+p if {
+	rego.metadata.rule(x)
+	x.title == "My P Rule"
+	rego.metadata.chain()
+}
 
-p := self.metadata.chain(42)
-q := self.metadata.rule`),
+q if {
+	rego.metadata.rule(y)
+	y == {}
+}`, ParserOptions{ProcessAnnotation: true})
+
+	if errs := RewriteRegoMetadataCalls(nil, mod); len(errs) > 0 {
+		t.Fatal(errs)
 	}
 
-	compileStages(c, nil)
-	assertNotFailed(t, c)
-}
+	exp := MustParseModule(`package test
+
+p if {
+	__local0__ = [{"annotations": {"scope": "rule", "title": "My P Rule"}, "path": ["test", "p"]}, {"annotations": {"description": "A test package", "scope": "package"}, "path": ["test"]}]
+	__local1__ = {"scope": "rule", "title": "My P Rule"}
+	x = __local1__
+	x.title == "My P Rule"
+	__local0__
+}
+
+q if {
+	__local2__ = {}
+	y = __local2__
+	y == {}
+}`)
+
+	for i := range exp.Rules {
+		if mod.Rules[i].Body.Compare(exp.Rules[i].Body) != 0 {
+			t.Fatalf("\nExpected:\n\n%v\n\nGot:\n\n%v", exp.Rules[i].Body, mod.Rules[i].Body)
+		}
+	}
+}
+
+func TestCompilerOverridingSelfCalls(t *testing.T) {
+	c := NewCompiler()
+	c.Modules = map[string]*Module{
+		"self.rego": MustParseModule(`package self.metadata
+
+chain(x) = "foo"
+rule := "bar"`),
+		"test.rego": MustParseModule(`package test
+import data.self
+
+p := self.metadata.chain(42)
+q := self.metadata.rule`),
+	}
+
+	compileStages(c, nil)
+	assertNotFailed(t, c)
+}
 
 func TestCompilerRewriteLocalAssignments(t *testing.T) {
 
@@ -8345,8 +8890,7 @@ func TestCompilerSetGraph(t *testing.T) {
 		t.Fatalf("Expected numRules (%v) to be same as len(sorted) (%v)", numRules, len(sorted))
 	}
 
-	// Probe rules with dependencies. Ordering is not stable for ties because
-	// nodes are stored in a map.
+	// Probe rules with dependencies.
 	probes := [][2]*Rule{
 		{c.Modules["mod1"].Rules[1], c.Modules["mod1"].Rules[0]},               // mod1.q before mod1.p
 		{c.Modules["mod2"].Rules[0], c.Modules["mod1"].Rules[0]},               // mod2.r before mod1.p
@@ -8381,6 +8925,56 @@ func TestCompilerSetGraph(t *testing.T) {
 	}
 }
 
+func TestCompilerSortedRules(t *testing.T) {
+	modules := map[string]string{
+		"b.rego": `package b
+
+		x := data.a.z + 1
+		y := 2`,
+		"a.rego": `package a
+
+		z := data.b.y
+
+		w := 1 if {
+			false
+		} else := z`,
+		"c.rego": `package c
+
+		v := data.b.x`,
+	}
+
+	exp := []string{
+		"data.b.y",
+		"data.a.z",
+		"data.a.w",
+		"data.a.w",
+		"data.b.x",
+		"data.c.v",
+	}
+
+	// The order must not depend on the order modules are iterated in.
+	for range 10 {
+		c := MustCompileModules(modules)
+
+		sorted := c.SortedRules()
+		act := make([]string, len(sorted))
+		for i, rule := range sorted {
+			act[i] = rule.Path().String()
+		}
+
+		if !slices.Equal(exp, act) {
+			t.Fatalf("Expected rules %v but got %v", exp, act)
+		}
+		if sorted[3] != sorted[2].Else {
+			t.Fatalf("Expected else branch after its rule")
+		}
+	}
+
+	if rules := NewCompiler().SortedRules(); rules != nil {
+		t.Fatalf("Expected no rules before compilation but got %v", rules)
+	}
+}
+
 func TestGraphCycle(t *testing.T) {
 	mod1 := `package a.b.c
 
@@ -8910,6 +9504,79 @@ q["b"] = 2 if { true }`,
 
 }
 
+func TestCompilerRuleCardinality(t *testing.T) {
+	compiler := getCompilerWithParsedModules(map[string]string{
+		"mod1": `package a
+
+default p := 1
+p := 2 if { input.x }
+q contains x if { some x in input.xs }
+r[x] := y if { some x, y in input.xs }
+s.t[x].u := 1 if { some x in input.xs }
+f(x) := x`,
+		"mod2": `package b
+
+p := 1
+q := 2
+f(x) := x`,
+	})
+
+	compileStages(compiler, nil)
+
+	tests := []struct {
+		input    string
+		expected Cardinality
+	}{
+		{"data.a.p", CardinalityOne},
+		{"data.a.p.x", CardinalityUnknown},
+		{"data.a.q", CardinalityMany},
+		{"data.a.q.x", CardinalityOne},
+		{"data.a.q[x]", CardinalityMany},
+		{"data.a.r", CardinalityMany},
+		{"data.a.r.x", CardinalityOne},
+		{"data.a.r[x]", CardinalityMany},
+		{"data.a.r.x.y", CardinalityUnknown},
+		{"data.a.s", CardinalityMany},
+		{"data.a.s.t.x.u", CardinalityOne},
+		{"data.a.s.t.x.v", CardinalityUnknown},
+		{"data.a.f", CardinalityUnknown},
+		{"data.a", CardinalityMany},
+		{"data.b", CardinalityMany},
+		{"data.b.q", CardinalityOne},
+		{"data.c", CardinalityUnknown},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			if act := compiler.RuleCardinality(MustParseRef(tc.input)); act != tc.expected {
+				t.Fatalf("expected %v but got %v", tc.expected, act)
+			}
+		})
+	}
+}
+
+func TestCompilerCheckDefaultOfMultiValueRules(t *testing.T) {
+	c := getCompilerWithParsedModules(map[string]string{"mod": `package a
+
+default p.q := 1
+p[x] := 2 if { some x in input.xs }
+
+default r := 1
+r := 2 if { input.x }`})
+	compileStages(c, nil)
+	if c.Failed() {
+		t.Fatalf("unexpected errors: %v", c.Errors)
+	}
+
+	exp := "mod:3: rego_compile_error: default rule data.a.p.q only applies to a single value of multi-value rule data.a.p[x]"
+	if len(c.Warnings) != 1 || c.Warnings[0].Error() != exp {
+		t.Fatalf("expected warning %v, got %v", exp, c.Warnings)
+	}
+	if related := c.Warnings[0].Related; len(related) != 1 || related[0].Location.Row != 4 {
+		t.Fatalf("expected related multi-value rule on row 4, got %v", related)
+	}
+}
+
 func TestCompilerGetRulesDynamic(t *testing.T) {
 	compiler := getCompilerWithParsedModules(map[string]string{
 		"mod1": `package a.b.c.d
@@ -9040,6 +9707,147 @@ func TestCompileCustomBuiltins(t *testing.T) {
 	assertCompilerErrorStrings(t, compiler, []string{exp1, exp2})
 }
 
+func TestCompilerResolveIncludes(t *testing.T) {
+	caps := CapabilitiesForThisVersion()
+	caps.Features = append(caps.Features, FeatureIncludeDirective)
+	popts := ParserOptions{Capabilities: caps, RegoVersion: RegoV1}
+
+	parse := func(file, src string) *Module {
+		t.Helper()
+		m, err := ParseModuleWithOpts(file, src, popts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+
+	modules := map[string]*Module{
+		"tests/a_test.rego": parse("tests/a_test.rego", `package a_test
+
+import include["fixtures.rego"]
+
+test_admin if allow with input as admin`),
+		"tests/b_test.rego": parse("tests/b_test.rego", `package b_test
+
+import include["fixtures.rego"]
+
+test_user if not allow with input as user`),
+	}
+
+	// fixtures.rego is loaded lazily and includes the policy under test.
+	loader := func(resolved map[string]*Module) (map[string]*Module, error) {
+		if _, ok := resolved["tests/fixtures.rego"]; ok {
+			return nil, nil
+		}
+		return map[string]*Module{
+			"tests/fixtures.rego": parse("tests/fixtures.rego", `package fixtures
+
+import data.users
+import include["../policy.rego"]
+
+admin := users.alice
+
+user := users.bob`),
+			"policy.rego": parse("policy.rego", `package policy
+
+import data.roles
+
+allow if input.user in roles.admins`),
+		}, nil
+	}
+
+	c := NewCompiler().WithCapabilities(caps).WithModuleLoader(loader)
+	c.Compile(modules)
+	assertNotFailed(t, c)
+
+	for _, pkg := range []string{"a_test", "b_test", "fixtures"} {
+		rules := c.GetRulesExact(MustParseRef("data." + pkg + ".allow"))
+		if len(rules) != 1 {
+			t.Fatalf("expected allow rule in package %v but got %v", pkg, rules)
+		}
+		if exp := "policy.rego"; rules[0].Location.File != exp {
+			t.Errorf("expected location in %v but got %v", exp, rules[0].Location)
+		}
+		if !strings.Contains(rules[0].Body.String(), "data.roles.admins") {
+			t.Errorf("expected imports of included module to be resolved but got %v", rules[0].Body)
+		}
+	}
+
+	rules := c.GetRulesExact(MustParseRef("data.a_test.admin"))
+	if len(rules) != 1 || !strings.Contains(rules[0].String(), "data.users.alice") {
+		t.Fatalf("expected admin rule in package a_test but got %v", rules)
+	}
+
+	if !slices.Contains(c.Required.Features, FeatureIncludeDirective) {
+		t.Fatalf("expected %v in required features but got %v", FeatureIncludeDirective, c.Required.Features)
+	}
+}
+
+func TestCompilerResolveIncludesErrors(t *testing.T) {
+	caps := CapabilitiesForThisVersion()
+	caps.Features = append(caps.Features, FeatureIncludeDirective)
+	popts := ParserOptions{Capabilities: caps, RegoVersion: RegoV1}
+
+	tests := []struct {
+		note    string
+		modules map[string]string
+		caps    *Capabilities
+		exp     string
+	}{
+		{
+			note: "missing module",
+			modules: map[string]string{
+				"x.rego": `package x
+import include["y.rego"]`,
+			},
+			exp: `1 error occurred: x.rego:2: rego_compile_error: include["y.rego"]: module not found`,
+		},
+		{
+			note: "cycle",
+			modules: map[string]string{
+				"x.rego": `package x
+import include["y.rego"]`,
+				"y.rego": `package y
+import include["x.rego"]`,
+			},
+			exp: `1 error occurred: y.rego:2: rego_compile_error: include["x.rego"]: include cycle: x.rego -> y.rego -> x.rego`,
+		},
+		{
+			note: "not supported",
+			modules: map[string]string{
+				"x.rego": `package x
+import include["y.rego"]`,
+				"y.rego": `package y`,
+			},
+			caps: CapabilitiesForThisVersion(),
+			exp:  `1 error occurred: x.rego:2: rego_compile_error: include directives are not supported`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			modules := make(map[string]*Module, len(tc.modules))
+			for file, src := range tc.modules {
+				modules[file] = MustParseModuleWithOpts(src, popts)
+				modules[file].Package.Location.File = file
+				for _, imp := range modules[file].Imports {
+					imp.Location.File = file
+				}
+			}
+
+			if tc.caps == nil {
+				tc.caps = caps
+			}
+
+			c := NewCompiler().WithCapabilities(tc.caps)
+			c.Compile(modules)
+			if !c.Failed() || !strings.Contains(c.Errors.Error(), tc.exp) {
+				t.Fatalf("expected error %q but got %v", tc.exp, c.Errors)
+			}
+		})
+	}
+}
+
 func TestCompilerLazyLoadingError(t *testing.T) {
 
 	testLoader := func(map[string]*Module) (map[string]*Module, error) {
@@ -9284,6 +10092,17 @@ func TestCompilerBuildComprehensionIndexKeySet(t *testing.T) {
 				}`,
 			wantDebug: 0,
 		},
+		{
+			note: "skip: due to with modifier on input",
+			module: `
+				package test
+
+				p if {
+					v = input[i]
+					ks = [j | input[j] = v] with input.x as 1  # skip because the cache does not survive the with scope
+				}`,
+			wantDebug: 0,
+		},
 		{
 			note: "skip: due to negation",
 			module: `
@@ -9737,6 +10556,94 @@ func TestCompilerBuildRequiredCapabilities(t *testing.T) {
 	}
 }
 
+func TestCompilerRequiredBy(t *testing.T) {
+	compiler := MustCompileModulesWithOpts(map[string]string{
+		"x.rego": `
+			package x
+
+			import future.keywords.in
+
+			p { 1 in input }
+
+			q[x] { x := regex.match(` + "`^a$`" + `, input.y) }
+
+			a.b[c] { c := input.c }
+		`,
+		"y.rego": `
+			package y
+
+			import rego.v1
+
+			r if { print(input) }
+		`,
+	}, CompileOpts{ParserOptions: ParserOptions{RegoVersion: RegoV0}, EnablePrintStatements: true})
+
+	tests := []struct {
+		ref      string
+		builtins []string
+		features []string
+		keywords []string
+	}{
+		{
+			ref:      "data.x.p",
+			builtins: []string{"eq", "internal.member_2"},
+			keywords: []string{"in"},
+		},
+		{
+			ref:      "data.x.q",
+			builtins: []string{"assign", "eq", "regex.match"},
+			keywords: []string{"in"},
+		},
+		{
+			ref:      "data.x.a.b",
+			builtins: []string{"assign", "eq"},
+			features: []string{"rule_head_refs"},
+			keywords: []string{"in"},
+		},
+		{
+			ref:      "data.x",
+			builtins: []string{"assign", "eq", "internal.member_2", "regex.match"},
+			features: []string{"rule_head_refs"},
+			keywords: []string{"in"},
+		},
+		{
+			ref:      "data.y.r",
+			builtins: []string{"eq", "internal.print", "print"},
+			features: []string{"rego_v1_import"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.ref, func(t *testing.T) {
+			required := compiler.RequiredBy(MustParseRef(tc.ref))
+			if required == nil {
+				t.Fatal("expected required capabilities")
+			}
+
+			var names []string
+			for _, bi := range required.Builtins {
+				names = append(names, bi.Name)
+			}
+
+			if !slices.Equal(names, tc.builtins) {
+				t.Errorf("expected builtins to be %v but got %v", tc.builtins, names)
+			}
+
+			if !slices.Equal(required.Features, tc.features) {
+				t.Errorf("expected features to be %v but got %v", tc.features, required.Features)
+			}
+
+			if !slices.Equal(required.FutureKeywords, tc.keywords) {
+				t.Errorf("expected keywords to be %v but got %v", tc.keywords, required.FutureKeywords)
+			}
+		})
+	}
+
+	if required := compiler.RequiredBy(MustParseRef("data.z")); required != nil {
+		t.Fatalf("expected no required capabilities, got %v", required)
+	}
+}
+
 func TestCompilerAllowMultipleAssignments(t *testing.T) {
 
 	_, err := CompileModules(map[string]string{"test.rego": `
@@ -9971,6 +10878,72 @@ func TestQueryCompilerRecompile(t *testing.T) {
 
 }
 
+func TestQueryCompilerWithQueryCache(t *testing.T) {
+	c := NewCompiler().WithQueryCache(2)
+	c.Compile(getCompilerTestModules())
+	assertNotFailed(t, c)
+
+	compile := func(qc QueryCompiler, query string) Body {
+		t.Helper()
+		compiled, err := qc.Compile(MustParseBody(query))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return compiled
+	}
+
+	cached := func(a, b Body) bool {
+		return &a[0] == &b[0]
+	}
+
+	qc := c.QueryCompiler()
+	compiled := compile(qc, `a := [1]; data.a.b.c.q == a[0]`)
+	rewritten := qc.RewrittenVars()
+
+	qc = c.QueryCompiler()
+	if act := compile(qc, `a := [1]; data.a.b.c.q == a[0]`); !cached(act, compiled) {
+		t.Fatal("expected compiled query to be cached")
+	}
+	if !maps.Equal(qc.RewrittenVars(), rewritten) || qc.TypeEnv() == nil {
+		t.Fatalf("expected rewritten vars %v of cached query, got %v", rewritten, qc.RewrittenVars())
+	}
+
+	// Queries differing in their source text, context or options are compiled again.
+	if act := compile(c.QueryCompiler(), `a := [1];  data.a.b.c.q == a[0]`); cached(act, compiled) {
+		t.Fatal("expected query with different source text to be compiled")
+	}
+
+	qctx := NewQueryContext().WithPackage(MustParsePackage(`package a.b.c`))
+	if act := compile(c.QueryCompiler().WithContext(qctx), `a := [1]; data.a.b.c.q == a[0]`); cached(act, compiled) {
+		t.Fatal("expected query with different context to be compiled")
+	}
+
+	if act := compile(c.QueryCompiler().WithEnablePrintStatements(true), `a := [1]; data.a.b.c.q == a[0]`); cached(act, compiled) {
+		t.Fatal("expected query with different options to be compiled")
+	}
+
+	mock := QueryCompilerStageDefinition{"MockStage", "mock_stage", func(_ QueryCompiler, b Body) (Body, error) { return b, nil }}
+	qc = c.QueryCompiler().WithStageAfter("CheckSafety", mock)
+	if a, b := compile(qc, `x := 1`), compile(qc, `x := 1`); cached(a, b) {
+		t.Fatal("expected query with additional stages not to be cached")
+	}
+
+	if _, err := c.QueryCompiler().Compile(MustParseBody(`x`)); err == nil {
+		t.Fatal("expected error")
+	}
+	if c.queryCache.l.Len() != 2 {
+		t.Fatalf("expected least recently used queries to be evicted, got %d cached queries", c.queryCache.l.Len())
+	}
+
+	// Compiling modules invalidates the cache.
+	a := compile(c.QueryCompiler(), `x := 1`)
+	c.Compile(getCompilerTestModules())
+	assertNotFailed(t, c)
+	if b := compile(c.QueryCompiler(), `x := 1`); cached(a, b) {
+		t.Fatal("expected cache to be cleared after compiling modules")
+	}
+}
+
 func TestQueryCompilerWithMetrics(t *testing.T) {
 	m := metrics.New()
 	c := NewCompiler().WithMetrics(m)
@@ -10664,6 +11637,214 @@ func TestCompilerCapabilitiesExtendedWithCustomBuiltins(t *testing.T) {
 
 }
 
+func TestCompilerCapabilitiesDenyCategories(t *testing.T) {
+	custom := &Builtin{
+		Name: "custom.fetch",
+		Decl: types.NewFunction(types.Args(types.S), types.A),
+		Tags: []string{BuiltinCategoryNetwork},
+	}
+
+	current := CapabilitiesForThisVersion()
+	current.Builtins = append(current.Builtins, custom)
+
+	bs, err := json.Marshal(current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadCapabilitiesJSON(bytes.NewReader(bs))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Capabilities of earlier versions do not include the categories of their
+	// built-in functions, they are looked up by name.
+	untagged, err := LoadCapabilitiesJSON(bytes.NewReader(bs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, bi := range untagged.Builtins {
+		if bi.Name != custom.Name {
+			bi.Tags = nil
+		}
+	}
+
+	tests := []struct {
+		note   string
+		module string
+		denied bool
+	}{
+		{note: "network", module: `p := http.send({"method": "get", "url": "https://example.com"})`, denied: true},
+		{note: "io", module: `p := opa.runtime()`, denied: true},
+		{note: "custom", module: `p := custom.fetch("x")`, denied: true},
+		{note: "crypto", module: `p := crypto.sha256("x")`},
+		{note: "uncategorized", module: `p := count([1])`},
+	}
+
+	for _, caps := range []*Capabilities{current, loaded, untagged} {
+		caps.DenyCategories = []string{BuiltinCategoryNetwork, BuiltinCategoryIO}
+
+		for _, tc := range tests {
+			t.Run(tc.note, func(t *testing.T) {
+				compiler := NewCompiler().WithCapabilities(caps)
+				compiler.Compile(map[string]*Module{"x": module("package test\n\n" + tc.module)})
+
+				if !tc.denied {
+					if compiler.Failed() {
+						t.Fatal("unexpected error:", compiler.Errors)
+					}
+					return
+				}
+				if !compiler.Failed() || !strings.Contains(compiler.Errors[0].Error(), "undefined function") {
+					t.Fatal("expected undefined function error but got:", compiler.Errors)
+				}
+			})
+		}
+	}
+}
+
+func TestCompilerWithBuiltinOverride(t *testing.T) {
+	decl := types.NewFunction(
+		types.Args(types.NewObject(nil, types.NewDynamicProperty(types.S, types.A))),
+		types.NewObject([]*types.StaticProperty{types.NewStaticProperty("status_code", types.N)}, nil),
+	)
+
+	module1 := module(`package test
+
+	p if { http.send({"method": "get", "url": "https://example.com"}).status_code == 200 }`)
+	module2 := module(`package test
+
+	p if { http.send({"method": "get", "url": "https://example.com"}).body.foo == 1 }`)
+
+	compiler := NewCompiler().WithBuiltinOverride(HTTPSend.Name, decl)
+
+	compiler.Compile(map[string]*Module{"x": module1})
+	if compiler.Failed() {
+		t.Fatal("unexpected error:", compiler.Errors)
+	}
+
+	compiler.Compile(map[string]*Module{"x": module2})
+	if !compiler.Failed() {
+		t.Fatal("expected error but got success")
+	} else if !strings.Contains(compiler.Errors[0].Error(), "undefined ref") {
+		t.Fatal("expected type error but got:", compiler.Errors)
+	}
+
+	// Other compilers and the global registry are unaffected.
+	if BuiltinMap[HTTPSend.Name].Decl == decl {
+		t.Fatal("expected global built-in declaration to be unchanged")
+	}
+
+	compiler = NewCompiler()
+	compiler.Compile(map[string]*Module{"x": module2})
+	if compiler.Failed() {
+		t.Fatal("unexpected error:", compiler.Errors)
+	}
+
+	compiler = NewCompiler().WithBuiltinOverride("no.such.builtin", decl)
+	compiler.Compile(map[string]*Module{"x": module1})
+	if !compiler.Failed() {
+		t.Fatal("expected error but got success")
+	} else if !strings.Contains(compiler.Errors[0].Error(), "unknown built-in function no.such.builtin") {
+		t.Fatal("unexpected error:", compiler.Errors)
+	}
+
+	compiler = NewCompiler().WithBuiltinOverride(HTTPSend.Name, types.NewFunction(types.Args(types.A, types.A), types.A))
+	compiler.Compile(map[string]*Module{"x": module1})
+	if !compiler.Failed() {
+		t.Fatal("expected error but got success")
+	} else if !strings.Contains(compiler.Errors[0].Error(), "cannot override type declaration of built-in function http.send: arguments") {
+		t.Fatal("unexpected error:", compiler.Errors)
+	}
+
+	// Errors found before compiling count towards the error limit.
+	compiler = NewCompiler().
+		SetErrorLimit(1).
+		WithBuiltinOverride("no.such.builtin", decl).
+		WithBuiltinOverride("no.such.builtin2", decl)
+	compiler.Compile(map[string]*Module{"x": module1})
+	if len(compiler.Errors) != 2 || compiler.Errors[1] != errLimitReached {
+		t.Fatal("expected error limit to be reached but got:", compiler.Errors)
+	}
+}
+
+func TestCompilerCheckSafetyBuiltinOutputArgs(t *testing.T) {
+	compiler := NewCompiler().WithCapabilities(&Capabilities{
+		Builtins: []*Builtin{
+			Equality,
+			Assign,
+			Concat,
+			{
+				Name:       "edge",
+				Decl:       types.NewFunction(types.Args(types.S, types.S), nil),
+				OutputArgs: []int{1},
+			},
+		},
+	})
+
+	compiler.Compile(map[string]*Module{"x": module(`package test
+
+	p contains y if { z := concat("", [y]); edge(x, y); x = "a" }`)})
+	if compiler.Failed() {
+		t.Fatal("unexpected error:", compiler.Errors)
+	}
+
+	exp := MustParseBody(`x = "a"; edge(x, y); __local1__ = concat("", [y]); __local0__ = __local1__`)
+	body := compiler.Modules["x"].Rules[0].Body
+	if len(body) != len(exp) || !body[0].Equal(exp[0]) || !body[1].Equal(exp[1]) {
+		t.Fatalf("expected body %v but got %v", exp, body)
+	}
+
+	compiler = NewCompiler().WithCapabilities(compiler.capabilities)
+	compiler.Compile(map[string]*Module{"x": module(`package test
+
+	p contains y if { edge(x, y) }`)})
+	if !compiler.Failed() || !strings.Contains(compiler.Errors.Error(), "var x is unsafe") {
+		t.Fatal("expected safety error but got:", compiler.Errors)
+	}
+
+	tests := []struct {
+		note       string
+		outputArgs []int
+		exp        string
+	}{
+		{
+			note:       "out of range",
+			outputArgs: []int{2},
+			exp:        "built-in function edge: output argument 2 out of range, function has 2 arguments",
+		},
+		{
+			note:       "negative",
+			outputArgs: []int{-1},
+			exp:        "built-in function edge: output argument -1 out of range, function has 2 arguments",
+		},
+		{
+			note:       "duplicate",
+			outputArgs: []int{1, 1},
+			exp:        "built-in function edge: duplicate output argument 1",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			compiler := NewCompiler().WithCapabilities(&Capabilities{
+				Builtins: []*Builtin{
+					{
+						Name:       "edge",
+						Decl:       types.NewFunction(types.Args(types.S, types.S), nil),
+						OutputArgs: tc.outputArgs,
+					},
+				},
+			})
+			compiler.Compile(map[string]*Module{"x": module(`package test
+
+			p if { edge("a", "b") }`)})
+			if !compiler.Failed() || !strings.Contains(compiler.Errors[0].Error(), tc.exp) {
+				t.Fatalf("expected error %q but got: %v", tc.exp, compiler.Errors)
+			}
+		})
+	}
+}
+
 func TestCompilerWithUnsafeBuiltins(t *testing.T) {
 	// Rego includes a number of built-in functions. In some cases, you may not
 	// want all builtins to be available to a program. This test shows how to
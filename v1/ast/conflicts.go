@@ -5,6 +5,7 @@
 package ast
 
 import (
+	"maps"
 	"slices"
 	"strings"
 )
@@ -27,28 +28,41 @@ func CheckPathConflicts(c *Compiler, exists func([]string) (bool, error)) Errors
 	}
 
 	for _, rootPath := range c.pathConflictCheckRoots {
-		// traverse AST from `path` to go to the new root
-		paths := strings.Split(rootPath, "/")
-		node := root
-		for _, key := range paths {
-			node = node.Child(String(key))
-			if node == nil {
-				break
+		// traverse AST from `path` to go to the new root(s)
+		for node, paths := range rootNodes(root, strings.Split(rootPath, "/"), nil) {
+			for _, child := range node.Children {
+				errs = append(errs, checkDocumentConflicts(child, exists, paths)...)
 			}
 		}
+	}
 
-		if node == nil {
-			// could not find the node from the AST (e.g. `path` is from a data file)
-			// then no conflict is possible
-			continue
-		}
+	return errs
+}
 
-		for _, child := range node.Children {
-			errs = append(errs, checkDocumentConflicts(child, exists, paths)...)
+// rootNodes returns the nodes at the root path keys below node, by the paths
+// to them. Bundle roots may contain wildcard keys ("*") matching any key. If a
+// node cannot be found from the AST (e.g. the root is from a data file) then
+// no conflict is possible.
+func rootNodes(node *TreeNode, keys []string, path []string) map[*TreeNode][]string {
+	if len(keys) == 0 {
+		return map[*TreeNode][]string{node: path}
+	}
+
+	if keys[0] != "*" {
+		child := node.Child(String(keys[0]))
+		if child == nil {
+			return nil
 		}
+		return rootNodes(child, keys[1:], append(path, keys[0]))
 	}
 
-	return errs
+	result := map[*TreeNode][]string{}
+	for key, child := range node.Children {
+		if s, ok := key.(String); ok {
+			maps.Copy(result, rootNodes(child, keys[1:], append(slices.Clip(path), string(s))))
+		}
+	}
+	return result
 }
 
 func checkDocumentConflicts(node *TreeNode, exists func([]string) (bool, error), path []string) Errors {
@@ -0,0 +1,240 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+// typeTests maps the built-in functions testing the type of their operand to
+// the name of the type they test for.
+var typeTests = map[string]string{
+	IsNumber.Name:  "number",
+	IsString.Name:  "string",
+	IsBoolean.Name: "boolean",
+	IsArray.Name:   "array",
+	IsSet.Name:     "set",
+	IsObject.Name:  "object",
+	IsNull.Name:    "null",
+}
+
+// constraint is what the expressions of a body seen so far require of the
+// value of a var or ref.
+type constraint struct {
+	eq      *Term   // constant the value equals, if any
+	eqExpr  *Expr   // expression requiring eq
+	neq     []*Term // constants the value does not equal
+	neqExpr []*Expr // expressions requiring neq
+	typ     string  // type of the value, if tested
+	typExpr *Expr   // expression testing typ
+}
+
+// contradictionChecker finds the expressions of a body that can never be
+// true because they contradict earlier expressions of the body, e.g., x == 2
+// after x == 1. Negated expressions and expressions with the with keyword are
+// ignored.
+type contradictionChecker struct {
+	rewritten   map[Var]Var
+	aliases     map[Var]*Term
+	constraints map[string]*constraint
+	errs        Errors
+}
+
+func newContradictionChecker(rewritten map[Var]Var) *contradictionChecker {
+	return &contradictionChecker{rewritten: rewritten}
+}
+
+// Check returns a warning for every expression of body that contradicts an
+// earlier expression.
+func (cc *contradictionChecker) Check(body Body) Errors {
+	cc.aliases = map[Var]*Term{}
+	cc.constraints = map[string]*constraint{}
+	cc.errs = nil
+
+	for _, expr := range body {
+		if expr.Negated || len(expr.With) > 0 || !expr.IsCall() {
+			continue
+		}
+
+		operands := expr.Operands()
+		name := expr.Operator().String()
+
+		switch {
+		case (name == Equality.Name || name == Equal.Name) && len(operands) == 2:
+			a, b := operands[0], operands[1]
+			if IsConstant(a.Value) {
+				a, b = b, a
+			}
+			if IsConstant(b.Value) {
+				cc.equal(expr, a, b)
+			} else if name == Equality.Name {
+				cc.alias(a, b)
+			}
+		case name == NotEqual.Name && len(operands) == 2:
+			a, b := operands[0], operands[1]
+			if IsConstant(a.Value) {
+				a, b = b, a
+			}
+			if IsConstant(b.Value) {
+				cc.notEqual(expr, a, b)
+			}
+		case len(operands) == 1:
+			if typ, ok := typeTests[name]; ok {
+				cc.typeTest(expr, operands[0], typ)
+			}
+		}
+	}
+
+	return cc.errs
+}
+
+// alias records that the var a (or b) has the value of the ref b (or a), if
+// nothing is required of the var yet, so that x := input.x; x == 1 constrains
+// input.x.
+func (cc *contradictionChecker) alias(a, b *Term) {
+	v, ok := a.Value.(Var)
+	if !ok {
+		if v, ok = b.Value.(Var); !ok {
+			return
+		}
+		a, b = b, a
+	}
+
+	if _, ok := b.Value.(Ref); !ok {
+		return
+	}
+
+	if _, ok := cc.aliases[v]; ok {
+		return
+	}
+	if _, ok := cc.constraints[a.String()]; ok {
+		return
+	}
+
+	cc.aliases[v] = cc.resolve(b)
+}
+
+func (cc *contradictionChecker) equal(expr *Expr, x, value *Term) {
+	c := cc.constraint(x)
+	if c == nil {
+		return
+	}
+
+	switch {
+	case c.eq != nil && c.eq.Value.Compare(value.Value) != 0:
+		cc.report(expr, c.eqExpr, x, c.eq.String(), value.String())
+		return
+	case c.typ != "" && ValueName(value.Value) != c.typ:
+		cc.report(expr, c.typExpr, x, typeDescription(c.typ), value.String())
+		return
+	}
+
+	for i := range c.neq {
+		if c.neq[i].Value.Compare(value.Value) == 0 {
+			cc.report(expr, c.neqExpr[i], x, "not "+c.neq[i].String(), value.String())
+			return
+		}
+	}
+
+	if c.eq == nil {
+		c.eq, c.eqExpr = value, expr
+	}
+}
+
+func (cc *contradictionChecker) notEqual(expr *Expr, x, value *Term) {
+	c := cc.constraint(x)
+	if c == nil {
+		return
+	}
+
+	if c.eq != nil && c.eq.Value.Compare(value.Value) == 0 {
+		cc.report(expr, c.eqExpr, x, c.eq.String(), "not "+value.String())
+		return
+	}
+
+	c.neq = append(c.neq, value)
+	c.neqExpr = append(c.neqExpr, expr)
+}
+
+func (cc *contradictionChecker) typeTest(expr *Expr, x *Term, typ string) {
+	c := cc.constraint(x)
+	if c == nil {
+		return
+	}
+
+	switch {
+	case c.typ != "" && c.typ != typ:
+		cc.report(expr, c.typExpr, x, typeDescription(c.typ), typeDescription(typ))
+		return
+	case c.eq != nil && ValueName(c.eq.Value) != typ:
+		cc.report(expr, c.eqExpr, x, c.eq.String(), typeDescription(typ))
+		return
+	}
+
+	if c.typ == "" {
+		c.typ, c.typExpr = typ, expr
+	}
+}
+
+// constraint returns the constraint of the var or ref x, resolving aliases, or
+// nil if x is neither. Terms with wildcards are ignored since all wildcards
+// print the same, e.g., input.x[_] == 1; input.x[_] == 2 is satisfiable.
+func (cc *contradictionChecker) constraint(x *Term) *constraint {
+	switch x.Value.(type) {
+	case Var, Ref:
+	default:
+		return nil
+	}
+
+	x = cc.resolve(x)
+	for v := range x.Vars() {
+		if v.IsWildcard() {
+			return nil
+		}
+	}
+	key := x.String()
+
+	c, ok := cc.constraints[key]
+	if !ok {
+		c = &constraint{}
+		cc.constraints[key] = c
+	}
+	return c
+}
+
+func (cc *contradictionChecker) resolve(x *Term) *Term {
+	if v, ok := x.Value.(Var); ok {
+		if t, ok := cc.aliases[v]; ok {
+			return t
+		}
+	}
+	return x
+}
+
+// report warns about expr contradicting prev. Vars are reported by the names
+// in the policy, and vars introduced by the compiler by the refs they alias.
+func (cc *contradictionChecker) report(expr, prev *Expr, x *Term, prevDesc, desc string) {
+	if v, ok := x.Value.(Var); ok && v.IsGenerated() {
+		if _, ok := cc.rewritten[v]; !ok {
+			x = cc.resolve(x)
+		}
+	}
+
+	name, _ := TransformVars(x, func(v Var) (Value, error) {
+		if w, ok := cc.rewritten[v]; ok {
+			return w, nil
+		}
+		return v, nil
+	})
+
+	cc.errs = append(cc.errs, NewError(CompileErr, expr.Loc(), "expression is always false, %v cannot be %s and %s", name, prevDesc, desc).
+		WithRelated(prev.Loc(), "conflicting expression"))
+}
+
+func typeDescription(typ string) string {
+	switch typ {
+	case "null":
+		return typ
+	case "array", "object":
+		return "an " + typ
+	}
+	return "a " + typ
+}
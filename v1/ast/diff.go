@@ -0,0 +1,198 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ChangeType describes how a rule differs between two modules.
+type ChangeType string
+
+// Types of changes reported by SemanticDiff.
+const (
+	ChangeAdded    ChangeType = "added"
+	ChangeRemoved  ChangeType = "removed"
+	ChangeModified ChangeType = "modified"
+)
+
+// Change represents a rule that differs between two modules.
+type Change struct {
+	Type   ChangeType `json:"type"`
+	Path   Ref        `json:"path"`             // path of the document produced by the rule
+	Before *Rule      `json:"before,omitempty"` // rule in the first module, nil if the rule was added
+	After  *Rule      `json:"after,omitempty"`  // rule in the second module, nil if the rule was removed
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%v %v", c.Type, c.Path)
+}
+
+// SemanticDiff returns the rules that were added, removed or modified between
+// modules a and b. Rules are compared on their AST, so differences in
+// formatting, comments, syntax variants and the names of local variables are
+// not reported. Rules are grouped by the path of the document they produce,
+// and by whether they are default rules. Within a group, rules that are not
+// equal to any rule of the other module are reported as modified in pairs,
+// in the order they appear in their module, and the remainder as added or
+// removed.
+//
+// The returned changes are sorted by path. Either module may be nil.
+func SemanticDiff(a, b *Module) []Change {
+	globals := diffGlobals(a, b)
+
+	groupsA, keysA := diffGroups(a)
+	groupsB, keysB := diffGroups(b)
+
+	keys := append(keysA, keysB...)
+	slices.Sort(keys)
+	keys = slices.Compact(keys)
+
+	var changes []Change
+
+	for _, key := range keys {
+		before := unmatchedRules(groupsA[key], groupsB[key], globals)
+		after := unmatchedRules(groupsB[key], groupsA[key], globals)
+
+		n := min(len(before), len(after))
+		for i := range n {
+			changes = append(changes, Change{Type: ChangeModified, Path: diffRulePath(after[i]), Before: before[i], After: after[i]})
+		}
+		for _, rule := range before[n:] {
+			changes = append(changes, Change{Type: ChangeRemoved, Path: diffRulePath(rule), Before: rule})
+		}
+		for _, rule := range after[n:] {
+			changes = append(changes, Change{Type: ChangeAdded, Path: diffRulePath(rule), After: rule})
+		}
+	}
+
+	return changes
+}
+
+func diffGroups(m *Module) (map[string][]*Rule, []string) {
+	if m == nil {
+		return nil, nil
+	}
+
+	groups := map[string][]*Rule{}
+	var keys []string
+
+	for _, rule := range m.Rules {
+		key := diffRulePath(rule).String()
+		if rule.Default {
+			key += " default"
+		}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], rule)
+	}
+
+	return groups, keys
+}
+
+func diffRulePath(rule *Rule) Ref {
+	if rule.Module == nil {
+		return rule.Head.Ref().GroundPrefix()
+	}
+	return rule.Path()
+}
+
+// unmatchedRules returns the rules in xs that are not equal to a rule in ys,
+// counting duplicates.
+func unmatchedRules(xs, ys []*Rule, globals VarSet) []*Rule {
+	counts := make(map[string]int, len(ys))
+	for _, y := range ys {
+		counts[normalizeRule(y, globals)]++
+	}
+
+	var result []*Rule
+	for _, x := range xs {
+		key := normalizeRule(x, globals)
+		if counts[key] > 0 {
+			counts[key]--
+			continue
+		}
+		result = append(result, x)
+	}
+
+	return result
+}
+
+// normalizeRule returns a string representation of the rule where local
+// variables are renamed in the order they appear, and syntax variants that
+// do not affect the meaning of the rule are erased.
+func normalizeRule(rule *Rule, globals VarSet) string {
+	cpy := rule.Copy()
+
+	// Rule heads are not walked into their references, which hold the
+	// variables of rules like p[x].q if { ... }, so they are walked first.
+	nodes := []any{}
+	for r := cpy; r != nil; r = r.Else {
+		nodes = append(nodes, r.Head.Reference)
+	}
+	nodes = append(nodes, cpy)
+
+	names := map[Var]Var{}
+	for _, node := range nodes {
+		WalkVars(node, func(v Var) bool {
+			if _, ok := names[v]; !ok && !globals.Contains(v) {
+				names[v] = Var(fmt.Sprintf("__diff%d__", len(names)))
+			}
+			return false
+		})
+	}
+
+	// The copy is only used for printing, so terms are renamed in place.
+	for _, node := range nodes {
+		WalkNodes(node, func(t *Term) bool {
+			if v, ok := t.Value.(Var); ok {
+				if n, ok := names[v]; ok {
+					t.Value = n
+				}
+			}
+			return false
+		})
+	}
+
+	for r := cpy; r != nil; r = r.Else {
+		r.Head.Assign = false
+	}
+
+	return cpy.stringWithOpts(toStringOpts{regoVersion: RegoV1})
+}
+
+// diffGlobals returns the variables that do not refer to local variables in
+// rules of a or b: root documents, rules, imports and built-in functions.
+func diffGlobals(a, b *Module) VarSet {
+	globals := NewVarSet()
+
+	RootDocumentNames.Foreach(func(t *Term) {
+		globals.Add(t.Value.(Var))
+	})
+
+	for name := range BuiltinMap {
+		head, _, _ := strings.Cut(name, ".")
+		globals.Add(Var(head))
+	}
+
+	for _, m := range []*Module{a, b} {
+		if m == nil {
+			continue
+		}
+		for _, imp := range m.Imports {
+			globals.Add(imp.Name())
+		}
+		for _, rule := range m.Rules {
+			if v, ok := rule.Head.Ref()[0].Value.(Var); ok {
+				globals.Add(v)
+			}
+		}
+	}
+
+	return globals
+}
@@ -0,0 +1,112 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSemanticDiff(t *testing.T) {
+	tests := []struct {
+		note string
+		a, b string
+		exp  []string
+	}{
+		{
+			note: "no changes",
+			a:    "package a\np if { input.x == 1 }",
+			b:    "package a\n\n# comment\np if {\n\tinput.x == 1\n}",
+		},
+		{
+			note: "renamed local variables",
+			a:    "package a\np contains x if { some x; x := input.xs[_]; x > 1 }\nf(a) := b if { b := a }",
+			b:    "package a\np contains y if { some y; y := input.xs[_]; y > 1 }\nf(x) := y if { y := x }",
+		},
+		{
+			note: "renamed variables in rule head references",
+			a:    "package a\np[x].q := y if { x := input.a; y := input.b }",
+			b:    "package a\np[z].q := w if { z := input.a; w := input.b }",
+		},
+		{
+			note: "swapped variables in rule head references",
+			a:    "package a\np[x].q := y if { x := input.a; y := input.b }",
+			b:    "package a\np[y].q := x if { x := input.a; y := input.b }",
+			exp:  []string{"modified data.a.p"},
+		},
+		{
+			note: "assignment operator",
+			a:    "package a\np := 1",
+			b:    "package a\np = 1",
+		},
+		{
+			note: "reference to renamed rule",
+			a:    "package a\nq if { x }\nx := true\ny := true",
+			b:    "package a\nq if { y }\nx := true\ny := true",
+			exp:  []string{"modified data.a.q"},
+		},
+		{
+			note: "added, removed and modified",
+			a:    "package a\np := 1\nq := 1\ndefault r := false",
+			b:    "package a\np := 2\ns := 1\ndefault r := false",
+			exp:  []string{"modified data.a.p", "removed data.a.q", "added data.a.s"},
+		},
+		{
+			note: "multiple definitions",
+			a:    "package a\np contains 1\np contains 2\np contains 3",
+			b:    "package a\np contains 3\np contains 4\np contains 1",
+			exp:  []string{"modified data.a.p"},
+		},
+		{
+			note: "default rules",
+			a:    "package a\ndefault p := false\np if { input.x }",
+			b:    "package a\ndefault p := true\np if { input.x }",
+			exp:  []string{"modified data.a.p"},
+		},
+		{
+			note: "else",
+			a:    "package a\np := 1 if { input.x } else := 2",
+			b:    "package a\np := 1 if { input.x } else := 3",
+			exp:  []string{"modified data.a.p"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			changes := SemanticDiff(MustParseModule(tc.a), MustParseModule(tc.b))
+
+			var act []string
+			for _, c := range changes {
+				act = append(act, c.String())
+			}
+
+			if !slices.Equal(tc.exp, act) {
+				t.Fatalf("expected %v but got %v", tc.exp, act)
+			}
+		})
+	}
+}
+
+func TestSemanticDiffRules(t *testing.T) {
+	a := MustParseModule("package a\np := 1\nq := 1")
+	b := MustParseModule("package a\np := 2")
+
+	changes := SemanticDiff(a, b)
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes but got %v", changes)
+	}
+
+	if changes[0].Before != a.Rules[0] || changes[0].After != b.Rules[0] {
+		t.Fatalf("expected modified rules to be set, got %v and %v", changes[0].Before, changes[0].After)
+	}
+
+	if changes[1].Before != a.Rules[1] || changes[1].After != nil {
+		t.Fatalf("expected removed rule to be set, got %v and %v", changes[1].Before, changes[1].After)
+	}
+
+	if changes := SemanticDiff(nil, b); len(changes) != 1 || changes[0].Type != ChangeAdded {
+		t.Fatalf("expected rule to be added, got %v", changes)
+	}
+}
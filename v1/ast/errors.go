@@ -78,12 +78,26 @@ type ErrorDetails interface {
 	Lines() []string
 }
 
+// ErrorDetail represents a location related to an error, e.g., another rule
+// conflicting with the rule the error is reported for.
+type ErrorDetail struct {
+	Message  string    `json:"message"`
+	Location *Location `json:"location,omitempty"`
+}
+
 // Error represents a single error caught during parsing, compiling, etc.
 type Error struct {
 	Code     string       `json:"code"`
 	Message  string       `json:"message"`
 	Location *Location    `json:"location,omitempty"`
 	Details  ErrorDetails `json:"details,omitempty"`
+
+	// Related are other locations relevant to the error, for editors to
+	// present along with it.
+	Related []ErrorDetail `json:"related,omitempty"`
+
+	// Suggestion describes how the error may be fixed, if known.
+	Suggestion string `json:"suggestion,omitempty"`
 }
 
 func (e *Error) Error() string {
@@ -122,3 +136,15 @@ func NewError(code string, loc *Location, f string, a ...any) *Error {
 		Message:  fmt.Sprintf(f, a...),
 	}
 }
+
+// WithRelated adds a related location to the error, and returns the error.
+func (e *Error) WithRelated(loc *Location, f string, a ...any) *Error {
+	e.Related = append(e.Related, ErrorDetail{Location: loc, Message: fmt.Sprintf(f, a...)})
+	return e
+}
+
+// WithSuggestion sets the suggested fix of the error, and returns the error.
+func (e *Error) WithSuggestion(f string, a ...any) *Error {
+	e.Suggestion = fmt.Sprintf(f, a...)
+	return e
+}
@@ -4,7 +4,10 @@
 
 package ast
 
-import "testing"
+import (
+	"encoding/json"
+	"testing"
+)
 
 func TestErrorsString(t *testing.T) {
 
@@ -39,3 +42,25 @@ foo.rego:100: rego_parse_error: blarg`
 	}
 
 }
+
+func TestErrorJSONRelatedAndSuggestion(t *testing.T) {
+
+	err := NewError(TypeErr, NewLocation(nil, "foo.rego", 3, 1), "conflicting rules data.foo.p found").
+		WithRelated(NewLocation(nil, "bar.rego", 5, 1), "conflicting rule %v", "data.foo.p").
+		WithSuggestion("rename %v", "data.foo.p")
+
+	bs, e := json.Marshal(err)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	expected := `{"code":"rego_type_error","message":"conflicting rules data.foo.p found","location":{"file":"foo.rego","row":3,"col":1},"related":[{"message":"conflicting rule data.foo.p","location":{"file":"bar.rego","row":5,"col":1}}],"suggestion":"rename data.foo.p"}`
+	if string(bs) != expected {
+		t.Errorf("Expected %v but got: %v", expected, string(bs))
+	}
+
+	// The related locations and suggestion are not part of the message.
+	if exp := "foo.rego:3: rego_type_error: conflicting rules data.foo.p found"; err.Error() != exp {
+		t.Errorf("Expected %v but got: %v", exp, err.Error())
+	}
+}
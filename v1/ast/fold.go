@@ -0,0 +1,154 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+// BuiltinEvaluator evaluates a call to the built-in function bi with constant
+// operands. It returns the result of the call, or nil if the call is
+// undefined.
+type BuiltinEvaluator func(bi *Builtin, operands []*Term) (*Term, error)
+
+var builtinEvaluator BuiltinEvaluator
+
+// RegisterBuiltinEvaluator sets the function used by the compiler to evaluate
+// calls to built-in functions when constant folding is enabled. The topdown
+// package registers its built-in function implementations on initialization.
+func RegisterBuiltinEvaluator(f BuiltinEvaluator) {
+	builtinEvaluator = f
+}
+
+// unfoldableBuiltins contains built-in functions that do not depend on the
+// built-in context, but whose results still depend on the time of evaluation.
+var unfoldableBuiltins = map[string]struct{}{
+	CryptoX509ParseAndVerifyCertificates.Name:            {},
+	CryptoX509ParseAndVerifyCertificatesWithOptions.Name: {},
+}
+
+// foldConstants replaces calls to deterministic built-in functions with
+// constant operands by the result of the call.
+func (c *Compiler) foldConstants() {
+	if !c.constantFolding || builtinEvaluator == nil {
+		return
+	}
+
+	// Calls to built-in functions replaced with the with keyword must be kept,
+	// as the replacement applies to the evaluation of the rules called too.
+	mocked := map[string]struct{}{}
+	for _, name := range c.sorted {
+		WalkNodes(c.Modules[name], func(w *With) bool {
+			switch target := w.Target.Value.(type) {
+			case Var, Ref:
+				mocked[target.String()] = struct{}{}
+			}
+			return false
+		})
+	}
+
+	var rule *Rule // rule being folded
+	var t *GenericTransformer
+	t = NewGenericTransformer(func(x any) (any, error) {
+		call, ok := x.(Call)
+		if !ok {
+			return x, nil
+		}
+
+		// Fold the operands first, so that nested calls can be folded too.
+		cpy := make(Call, len(call))
+		cpy[0] = call[0]
+		for i := 1; i < len(call); i++ {
+			v, err := transformValue(t, call[i].Value)
+			if err != nil {
+				return nil, err
+			}
+			cpy[i] = &Term{Value: v, Location: call[i].Location}
+		}
+
+		if result := c.foldCall(rule, cpy, mocked); result != nil {
+			return result.Value, nil
+		}
+
+		return cpy, nil
+	})
+
+	for _, name := range c.sorted {
+		for _, rule = range c.Modules[name].Rules {
+			if _, err := Transform(t, rule); err != nil {
+				c.err(NewError(CompileErr, nil, "%v", err))
+				return
+			}
+		}
+	}
+}
+
+// foldCall returns the result of call in rule, if it can be folded. The
+// built-in function called is still required by rule.
+func (c *Compiler) foldCall(rule *Rule, call Call, mocked map[string]struct{}) *Term {
+	bi, result := c.evalConstantCall(call, mocked)
+	if result != nil {
+		c.Required.addBuiltinSorted(bi)
+		c.requiredFor(rule).addBuiltinSorted(bi)
+	}
+	return result
+}
+
+// FoldCall returns the result of call if it is a call to a deterministic
+// built-in function with constant operands, and nil otherwise. Built-in
+// functions are resolved against the built-ins known to c, so c must have
+// compiled the policy that call originates from. Calls are only evaluated if
+// a BuiltinEvaluator has been registered, i.e., if the topdown package is
+// linked into the program. Calls that fail are not folded.
+func (c *Compiler) FoldCall(call Call) *Term {
+	_, result := c.evalConstantCall(call, nil)
+	return result
+}
+
+func (c *Compiler) evalConstantCall(call Call, mocked map[string]struct{}) (*Builtin, *Term) {
+	if builtinEvaluator == nil {
+		return nil, nil
+	}
+
+	ref, ok := call[0].Value.(Ref)
+	if !ok {
+		return nil, nil
+	}
+
+	name := ref.String()
+	bi, ok := c.builtins[name]
+	if !ok || bi.Nondeterministic || bi.Relation || len(bi.OutputArgs) > 0 || bi.NeedsBuiltInContext() || bi.IsDeprecated() {
+		return nil, nil
+	}
+
+	if _, ok := unfoldableBuiltins[name]; ok {
+		return nil, nil
+	}
+
+	if _, ok := c.unsafeBuiltinsMap[name]; ok {
+		return nil, nil
+	}
+
+	if _, ok := mocked[name]; ok {
+		return nil, nil
+	}
+
+	operands := call[1:]
+	fargs := bi.Decl.FuncArgs()
+	if fargs.Variadic != nil || len(operands) != len(fargs.Args) || bi.Decl.Result() == nil {
+		return nil, nil
+	}
+
+	for _, op := range operands {
+		if !IsConstant(op.Value) {
+			return nil, nil
+		}
+	}
+
+	// Calls that fail are left in place, so that errors are reported when the
+	// policy is evaluated.
+	result, err := builtinEvaluator(bi, operands)
+	if err != nil || result == nil {
+		return nil, nil
+	}
+
+	return bi, result
+}
@@ -0,0 +1,106 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// InternPool deduplicates strings seen while parsing policies and decoding
+// data, so that identical keys, string literals and ref elements shared by
+// many modules or data files are only stored once. Unlike the global interned
+// terms, an InternPool is safe for concurrent use and may be populated at any
+// time, e.g. by sharing a single pool across all bundle reads.
+type InternPool struct {
+	mtx     sync.RWMutex
+	strings map[string]string
+}
+
+// NewInternPool returns a new, empty InternPool.
+func NewInternPool() *InternPool {
+	return &InternPool{
+		strings: map[string]string{},
+	}
+}
+
+// String returns the canonical copy of s held by the pool, adding it if not
+// already present. The first copy stored is cloned, so that the pool never
+// retains a reference to a larger buffer that s may have been sliced from.
+func (p *InternPool) String(s string) string {
+	if p == nil || s == "" {
+		return s
+	}
+
+	p.mtx.RLock()
+	v, ok := p.strings[s]
+	p.mtx.RUnlock()
+	if ok {
+		return v
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if v, ok := p.strings[s]; ok {
+		return v
+	}
+	v = strings.Clone(s)
+	p.strings[v] = v
+	return v
+}
+
+// Value interns all object keys and strings found in v, which is expected to
+// be the result of decoding JSON or YAML (i.e. composed of map[string]any,
+// []any and scalar values). Maps and slices are updated in place, and the
+// (possibly new) value is returned.
+func (p *InternPool) Value(v any) any {
+	if p == nil {
+		return v
+	}
+	switch x := v.(type) {
+	case string:
+		return p.String(x)
+	case []any:
+		for i := range x {
+			x[i] = p.Value(x[i])
+		}
+	case map[string]any:
+		for k, e := range x {
+			ik := p.String(k)
+			e = p.Value(e)
+			// Re-keying with an equal string keeps the existing key, so
+			// the entry has to be removed first for the pooled copy to stick.
+			if unsafe.StringData(ik) != unsafe.StringData(k) {
+				delete(x, k)
+			}
+			x[ik] = e
+		}
+	}
+	return v
+}
+
+// Len returns the number of distinct strings held by the pool.
+func (p *InternPool) Len() int {
+	if p == nil {
+		return 0
+	}
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+	return len(p.strings)
+}
+
+// Reset drops all strings held by the pool. Strings returned before remain
+// valid, but are no longer shared with strings added afterwards. Long-lived
+// pools, e.g. the pool of the bundle plugin, are reset whenever the values
+// read with them were activated, so that they do not grow without bound.
+func (p *InternPool) Reset() {
+	if p == nil {
+		return
+	}
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	clear(p.strings)
+}
@@ -29,6 +29,8 @@ type Scanner struct {
 	width            int
 	curr             rune
 	regoV1Compatible bool
+	maxTokenLength   int
+	strictUTF8       bool
 }
 
 // Error represents a scanner error.
@@ -129,6 +131,18 @@ func (s *Scanner) RegoV1Compatible() bool {
 	return s.regoV1Compatible
 }
 
+// SetMaxTokenLength makes the scanner report an error for tokens longer than n
+// bytes. If n is 0 or negative, the length of tokens is not limited.
+func (s *Scanner) SetMaxTokenLength(n int) {
+	s.maxTokenLength = n
+}
+
+// SetStrictUTF8 makes the scanner report an error for escape sequences in
+// strings that do not encode valid UTF-8, i.e., unpaired surrogates.
+func (s *Scanner) SetStrictUTF8() {
+	s.strictUTF8 = true
+}
+
 // WithKeywords returns a new copy of the Scanner struct `s`, with the set
 // of known keywords being that of `s` with `kws` added.
 func (s *Scanner) WithKeywords(kws map[string]tokens.Token) *Scanner {
@@ -279,6 +293,10 @@ func (s *Scanner) Scan() (tokens.Token, Position, string, []Error) {
 	}
 
 	pos.End = s.offset - s.width
+	if s.maxTokenLength > 0 && pos.End-pos.Offset > s.maxTokenLength {
+		s.errorAt(pos, fmt.Sprintf("token exceeds maximum length of %d bytes", s.maxTokenLength))
+	}
+
 	errs := s.errors
 	s.errors = nil
 
@@ -347,6 +365,7 @@ func (s *Scanner) scanNumber() string {
 
 func (s *Scanner) scanString() string {
 	start := s.literalStart()
+	var high *Position // position of a high surrogate escape awaiting its pair
 	for {
 		ch := s.curr
 
@@ -355,30 +374,93 @@ func (s *Scanner) scanString() string {
 			break
 		}
 
+		pos := s.currPos()
 		s.next()
 
-		if ch == '"' {
-			break
-		}
-
+		r := rune(-1) // rune encoded by a \u escape sequence
 		if ch == '\\' {
 			switch s.curr {
 			case '\\', '"', '/', 'b', 'f', 'n', 'r', 't':
 				s.next()
 			case 'u':
 				s.next()
+				if s.strictUTF8 {
+					r = s.escapedRune(pos)
+				}
 				s.next()
 				s.next()
 				s.next()
+				if r >= 0 {
+					// consume the last digit, which must not count as a
+					// character following the escape sequence
+					s.next()
+				}
 			default:
 				s.error("illegal escape sequence")
 			}
 		}
+
+		if s.strictUTF8 {
+			high = s.checkSurrogate(pos, high, r)
+		}
+
+		if ch == '"' {
+			break
+		}
 	}
 
 	return util.ByteSliceToString(s.bs[start : s.offset-1])
 }
 
+// escapedRune returns the rune encoded by the four hexadecimal digits of the
+// \u escape sequence at pos, starting at the current character. If they are
+// not hexadecimal digits, it reports an error and returns -1.
+func (s *Scanner) escapedRune(pos Position) rune {
+	begin := s.offset - s.width
+	if begin+4 > len(s.bs) {
+		return -1
+	}
+
+	var r rune
+	for _, b := range s.bs[begin : begin+4] {
+		var d byte
+		switch {
+		case '0' <= b && b <= '9':
+			d = b - '0'
+		case 'a' <= lower(rune(b)) && lower(rune(b)) <= 'f':
+			d = byte(lower(rune(b))) - 'a' + 10
+		default:
+			s.errorAt(pos, "illegal escape sequence")
+			return -1
+		}
+		r = r<<4 | rune(d)
+	}
+
+	return r
+}
+
+// checkSurrogate reports unpaired surrogates in escape sequences, given the
+// position of the current character of a string, the rune it escapes (or -1),
+// and the position of the preceding high surrogate escape, if any. It returns
+// the position of the high surrogate escape awaiting its pair, if any.
+func (s *Scanner) checkSurrogate(pos Position, high *Position, r rune) *Position {
+	if high != nil {
+		if r >= 0xDC00 && r <= 0xDFFF {
+			return nil
+		}
+		s.errorAt(*high, "illegal unpaired surrogate in escape sequence")
+	}
+
+	switch {
+	case r >= 0xD800 && r <= 0xDBFF:
+		return &pos
+	case r >= 0xDC00 && r <= 0xDFFF:
+		s.errorAt(pos, "illegal unpaired surrogate in escape sequence")
+	}
+
+	return nil
+}
+
 func (s *Scanner) scanRawString() string {
 	start := s.literalStart()
 	for {
@@ -420,14 +502,18 @@ func (s *Scanner) next() {
 	s.curr = rune(s.bs[s.offset])
 	s.width = 1
 
+	// The column is not advanced yet, so errors about the character are
+	// reported at the column it is read into.
+	pos := Position{Offset: s.offset, Row: s.row, Col: s.col + 1}
+
 	if s.curr == 0 {
-		s.error("illegal null character")
+		s.errorAt(pos, "illegal null character")
 	} else if s.curr >= utf8.RuneSelf {
 		s.curr, s.width = utf8.DecodeRune(s.bs[s.offset:])
 		if s.curr == utf8.RuneError && s.width == 1 {
-			s.error("illegal utf-8 character")
+			s.errorAt(pos, "illegal utf-8 character")
 		} else if s.curr == bom && s.offset > 0 {
-			s.error("illegal byte-order mark")
+			s.errorAt(pos, "illegal byte-order mark")
 		}
 	}
 
@@ -470,9 +556,18 @@ func (s *Scanner) isWhitespace() bool {
 }
 
 func (s *Scanner) error(reason string) {
+	s.errorAt(Position{Offset: s.offset, Row: s.row, Col: s.col}, reason)
+}
+
+func (s *Scanner) errorAt(pos Position, reason string) {
 	s.errors = append(s.errors, Error{Pos: Position{
-		Offset: s.offset,
-		Row:    s.row,
-		Col:    s.col,
+		Offset: pos.Offset,
+		Row:    pos.Row,
+		Col:    pos.Col,
 	}, Message: reason})
 }
+
+// currPos returns the position of the current character.
+func (s *Scanner) currPos() Position {
+	return Position{Offset: s.offset - s.width, Row: s.row, Col: s.col}
+}
@@ -2,6 +2,7 @@ package ast_test
 
 import (
 	"testing"
+	"unsafe"
 
 	"github.com/open-policy-agent/opa/v1/ast"
 )
@@ -64,3 +65,76 @@ func BenchmarkInterningAccessValue(b *testing.B) {
 		}
 	})
 }
+
+func TestInternPool(t *testing.T) {
+	pool := ast.NewInternPool()
+
+	a := pool.String(string([]byte("owner")))
+	b := pool.String(string([]byte("owner")))
+	if unsafe.StringData(a) != unsafe.StringData(b) {
+		t.Fatal("expected identical strings to share storage")
+	}
+
+	if pool.Len() != 1 {
+		t.Fatalf("expected 1 pooled string, got %d", pool.Len())
+	}
+
+	pool.Reset()
+	if pool.Len() != 0 {
+		t.Fatalf("expected empty pool after reset, got %d", pool.Len())
+	}
+	if c := pool.String(string([]byte("owner"))); c != "owner" || unsafe.StringData(c) == unsafe.StringData(a) {
+		t.Fatal("expected string added after reset to be stored anew")
+	}
+
+	var nilPool *ast.InternPool
+	nilPool.Reset()
+	if nilPool.String("x") != "x" || nilPool.Len() != 0 {
+		t.Fatal("expected nil pool to be a no-op")
+	}
+}
+
+func TestInternPoolParser(t *testing.T) {
+	pool := ast.NewInternPool()
+	popts := ast.ParserOptions{InternPool: pool}
+
+	m1 := ast.MustParseModuleWithOpts(`package a
+p := input.owner == "owner"`, popts)
+	m2 := ast.MustParseModuleWithOpts(`package b
+q := input.owner == "owner"`, popts)
+
+	elem := func(m *ast.Module) (string, string) {
+		expr := m.Rules[0].Head.Value.Value.(ast.Call)
+		ref := expr[1].Value.(ast.Ref)
+		return string(ref[1].Value.(ast.String)), string(expr[2].Value.(ast.String))
+	}
+
+	r1, s1 := elem(m1)
+	r2, s2 := elem(m2)
+
+	if unsafe.StringData(r1) != unsafe.StringData(r2) || unsafe.StringData(s1) != unsafe.StringData(s2) {
+		t.Fatal("expected strings to be shared across modules")
+	}
+	if unsafe.StringData(r1) != unsafe.StringData(s1) {
+		t.Fatal("expected ref element and string literal to be shared")
+	}
+}
+
+func TestInternPoolValue(t *testing.T) {
+	pool := ast.NewInternPool()
+	owner := pool.String("owner")
+
+	v := map[string]any{
+		string([]byte("owner")): []any{string([]byte("owner")), 1},
+	}
+	pool.Value(v)
+
+	for k, e := range v {
+		if unsafe.StringData(k) != unsafe.StringData(owner) {
+			t.Fatal("expected key to be interned")
+		}
+		if s := e.([]any)[0].(string); unsafe.StringData(s) != unsafe.StringData(owner) {
+			t.Fatal("expected element to be interned")
+		}
+	}
+}
@@ -0,0 +1,268 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"unicode/utf8"
+)
+
+// ValueFromJSONReader returns an AST value from the first JSON serialized value
+// in the reader. Unlike ValueFromReader, the value is built while the JSON
+// tokens are read, i.e., without decoding them into intermediate native Go
+// values first, which reduces the overhead of parsing large documents.
+func ValueFromJSONReader(r io.Reader) (Value, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	return valueFromJSONToken(dec, tok)
+}
+
+func valueFromJSONToken(dec *json.Decoder, tok json.Token) (Value, error) {
+	switch tok := tok.(type) {
+	case nil:
+		return NullValue, nil
+	case bool:
+		return InternedTerm(tok).Value, nil
+	case json.Number:
+		if interned := InternedIntNumberTermFromString(string(tok)); interned != nil {
+			return interned.Value, nil
+		}
+		return Number(tok), nil
+	case string:
+		return String(tok), nil
+	case json.Delim:
+		switch tok {
+		case '[':
+			return arrayFromJSONTokens(dec)
+		case '{':
+			return objectFromJSONTokens(dec)
+		}
+	}
+	return nil, fmt.Errorf("ast: unexpected JSON token %v", tok)
+}
+
+func arrayFromJSONTokens(dec *json.Decoder) (Value, error) {
+	var elems []*Term
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		v, err := valueFromJSONToken(dec, tok)
+		if err != nil {
+			return nil, err
+		}
+		elems = append(elems, NewTerm(v))
+	}
+
+	// Consume the closing delimiter.
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+
+	return NewArray(elems...), nil
+}
+
+func objectFromJSONTokens(dec *json.Decoder) (Value, error) {
+	obj := newobject(0)
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		k, ok := tok.(string)
+		if !ok {
+			return nil, fmt.Errorf("ast: unexpected JSON object key %v", tok)
+		}
+		if tok, err = dec.Token(); err != nil {
+			return nil, err
+		}
+		v, err := valueFromJSONToken(dec, tok)
+		if err != nil {
+			return nil, err
+		}
+		// Like encoding/json, the last of duplicate keys wins.
+		obj.Insert(StringTerm(k), NewTerm(v))
+	}
+
+	// Consume the closing delimiter.
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+// ValueToJSONWriter writes the JSON representation of v to w, without
+// converting v into native Go values first. Like JSON, the value must not
+// contain any refs or terms that require evaluation (e.g., vars,
+// comprehensions, etc.) Sets are written as arrays, and object keys that are
+// not strings are written as the strings of their JSON representation. The
+// elements of sets and objects are written in sorted order.
+func ValueToJSONWriter(w io.Writer, v Value) error {
+	bw := bufio.NewWriter(w)
+	if err := writeJSONValue(bw, v); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func writeJSONValue(w *bufio.Writer, v Value) error {
+	switch v := v.(type) {
+	case Null:
+		_, err := w.WriteString("null")
+		return err
+	case Boolean:
+		_, err := w.WriteString(strconv.FormatBool(bool(v)))
+		return err
+	case Number:
+		_, err := w.WriteString(string(v))
+		return err
+	case String:
+		return writeJSONString(w, string(v))
+	case *Array:
+		if err := w.WriteByte('['); err != nil {
+			return err
+		}
+		for i := range v.Len() {
+			if i > 0 {
+				if err := w.WriteByte(','); err != nil {
+					return err
+				}
+			}
+			if err := writeJSONValue(w, v.Elem(i).Value); err != nil {
+				return err
+			}
+		}
+		return w.WriteByte(']')
+	case Set:
+		if err := w.WriteByte('['); err != nil {
+			return err
+		}
+		first := true
+		err := v.Iter(func(x *Term) error {
+			if !first {
+				if err := w.WriteByte(','); err != nil {
+					return err
+				}
+			}
+			first = false
+			return writeJSONValue(w, x.Value)
+		})
+		if err != nil {
+			return err
+		}
+		return w.WriteByte(']')
+	case *lazyObj:
+		return writeJSONValue(w, v.force())
+	case Object:
+		if err := w.WriteByte('{'); err != nil {
+			return err
+		}
+		first := true
+		err := v.Iter(func(k, x *Term) error {
+			if !first {
+				if err := w.WriteByte(','); err != nil {
+					return err
+				}
+			}
+			first = false
+			if err := writeJSONKey(w, k.Value); err != nil {
+				return err
+			}
+			if err := w.WriteByte(':'); err != nil {
+				return err
+			}
+			return writeJSONValue(w, x.Value)
+		})
+		if err != nil {
+			return err
+		}
+		return w.WriteByte('}')
+	default:
+		return fmt.Errorf("%v requires evaluation", TypeName(v))
+	}
+}
+
+// writeJSONKey writes k as a JSON object key, i.e., as a string.
+func writeJSONKey(w *bufio.Writer, k Value) error {
+	if s, ok := k.(String); ok {
+		return writeJSONString(w, string(s))
+	}
+
+	var buf bytes.Buffer
+	kw := bufio.NewWriter(&buf)
+	if err := writeJSONValue(kw, k); err != nil {
+		return err
+	}
+	if err := kw.Flush(); err != nil {
+		return err
+	}
+	return writeJSONString(w, buf.String())
+}
+
+const hexDigits = "0123456789abcdef"
+
+// writeJSONString writes s as a JSON string. Like encoding/json, invalid UTF-8
+// is replaced by U+FFFD, and U+2028 and U+2029 are escaped, but HTML characters
+// are not. Errors of w are sticky, and returned by the last write.
+func writeJSONString(w *bufio.Writer, s string) error {
+	_ = w.WriteByte('"')
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if b >= 0x20 && b != '"' && b != '\\' {
+				i++
+				continue
+			}
+			_, _ = w.WriteString(s[start:i])
+			switch b {
+			case '"', '\\':
+				_, _ = w.Write([]byte{'\\', b})
+			case '\n':
+				_, _ = w.WriteString(`\n`)
+			case '\r':
+				_, _ = w.WriteString(`\r`)
+			case '\t':
+				_, _ = w.WriteString(`\t`)
+			default:
+				_, _ = w.Write([]byte{'\\', 'u', '0', '0', hexDigits[b>>4], hexDigits[b&0xF]})
+			}
+			i++
+			start = i
+			continue
+		}
+		c, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case c == utf8.RuneError && size == 1:
+			_, _ = w.WriteString(s[start:i])
+			_, _ = w.WriteString(`\ufffd`)
+		case c == '\u2028' || c == '\u2029':
+			_, _ = w.WriteString(s[start:i])
+			_, _ = w.WriteString(`\u202`)
+			_ = w.WriteByte(hexDigits[c&0xF])
+		default:
+			i += size
+			continue
+		}
+		i += size
+		start = i
+	}
+	_, _ = w.WriteString(s[start:])
+	return w.WriteByte('"')
+}
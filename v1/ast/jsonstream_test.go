@@ -0,0 +1,116 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValueFromJSONReader(t *testing.T) {
+	tests := []struct {
+		note    string
+		input   string
+		exp     string
+		wantErr bool
+	}{
+		{note: "null", input: `null`, exp: `null`},
+		{note: "number", input: `1.5e3`, exp: `1.5e3`},
+		{note: "nested", input: `{"a": [1, "x", true, {"b": null}], "c": {}}`, exp: `{"a": [1, "x", true, {"b": null}], "c": {}}`},
+		{note: "duplicate keys", input: `{"a": 1, "a": 2}`, exp: `{"a": 2}`},
+		{note: "trailing values", input: `[1] [2]`, exp: `[1]`},
+		{note: "empty", input: ``, wantErr: true},
+		{note: "malformed", input: `{"a": }`, wantErr: true},
+		{note: "truncated", input: `[1, 2`, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			v, err := ValueFromJSONReader(strings.NewReader(tc.input))
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %v", v)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if exp := MustParseTerm(tc.exp).Value; exp.Compare(v) != 0 {
+				t.Fatalf("expected %v, got %v", exp, v)
+			}
+		})
+	}
+}
+
+func TestValueToJSONWriter(t *testing.T) {
+	tests := []struct {
+		note string
+		term string
+		exp  string
+		err  string
+	}{
+		{note: "scalars", term: `[null, true, 1.5, "x"]`, exp: `[null,true,1.5,"x"]`},
+		{note: "set", term: `{3, 1, 2}`, exp: `[1,2,3]`},
+		{note: "object", term: `{"b": {1}, "a": []}`, exp: `{"a":[],"b":[1]}`},
+		{note: "non-string keys", term: `{1: "a", [true]: "b"}`, exp: `{"1":"a","[true]":"b"}`},
+		{note: "escaped strings", term: `"<a\"\\\n\t\u0001\u2028>"`, exp: `"<a\"\\\n\t\u0001\u2028>"`},
+		{note: "ref", term: `[data.x]`, err: "ref requires evaluation"},
+		{note: "var", term: `{"a": x}`, err: "var requires evaluation"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := ValueToJSONWriter(&buf, MustParseTerm(tc.term).Value)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error containing %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tc.exp {
+				t.Fatalf("expected %s, got %s", tc.exp, buf.String())
+			}
+		})
+	}
+}
+
+func TestValueJSONStreamRoundTrip(t *testing.T) {
+	input := `{"a": [1, 2.5, -3e10, "é😀"], "b": {"c": null, "d": [true, false]}, "e": "</script>"}`
+
+	v, err := ValueFromJSONReader(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := ValueToJSONWriter(&buf, v); err != nil {
+		t.Fatal(err)
+	}
+
+	var exp, act any
+	if err := json.Unmarshal([]byte(input), &exp); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(buf.Bytes(), &act); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(exp, act) {
+		t.Fatalf("expected %v, got %v", exp, act)
+	}
+
+	if other, err := ValueFromReader(strings.NewReader(input)); err != nil {
+		t.Fatal(err)
+	} else if other.Compare(v) != 0 {
+		t.Fatalf("expected %v, got %v", other, v)
+	}
+}
@@ -164,7 +164,20 @@ type ParserOptions struct {
 	FutureKeywords    []string
 	SkipRules         bool
 	// RegoVersion is the version of Rego to parse for.
-	RegoVersion        RegoVersion
+	RegoVersion RegoVersion
+	// InternPool, if set, is used to deduplicate string literals, variable
+	// names and ref elements across everything parsed with the same pool.
+	InternPool *InternPool
+	// MaxTokenLength, if positive, is the maximum length in bytes of tokens,
+	// e.g., identifiers, numbers, strings and comments.
+	MaxTokenLength int
+	// MaxNestingDepth, if positive, overrides the maximum recursion depth of
+	// the parser, which limits how deeply terms and bodies can be nested.
+	MaxNestingDepth int
+	// StrictUTF8 rejects string escape sequences that do not encode valid
+	// UTF-8, i.e., unpaired surrogates, and locates encoding errors at the
+	// offending bytes rather than at the start of their token.
+	StrictUTF8         bool
 	unreleasedKeywords bool // TODO(sr): cleanup
 }
 
@@ -269,6 +282,26 @@ func (p *Parser) WithRegoVersion(version RegoVersion) *Parser {
 	return p
 }
 
+// WithInternPool sets the pool used to deduplicate strings in parsed terms.
+func (p *Parser) WithInternPool(pool *InternPool) *Parser {
+	p.po.InternPool = pool
+	return p
+}
+
+// WithMaxTokenLength sets the maximum length in bytes of tokens. If n is 0 or
+// negative, the length of tokens is not limited.
+func (p *Parser) WithMaxTokenLength(n int) *Parser {
+	p.po.MaxTokenLength = n
+	return p
+}
+
+// WithStrictUTF8 enables or disables strict UTF-8 validation, see
+// ParserOptions.StrictUTF8.
+func (p *Parser) WithStrictUTF8(yes bool) *Parser {
+	p.po.StrictUTF8 = yes
+	return p
+}
+
 func (p *Parser) parsedTermCacheLookup() (*Term, *state) {
 	l := p.s.loc.Offset
 	// stop comparing once the cached offsets are lower than l
@@ -413,6 +446,11 @@ func (p *Parser) Parse() ([]Statement, []*Comment, Errors) {
 		}
 	}
 
+	p.s.s.SetMaxTokenLength(p.po.MaxTokenLength)
+	if p.po.StrictUTF8 {
+		p.s.s.SetStrictUTF8()
+	}
+
 	selected := map[string]tokens.Token{}
 	if p.po.AllFutureKeywords || p.po.EffectiveRegoVersion() == RegoV1 {
 		maps.Copy(selected, allowedFutureKeywords)
@@ -689,6 +727,16 @@ func (p *Parser) parseImport() *Import {
 	case RootDocumentNames.Contains(path[0]):
 	case FutureRootDocument.Equal(path[0]):
 	case RegoRootDocument.Equal(path[0]):
+	case IncludeRootDocument.Equal(path[0]) && p.po.Capabilities.ContainsFeature(FeatureIncludeDirective):
+		if len(path) != 2 {
+			p.errorf(imp.Path.Location, "unexpected include path, must be of the form include[\"<file>\"], got: %v", imp.Path)
+			return nil
+		}
+		if p.s.tok == tokens.As {
+			p.errorf(imp.Path.Location, "unexpected alias for include directive")
+			return nil
+		}
+		return &imp
 	default:
 		p.hint("if this is unexpected, try updating OPA")
 		p.errorf(imp.Path.Location, "unexpected import path, must begin with one of: %v, got: %v",
@@ -1447,8 +1495,10 @@ func (p *Parser) parseEvery() *Expr {
 		p.illegal("expected `x[, y] in xs { ... }` expression")
 		return nil
 	}
-	if _, ok := qb.Value.Value.(Var); !ok {
-		p.illegal("expected value to be a variable")
+	switch qb.Value.Value.(type) {
+	case Var, *Array, Object: // every {"id": id} in xs { ... }
+	default:
+		p.illegal("expected value to be a variable, array or object")
 		return nil
 	}
 	if p.s.tok == tokens.LBrace { // every x in xs { ... }
@@ -1854,7 +1904,7 @@ func (p *Parser) parseString() *Term {
 			p.errorf(p.s.Loc(), "illegal string literal: %s", p.s.lit)
 			return nil
 		}
-		term := StringTerm(s).SetLocation(p.s.Loc())
+		term := StringTerm(p.po.InternPool.String(s)).SetLocation(p.s.Loc())
 		return term
 	}
 	return p.parseRawString()
@@ -1864,7 +1914,7 @@ func (p *Parser) parseRawString() *Term {
 	if len(p.s.lit) < 2 {
 		return nil
 	}
-	term := StringTerm(p.s.lit[1 : len(p.s.lit)-1]).SetLocation(p.s.Loc())
+	term := StringTerm(p.po.InternPool.String(p.s.lit[1 : len(p.s.lit)-1])).SetLocation(p.s.Loc())
 	return term
 }
 
@@ -1934,7 +1984,7 @@ func (p *Parser) parseRef(head *Term, offset int) (term *Term) {
 				p.illegal("expected %v", tokens.Ident)
 				return nil
 			}
-			ref = append(ref, StringTerm(p.s.lit).SetLocation(p.s.Loc()))
+			ref = append(ref, StringTerm(p.po.InternPool.String(p.s.lit)).SetLocation(p.s.Loc()))
 			p.scanWS()
 		case tokens.LParen:
 			term = p.parseCall(p.setLoc(RefTerm(ref...), loc, offset, p.s.loc.Offset), offset)
@@ -2324,7 +2374,7 @@ func (p *Parser) parseTermOpName(ref Ref, values ...tokens.Token) *Term {
 
 func (p *Parser) parseVar() *Term {
 
-	s := p.s.lit
+	s := p.po.InternPool.String(p.s.lit)
 
 	term := VarTerm(s).SetLocation(p.s.Loc())
 
@@ -2438,7 +2488,12 @@ func (p *Parser) doScan(skipws bool) {
 		p.s.loc.Tabs = pos.Tabs
 
 		for _, err := range errs {
-			p.error(p.s.Loc(), err.Message)
+			loc := p.s.Loc()
+			if p.po.StrictUTF8 {
+				loc.Row, loc.Col, loc.Offset = err.Pos.Row, err.Pos.Col, err.Pos.Offset
+				loc.Text = p.s.Text(err.Pos.Offset, err.Pos.Offset+1)
+			}
+			p.error(loc, err.Message)
 		}
 
 		if len(errs) > 0 {
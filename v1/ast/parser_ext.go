@@ -635,8 +635,15 @@ func ParseStatementsWithOpts(filename, input string, popts ParserOptions) ([]Sta
 		WithCapabilities(popts.Capabilities).
 		WithSkipRules(popts.SkipRules).
 		WithRegoVersion(popts.RegoVersion).
+		WithInternPool(popts.InternPool).
+		WithMaxTokenLength(popts.MaxTokenLength).
+		WithStrictUTF8(popts.StrictUTF8).
 		withUnreleasedKeywords(popts.unreleasedKeywords)
 
+	if popts.MaxNestingDepth > 0 {
+		parser = parser.WithMaxRecursionDepth(popts.MaxNestingDepth)
+	}
+
 	stmts, comments, errs := parser.Parse()
 
 	if len(errs) > 0 {
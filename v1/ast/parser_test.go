@@ -2441,7 +2441,30 @@ func TestEvery(t *testing.T) {
 		}, opts)
 
 	assertParseErrorContains(t, "arbitrary term", "every 10", "expected `x[, y] in xs { ... }` expression", opts)
-	assertParseErrorContains(t, "non-var value", "every 10 in xs { true }", "unexpected { token: expected value to be a variable", opts)
+	assertParseOneExpr(t, "object pattern", `every {"id": id} in xs { id }`,
+		&Expr{
+			Terms: &Every{
+				Value:  ObjectTerm(Item(StringTerm("id"), VarTerm("id"))),
+				Domain: VarTerm("xs"),
+				Body: []*Expr{
+					NewExpr(VarTerm("id")),
+				},
+			},
+		}, opts)
+
+	assertParseOneExpr(t, "array pattern with key", "every i, [a, b] in xs { a }",
+		&Expr{
+			Terms: &Every{
+				Key:    VarTerm("i"),
+				Value:  ArrayTerm(VarTerm("a"), VarTerm("b")),
+				Domain: VarTerm("xs"),
+				Body: []*Expr{
+					NewExpr(VarTerm("a")),
+				},
+			},
+		}, opts)
+
+	assertParseErrorContains(t, "non-var value", "every 10 in xs { true }", "unexpected { token: expected value to be a variable, array or object", opts)
 	assertParseErrorContains(t, "non-var key", "every 10, x in xs { true }", "unexpected { token: expected key to be a variable", opts)
 	assertParseErrorContains(t, "arbitrary call", "every f(10)", "expected `x[, y] in xs { ... }` expression", opts)
 	assertParseErrorContains(t, "no body", "every x in xs", "missing body", opts)
@@ -2884,6 +2907,21 @@ func TestFutureImports(t *testing.T) {
 	assertParseModule(t, "multiple imports, single in options", mod, &parsed, ParserOptions{FutureKeywords: []string{"in"}})
 }
 
+func TestIncludeImports(t *testing.T) {
+	caps := CapabilitiesForThisVersion()
+	caps.Features = append(caps.Features, FeatureIncludeDirective)
+	popts := ParserOptions{Capabilities: caps}
+
+	assertParseImport(t, "include", `import include["helpers.rego"]`,
+		&Import{Path: RefTerm(IncludeRootDocument, StringTerm("helpers.rego"))}, popts)
+	assertParseErrorContains(t, "include nested", `import include["helpers.rego"].x`,
+		`unexpected include path, must be of the form include["<file>"], got: include["helpers.rego"].x`, popts)
+	assertParseErrorContains(t, "include alias", `import include["helpers.rego"] as x`,
+		"unexpected alias for include directive", popts)
+	assertParseErrorContains(t, "include not supported", `import include["helpers.rego"]`,
+		"unexpected import path, must begin with one of: {data, future, input, rego}, got: include")
+}
+
 func TestFutureAndRegoV1ImportsExtraction(t *testing.T) {
 	// These tests assert that "import future..." and "import rego.v1" statements in policies cause
 	// the proper keywords to be added to the parser's list of known keywords.
@@ -8072,6 +8110,109 @@ func TestMaxParsingRecursionDepth(t *testing.T) {
 	}
 }
 
+func TestParserLimits(t *testing.T) {
+	tests := []struct {
+		note   string
+		input  string
+		opts   ParserOptions
+		errMsg string
+		errLoc *Location
+	}{
+		{
+			note:  "token within limit",
+			input: "package test\n\np := \"abc\"",
+			opts:  ParserOptions{MaxTokenLength: 10},
+		},
+		{
+			note:   "string exceeds limit",
+			input:  "package test\n\np := \"abcdefghij\"",
+			opts:   ParserOptions{MaxTokenLength: 10},
+			errMsg: "token exceeds maximum length of 10 bytes",
+			errLoc: &Location{Row: 3, Col: 6},
+		},
+		{
+			note:   "identifier exceeds limit",
+			input:  "package test\n\nabcdefghijk := 1",
+			opts:   ParserOptions{MaxTokenLength: 10},
+			errMsg: "token exceeds maximum length of 10 bytes",
+			errLoc: &Location{Row: 3, Col: 1},
+		},
+		{
+			note:   "nesting exceeds limit",
+			input:  "package test\n\np := " + generateDeeplyNestedArray(20),
+			opts:   ParserOptions{MaxNestingDepth: 10},
+			errMsg: ErrMaxParsingRecursionDepthExceeded.Error(),
+		},
+		{
+			note:  "unpaired surrogate",
+			input: "package test\n\np := \"\\ud800\"",
+		},
+		{
+			note:   "unpaired surrogate, strict",
+			input:  "package test\n\np := \"ab\\ud800c\"",
+			opts:   ParserOptions{StrictUTF8: true},
+			errMsg: "illegal unpaired surrogate in escape sequence",
+			errLoc: &Location{Row: 3, Col: 9},
+		},
+		{
+			note:   "unpaired low surrogate, strict",
+			input:  "package test\n\np := \"\\udc00\"",
+			opts:   ParserOptions{StrictUTF8: true},
+			errMsg: "illegal unpaired surrogate in escape sequence",
+			errLoc: &Location{Row: 3, Col: 7},
+		},
+		{
+			note:  "surrogate pair, strict",
+			input: "package test\n\np := \"\\ud83d\\ude00\"",
+			opts:  ParserOptions{StrictUTF8: true},
+		},
+		{
+			note:   "invalid utf-8, strict",
+			input:  "package test\n\np := \"abc\xffd\"",
+			opts:   ParserOptions{StrictUTF8: true},
+			errMsg: "illegal utf-8 character",
+			errLoc: &Location{Row: 3, Col: 10},
+		},
+		{
+			note:   "invalid utf-8 in comment, strict",
+			input:  "package test\n\n# abc\xff\np := 1",
+			opts:   ParserOptions{StrictUTF8: true},
+			errMsg: "illegal utf-8 character",
+			errLoc: &Location{Row: 3, Col: 6},
+		},
+		{
+			note:   "invalid utf-8 at start of line, strict",
+			input:  "package test\n\np := 1\n\xff",
+			opts:   ParserOptions{StrictUTF8: true},
+			errMsg: "illegal utf-8 character",
+			errLoc: &Location{Row: 4, Col: 1},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			_, err := ParseModuleWithOpts("test.rego", tc.input, tc.opts)
+			if tc.errMsg == "" {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				return
+			}
+
+			var errs Errors
+			if !errors.As(err, &errs) || len(errs) == 0 {
+				t.Fatalf("Expected errors but got: %v", err)
+			}
+			if !strings.Contains(errs[0].Message, tc.errMsg) {
+				t.Fatalf("Expected error %q but got: %v", tc.errMsg, errs)
+			}
+			if tc.errLoc != nil && (errs[0].Location.Row != tc.errLoc.Row || errs[0].Location.Col != tc.errLoc.Col) {
+				t.Fatalf("Expected error at %d:%d but got %d:%d", tc.errLoc.Row, tc.errLoc.Col, errs[0].Location.Row, errs[0].Location.Col)
+			}
+		})
+	}
+}
+
 // generateDeeplyNestedArray creates a deeply nested array as a string
 // with the specified depth.
 func generateDeeplyNestedArray(depth int) string {
@@ -41,6 +41,10 @@ var FutureRootDocument = VarTerm("future")
 // features in a future versioned release.
 var RegoRootDocument = VarTerm("rego")
 
+// IncludeRootDocument names the root of include directives, which inline the
+// rules of other modules. See FeatureIncludeDirective.
+var IncludeRootDocument = VarTerm("include")
+
 // RootDocumentNames contains the names of top-level documents that can be
 // referred to in modules and queries.
 //
@@ -0,0 +1,148 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"container/list"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// WithQueryCache enables caching the queries compiled by the query compilers
+// of the compiler, so that compiling the same query in the same context and
+// with the same options again returns the compiled query of the first
+// compilation. At most size compiled queries are kept, the least recently used
+// ones are evicted first. The cache is cleared when the compiler compiles
+// modules. Queries compiled with additional stages registered with
+// QueryCompiler.WithStageAfter, and queries that fail to compile, are not
+// cached.
+//
+// Compiled queries returned from the cache are shared by the callers, and must
+// not be modified.
+func (c *Compiler) WithQueryCache(size int) *Compiler {
+	if size > 0 {
+		c.queryCache = newQueryCache(size)
+	} else {
+		c.queryCache = nil
+	}
+	return c
+}
+
+type queryCacheEntry struct {
+	key                  string
+	query                Body
+	typeEnv              *TypeEnv
+	rewritten            map[Var]Var
+	comprehensionIndices map[*Term]*ComprehensionIndex
+}
+
+type queryCache struct {
+	mtx   sync.Mutex
+	size  int
+	l     *list.List
+	items map[string]*list.Element
+}
+
+func newQueryCache(size int) *queryCache {
+	return &queryCache{
+		size:  size,
+		l:     list.New(),
+		items: map[string]*list.Element{},
+	}
+}
+
+func (c *queryCache) get(key string) (*queryCacheEntry, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.l.MoveToFront(e)
+	return e.Value.(*queryCacheEntry), true
+}
+
+func (c *queryCache) put(entry *queryCacheEntry) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if e, ok := c.items[entry.key]; ok {
+		e.Value = entry
+		c.l.MoveToFront(e)
+		return
+	}
+
+	c.items[entry.key] = c.l.PushFront(entry)
+
+	for c.l.Len() > c.size {
+		e := c.l.Back()
+		c.l.Remove(e)
+		delete(c.items, e.Value.(*queryCacheEntry).key)
+	}
+}
+
+func (c *queryCache) clear() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.l.Init()
+	clear(c.items)
+}
+
+// cacheKey returns the key of query in the query cache. The key covers the
+// query, including the source text and location of its expressions, the query
+// context and the options of the query compiler.
+func (qc *queryCompiler) cacheKey(query Body) string {
+	var sb strings.Builder
+
+	sb.WriteString(strconv.FormatBool(qc.compiler.strict))
+	sb.WriteByte(0)
+	sb.WriteString(strconv.FormatBool(qc.enablePrintStatements))
+	sb.WriteByte(0)
+
+	if qc.unsafeBuiltins != nil {
+		unsafe := make([]string, 0, len(qc.unsafeBuiltins))
+		for name := range qc.unsafeBuiltins {
+			unsafe = append(unsafe, name)
+		}
+		slices.Sort(unsafe)
+		sb.WriteString(strings.Join(unsafe, ","))
+	} else {
+		sb.WriteByte('-')
+	}
+	sb.WriteByte(0)
+
+	if qc.qctx != nil {
+		if qc.qctx.Package != nil {
+			sb.WriteString(qc.qctx.Package.String())
+		}
+		sb.WriteByte(0)
+		for _, imp := range qc.qctx.Imports {
+			sb.WriteString(imp.String())
+			sb.WriteByte(0)
+		}
+	}
+	sb.WriteByte(0)
+
+	for _, expr := range query {
+		sb.WriteString(expr.String())
+		sb.WriteByte(0)
+		if loc := expr.Location; loc != nil {
+			sb.WriteString(loc.File)
+			sb.WriteByte(':')
+			sb.WriteString(strconv.Itoa(loc.Row))
+			sb.WriteByte(':')
+			sb.WriteString(strconv.Itoa(loc.Col))
+			sb.WriteByte(0)
+			sb.Write(loc.Text)
+		}
+		sb.WriteByte(0)
+	}
+
+	return sb.String()
+}
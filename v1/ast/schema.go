@@ -52,3 +52,134 @@ func loadSchema(raw any, allowNet []string) (types.Type, error) {
 
 	return tpe, nil
 }
+
+// ToJSONSchema returns a JSON Schema (draft-07) document describing the values
+// of the document referred to by ref, according to the types inferred by the
+// type checker, e.g., to describe the responses of decision endpoints.
+//
+// Sets are described as arrays of unique items, which is how they are
+// serialized to JSON. Properties of objects are never required, as rules
+// contributing them may be undefined. An error is returned if the type of the
+// document is unknown, or if it is a function.
+func (env *TypeEnv) ToJSONSchema(ref Ref) (map[string]any, error) {
+	tpe := env.GetByRef(ref)
+	if tpe == nil {
+		return nil, fmt.Errorf("%v: undefined type", ref)
+	}
+
+	schema, err := typeToJSONSchema(tpe)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", ref, err)
+	}
+
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	return schema, nil
+}
+
+func typeToJSONSchema(tpe types.Type) (map[string]any, error) {
+	switch t := tpe.(type) {
+	case *types.NamedType:
+		schema, err := typeToJSONSchema(t.Type)
+		if err != nil {
+			return nil, err
+		}
+		if t.Descr != "" {
+			schema["description"] = t.Descr
+		}
+		return schema, nil
+	case types.Null:
+		return map[string]any{"type": "null"}, nil
+	case types.Boolean:
+		return map[string]any{"type": "boolean"}, nil
+	case types.Number:
+		return map[string]any{"type": "number"}, nil
+	case types.String:
+		return map[string]any{"type": "string"}, nil
+	case *types.Array:
+		schema := map[string]any{"type": "array"}
+		var dynamic any = false
+		if t.Dynamic() != nil {
+			d, err := typeToJSONSchema(t.Dynamic())
+			if err != nil {
+				return nil, err
+			}
+			dynamic = d
+		}
+		if t.Len() == 0 {
+			if t.Dynamic() == nil {
+				schema["maxItems"] = 0
+			} else {
+				schema["items"] = dynamic
+			}
+			return schema, nil
+		}
+		items := make([]any, t.Len())
+		for i := range items {
+			item, err := typeToJSONSchema(t.Select(i))
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		schema["items"] = items
+		schema["minItems"] = t.Len()
+		schema["additionalItems"] = dynamic
+		return schema, nil
+	case *types.Set:
+		schema := map[string]any{"type": "array", "uniqueItems": true}
+		if t.Of() != nil {
+			of, err := typeToJSONSchema(t.Of())
+			if err != nil {
+				return nil, err
+			}
+			schema["items"] = of
+		}
+		return schema, nil
+	case *types.Object:
+		schema := map[string]any{"type": "object"}
+		if static := t.StaticProperties(); len(static) > 0 {
+			properties := make(map[string]any, len(static))
+			for _, p := range static {
+				value, err := typeToJSONSchema(p.Value)
+				if err != nil {
+					return nil, err
+				}
+				key, ok := p.Key.(string)
+				if !ok {
+					key = string(util.MustMarshalJSON(p.Key))
+				}
+				properties[key] = value
+			}
+			schema["properties"] = properties
+		}
+		if dynamic := t.DynamicValue(); dynamic != nil {
+			value, err := typeToJSONSchema(dynamic)
+			if err != nil {
+				return nil, err
+			}
+			schema["additionalProperties"] = value
+		} else {
+			schema["additionalProperties"] = false
+		}
+		return schema, nil
+	case types.Any:
+		if len(t) == 0 {
+			return map[string]any{}, nil
+		}
+		if len(t) == 1 {
+			return typeToJSONSchema(t[0])
+		}
+		anyOf := make([]any, len(t))
+		for i := range t {
+			schema, err := typeToJSONSchema(t[i])
+			if err != nil {
+				return nil, err
+			}
+			anyOf[i] = schema
+		}
+		return map[string]any{"anyOf": anyOf}, nil
+	case *types.Function:
+		return nil, fmt.Errorf("cannot describe function type %v", t)
+	}
+	return nil, fmt.Errorf("cannot describe type %v", tpe)
+}
@@ -1654,3 +1654,90 @@ const recursiveElements = `{
   }
 }
 `
+
+func TestTypeEnvToJSONSchema(t *testing.T) {
+	module := MustParseModule(`package test
+
+allow if input.x == 1
+
+roles contains role if some role in ["admin", "dev"]
+
+result := {"allowed": allow, "count": count(roles), "reasons": [r | some r in ["a"]], "tuple": [1, "a"]}
+
+f(x) := x
+
+deny := x if {
+	x := 1
+} else := "denied"
+`)
+
+	c := NewCompiler()
+	c.Compile(map[string]*Module{"test.rego": module})
+	if c.Failed() {
+		t.Fatal(c.Errors)
+	}
+
+	tests := []struct {
+		ref string
+		exp string
+		err string
+	}{
+		{
+			ref: "data.test.allow",
+			exp: `{"$schema": "http://json-schema.org/draft-07/schema#", "type": "boolean"}`,
+		},
+		{
+			ref: "data.test.roles",
+			exp: `{"$schema": "http://json-schema.org/draft-07/schema#", "type": "array", "uniqueItems": true, "items": {"type": "string"}}`,
+		},
+		{
+			ref: "data.test.result",
+			exp: `{
+				"$schema": "http://json-schema.org/draft-07/schema#",
+				"type": "object",
+				"properties": {
+					"allowed": {"type": "boolean"},
+					"count": {"type": "number"},
+					"reasons": {"type": "array", "items": {"type": "string"}},
+					"tuple": {"type": "array", "items": [{"type": "number"}, {"type": "string"}], "minItems": 2, "additionalItems": false}
+				},
+				"additionalProperties": false
+			}`,
+		},
+		{
+			ref: "data.test.deny",
+			exp: `{"$schema": "http://json-schema.org/draft-07/schema#", "anyOf": [{"type": "number"}, {"type": "string"}]}`,
+		},
+		{
+			ref: "data.test.f",
+			err: "data.test.f: cannot describe function type",
+		},
+		{
+			ref: "input.x",
+			exp: `{"$schema": "http://json-schema.org/draft-07/schema#"}`,
+		},
+		{
+			ref: "x.y",
+			err: "x.y: undefined type",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.ref, func(t *testing.T) {
+			schema, err := c.TypeEnv.ToJSONSchema(MustParseRef(tc.ref))
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error %q but got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			exp := util.MustUnmarshalJSON([]byte(tc.exp))
+			if string(util.MustMarshalJSON(schema)) != string(util.MustMarshalJSON(exp)) {
+				t.Fatalf("expected %v but got %v", string(util.MustMarshalJSON(exp)), string(util.MustMarshalJSON(schema)))
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ package ast
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -1136,6 +1137,118 @@ func (ref Ref) DynamicSuffix() Ref {
 	return ref[i:]
 }
 
+// Canonical returns the canonical form of ref. Refs that are spelled
+// differently but refer to the same document have the same canonical form:
+//
+//   - a string head that is a valid variable name is replaced by the variable,
+//     e.g. "data".foo becomes data.foo
+//   - number operands are normalized, e.g. data.x[1.0] becomes data.x[1]
+//   - refs nested in the operands are canonicalized, e.g. data.x[data.y[1.0]]
+//     becomes data.x[data.y[1]]
+//   - composite operands are canonicalized recursively, e.g. data.x[[1.0]]
+//     becomes data.x[[1]]
+//
+// String operands need no normalization, as data.foo and data["foo"] are
+// parsed into the same ref. The ground prefix of the canonical form, e.g. to
+// key a cache by the documents a ref reads, is returned by
+// ref.Canonical().GroundPrefix().
+func (ref Ref) Canonical() Ref {
+	if len(ref) == 0 {
+		return ref
+	}
+
+	cpy := make(Ref, len(ref))
+	for i, x := range ref {
+		cpy[i] = x
+		switch v := x.Value.(type) {
+		case String:
+			if i == 0 && IsVarCompatibleString(string(v)) && !IsKeyword(string(v)) {
+				cpy[i] = &Term{Value: Var(v), Location: x.Location}
+			}
+		default:
+			if i > 0 {
+				cpy[i] = canonicalTerm(x)
+			}
+		}
+	}
+
+	return cpy
+}
+
+// canonicalTerm returns x with the numbers and refs it contains canonicalized.
+func canonicalTerm(x *Term) *Term {
+	switch v := x.Value.(type) {
+	case Number:
+		return &Term{Value: canonicalNumber(v), Location: x.Location}
+	case Ref:
+		return &Term{Value: v.Canonical(), Location: x.Location}
+	case *Array:
+		elems := make([]*Term, v.Len())
+		for i := range elems {
+			elems[i] = canonicalTerm(v.Elem(i))
+		}
+		return &Term{Value: NewArray(elems...), Location: x.Location}
+	case Object:
+		obj, _ := v.Map(func(k, v *Term) (*Term, *Term, error) {
+			return canonicalTerm(k), canonicalTerm(v), nil
+		})
+		return &Term{Value: obj, Location: x.Location}
+	case Set:
+		set, _ := v.Map(func(t *Term) (*Term, error) {
+			return canonicalTerm(t), nil
+		})
+		return &Term{Value: set, Location: x.Location}
+	}
+	return x
+}
+
+// CanonicalHash returns the hash code of the canonical form of ref. Unlike
+// Hash, the hash code is stable across processes and platforms, and can be used
+// to key refs in external caches.
+func (ref Ref) CanonicalHash() uint64 {
+	return xxhash.Sum64String(ref.Canonical().String())
+}
+
+// canonicalNumber returns the shortest decimal spelling of num, e.g. 1 for 1.0
+// or 1e0, and 0.15 for 1.50e-1. Unlike a float64 conversion, the spelling is
+// exact, so numbers that differ beyond float64 precision stay distinct.
+func canonicalNumber(num Number) Number {
+	if i, ok := num.Int64(); ok {
+		return Number(strconv.FormatInt(i, 10))
+	}
+	r, ok := new(big.Rat).SetString(string(num))
+	if !ok {
+		return num
+	}
+	if r.IsInt() {
+		return Number(r.Num().String())
+	}
+
+	// The denominator of a decimal number only has the factors 2 and 5, and
+	// the larger of their multiplicities is the number of decimal places.
+	d := new(big.Int).Set(r.Denom())
+	places := max(removeFactor(d, 2), removeFactor(d, 5))
+	if d.Cmp(big.NewInt(1)) != 0 {
+		return num
+	}
+	return Number(r.FloatString(places))
+}
+
+// removeFactor divides x by f as often as possible, and returns the number of
+// divisions.
+func removeFactor(x *big.Int, f int64) int {
+	n := 0
+	div, mod := big.NewInt(f), new(big.Int)
+	for {
+		q, m := new(big.Int).QuoRem(x, div, mod)
+		if m.Sign() != 0 {
+			return n
+		}
+		x.Set(q)
+		n++
+	}
+}
+
 // IsGround returns true if all of the parts of the Ref are ground.
 func (ref Ref) IsGround() bool {
 	if len(ref) == 0 {
@@ -3226,6 +3339,17 @@ func unmarshalLocation(loc *Location, v map[string]any) error {
 			return fmt.Errorf("ast: unable to unmarshal col field with type: %T (expected number)", v["col"])
 		}
 	}
+	if x, ok := v["text"]; ok {
+		s, ok := x.(string)
+		if !ok {
+			return fmt.Errorf("ast: unable to unmarshal text field with type: %T (expected string)", v["text"])
+		}
+		text, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return fmt.Errorf("ast: unable to unmarshal text field: %w", err)
+		}
+		loc.Text = text
+	}
 
 	return nil
 }
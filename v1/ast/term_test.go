@@ -731,6 +731,58 @@ func TestRefDynamic(t *testing.T) {
 	}
 }
 
+func TestRefCanonical(t *testing.T) {
+	tests := []struct {
+		ref Ref
+		exp string
+	}{
+		{MustParseRef("data.foo.bar"), "data.foo.bar"},
+		{MustParseRef(`data["foo"].bar`), "data.foo.bar"},
+		{Ref{StringTerm("data"), StringTerm("foo")}, "data.foo"},
+		{Ref{VarTerm("data"), StringTerm("x"), NumberTerm("1.0")}, "data.x[1]"},
+		{Ref{VarTerm("data"), StringTerm("x"), NumberTerm("-0")}, "data.x[0]"},
+		{Ref{VarTerm("data"), StringTerm("x"), NumberTerm("1e3")}, "data.x[1000]"},
+		{Ref{VarTerm("data"), StringTerm("x"), NumberTerm("1.50")}, "data.x[1.5]"},
+		{Ref{VarTerm("data"), StringTerm("x"), NumberTerm("15e-5")}, "data.x[0.00015]"},
+		{Ref{VarTerm("data"), StringTerm("x"), NumberTerm("-2.50e-1")}, "data.x[-0.25]"},
+		{Ref{VarTerm("data"), StringTerm("x"), NumberTerm("0.10000000000000000001000")}, "data.x[0.10000000000000000001]"},
+		{Ref{VarTerm("data"), StringTerm("x"), ArrayTerm(NumberTerm("1.0"), ObjectTerm(Item(NumberTerm("2e0"), SetTerm(NumberTerm("3.0")))))}, "data.x[[1, {2: {3}}]]"},
+		{Ref{VarTerm("data"), StringTerm("x"), RefTerm(VarTerm("data"), StringTerm("y"), NumberTerm("2.0"))}, "data.x[data.y[2]]"},
+		{MustParseRef("data.x[y].z"), "data.x[y].z"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.exp, func(t *testing.T) {
+			exp := MustParseRef(tc.exp)
+			act := tc.ref.Canonical()
+			if !act.Equal(exp) || act.String() != exp.String() {
+				t.Fatalf("expected %v but got %v", exp, act)
+			}
+			if tc.ref.CanonicalHash() != exp.CanonicalHash() {
+				t.Fatalf("expected canonical hash of %v to equal canonical hash of %v", tc.ref, exp)
+			}
+		})
+	}
+
+	if a := (Ref{StringTerm("foo bar"), StringTerm("baz")}); !a.Canonical().Equal(a) {
+		t.Fatalf("expected string head of %v to be kept", a)
+	}
+
+	a := Ref{StringTerm("data"), StringTerm("x"), NumberTerm("1.0")}
+	if a.Canonical().Equal(a) || !a[0].Equal(StringTerm("data")) {
+		t.Fatalf("expected %v to be left unmodified", a)
+	}
+
+	// The canonical hash is stable across processes.
+	if h := MustParseRef("data.foo.bar").CanonicalHash(); h != 13213961588615747940 {
+		t.Fatalf("unexpected canonical hash %d", h)
+	}
+
+	if MustParseRef("data.foo").CanonicalHash() == MustParseRef("data.bar").CanonicalHash() {
+		t.Fatal("expected different canonical hashes")
+	}
+}
+
 func TestRefExtend(t *testing.T) {
 	a := MustParseRef("foo.bar.baz")
 	b := MustParseRef("qux.corge")
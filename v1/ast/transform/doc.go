@@ -0,0 +1,21 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+// Package transform implements the simplifications that partial evaluation
+// applies to queries and support rules. Consumers that translate the result
+// of partial evaluation into another language (e.g., SQL or filter
+// expressions) can use them to simplify bodies before converting them.
+//
+// All transformations operate on a single ast.Body and share the following
+// invariants:
+//
+//   - The input body is not modified. A new body is returned, which may share
+//     terms with the input.
+//   - The result is satisfied for exactly the same bindings of the live vars
+//     as the input. Vars that are not live may be renamed or removed.
+//   - Vars that are not live are only removed if the expressions that bind
+//     them are not needed for safety or definedness, e.g., input.x = a is
+//     kept as input.x if a is unused, because input.x may be undefined.
+//   - If a body cannot be simplified safely, it is returned unchanged.
+package transform
@@ -0,0 +1,131 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package transform
+
+import (
+	"github.com/open-policy-agent/opa/v1/ast"
+)
+
+// FoldConstants returns a copy of body where calls to deterministic built-in
+// functions with constant operands are replaced by the result of the call.
+// Nested calls are folded from the inside out. Call expressions are folded
+// depending on their form:
+//
+//   - concat(".", ["a", "b"], x) becomes x = "a.b"
+//   - startswith("ab", "a") becomes true, startswith("ab", "b") becomes false
+//
+// Calls to functions replaced with the with keyword anywhere in body are kept.
+//
+// Built-in functions are resolved against c, which should be the compiler
+// used to compile the policy that body originates from. If c is nil, body is
+// returned unchanged. See ast.Compiler.FoldCall for the calls that are folded.
+func FoldConstants(c *ast.Compiler, body ast.Body) (ast.Body, error) {
+	if c == nil {
+		return body, nil
+	}
+
+	mocked := map[string]struct{}{}
+	ast.WalkNodes(body, func(n ast.Node) bool {
+		if w, ok := n.(*ast.With); ok {
+			switch target := w.Target.Value.(type) {
+			case ast.Var, ast.Ref:
+				mocked[target.String()] = struct{}{}
+			}
+		}
+		return false
+	})
+
+	fold := func(call ast.Call) *ast.Term {
+		if _, ok := mocked[call[0].String()]; ok {
+			return nil
+		}
+		return c.FoldCall(call)
+	}
+
+	// Operands are folded before the calls they appear in, so that the calls
+	// can be folded too.
+	var t *ast.GenericTransformer
+	t = ast.NewGenericTransformer(func(x any) (any, error) {
+		switch x := x.(type) {
+		case ast.Call:
+			cpy := make(ast.Call, len(x))
+			cpy[0] = x[0]
+			for i := 1; i < len(x); i++ {
+				operand, err := transformTerm(t, x[i])
+				if err != nil {
+					return nil, err
+				}
+				cpy[i] = operand
+			}
+			if result := fold(cpy); result != nil {
+				return result.Value, nil
+			}
+			return cpy, nil
+		case *ast.Expr:
+			if terms, ok := x.Terms.([]*ast.Term); ok && x.IsCall() {
+				for i := 1; i < len(terms); i++ {
+					operand, err := transformTerm(t, terms[i])
+					if err != nil {
+						return nil, err
+					}
+					terms[i] = operand
+				}
+				return foldCallExpr(x, fold), nil
+			}
+		}
+		return x, nil
+	})
+
+	result, err := ast.Transform(t, body.Copy())
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(ast.Body), nil
+}
+
+// Simplify returns a copy of body where constant calls are folded (see
+// FoldConstants) and intermediate variables are removed by copy propagation
+// (see CopyPropagator), preserving the vars in livevars.
+func Simplify(c *ast.Compiler, body ast.Body, livevars ast.VarSet) (ast.Body, error) {
+	folded, err := FoldConstants(c, body)
+	if err != nil {
+		return nil, err
+	}
+	return NewCopyPropagator(livevars).WithCompiler(c).Apply(folded), nil
+}
+
+func transformTerm(t ast.Transformer, term *ast.Term) (*ast.Term, error) {
+	v, err := ast.Transform(t, term.Value)
+	if err != nil {
+		return nil, err
+	}
+	return &ast.Term{Value: v.(ast.Value), Location: term.Location}, nil
+}
+
+// foldCallExpr replaces the call expression x by its result if the call
+// can be folded.
+func foldCallExpr(x *ast.Expr, fold func(ast.Call) *ast.Term) *ast.Expr {
+	terms := x.Terms.([]*ast.Term)
+
+	var result *ast.Expr
+	if value := fold(ast.Call(terms)); value != nil {
+		result = ast.NewExpr(ast.InternedTerm(!value.Equal(ast.InternedTerm(false))))
+	} else if len(terms) < 2 {
+		return x
+	} else if value := fold(ast.Call(terms[:len(terms)-1])); value != nil {
+		result = ast.Equality.Expr(terms[len(terms)-1], value)
+	} else {
+		return x
+	}
+
+	result.Negated = x.Negated
+	result.Location = x.Location
+	result.Index = x.Index
+	result.Generated = x.Generated
+	result.With = x.With
+
+	return result
+}
@@ -0,0 +1,97 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package transform_test
+
+import (
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/ast/transform"
+
+	_ "github.com/open-policy-agent/opa/v1/topdown" // registers the built-in evaluator
+)
+
+func TestFoldConstants(t *testing.T) {
+	tests := []struct {
+		note  string
+		query string
+		exp   string
+	}{
+		{
+			note:  "output",
+			query: `concat(".", ["a", "b"], x)`,
+			exp:   `x = "a.b"`,
+		},
+		{
+			note:  "nested",
+			query: `x = upper(concat(".", ["a", "b"]))`,
+			exp:   `x = "A.B"`,
+		},
+		{
+			note:  "test",
+			query: `startswith("ab", "a"); not startswith("ab", "b")`,
+			exp:   `true; not false`,
+		},
+		{
+			note:  "non-constant operands",
+			query: `concat(".", [input.x, "b"], x)`,
+			exp:   `concat(".", [input.x, "b"], x)`,
+		},
+		{
+			note:  "nondeterministic",
+			query: `rand.intn("x", 10, x)`,
+			exp:   `rand.intn("x", 10, x)`,
+		},
+		{
+			note:  "error",
+			query: `x = to_number("a")`,
+			exp:   `x = to_number("a")`,
+		},
+		{
+			note:  "mocked",
+			query: `upper("a", x) with upper as lower`,
+			exp:   `upper("a", x) with upper as lower`,
+		},
+	}
+
+	c := ast.NewCompiler()
+	c.Compile(nil)
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			body := ast.MustParseBody(tc.query)
+			orig := body.Copy()
+
+			result, err := transform.FoldConstants(c, body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if exp := ast.MustParseBody(tc.exp); !result.Equal(exp) {
+				t.Fatalf("expected %v but got %v", exp, result)
+			}
+
+			if !body.Equal(orig) {
+				t.Fatalf("expected input to be unchanged but got %v", body)
+			}
+		})
+	}
+}
+
+func TestSimplify(t *testing.T) {
+	c := ast.NewCompiler()
+	c.Compile(nil)
+
+	body := ast.MustParseBody(`concat(".", ["a", "b"], y); input.x = z; z = y`)
+
+	result, err := transform.Simplify(c, body, ast.NewVarSet())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exp := ast.MustParseBody(`input.x = "a.b"`); !result.Equal(exp) {
+		t.Fatalf("expected %v but got %v", exp, result)
+	}
+}
@@ -192,78 +192,275 @@ func WalkRefs(x any, f func(Ref) bool) {
 // WalkTerms calls the function f on all terms under x. If the function f
 // returns true, AST nodes under the last node will not be visited.
 func WalkTerms(x any, f func(*Term) bool) {
-	vis := &GenericVisitor{func(x any) bool {
-		if term, ok := x.(*Term); ok {
-			return f(term)
-		}
-		return false
-	}}
-	vis.Walk(x)
+	WalkNodes(x, f)
 }
 
 // WalkWiths calls the function f on all with modifiers under x. If the function f
 // returns true, AST nodes under the last node will not be visited.
 func WalkWiths(x any, f func(*With) bool) {
-	vis := &GenericVisitor{func(x any) bool {
-		if w, ok := x.(*With); ok {
-			return f(w)
-		}
-		return false
-	}}
-	vis.Walk(x)
+	WalkNodes(x, f)
 }
 
 // WalkExprs calls the function f on all expressions under x. If the function f
 // returns true, AST nodes under the last node will not be visited.
 func WalkExprs(x any, f func(*Expr) bool) {
-	vis := &GenericVisitor{func(x any) bool {
-		if r, ok := x.(*Expr); ok {
-			return f(r)
-		}
-		return false
-	}}
-	vis.Walk(x)
+	WalkNodes(x, f)
 }
 
 // WalkBodies calls the function f on all bodies under x. If the function f
 // returns true, AST nodes under the last node will not be visited.
 func WalkBodies(x any, f func(Body) bool) {
-	vis := &GenericVisitor{func(x any) bool {
-		if b, ok := x.(Body); ok {
-			return f(b)
-		}
-		return false
-	}}
-	vis.Walk(x)
+	WalkNodes(x, f)
 }
 
 // WalkRules calls the function f on all rules under x. If the function f
 // returns true, AST nodes under the last node will not be visited.
 func WalkRules(x any, f func(*Rule) bool) {
-	vis := &GenericVisitor{func(x any) bool {
-		if r, ok := x.(*Rule); ok {
-			stop := f(r)
-			// NOTE(tsandall): since rules cannot be embedded inside of queries
-			// we can stop early if there is no else block.
-			if stop || r.Else == nil {
-				return true
-			}
+	WalkNodes(x, func(r *Rule) bool {
+		stop := f(r)
+		// NOTE(tsandall): since rules cannot be embedded inside of queries
+		// we can stop early if there is no else block.
+		return stop || r.Else == nil
+	})
+}
+
+// WalkNodes calls the function f on all nodes of type T under x. If the
+// function f returns true, AST nodes under the last node will not be visited.
+// The AST is traversed in the same order as by GenericVisitor, but without
+// boxing every visited element into an interface value, which makes WalkNodes
+// considerably cheaper when only a specific node type is of interest.
+func WalkNodes[T Node](x any, f func(T) bool) {
+	w := nodeWalker[T]{f: f}
+	// Boxing bodies and args into a Node allocates, so only do it when they
+	// can be of type T.
+	_, w.bodies = any(Body(nil)).(T)
+	_, w.args = any(Args(nil)).(T)
+	w.walk(x)
+}
+
+type nodeWalker[T Node] struct {
+	f      func(T) bool
+	bodies bool
+	args   bool
+}
+
+func (w *nodeWalker[T]) walk(x any) {
+	switch x := x.(type) {
+	case *Module:
+		w.walkModule(x)
+	case *Package:
+		w.walkPackage(x)
+	case *Import:
+		w.walkImport(x)
+	case *Rule:
+		w.walkRule(x)
+	case *Head:
+		w.walkHead(x)
+	case Body:
+		w.walkBody(x)
+	case Args:
+		w.walkArgs(x)
+	case *Expr:
+		w.walkExpr(x)
+	case *With:
+		w.walkWith(x)
+	case *Term:
+		w.walkTerm(x)
+	case *Every:
+		w.walkEvery(x)
+	case *SomeDecl:
+		w.walkSomeDecl(x)
+	case *Comment:
+		w.visit(x)
+	case *Annotations:
+		w.visit(x)
+	case Value:
+		w.walkValue(x)
+	}
+}
+
+// visit calls f on x if x is of type T, and returns true if the nodes under x
+// should not be visited.
+func (w *nodeWalker[T]) visit(x Node) bool {
+	if n, ok := x.(T); ok {
+		return w.f(n)
+	}
+	return false
+}
+
+func (w *nodeWalker[T]) walkModule(x *Module) {
+	w.walkPackage(x.Package)
+	for i := range x.Imports {
+		w.walkImport(x.Imports[i])
+	}
+	for i := range x.Rules {
+		w.walkRule(x.Rules[i])
+	}
+	for i := range x.Annotations {
+		w.visit(x.Annotations[i])
+	}
+	for i := range x.Comments {
+		w.visit(x.Comments[i])
+	}
+}
+
+func (w *nodeWalker[T]) walkPackage(x *Package) {
+	if w.visit(x) {
+		return
+	}
+	w.walkRef(x.Path)
+}
+
+func (w *nodeWalker[T]) walkImport(x *Import) {
+	if w.visit(x) {
+		return
+	}
+	w.walkTerm(x.Path)
+}
+
+func (w *nodeWalker[T]) walkRule(x *Rule) {
+	if w.visit(x) {
+		return
+	}
+	w.walkHead(x.Head)
+	w.walkBody(x.Body)
+	if x.Else != nil {
+		w.walkRule(x.Else)
+	}
+}
+
+func (w *nodeWalker[T]) walkHead(x *Head) {
+	if w.visit(x) {
+		return
+	}
+	w.walkArgs(x.Args)
+	if x.Key != nil {
+		w.walkTerm(x.Key)
+	}
+	if x.Value != nil {
+		w.walkTerm(x.Value)
+	}
+}
+
+func (w *nodeWalker[T]) walkBody(x Body) {
+	if w.bodies && w.visit(x) {
+		return
+	}
+	for i := range x {
+		w.walkExpr(x[i])
+	}
+}
+
+func (w *nodeWalker[T]) walkArgs(x Args) {
+	if w.args && w.visit(x) {
+		return
+	}
+	for i := range x {
+		w.walkTerm(x[i])
+	}
+}
+
+func (w *nodeWalker[T]) walkExpr(x *Expr) {
+	if w.visit(x) {
+		return
+	}
+	switch ts := x.Terms.(type) {
+	case *Term:
+		w.walkTerm(ts)
+	case *SomeDecl:
+		w.walkSomeDecl(ts)
+	case *Every:
+		w.walkEvery(ts)
+	case []*Term:
+		for i := range ts {
+			w.walkTerm(ts[i])
 		}
-		return false
-	}}
-	vis.Walk(x)
+	}
+	for i := range x.With {
+		w.walkWith(x.With[i])
+	}
 }
 
-// WalkNodes calls the function f on all nodes under x. If the function f
-// returns true, AST nodes under the last node will not be visited.
-func WalkNodes(x any, f func(Node) bool) {
-	vis := &GenericVisitor{func(x any) bool {
-		if n, ok := x.(Node); ok {
-			return f(n)
+func (w *nodeWalker[T]) walkWith(x *With) {
+	if w.visit(x) {
+		return
+	}
+	w.walkTerm(x.Target)
+	w.walkTerm(x.Value)
+}
+
+func (w *nodeWalker[T]) walkEvery(x *Every) {
+	if w.visit(x) {
+		return
+	}
+	if x.Key != nil {
+		w.walkTerm(x.Key)
+	}
+	w.walkTerm(x.Value)
+	w.walkTerm(x.Domain)
+	w.walkBody(x.Body)
+}
+
+func (w *nodeWalker[T]) walkSomeDecl(x *SomeDecl) {
+	if w.visit(x) {
+		return
+	}
+	for i := range x.Symbols {
+		w.walkTerm(x.Symbols[i])
+	}
+}
+
+func (w *nodeWalker[T]) walkTerm(x *Term) {
+	if w.visit(x) {
+		return
+	}
+	w.walkValue(x.Value)
+}
+
+func (w *nodeWalker[T]) walkRef(x Ref) {
+	for i := range x {
+		w.walkTerm(x[i])
+	}
+}
+
+func (w *nodeWalker[T]) walkValue(x Value) {
+	switch x := x.(type) {
+	case Ref:
+		w.walkRef(x)
+	case *object:
+		for _, node := range x.sortedKeys() {
+			w.walkTerm(node.key)
+			w.walkTerm(node.value)
 		}
-		return false
-	}}
-	vis.Walk(x)
+	case Object:
+		for _, k := range x.Keys() {
+			w.walkTerm(k)
+			w.walkTerm(x.Get(k))
+		}
+	case *Array:
+		for i := range x.Len() {
+			w.walkTerm(x.Elem(i))
+		}
+	case Set:
+		xSlice := x.Slice()
+		for i := range xSlice {
+			w.walkTerm(xSlice[i])
+		}
+	case *ArrayComprehension:
+		w.walkTerm(x.Term)
+		w.walkBody(x.Body)
+	case *ObjectComprehension:
+		w.walkTerm(x.Key)
+		w.walkTerm(x.Value)
+		w.walkBody(x.Body)
+	case *SetComprehension:
+		w.walkTerm(x.Term)
+		w.walkBody(x.Body)
+	case Call:
+		for i := range x {
+			w.walkTerm(x[i])
+		}
+	}
 }
 
 // GenericVisitor provides a utility to walk over AST nodes using a
@@ -49,3 +49,50 @@ func BenchmarkVarSetUpdateEmpty(b *testing.B) {
 		}
 	}
 }
+
+// BenchmarkWalkNodes compares walking all terms of a module with WalkNodes
+// against the same walk with GenericVisitor.
+//
+// BenchmarkWalkNodes/GenericVisitor     301330     4298 ns/op     384 B/op     18 allocs/op
+// BenchmarkWalkNodes/WalkNodes          539968     2088 ns/op       0 B/op      0 allocs/op
+func BenchmarkWalkNodes(b *testing.B) {
+	mod := MustParseModule(`package a.b
+
+import input.x.y as z
+
+t[x] = y if {
+	p[x] = {"foo": [y, 2, {"bar": 3}]}
+	not q[x]
+	y = [[x, z] | x = "x"; z = "z"]
+	z = {"foo": [x, z] | x = "x"; z = "z"}
+	s = {1 | a[i] = "foo"}
+	count({1, 2, 3}, n) with input.foo.bar as x
+}
+
+p if { false } else if { false } else if { true }
+
+fn([x, y]) = z if { json.unmarshal(x, z); z > y }
+`)
+
+	b.Run("GenericVisitor", func(b *testing.B) {
+		for range b.N {
+			n := 0
+			NewGenericVisitor(func(x any) bool {
+				if _, ok := x.(*Term); ok {
+					n++
+				}
+				return false
+			}).Walk(mod)
+		}
+	})
+
+	b.Run("WalkNodes", func(b *testing.B) {
+		for range b.N {
+			n := 0
+			WalkNodes(mod, func(*Term) bool {
+				n++
+				return false
+			})
+		}
+	})
+}
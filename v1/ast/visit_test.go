@@ -5,6 +5,7 @@
 package ast
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -112,6 +113,107 @@ fn([x, y]) = z if { json.unmarshal(x, z); z > y }
 	}
 }
 
+func TestWalkNodesMatchesGenericVisitor(t *testing.T) {
+	mod := module(`package a.b
+
+import input.x.y as z
+
+# METADATA
+# title: t
+t[x] = y if {
+	p[x] = {"foo": [y, 2, {"bar": 3}]}
+	not q[x]
+	y = [[x, z] | x = "x"; z = "z"]
+	z = {"foo": [x, z] | x = "x"; z = "z"}
+	s = {1 | a[i] = "foo"}
+	some x0, y0, z0
+	count({1, 2, 3}, n) with input.foo.bar as x
+	every k, v in [1] { k < v }
+}
+
+p if { false } else if { false } else if { true }
+
+fn([x, y]) = z if { json.unmarshal(x, z); z > y }
+`)
+
+	// stopAt prunes the walk under expressions calling count and under array
+	// comprehensions, so that the early exit behaviour of both visitors is
+	// compared too.
+	stopAt := func(x any) bool {
+		switch x := x.(type) {
+		case *Expr:
+			return x.IsCall() && x.Operator().Equal(Count.Ref())
+		case *Term:
+			_, ok := x.Value.(*ArrayComprehension)
+			return ok
+		}
+		return false
+	}
+
+	expected := func(match func(any) bool) []any {
+		var exp []any
+		NewGenericVisitor(func(x any) bool {
+			if match(x) {
+				exp = append(exp, x)
+				return stopAt(x)
+			}
+			return false
+		}).Walk(mod)
+		return exp
+	}
+
+	check := func(t *testing.T, exp, act []any) {
+		t.Helper()
+		if len(exp) == 0 {
+			t.Fatal("expected nodes to be visited")
+		}
+		if len(exp) != len(act) {
+			t.Fatalf("expected %d nodes but got %d", len(exp), len(act))
+		}
+		for i := range exp {
+			if !reflect.DeepEqual(exp[i], act[i]) {
+				t.Fatalf("expected node %d to be %v but got %v", i, exp[i], act[i])
+			}
+		}
+	}
+
+	t.Run("terms", func(t *testing.T) {
+		var act []any
+		WalkNodes(mod, func(x *Term) bool {
+			act = append(act, x)
+			return stopAt(x)
+		})
+		check(t, expected(func(x any) bool { _, ok := x.(*Term); return ok }), act)
+	})
+
+	t.Run("exprs", func(t *testing.T) {
+		var act []any
+		WalkNodes(mod, func(x *Expr) bool {
+			act = append(act, x)
+			return stopAt(x)
+		})
+		check(t, expected(func(x any) bool { _, ok := x.(*Expr); return ok }), act)
+	})
+
+	t.Run("bodies", func(t *testing.T) {
+		var act []any
+		WalkNodes(mod, func(x Body) bool {
+			act = append(act, x)
+			return false
+		})
+		check(t, expected(func(x any) bool { _, ok := x.(Body); return ok }), act)
+	})
+
+	t.Run("nodes", func(t *testing.T) {
+		var act []any
+		WalkNodes(mod, func(x Node) bool {
+			act = append(act, x)
+			return stopAt(x)
+		})
+		check(t, expected(func(x any) bool { _, ok := x.(Node); return ok }), act)
+	})
+}
+
 func TestBeforeAfterVisitor(t *testing.T) {
 	rule := module(`package a.b
 
@@ -9,6 +9,7 @@ import (
 	"archive/tar"
 	"bytes"
 	"compress/gzip"
+	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -20,6 +21,7 @@ import (
 	"path"
 	"path/filepath"
 	"reflect"
+	"slices"
 	"strings"
 	"sync"
 
@@ -75,10 +77,11 @@ type Raw struct {
 	module *ModuleFile
 }
 
-// Patch contains an array of objects wherein each object represents the patch operation to be
-// applied to the bundle data.
+// Patch contains arrays of objects wherein each object represents the patch operation to be
+// applied to the bundle data or policies.
 type Patch struct {
-	Data []PatchOperation `json:"data,omitempty"`
+	Data    []PatchOperation       `json:"data,omitempty"`
+	Modules []ModulePatchOperation `json:"modules,omitempty"`
 }
 
 // PatchOperation models a single patch operation against a document.
@@ -88,6 +91,15 @@ type PatchOperation struct {
 	Value any    `json:"value"`
 }
 
+// ModulePatchOperation models a single patch operation against the policies of
+// a bundle. Op is either "upsert" or "remove", Path is the path of the module
+// in the bundle, and Raw holds the module source for upserts.
+type ModulePatchOperation struct {
+	Op   string `json:"op"`
+	Path string `json:"path"`
+	Raw  string `json:"raw,omitempty"`
+}
+
 // SignaturesConfig represents an array of JWTs that encapsulate the signatures for the bundle.
 type SignaturesConfig struct {
 	Signatures []string `json:"signatures,omitempty"`
@@ -113,6 +125,11 @@ type FileInfo struct {
 	Name      string `json:"name"`
 	Hash      string `json:"hash"`
 	Algorithm string `json:"algorithm"`
+
+	// Raw is true if Hash is the digest of the raw bytes of the file, even if
+	// the file is a structured document. Raw digests can be verified while the
+	// file is read, without parsing it. See NewVerifyingReader.
+	Raw bool `json:"raw,omitempty"`
 }
 
 // NewFile returns a new FileInfo.
@@ -142,6 +159,14 @@ type Manifest struct {
 	// This allows individual files to override the global Rego version specified by RegoVersion.
 	FileRegoVersions map[string]int `json:"file_rego_versions,omitempty"`
 	Metadata         map[string]any `json:"metadata,omitempty"`
+	// Schemas maps "/"-delimited data paths within the bundle roots to JSON
+	// Schemas that the bundle data at those paths must conform to. The schemas
+	// are checked by Compile.
+	Schemas map[string]any `json:"schemas,omitempty"`
+	// Priority resolves the ownership of overlapping roots: a snapshot bundle
+	// whose roots overlap those of an activated bundle of lower priority takes
+	// them over, deactivating that bundle, instead of failing to activate.
+	Priority int `json:"priority,omitempty"`
 
 	compiledFileRegoVersions []fileRegoVersion
 }
@@ -151,6 +176,49 @@ type fileRegoVersion struct {
 	version int
 }
 
+// ModulesHash returns a hash of everything in b that affects the compiled
+// policies: the modules, the Wasm modules, and the roots, Rego versions and
+// Wasm resolvers of the manifest. Two bundles with the same hash only differ
+// in their data, so activating one in place of the other does not require the
+// policies to be recompiled.
+func ModulesHash(b *Bundle) string {
+	h := sha256.New()
+
+	write := func(bs []byte) {
+		fmt.Fprintf(h, "%d:", len(bs))
+		h.Write(bs)
+	}
+
+	m := Manifest{
+		Roots:            b.Manifest.Roots,
+		WasmResolvers:    b.Manifest.WasmResolvers,
+		RegoVersion:      b.Manifest.RegoVersion,
+		FileRegoVersions: b.Manifest.FileRegoVersions,
+	}
+	m.Init()
+	roots := slices.Sorted(slices.Values(*m.Roots))
+	m.Roots = &roots
+	write(util.MustMarshalJSON(m))
+
+	modules := slices.SortedFunc(slices.Values(b.Modules), func(a, b ModuleFile) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	for _, mf := range modules {
+		write([]byte(mf.Path))
+		write(mf.Raw)
+	}
+
+	wasmModules := slices.SortedFunc(slices.Values(b.WasmModules), func(a, b WasmModuleFile) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	for _, wm := range wasmModules {
+		write([]byte(wm.Path))
+		write(wm.Raw)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
 // WasmResolver maps a wasm module to an entrypoint ref.
 type WasmResolver struct {
 	Entrypoint  string             `json:"entrypoint,omitempty"`
@@ -215,6 +283,14 @@ func (m Manifest) Equal(other Manifest) bool {
 		return false
 	}
 
+	if !reflect.DeepEqual(m.Schemas, other.Schemas) {
+		return false
+	}
+
+	if m.Priority != other.Priority {
+		return false
+	}
+
 	return m.equalWasmResolversAndRoots(other)
 }
 
@@ -240,6 +316,11 @@ func (m Manifest) Copy() Manifest {
 		maps.Copy(m.Metadata, metadata)
 	}
 
+	if schemas := m.Schemas; schemas != nil {
+		m.Schemas = make(map[string]any, len(schemas))
+		maps.Copy(m.Schemas, schemas)
+	}
+
 	return m
 }
 
@@ -332,6 +413,12 @@ func (m *Manifest) validateAndInjectDefaults(b Bundle) error {
 	// Standardize the roots (no starting or trailing slash)
 	for i := range roots {
 		roots[i] = strings.Trim(roots[i], "/")
+
+		for _, segment := range rootPathSegments(roots[i]) {
+			if segment != RootWildcard && strings.Contains(segment, RootWildcard) {
+				return fmt.Errorf("manifest root '%v' has invalid wildcard segment '%v'", roots[i], segment)
+			}
+		}
 	}
 
 	for i := range len(roots) - 1 {
@@ -387,6 +474,13 @@ func (m *Manifest) validateAndInjectDefaults(b Bundle) error {
 		}
 	}
 
+	// Validate data schemas in bundle.
+	for _, path := range util.KeysSorted(m.Schemas) {
+		if !RootPathsContain(roots, strings.Trim(path, "/")) {
+			return fmt.Errorf("manifest roots %v do not permit data schema at path '%s'", roots, path)
+		}
+	}
+
 	if b.lazyLoadingMode {
 		return nil
 	}
@@ -494,6 +588,8 @@ type Reader struct {
 	persist               bool
 	regoVersion           ast.RegoVersion
 	followSymlinks        bool
+	internPool            *ast.InternPool
+	roots                 []string
 }
 
 // NewReader is deprecated. Use NewCustomReader instead.
@@ -599,6 +695,15 @@ func (r *Reader) WithLazyLoadingMode(yes bool) *Reader {
 	return r
 }
 
+// WithRoots sets the roots of the bundle data that are consumed. Data files
+// whose paths do not overlap with any of the roots are skipped: they are
+// neither read nor verified, and their entries in the bundle signatures are
+// ignored. Policy and other files are always read.
+func (r *Reader) WithRoots(roots []string) *Reader {
+	r.roots = roots
+	return r
+}
+
 // WithBundlePersistence specifies if the downloaded bundle will eventually be persisted to disk.
 func (r *Reader) WithBundlePersistence(persist bool) *Reader {
 	r.persist = persist
@@ -610,11 +715,20 @@ func (r *Reader) WithRegoVersion(version ast.RegoVersion) *Reader {
 	return r
 }
 
+// WithInternPool sets the pool used to deduplicate strings in parsed modules
+// and decoded data. Sharing one pool across readers lets identical keys found
+// in many bundles be stored only once.
+func (r *Reader) WithInternPool(pool *ast.InternPool) *Reader {
+	r.internPool = pool
+	return r
+}
+
 func (r *Reader) ParserOptions() ast.ParserOptions {
 	return ast.ParserOptions{
 		ProcessAnnotation: r.processAnnotations,
 		Capabilities:      r.capabilities,
 		RegoVersion:       r.regoVersion,
+		InternPool:        r.internPool,
 	}
 }
 
@@ -645,12 +759,9 @@ func (r *Reader) Read() (Bundle, error) {
 
 	var modules []ModuleFile
 	for _, f := range descriptors {
-		buf, err := readFile(f, r.sizeLimitBytes)
-		if err != nil {
-			return bundle, err
-		}
+		// files to verify once they have been read
+		var verifyPath string
 
-		// verify the file content
 		if bundle.Type() == SnapshotBundleType && !bundle.Signatures.isEmpty() {
 			path := f.Path()
 			if r.baseDir != "" {
@@ -661,10 +772,32 @@ func (r *Reader) Read() (Bundle, error) {
 			// check if the file is to be excluded from bundle verification
 			if r.isFileExcluded(path) {
 				delete(r.files, path)
-			} else {
-				if err = r.verifyBundleFile(path, buf); err != nil {
+			} else if r.isFileSkipped(f.Path()) {
+				delete(r.files, path)
+			} else if r.files[path].Raw {
+				// verify files with raw digests while they are read
+				if f.reader, err = NewVerifyingReader(path, f.reader, r.files); err != nil {
 					return bundle, err
 				}
+			} else {
+				verifyPath = path
+			}
+		}
+
+		if r.isFileSkipped(f.Path()) {
+			_ = f.Close()
+			continue
+		}
+
+		buf, err := readFile(f, r.sizeLimitBytes)
+		if err != nil {
+			return bundle, err
+		}
+
+		// verify the file content
+		if verifyPath != "" {
+			if err = r.verifyBundleFile(verifyPath, buf); err != nil {
+				return bundle, err
 			}
 		}
 
@@ -720,6 +853,8 @@ func (r *Reader) Read() (Bundle, error) {
 				return bundle, fmt.Errorf("bundle load failed on %v: %w", r.fullPath(path), err)
 			}
 
+			value = r.internPool.Value(value)
+
 			if err := insertValue(&bundle, path, value); err != nil {
 				return bundle, err
 			}
@@ -740,6 +875,8 @@ func (r *Reader) Read() (Bundle, error) {
 				return bundle, fmt.Errorf("bundle load failed on %v: %w", r.fullPath(path), err)
 			}
 
+			value = r.internPool.Value(value)
+
 			if err := insertValue(&bundle, path, value); err != nil {
 				return bundle, err
 			}
@@ -793,9 +930,13 @@ func (r *Reader) Read() (Bundle, error) {
 	if bundle.Type() == SnapshotBundleType && len(r.files) != 0 {
 		extra := []string{}
 		for k := range r.files {
-			extra = append(extra, k)
+			if !r.isFileSkipped(k) {
+				extra = append(extra, k)
+			}
+		}
+		if len(extra) > 0 {
+			return bundle, fmt.Errorf("file(s) %v specified in bundle signatures but not found in the target bundle", extra)
 		}
-		return bundle, fmt.Errorf("file(s) %v specified in bundle signatures but not found in the target bundle", extra)
 	}
 
 	if err := bundle.Manifest.validateAndInjectDefaults(bundle); err != nil {
@@ -854,6 +995,34 @@ func (r *Reader) isFileExcluded(path string) bool {
 	return false
 }
 
+// isFileSkipped returns true if path is a data file outside of the roots set
+// with WithRoots.
+func (r *Reader) isFileSkipped(p string) bool {
+	if r.roots == nil {
+		return false
+	}
+
+	p = filepath.ToSlash(p)
+	switch path.Base(p) {
+	case dataFile, yamlDataFile, ymlDataFile:
+	default:
+		return false
+	}
+
+	dir := strings.Trim(path.Dir(p), "/")
+	if dir == "." {
+		dir = ""
+	}
+
+	for _, root := range r.roots {
+		if RootPathsOverlap(dir, root) {
+			return false
+		}
+	}
+
+	return true
+}
+
 func (r *Reader) checkSignaturesAndDescriptors(signatures SignaturesConfig) error {
 	if r.skipVerify {
 		return nil
@@ -1065,11 +1234,23 @@ func writeSignatures(tw *tar.Writer, bundle Bundle) error {
 	return archive.WriteFile(tw, fmt.Sprintf(".%v", SignaturesFile), bs)
 }
 
-func hashBundleFiles(hash SignatureHasher, b *Bundle) ([]FileInfo, error) {
+// hashBundleFiles hashes the data, manifest and Wasm files of a bundle. If raw
+// is true, the data and manifest files are hashed as encoded by the Writer,
+// instead of as structured documents.
+func hashBundleFiles(hash SignatureHasher, b *Bundle, raw bool) ([]FileInfo, error) {
 
 	files := []FileInfo{}
 
-	bs, err := hash.HashFile(b.Data)
+	var data any = b.Data
+	if raw {
+		var buf bytes.Buffer
+		if err := json.NewEncoder(&buf).Encode(b.Data); err != nil {
+			return files, err
+		}
+		data = buf.Bytes()
+	}
+
+	bs, err := hash.HashFile(data)
 	if err != nil {
 		return files, err
 	}
@@ -1104,7 +1285,19 @@ func hashBundleFiles(hash SignatureHasher, b *Bundle) ([]FileInfo, error) {
 	// parse the manifest into a JSON structure;
 	// then recursively order the fields of all objects alphabetically and then apply
 	// the hash function to result to compute the hash.
-	if !b.Manifest.Empty() {
+	if !b.Manifest.Empty() && raw {
+		var buf bytes.Buffer
+		if err := json.NewEncoder(&buf).Encode(b.Manifest); err != nil {
+			return files, err
+		}
+
+		bs, err = hash.HashFile(buf.Bytes())
+		if err != nil {
+			return files, err
+		}
+
+		files = append(files, NewFile(strings.TrimPrefix(ManifestExt, "/"), hex.EncodeToString(bs), defaultHashingAlg))
+	} else if !b.Manifest.Empty() {
 		mbs, err := json.Marshal(b.Manifest)
 		if err != nil {
 			return files, err
@@ -1215,12 +1408,18 @@ func (b *Bundle) GenerateSignature(signingConfig *SigningConfig, keyID string, u
 		files = append(files, NewFile(strings.TrimPrefix(path, "/"), hex.EncodeToString(bytes), defaultHashingAlg))
 	}
 
-	result, err := hashBundleFiles(hash, b)
+	result, err := hashBundleFiles(hash, b, signingConfig.RawDigests)
 	if err != nil {
 		return err
 	}
 	files = append(files, result...)
 
+	if signingConfig.RawDigests {
+		for i := range files {
+			files[i].Raw = true
+		}
+	}
+
 	// generate signed token
 	token, err := GenerateSignedToken(files, signingConfig, keyID)
 	if err != nil {
@@ -1443,7 +1642,7 @@ func (b *Bundle) readData(key []string) *any {
 
 // Type returns the type of the bundle.
 func (b *Bundle) Type() string {
-	if len(b.Patch.Data) != 0 {
+	if len(b.Patch.Data) != 0 || len(b.Patch.Modules) != 0 {
 		return DeltaBundleType
 	}
 	return SnapshotBundleType
@@ -1522,10 +1721,11 @@ func MergeWithRegoVersion(bundles []*Bundle, regoVersion ast.RegoVersion, usePat
 		result.Modules = append(result.Modules, b.Modules...)
 
 		for _, root := range *b.Manifest.Roots {
-			key := strings.Split(root, "/")
-			if val := b.readData(key); val != nil {
-				if err := result.insertData(key, *val); err != nil {
-					return nil, err
+			for _, key := range expandRootPath(rootPathSegments(root), b.readData) {
+				if val := b.readData(key); val != nil {
+					if err := result.insertData(key, *val); err != nil {
+						return nil, err
+					}
 				}
 			}
 		}
@@ -1619,11 +1819,26 @@ func bundleAbsolutePath(m ModuleFile, usePath bool) string {
 	return path.Clean(p)
 }
 
+// RootWildcard is the root path segment that matches any key, e.g., as in
+// "tenants/*/policies".
+const RootWildcard = "*"
+
 // RootPathsOverlap takes in two bundle root paths and returns true if they overlap.
 func RootPathsOverlap(pathA string, pathB string) bool {
 	a := rootPathSegments(pathA)
 	b := rootPathSegments(pathB)
-	return rootContains(a, b) || rootContains(b, a)
+
+	if (len(a) == 1 && a[0] == "") || (len(b) == 1 && b[0] == "") {
+		return true
+	}
+
+	for i := range min(len(a), len(b)) {
+		if a[i] != b[i] && a[i] != RootWildcard && b[i] != RootWildcard {
+			return false
+		}
+	}
+
+	return true
 }
 
 // RootPathsContain takes a set of bundle root paths and returns true if the path is contained.
@@ -1641,6 +1856,10 @@ func rootPathSegments(path string) []string {
 	return strings.Split(path, "/")
 }
 
+func hasRootWildcard(path string) bool {
+	return slices.Contains(rootPathSegments(path), RootWildcard)
+}
+
 func rootContains(root []string, other []string) bool {
 
 	// A single segment, empty string root always contains the other.
@@ -1653,7 +1872,7 @@ func rootContains(root []string, other []string) bool {
 	}
 
 	for j := range root {
-		if root[j] != other[j] {
+		if root[j] != other[j] && root[j] != RootWildcard {
 			return false
 		}
 	}
@@ -1661,6 +1880,36 @@ func rootContains(root []string, other []string) bool {
 	return true
 }
 
+// expandRootPath returns the paths matching the root path segments, whose
+// wildcard segments match the keys of the objects returned by read. A root
+// without wildcard segments is returned as is.
+func expandRootPath(root []string, read func(path []string) *any) [][]string {
+	paths := [][]string{{}}
+
+	for _, segment := range root {
+		var next [][]string
+		for _, path := range paths {
+			if segment != RootWildcard {
+				next = append(next, append(slices.Clip(path), segment))
+				continue
+			}
+
+			value := read(path)
+			if value == nil {
+				continue
+			}
+			if obj, ok := (*value).(map[string]any); ok {
+				for _, key := range util.KeysSorted(obj) {
+					next = append(next, append(slices.Clip(path), key))
+				}
+			}
+		}
+		paths = next
+	}
+
+	return paths
+}
+
 func insertValue(b *Bundle, path string, value any) error {
 	if err := b.insertData(getNormalizedPath(path), value); err != nil {
 		return fmt.Errorf("bundle load failed on %v: %w", path, err)
@@ -1785,6 +2034,21 @@ func readFile(f *Descriptor, sizeLimitBytes int64) (bytes.Buffer, error) {
 		return *bb, nil
 	}
 
+	// Case for pre-loaded byte buffers verified while they are read: the buffer
+	// is hashed as a whole instead of being copied.
+	if vr, ok := f.reader.(*verifyingReader); ok {
+		if bb, ok := vr.r.(*bytes.Buffer); ok {
+			f.reader = bb
+			buf, err := readFile(f, sizeLimitBytes)
+			if err != nil {
+				return buf, err
+			}
+
+			_, _ = vr.h.Write(buf.Bytes())
+			return buf, vr.verify()
+		}
+	}
+
 	// Case for *lazyFile readers:
 	if lf, ok := f.reader.(*lazyFile); ok {
 		var buf bytes.Buffer
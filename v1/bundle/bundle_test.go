@@ -9,15 +9,19 @@ import (
 	"archive/tar"
 	"bytes"
 	"compress/gzip"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"path/filepath"
 	"reflect"
+	"slices"
 	"strings"
 	"testing"
 	"testing/fstest"
+	"unsafe"
 
 	"github.com/open-policy-agent/opa/internal/file/archive"
 	"github.com/open-policy-agent/opa/v1/ast"
@@ -315,6 +319,61 @@ func TestReadWithBundleEtag(t *testing.T) {
 	}
 }
 
+func TestReadWithInternPool(t *testing.T) {
+
+	files := [][2]string{
+		{"/a/data.json", `{"owner": "alice", "tags": ["owner"]}`},
+		{"/b/data.yaml", "owner: alice\n"},
+		{"/a/policy.rego", `package a
+
+owner := data.a.owner`},
+		{"/b/policy.rego", `package b
+
+owner := data.b.owner`},
+	}
+
+	pool := ast.NewInternPool()
+
+	for range 2 {
+		buf := archive.MustWriteTarGz(files)
+		if _, err := NewReader(buf).WithInternPool(pool).Read(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Reading the same bundle twice must not grow the pool.
+	exp := pool.Len()
+
+	buf := archive.MustWriteTarGz(files)
+	b, err := NewReader(buf).WithInternPool(pool).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pool.Len() != exp {
+		t.Fatalf("expected pool size %d, got %d", exp, pool.Len())
+	}
+
+	a := b.Data["a"].(map[string]any)
+	owner := pool.String("owner")
+	for k := range a {
+		if k == "owner" && unsafe.StringData(k) != unsafe.StringData(owner) {
+			t.Fatal("expected data key to be interned")
+		}
+	}
+	if s := a["owner"].(string); unsafe.StringData(s) != unsafe.StringData(pool.String("alice")) {
+		t.Fatal("expected data value to be interned")
+	}
+
+	for _, mf := range b.Modules {
+		last := mf.Parsed.Rules[0].Head.Value.Value.(ast.Ref)
+		s := string(last[len(last)-1].Value.(ast.String))
+		if unsafe.StringData(s) != unsafe.StringData(owner) {
+			t.Fatalf("expected ref element in %s to be interned", mf.Path)
+		}
+	}
+}
+
 func testReadBundle(t *testing.T, baseDir string, useMemoryFS bool) {
 	module := `package example`
 	if useMemoryFS && baseDir == "" {
@@ -438,6 +497,68 @@ func TestReadWithManifest(t *testing.T) {
 	}
 }
 
+func TestModulesHash(t *testing.T) {
+	newBundle := func() *Bundle {
+		return &Bundle{
+			Manifest: Manifest{Revision: "a", Roots: &[]string{"x", "y"}},
+			Data:     map[string]any{"x": 1},
+			Modules: []ModuleFile{
+				{Path: "x/a.rego", Raw: []byte("package x.a")},
+				{Path: "x/b.rego", Raw: []byte("package x.b")},
+			},
+		}
+	}
+
+	hash := ModulesHash(newBundle())
+
+	tests := []struct {
+		note   string
+		modify func(*Bundle)
+		same   bool
+	}{
+		{
+			note:   "data and revision",
+			modify: func(b *Bundle) { b.Data = map[string]any{"x": 2}; b.Manifest.Revision = "b" },
+			same:   true,
+		},
+		{
+			note:   "module order and root order",
+			modify: func(b *Bundle) { slices.Reverse(b.Modules); b.Manifest.Roots = &[]string{"y", "x"} },
+			same:   true,
+		},
+		{
+			note:   "module content",
+			modify: func(b *Bundle) { b.Modules[0].Raw = []byte("package x.c") },
+		},
+		{
+			note:   "module path",
+			modify: func(b *Bundle) { b.Modules[0].Path = "x/c.rego" },
+		},
+		{
+			note:   "roots",
+			modify: func(b *Bundle) { b.Manifest.AddRoot("z") },
+		},
+		{
+			note:   "rego version",
+			modify: func(b *Bundle) { b.Manifest.SetRegoVersion(ast.RegoV0) },
+		},
+		{
+			note:   "wasm modules",
+			modify: func(b *Bundle) { b.WasmModules = []WasmModuleFile{{Path: "x/policy.wasm", Raw: []byte{0}}} },
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			b := newBundle()
+			tc.modify(b)
+			if same := ModulesHash(b) == hash; same != tc.same {
+				t.Fatalf("expected same hash to be %v", tc.same)
+			}
+		})
+	}
+}
+
 func TestManifestMetadata(t *testing.T) {
 	files := [][2]string{
 		{"/.manifest", `{
@@ -995,6 +1116,13 @@ func TestReadRootValidation(t *testing.T) {
 			},
 			err: "manifest roots [a b c/d] do not permit data patch at path 'c/e'",
 		},
+		{
+			note: "err data schema outside scope",
+			files: [][2]string{
+				{"/.manifest", `{"revision": "abcd", "roots": ["a", "b"], "schemas": {"a/x": {"type": "object"}, "/c/e": {"type": "string"}}}`},
+			},
+			err: "manifest roots [a b] do not permit data schema at path '/c/e'",
+		},
 	}
 
 	for _, tc := range cases {
@@ -1081,6 +1209,24 @@ func TestRootPathsContain(t *testing.T) {
 			path:  "foo/bar",
 			want:  false,
 		},
+		{
+			note:  "wildcard",
+			roots: []string{"tenants/*/policies"},
+			path:  "tenants/acme/policies/authz",
+			want:  true,
+		},
+		{
+			note:  "wildcard no match",
+			roots: []string{"tenants/*/policies"},
+			path:  "tenants/acme/data",
+			want:  false,
+		},
+		{
+			note:  "wildcard prefix",
+			roots: []string{"tenants/*/policies"},
+			path:  "tenants/acme",
+			want:  false,
+		},
 	}
 
 	for _, tc := range tests {
@@ -1427,6 +1573,187 @@ func TestGenerateSignature(t *testing.T) {
 	}
 }
 
+func TestReadWithRawDigests(t *testing.T) {
+	bundle := Bundle{
+		Data: map[string]any{
+			"foo": map[string]any{
+				"bar": []any{json.Number("1"), json.Number("2"), json.Number("3")},
+			},
+		},
+		Modules: []ModuleFile{
+			{
+				URL:    "/foo/corge/corge.rego",
+				Path:   "/foo/corge/corge.rego",
+				Parsed: ast.MustParseModule(`package foo.corge`),
+				Raw:    []byte("package foo.corge\n"),
+			},
+		},
+		Manifest: Manifest{
+			Revision: "quickbrownfaux",
+		},
+	}
+
+	sc := NewSigningConfig("secret", "HS256", "").WithRawDigests(true)
+	if err := bundle.GenerateSignature(sc, "", false); err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).Write(bundle); err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+
+	// unpack the bundle, so that its files are not buffered by the loader
+	fsys := fstest.MapFS{}
+	loader := NewTarballLoader(bytes.NewReader(buf.Bytes()))
+	for {
+		f, err := loader.NextFile()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		var content bytes.Buffer
+		if _, err := f.Read(&content, DefaultSizeLimitBytes); err != nil && err != io.EOF {
+			t.Fatal(err)
+		}
+		fsys[strings.TrimPrefix(f.Path(), "/")] = &fstest.MapFile{Data: content.Bytes()}
+	}
+
+	vc := NewVerificationConfig(map[string]*KeyConfig{"foo": {Key: "secret", Algorithm: "HS256"}}, "foo", "", nil)
+
+	t.Run("tarball", func(t *testing.T) {
+		_, err := NewReader(bytes.NewReader(buf.Bytes())).WithBundleVerificationConfig(vc).Read()
+		if err != nil {
+			t.Fatal("Unexpected error:", err)
+		}
+	})
+
+	t.Run("streaming", func(t *testing.T) {
+		loader, err := NewFSLoader(fsys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := NewCustomReader(loader).WithBundleVerificationConfig(vc).Read()
+		if err != nil {
+			t.Fatal("Unexpected error:", err)
+		}
+		if !reflect.DeepEqual(bundle.Data, b.Data) {
+			t.Fatalf("Expected data %v but got %v", bundle.Data, b.Data)
+		}
+	})
+
+	t.Run("streaming digest mismatch", func(t *testing.T) {
+		tampered := maps.Clone(fsys)
+		tampered["data.json"] = &fstest.MapFile{Data: []byte(`{"foo":{"bar":[1,2,4]}}` + "\n")}
+
+		loader, err := NewFSLoader(tampered)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = NewCustomReader(loader).WithBundleVerificationConfig(vc).Read()
+		if err == nil || !strings.Contains(err.Error(), "data.json: digest mismatch") {
+			t.Fatal("Expected digest mismatch but got:", err)
+		}
+	})
+
+	t.Run("tarball digest mismatch", func(t *testing.T) {
+		files := [][2]string{}
+		for name, f := range fsys {
+			files = append(files, [2]string{"/" + name, string(f.Data)})
+		}
+		for i := range files {
+			if files[i][0] == "/data.json" {
+				files[i][1] = `{"foo":{"bar":[1,2,4]}}` + "\n"
+			}
+		}
+
+		_, err := NewReader(archive.MustWriteTarGz(files)).WithBundleVerificationConfig(vc).Read()
+		if err == nil || !strings.Contains(err.Error(), "data.json: digest mismatch") {
+			t.Fatal("Expected digest mismatch but got:", err)
+		}
+	})
+}
+
+func TestReadWithRoots(t *testing.T) {
+	files := map[string]string{
+		"a/data.json":   `{"x": 1}`,
+		"b/data.json":   `{"y": 2}`,
+		"b/policy.rego": "package b\n\np := 1\n",
+	}
+
+	var infos []FileInfo
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		h, err := NewSignatureHasher(SHA256)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bs, err := h.HashFile([]byte(files[name]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		info := NewFile(name, hex.EncodeToString(bs), string(SHA256))
+		info.Raw = true
+		infos = append(infos, info)
+	}
+
+	token, err := GenerateSignedToken(infos, NewSigningConfig("secret", "HS256", ""), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signatures, err := json.Marshal(SignaturesConfig{Signatures: []string{token}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vc := NewVerificationConfig(map[string]*KeyConfig{"foo": {Key: "secret", Algorithm: "HS256"}}, "foo", "", nil)
+
+	read := func(files map[string]string, roots []string) (Bundle, error) {
+		tarFiles := [][2]string{{"/.signatures.json", string(signatures)}}
+		for _, name := range slices.Sorted(maps.Keys(files)) {
+			tarFiles = append(tarFiles, [2]string{"/" + name, files[name]})
+		}
+		return NewReader(archive.MustWriteTarGz(tarFiles)).
+			WithBundleVerificationConfig(vc).
+			WithRoots(roots).
+			Read()
+	}
+
+	// data files outside of the roots may be tampered with or missing
+	tampered := maps.Clone(files)
+	tampered["b/data.json"] = `{"y": 3}`
+
+	missing := maps.Clone(files)
+	delete(missing, "b/data.json")
+
+	for _, fs := range []map[string]string{files, tampered, missing} {
+		b, err := read(fs, []string{"a"})
+		if err != nil {
+			t.Fatal("Unexpected error:", err)
+		}
+
+		exp := map[string]any{"a": map[string]any{"x": json.Number("1")}}
+		if !reflect.DeepEqual(exp, b.Data) {
+			t.Fatalf("Expected data %v but got %v", exp, b.Data)
+		}
+
+		if len(b.Modules) != 1 {
+			t.Fatalf("Expected policies to be read, got %v", b.Modules)
+		}
+	}
+
+	// data files within the roots are verified
+	if _, err := read(tampered, []string{"b"}); err == nil || !strings.Contains(err.Error(), "b/data.json: digest mismatch") {
+		t.Fatal("Expected digest mismatch but got:", err)
+	}
+
+	// all data files are verified without roots
+	if _, err := read(tampered, nil); err == nil || !strings.Contains(err.Error(), "b/data.json: digest mismatch") {
+		t.Fatal("Expected digest mismatch but got:", err)
+	}
+}
+
 func TestGenerateSignatureWithPlugin(t *testing.T) {
 	signatures := SignaturesConfig{Signatures: []string{"some_token"}, Plugin: "_foo"}
 
@@ -1596,7 +1923,7 @@ func TestHashBundleFiles(t *testing.T) {
 				})
 			}
 
-			f, err := hashBundleFiles(h, &Bundle{Data: tc.data, Manifest: tc.manifest, Wasm: tc.wasm, PlanModules: plans})
+			f, err := hashBundleFiles(h, &Bundle{Data: tc.data, Manifest: tc.manifest, Wasm: tc.wasm, PlanModules: plans}, false)
 			if err != nil {
 				t.Fatal("Unexpected error:", err)
 			}
@@ -1654,6 +1981,10 @@ func TestRootPathsOverlap(t *testing.T) {
 		{"partial segment overlap b", "a/banana", "a/b", false},
 		{"overlap a", "a/b", "a/b/c", true},
 		{"overlap b", "a/b/c", "a/b", true},
+		{"wildcard overlap a", "tenants/*/policies", "tenants/acme", true},
+		{"wildcard overlap b", "tenants/acme/policies/x", "tenants/*/policies", true},
+		{"wildcards overlap", "tenants/*/policies", "*/acme", true},
+		{"wildcard no overlap", "tenants/*/policies", "tenants/acme/data", false},
 	}
 
 	for _, tc := range cases {
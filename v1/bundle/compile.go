@@ -0,0 +1,118 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package bundle
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"strings"
+
+	"github.com/open-policy-agent/opa/internal/gojsonschema"
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/storage"
+	"github.com/open-policy-agent/opa/v1/util"
+)
+
+// Compile compiles the modules of the bundle b with compiler and checks the
+// bundle data against the schemas declared in the manifest. Modules already on
+// compiler, e.g., the policies of other bundles, are compiled along with the
+// modules of b. Rules are checked for conflicts with the bundle data, limited
+// to the manifest roots if any are set. The errors of compilation and of the
+// schema checks are returned together. For bundles in lazy loading mode, the
+// data files are parsed for these checks.
+func Compile(compiler *ast.Compiler, b *Bundle) ast.Errors {
+	data, err := bundleData(b)
+	if err != nil {
+		return ast.Errors{ast.NewError(ast.CompileErr, nil, "%v", err)}
+	}
+
+	compiler = compiler.WithPathConflictsCheck(func(path []string) (bool, error) {
+		_, ok := lookup(storage.Path(path), data)
+		return ok, nil
+	})
+
+	if b.Manifest.Roots != nil {
+		compiler = compiler.WithPathConflictsCheckRoots(*b.Manifest.Roots)
+	}
+
+	modules := make(map[string]*ast.Module, len(compiler.Modules)+len(b.Modules))
+	maps.Copy(modules, compiler.Modules)
+	for _, mf := range b.Modules {
+		modules[mf.Path] = mf.Parsed
+	}
+
+	compiler.Compile(modules)
+
+	var errs ast.Errors
+	errs = append(errs, compiler.Errors...)
+	errs = append(errs, checkDataSchemas(b.Manifest.Schemas, data)...)
+
+	return errs
+}
+
+// bundleData returns the data of the bundle b. The data of bundles in lazy
+// loading mode is kept in its raw form, so it is parsed from the data files.
+func bundleData(b *Bundle) (map[string]any, error) {
+	if !b.lazyLoadingMode {
+		return b.Data, nil
+	}
+
+	parsed := Bundle{Data: map[string]any{}}
+	for _, item := range b.Raw {
+		switch filepath.Base(item.Path) {
+		case dataFile, yamlDataFile, ymlDataFile:
+		default:
+			continue
+		}
+
+		var value any
+		if err := util.Unmarshal(item.Value, &value); err != nil {
+			return nil, fmt.Errorf("bundle load failed on %v: %w", item.Path, err)
+		}
+
+		if err := insertValue(&parsed, item.Path, value); err != nil {
+			return nil, err
+		}
+	}
+
+	return parsed.Data, nil
+}
+
+// checkDataSchemas validates the bundle data against the schemas declared in
+// the manifest. Schemas for paths without data are skipped.
+func checkDataSchemas(schemas map[string]any, data map[string]any) ast.Errors {
+	var errs ast.Errors
+
+	for _, path := range util.KeysSorted(schemas) {
+		key := strings.Trim(path, "/")
+
+		var value any = data
+		if key != "" {
+			var ok bool
+			if value, ok = lookup(storage.Path(strings.Split(key, "/")), data); !ok {
+				continue
+			}
+		}
+
+		schema, err := gojsonschema.NewSchema(gojsonschema.NewGoLoader(schemas[path]))
+		if err != nil {
+			errs = append(errs, ast.NewError(ast.TypeErr, nil, "invalid data schema at path '%s': %v", path, err))
+			continue
+		}
+
+		result, err := schema.Validate(gojsonschema.NewGoLoader(value))
+		if err != nil {
+			errs = append(errs, ast.NewError(ast.TypeErr, nil, "data at path '%s' could not be validated: %v", path, err))
+			continue
+		}
+
+		for _, re := range result.Errors() {
+			errs = append(errs, ast.NewError(ast.TypeErr, nil, "data at path '%s' does not match schema: %v", path, re))
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,100 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package bundle
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/open-policy-agent/opa/internal/file/archive"
+	"github.com/open-policy-agent/opa/v1/ast"
+)
+
+func TestCompile(t *testing.T) {
+	cases := []struct {
+		note  string
+		files [][2]string
+		errs  []string
+	}{
+		{
+			note: "ok",
+			files: [][2]string{
+				{"/.manifest", `{"roots": ["a", "b"], "schemas": {"a/users": {"type": "array", "items": {"type": "string"}}}}`},
+				{"/a/data.json", `{"users": ["alice", "bob"]}`},
+				{"/b/x.rego", `package b
+p if "alice" in data.a.users`},
+			},
+		},
+		{
+			note: "schema without data",
+			files: [][2]string{
+				{"/.manifest", `{"schemas": {"a/users": {"type": "array"}}}`},
+				{"/x.rego", `package b`},
+			},
+		},
+		{
+			note: "schema at data root",
+			files: [][2]string{
+				{"/.manifest", `{"schemas": {"": {"type": "object", "required": ["c"]}}}`},
+				{"/a/data.json", `{"users": []}`},
+			},
+			errs: []string{"rego_type_error: data at path '' does not match schema: (Root): c is required"},
+		},
+		{
+			note: "data mismatch",
+			files: [][2]string{
+				{"/.manifest", `{"schemas": {"/a/users": {"type": "array", "items": {"type": "string"}}}}`},
+				{"/a/data.json", `{"users": ["alice", 7]}`},
+			},
+			errs: []string{"rego_type_error: data at path '/a/users' does not match schema: 1: Invalid type. Expected: string, given: integer"},
+		},
+		{
+			note: "compile and schema errors",
+			files: [][2]string{
+				{"/.manifest", `{"schemas": {"a/users": {"type": "string"}}}`},
+				{"/a/data.json", `{"users": ["alice"]}`},
+				{"/x.rego", `package b
+p if x`},
+			},
+			errs: []string{
+				"rego_unsafe_var_error: var x is unsafe",
+				"rego_type_error: data at path 'a/users' does not match schema: (Root): Invalid type. Expected: string, given: array",
+			},
+		},
+		{
+			note: "rule conflicts with data",
+			files: [][2]string{
+				{"/.manifest", `{"roots": ["a"]}`},
+				{"/a/data.json", `{"users": ["alice"]}`},
+				{"/x.rego", `package a
+users := []`},
+			},
+			errs: []string{"rego_compile_error: conflicting rule for data path a/users found"},
+		},
+	}
+
+	for _, tc := range cases {
+		for _, lazy := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/lazy=%v", tc.note, lazy), func(t *testing.T) {
+				b, err := NewReader(archive.MustWriteTarGz(tc.files)).WithLazyLoadingMode(lazy).Read()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				errs := Compile(ast.NewCompiler(), &b)
+				if len(errs) != len(tc.errs) {
+					t.Fatalf("expected %d errors, got %d: %v", len(tc.errs), len(errs), errs)
+				}
+
+				for i := range errs {
+					if !strings.Contains(errs[i].Error(), tc.errs[i]) {
+						t.Errorf("expected error %d to contain %q, got %q", i, tc.errs[i], errs[i])
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package bundle
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/open-policy-agent/opa/v1/util"
+)
+
+// Operations supported in delta bundle patches.
+const (
+	PatchOpUpsert  = "upsert"
+	PatchOpRemove  = "remove"
+	PatchOpReplace = "replace"
+)
+
+// ErrNoChanges is returned by Diff when the two bundles have the same data
+// and policies, in which case no delta bundle can be produced.
+var ErrNoChanges = errors.New("bundles have no differences")
+
+// Diff returns a delta bundle that, when activated on top of from, results in
+// the data and policies of to. Data changes are expressed as JSON patch
+// operations, and policy changes as module upsert and remove operations. The
+// manifest of the returned bundle is a copy of the manifest of to.
+//
+// Delta bundles cannot change the manifest roots or Wasm resolvers of a
+// bundle, nor carry Wasm or plan modules; if from and to differ in any of
+// those, a snapshot bundle must be used instead and an error is returned.
+func Diff(from, to Bundle) (Bundle, error) {
+	if len(from.Raw) != 0 || len(to.Raw) != 0 {
+		return Bundle{}, errors.New("bundle diff not supported for bundles read in lazy loading mode")
+	}
+
+	if from.Type() != SnapshotBundleType || to.Type() != SnapshotBundleType {
+		return Bundle{}, errors.New("bundle diff requires snapshot bundles")
+	}
+
+	if len(to.WasmModules) != 0 || len(to.PlanModules) != 0 {
+		return Bundle{}, errors.New("delta bundles cannot contain wasm or plan modules")
+	}
+
+	fromManifest, toManifest := from.Manifest.Copy(), to.Manifest.Copy()
+	fromManifest.Init()
+	toManifest.Init()
+
+	if !fromManifest.equalWasmResolversAndRoots(toManifest) {
+		return Bundle{}, errors.New("delta bundles cannot change manifest roots or wasm resolvers")
+	}
+
+	var patch Patch
+
+	diffData(&patch, "", from.Data, to.Data)
+
+	// Deep copy patch values, so that the delta does not share state with to.
+	for i := range patch.Data {
+		if patch.Data[i].Value != nil {
+			if err := util.RoundTrip(&patch.Data[i].Value); err != nil {
+				return Bundle{}, err
+			}
+		}
+	}
+
+	diffModules(&patch, from.Modules, to.Modules)
+
+	if len(patch.Data) == 0 && len(patch.Modules) == 0 {
+		return Bundle{}, ErrNoChanges
+	}
+
+	return Bundle{
+		Manifest: to.Manifest.Copy(),
+		Patch:    patch,
+	}, nil
+}
+
+func diffData(patch *Patch, path string, from, to map[string]any) {
+	for _, k := range util.KeysSorted(from) {
+		if _, ok := to[k]; !ok {
+			patch.Data = append(patch.Data, PatchOperation{Op: PatchOpRemove, Path: path + "/" + escapePatchPathSegment(k)})
+		}
+	}
+
+	for _, k := range util.KeysSorted(to) {
+		p := path + "/" + escapePatchPathSegment(k)
+		nv := to[k]
+		ov, ok := from[k]
+		if !ok {
+			patch.Data = append(patch.Data, PatchOperation{Op: PatchOpUpsert, Path: p, Value: nv})
+			continue
+		}
+
+		om, oIsObj := ov.(map[string]any)
+		nm, nIsObj := nv.(map[string]any)
+		if oIsObj && nIsObj {
+			diffData(patch, p, om, nm)
+		} else if !reflect.DeepEqual(ov, nv) {
+			patch.Data = append(patch.Data, PatchOperation{Op: PatchOpReplace, Path: p, Value: nv})
+		}
+	}
+}
+
+func diffModules(patch *Patch, from, to []ModuleFile) {
+	key := func(mf ModuleFile) string {
+		return strings.TrimPrefix(mf.Path, "/")
+	}
+
+	toModules := make(map[string]ModuleFile, len(to))
+	for _, mf := range to {
+		toModules[key(mf)] = mf
+	}
+
+	fromModules := make(map[string]ModuleFile, len(from))
+	for _, mf := range from {
+		fromModules[key(mf)] = mf
+		if _, ok := toModules[key(mf)]; !ok {
+			patch.Modules = append(patch.Modules, ModulePatchOperation{Op: PatchOpRemove, Path: mf.Path})
+		}
+	}
+
+	for _, mf := range to {
+		if prev, ok := fromModules[key(mf)]; ok && bytes.Equal(prev.Raw, mf.Raw) {
+			continue
+		}
+		patch.Modules = append(patch.Modules, ModulePatchOperation{Op: PatchOpUpsert, Path: mf.Path, Raw: string(mf.Raw)})
+	}
+}
+
+// escapePatchPathSegment escapes a key for use in a JSON pointer, as described
+// in RFC 6901, section 3. Patch paths are also URL unescaped when applied, so
+// percent signs are escaped too.
+func escapePatchPathSegment(s string) string {
+	s = strings.ReplaceAll(s, "~", "~0")
+	s = strings.ReplaceAll(s, "/", "~1")
+	return strings.ReplaceAll(s, "%", "%25")
+}
@@ -0,0 +1,226 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/metrics"
+	"github.com/open-policy-agent/opa/v1/storage"
+	"github.com/open-policy-agent/opa/v1/storage/inmem"
+	"github.com/open-policy-agent/opa/v1/util"
+)
+
+func TestDiff(t *testing.T) {
+	from := Bundle{
+		Manifest: Manifest{Revision: "r1", Roots: &[]string{"a"}},
+		Data: map[string]any{
+			"a": map[string]any{
+				"keep":   "x",
+				"remove": "y",
+				"change": []any{"1"},
+				"nested": map[string]any{"k/ey": "v1"},
+			},
+		},
+		Modules: []ModuleFile{
+			{Path: "/a/keep.rego", Raw: []byte("package a.keep\np := 1")},
+			{Path: "/a/change.rego", Raw: []byte("package a.change\np := 1")},
+			{Path: "/a/remove.rego", Raw: []byte("package a.remove\np := 1")},
+		},
+	}
+
+	to := Bundle{
+		Manifest: Manifest{Revision: "r2", Roots: &[]string{"a"}},
+		Data: map[string]any{
+			"a": map[string]any{
+				"keep":   "x",
+				"change": []any{"1", "2"},
+				"nested": map[string]any{"k/ey": "v2"},
+				"add":    map[string]any{"z": true},
+			},
+		},
+		Modules: []ModuleFile{
+			{Path: "/a/keep.rego", Raw: []byte("package a.keep\np := 1")},
+			{Path: "/a/change.rego", Raw: []byte("package a.change\np := 2")},
+			{Path: "/a/add.rego", Raw: []byte("package a.add\np := 1")},
+		},
+	}
+
+	delta, err := Diff(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if delta.Type() != DeltaBundleType {
+		t.Fatalf("expected delta bundle, got %v", delta.Type())
+	}
+
+	if delta.Manifest.Revision != "r2" {
+		t.Fatalf("expected revision r2, got %v", delta.Manifest.Revision)
+	}
+
+	exp := Patch{
+		Data: []PatchOperation{
+			{Op: "remove", Path: "/a/remove"},
+			{Op: "upsert", Path: "/a/add", Value: map[string]any{"z": true}},
+			{Op: "replace", Path: "/a/change", Value: []any{"1", "2"}},
+			{Op: "replace", Path: "/a/nested/k~1ey", Value: "v2"},
+		},
+		Modules: []ModulePatchOperation{
+			{Op: "remove", Path: "/a/remove.rego"},
+			{Op: "upsert", Path: "/a/change.rego", Raw: "package a.change\np := 2"},
+			{Op: "upsert", Path: "/a/add.rego", Raw: "package a.add\np := 1"},
+		},
+	}
+
+	if !reflect.DeepEqual(exp, delta.Patch) {
+		t.Fatalf("expected patch:\n%+v\ngot:\n%+v", exp, delta.Patch)
+	}
+}
+
+func TestDiffErrors(t *testing.T) {
+	tests := []struct {
+		note string
+		from Bundle
+		to   Bundle
+		exp  error
+	}{
+		{
+			note: "no changes",
+			from: Bundle{Manifest: Manifest{Revision: "r1"}, Data: map[string]any{"a": 1}},
+			to:   Bundle{Manifest: Manifest{Revision: "r2"}, Data: map[string]any{"a": 1}},
+			exp:  ErrNoChanges,
+		},
+		{
+			note: "roots changed",
+			from: Bundle{Manifest: Manifest{Roots: &[]string{"a"}}},
+			to:   Bundle{Manifest: Manifest{Roots: &[]string{"b"}}},
+			exp:  errors.New("delta bundles cannot change manifest roots or wasm resolvers"),
+		},
+		{
+			note: "wasm modules",
+			from: Bundle{},
+			to:   Bundle{WasmModules: []WasmModuleFile{{Path: "/policy.wasm"}}},
+			exp:  errors.New("delta bundles cannot contain wasm or plan modules"),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			_, err := Diff(tc.from, tc.to)
+			if err == nil || err.Error() != tc.exp.Error() {
+				t.Fatalf("expected error %v, got %v", tc.exp, err)
+			}
+		})
+	}
+}
+
+func TestDiffActivate(t *testing.T) {
+	ctx := context.Background()
+
+	from := Bundle{
+		Manifest: Manifest{Revision: "r1", Roots: &[]string{"a"}},
+		Data:     map[string]any{"a": map[string]any{"x": util.MustUnmarshalJSON([]byte(`1`)), "y": util.MustUnmarshalJSON([]byte(`{"z": [1]}`)), "x%41y": util.MustUnmarshalJSON([]byte(`1`)), "k~/ey": util.MustUnmarshalJSON([]byte(`1`))}},
+		Modules: []ModuleFile{
+			{Path: "/a/p.rego", Raw: []byte("package a.p\nallow := data.a.x == 1")},
+			{Path: "/a/q.rego", Raw: []byte("package a.q\nq := 1")},
+		},
+	}
+
+	to := Bundle{
+		Manifest: Manifest{Revision: "r2", Roots: &[]string{"a"}},
+		Data:     map[string]any{"a": map[string]any{"x": util.MustUnmarshalJSON([]byte(`2`)), "w": util.MustUnmarshalJSON([]byte(`"new"`)), "x%41y": util.MustUnmarshalJSON([]byte(`2`)), "k~/ey": util.MustUnmarshalJSON([]byte(`2`))}},
+		Modules: []ModuleFile{
+			{Path: "/a/p.rego", Raw: []byte("package a.p\nallow := data.a.x == 2")},
+			{Path: "/a/r.rego", Raw: []byte("package a.r\nr := 1")},
+		},
+	}
+
+	delta, err := Diff(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Round-trip the delta through the bundle writer and reader.
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).Write(delta); err != nil {
+		t.Fatal(err)
+	}
+	delta, err = NewReader(&buf).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	activated := func(bundles ...Bundle) (any, map[string]string) {
+		t.Helper()
+
+		store := inmem.New()
+		compiler := ast.NewCompiler()
+
+		for _, b := range bundles {
+			for i := range b.Modules {
+				b.Modules[i].Parsed = ast.MustParseModule(string(b.Modules[i].Raw))
+			}
+
+			err := storage.Txn(ctx, store, storage.WriteParams, func(txn storage.Transaction) error {
+				return Activate(&ActivateOpts{
+					Ctx:      ctx,
+					Store:    store,
+					Txn:      txn,
+					Compiler: compiler,
+					Metrics:  metrics.New(),
+					Bundles:  map[string]*Bundle{"b": &b},
+				})
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		txn := storage.NewTransactionOrDie(ctx, store)
+		defer store.Abort(ctx, txn)
+
+		data, err := store.Read(ctx, txn, storage.MustParsePath("/a"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ids, err := store.ListPolicies(ctx, txn)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		policies := map[string]string{}
+		for _, id := range ids {
+			bs, err := store.GetPolicy(ctx, txn, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			policies[id] = string(bs)
+		}
+
+		if _, ok := compiler.Modules["b/a/q.rego"]; ok {
+			t.Fatal("expected removed module to be removed from compiler")
+		}
+
+		return data, policies
+	}
+
+	expData, expPolicies := activated(to)
+	actData, actPolicies := activated(from, delta)
+
+	if !reflect.DeepEqual(expData, actData) {
+		t.Fatalf("expected data %v, got %v", expData, actData)
+	}
+
+	if !reflect.DeepEqual(expPolicies, actPolicies) {
+		t.Fatalf("expected policies %v, got %v", expPolicies, actPolicies)
+	}
+}
@@ -50,30 +50,36 @@ type hasher struct {
 
 // NewSignatureHasher returns a signature hasher suitable for a particular hashing algorithm
 func NewSignatureHasher(alg HashingAlgorithm) (SignatureHasher, error) {
-	h := &hasher{}
+	h, err := hashFunc(alg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &hasher{h: h}, nil
+}
 
+// hashFunc returns the hash function factory of a hashing algorithm.
+func hashFunc(alg HashingAlgorithm) (func() hash.Hash, error) {
 	switch alg {
 	case MD5:
-		h.h = md5.New
+		return md5.New, nil
 	case SHA1:
-		h.h = sha1.New
+		return sha1.New, nil
 	case SHA224:
-		h.h = sha256.New224
+		return sha256.New224, nil
 	case SHA256:
-		h.h = sha256.New
+		return sha256.New, nil
 	case SHA384:
-		h.h = sha512.New384
+		return sha512.New384, nil
 	case SHA512:
-		h.h = sha512.New
+		return sha512.New, nil
 	case SHA512224:
-		h.h = sha512.New512_224
+		return sha512.New512_224, nil
 	case SHA512256:
-		h.h = sha512.New512_256
+		return sha512.New512_256, nil
 	default:
 		return nil, fmt.Errorf("unsupported hashing algorithm: %s", alg)
 	}
-
-	return h, nil
 }
 
 // HashFile hashes the file content, JSON or binary, both in golang native format.
@@ -0,0 +1,357 @@
+// Copyright 2021 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+// Package inspect reports on the contents of bundles and Rego files, like the
+// inspect command does, e.g., for registries and CI gates that introspect
+// bundles.
+package inspect
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	initload "github.com/open-policy-agent/opa/internal/runtime/init"
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/bundle"
+	"github.com/open-policy-agent/opa/v1/loader"
+	"github.com/open-policy-agent/opa/v1/util"
+)
+
+// Options control what is reported and how files are loaded.
+type Options struct {
+	// RegoVersion is the Rego version of modules that do not declare one.
+	// Defaults to ast.DefaultRegoVersion.
+	RegoVersion ast.RegoVersion
+
+	// IncludeAnnotations enables reporting the annotations of the modules.
+	IncludeAnnotations bool
+}
+
+// Report represents information about a bundle or Rego file.
+type Report struct {
+	Manifest    *bundle.Manifest        `json:"manifest,omitempty"`
+	Signatures  bundle.SignaturesConfig `json:"signatures_config,omitempty"`
+	WasmModules []WasmModule            `json:"wasm_modules,omitempty"`
+
+	// Namespaces maps the packages and data paths to the files contributing to
+	// them.
+	Namespaces map[string][]string `json:"namespaces,omitempty"`
+
+	// Modules are the Rego modules, sorted by path.
+	Modules []Module `json:"modules,omitempty"`
+
+	// RuleCounts maps packages to the number of rules they contain.
+	RuleCounts map[string]int `json:"rule_counts,omitempty"`
+
+	// Entrypoints are the paths of the documents annotated as entrypoints.
+	Entrypoints []string `json:"entrypoints,omitempty"`
+
+	// DataRoots are the roots of the bundle, as declared by its manifest, or the
+	// first path segments of the packages of a Rego file. A root of "" stands
+	// for all of data, as for bundles without roots in their manifest.
+	DataRoots []string `json:"data_roots,omitempty"`
+
+	// Dependencies maps the paths of rules to the paths of the rules they
+	// refer to.
+	Dependencies map[string][]string `json:"dependencies,omitempty"`
+
+	Annotations []*ast.AnnotationsRef `json:"annotations,omitempty"`
+
+	// Required are the capabilities required by the modules, see
+	// ast.Compiler.Required.
+	Required *ast.Capabilities `json:"capabilities,omitempty"`
+}
+
+// Module represents information about a Rego module.
+type Module struct {
+	Path    string `json:"path"`
+	Package string `json:"package"`
+	Rules   int    `json:"rules"`
+}
+
+// WasmModule represents information about a Wasm module of a bundle.
+type WasmModule struct {
+	URL         string   `json:"url"`
+	Path        string   `json:"path"`
+	Entrypoints []string `json:"entrypoints"`
+}
+
+// File returns a report on the bundle, bundle directory or Rego file at path.
+// If the modules do not compile, the report is returned along with the
+// compilation errors, lacking the required capabilities and dependencies.
+func File(path string, opts Options) (*Report, error) {
+	if opts.RegoVersion == ast.RegoUndefined {
+		opts.RegoVersion = ast.DefaultRegoVersion
+	}
+
+	if strings.HasSuffix(path, bundle.RegoExt) {
+		return fileReport(path, opts)
+	}
+
+	return bundleOrDirReport(path, opts)
+}
+
+func bundleOrDirReport(path string, opts Options) (*Report, error) {
+	b, err := loader.NewFileLoader().
+		WithRegoVersion(opts.RegoVersion).
+		WithSkipBundleVerification(true).
+		WithBundleLazyLoadingMode(true). // Bundle lazy loading mode skips parsing data files
+		WithProcessAnnotation(true).     // Always process annotations, for enriching namespace listing
+		AsBundle(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Report{Manifest: &b.Manifest}
+
+	namespaces := make(map[string][]string, len(b.Modules))
+	modules := make([]*ast.Module, 0, len(b.Modules))
+	for _, m := range b.Modules {
+		namespaces[m.Parsed.Package.Path.String()] = append(namespaces[m.Parsed.Package.Path.String()], filepath.Clean(m.Path))
+		modules = append(modules, m.Parsed)
+	}
+	r.Namespaces = namespaces
+
+	if opts.IncludeAnnotations {
+		as, errs := ast.BuildAnnotationSet(modules)
+		if len(errs) > 0 {
+			return nil, errs
+		}
+		flattened := as.Flatten()
+
+		for _, wr := range r.Manifest.WasmResolvers {
+			if as := wr.Annotations; len(as) > 0 {
+				path, err := ast.PtrRef(ast.DefaultRootDocument, wr.Entrypoint)
+				if err != nil {
+					return nil, fmt.Errorf("failed to parse Wasm entrypoint in manifest: %s", err)
+				}
+				for _, a := range as {
+					ar := ast.NewAnnotationsRef(a)
+					ar.Path = path
+					ar.Location = ast.NewLocation(nil, wr.Module, 0, 0)
+					flattened = flattened.Insert(ar)
+				}
+			}
+		}
+
+		r.Annotations = flattened
+	}
+
+	err = r.getBundleDataWasmAndSignatures(path)
+	if err != nil {
+		return nil, err
+	}
+
+	wasmModules := make([]WasmModule, 0, len(b.WasmModules))
+	for _, w := range b.WasmModules {
+		wasmModule := WasmModule{
+			URL:  w.URL,
+			Path: w.Path,
+		}
+		for _, r := range w.Entrypoints {
+			wasmModule.Entrypoints = append(wasmModule.Entrypoints, r.String())
+		}
+		wasmModules = append(wasmModules, wasmModule)
+	}
+	r.WasmModules = wasmModules
+
+	moduleMap := make(map[string]*ast.Module, len(b.Modules))
+	paths := make(map[string]string, len(b.Modules))
+	for _, f := range b.Modules {
+		moduleMap[f.URL] = f.Parsed
+		paths[f.URL] = filepath.Clean(f.Path)
+	}
+
+	return r, r.analyze(moduleMap, paths)
+}
+
+func (r *Report) getBundleDataWasmAndSignatures(name string) error {
+
+	load, err := initload.WalkPaths([]string{name}, nil, true)
+	if err != nil {
+		return err
+	}
+
+	if len(load.BundlesLoader) == 0 || len(load.BundlesLoader) > 1 {
+		return errors.New("expected information on one bundle only but got none or multiple")
+	}
+
+	bl := load.BundlesLoader[0]
+	descriptors := []*bundle.Descriptor{}
+
+	for {
+		f, err := bl.DirectoryLoader.NextFile()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return fmt.Errorf("bundle read failed: %w", err)
+		}
+
+		if strings.HasSuffix(f.Path(), bundle.SignaturesFile) {
+			var buf bytes.Buffer
+			n, err := f.Read(&buf, bundle.DefaultSizeLimitBytes+1)
+			f.Close()
+
+			if err != nil && err != io.EOF {
+				return err
+			} else if err == nil && n >= bundle.DefaultSizeLimitBytes {
+				return fmt.Errorf("bundle file exceeded max size (%v bytes)", bundle.DefaultSizeLimitBytes)
+			}
+
+			var signatures bundle.SignaturesConfig
+			if err := util.NewJSONDecoder(&buf).Decode(&signatures); err != nil {
+				return fmt.Errorf("bundle load failed on signatures decode: %w", err)
+			}
+			r.Signatures = signatures
+		}
+
+		if filepath.Base(f.Path()) == "data.json" || filepath.Base(f.Path()) == "data.yaml" {
+			descriptors = append(descriptors, f)
+		}
+	}
+
+	for _, f := range descriptors {
+		path := filepath.Clean(f.Path())
+		key := strings.Split(strings.TrimPrefix(path, string(os.PathSeparator)), string(os.PathSeparator))
+
+		value := path
+		if bl.IsDir {
+			value = filepath.Clean(f.URL())
+		}
+
+		if len(key) > 1 {
+			key = key[:len(key)-1] // ignore file name ie. data.json / data.yaml
+			path := fmt.Sprintf("%v.%v", ast.DefaultRootDocument, strings.Join(key, "."))
+			r.Namespaces[path] = append(r.Namespaces[path], value)
+		} else {
+			r.Namespaces[ast.DefaultRootDocument.String()] = append(r.Namespaces[ast.DefaultRootDocument.String()], value) // data file at bundle root
+		}
+	}
+
+	for _, item := range r.Manifest.WasmResolvers {
+		key := strings.Split(strings.TrimPrefix(item.Entrypoint, "/"), "/")
+		path := fmt.Sprintf("%v.%v", ast.DefaultRootDocument, strings.Join(key, "."))
+		r.Namespaces[path] = append(r.Namespaces[path], item.Module)
+	}
+
+	return nil
+}
+
+func fileReport(path string, opts Options) (*Report, error) {
+	res, err := loader.NewFileLoader().
+		WithRegoVersion(opts.RegoVersion).
+		WithSkipBundleVerification(true).
+		WithProcessAnnotation(true). // Always process annotations, for enriching namespace listing
+		All([]string{path})
+	if err != nil {
+		return nil, err
+	}
+	r := &Report{
+		Namespaces: make(map[string][]string, len(res.Modules)),
+	}
+
+	moduleMap := make(map[string]*ast.Module, len(res.Modules))
+	paths := make(map[string]string, len(res.Modules))
+
+	for _, m := range res.Modules {
+		r.Namespaces[m.Parsed.Package.Path.String()] = append(
+			r.Namespaces[m.Parsed.Package.Path.String()],
+			filepath.Clean(m.Name),
+		)
+		moduleMap[m.Name] = m.Parsed
+		paths[m.Name] = filepath.Clean(m.Name)
+	}
+
+	if opts.IncludeAnnotations {
+		as, errs := ast.BuildAnnotationSet(util.Values(moduleMap))
+		if len(errs) > 0 {
+			return nil, errs
+		}
+
+		r.Annotations = as.Flatten()
+	}
+
+	return r, r.analyze(moduleMap, paths)
+}
+
+// analyze reports on the modules, keyed by their names, and compiles them to
+// report the entrypoints, dependencies and required capabilities.
+func (r *Report) analyze(modules map[string]*ast.Module, paths map[string]string) error {
+	r.RuleCounts = make(map[string]int, len(modules))
+	for name, m := range modules {
+		pkg := m.Package.Path.String()
+		r.Modules = append(r.Modules, Module{Path: paths[name], Package: pkg, Rules: len(m.Rules)})
+		r.RuleCounts[pkg] += len(m.Rules)
+	}
+	slices.SortFunc(r.Modules, func(a, b Module) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	r.DataRoots = r.dataRoots()
+
+	c := ast.NewCompiler().
+		WithAllowUndefinedFunctionCalls(true)
+	c.Compile(modules)
+	if c.Failed() {
+		return c.Errors
+	}
+
+	r.Required = c.Required
+
+	if as := c.GetAnnotationSet(); as != nil {
+		for _, ar := range as.Flatten() {
+			if ar.Annotations.Entrypoint {
+				if path := ar.Path.String(); !slices.Contains(r.Entrypoints, path) {
+					r.Entrypoints = append(r.Entrypoints, path)
+				}
+			}
+		}
+		slices.Sort(r.Entrypoints)
+	}
+
+	r.Dependencies = map[string][]string{}
+	for _, name := range util.KeysSorted(c.Modules) {
+		for _, rule := range c.Modules[name].Rules {
+			path := rule.Path().String()
+			for dep := range c.Graph.Dependencies(rule) {
+				if dep, ok := dep.(*ast.Rule); ok {
+					if depPath := dep.Path().String(); depPath != path && !slices.Contains(r.Dependencies[path], depPath) {
+						r.Dependencies[path] = append(r.Dependencies[path], depPath)
+					}
+				}
+			}
+			slices.Sort(r.Dependencies[path])
+		}
+	}
+
+	return nil
+}
+
+// dataRoots returns the roots declared by the manifest or, if there is none,
+// the first path segments of the namespaces.
+func (r *Report) dataRoots() []string {
+	if r.Manifest != nil && r.Manifest.Roots != nil {
+		roots := slices.Clone(*r.Manifest.Roots)
+		slices.Sort(roots)
+		return roots
+	}
+
+	var roots []string
+	for ns := range r.Namespaces {
+		root, _, _ := strings.Cut(strings.TrimPrefix(strings.TrimPrefix(ns, ast.DefaultRootDocument.String()), "."), ".")
+		if !slices.Contains(roots, root) {
+			roots = append(roots, root)
+		}
+	}
+	slices.Sort(roots)
+	return roots
+}
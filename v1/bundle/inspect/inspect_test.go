@@ -0,0 +1,112 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package inspect
+
+import (
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/util/test"
+)
+
+func TestFileReport(t *testing.T) {
+	files := map[string]string{
+		"/.manifest":       `{"roots": ["authz", "users"]}`,
+		"/users/data.json": `{"alice": {"admin": true}}`,
+		"/authz/authz.rego": `# METADATA
+# entrypoint: true
+package authz
+
+allow if admin
+
+admin if data.users[input.user].admin
+`,
+		"/authz/util.rego": `package authz
+
+names := [upper(n) | some n, _ in data.users]
+`,
+	}
+
+	test.WithTempFS(files, func(rootDir string) {
+		r, err := File(rootDir, Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expModules := []Module{
+			{Path: filepath.Join(rootDir, "authz", "authz.rego"), Package: "data.authz", Rules: 2},
+			{Path: filepath.Join(rootDir, "authz", "util.rego"), Package: "data.authz", Rules: 1},
+		}
+		if !reflect.DeepEqual(r.Modules, expModules) {
+			t.Errorf("expected modules %v, got %v", expModules, r.Modules)
+		}
+
+		if exp := map[string]int{"data.authz": 3}; !reflect.DeepEqual(r.RuleCounts, exp) {
+			t.Errorf("expected rule counts %v, got %v", exp, r.RuleCounts)
+		}
+
+		if exp := []string{"data.authz"}; !reflect.DeepEqual(r.Entrypoints, exp) {
+			t.Errorf("expected entrypoints %v, got %v", exp, r.Entrypoints)
+		}
+
+		if exp := []string{"authz", "users"}; !reflect.DeepEqual(r.DataRoots, exp) {
+			t.Errorf("expected data roots %v, got %v", exp, r.DataRoots)
+		}
+
+		if exp := map[string][]string{"data.authz.allow": {"data.authz.admin"}}; !reflect.DeepEqual(r.Dependencies, exp) {
+			t.Errorf("expected dependencies %v, got %v", exp, r.Dependencies)
+		}
+
+		if r.Required == nil || !slices.ContainsFunc(r.Required.Builtins, func(bi *ast.Builtin) bool { return bi.Name == "upper" }) {
+			t.Errorf("expected upper to be required, got %v", r.Required)
+		}
+	})
+}
+
+func TestFileReportDataRootsWithoutManifest(t *testing.T) {
+	files := map[string]string{
+		"/x/data.json":     `{}`,
+		"/a/b/policy.rego": "package a.b\n\np := 1\n",
+	}
+
+	test.WithTempFS(files, func(rootDir string) {
+		r, err := File(rootDir, Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if exp := []string{""}; !reflect.DeepEqual(r.DataRoots, exp) {
+			t.Errorf("expected data roots %v, got %v", exp, r.DataRoots)
+		}
+
+		r, err = File(filepath.Join(rootDir, "a", "b", "policy.rego"), Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if exp := []string{"a"}; !reflect.DeepEqual(r.DataRoots, exp) {
+			t.Errorf("expected data roots %v, got %v", exp, r.DataRoots)
+		}
+	})
+}
+
+func TestFileReportCompileErrors(t *testing.T) {
+	files := map[string]string{
+		"/policy.rego": "package p\n\nq := x\n",
+	}
+
+	test.WithTempFS(files, func(rootDir string) {
+		r, err := File(filepath.Join(rootDir, "policy.rego"), Options{})
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if r == nil || len(r.Modules) != 1 || r.Required != nil {
+			t.Fatalf("expected report without requirements, got %v", r)
+		}
+	})
+}
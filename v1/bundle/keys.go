@@ -80,6 +80,10 @@ type SigningConfig struct {
 	Key        string
 	Algorithm  string
 	ClaimsPath string
+
+	// RawDigests makes the signature payload contain the digests of the raw
+	// bytes of all files, so that they can be verified while they are read.
+	RawDigests bool
 }
 
 // NewSigningConfig return a new SigningConfig
@@ -104,6 +108,13 @@ func (s *SigningConfig) WithPlugin(plugin string) *SigningConfig {
 	return s
 }
 
+// WithRawDigests sets whether the signature payload contains the digests of
+// the raw bytes of the files in the signing config
+func (s *SigningConfig) WithRawDigests(yes bool) *SigningConfig {
+	s.RawDigests = yes
+	return s
+}
+
 // GetPrivateKey returns the private key or secret from the signing config
 func (s *SigningConfig) GetPrivateKey() (any, error) {
 
@@ -12,6 +12,7 @@ import (
 	"fmt"
 	"maps"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 
@@ -49,6 +50,11 @@ func EtagStoragePath(name string) storage.Path {
 	return append(BundlesBasePath, name, "etag")
 }
 
+// ModulesHashStoragePath is the storage path used for the given named bundle modules hash.
+func ModulesHashStoragePath(name string) storage.Path {
+	return append(BundlesBasePath, name, "modules_hash")
+}
+
 func namedBundlePath(name string) storage.Path {
 	return append(BundlesBasePath, name)
 }
@@ -82,19 +88,7 @@ func moduleInfoPath(id string) storage.Path {
 }
 
 func read(ctx context.Context, store storage.Store, txn storage.Transaction, path storage.Path) (any, error) {
-	value, err := store.Read(ctx, txn, path)
-	if err != nil {
-		return nil, err
-	}
-
-	if astValue, ok := value.(ast.Value); ok {
-		value, err = ast.JSON(astValue)
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	return value, nil
+	return storage.ReadJSON(ctx, store, txn, path)
 }
 
 // ReadBundleNamesFromStore will return a list of bundle names which have had their metadata stored.
@@ -129,6 +123,19 @@ func WriteEtagToStore(ctx context.Context, store storage.Store, txn storage.Tran
 	return write(ctx, store, txn, EtagStoragePath(name), etag)
 }
 
+// WriteModulesHashToStore will write the bundle modules hash, as returned by
+// ModulesHash, into the storage. Activating a bundle with a different manifest
+// or modules erases it.
+func WriteModulesHashToStore(ctx context.Context, store storage.Store, txn storage.Transaction, name, hash string) error {
+	return write(ctx, store, txn, ModulesHashStoragePath(name), hash)
+}
+
+// EraseModulesHashFromStore will remove the bundle modules hash from storage.
+func EraseModulesHashFromStore(ctx context.Context, store storage.Store, txn storage.Transaction, name string) error {
+	err := store.Write(ctx, txn, storage.RemoveOp, ModulesHashStoragePath(name), nil)
+	return suppressNotFound(err)
+}
+
 func write(ctx context.Context, store storage.Store, txn storage.Transaction, path storage.Path, value any) error {
 	if err := util.RoundTrip(&value); err != nil {
 		return err
@@ -324,6 +331,23 @@ func ReadBundleEtagFromStore(ctx context.Context, store storage.Store, txn stora
 	return readEtagFromStore(ctx, store, txn, EtagStoragePath(name))
 }
 
+// ReadBundleModulesHashFromStore returns the modules hash for the specified
+// bundle. If the bundle has no modules hash stored, a not found error is
+// returned.
+func ReadBundleModulesHashFromStore(ctx context.Context, store storage.Store, txn storage.Transaction, name string) (string, error) {
+	value, err := read(ctx, store, txn, ModulesHashStoragePath(name))
+	if err != nil {
+		return "", err
+	}
+
+	str, ok := value.(string)
+	if !ok {
+		return "", errors.New("corrupt bundle modules hash")
+	}
+
+	return str, nil
+}
+
 func readEtagFromStore(ctx context.Context, store storage.Store, txn storage.Transaction, path storage.Path) (string, error) {
 	value, err := read(ctx, store, txn, path)
 	if err != nil {
@@ -357,6 +381,16 @@ type ActivateOpts struct {
 	ParserOptions            ast.ParserOptions
 	Plugin                   string
 
+	// AdmitModule is called with every module written by the activation. If
+	// it returns an error, the activation fails with that error. Optional.
+	AdmitModule func(ctx context.Context, id string, module *ast.Module) error
+
+	// DataOnly indicates that the snapshot bundles only change data, i.e.,
+	// that their ModulesHash equals the one of the active bundles. The data of
+	// the bundles is replaced, but their policies are not recompiled. Compiler
+	// must be the compiler of the active policies.
+	DataOnly bool
+
 	legacy bool
 }
 
@@ -417,6 +451,10 @@ func Deactivate(opts *DeactivateOpts) error {
 
 func activateBundles(opts *ActivateOpts) error {
 
+	if opts.DataOnly {
+		return activateDataOnly(opts)
+	}
+
 	// Build collections of bundle names, modules, and roots to erase
 	erase := map[string]struct{}{}
 	names := map[string]struct{}{}
@@ -447,13 +485,39 @@ func activateBundles(opts *ActivateOpts) error {
 
 	// Before changing anything make sure the roots don't collide with any
 	// other bundles that already are activated or other bundles being activated.
-	err := hasRootsOverlap(opts.Ctx, opts.Store, opts.Txn, opts.Bundles)
+	evicted, err := hasRootsOverlap(opts.Ctx, opts.Store, opts.Txn, opts.Bundles)
 	if err != nil {
 		return err
 	}
 
+	// Bundles whose roots are taken over by bundles of higher priority are
+	// deactivated.
+	for _, name := range evicted {
+		names[name] = struct{}{}
+
+		roots, err := ReadBundleRootsFromStore(opts.Ctx, opts.Store, opts.Txn, name)
+		if suppressNotFound(err) != nil {
+			return err
+		}
+		for _, root := range roots {
+			erase[root] = struct{}{}
+		}
+	}
+
+	// Module patches are parsed up front so that the modules upserted by delta
+	// bundles are admitted together with the modules of snapshot bundles.
+	patched, err := parseModulePatches(opts, deltaBundles)
+	if err != nil {
+		return err
+	}
+
+	if err := admitModules(opts, snapshotBundles, deltaBundles, patched); err != nil {
+		return err
+	}
+
+	var removedModules []string
 	if len(deltaBundles) != 0 {
-		err := activateDeltaBundles(opts, deltaBundles)
+		removedModules, err = activateDeltaBundles(opts, deltaBundles, patched)
 		if err != nil {
 			return err
 		}
@@ -466,35 +530,90 @@ func activateBundles(opts *ActivateOpts) error {
 		return err
 	}
 
-	// Validate data in bundle does not contain paths outside the bundle's roots.
-	for _, b := range snapshotBundles {
+	// Validate data in bundle does not contain paths outside the bundle's roots,
+	// and find the paths matching wildcard roots to write the data at.
+	basePaths, err := lazyDataBasePaths(snapshotBundles)
+	if err != nil {
+		return err
+	}
+
+	// Compile the modules all at once to avoid having to re-do work.
+	remainingAndExtra := make(map[string]*ast.Module)
+	maps.Copy(remainingAndExtra, remaining)
+	maps.Copy(remainingAndExtra, opts.ExtraModules)
+
+	err = compileModules(opts.Compiler, opts.Metrics, snapshotBundles, remainingAndExtra, removedModules, opts.legacy, opts.AuthorizationDecisionRef)
+	if err != nil {
+		return err
+	}
+
+	if err := writeDataAndModules(opts.Ctx, opts.Store, opts.Txn, opts.TxnCtx, snapshotBundles, basePaths, opts.legacy, opts.ParserOptions.RegoVersion); err != nil {
+		return err
+	}
+
+	if err := ast.CheckPathConflicts(opts.Compiler, storage.NonEmpty(opts.Ctx, opts.Store, opts.Txn)); len(err) > 0 {
+		return err
+	}
+
+	for name, b := range snapshotBundles {
+		if err := writeManifestToStore(opts, name, b.Manifest); err != nil {
+			return err
+		}
+
+		if err := writeEtagToStore(opts, name, b.Etag); err != nil {
+			return err
+		}
+
+		if err := writeWasmModulesToStore(opts.Ctx, opts.Store, opts.Txn, name, b); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lazyDataBasePaths validates that the data of bundles in lazy loading mode
+// does not contain paths outside the bundle's roots, and returns the paths
+// matching wildcard roots to write the data at.
+func lazyDataBasePaths(bundles map[string]*Bundle) (map[string][]string, error) {
+	basePaths := map[string][]string{}
+	for name, b := range bundles {
 
 		if b.lazyLoadingMode {
+			visit := func(string) {}
+			if slices.ContainsFunc(*b.Manifest.Roots, hasRootWildcard) {
+				basePaths[name] = slices.DeleteFunc(slices.Clone(*b.Manifest.Roots), hasRootWildcard)
+				visit = func(path string) {
+					if !RootPathsContain(basePaths[name], path) {
+						basePaths[name] = append(basePaths[name], path)
+					}
+				}
+			}
 
 			for _, item := range b.Raw {
 				path := filepath.ToSlash(item.Path)
 
 				if filepath.Base(path) == dataFile || filepath.Base(path) == yamlDataFile {
 					var val map[string]json.RawMessage
-					err = util.Unmarshal(item.Value, &val)
+					err := util.Unmarshal(item.Value, &val)
 					if err == nil {
-						err = doDFS(val, filepath.Dir(strings.Trim(path, "/")), *b.Manifest.Roots)
+						err = walkDataInRoots(val, filepath.Dir(strings.Trim(path, "/")), *b.Manifest.Roots, visit)
 						if err != nil {
-							return err
+							return nil, err
 						}
 					} else {
 						// Build an object for the value
 						p := getNormalizedPath(path)
 
 						if len(p) == 0 {
-							return errors.New("root value must be object")
+							return nil, errors.New("root value must be object")
 						}
 
 						// verify valid YAML or JSON value
 						var x any
 						err := util.Unmarshal(item.Value, &x)
 						if err != nil {
-							return err
+							return nil, err
 						}
 
 						value := item.Value
@@ -504,7 +623,7 @@ func activateBundles(opts *ActivateOpts) error {
 
 							bs, err := json.Marshal(dir)
 							if err != nil {
-								return err
+								return nil, err
 							}
 
 							value = bs
@@ -512,9 +631,9 @@ func activateBundles(opts *ActivateOpts) error {
 						}
 						dir[p[0]] = value
 
-						err = doDFS(dir, filepath.Dir(strings.Trim(path, "/")), *b.Manifest.Roots)
+						err = walkDataInRoots(dir, filepath.Dir(strings.Trim(path, "/")), *b.Manifest.Roots, visit)
 						if err != nil {
-							return err
+							return nil, err
 						}
 					}
 				}
@@ -522,42 +641,68 @@ func activateBundles(opts *ActivateOpts) error {
 		}
 	}
 
-	// Compile the modules all at once to avoid having to re-do work.
-	remainingAndExtra := make(map[string]*ast.Module)
-	maps.Copy(remainingAndExtra, remaining)
-	maps.Copy(remainingAndExtra, opts.ExtraModules)
+	return basePaths, nil
+}
 
-	err = compileModules(opts.Compiler, opts.Metrics, snapshotBundles, remainingAndExtra, opts.legacy, opts.AuthorizationDecisionRef)
-	if err != nil {
-		return err
+// activateDataOnly replaces the data of snapshot bundles whose modules have
+// not changed. Since the policies stay the same, the compiler is only used to
+// check that the new data does not conflict with them.
+func activateDataOnly(opts *ActivateOpts) error {
+
+	roots := map[string]struct{}{}
+	for name, b := range opts.Bundles {
+		if b.Type() != SnapshotBundleType {
+			return fmt.Errorf("bundle '%s' cannot be activated without recompiling policies", name)
+		}
+		for _, root := range *b.Manifest.Roots {
+			roots[root] = struct{}{}
+		}
 	}
 
-	if err := writeDataAndModules(opts.Ctx, opts.Store, opts.Txn, opts.TxnCtx, snapshotBundles, opts.legacy, opts.ParserOptions.RegoVersion); err != nil {
+	basePaths, err := lazyDataBasePaths(opts.Bundles)
+	if err != nil {
 		return err
 	}
 
-	if err := ast.CheckPathConflicts(opts.Compiler, storage.NonEmpty(opts.Ctx, opts.Store, opts.Txn)); len(err) > 0 {
+	if err := eraseData(opts.Ctx, opts.Store, opts.Txn, roots); err != nil {
 		return err
 	}
 
-	for name, b := range snapshotBundles {
-		if err := writeManifestToStore(opts, name, b.Manifest); err != nil {
+	for name, b := range opts.Bundles {
+		// Bundles in lazy loading mode write their data, and their unchanged
+		// modules, straight from the raw bundle files.
+		if b.lazyLoadingMode {
+			err = writeDataAndModules(opts.Ctx, opts.Store, opts.Txn, opts.TxnCtx, map[string]*Bundle{name: b}, basePaths, opts.legacy, opts.ParserOptions.RegoVersion)
+		} else {
+			err = writeData(opts.Ctx, opts.Store, opts.Txn, *b.Manifest.Roots, b.Data)
+		}
+		if err != nil {
 			return err
 		}
 
-		if err := writeEtagToStore(opts, name, b.Etag); err != nil {
+		if err := writeManifestToStore(opts, name, b.Manifest); err != nil {
 			return err
 		}
 
-		if err := writeWasmModulesToStore(opts.Ctx, opts.Store, opts.Txn, name, b); err != nil {
+		if err := writeEtagToStore(opts, name, b.Etag); err != nil {
 			return err
 		}
 	}
 
+	if err := ast.CheckPathConflicts(opts.Compiler, storage.NonEmpty(opts.Ctx, opts.Store, opts.Txn)); len(err) > 0 {
+		return err
+	}
+
 	return nil
 }
 
 func doDFS(obj map[string]json.RawMessage, path string, roots []string) error {
+	return walkDataInRoots(obj, path, roots, func(string) {})
+}
+
+// walkDataInRoots checks that the data in obj at path is within roots, and
+// calls visit with the paths of the data contained in roots.
+func walkDataInRoots(obj map[string]json.RawMessage, path string, roots []string, visit func(string)) error {
 	if len(roots) == 1 && roots[0] == "" {
 		return nil
 	}
@@ -576,7 +721,8 @@ func doDFS(obj map[string]json.RawMessage, path string, roots []string) error {
 			contains = true
 		} else {
 			for i := range roots {
-				if strings.HasPrefix(strings.Trim(roots[i], "/"), newPath) {
+				if strings.HasPrefix(strings.Trim(roots[i], "/"), newPath) ||
+					(hasRootWildcard(roots[i]) && RootPathsOverlap(strings.Trim(roots[i], "/"), newPath)) {
 					prefix = true
 					break
 				}
@@ -588,6 +734,7 @@ func doDFS(obj map[string]json.RawMessage, path string, roots []string) error {
 		}
 
 		if contains {
+			visit(newPath)
 			continue
 		}
 
@@ -597,14 +744,14 @@ func doDFS(obj map[string]json.RawMessage, path string, roots []string) error {
 			return fmt.Errorf("manifest roots %v do not permit data at path '/%s' (hint: check bundle directory structure)", roots, newPath)
 		}
 
-		if err := doDFS(next, newPath, roots); err != nil {
+		if err := walkDataInRoots(next, newPath, roots, visit); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func activateDeltaBundles(opts *ActivateOpts, bundles map[string]*Bundle) error {
+func activateDeltaBundles(opts *ActivateOpts, bundles map[string]*Bundle, patched map[string][]*ast.Module) ([]string, error) {
 
 	// Check that the manifest roots and wasm resolvers in the delta bundle
 	// match with those currently in the store
@@ -614,41 +761,51 @@ func activateDeltaBundles(opts *ActivateOpts, bundles map[string]*Bundle) error
 			if storage.IsNotFound(err) {
 				continue
 			}
-			return err
+			return nil, err
 		}
 
 		manifest, err := valueToManifest(value)
 		if err != nil {
-			return fmt.Errorf("corrupt manifest data: %w", err)
+			return nil, fmt.Errorf("corrupt manifest data: %w", err)
 		}
 
 		if !b.Manifest.equalWasmResolversAndRoots(manifest) {
-			return fmt.Errorf("delta bundle '%s' has wasm resolvers or manifest roots that are different from those in the store", name)
+			return nil, fmt.Errorf("delta bundle '%s' has wasm resolvers or manifest roots that are different from those in the store", name)
 		}
 	}
 
-	for _, b := range bundles {
+	var removed []string
+
+	for name, b := range bundles {
 		err := applyPatches(opts.Ctx, opts.Store, opts.Txn, b.Patch.Data)
 		if err != nil {
-			return err
+			return nil, err
+		}
+
+		// Policies are written to the store before the store is read back
+		// for compilation, so only removed modules need to be tracked.
+		r, err := applyModulePatches(opts, name, b, patched[name])
+		if err != nil {
+			return nil, err
 		}
+		removed = append(removed, r...)
 	}
 
 	if err := ast.CheckPathConflicts(opts.Compiler, storage.NonEmpty(opts.Ctx, opts.Store, opts.Txn)); len(err) > 0 {
-		return err
+		return nil, err
 	}
 
 	for name, b := range bundles {
 		if err := writeManifestToStore(opts, name, b.Manifest); err != nil {
-			return err
+			return nil, err
 		}
 
 		if err := writeEtagToStore(opts, name, b.Etag); err != nil {
-			return err
+			return nil, err
 		}
 	}
 
-	return nil
+	return removed, nil
 }
 
 func valueToManifest(v any) (Manifest, error) {
@@ -721,15 +878,50 @@ func eraseData(ctx context.Context, store storage.Store, txn storage.Transaction
 			return fmt.Errorf("manifest root path invalid: %v", root)
 		}
 
-		if len(path) > 0 {
-			if err := store.Write(ctx, txn, storage.RemoveOp, path, nil); suppressNotFound(err) != nil {
+		paths := []storage.Path{path}
+		if hasRootWildcard(root) {
+			var err error
+			if paths, err = expandRootPathInStore(ctx, store, txn, path); err != nil {
 				return err
 			}
 		}
+
+		for _, path := range paths {
+			if len(path) > 0 {
+				if err := store.Write(ctx, txn, storage.RemoveOp, path, nil); suppressNotFound(err) != nil {
+					return err
+				}
+			}
+		}
 	}
 	return nil
 }
 
+// expandRootPathInStore returns the paths in the store matching the root path
+// with wildcard segments.
+func expandRootPathInStore(ctx context.Context, store storage.Store, txn storage.Transaction, root storage.Path) ([]storage.Path, error) {
+	var readErr error
+	paths := expandRootPath(root, func(path []string) *any {
+		value, err := read(ctx, store, txn, path)
+		if err != nil {
+			if !storage.IsNotFound(err) && readErr == nil {
+				readErr = err
+			}
+			return nil
+		}
+		return &value
+	})
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	result := make([]storage.Path, len(paths))
+	for i := range paths {
+		result[i] = paths[i]
+	}
+	return result, nil
+}
+
 type moduleInfo struct {
 	RegoVersion ast.RegoVersion `json:"rego_version"`
 }
@@ -877,7 +1069,7 @@ func writeModuleRegoVersionToStore(ctx context.Context, store storage.Store, txn
 	return nil
 }
 
-func writeDataAndModules(ctx context.Context, store storage.Store, txn storage.Transaction, txnCtx *storage.Context, bundles map[string]*Bundle, legacy bool, runtimeRegoVersion ast.RegoVersion) error {
+func writeDataAndModules(ctx context.Context, store storage.Store, txn storage.Transaction, txnCtx *storage.Context, bundles map[string]*Bundle, basePaths map[string][]string, legacy bool, runtimeRegoVersion ast.RegoVersion) error {
 	params := storage.WriteParams
 	params.Context = txnCtx
 
@@ -910,6 +1102,9 @@ func writeDataAndModules(ctx context.Context, store storage.Store, txn storage.T
 			}
 		} else {
 			params.BasePaths = *b.Manifest.Roots
+			if paths, ok := basePaths[name]; ok {
+				params.BasePaths = paths
+			}
 
 			err := store.Truncate(ctx, txn, params, NewIterator(b.Raw))
 			if err != nil {
@@ -939,11 +1134,30 @@ func writeDataAndModules(ctx context.Context, store storage.Store, txn storage.T
 }
 
 func writeData(ctx context.Context, store storage.Store, txn storage.Transaction, roots []string, data map[string]any) error {
+	var paths []storage.Path
 	for _, root := range roots {
 		path, ok := storage.ParsePathEscaped("/" + root)
 		if !ok {
 			return fmt.Errorf("manifest root path invalid: %v", root)
 		}
+
+		if !hasRootWildcard(root) {
+			paths = append(paths, path)
+			continue
+		}
+
+		for _, path := range expandRootPath(path, func(path []string) *any {
+			value, ok := lookup(path, data)
+			if !ok {
+				return nil
+			}
+			return &value
+		}) {
+			paths = append(paths, path)
+		}
+	}
+
+	for _, path := range paths {
 		if value, ok := lookup(path, data); ok {
 			if len(path) > 0 {
 				if err := storage.MakeDir(ctx, store, txn, path[:len(path)-1]); err != nil {
@@ -958,15 +1172,18 @@ func writeData(ctx context.Context, store storage.Store, txn storage.Transaction
 	return nil
 }
 
-func compileModules(compiler *ast.Compiler, m metrics.Metrics, bundles map[string]*Bundle, extraModules map[string]*ast.Module, legacy bool, authorizationDecisionRef ast.Ref) error {
+func compileModules(compiler *ast.Compiler, m metrics.Metrics, bundles map[string]*Bundle, extraModules map[string]*ast.Module, removedModules []string, legacy bool, authorizationDecisionRef ast.Ref) error {
 
 	m.Timer(metrics.RegoModuleCompile).Start()
 	defer m.Timer(metrics.RegoModuleCompile).Stop()
 
 	modules := map[string]*ast.Module{}
 
-	// preserve any modules already on the compiler
+	// preserve any modules already on the compiler, except those removed by delta bundles
 	maps.Copy(modules, compiler.Modules)
+	for _, id := range removedModules {
+		delete(modules, id)
+	}
 
 	// preserve any modules passed in from the store
 	maps.Copy(modules, extraModules)
@@ -1059,22 +1276,32 @@ func lookup(path storage.Path, data map[string]any) (any, bool) {
 	return value, ok
 }
 
-func hasRootsOverlap(ctx context.Context, store storage.Store, txn storage.Transaction, bundles map[string]*Bundle) error {
+// hasRootsOverlap checks that the roots of the bundles overlap neither with
+// each other nor with those of the activated bundles. It returns the names of
+// the activated bundles whose roots are taken over by snapshot bundles of
+// higher priority instead.
+func hasRootsOverlap(ctx context.Context, store storage.Store, txn storage.Transaction, bundles map[string]*Bundle) ([]string, error) {
 	collisions := map[string][]string{}
 	allBundles, err := ReadBundleNamesFromStore(ctx, store, txn)
 	if suppressNotFound(err) != nil {
-		return err
+		return nil, err
 	}
 
 	allRoots := map[string][]string{}
+	priorities := map[string]int{}
 
 	// Build a map of roots for existing bundles already in the system
 	for _, name := range allBundles {
 		roots, err := ReadBundleRootsFromStore(ctx, store, txn, name)
 		if suppressNotFound(err) != nil {
-			return err
+			return nil, err
 		}
 		allRoots[name] = roots
+
+		priorities[name], err = readBundlePriorityFromStore(ctx, store, txn, name)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// Add in any bundles that are being activated, overwrite existing roots
@@ -1083,6 +1310,8 @@ func hasRootsOverlap(ctx context.Context, store storage.Store, txn storage.Trans
 		allRoots[name] = *bundle.Manifest.Roots
 	}
 
+	evicted := map[string]struct{}{}
+
 	// Now check for each new bundle if it conflicts with any of the others
 	for name, bundle := range bundles {
 		for otherBundle, otherRoots := range allRoots {
@@ -1091,11 +1320,20 @@ func hasRootsOverlap(ctx context.Context, store storage.Store, txn storage.Trans
 				continue
 			}
 
+			// Activated bundles of lower priority give up their roots to
+			// snapshot bundles.
+			_, activating := bundles[otherBundle]
+			takeOver := !activating && bundle.Type() == SnapshotBundleType && bundle.Manifest.Priority > priorities[otherBundle]
+
 			// Compare the "new" roots with other existing (or a different bundles new roots)
 			for _, newRoot := range *bundle.Manifest.Roots {
 				for _, otherRoot := range otherRoots {
 					if RootPathsOverlap(newRoot, otherRoot) {
-						collisions[otherBundle] = append(collisions[otherBundle], newRoot)
+						if takeOver {
+							evicted[otherBundle] = struct{}{}
+						} else {
+							collisions[otherBundle] = append(collisions[otherBundle], newRoot)
+						}
 					}
 				}
 			}
@@ -1107,9 +1345,28 @@ func hasRootsOverlap(ctx context.Context, store storage.Store, txn storage.Trans
 		for name := range collisions {
 			bundleNames = append(bundleNames, name)
 		}
-		return fmt.Errorf("detected overlapping roots in bundle manifest with: %s", bundleNames)
+		return nil, fmt.Errorf("detected overlapping roots in bundle manifest with: %s", bundleNames)
 	}
-	return nil
+	return util.KeysSorted(evicted), nil
+}
+
+func readBundlePriorityFromStore(ctx context.Context, store storage.Store, txn storage.Transaction, name string) (int, error) {
+	value, err := read(ctx, store, txn, append(ManifestStoragePath(name), "priority"))
+	if err != nil {
+		return 0, suppressNotFound(err)
+	}
+
+	n, ok := value.(json.Number)
+	if !ok {
+		return 0, errors.New("corrupt manifest priority")
+	}
+
+	priority, err := n.Int64()
+	if err != nil {
+		return 0, errors.New("corrupt manifest priority")
+	}
+
+	return int(priority), nil
 }
 
 func applyPatches(ctx context.Context, store storage.Store, txn storage.Transaction, patches []PatchOperation) error {
@@ -1123,7 +1380,7 @@ func applyPatches(ctx context.Context, store storage.Store, txn storage.Transact
 
 		var op storage.PatchOp
 		switch pat.Op {
-		case "upsert":
+		case PatchOpUpsert:
 			op = storage.AddOp
 
 			_, err := store.Read(ctx, txn, path[:len(path)-1])
@@ -1136,9 +1393,9 @@ func applyPatches(ctx context.Context, store storage.Store, txn storage.Transact
 					return err
 				}
 			}
-		case "remove":
+		case PatchOpRemove:
 			op = storage.RemoveOp
-		case "replace":
+		case PatchOpReplace:
 			op = storage.ReplaceOp
 		default:
 			return fmt.Errorf("bad patch operation: %v", pat.Op)
@@ -1153,6 +1410,130 @@ func applyPatches(ctx context.Context, store storage.Store, txn storage.Transact
 	return nil
 }
 
+// admitModules calls the AdmitModule hook of opts with the modules of the
+// snapshot bundles, and the modules upserted by the module patches of the
+// delta bundles.
+func admitModules(opts *ActivateOpts, snapshots, deltas map[string]*Bundle, patched map[string][]*ast.Module) error {
+	if opts.AdmitModule == nil {
+		return nil
+	}
+
+	for _, name := range util.KeysSorted(snapshots) {
+		for _, mf := range snapshots[name].Modules {
+			if err := opts.AdmitModule(opts.Ctx, moduleID(opts, name, mf.Path), mf.Parsed); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, name := range util.KeysSorted(deltas) {
+		for i, pat := range deltas[name].Patch.Modules {
+			module := patched[name][i]
+			if module == nil {
+				continue
+			}
+
+			if err := opts.AdmitModule(opts.Ctx, moduleID(opts, name, pat.Path), module); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// moduleID returns the id of the module at path in the store.
+func moduleID(opts *ActivateOpts, name string, path string) string {
+	if opts.legacy {
+		return path
+	}
+	return modulePathWithPrefix(name, path)
+}
+
+// parseModulePatches parses the modules upserted by the module patches of the
+// delta bundles. The parsed modules of each bundle are indexed like its module
+// patches, with nil entries for patches that remove modules.
+func parseModulePatches(opts *ActivateOpts, bundles map[string]*Bundle) (map[string][]*ast.Module, error) {
+	patched := make(map[string][]*ast.Module, len(bundles))
+
+	for name, b := range bundles {
+		modules := make([]*ast.Module, len(b.Patch.Modules))
+
+		for i, pat := range b.Patch.Modules {
+			switch pat.Op {
+			case PatchOpUpsert:
+				popts := opts.ParserOptions
+				regoVersion, err := b.RegoVersionForFile(pat.Path, popts.EffectiveRegoVersion())
+				if err != nil {
+					return nil, err
+				}
+				popts.RegoVersion = regoVersion
+
+				module, err := ast.ParseModuleWithOpts(moduleID(opts, name, pat.Path), pat.Raw, popts)
+				if err != nil {
+					return nil, err
+				}
+
+				path, err := module.Package.Path.Ptr()
+				if err != nil {
+					return nil, err
+				}
+				if roots := b.Manifest.Roots; roots != nil && !RootPathsContain(*roots, path) {
+					return nil, fmt.Errorf("manifest roots %v do not permit module patch for '%s' in package '%s'", *roots, pat.Path, module.Package.Path)
+				}
+
+				modules[i] = module
+			case PatchOpRemove:
+			default:
+				return nil, fmt.Errorf("bad module patch operation: %v", pat.Op)
+			}
+		}
+
+		patched[name] = modules
+	}
+
+	return patched, nil
+}
+
+// applyModulePatches writes the module patches of the delta bundle b to the
+// store, and returns the ids of the removed modules. The modules upserted by
+// the patches are given by parseModulePatches.
+func applyModulePatches(opts *ActivateOpts, name string, b *Bundle, modules []*ast.Module) ([]string, error) {
+	var removed []string
+
+	for i, pat := range b.Patch.Modules {
+		id := moduleID(opts, name, pat.Path)
+
+		switch pat.Op {
+		case PatchOpUpsert:
+			if err := opts.Store.UpsertPolicy(opts.Ctx, opts.Txn, id, []byte(pat.Raw)); err != nil {
+				return nil, err
+			}
+
+			if err := eraseModuleRegoVersionsFromStore(opts.Ctx, opts.Store, opts.Txn, []string{id}); err != nil {
+				return nil, err
+			}
+
+			mf := ModuleFile{Path: pat.Path, Raw: []byte(pat.Raw), Parsed: modules[i]}
+			if err := writeModuleRegoVersionToStore(opts.Ctx, opts.Store, opts.Txn, b, mf, id, opts.ParserOptions.RegoVersion); err != nil {
+				return nil, err
+			}
+		case PatchOpRemove:
+			if err := opts.Store.DeletePolicy(opts.Ctx, opts.Txn, id); err != nil {
+				return nil, err
+			}
+
+			if err := eraseModuleRegoVersionsFromStore(opts.Ctx, opts.Store, opts.Txn, []string{id}); err != nil {
+				return nil, err
+			}
+
+			removed = append(removed, id)
+		}
+	}
+
+	return removed, nil
+}
+
 // Helpers for the older single (unnamed) bundle style manifest storage.
 
 // LegacyManifestStoragePath is the older unnamed bundle path for manifests to be stored.
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -5755,6 +5756,97 @@ func TestDeltaBundleActivate(t *testing.T) {
 	}
 }
 
+func TestDeltaBundleAdmitModule(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		note     string
+		raw      string
+		expError string
+	}{
+		{
+			note: "admitted",
+			raw:  "package a.x\np := 1",
+		},
+		{
+			note:     "rejected",
+			raw:      "package a.x\nimport input.y\np := y",
+			expError: "imports of input are not allowed",
+		},
+	} {
+		t.Run(tc.note, func(t *testing.T) {
+			mockStore := mock.New()
+
+			deltaBundles := map[string]*Bundle{
+				"bundle1": {
+					Manifest: Manifest{
+						Revision: "delta",
+						Roots:    &[]string{"a"},
+					},
+					Patch: Patch{Modules: []ModulePatchOperation{
+						{Op: PatchOpRemove, Path: "/a/y.rego"},
+						{Op: PatchOpUpsert, Path: "/a/x.rego", Raw: tc.raw},
+					}},
+				},
+			}
+
+			var admitted []string
+			admit := func(_ context.Context, id string, module *ast.Module) error {
+				admitted = append(admitted, id)
+				for _, imp := range module.Imports {
+					if imp.Path.Value.(ast.Ref).HasPrefix(ast.InputRootRef) {
+						return errors.New("imports of input are not allowed")
+					}
+				}
+				return nil
+			}
+
+			txn := storage.NewTransactionOrDie(ctx, mockStore, storage.WriteParams)
+			defer mockStore.Abort(ctx, txn)
+
+			if err := mockStore.UpsertPolicy(ctx, txn, "bundle1/a/y.rego", []byte("package a.y")); err != nil {
+				t.Fatal(err)
+			}
+
+			err := Activate(&ActivateOpts{
+				Ctx:         ctx,
+				Store:       mockStore,
+				Txn:         txn,
+				Compiler:    ast.NewCompiler(),
+				Metrics:     metrics.New(),
+				Bundles:     deltaBundles,
+				AdmitModule: admit,
+			})
+
+			if exp := []string{"bundle1/a/x.rego"}; !slices.Equal(admitted, exp) {
+				t.Fatalf("expected admitted modules %v, got %v", exp, admitted)
+			}
+
+			if tc.expError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expError) {
+					t.Fatalf("expected error %q, got %v", tc.expError, err)
+				}
+
+				ids, err := mockStore.ListPolicies(ctx, txn)
+				if err != nil {
+					t.Fatal(err)
+				} else if exp := []string{"bundle1/a/y.rego"}; !slices.Equal(ids, exp) {
+					t.Fatalf("expected rejected module patches not to be applied, got %v", ids)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if _, err := mockStore.GetPolicy(ctx, txn, "bundle1/a/x.rego"); err != nil {
+				t.Fatalf("expected admitted module patch to be written: %v", err)
+			}
+		})
+	}
+}
+
 func assertEqual(t *testing.T, expectAst bool, expected string, actual any) {
 	t.Helper()
 
@@ -6624,7 +6716,9 @@ func TestHasRootsOverlap(t *testing.T) {
 		note        string
 		storeRoots  map[string]*[]string
 		bundleRoots map[string]*[]string
+		priorities  map[string]int
 		overlaps    bool
+		evicted     []string
 	}{
 		{
 			note:        "no overlap with existing roots",
@@ -6668,6 +6762,39 @@ func TestHasRootsOverlap(t *testing.T) {
 			bundleRoots: map[string]*[]string{"bundle2": {"c", "a"}, "bundle3": {"a"}},
 			overlaps:    true,
 		},
+		{
+			note:        "wildcard overlap with existing roots",
+			storeRoots:  map[string]*[]string{"bundle1": {"tenants/acme"}},
+			bundleRoots: map[string]*[]string{"bundle2": {"tenants/*/policies"}},
+			overlaps:    true,
+		},
+		{
+			note:        "no wildcard overlap with existing roots",
+			storeRoots:  map[string]*[]string{"bundle1": {"tenants/acme/data"}},
+			bundleRoots: map[string]*[]string{"bundle2": {"tenants/*/policies"}},
+			overlaps:    false,
+		},
+		{
+			note:        "overlap with existing roots of lower priority",
+			storeRoots:  map[string]*[]string{"bundle1": {"a", "b"}, "bundle2": {"c"}},
+			bundleRoots: map[string]*[]string{"bundle3": {"*/x"}},
+			priorities:  map[string]int{"bundle2": -1, "bundle3": 1},
+			evicted:     []string{"bundle1", "bundle2"},
+		},
+		{
+			note:        "overlap with existing roots of same priority",
+			storeRoots:  map[string]*[]string{"bundle1": {"a", "b"}},
+			bundleRoots: map[string]*[]string{"bundle2": {"a"}},
+			priorities:  map[string]int{"bundle1": 1, "bundle2": 1},
+			overlaps:    true,
+		},
+		{
+			note:        "overlap with roots of bundles of lower priority being activated",
+			storeRoots:  map[string]*[]string{},
+			bundleRoots: map[string]*[]string{"bundle1": {"a"}, "bundle2": {"a"}},
+			priorities:  map[string]int{"bundle2": 1},
+			overlaps:    true,
+		},
 	}
 
 	for _, tc := range cases {
@@ -6676,7 +6803,7 @@ func TestHasRootsOverlap(t *testing.T) {
 			txn := storage.NewTransactionOrDie(ctx, mockStore, storage.WriteParams)
 
 			for name, roots := range tc.storeRoots {
-				err := WriteManifestToStore(ctx, mockStore, txn, name, Manifest{Roots: roots})
+				err := WriteManifestToStore(ctx, mockStore, txn, name, Manifest{Roots: roots, Priority: tc.priorities[name]})
 				if err != nil {
 					t.Fatalf("unexpected error: %s", err)
 				}
@@ -6686,18 +6813,23 @@ func TestHasRootsOverlap(t *testing.T) {
 			for name, roots := range tc.bundleRoots {
 				bundles[name] = &Bundle{
 					Manifest: Manifest{
-						Roots: roots,
+						Roots:    roots,
+						Priority: tc.priorities[name],
 					},
 				}
 			}
 
-			err := hasRootsOverlap(ctx, mockStore, txn, bundles)
+			evicted, err := hasRootsOverlap(ctx, mockStore, txn, bundles)
 			if !tc.overlaps && err != nil {
 				t.Fatalf("unepected error: %s", err)
 			} else if tc.overlaps && (err == nil || !strings.Contains(err.Error(), "detected overlapping roots in bundle manifest")) {
 				t.Fatalf("expected overlapping roots error, got: %s", err)
 			}
 
+			if !slices.Equal(evicted, tc.evicted) {
+				t.Fatalf("expected evicted bundles %v, got %v", tc.evicted, evicted)
+			}
+
 			err = mockStore.Commit(ctx, txn)
 			if err != nil {
 				t.Fatalf("unexpected error: %s", err)
@@ -7146,3 +7278,112 @@ func TestDeactivate_DefaultRegoVersion(t *testing.T) {
 		})
 	}
 }
+
+func TestActivateWildcardRootsAndPriority(t *testing.T) {
+	for _, lazy := range []bool{false, true} {
+		t.Run(fmt.Sprintf("lazy=%v", lazy), func(t *testing.T) {
+			ctx := context.Background()
+			store := inmem.New()
+
+			activate := func(name string, files [][2]string) error {
+				t.Helper()
+
+				loader := NewTarballLoaderWithBaseURL(archive.MustWriteTarGz(files), "")
+				b, err := NewCustomReader(loader).WithLazyLoadingMode(lazy).Read()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				txn := storage.NewTransactionOrDie(ctx, store, storage.WriteParams)
+				err = Activate(&ActivateOpts{
+					Ctx:      ctx,
+					Store:    store,
+					Txn:      txn,
+					Compiler: ast.NewCompiler(),
+					Metrics:  metrics.New(),
+					Bundles:  map[string]*Bundle{name: &b},
+				})
+				if err != nil {
+					store.Abort(ctx, txn)
+					return err
+				}
+				return store.Commit(ctx, txn)
+			}
+
+			assertState := func(expNames []string, expTenants string) {
+				t.Helper()
+
+				txn := storage.NewTransactionOrDie(ctx, store)
+				defer store.Abort(ctx, txn)
+
+				names, err := ReadBundleNamesFromStore(ctx, store, txn)
+				if err != nil {
+					t.Fatal(err)
+				}
+				slices.Sort(names)
+				if !slices.Equal(names, expNames) {
+					t.Fatalf("expected bundles %v, got %v", expNames, names)
+				}
+
+				tenants, err := store.Read(ctx, txn, storage.MustParsePath("/tenants"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if exp := util.MustUnmarshalJSON([]byte(expTenants)); !reflect.DeepEqual(tenants, exp) {
+					t.Fatalf("expected tenants %v, got %v", exp, tenants)
+				}
+			}
+
+			err := activate("acme", [][2]string{
+				{"/.manifest", `{"roots": ["tenants/acme"]}`},
+				{"/tenants/acme/data.json", `{"policies": {"x": 1}, "users": ["alice"]}`},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Takes over tenants/acme/policies from the bundle of lower priority.
+			err = activate("policies", [][2]string{
+				{"/.manifest", `{"roots": ["tenants/*/policies"], "priority": 1}`},
+				{"/tenants/acme/policies/data.json", `{"y": 2}`},
+				{"/tenants/data.json", `{"globex": {"policies": {"z": 3}}}`},
+				{"/tenants/globex/policies/authz.rego", "package tenants.globex.policies.authz\n\nallow := true\n"},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertState([]string{"policies"}, `{"acme": {"policies": {"y": 2}}, "globex": {"policies": {"z": 3}}}`)
+
+			// Erases the data at all paths matching the wildcard root.
+			err = activate("policies", [][2]string{
+				{"/.manifest", `{"roots": ["tenants/*/policies"], "priority": 1}`},
+				{"/tenants/acme/policies/data.json", `{"y": 4}`},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertState([]string{"policies"}, `{"acme": {"policies": {"y": 4}}, "globex": {}}`)
+
+			err = activate("globex", [][2]string{
+				{"/.manifest", `{"roots": ["tenants/globex"]}`},
+				{"/tenants/globex/data.json", `{"users": ["bob"]}`},
+			})
+			if err == nil || !strings.Contains(err.Error(), "detected overlapping roots in bundle manifest with: [policies]") {
+				t.Fatalf("expected overlapping roots error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestReadInvalidWildcardRoot(t *testing.T) {
+	loader := NewTarballLoaderWithBaseURL(archive.MustWriteTarGz([][2]string{
+		{"/.manifest", `{"roots": ["tenants/acme*"]}`},
+	}), "")
+
+	_, err := NewCustomReader(loader).Read()
+	if err == nil || !strings.Contains(err.Error(), "manifest root 'tenants/acme*' has invalid wildcard segment 'acme*'") {
+		t.Fatalf("expected invalid wildcard error, got %v", err)
+	}
+}
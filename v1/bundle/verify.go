@@ -12,6 +12,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash"
+	"io"
 
 	"github.com/open-policy-agent/opa/internal/jwx/jwa"
 	"github.com/open-policy-agent/opa/internal/jwx/jws"
@@ -81,32 +83,39 @@ func (*DefaultVerifier) VerifyBundleSignature(sc SignaturesConfig, bvc *Verifica
 	return files, nil
 }
 
-func verifyJWTSignature(token string, bvc *VerificationConfig) (*DecodedSignature, error) {
+// VerificationKeyID returns the ID of the key used to verify the JWT signature
+// token of a bundle, as configured by bvc or otherwise specified by the token.
+func VerificationKeyID(token string, bvc *VerificationConfig) (string, error) {
+	_, keyID, err := decodeJWTSignature(token, bvc)
+	return keyID, err
+}
+
+func decodeJWTSignature(token string, bvc *VerificationConfig) (*DecodedSignature, string, error) {
 	// decode JWT to check if the header specifies the key to use and/or if claims have the scope.
 
 	parts, err := jws.SplitCompact(token)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	var decodedHeader []byte
 	if decodedHeader, err = base64.RawURLEncoding.DecodeString(parts[0]); err != nil {
-		return nil, fmt.Errorf("failed to base64 decode JWT headers: %w", err)
+		return nil, "", fmt.Errorf("failed to base64 decode JWT headers: %w", err)
 	}
 
 	var hdr jws.StandardHeaders
 	if err := json.Unmarshal(decodedHeader, &hdr); err != nil {
-		return nil, fmt.Errorf("failed to parse JWT headers: %w", err)
+		return nil, "", fmt.Errorf("failed to parse JWT headers: %w", err)
 	}
 
 	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	var ds DecodedSignature
 	if err := json.Unmarshal(payload, &ds); err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	// check for the id of the key to use for JWT signature verification
@@ -121,7 +130,16 @@ func verifyJWTSignature(token string, bvc *VerificationConfig) (*DecodedSignatur
 	}
 
 	if keyID == "" {
-		return nil, errors.New("verification key ID is empty")
+		return nil, "", errors.New("verification key ID is empty")
+	}
+
+	return &ds, keyID, nil
+}
+
+func verifyJWTSignature(token string, bvc *VerificationConfig) (*DecodedSignature, error) {
+	ds, keyID, err := decodeJWTSignature(token, bvc)
+	if err != nil {
+		return nil, err
 	}
 
 	// now that we have the keyID, fetch the actual key
@@ -151,7 +169,7 @@ func verifyJWTSignature(token string, bvc *VerificationConfig) (*DecodedSignatur
 	if ds.Scope != scope {
 		return nil, errors.New("scope mismatch")
 	}
-	return &ds, nil
+	return ds, nil
 }
 
 // VerifyBundleFile verifies the hash of a file in the bundle matches to that provided in the bundle's signature
@@ -173,13 +191,13 @@ func VerifyBundleFile(path string, data bytes.Buffer, files map[string]FileInfo)
 	}
 
 	// hash the file content
-	// For unstructured files, hash the byte stream of the file
+	// For unstructured files, and files with raw digests, hash the byte stream of the file
 	// For structured files, read the byte stream and parse into a JSON structure;
 	// then recursively order the fields of all objects alphabetically and then apply
 	// the hash function to result to compute the hash. This ensures that the digital signature is
 	// independent of whitespace and other non-semantic JSON features.
 	var value any
-	if IsStructuredDoc(path) {
+	if IsStructuredDoc(path) && !file.Raw {
 		err := util.Unmarshal(data.Bytes(), &value)
 		if err != nil {
 			return err
@@ -207,6 +225,75 @@ func VerifyBundleFile(path string, data bytes.Buffer, files map[string]FileInfo)
 	return nil
 }
 
+// NewVerifyingReader returns a reader of the file at path that verifies the
+// digest of the file against the files in the bundle signature payload while
+// the file is read from r, so that the file does not have to be buffered and
+// hashed separately. Once the file has been read, the reader returns an error
+// instead of io.EOF if the digests do not match. The file must have a raw
+// digest, as the digest of other structured files is computed over their
+// parsed content.
+func NewVerifyingReader(path string, r io.Reader, files map[string]FileInfo) (io.Reader, error) {
+	file, ok := files[path]
+	if !ok {
+		return nil, fmt.Errorf("file %v not included in bundle signature", path)
+	}
+
+	if !file.Raw {
+		return nil, fmt.Errorf("file %v does not have a raw digest", path)
+	}
+
+	if file.Algorithm == "" {
+		return nil, fmt.Errorf("no hashing algorithm provided for file %v", path)
+	}
+
+	h, err := hashFunc(HashingAlgorithm(file.Algorithm))
+	if err != nil {
+		return nil, err
+	}
+
+	want, err := hex.DecodeString(file.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(files, path)
+
+	return &verifyingReader{
+		path: path,
+		r:    r,
+		h:    h(),
+		want: want,
+	}, nil
+}
+
+type verifyingReader struct {
+	path string
+	r    io.Reader
+	h    hash.Hash
+	want []byte
+}
+
+func (v *verifyingReader) Read(p []byte) (int, error) {
+	n, err := v.r.Read(p)
+	_, _ = v.h.Write(p[:n])
+
+	if err == io.EOF {
+		if err := v.verify(); err != nil {
+			return n, err
+		}
+	}
+
+	return n, err
+}
+
+// verify compares the digest of the bytes read so far with the expected one.
+func (v *verifyingReader) verify() error {
+	if got := v.h.Sum(nil); !bytes.Equal(v.want, got) {
+		return fmt.Errorf("%v: digest mismatch (want: %x, got: %x)", v.path, v.want, got)
+	}
+	return nil
+}
+
 // GetVerifier returns the Verifier registered under the given id
 func GetVerifier(id string) (Verifier, error) {
 	verifier, ok := verifiers[id]
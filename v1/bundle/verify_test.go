@@ -7,6 +7,8 @@ package bundle
 import (
 	"bytes"
 	"errors"
+	"io"
+	"strings"
 	"testing"
 )
 
@@ -225,6 +227,28 @@ yQjtQ8mbDOsiLLvh7wIDAQAB==
 	if err != nil {
 		t.Fatalf("Unexpected error %v", err)
 	}
+
+	keyIDs := map[string]struct {
+		token string
+		keyID string
+		exp   string
+	}{
+		"configured":     {signedTokenWithBarKidHS256, "foo", "foo"},
+		"header":         {signedTokenWithBarKidHS256, "", "bar"},
+		"deprecated_key": {signedTokenWithDeprecatedKidClaimHS256, "", "foo"},
+	}
+
+	for name, tc := range keyIDs {
+		t.Run("key_id_"+name, func(t *testing.T) {
+			keyID, err := VerificationKeyID(tc.token, NewVerificationConfig(nil, tc.keyID, "", nil))
+			if err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			if keyID != tc.exp {
+				t.Fatalf("Expected key ID %v but got %v", tc.exp, keyID)
+			}
+		})
+	}
 }
 
 func TestVerifyBundleFile(t *testing.T) {
@@ -258,6 +282,16 @@ func TestVerifyBundleFile(t *testing.T) {
 			}},
 			true, errors.New("/.manifest: digest mismatch (want: 874984d68515ba2439c04dddf5b21574, got: a005c38a509dc2d5a7407b9494efb2ad)"),
 		},
+		"raw_digest": {
+			[][2]string{{"/.manifest", `{"revision": "quickbrownfaux"}`}},
+			map[string]FileInfo{"/.manifest": {
+				Name:      "/.manifest",
+				Hash:      "cd7d0a9a021392e824ebc3559ec5f36d",
+				Algorithm: MD5.String(),
+				Raw:       true,
+			}},
+			false, nil,
+		},
 	}
 
 	for name, tc := range tests {
@@ -280,6 +314,51 @@ func TestVerifyBundleFile(t *testing.T) {
 	}
 }
 
+func TestVerifyingReader(t *testing.T) {
+	const content = `{"revision": "quickbrownfaux"}`
+
+	tests := map[string]struct {
+		file FileInfo
+		err  string
+	}{
+		"match": {
+			file: FileInfo{Name: "/.manifest", Hash: "cd7d0a9a021392e824ebc3559ec5f36d", Algorithm: MD5.String(), Raw: true},
+		},
+		"digest_mismatch": {
+			file: FileInfo{Name: "/.manifest", Hash: "874984d68515ba2439c04dddf5b21574", Algorithm: MD5.String(), Raw: true},
+			err:  "/.manifest: digest mismatch (want: 874984d68515ba2439c04dddf5b21574, got: cd7d0a9a021392e824ebc3559ec5f36d)",
+		},
+		"not_raw": {
+			file: FileInfo{Name: "/.manifest", Hash: "cd7d0a9a021392e824ebc3559ec5f36d", Algorithm: MD5.String()},
+			err:  "file /.manifest does not have a raw digest",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			files := map[string]FileInfo{"/.manifest": tc.file}
+
+			r, err := NewVerifyingReader("/.manifest", strings.NewReader(content), files)
+			if err == nil {
+				if _, ok := files["/.manifest"]; ok {
+					t.Fatal("Expected file to be removed from files to verify")
+				}
+				var bs []byte
+				bs, err = io.ReadAll(r)
+				if err == nil && string(bs) != content {
+					t.Fatalf("Expected content %v but got %v", content, string(bs))
+				}
+			}
+
+			if tc.err == "" && err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			} else if tc.err != "" && (err == nil || err.Error() != tc.err) {
+				t.Fatalf("Expected error message %v but got %v", tc.err, err)
+			}
+		})
+	}
+}
+
 type CustomVerifier struct{}
 
 func (*CustomVerifier) VerifyBundleSignature(_ SignaturesConfig, _ *VerificationConfig) (map[string]FileInfo, error) {
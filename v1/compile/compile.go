@@ -32,6 +32,7 @@ import (
 	"github.com/open-policy-agent/opa/v1/rego"
 	"github.com/open-policy-agent/opa/v1/storage"
 	"github.com/open-policy-agent/opa/v1/storage/inmem"
+	"github.com/open-policy-agent/opa/v1/util"
 )
 
 const (
@@ -65,6 +66,7 @@ type Compiler struct {
 	revision                     *string                    // the revision to set on the output bundle
 	asBundle                     bool                       // whether to assume bundle layout on file loading or not
 	pruneUnused                  bool                       // whether to extend the entrypoint set for semantic equivalence of built bundles
+	pruneUnusedData              bool                       // whether to drop data that no rule or entrypoint refers to from the output bundle
 	filter                       loader.Filter              // filter to apply to file loader
 	paths                        []string                   // file paths to load. TODO(tsandall): add support for supplying readers for embedded users.
 	entrypoints                  orderedStringSet           // policy entrypoints required for optimization and certain targets
@@ -126,6 +128,16 @@ func (c *Compiler) WithPruneUnused(enabled bool) *Compiler {
 	return c
 }
 
+// WithPruneUnusedData will make data be dropped from the output bundle if
+// no rule and no entrypoint refers to it. A reference with a dynamic part, e.g.,
+// data.users[x].name, keeps all of the data below its constant prefix, e.g.,
+// data.users. Data that is only queried directly, without an entrypoint
+// referring to it, is dropped.
+func (c *Compiler) WithPruneUnusedData(enabled bool) *Compiler {
+	c.pruneUnusedData = enabled
+	return c
+}
+
 // WithEntrypoints sets the policy entrypoints on the compiler. Entrypoints tell the
 // compiler what rules to expect and where optimizations can be targeted. The wasm
 // target requires at least one entrypoint as does optimization.
@@ -356,6 +368,12 @@ func (c *Compiler) Build(ctx context.Context) error {
 		return err
 	}
 
+	if c.pruneUnusedData {
+		if err := c.pruneData(); err != nil {
+			return err
+		}
+	}
+
 	switch c.target {
 	case TargetWasm:
 		if err := c.compileWasm(ctx); err != nil {
@@ -587,6 +605,71 @@ func (c *Compiler) optimize(ctx context.Context) error {
 	return nil
 }
 
+// pruneData drops the data from the bundle that is not referred to by any
+// rule or entrypoint.
+func (c *Compiler) pruneData() error {
+
+	// Lazily compile the modules if needed, so that all references in the
+	// compiled modules are fully qualified.
+	if c.compiler == nil {
+		var err error
+		c.compiler, err = compile(c.capabilities, c.bundle, c.debug, c.enablePrintStatements)
+		if err != nil {
+			return err
+		}
+	}
+
+	used := newRefSet()
+
+	for _, e := range c.entrypointrefs {
+		used.AddPrefix(e.Value.(ast.Ref))
+	}
+
+	for _, module := range c.compiler.Modules {
+		ast.WalkRefs(module, func(r ast.Ref) bool {
+			if r.HasPrefix(ast.DefaultRootRef) {
+				used.AddPrefix(r.StringPrefix())
+			}
+			return false
+		})
+	}
+
+	if used.ContainsPrefix(ast.DefaultRootRef) {
+		return nil
+	}
+
+	pruned := pruneUnusedData(c.bundle.Data, ast.DefaultRootRef, used)
+	for _, path := range pruned {
+		c.debug.Printf("pruned unused data: %v", path)
+	}
+
+	return nil
+}
+
+// pruneUnusedData deletes the documents from data that are not prefixed by,
+// and do not prefix, any of the used refs, and returns their refs.
+func pruneUnusedData(data map[string]any, path ast.Ref, used *refSet) []ast.Ref {
+	var pruned []ast.Ref
+
+	for _, key := range util.KeysSorted(data) {
+		p := path.Append(ast.StringTerm(key))
+
+		switch {
+		case used.ContainsPrefix(p):
+			// The whole document is used.
+		case used.ContainsExtension(p):
+			if obj, ok := data[key].(map[string]any); ok {
+				pruned = append(pruned, pruneUnusedData(obj, p, used)...)
+			}
+		default:
+			delete(data, key)
+			pruned = append(pruned, p)
+		}
+	}
+
+	return pruned
+}
+
 func (c *Compiler) compilePlan(context.Context) error {
 
 	// Lazily compile the modules if needed. If optimizations were run, the
@@ -674,6 +757,7 @@ func (c *Compiler) compilePlan(context.Context) error {
 		WithQueries(queries).
 		WithModules(modules).
 		WithBuiltinDecls(builtins).
+		WithCommonSubexpressionElimination(c.optimizationLevel > 0).
 		WithDebug(c.debug.Writer())
 	policy, err := p.Plan()
 	if err != nil {
@@ -1251,21 +1335,20 @@ var safePathPattern = regexp.MustCompile(`^[\w-_/]+$`)
 
 func compile(c *ast.Capabilities, b *bundle.Bundle, dbg debug.Debug, enablePrintStatements bool) (*ast.Compiler, error) {
 
-	modules := map[string]*ast.Module{}
+	urls := map[string]struct{}{}
 
 	for _, mf := range b.Modules {
-		if _, ok := modules[mf.URL]; ok {
+		if _, ok := urls[mf.URL]; ok {
 			return nil, fmt.Errorf("duplicate module URL: %s", mf.URL)
 		}
 
-		modules[mf.URL] = mf.Parsed
+		urls[mf.URL] = struct{}{}
 	}
 
 	compiler := ast.NewCompiler().WithCapabilities(c).WithDebug(dbg.Writer()).WithEnablePrintStatements(enablePrintStatements)
-	compiler.Compile(modules)
 
-	if compiler.Failed() {
-		return nil, compiler.Errors
+	if errs := bundle.Compile(compiler, b); len(errs) > 0 {
+		return nil, errs
 	}
 
 	minVersion, ok := compiler.Required.MinimumCompatibleVersion()
@@ -1338,6 +1421,14 @@ func (rs *refSet) ContainsPrefix(r ast.Ref) bool {
 	return slices.ContainsFunc(rs.s, r.HasPrefix)
 }
 
+// ContainsExtension returns true if any of the existing refs in the set is
+// prefixed by r.
+func (rs *refSet) ContainsExtension(r ast.Ref) bool {
+	return slices.ContainsFunc(rs.s, func(x ast.Ref) bool {
+		return x.HasPrefix(r)
+	})
+}
+
 // AddPrefix inserts r into the set if r is not prefixed by any existing
 // refs in the set. If any existing refs are prefixed by r, those existing
 // refs are removed.
@@ -11,6 +11,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"reflect"
 	"slices"
 	"strconv"
 	"strings"
@@ -136,6 +137,30 @@ func TestCompilerLoadError(t *testing.T) {
 	}
 }
 
+func TestCompilerDataSchemaError(t *testing.T) {
+
+	files := map[string]string{
+		".manifest":   `{"roots": ["a"], "schemas": {"a/users": {"type": "array", "items": {"type": "string"}}}}`,
+		"a/data.json": `{"users": ["alice", 7]}`,
+		"a/test.rego": `package a
+			p := count(data.a.users)`,
+	}
+
+	for _, useMemoryFS := range []bool{false, true} {
+		test.WithTestFS(files, useMemoryFS, func(root string, fsys fs.FS) {
+
+			err := New().
+				WithFS(fsys).
+				WithPaths(root).
+				WithAsBundle(true).
+				Build(context.Background())
+			if err == nil || !strings.Contains(err.Error(), "data at path 'a/users' does not match schema") {
+				t.Fatalf("expected schema error, got %v", err)
+			}
+		})
+	}
+}
+
 func TestCompilerLoadAsBundleSuccess(t *testing.T) {
 
 	ctx := context.Background()
@@ -1984,6 +2009,77 @@ func wasmABIVersions(vs ...ast.WasmABIVersion) *ast.Capabilities {
 	return caps
 }
 
+func TestCompilerPruneUnusedData(t *testing.T) {
+	tests := []struct {
+		note        string
+		policy      string
+		entrypoints []string
+		optimize    int
+		exp         string
+	}{
+		{
+			note: "static and dynamic refs",
+			policy: `package test
+
+allow if input.user in data.users.admins
+
+allow if data.roles[input.role].admin`,
+			exp: `{"users": {"admins": ["alice"]}, "roles": {"dev": {"admin": false}}}`,
+		},
+		{
+			note: "entrypoint",
+			policy: `package test
+
+allow if input.user in data.users.admins`,
+			entrypoints: []string{"upstream/large"},
+			exp:         `{"users": {"admins": ["alice"]}, "upstream": {"large": [1, 2, 3]}}`,
+		},
+		{
+			note: "package ref",
+			policy: `package test
+
+x := data.upstream`,
+			exp: `{"upstream": {"large": [1, 2, 3], "unused": true}}`,
+		},
+		{
+			note: "optimized",
+			policy: `package test
+
+allow if input.user in data.users.admins`,
+			entrypoints: []string{"test/allow"},
+			optimize:    1,
+			exp:         `{}`, // the optimized policy inlines the data
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			files := map[string]string{
+				"test.rego": tc.policy,
+				"data.json": `{"users": {"admins": ["alice"], "guests": ["bob"]}, "roles": {"dev": {"admin": false}}, "upstream": {"large": [1, 2, 3], "unused": true}}`,
+			}
+
+			test.WithTestFS(files, true, func(root string, fsys fs.FS) {
+				compiler := New().
+					WithFS(fsys).
+					WithPaths(root).
+					WithEntrypoints(tc.entrypoints...).
+					WithOptimizationLevel(tc.optimize).
+					WithPruneUnusedData(true)
+
+				if err := compiler.Build(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+
+				exp := util.MustUnmarshalJSON([]byte(tc.exp))
+				if !reflect.DeepEqual(compiler.Bundle().Data, exp) {
+					t.Fatalf("expected data %v, got %v", exp, compiler.Bundle().Data)
+				}
+			})
+		})
+	}
+}
+
 func TestCompilerWasmTarget(t *testing.T) {
 	files := map[string]string{
 		"test.rego": `package test
@@ -34,14 +34,19 @@ type Config struct {
 	Keys                         json.RawMessage            `json:"keys,omitempty"`
 	DefaultDecision              *string                    `json:"default_decision,omitempty"`
 	DefaultAuthorizationDecision *string                    `json:"default_authorization_decision,omitempty"`
+	PolicyAdmissionDecision      *string                    `json:"policy_admission_decision,omitempty"`
 	Caching                      json.RawMessage            `json:"caching,omitempty"`
 	NDBuiltinCache               bool                       `json:"nd_builtin_cache,omitempty"`
 	PersistenceDirectory         *string                    `json:"persistence_directory,omitempty"`
+	PersistenceEncryption        json.RawMessage            `json:"persistence_encryption,omitempty"`
 	DistributedTracing           json.RawMessage            `json:"distributed_tracing,omitempty"`
+	Watchdog                     json.RawMessage            `json:"watchdog,omitempty"`
 	Server                       *struct {
-		Encoding json.RawMessage `json:"encoding,omitempty"`
-		Decoding json.RawMessage `json:"decoding,omitempty"`
-		Metrics  json.RawMessage `json:"metrics,omitempty"`
+		Encoding   json.RawMessage `json:"encoding,omitempty"`
+		Decoding   json.RawMessage `json:"decoding,omitempty"`
+		Metrics    json.RawMessage `json:"metrics,omitempty"`
+		RateLimits json.RawMessage `json:"rate_limits,omitempty"`
+		CORS       json.RawMessage `json:"cors,omitempty"`
 	} `json:"server,omitempty"`
 	Storage *struct {
 		Disk json.RawMessage `json:"disk,omitempty"`
@@ -117,6 +122,16 @@ func (c Config) DefaultAuthorizationDecisionRef() ast.Ref {
 	return r
 }
 
+// PolicyAdmissionDecisionRef returns the policy admission decision as a
+// reference, or nil if policy admission is not configured.
+func (c Config) PolicyAdmissionDecisionRef() ast.Ref {
+	if c.PolicyAdmissionDecision == nil {
+		return nil
+	}
+	r, _ := ref.ParseDataPath(*c.PolicyAdmissionDecision)
+	return r
+}
+
 // NDBuiltinCacheEnabled returns if the ND builtins cache should be used.
 func (c Config) NDBuiltinCacheEnabled() bool {
 	return c.NDBuiltinCache
@@ -144,6 +159,12 @@ func (c *Config) validateAndInjectDefaults(id string) error {
 		return err
 	}
 
+	if c.PolicyAdmissionDecision != nil {
+		if _, err := ref.ParseDataPath(*c.PolicyAdmissionDecision); err != nil {
+			return err
+		}
+	}
+
 	if c.Labels == nil {
 		c.Labels = map[string]string{}
 	}
@@ -5,11 +5,13 @@
 package dependencies
 
 import (
+	"reflect"
 	"sort"
 	"strconv"
 	"testing"
 
 	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/types"
 )
 
 type testData struct {
@@ -490,6 +492,80 @@ func TestBase(t *testing.T) {
 
 }
 
+func TestRequired(t *testing.T) {
+	modules := map[string]*ast.Module{
+		"test": ast.MustParseModule(`
+			package test
+			import rego.v1
+
+			p if startswith(data.users[input.name].title, "admin")
+
+			q := count(data.roles)
+		`),
+	}
+
+	compiler := ast.NewCompiler()
+	compiler.Compile(modules)
+	if compiler.Failed() {
+		t.Fatal(compiler.Errors)
+	}
+
+	req, err := Required(compiler, ast.MustParseBody(`data.test.p; x := time.now_ns()`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := &Requirements{
+		Builtins: []BuiltinRequirement{
+			{Name: "assign", MinimumVersion: "0.17.0"},
+			{Name: "count", MinimumVersion: "0.17.0"},
+			{Name: "eq", MinimumVersion: "0.17.0"},
+			{Name: "startswith", MinimumVersion: "0.17.0"},
+			{Name: "time.now_ns", MinimumVersion: "0.17.0"},
+		},
+		DataRoots:      []string{"users"},
+		FutureKeywords: []string{},
+		Features:       []string{"rego_v1"},
+		MinimumVersion: "1.0.0",
+	}
+
+	if !reflect.DeepEqual(req, exp) {
+		t.Fatalf("Expected %+v but got %+v", exp, req)
+	}
+}
+
+func TestRequiredCustomBuiltinsAndDynamicRoots(t *testing.T) {
+	caps := ast.CapabilitiesForThisVersion()
+	caps.Builtins = append(caps.Builtins, &ast.Builtin{
+		Name: "custom.fetch",
+		Decl: types.NewFunction(types.Args(types.S), types.A),
+	})
+
+	compiler := ast.NewCompiler().WithCapabilities(caps)
+	compiler.Compile(nil)
+	if compiler.Failed() {
+		t.Fatal(compiler.Errors)
+	}
+
+	req, err := Required(compiler, ast.MustParseBody(`custom.fetch(data[1].y)`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []BuiltinRequirement{{Name: "custom.fetch"}}
+	if !reflect.DeepEqual(req.Builtins, exp) {
+		t.Fatalf("Expected built-ins %+v but got %+v", exp, req.Builtins)
+	}
+
+	if !reflect.DeepEqual(req.DataRoots, []string{""}) {
+		t.Fatalf("Expected data roots [\"\"] but got %q", req.DataRoots)
+	}
+
+	if req.MinimumVersion != "" {
+		t.Fatalf("Expected no minimum version but got %v", req.MinimumVersion)
+	}
+}
+
 func runDeps(t *testing.T, x any) (min, full []ast.Ref) {
 	min, err := Minimal(x)
 	if err != nil {
@@ -0,0 +1,109 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package dependencies
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+)
+
+// Requirements describes what the OPA instance evaluating an AST element must
+// provide: the built-in functions it calls, the data it reads, and the language
+// features it uses.
+type Requirements struct {
+	Builtins       []BuiltinRequirement `json:"builtins,omitempty"`
+	DataRoots      []string             `json:"data_roots,omitempty"`
+	FutureKeywords []string             `json:"future_keywords,omitempty"`
+	Features       []string             `json:"features,omitempty"`
+
+	// MinimumVersion is the oldest OPA version that provides all the
+	// requirements. It is empty if the version cannot be determined, e.g.,
+	// because a custom built-in function is required.
+	MinimumVersion string `json:"minimum_opa_version,omitempty"`
+}
+
+// BuiltinRequirement is a built-in function required by an AST element, and
+// the oldest OPA version that provides it.
+type BuiltinRequirement struct {
+	Name           string `json:"name"`
+	MinimumVersion string `json:"minimum_opa_version,omitempty"`
+}
+
+// Required returns the requirements of the given AST element, e.g., a query,
+// evaluated against the modules compiled by compiler.
+//
+// Built-in functions, keywords and features are those required by the compiled
+// modules, see ast.Compiler.Required, as well as the built-in functions of the
+// compiler's capabilities called by x. Data roots are the first path segments
+// of the base data documents x depends on, in the format of bundle roots; a
+// root of "" means that x depends on all of data, e.g., because the first
+// segment is not a string.
+func Required(compiler *ast.Compiler, x any) (*Requirements, error) {
+	base, err := Base(compiler, x)
+	if err != nil {
+		return nil, err
+	}
+
+	caps := &ast.Capabilities{}
+	if compiler.Required != nil {
+		caps.Builtins = slices.Clone(compiler.Required.Builtins)
+		caps.FutureKeywords = compiler.Required.FutureKeywords
+		caps.Features = compiler.Required.Features
+	}
+
+	builtins := ast.BuiltinMap
+	if c := compiler.Capabilities(); c != nil {
+		builtins = make(map[string]*ast.Builtin, len(c.Builtins))
+		for _, bi := range c.Builtins {
+			builtins[bi.Name] = bi
+		}
+	}
+
+	ast.WalkRefs(x, func(r ast.Ref) bool {
+		bi := builtins[r.String()]
+		if bi != nil && !slices.ContainsFunc(caps.Builtins, func(other *ast.Builtin) bool { return other.Name == bi.Name }) {
+			caps.Builtins = append(caps.Builtins, bi)
+		}
+		return false
+	})
+
+	slices.SortFunc(caps.Builtins, func(a, b *ast.Builtin) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	result := &Requirements{
+		FutureKeywords: caps.FutureKeywords,
+		Features:       caps.Features,
+	}
+
+	for _, bi := range caps.Builtins {
+		version, _ := (&ast.Capabilities{Builtins: []*ast.Builtin{bi}}).MinimumCompatibleVersion()
+		result.Builtins = append(result.Builtins, BuiltinRequirement{Name: bi.Name, MinimumVersion: version})
+	}
+
+	for _, r := range base {
+		if !r.HasPrefix(ast.DefaultRootRef) {
+			continue
+		}
+		var root string
+		if len(r) > 1 {
+			if s, ok := r[1].Value.(ast.String); ok {
+				root = string(s)
+			}
+		}
+		if !slices.Contains(result.DataRoots, root) {
+			result.DataRoots = append(result.DataRoots, root)
+		}
+	}
+	slices.Sort(result.DataRoots)
+
+	if version, ok := caps.MinimumCompatibleVersion(); ok {
+		result.MinimumVersion = version
+	}
+
+	return result, nil
+}
@@ -28,12 +28,18 @@ type PollingConfig struct {
 	MinDelaySeconds           *int64 `json:"min_delay_seconds,omitempty"`            // min amount of time to wait between successful poll attempts
 	MaxDelaySeconds           *int64 `json:"max_delay_seconds,omitempty"`            // max amount of time to wait between poll attempts
 	LongPollingTimeoutSeconds *int64 `json:"long_polling_timeout_seconds,omitempty"` // max amount of time the server should wait before issuing a timeout if there's no update available
+	SplaySeconds              *int64 `json:"splay_seconds,omitempty"`                // max amount of time to wait before the first poll attempt
+	DeterministicJitter       bool   `json:"deterministic_jitter,omitempty"`         // derive the random delays from the instance ID instead of a global random source
 }
 
 // Config represents the configuration for the downloader.
 type Config struct {
-	Trigger *plugins.TriggerMode `json:"trigger,omitempty"`
-	Polling PollingConfig        `json:"polling"`
+	Trigger              *plugins.TriggerMode `json:"trigger,omitempty"`
+	Polling              PollingConfig        `json:"polling"`
+	MaxDownloadRateBytes *int64               `json:"max_download_rate_bytes,omitempty"` // max bytes per second read while downloading a bundle
+	DownloadWindows      []string             `json:"download_windows,omitempty"`        // cron-style expressions of the times periodic downloads are allowed at
+
+	windows []*downloadWindow
 }
 
 // ValidateAndInjectDefaults checks for configuration errors and ensures all
@@ -81,5 +87,26 @@ func (c *Config) ValidateAndInjectDefaults() error {
 		}
 	}
 
+	if c.Polling.SplaySeconds != nil {
+		if *c.Polling.SplaySeconds < 0 {
+			return errors.New("'splay_seconds' must be at least 0")
+		}
+		splaySeconds := int64(time.Duration(*c.Polling.SplaySeconds) * time.Second)
+		c.Polling.SplaySeconds = &splaySeconds
+	}
+
+	if c.MaxDownloadRateBytes != nil && *c.MaxDownloadRateBytes < 1 {
+		return errors.New("'max_download_rate_bytes' must be at least 1")
+	}
+
+	c.windows = nil
+	for _, s := range c.DownloadWindows {
+		w, err := parseDownloadWindow(s)
+		if err != nil {
+			return err
+		}
+		c.windows = append(c.windows, w)
+	}
+
 	return nil
 }
@@ -73,6 +73,33 @@ func TestConfigValidation(t *testing.T) {
 			}`,
 			wantErr: true,
 		},
+		{
+			note:    "download rate < 1",
+			input:   `{"max_download_rate_bytes": 0}`,
+			wantErr: true,
+		},
+		{
+			note:    "invalid download window",
+			input:   `{"download_windows": ["* 0-25 * * *"]}`,
+			wantErr: true,
+		},
+		{
+			note:   "download rate and windows",
+			input:  `{"max_download_rate_bytes": 1024, "download_windows": ["* 0-6 * * 1-5", "* * * * 0,6"]}`,
+			expMin: time.Second * time.Duration(defaultMinDelaySeconds),
+			expMax: time.Second * time.Duration(defaultMaxDelaySeconds),
+		},
+		{
+			note:    "splay < 0",
+			input:   `{"polling": {"splay_seconds": -1}}`,
+			wantErr: true,
+		},
+		{
+			note:   "splay and deterministic jitter",
+			input:  `{"polling": {"splay_seconds": 300, "deterministic_jitter": true}}`,
+			expMin: time.Second * time.Duration(defaultMinDelaySeconds),
+			expMax: time.Second * time.Duration(defaultMaxDelaySeconds),
+		},
 	}
 
 	for _, test := range tests {
@@ -86,6 +113,8 @@ func TestConfigValidation(t *testing.T) {
 		err := config.ValidateAndInjectDefaults()
 		if err != nil && !test.wantErr {
 			t.Errorf("Unexpected error on: %v, err: %v", test.input, err)
+		} else if err == nil && test.wantErr {
+			t.Errorf("Expected error on: %v", test.input)
 		}
 
 		if err == nil {
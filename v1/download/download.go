@@ -8,6 +8,7 @@ package download
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"math/rand"
@@ -51,8 +52,9 @@ type Update struct {
 type Downloader struct {
 	config             Config                        // downloader configuration for tuning polling and other downloader behaviour
 	client             rest.Client                   // HTTP client to use for bundle downloading
+	transport          Transport                     // transport sending bundle requests to the service
 	path               string                        // path to use in bundle download request
-	trigger            chan chan struct{}            // channel to signal downloads when manual triggering is enabled
+	trigger            chan chan error               // channel to signal out-of-band downloads when periodic polling is running
 	stop               chan chan struct{}            // used to signal plugin to stop running
 	f                  func(context.Context, Update) // callback function invoked when download updates occur
 	etag               string                        // HTTP Etag for caching purposes
@@ -63,11 +65,13 @@ type Downloader struct {
 	logger             logging.Logger
 	mtx                sync.Mutex
 	stopped            bool
+	running            chan struct{} // closed once the polling loop has exited; nil until started
 	persist            bool
 	longPollingEnabled bool
 	lazyLoadingMode    bool
 	bundleName         string
 	bundleParserOpts   ast.ParserOptions
+	rnd                *rand.Rand // source of the polling delays; nil for the global source
 }
 
 type downloaderResponse struct {
@@ -83,8 +87,9 @@ func New(config Config, client rest.Client, path string) *Downloader {
 	return &Downloader{
 		config:             config,
 		client:             client,
+		transport:          transportFor(client),
 		path:               path,
-		trigger:            make(chan chan struct{}),
+		trigger:            make(chan chan error),
 		stop:               make(chan chan struct{}),
 		logger:             client.Logger(),
 		longPollingEnabled: config.Polling.LongPollingTimeoutSeconds != nil,
@@ -97,6 +102,13 @@ func (d *Downloader) WithCallback(f func(context.Context, Update)) *Downloader {
 	return d
 }
 
+// WithTransport sets the transport sending bundle requests to the service,
+// overriding the transport registered for the type of the service.
+func (d *Downloader) WithTransport(t Transport) *Downloader {
+	d.transport = t
+	return d
+}
+
 // WithLogAttrs sets an optional set of key/value pair attributes to include in
 // log messages emitted by the downloader.
 func (d *Downloader) WithLogAttrs(attrs map[string]any) *Downloader {
@@ -154,8 +166,18 @@ func (d *Downloader) SetCache(etag string) {
 }
 
 // Trigger can be used to control when the downloader attempts to download
-// a new bundle in manual triggering mode.
+// a new bundle in manual triggering mode. In periodic mode, Trigger asks the
+// running polling loop to download immediately instead of waiting for the
+// next polling interval, and returns the result of that download.
 func (d *Downloader) Trigger(ctx context.Context) error {
+	d.mtx.Lock()
+	running := d.running
+	d.mtx.Unlock()
+
+	if running != nil {
+		return triggerLoop(ctx, d.trigger, running)
+	}
+
 	done := make(chan error)
 
 	go func() {
@@ -177,19 +199,47 @@ func (d *Downloader) Trigger(ctx context.Context) error {
 	}
 }
 
+// triggerLoop asks a running polling loop to download immediately, and waits
+// for the result of that download.
+func triggerLoop(ctx context.Context, trigger chan chan error, running chan struct{}) error {
+	done := make(chan error, 1)
+
+	select {
+	case trigger <- done:
+	case <-running:
+		return errors.New("downloader stopped")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // Start tells the Downloader to begin downloading bundles.
 func (d *Downloader) Start(ctx context.Context) {
 	if *d.config.Trigger == plugins.TriggerPeriodic {
-		go d.doStart(ctx)
+		running := make(chan struct{})
+		d.mtx.Lock()
+		d.running = running
+		d.mtx.Unlock()
+		go d.doStart(ctx, running)
 	}
 }
 
-func (d *Downloader) doStart(context.Context) {
+func (d *Downloader) doStart(_ context.Context, running chan struct{}) {
 	// We'll revisit context passing/usage later.
 	ctx, cancel := context.WithCancel(context.Background())
 
 	d.wg.Add(1)
-	go d.loop(ctx)
+	go func() {
+		defer close(running)
+		d.loop(ctx)
+	}()
 
 	done := <-d.stop // blocks until there's something to read
 	cancel()
@@ -198,6 +248,14 @@ func (d *Downloader) doStart(context.Context) {
 	close(done)
 }
 
+// WithJitterSeed seeds the random polling delays of the downloader with seed,
+// usually the instance ID, if deterministic jitter is enabled in the polling
+// configuration.
+func (d *Downloader) WithJitterSeed(seed string) *Downloader {
+	d.rnd = d.config.jitterSource(seed)
+	return d
+}
+
 // Stop tells the Downloader to stop downloading bundles.
 func (d *Downloader) Stop(context.Context) {
 	if *d.config.Trigger == plugins.TriggerManual {
@@ -221,26 +279,41 @@ func (d *Downloader) loop(ctx context.Context) {
 
 	var retry int
 
+	triggered, ok := waitForSplay(ctx, &d.config, d.rnd, d.trigger, d.logger)
+	if !ok {
+		return
+	}
+
 	for {
 
 		var delay time.Duration
 
+		if triggered == nil {
+			var ok bool
+			if triggered, ok = waitForDownloadWindow(ctx, &d.config, d.trigger, d.logger); !ok {
+				return
+			}
+		}
+
 		err := d.oneShot(ctx)
 
+		if triggered != nil {
+			triggered <- err
+			triggered = nil
+		}
+
 		if ctx.Err() != nil {
 			return
 		}
 
 		if err != nil {
-			delay = util.DefaultBackoff(float64(minRetryDelay), float64(*d.config.Polling.MaxDelaySeconds), retry)
+			delay = rest.Backoff(minRetryDelay, time.Duration(*d.config.Polling.MaxDelaySeconds), retry, err)
 		} else if !d.longPollingEnabled || d.config.Polling.LongPollingTimeoutSeconds == nil {
 			// revert the response header timeout value on the http client's transport
 			if *d.client.Config().ResponseHeaderTimeoutSeconds == 0 {
 				d.client = d.client.SetResponseHeaderTimeout(&d.respHdrTimeoutSec)
 			}
-			min := float64(*d.config.Polling.MinDelaySeconds)
-			max := float64(*d.config.Polling.MaxDelaySeconds)
-			delay = time.Duration(((max - min) * rand.Float64()) + min)
+			delay = d.config.pollingDelay(d.rnd)
 		}
 
 		d.logger.Debug("Waiting %v before next download/retry.", delay)
@@ -253,6 +326,8 @@ func (d *Downloader) loop(ctx context.Context) {
 			} else {
 				retry = 0
 			}
+		case triggered = <-d.trigger:
+			timerCancel()
 		case <-ctx.Done():
 			timerCancel() // explicitly cancel the timer.
 			return
@@ -307,7 +382,7 @@ func (d *Downloader) download(ctx context.Context, m metrics.Metrics) (*download
 	d.client = d.client.WithHeader("Prefer", preferValue)
 
 	m.Timer(metrics.BundleRequest).Start()
-	resp, err := d.client.Do(ctx, "GET", d.path)
+	resp, err := d.transport.Do(ctx, d.client, d.path)
 	m.Timer(metrics.BundleRequest).Stop()
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
@@ -324,8 +399,13 @@ func (d *Downloader) download(ctx context.Context, m metrics.Metrics) (*download
 			defer m.Timer(metrics.RegoLoadBundles).Stop()
 			baseURL := path.Join(d.client.Config().URL, d.path)
 
+			var body io.Reader = resp.Body
+			if d.config.MaxDownloadRateBytes != nil {
+				body = newThrottledReader(ctx, body, *d.config.MaxDownloadRateBytes)
+			}
+
 			cnt := &count{}
-			r := io.TeeReader(resp.Body, cnt)
+			r := io.TeeReader(body, cnt)
 
 			var loader bundle.DirectoryLoader
 			if d.persist {
@@ -345,6 +425,7 @@ func (d *Downloader) download(ctx context.Context, m metrics.Metrics) (*download
 
 			reader := bundle.NewCustomReader(loader).
 				WithRegoVersion(d.bundleParserOpts.RegoVersion).
+				WithInternPool(d.bundleParserOpts.InternPool).
 				WithMetrics(m).
 				WithBundleVerificationConfig(d.bvc).
 				WithBundleEtag(etag).
@@ -413,7 +494,8 @@ func (d *Downloader) download(ctx context.Context, m metrics.Metrics) (*download
 			}
 		}
 
-		return nil, HTTPError{StatusCode: resp.StatusCode}
+		retryAfter, _ := rest.RetryAfter(resp)
+		return nil, HTTPError{StatusCode: resp.StatusCode, RetryAfterDelay: retryAfter}
 	}
 }
 
@@ -436,9 +518,15 @@ func isLongPollSupported(header http.Header) bool {
 }
 
 type HTTPError struct {
-	StatusCode int
+	StatusCode      int
+	RetryAfterDelay time.Duration // delay the server asked for in a Retry-After header
 }
 
 func (e HTTPError) Error() string {
 	return "server replied with " + http.StatusText(e.StatusCode)
 }
+
+// RetryAfter returns the delay the server asked for in a Retry-After header.
+func (e HTTPError) RetryAfter() time.Duration {
+	return e.RetryAfterDelay
+}
@@ -935,6 +935,58 @@ func TestTriggerManual(t *testing.T) {
 	d.Stop(ctx)
 }
 
+func TestTriggerPeriodic(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	fixture := newTestFixture(t)
+
+	// Poll rarely enough that any further updates must come from triggers.
+	config := Config{}
+	min := int64(3600)
+	max := int64(7200)
+	config.Polling.MinDelaySeconds = &min
+	config.Polling.MaxDelaySeconds = &max
+
+	if err := config.ValidateAndInjectDefaults(); err != nil {
+		t.Fatal(err)
+	}
+
+	updates := make(chan *Update, 1)
+
+	d := New(config, fixture.client, "/bundles/test/bundle1").
+		WithCallback(func(_ context.Context, u Update) {
+			updates <- &u
+		})
+
+	d.Start(ctx)
+
+	// wait for the initial download
+	<-updates
+
+	for i := range 3 {
+		exp := fmt.Sprintf("rev%d", i)
+		b := fixture.server.bundles["test/bundle1"]
+		b.Manifest.Revision = exp
+		fixture.server.bundles["test/bundle1"] = b
+
+		if err := d.Trigger(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		u := <-updates
+		if u.Bundle.Manifest.Revision != exp {
+			t.Fatalf("expected revision %q but got %q", exp, u.Bundle.Manifest.Revision)
+		}
+	}
+
+	d.Stop(ctx)
+
+	if err := d.Trigger(ctx); err == nil {
+		t.Fatal("expected error triggering stopped downloader")
+	}
+}
+
 func TestTriggerManualWithTimeout(t *testing.T) {
 	t.Parallel()
 
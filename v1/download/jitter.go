@@ -0,0 +1,76 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package download
+
+import (
+	"context"
+	"hash/fnv"
+	"math/rand"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/logging"
+	"github.com/open-policy-agent/opa/v1/util"
+)
+
+// jitterSource returns a random source seeded by seed if deterministic jitter
+// is enabled, so that the delays drawn from it are stable for an instance but
+// spread out across a fleet of instances. Otherwise, nil is returned and the
+// global random source is used.
+func (c *Config) jitterSource(seed string) *rand.Rand {
+	if !c.Polling.DeterministicJitter {
+		return nil
+	}
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(seed))
+
+	return rand.New(rand.NewSource(int64(h.Sum64())))
+}
+
+func randFloat64(rnd *rand.Rand) float64 {
+	if rnd == nil {
+		return rand.Float64()
+	}
+	return rnd.Float64()
+}
+
+// pollingDelay returns a random delay between the min and max polling delays.
+func (c *Config) pollingDelay(rnd *rand.Rand) time.Duration {
+	min := float64(*c.Polling.MinDelaySeconds)
+	max := float64(*c.Polling.MaxDelaySeconds)
+	return time.Duration(((max - min) * randFloat64(rnd)) + min)
+}
+
+// splayDelay returns a random delay of at most the configured splay.
+func (c *Config) splayDelay(rnd *rand.Rand) time.Duration {
+	if c.Polling.SplaySeconds == nil || *c.Polling.SplaySeconds == 0 {
+		return 0
+	}
+	return time.Duration(float64(*c.Polling.SplaySeconds) * randFloat64(rnd))
+}
+
+// waitForSplay blocks for the splay delay before the first download. A
+// download triggered while waiting ends the wait, and its result channel is
+// returned. If ctx is cancelled, false is returned.
+func waitForSplay(ctx context.Context, c *Config, rnd *rand.Rand, trigger chan chan error, logger logging.Logger) (chan error, bool) {
+	delay := c.splayDelay(rnd)
+	if delay == 0 {
+		return nil, true
+	}
+
+	logger.Debug("Waiting %v before first download.", delay)
+
+	timer, timerCancel := util.TimerWithCancel(delay)
+	select {
+	case <-timer.C:
+		return nil, true
+	case triggered := <-trigger:
+		timerCancel()
+		return triggered, true
+	case <-ctx.Done():
+		timerCancel()
+		return nil, false
+	}
+}
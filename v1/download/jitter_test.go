@@ -0,0 +1,82 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package download
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/logging"
+	"github.com/open-policy-agent/opa/v1/util"
+)
+
+func TestConfigSplayAndJitter(t *testing.T) {
+	var config Config
+	if err := util.Unmarshal([]byte(`{"polling": {"min_delay_seconds": 10, "max_delay_seconds": 20, "splay_seconds": 300, "deterministic_jitter": true}}`), &config); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := config.ValidateAndInjectDefaults(); err != nil {
+		t.Fatal(err)
+	}
+
+	if exp, act := 300*time.Second, time.Duration(*config.Polling.SplaySeconds); exp != act {
+		t.Fatalf("expected splay %v, got %v", exp, act)
+	}
+
+	// The same instance ID yields the same delays, different instance IDs
+	// spread out.
+	a, b, c := config.jitterSource("instance-a"), config.jitterSource("instance-a"), config.jitterSource("instance-b")
+
+	splayA, splayB, splayC := config.splayDelay(a), config.splayDelay(b), config.splayDelay(c)
+	if splayA != splayB {
+		t.Fatalf("expected equal splay for equal seeds, got %v and %v", splayA, splayB)
+	}
+	if splayA == splayC {
+		t.Fatalf("expected different splay for different seeds, got %v", splayA)
+	}
+	if splayA < 0 || splayA >= 300*time.Second {
+		t.Fatalf("expected splay within [0s, 5m), got %v", splayA)
+	}
+
+	for range 10 {
+		delayA, delayB := config.pollingDelay(a), config.pollingDelay(b)
+		if delayA != delayB {
+			t.Fatalf("expected equal polling delay for equal seeds, got %v and %v", delayA, delayB)
+		}
+		if delayA < 10*time.Second || delayA > 20*time.Second {
+			t.Fatalf("expected polling delay within [10s, 20s], got %v", delayA)
+		}
+	}
+
+	config.Polling.DeterministicJitter = false
+	if rnd := config.jitterSource("instance-a"); rnd != nil {
+		t.Fatal("expected global random source without deterministic jitter")
+	}
+}
+
+func TestWaitForSplay(t *testing.T) {
+	splay := int64(time.Hour)
+	config := Config{Polling: PollingConfig{SplaySeconds: &splay}}
+	trigger := make(chan chan error)
+
+	done := make(chan error, 1)
+	go func() {
+		trigger <- done
+	}()
+
+	triggered, ok := waitForSplay(context.Background(), &config, nil, trigger, logging.NewNoOpLogger())
+	if !ok || triggered != done {
+		t.Fatal("expected trigger to end the splay")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, ok := waitForSplay(ctx, &config, nil, trigger, logging.NewNoOpLogger()); ok {
+		t.Fatal("expected cancelled context to end the splay")
+	}
+}
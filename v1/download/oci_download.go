@@ -9,7 +9,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"math/rand"
 	"net/http"
 	"net/url"
 	"os"
@@ -45,7 +44,7 @@ func NewOCI(config Config, client rest.Client, path, storePath string) *OCIDownl
 		path:           path,
 		localStorePath: storePath,
 		client:         client,
-		trigger:        make(chan chan struct{}),
+		trigger:        make(chan chan error),
 		stop:           make(chan chan struct{}),
 		logger:         client.Logger(),
 		store:          localstore,
@@ -89,6 +88,14 @@ func (d *OCIDownloader) WithBundleParserOpts(opts ast.ParserOptions) *OCIDownloa
 	return d
 }
 
+// WithJitterSeed seeds the random polling delays of the downloader with seed,
+// usually the instance ID, if deterministic jitter is enabled in the polling
+// configuration.
+func (d *OCIDownloader) WithJitterSeed(seed string) *OCIDownloader {
+	d.rnd = d.config.jitterSource(seed)
+	return d
+}
+
 // ClearCache is deprecated. Use SetCache instead.
 func (*OCIDownloader) ClearCache() {
 }
@@ -99,8 +106,17 @@ func (d *OCIDownloader) SetCache(etag string) {
 }
 
 // Trigger can be used to control when the downloader attempts to download
-// a new bundle in manual triggering mode.
+// a new bundle in manual triggering mode. In periodic mode, Trigger asks the
+// running polling loop to download immediately.
 func (d *OCIDownloader) Trigger(ctx context.Context) error {
+	d.mtx.Lock()
+	running := d.running
+	d.mtx.Unlock()
+
+	if running != nil {
+		return triggerLoop(ctx, d.trigger, running)
+	}
+
 	done := make(chan error)
 
 	go func() {
@@ -125,7 +141,11 @@ func (d *OCIDownloader) Trigger(ctx context.Context) error {
 // Start tells the Downloader to begin downloading bundles.
 func (d *OCIDownloader) Start(ctx context.Context) {
 	if *d.config.Trigger == plugins.TriggerPeriodic {
-		go d.doStart(ctx)
+		running := make(chan struct{})
+		d.mtx.Lock()
+		d.running = running
+		d.mtx.Unlock()
+		go d.doStart(ctx, running)
 	}
 }
 
@@ -147,12 +167,15 @@ func (d *OCIDownloader) Stop(context.Context) {
 	<-done
 }
 
-func (d *OCIDownloader) doStart(context.Context) {
+func (d *OCIDownloader) doStart(_ context.Context, running chan struct{}) {
 	// We'll revisit context passing/usage later.
 	ctx, cancel := context.WithCancel(context.Background())
 
 	d.wg.Add(1)
-	go d.loop(ctx)
+	go func() {
+		defer close(running)
+		d.loop(ctx)
+	}()
 
 	done := <-d.stop // blocks until there's something to read
 	cancel()
@@ -166,23 +189,38 @@ func (d *OCIDownloader) loop(ctx context.Context) {
 
 	var retry int
 
+	triggered, ok := waitForSplay(ctx, &d.config, d.rnd, d.trigger, d.logger)
+	if !ok {
+		return
+	}
+
 	for {
 
 		var delay time.Duration
 
+		if triggered == nil {
+			var ok bool
+			if triggered, ok = waitForDownloadWindow(ctx, &d.config, d.trigger, d.logger); !ok {
+				return
+			}
+		}
+
 		err := d.oneShot(ctx)
 
+		if triggered != nil {
+			triggered <- err
+			triggered = nil
+		}
+
 		if ctx.Err() != nil {
 			return
 		}
 
 		if err != nil {
-			delay = util.DefaultBackoff(float64(minRetryDelay), float64(*d.config.Polling.MaxDelaySeconds), retry)
+			delay = rest.Backoff(minRetryDelay, time.Duration(*d.config.Polling.MaxDelaySeconds), retry, err)
 		} else {
 			// revert the response header timeout value on the http client's transport
-			min := float64(*d.config.Polling.MinDelaySeconds)
-			max := float64(*d.config.Polling.MaxDelaySeconds)
-			delay = time.Duration(((max - min) * rand.Float64()) + min)
+			delay = d.config.pollingDelay(d.rnd)
 		}
 
 		d.logger.Debug("OCI - Waiting %v before next download/retry.", delay)
@@ -195,6 +233,8 @@ func (d *OCIDownloader) loop(ctx context.Context) {
 			} else {
 				retry = 0
 			}
+		case triggered = <-d.trigger:
+			timerCancel()
 		case <-ctx.Done():
 			timerCancel() // explicitly cancel the timer.
 			return
@@ -274,7 +314,8 @@ func (d *OCIDownloader) download(ctx context.Context, m metrics.Metrics) (*downl
 		WithMetrics(m).
 		WithBundleVerificationConfig(d.bvc).
 		WithBundleEtag(etag).
-		WithRegoVersion(d.bundleParserOpts.RegoVersion)
+		WithRegoVersion(d.bundleParserOpts.RegoVersion).
+		WithInternPool(d.bundleParserOpts.InternPool)
 	bundleInfo, err := reader.Read()
 	if err != nil {
 		return &downloaderResponse{}, fmt.Errorf("unexpected error %w", err)
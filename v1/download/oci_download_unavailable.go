@@ -34,6 +34,10 @@ func (d *OCIDownloader) WithBundlePersistence(bool) *OCIDownloader {
 	panic("built without OCI support")
 }
 
+func (d *OCIDownloader) WithJitterSeed(string) *OCIDownloader {
+	panic("built without OCI support")
+}
+
 func (d *OCIDownloader) ClearCache() {
 	panic("built without OCI support")
 }
@@ -2,6 +2,7 @@ package download
 
 import (
 	"context"
+	"math/rand"
 	"sync"
 
 	"github.com/open-policy-agent/opa/v1/ast"
@@ -16,7 +17,7 @@ type OCIDownloader struct {
 	client           rest.Client                   // HTTP client to use for bundle downloading
 	path             string                        // path for OCI image as <registry>/<org>/<repo>:<tag>
 	localStorePath   string                        // path for the local OCI storage
-	trigger          chan chan struct{}            // channel to signal downloads when manual triggering is enabled
+	trigger          chan chan error               // channel to signal out-of-band downloads when periodic polling is running
 	stop             chan chan struct{}            // used to signal plugin to stop running
 	f                func(context.Context, Update) // callback function invoked when download updates occur
 	sizeLimitBytes   *int64                        // max bundle file size in bytes (passed to reader)
@@ -25,8 +26,10 @@ type OCIDownloader struct {
 	logger           logging.Logger
 	mtx              sync.Mutex
 	stopped          bool
+	running          chan struct{} // closed once the polling loop has exited; nil until started
 	persist          bool
 	store            *oci.Store
 	etag             string
 	bundleParserOpts ast.ParserOptions
+	rnd              *rand.Rand // source of the polling delays; nil for the global source
 }
@@ -0,0 +1,44 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package download
+
+import (
+	"context"
+	"io"
+
+	"golang.org/x/time/rate"
+)
+
+// throttledReader limits the rate at which bytes are read from r.
+type throttledReader struct {
+	ctx     context.Context
+	r       io.Reader
+	limiter *rate.Limiter
+}
+
+func newThrottledReader(ctx context.Context, r io.Reader, bytesPerSecond int64) io.Reader {
+	return &throttledReader{
+		ctx:     ctx,
+		r:       r,
+		limiter: rate.NewLimiter(rate.Limit(bytesPerSecond), int(bytesPerSecond)),
+	}
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	// Reads are limited to the burst size, so that every read can be allowed
+	// by the limiter.
+	if burst := t.limiter.Burst(); len(p) > burst {
+		p = p[:burst]
+	}
+
+	n, err := t.r.Read(p)
+	if n > 0 {
+		if werr := t.limiter.WaitN(t.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+
+	return n, err
+}
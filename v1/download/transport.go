@@ -0,0 +1,147 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package download
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/open-policy-agent/opa/v1/plugins/rest"
+)
+
+// Transport sends the bundle requests of a downloader to the service it
+// downloads bundles from. The response is handled like one of a bundle server:
+// a 200 response carries the bundle and its ETag, and a 304 response indicates
+// that the bundle has not changed since the ETag of the If-None-Match header.
+//
+// Transports are selected by the type of the service, so that bundles can be
+// downloaded from services other than bundle servers, e.g., object storage.
+type Transport interface {
+	Do(ctx context.Context, client rest.Client, path string) (*http.Response, error)
+}
+
+// Service types of the object storage transports.
+const (
+	ServiceTypeS3        = "s3"
+	ServiceTypeGCS       = "gcs"
+	ServiceTypeAzureBlob = "azure_blob"
+)
+
+var transports = struct {
+	sync.Mutex
+	m map[string]Transport
+}{
+	m: map[string]Transport{
+		ServiceTypeS3: objectStorageTransport{
+			scheme: "s3://",
+			names:  1,
+			url:    s3BucketURL,
+		},
+		ServiceTypeGCS: objectStorageTransport{
+			scheme: "gs://",
+			names:  1,
+			url: func(_ *rest.Config, names []string) string {
+				return "https://storage.googleapis.com/" + names[0]
+			},
+		},
+		ServiceTypeAzureBlob: objectStorageTransport{
+			scheme: "azblob://",
+			names:  2,
+			url: func(_ *rest.Config, names []string) string {
+				return fmt.Sprintf("https://%s.blob.core.windows.net/%s", names[0], names[1])
+			},
+			// Requests authorized with bearer tokens of managed identities
+			// require a version of the API supporting them.
+			headers: map[string]string{"x-ms-version": "2021-08-06"},
+		},
+	},
+}
+
+// RegisterTransport registers t as the transport of services of type typ,
+// replacing the transport registered before, if any.
+func RegisterTransport(typ string, t Transport) {
+	transports.Lock()
+	defer transports.Unlock()
+	transports.m[strings.ToLower(typ)] = t
+}
+
+// transportFor returns the transport registered for the type of the service
+// of client, or the transport of bundle servers if there is none.
+func transportFor(client rest.Client) Transport {
+	transports.Lock()
+	defer transports.Unlock()
+	if t, ok := transports.m[strings.ToLower(client.Config().Type)]; ok {
+		return t
+	}
+	return httpTransport{}
+}
+
+// httpTransport sends requests to bundle servers.
+type httpTransport struct{}
+
+func (httpTransport) Do(ctx context.Context, client rest.Client, path string) (*http.Response, error) {
+	return client.Do(ctx, http.MethodGet, path)
+}
+
+// objectStorageTransport sends requests to object storage. The URL of the
+// service is either the URL of a bucket or container, or its name prefixed
+// with scheme, e.g., s3://my-bucket. Objects are authorized with the
+// credentials of the service, e.g., s3_signing for S3, gcp_metadata for GCS,
+// and azure_managed_identity for Azure Blob Storage.
+type objectStorageTransport struct {
+	scheme  string                                      // scheme of URLs naming buckets, e.g., s3://
+	names   int                                         // number of names in URLs naming buckets
+	url     func(c *rest.Config, names []string) string // URL of the bucket named by a URL
+	headers map[string]string                           // headers required by the service
+}
+
+func (t objectStorageTransport) Do(ctx context.Context, client rest.Client, path string) (*http.Response, error) {
+	url, err := t.resolve(client.Config())
+	if err != nil {
+		return nil, err
+	}
+
+	client = client.WithURL(url)
+	for k, v := range t.headers {
+		client = client.WithHeader(k, v)
+	}
+
+	return client.Do(ctx, http.MethodGet, path)
+}
+
+// resolve returns the URL of the bucket or container named by the URL of the
+// service, or the URL of the service if it does not name one.
+func (t objectStorageTransport) resolve(c *rest.Config) (string, error) {
+	name, ok := strings.CutPrefix(c.URL, t.scheme)
+	if !ok {
+		return c.URL, nil
+	}
+
+	names := strings.Split(strings.Trim(name, "/"), "/")
+	if len(names) != t.names || names[0] == "" {
+		return "", fmt.Errorf("invalid object storage URL %q, expected %s followed by %d name(s)", c.URL, t.scheme, t.names)
+	}
+
+	return t.url(c, names), nil
+}
+
+// s3BucketURL returns the virtual-hosted-style URL of an S3 bucket in the
+// region of the service, e.g., https://my-bucket.s3.eu-west-1.amazonaws.com.
+// Buckets in the China regions are served from the amazonaws.com.cn domain.
+// Without a region, the legacy global endpoint is used.
+func s3BucketURL(c *rest.Config, names []string) string {
+	region := c.AWSRegion()
+	switch {
+	case region == "":
+		return fmt.Sprintf("https://%s.s3.amazonaws.com", names[0])
+	case strings.HasPrefix(region, "cn-"):
+		return fmt.Sprintf("https://%s.s3.%s.amazonaws.com.cn", names[0], region)
+	default:
+		return fmt.Sprintf("https://%s.s3.%s.amazonaws.com", names[0], region)
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package download
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/plugins/rest"
+)
+
+func TestObjectStorageTransportResolve(t *testing.T) {
+	t.Setenv("AWS_REGION", "")
+
+	tests := []struct {
+		typ      string
+		url      string
+		config   string
+		expected string
+		err      bool
+	}{
+		{typ: ServiceTypeS3, url: "s3://my-bucket", expected: "https://my-bucket.s3.amazonaws.com"},
+		{
+			typ:      ServiceTypeS3,
+			url:      "s3://my-bucket",
+			config:   `"credentials": {"s3_signing": {"web_identity_credentials": {"aws_region": "eu-west-1"}}}`,
+			expected: "https://my-bucket.s3.eu-west-1.amazonaws.com",
+		},
+		{
+			typ:      ServiceTypeS3,
+			url:      "s3://my-bucket",
+			config:   `"credentials": {"s3_signing": {"metadata_credentials": {"aws_region": "us-gov-west-1"}}}`,
+			expected: "https://my-bucket.s3.us-gov-west-1.amazonaws.com",
+		},
+		{
+			typ:      ServiceTypeS3,
+			url:      "s3://my-bucket",
+			config:   `"credentials": {"s3_signing": {"profile_credentials": {"aws_region": "cn-north-1"}}}`,
+			expected: "https://my-bucket.s3.cn-north-1.amazonaws.com.cn",
+		},
+		{typ: ServiceTypeS3, url: "https://my-bucket.s3.eu-west-1.amazonaws.com", expected: "https://my-bucket.s3.eu-west-1.amazonaws.com"},
+		{typ: ServiceTypeS3, url: "https://minio.example.com/my-bucket", expected: "https://minio.example.com/my-bucket"},
+		{typ: ServiceTypeS3, url: "s3://", err: true},
+		{typ: ServiceTypeGCS, url: "gs://my-bucket", expected: "https://storage.googleapis.com/my-bucket"},
+		{typ: ServiceTypeGCS, url: "gs://my-bucket/extra", err: true},
+		{typ: ServiceTypeAzureBlob, url: "azblob://account/container", expected: "https://account.blob.core.windows.net/container"},
+		{typ: ServiceTypeAzureBlob, url: "azblob://account", err: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.url, func(t *testing.T) {
+			var c rest.Config
+			raw := `{"url": "` + tc.url + `"`
+			if tc.config != "" {
+				raw += ", " + tc.config
+			}
+			if err := json.Unmarshal([]byte(raw+"}"), &c); err != nil {
+				t.Fatal(err)
+			}
+
+			actual, err := transports.m[tc.typ].(objectStorageTransport).resolve(&c)
+			switch {
+			case tc.err && err == nil:
+				t.Fatalf("expected error, got %v", actual)
+			case !tc.err && err != nil:
+				t.Fatal(err)
+			case actual != tc.expected:
+				t.Fatalf("expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+
+	t.Setenv("AWS_REGION", "ap-southeast-2")
+	c := rest.Config{URL: "s3://my-bucket"}
+	if actual, _ := transports.m[ServiceTypeS3].(objectStorageTransport).resolve(&c); actual != "https://my-bucket.s3.ap-southeast-2.amazonaws.com" {
+		t.Fatalf("expected bucket in region of the environment, got %v", actual)
+	}
+}
+
+func TestObjectStorageTransportHeaders(t *testing.T) {
+	var version, path string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		version, path = r.Header.Get("x-ms-version"), r.URL.Path
+		w.WriteHeader(http.StatusNotModified)
+	}))
+	defer ts.Close()
+
+	client, err := rest.New([]byte(`{"name": "blob", "type": "azure_blob", "url": "`+ts.URL+`/container"}`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := New(Config{}, client, "/bundles/bundle.tar.gz")
+	if _, ok := d.transport.(objectStorageTransport); !ok {
+		t.Fatalf("expected object storage transport, got %T", d.transport)
+	}
+
+	resp, err := d.transport.Do(context.Background(), client, d.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if version == "" {
+		t.Fatal("expected x-ms-version header")
+	}
+	if exp := "/container/bundles/bundle.tar.gz"; path != exp {
+		t.Fatalf("expected path %v, got %v", exp, path)
+	}
+}
+
+type testTransport struct {
+	paths []string
+}
+
+func (tt *testTransport) Do(_ context.Context, _ rest.Client, path string) (*http.Response, error) {
+	tt.paths = append(tt.paths, path)
+	header := http.Header{}
+	header.Set("ETag", "v1")
+	return &http.Response{StatusCode: http.StatusNotModified, Header: header, Body: http.NoBody}, nil
+}
+
+func TestRegisterTransport(t *testing.T) {
+	tt := &testTransport{}
+	RegisterTransport("Test", tt)
+	t.Cleanup(func() {
+		transports.Lock()
+		defer transports.Unlock()
+		delete(transports.m, "test")
+	})
+
+	client, err := rest.New([]byte(`{"name": "test", "type": "test", "url": "test://bundles"}`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := Config{}
+	if err := config.ValidateAndInjectDefaults(); err != nil {
+		t.Fatal(err)
+	}
+
+	var etag string
+	d := New(config, client, "/bundle.tar.gz").WithCallback(func(_ context.Context, u Update) {
+		etag = u.ETag
+	})
+
+	if err := d.oneShot(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tt.paths) != 1 || tt.paths[0] != "/bundle.tar.gz" {
+		t.Fatalf("expected one request to /bundle.tar.gz, got %v", tt.paths)
+	}
+	if etag != "v1" {
+		t.Fatalf("expected etag v1, got %v", etag)
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package download
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/logging"
+	"github.com/open-policy-agent/opa/v1/util"
+)
+
+// maxWindowSearch bounds the search for the start of the next download window.
+const maxWindowSearch = 366 * 24 * time.Hour
+
+// downloadWindow is a cron-style expression with the fields minute, hour,
+// day of month, month and day of week. A download window contains every
+// minute matched by the expression.
+type downloadWindow struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+func parseDownloadWindow(s string) (*downloadWindow, error) {
+	fields := strings.Fields(s)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("invalid download window %q: expected %d fields", s, len(cronFields))
+	}
+
+	var sets [5]uint64
+	for i, f := range cronFields {
+		set, err := parseCronField(fields[i], f)
+		if err != nil {
+			return nil, fmt.Errorf("invalid download window %q: %w", s, err)
+		}
+		sets[i] = set
+	}
+
+	w := &downloadWindow{
+		minute:  sets[0],
+		hour:    sets[1],
+		dom:     sets[2],
+		month:   sets[3],
+		dow:     sets[4],
+		domStar: fields[2] == "*",
+		dowStar: fields[4] == "*",
+	}
+
+	// Sunday is both 0 and 7.
+	if w.dow&(1<<7) != 0 {
+		w.dow |= 1
+	}
+
+	return w, nil
+}
+
+// parseCronField parses a comma-separated list of values (5), ranges (1-5),
+// wildcards (*) and steps (*/15 or 0-30/10) into a bit set.
+func parseCronField(s string, f cronField) (uint64, error) {
+	var set uint64
+
+	for _, item := range strings.Split(s, ",") {
+		rng, stepStr, hasStep := strings.Cut(item, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepStr)
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q in %v field", stepStr, f.name)
+			}
+		}
+
+		lo, hi := f.min, f.max
+		if rng != "*" {
+			loStr, hiStr, isRange := strings.Cut(rng, "-")
+
+			var err error
+			if lo, err = strconv.Atoi(loStr); err != nil {
+				return 0, fmt.Errorf("invalid value %q in %v field", loStr, f.name)
+			}
+
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(hiStr); err != nil {
+					return 0, fmt.Errorf("invalid value %q in %v field", hiStr, f.name)
+				}
+			} else if hasStep {
+				hi = f.max
+			}
+
+			if lo < f.min || hi > f.max || lo > hi {
+				return 0, fmt.Errorf("value %q out of range [%d, %d] in %v field", rng, f.min, f.max, f.name)
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			set |= 1 << v
+		}
+	}
+
+	return set, nil
+}
+
+func (w *downloadWindow) contains(t time.Time) bool {
+	if w.minute&(1<<t.Minute()) == 0 || w.hour&(1<<t.Hour()) == 0 || w.month&(1<<int(t.Month())) == 0 {
+		return false
+	}
+
+	dom := w.dom&(1<<t.Day()) != 0
+	dow := w.dow&(1<<int(t.Weekday())) != 0
+
+	// As in cron, if both day fields are restricted, either must match.
+	switch {
+	case w.domStar && w.dowStar:
+		return true
+	case w.domStar:
+		return dow
+	case w.dowStar:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+// untilDownloadWindow returns how long to wait from t until a download is
+// allowed, or zero if no windows are configured or t is within one.
+func (c *Config) untilDownloadWindow(t time.Time) time.Duration {
+	if len(c.windows) == 0 {
+		return 0
+	}
+
+	start := t.Truncate(time.Minute)
+	for next := start; next.Sub(start) < maxWindowSearch; next = next.Add(time.Minute) {
+		for _, w := range c.windows {
+			if w.contains(next) {
+				if next.Before(t) {
+					return 0
+				}
+				return next.Sub(t)
+			}
+		}
+	}
+
+	return maxWindowSearch
+}
+
+// waitForDownloadWindow blocks until downloads are allowed by the configured
+// download windows. It returns early with the channel of a triggered download
+// if one is received, and with false if ctx is done.
+func waitForDownloadWindow(ctx context.Context, c *Config, trigger chan chan error, logger logging.Logger) (chan error, bool) {
+	delay := c.untilDownloadWindow(time.Now())
+	if delay == 0 {
+		return nil, true
+	}
+
+	logger.Debug("Outside of download windows, waiting %v before next download.", delay)
+
+	timer, timerCancel := util.TimerWithCancel(delay)
+	select {
+	case <-timer.C:
+		return nil, true
+	case triggered := <-trigger:
+		timerCancel()
+		return triggered, true
+	case <-ctx.Done():
+		timerCancel()
+		return nil, false
+	}
+}
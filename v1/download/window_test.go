@@ -0,0 +1,115 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package download
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestDownloadWindows(t *testing.T) {
+	t.Parallel()
+
+	// 2025-01-06 was a Monday.
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2025, time.January, day, hour, minute, 30, 0, time.UTC)
+	}
+
+	tests := []struct {
+		note    string
+		windows []string
+		now     time.Time
+		exp     time.Duration
+	}{
+		{
+			note: "no windows",
+			now:  at(6, 12, 0),
+		},
+		{
+			note:    "within window",
+			windows: []string{"* 0-6 * * *"},
+			now:     at(6, 3, 15),
+		},
+		{
+			note:    "before window",
+			windows: []string{"* 0-6 * * *"},
+			now:     at(6, 23, 59),
+			exp:     30 * time.Second,
+		},
+		{
+			note:    "weekends only",
+			windows: []string{"* * * * 6,7"},
+			now:     at(9, 12, 0),
+			exp:     36*time.Hour - 30*time.Second,
+		},
+		{
+			note:    "any of multiple windows",
+			windows: []string{"* 0-6 * * 1-5", "*/15 12 * * *"},
+			now:     at(7, 12, 14),
+			exp:     30 * time.Second,
+		},
+		{
+			note:    "day of month or day of week",
+			windows: []string{"* * 1 * 1"},
+			now:     at(6, 0, 0),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			c := Config{DownloadWindows: tc.windows}
+			if err := c.ValidateAndInjectDefaults(); err != nil {
+				t.Fatal(err)
+			}
+
+			if d := c.untilDownloadWindow(tc.now); d != tc.exp {
+				t.Fatalf("expected %v until download window, got %v", tc.exp, d)
+			}
+		})
+	}
+}
+
+func TestParseDownloadWindowErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, s := range []string{
+		"* * * *",
+		"60 * * * *",
+		"* * 0 * *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+	} {
+		if _, err := parseDownloadWindow(s); err == nil {
+			t.Errorf("expected error for %q", s)
+		}
+	}
+}
+
+func TestThrottledReader(t *testing.T) {
+	t.Parallel()
+
+	data := bytes.Repeat([]byte("x"), 300)
+
+	start := time.Now()
+	bs, err := io.ReadAll(newThrottledReader(context.Background(), bytes.NewReader(data), 200))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(bs, data) {
+		t.Fatal("unexpected data read")
+	}
+
+	// The first 200 bytes are read at once, the remaining 100 bytes take half
+	// a second.
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Fatalf("expected throttled read, took %v", elapsed)
+	}
+}
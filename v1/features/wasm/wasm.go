@@ -41,6 +41,13 @@ func (o *OPA) WithDataJSON(data any) opa.EvalEngine {
 	return o
 }
 
+// WithMemoryLimit configures the maximum memory (in bytes) of the instances
+// evaluating the policy.
+func (o *OPA) WithMemoryLimit(limit uint32) opa.EvalEngine {
+	o.opa = o.opa.WithMemoryMax(limit)
+	return o
+}
+
 // Init initializes the OPA instance.
 func (o *OPA) Init() (opa.EvalEngine, error) {
 	i, err := o.opa.Init()
@@ -67,6 +74,7 @@ func (o *OPA) Eval(ctx context.Context, opts opa.EvalOpts) (*opa.Result, error)
 		NDBuiltinCache:         opts.NDBuiltinCache,
 		PrintHook:              opts.PrintHook,
 		Capabilities:           opts.Capabilities,
+		MemoryLimit:            opts.MemoryLimit,
 	}
 
 	res, err := o.opa.Eval(ctx, evalOptions)
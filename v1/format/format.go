@@ -1225,7 +1225,7 @@ func (w *writer) writeTermParens(parens bool, term *ast.Term, comments []*ast.Co
 			return nil, err
 		}
 	case ast.String:
-		if term.Location.Text[0] == '`' {
+		if len(term.Location.Text) > 0 && term.Location.Text[0] == '`' {
 			// To preserve raw strings, we need to output the original text,
 			w.write(string(term.Location.Text))
 		} else {
@@ -1233,7 +1233,11 @@ func (w *writer) writeTermParens(parens bool, term *ast.Term, comments []*ast.Co
 			var after, quote string
 			var found bool
 			// term.Location.Text could contain the prefix `else :=`, remove it
-			switch term.Location.Text[len(term.Location.Text)-1] {
+			var last byte
+			if len(term.Location.Text) > 0 {
+				last = term.Location.Text[len(term.Location.Text)-1]
+			}
+			switch last {
 			case '"':
 				quote = "\""
 				_, after, found = strings.Cut(string(term.Location.Text), quote)
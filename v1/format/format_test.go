@@ -6,6 +6,7 @@ package format
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -14,6 +15,7 @@ import (
 	"testing"
 
 	"github.com/open-policy-agent/opa/v1/ast"
+	astJSON "github.com/open-policy-agent/opa/v1/ast/json"
 	"github.com/open-policy-agent/opa/v1/ast/location"
 )
 
@@ -81,6 +83,61 @@ func TestFormatNilLocationFunctionArgs(t *testing.T) {
 	}
 }
 
+func TestFormatRawStringJSONRoundTrip(t *testing.T) {
+	module := ast.MustParseModule("package p\n\nr := regex.match(`^\\d+\\.\\d+$`, input.version)\n")
+
+	tests := []struct {
+		note string
+		text bool
+		exp  string
+	}{
+		{
+			note: "with location text",
+			text: true,
+			exp:  "package p\n\nr := regex.match(`^\\d+\\.\\d+$`, input.version)\n",
+		},
+		{
+			note: "without location text",
+			exp:  "package p\n\nr := regex.match(\"^\\\\d+\\\\.\\\\d+$\", input.version)\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			defer astJSON.SetOptions(astJSON.Defaults())
+			astJSON.SetOptions(astJSON.Options{
+				MarshalOptions: astJSON.MarshalOptions{
+					IncludeLocation:     astJSON.NodeToggle{Term: true, Package: true, Rule: true, Expr: true},
+					IncludeLocationText: tc.text,
+				},
+			})
+
+			bs, err := json.Marshal(module)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var decoded ast.Module
+			if err := json.Unmarshal(bs, &decoded); err != nil {
+				t.Fatal(err)
+			}
+
+			formatted, err := Ast(&decoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(formatted) != tc.exp {
+				t.Fatalf("Expected:\n\n%s\n\nbut got:\n\n%s", tc.exp, formatted)
+			}
+
+			if reparsed := ast.MustParseModule(string(formatted)); !reparsed.Equal(module) {
+				t.Fatalf("Expected formatted module to equal original, got:\n\n%v", reparsed)
+			}
+		})
+	}
+}
+
 func TestFormatSourceError(t *testing.T) {
 	rego := "testfiles/v0/test.rego.error"
 	contents, err := os.ReadFile(rego)
@@ -1026,3 +1083,83 @@ func BenchmarkFormatLargePolicy(b *testing.B) {
 		}
 	}
 }
+
+func TestMigrateToRegoV1(t *testing.T) {
+	module := ast.MustParseModuleWithOpts(`package test
+
+import future.keywords.in
+
+# METADATA
+# title: allow
+allow {
+	"admin" in input.roles
+}
+
+users[name] {
+	name := input.users[_].name
+}
+
+deny[msg] {
+	any([input.a, input.b])
+	msg := "a or b"
+}
+
+default ok = false
+`, ast.ParserOptions{RegoVersion: ast.RegoV0, ProcessAnnotation: true})
+
+	migrated, notes, err := MigrateToRegoV1(module)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := `package test
+
+# METADATA
+# title: allow
+allow if {
+	"admin" in input.roles
+}
+
+users contains name if {
+	name := input.users[_].name
+}
+
+deny contains msg if {
+	any([input.a, input.b])
+	msg := "a or b"
+}
+
+default ok := false
+`
+
+	if result := string(MustAstWithOpts(migrated, Opts{RegoVersion: ast.RegoV1})); result != exp {
+		t.Fatalf("Expected:\n\n%v\n\nGot:\n\n%v", exp, result)
+	}
+
+	if migrated.RegoVersion() != ast.RegoV1 {
+		t.Fatalf("Expected v1 module but got %v", migrated.RegoVersion())
+	}
+
+	if len(migrated.Annotations) != 1 {
+		t.Fatalf("Expected annotations to be preserved but got %v", migrated.Annotations)
+	}
+
+	if len(notes) != 1 || notes[0].Code != ast.TypeErr || notes[0].Location.Row != 16 || !strings.Contains(notes[0].Message, "deprecated built-in function calls in expression: any") {
+		t.Fatalf("Unexpected notes: %v", notes)
+	}
+}
+
+func TestMigrateToRegoV1Error(t *testing.T) {
+	module := ast.MustParseModuleWithOpts(`package test
+
+p[x] {
+	if := input.x
+	x := if
+}
+`, ast.ParserOptions{RegoVersion: ast.RegoV0})
+
+	migrated, _, err := MigrateToRegoV1(module)
+	if err == nil {
+		t.Fatalf("Expected error but got module:\n%v", migrated)
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package format
+
+import (
+	"fmt"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+)
+
+// Note describes a construct that MigrateToRegoV1 could not rewrite, and that
+// needs human attention before the migrated module can be used with OPA 1.0,
+// e.g., a call to a deprecated built-in function.
+type Note struct {
+	Code     string        `json:"code"`
+	Message  string        `json:"message"`
+	Location *ast.Location `json:"location,omitempty"`
+}
+
+func (n Note) String() string {
+	if n.Location != nil {
+		return fmt.Sprintf("%v: %v: %v", n.Location, n.Code, n.Message)
+	}
+	return fmt.Sprintf("%v: %v", n.Code, n.Message)
+}
+
+// MigrateToRegoV1 rewrites the v0 module to Rego v1 syntax, like the --v0-v1
+// flag of the fmt command does: the if and contains keywords are inserted
+// where required, rego.v1 and future.keywords imports are dropped, and rule
+// heads are rewritten to their v1 form. The module is not modified.
+//
+// Constructs that cannot be rewritten are reported as notes, located in the
+// original module. The returned module is nil if the rewritten module is not
+// a valid v1 module, in which case the error lists the offending constructs.
+func MigrateToRegoV1(module *ast.Module) (*ast.Module, []Note, error) {
+	checkOpts := ast.NewRegoCheckOptions()
+	// These are rewritten by the formatter.
+	checkOpts.RequireIfKeyword = false
+	checkOpts.RequireContainsKeyword = false
+	checkOpts.RequireRuleBodyOrValue = false
+
+	var notes []Note
+	for _, err := range ast.CheckRegoV1WithOptions(module, checkOpts) {
+		notes = append(notes, Note{Code: err.Code, Message: err.Message, Location: err.Location})
+	}
+
+	bs, err := AstWithOpts(module, Opts{RegoVersion: ast.RegoV1, DropV0Imports: true})
+	if err != nil {
+		return nil, notes, err
+	}
+
+	var filename string
+	if module.Package != nil && module.Package.Location != nil {
+		filename = module.Package.Location.File
+	}
+
+	migrated, err := ast.ParseModuleWithOpts(filename, string(bs), ast.ParserOptions{RegoVersion: ast.RegoV1, ProcessAnnotation: true})
+	if err != nil {
+		return nil, notes, err
+	}
+
+	return migrated, notes, nil
+}
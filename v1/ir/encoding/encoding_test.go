@@ -90,6 +90,44 @@ func TestRoundTrip(t *testing.T) {
 			if !bytes.Equal(bs, bs2) {
 				t.Fatal("expected bytes to be equal")
 			}
+
+			// Type declarations of built-in functions lose their descriptions
+			// when decoded, so compare with the decoded copy.
+			var text bytes.Buffer
+			if err := ir.Pretty(&text, plan); err != nil {
+				t.Fatal(err)
+			}
+
+			parsed, err := ir.Parse(&text)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var exp, act bytes.Buffer
+			if err := ir.Pretty(&exp, &cpy); err != nil {
+				t.Fatal(err)
+			}
+			if err := ir.Pretty(&act, parsed); err != nil {
+				t.Fatal(err)
+			}
+
+			if exp.String() != act.String() {
+				t.Fatalf("expected text to be equal, want:\n%v\ngot:\n%v", exp.String(), act.String())
+			}
+
+			bs3, err := json.Marshal(&cpy)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			bs4, err := json.Marshal(parsed)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(bs3, bs4) {
+				t.Fatalf("expected bytes to be equal, want:\n%s\ngot:\n%s", bs3, bs4)
+			}
 		})
 	}
 }
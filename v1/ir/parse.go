@@ -0,0 +1,547 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ir
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/open-policy-agent/opa/v1/types"
+)
+
+// Parse reads a policy in the text format written by Pretty. Comments start
+// with # and extend to the end of the line.
+func Parse(r io.Reader) (*Policy, error) {
+	bs, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	toks, err := tokenize(string(bs))
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{toks: toks}
+	return p.parsePolicy()
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	line int
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of input"
+	}
+	return strconv.Quote(t.text)
+}
+
+func isPunct(c byte) bool {
+	switch c {
+	case '{', '}', '[', ']', '=':
+		return true
+	}
+	return false
+}
+
+func isSpace(c byte) bool {
+	switch c {
+	case ' ', '\t', '\r', '\n':
+		return true
+	}
+	return false
+}
+
+func tokenize(s string) ([]token, error) {
+	var toks []token
+	line := 1
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case isSpace(c):
+			i++
+		case c == '#':
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+		case isPunct(c):
+			toks = append(toks, token{kind: tokPunct, text: s[i : i+1], line: line})
+			i++
+		case c == '"':
+			j := i + 1
+			for ; j < len(s) && s[j] != '"'; j++ {
+				if s[j] == '\\' {
+					j++
+				} else if s[j] == '\n' {
+					break
+				}
+			}
+			if j >= len(s) || s[j] != '"' {
+				return nil, fmt.Errorf("ir: line %d: unterminated string", line)
+			}
+			str, err := strconv.Unquote(s[i : j+1])
+			if err != nil {
+				return nil, fmt.Errorf("ir: line %d: invalid string: %w", line, err)
+			}
+			toks = append(toks, token{kind: tokString, text: str, line: line})
+			i = j + 1
+		default:
+			j := i
+			for j < len(s) && !isSpace(s[j]) && !isPunct(s[j]) && s[j] != '"' && s[j] != '#' {
+				j++
+			}
+			toks = append(toks, token{kind: tokWord, text: s[i:j], line: line})
+			i = j
+		}
+	}
+
+	return append(toks, token{kind: tokEOF, line: line}), nil
+}
+
+type parser struct {
+	toks []token
+	pos  int
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.pos]
+}
+
+func (p *parser) peekN(n int) token {
+	if p.pos+n < len(p.toks) {
+		return p.toks[p.pos+n]
+	}
+	return p.toks[len(p.toks)-1]
+}
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) errorf(t token, f string, a ...any) error {
+	return fmt.Errorf("ir: line %d: %s", t.line, fmt.Sprintf(f, a...))
+}
+
+func (p *parser) expect(kind tokenKind, text string) error {
+	t := p.next()
+	if t.kind != kind || t.text != text {
+		return p.errorf(t, "expected %q but got %v", text, t)
+	}
+	return nil
+}
+
+func (p *parser) isPunct(text string) bool {
+	t := p.peek()
+	return t.kind == tokPunct && t.text == text
+}
+
+func (p *parser) parseString() (string, error) {
+	t := p.next()
+	if t.kind != tokString {
+		return "", p.errorf(t, "expected string but got %v", t)
+	}
+	return t.text, nil
+}
+
+func (p *parser) parseInt() (int, error) {
+	t := p.next()
+	if t.kind != tokWord {
+		return 0, p.errorf(t, "expected integer but got %v", t)
+	}
+	i, err := strconv.Atoi(t.text)
+	if err != nil {
+		return 0, p.errorf(t, "expected integer but got %v", t)
+	}
+	return i, nil
+}
+
+// parseSection parses the body of a section enclosed in braces, calling f
+// for each entry that starts with one of the keywords.
+func (p *parser) parseSection(f func(keyword token) error) error {
+	if err := p.expect(tokPunct, "{"); err != nil {
+		return err
+	}
+	for !p.isPunct("}") {
+		t := p.next()
+		if t.kind != tokWord {
+			return p.errorf(t, "unexpected %v", t)
+		}
+		if err := f(t); err != nil {
+			return err
+		}
+	}
+	return p.expect(tokPunct, "}")
+}
+
+func (p *parser) parsePolicy() (*Policy, error) {
+	policy := &Policy{}
+
+	for p.peek().kind != tokEOF {
+		t := p.next()
+		var err error
+		switch {
+		case t.kind == tokWord && t.text == "static" && policy.Static == nil:
+			policy.Static, err = p.parseStatic()
+		case t.kind == tokWord && t.text == "plans" && policy.Plans == nil:
+			policy.Plans, err = p.parsePlans()
+		case t.kind == tokWord && t.text == "funcs" && policy.Funcs == nil:
+			policy.Funcs, err = p.parseFuncs()
+		default:
+			err = p.errorf(t, "unexpected %v", t)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return policy, nil
+}
+
+func (p *parser) parseStatic() (*Static, error) {
+	static := &Static{}
+
+	err := p.parseSection(func(t token) error {
+		switch t.text {
+		case "string", "file":
+			i, err := p.parseInt()
+			if err != nil {
+				return err
+			}
+			s, err := p.parseString()
+			if err != nil {
+				return err
+			}
+			consts := &static.Strings
+			if t.text == "file" {
+				consts = &static.Files
+			}
+			if i != len(*consts) {
+				return p.errorf(t, "expected %v index %d but got %d", t.text, len(*consts), i)
+			}
+			*consts = append(*consts, &StringConst{Value: s})
+		case "builtin":
+			name, err := p.parseString()
+			if err != nil {
+				return err
+			}
+			decl, err := p.parseString()
+			if err != nil {
+				return err
+			}
+			var f types.Function
+			if err := f.UnmarshalJSON([]byte(decl)); err != nil {
+				return p.errorf(t, "invalid declaration of built-in function %v: %v", name, err)
+			}
+			static.BuiltinFuncs = append(static.BuiltinFuncs, &BuiltinFunc{Name: name, Decl: &f})
+		default:
+			return p.errorf(t, "unexpected %v", t)
+		}
+		return nil
+	})
+
+	return static, err
+}
+
+func (p *parser) parsePlans() (*Plans, error) {
+	plans := &Plans{}
+
+	err := p.parseSection(func(t token) error {
+		if t.text != "plan" {
+			return p.errorf(t, "expected \"plan\" but got %v", t)
+		}
+		name, err := p.parseString()
+		if err != nil {
+			return err
+		}
+		blocks, err := p.parseBlocks()
+		if err != nil {
+			return err
+		}
+		plans.Plans = append(plans.Plans, &Plan{Name: name, Blocks: blocks})
+		return nil
+	})
+
+	return plans, err
+}
+
+func (p *parser) parseFuncs() (*Funcs, error) {
+	funcs := &Funcs{}
+
+	err := p.parseSection(func(t token) error {
+		if t.text != "func" {
+			return p.errorf(t, "expected \"func\" but got %v", t)
+		}
+		name, err := p.parseString()
+		if err != nil {
+			return err
+		}
+		fn := &Func{Name: name}
+		for !p.isPunct("{") {
+			key := p.next()
+			if key.kind != tokWord {
+				return p.errorf(key, "unexpected %v", key)
+			}
+			if err := p.expect(tokPunct, "="); err != nil {
+				return err
+			}
+			var dst reflect.Value
+			switch key.text {
+			case "params":
+				dst = reflect.ValueOf(&fn.Params).Elem()
+			case "return":
+				dst = reflect.ValueOf(&fn.Return).Elem()
+			case "path":
+				dst = reflect.ValueOf(&fn.Path).Elem()
+			default:
+				return p.errorf(key, "unknown func field %v", key)
+			}
+			if err := p.parseValue(dst); err != nil {
+				return err
+			}
+		}
+		fn.Blocks, err = p.parseBlocks()
+		if err != nil {
+			return err
+		}
+		funcs.Funcs = append(funcs.Funcs, fn)
+		return nil
+	})
+
+	return funcs, err
+}
+
+func (p *parser) parseBlocks() ([]*Block, error) {
+	blocks := []*Block{}
+
+	err := p.parseSection(func(t token) error {
+		if t.text != "block" {
+			return p.errorf(t, "expected \"block\" but got %v", t)
+		}
+		b, err := p.parseBlock()
+		if err != nil {
+			return err
+		}
+		blocks = append(blocks, b)
+		return nil
+	})
+
+	return blocks, err
+}
+
+func (p *parser) parseBlock() (*Block, error) {
+	b := &Block{Stmts: []Stmt{}}
+
+	err := p.parseSection(func(t token) error {
+		s, err := p.parseStmt(t)
+		if err != nil {
+			return err
+		}
+		b.Stmts = append(b.Stmts, s)
+		return nil
+	})
+
+	return b, err
+}
+
+func (p *parser) parseStmt(name token) (Stmt, error) {
+	f, ok := stmtFactories[name.text]
+	if !ok {
+		return nil, p.errorf(name, "unrecognized statement type %v", name)
+	}
+	stmt := f()
+
+	v := reflect.ValueOf(stmt).Elem()
+	t := v.Type()
+
+	fields := make(map[string]int, t.NumField())
+	nested := -1
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if f.Anonymous {
+			continue
+		}
+		switch f.Type {
+		case blockType, blocksType:
+			nested = i
+			continue
+		}
+		fields[fieldName(f)] = i
+	}
+
+	for {
+		tok := p.peek()
+		switch {
+		case tok.kind == tokWord && strings.HasPrefix(tok.text, "@"):
+			p.next()
+			var file, row, col int
+			if _, err := fmt.Sscanf(tok.text, "@%d:%d:%d", &file, &row, &col); err != nil {
+				return nil, p.errorf(tok, "invalid location %v", tok)
+			}
+			stmt.SetLocation(file, row, col, "", "")
+		case tok.kind == tokWord && p.peekN(1).kind == tokPunct && p.peekN(1).text == "=":
+			p.next()
+			p.next()
+			i, ok := fields[tok.text]
+			if !ok {
+				return nil, p.errorf(tok, "unknown %v field %v", name.text, tok)
+			}
+			if err := p.parseValue(v.Field(i)); err != nil {
+				return nil, err
+			}
+		case p.isPunct("{") && nested >= 0:
+			var err error
+			switch t.Field(nested).Type {
+			case blockType:
+				var b *Block
+				b, err = p.parseBlock()
+				v.Field(nested).Set(reflect.ValueOf(b))
+			case blocksType:
+				var bs []*Block
+				bs, err = p.parseBlocks()
+				v.Field(nested).Set(reflect.ValueOf(bs))
+			}
+			if err != nil {
+				return nil, err
+			}
+			return stmt, nil
+		default:
+			if nested >= 0 {
+				return nil, p.errorf(tok, "expected block of %v but got %v", name.text, tok)
+			}
+			return stmt, nil
+		}
+	}
+}
+
+func (p *parser) parseValue(v reflect.Value) error {
+	switch v.Type() {
+	case localType:
+		l, err := p.parseLocal()
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(l))
+		return nil
+	case operandType:
+		op, err := p.parseOperand()
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(op))
+		return nil
+	}
+
+	if v.Kind() == reflect.Slice {
+		if err := p.expect(tokPunct, "["); err != nil {
+			return err
+		}
+		s := reflect.MakeSlice(v.Type(), 0, 0)
+		for !p.isPunct("]") {
+			if p.peek().kind == tokEOF {
+				return p.errorf(p.peek(), "expected \"]\" but got %v", p.peek())
+			}
+			e := reflect.New(v.Type().Elem()).Elem()
+			if err := p.parseValue(e); err != nil {
+				return err
+			}
+			s = reflect.Append(s, e)
+		}
+		p.next()
+		v.Set(s)
+		return nil
+	}
+
+	t := p.next()
+
+	if v.Kind() == reflect.String {
+		if t.kind != tokString {
+			return p.errorf(t, "expected string but got %v", t)
+		}
+		v.SetString(t.text)
+		return nil
+	}
+
+	if t.kind != tokWord {
+		return p.errorf(t, "expected %v but got %v", v.Kind(), t)
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(t.text)
+		if err != nil {
+			return p.errorf(t, "expected bool but got %v", t)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(t.text, 10, v.Type().Bits())
+		if err != nil {
+			return p.errorf(t, "expected %v but got %v", v.Kind(), t)
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := strconv.ParseUint(t.text, 10, v.Type().Bits())
+		if err != nil {
+			return p.errorf(t, "expected %v but got %v", v.Kind(), t)
+		}
+		v.SetUint(i)
+	default:
+		return p.errorf(t, "unsupported field type %v", v.Type())
+	}
+
+	return nil
+}
+
+func (p *parser) parseLocal() (Local, error) {
+	t := p.next()
+	if t.kind == tokWord && strings.HasPrefix(t.text, "%") {
+		if i, err := strconv.Atoi(t.text[1:]); err == nil && i >= 0 {
+			return Local(i), nil
+		}
+	}
+	return 0, p.errorf(t, "expected local but got %v", t)
+}
+
+func (p *parser) parseOperand() (Operand, error) {
+	t := p.peek()
+	if t.kind == tokWord {
+		switch {
+		case strings.HasPrefix(t.text, "%"):
+			l, err := p.parseLocal()
+			return Operand{Value: l}, err
+		case strings.HasPrefix(t.text, "$"):
+			p.next()
+			if i, err := strconv.Atoi(t.text[1:]); err == nil && i >= 0 {
+				return Operand{Value: StringIndex(i)}, nil
+			}
+		case t.text == "true" || t.text == "false":
+			p.next()
+			return Operand{Value: Bool(t.text == "true")}, nil
+		}
+	}
+	return Operand{}, p.errorf(t, "expected operand but got %v", t)
+}
@@ -0,0 +1,113 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ir
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	text := `# hand-written plan
+static {
+  string 0 "foo"
+  file 0 "test.rego"
+}
+plans {
+  plan "main" {
+    block {
+      DotStmt source=%0 key=$0 target=%2 @0:3:5
+      NotStmt {
+        EqualStmt a=%2 b=false
+      }
+      MakeObjectStmt target=%3
+      ResultSetAddStmt value=%3
+    }
+  }
+}
+`
+
+	policy, err := Parse(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stmts := policy.Plans.Plans[0].Blocks[0].Stmts
+	if len(stmts) != 4 {
+		t.Fatalf("expected 4 statements but got %d", len(stmts))
+	}
+
+	dot, ok := stmts[0].(*DotStmt)
+	if !ok {
+		t.Fatalf("expected DotStmt but got %T", stmts[0])
+	}
+	if dot.Source.Value != Input || dot.Key.Value != StringIndex(0) || dot.Target != 2 || dot.Row != 3 || dot.Col != 5 {
+		t.Fatalf("unexpected statement: %+v", dot)
+	}
+
+	var buf bytes.Buffer
+	if err := Pretty(&buf, policy); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := strings.TrimPrefix(text, "# hand-written plan\n")
+	if buf.String() != exp {
+		t.Fatalf("expected:\n%v\ngot:\n%v", exp, buf.String())
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		note string
+		text string
+		exp  string
+	}{
+		{
+			note: "unknown section",
+			text: `foo {}`,
+			exp:  `ir: line 1: unexpected "foo"`,
+		},
+		{
+			note: "unknown statement",
+			text: "plans {\n plan \"main\" { block { FooStmt } } }",
+			exp:  `ir: line 2: unrecognized statement type "FooStmt"`,
+		},
+		{
+			note: "unknown field",
+			text: `plans { plan "main" { block { MakeNullStmt foo=%1 } } }`,
+			exp:  `ir: line 1: unknown MakeNullStmt field "foo"`,
+		},
+		{
+			note: "bad local",
+			text: `plans { plan "main" { block { MakeNullStmt target=$1 } } }`,
+			exp:  `ir: line 1: expected local but got "$1"`,
+		},
+		{
+			note: "missing block",
+			text: `plans { plan "main" { block { NotStmt } } }`,
+			exp:  `ir: line 1: expected block of NotStmt but got "}"`,
+		},
+		{
+			note: "string index out of order",
+			text: `static { string 1 "foo" }`,
+			exp:  `ir: line 1: expected string index 0 but got 1`,
+		},
+		{
+			note: "unterminated",
+			text: `plans { plan "main" {`,
+			exp:  `ir: line 1: unexpected end of input`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tc.text))
+			if err == nil || err.Error() != tc.exp {
+				t.Fatalf("expected error %q but got %v", tc.exp, err)
+			}
+		})
+	}
+}
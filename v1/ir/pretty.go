@@ -5,40 +5,272 @@
 package ir
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
+	"reflect"
+	"strconv"
 	"strings"
 )
 
-// Pretty writes a human-readable representation of an IR object to w.
+// Pretty writes a human-readable, assembly-like representation of an IR object
+// to w. The representation of a *Policy can be read back with Parse.
+//
+// Statements are written one per line as the statement type followed by its
+// fields, e.g.:
+//
+//	DotStmt source=%0 key=$3 target=%4 @1:5:3
+//
+// Locals are written as %N, string constants as $N (indexing into the static
+// strings) and boolean constants as true or false. The location of a statement
+// is written as @file:row:col. Nested blocks are enclosed in braces.
 func Pretty(w io.Writer, x any) error {
+	pp := &prettyPrinter{w: w}
 
-	pp := &prettyPrinter{
-		depth: -1,
-		w:     w,
+	switch x := x.(type) {
+	case *Policy:
+		pp.writePolicy(x)
+	case *Static:
+		pp.writeStatic(x)
+	case *Plans:
+		pp.writePlans(x)
+	case *Funcs:
+		pp.writeFuncs(x)
+	case *Plan:
+		pp.writePlan(x)
+	case *Func:
+		pp.writeFunc(x)
+	case *Block:
+		pp.writeBlock(x)
+	case Stmt:
+		pp.writeStmt(x)
+	default:
+		return fmt.Errorf("ir: cannot pretty print %T", x)
 	}
-	return Walk(pp, x)
+
+	return pp.err
 }
 
 type prettyPrinter struct {
 	depth int
 	w     io.Writer
+	err   error
+}
+
+func (pp *prettyPrinter) writePolicy(x *Policy) {
+	if x.Static != nil {
+		pp.writeStatic(x.Static)
+	}
+	if x.Plans != nil {
+		pp.writePlans(x.Plans)
+	}
+	if x.Funcs != nil {
+		pp.writeFuncs(x.Funcs)
+	}
+}
+
+func (pp *prettyPrinter) writeStatic(x *Static) {
+	pp.open("static")
+	for i, s := range x.Strings {
+		pp.writeIndent("string %d %s", i, strconv.Quote(s.Value))
+	}
+	for _, f := range x.BuiltinFuncs {
+		decl, err := json.Marshal(f.Decl)
+		if err != nil && pp.err == nil {
+			pp.err = err
+		}
+		pp.writeIndent("builtin %s %s", strconv.Quote(f.Name), strconv.Quote(string(decl)))
+	}
+	for i, f := range x.Files {
+		pp.writeIndent("file %d %s", i, strconv.Quote(f.Value))
+	}
+	pp.close()
+}
+
+func (pp *prettyPrinter) writePlans(x *Plans) {
+	pp.open("plans")
+	for _, p := range x.Plans {
+		pp.writePlan(p)
+	}
+	pp.close()
+}
+
+func (pp *prettyPrinter) writeFuncs(x *Funcs) {
+	pp.open("funcs")
+	for _, f := range x.Funcs {
+		pp.writeFunc(f)
+	}
+	pp.close()
+}
+
+func (pp *prettyPrinter) writePlan(x *Plan) {
+	pp.open("plan " + strconv.Quote(x.Name))
+	for _, b := range x.Blocks {
+		pp.writeBlock(b)
+	}
+	pp.close()
+}
+
+func (pp *prettyPrinter) writeFunc(x *Func) {
+	var sb strings.Builder
+	sb.WriteString("func ")
+	sb.WriteString(strconv.Quote(x.Name))
+	sb.WriteString(" params=")
+	sb.WriteString(formatValue(reflect.ValueOf(x.Params)))
+	sb.WriteString(" return=")
+	sb.WriteString(formatValue(reflect.ValueOf(x.Return)))
+	if x.Path != nil {
+		sb.WriteString(" path=")
+		sb.WriteString(formatValue(reflect.ValueOf(x.Path)))
+	}
+	pp.open(sb.String())
+	for _, b := range x.Blocks {
+		pp.writeBlock(b)
+	}
+	pp.close()
+}
+
+func (pp *prettyPrinter) writeBlock(x *Block) {
+	pp.open("block")
+	pp.writeStmts(x)
+	pp.close()
+}
+
+func (pp *prettyPrinter) writeStmts(x *Block) {
+	if x == nil {
+		return
+	}
+	for _, s := range x.Stmts {
+		pp.writeStmt(s)
+	}
 }
 
-func (pp *prettyPrinter) Before(_ any) {
+func (pp *prettyPrinter) writeStmt(x Stmt) {
+	v := reflect.Indirect(reflect.ValueOf(x))
+	t := v.Type()
+
+	var sb strings.Builder
+	sb.WriteString(t.Name())
+
+	var nested reflect.Value
+
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if f.Anonymous {
+			continue
+		}
+		switch f.Type {
+		case blockType, blocksType:
+			nested = v.Field(i)
+			continue
+		}
+		sb.WriteByte(' ')
+		sb.WriteString(fieldName(f))
+		sb.WriteByte('=')
+		sb.WriteString(formatValue(v.Field(i)))
+	}
+
+	if loc := x.GetLocation(); loc.File != 0 || loc.Row != 0 || loc.Col != 0 {
+		fmt.Fprintf(&sb, " @%d:%d:%d", loc.File, loc.Row, loc.Col)
+	}
+
+	if !nested.IsValid() {
+		pp.writeIndent("%s", sb.String())
+		return
+	}
+
+	pp.open(sb.String())
+	switch b := nested.Interface().(type) {
+	case *Block:
+		pp.writeStmts(b)
+	case []*Block:
+		for _, b := range b {
+			pp.writeBlock(b)
+		}
+	}
+	pp.close()
+}
+
+func (pp *prettyPrinter) open(s string) {
+	pp.writeIndent("%s {", s)
 	pp.depth++
 }
 
-func (pp *prettyPrinter) After(_ any) {
+func (pp *prettyPrinter) close() {
 	pp.depth--
+	pp.writeIndent("}")
 }
 
-func (pp *prettyPrinter) Visit(x any) (Visitor, error) {
-	pp.writeIndent("%T %+v", x, x)
-	return pp, nil
+func (pp *prettyPrinter) writeIndent(f string, a ...any) {
+	if pp.err != nil {
+		return
+	}
+	pad := strings.Repeat("  ", pp.depth)
+	_, pp.err = fmt.Fprintf(pp.w, pad+f+"\n", a...)
 }
 
-func (pp *prettyPrinter) writeIndent(f string, a ...any) {
-	pad := strings.Repeat("| ", pp.depth)
-	fmt.Fprintf(pp.w, pad+f+"\n", a...)
+var (
+	blockType   = reflect.TypeFor[*Block]()
+	blocksType  = reflect.TypeFor[[]*Block]()
+	localType   = reflect.TypeFor[Local]()
+	operandType = reflect.TypeFor[Operand]()
+)
+
+// fieldName returns the name of a statement field in the text format, which
+// is the name used in the JSON encoding.
+func fieldName(f reflect.StructField) string {
+	if name, _, _ := strings.Cut(f.Tag.Get("json"), ","); name != "" {
+		return name
+	}
+	return strings.ToLower(f.Name)
+}
+
+func formatValue(v reflect.Value) string {
+	switch v.Type() {
+	case localType:
+		return formatLocal(Local(v.Int()))
+	case operandType:
+		return formatOperand(v.Interface().(Operand))
+	}
+
+	switch v.Kind() {
+	case reflect.Slice:
+		elems := make([]string, v.Len())
+		for i := range elems {
+			elems[i] = formatValue(v.Index(i))
+		}
+		return "[" + strings.Join(elems, " ") + "]"
+	case reflect.String:
+		return strconv.Quote(v.String())
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	}
+
+	return fmt.Sprintf("%v", v.Interface())
+}
+
+func formatLocal(l Local) string {
+	return "%" + strconv.Itoa(int(l))
+}
+
+func formatOperand(op Operand) string {
+	switch v := op.Value.(type) {
+	case Local:
+		return formatLocal(v)
+	case *Local:
+		return formatLocal(*v)
+	case StringIndex:
+		return "$" + strconv.Itoa(int(v))
+	case *StringIndex:
+		return "$" + strconv.Itoa(int(*v))
+	case Bool:
+		return strconv.FormatBool(bool(v))
+	case *Bool:
+		return strconv.FormatBool(bool(*v))
+	}
+	return fmt.Sprintf("%v", op.Value)
 }
@@ -5,6 +5,7 @@
 package bundle
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
 	"path"
@@ -13,10 +14,10 @@ import (
 
 	"github.com/open-policy-agent/opa/v1/plugins"
 
+	"github.com/open-policy-agent/opa/v1/ast"
 	"github.com/open-policy-agent/opa/v1/bundle"
 	"github.com/open-policy-agent/opa/v1/download"
 	"github.com/open-policy-agent/opa/v1/keys"
-	"github.com/open-policy-agent/opa/v1/util"
 )
 
 // ParseConfig validates the config and injects default values. This is
@@ -30,7 +31,7 @@ func ParseConfig(config []byte, services []string) (*Config, error) {
 
 	var parsedConfig Config
 
-	if err := util.Unmarshal(config, &parsedConfig); err != nil {
+	if err := plugins.DecodeConfig("bundle", config, &parsedConfig); err != nil {
 		return nil, err
 	}
 
@@ -99,7 +100,7 @@ func (b *ConfigBuilder) Parse() (*Config, error) {
 
 	var bundleConfigs map[string]*Source
 
-	if err := util.Unmarshal(b.raw, &bundleConfigs); err != nil {
+	if err := plugins.DecodeConfig("bundles", b.raw, &bundleConfigs); err != nil {
 		return nil, err
 	}
 
@@ -137,9 +138,9 @@ type Config struct {
 
 	Bundles map[string]*Source
 
-	Name    string  `json:"name"`    // Deprecated: Use `Bundles` map instead
-	Service string  `json:"service"` // Deprecated: Use `Bundles` map instead
-	Prefix  *string `json:"prefix"`  // Deprecated: Use `Bundles` map instead
+	Name    string  `json:"name"`                     // Deprecated: Use `Bundles` map instead
+	Service string  `json:"service"`                  // Deprecated: Use `Bundles` map instead
+	Prefix  *string `json:"prefix" default:"bundles"` // Deprecated: Use `Bundles` map instead
 }
 
 // Source is a configured bundle source to download bundles from
@@ -151,6 +152,26 @@ type Source struct {
 	Signing        *bundle.VerificationConfig `json:"signing"`
 	Persist        bool                       `json:"persist"`
 	SizeLimitBytes int64                      `json:"size_limit_bytes"`
+	Candidate      *CandidateSource           `json:"candidate,omitempty"`
+}
+
+// CandidateSource is a configured source of candidate revisions of a bundle.
+// Candidate bundles are downloaded, compiled and smoke tested in isolation, and
+// their readiness is reported in the status of the bundle, but they are never
+// activated. This lets control planes promote only verified revisions.
+type CandidateSource struct {
+	download.Config
+
+	Service    string      `json:"service"`
+	Resource   string      `json:"resource"`
+	SmokeTests []SmokeTest `json:"smoke_tests,omitempty"`
+}
+
+// SmokeTest is a query evaluated against a candidate bundle. It passes if the
+// query is defined.
+type SmokeTest struct {
+	Query string `json:"query"`
+	Input any    `json:"input,omitempty"`
 }
 
 // IsMultiBundle returns whether or not the config is the newer multi-bundle
@@ -206,6 +227,54 @@ func (c *Config) validateAndInjectDefaults(services []string, keys map[string]*k
 		if source.SizeLimitBytes <= 0 {
 			source.SizeLimitBytes = bundle.DefaultSizeLimitBytes
 		}
+
+		if source.Candidate != nil {
+			if err := c.validateAndInjectDefaultsCandidate(source, services); err != nil {
+				return fmt.Errorf("invalid candidate configuration for bundle %q: %w", name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) validateAndInjectDefaultsCandidate(source *Source, services []string) error {
+	candidate := source.Candidate
+
+	if candidate.Resource == "" {
+		return errors.New("missing resource")
+	}
+
+	if strings.HasPrefix(candidate.Resource, "file://") {
+		if _, err := url.Parse(candidate.Resource); err != nil {
+			return fmt.Errorf("invalid URL: %w", err)
+		}
+	} else {
+		if candidate.Service == "" {
+			candidate.Service = source.Service
+		}
+		svc, err := c.getServiceFromList(candidate.Service, services)
+		if err != nil {
+			return err
+		}
+		candidate.Service = svc
+	}
+
+	// Candidates are downloaded in the trigger mode of their bundle.
+	t, err := plugins.ValidateAndInjectDefaultsForTriggerMode(source.Trigger, candidate.Trigger)
+	if err != nil {
+		return err
+	}
+	candidate.Trigger = t
+
+	if err := candidate.Config.ValidateAndInjectDefaults(); err != nil {
+		return err
+	}
+
+	for i, test := range candidate.SmokeTests {
+		if _, err := ast.ParseBody(test.Query); err != nil {
+			return fmt.Errorf("invalid query of smoke test %d: %w", i, err)
+		}
 	}
 
 	return nil
@@ -216,11 +285,6 @@ func (c *Config) validateAndInjectDefaultsLegacy(services []string) error {
 		return fmt.Errorf("invalid bundle name %q", c.Name)
 	}
 
-	if c.Prefix == nil {
-		s := defaultBundlePathPrefix
-		c.Prefix = &s
-	}
-
 	var err error
 	c.Service, err = c.getServiceFromList(c.Service, services)
 	if err == nil {
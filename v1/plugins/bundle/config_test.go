@@ -229,6 +229,26 @@ func TestParseAndValidateBundlesConfig(t *testing.T) {
 			services:  []string{"s1"},
 			wantError: true,
 		},
+		{
+			conf:      `{"b1":{"service": "s1", "candidate": {"resource": "/b1/next", "smoke_tests": [{"query": "data.b1.allow", "input": {"user": "alice"}}]}}}`,
+			services:  []string{"s1"},
+			wantError: false,
+		},
+		{
+			conf:      `{"b1":{"service": "s1", "candidate": {"service": "s2", "resource": "/b1/next"}}}`,
+			services:  []string{"s1"},
+			wantError: true,
+		},
+		{
+			conf:      `{"b1":{"service": "s1", "candidate": {}}}`,
+			services:  []string{"s1"},
+			wantError: true,
+		},
+		{
+			conf:      `{"b1":{"service": "s1", "candidate": {"resource": "/b1/next", "smoke_tests": [{"query": "data.b1["}]}}}`,
+			services:  []string{"s1"},
+			wantError: true,
+		},
 	}
 
 	keys := map[string]*keys.Config{"foo": {Key: "secret"}}
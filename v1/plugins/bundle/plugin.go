@@ -20,6 +20,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/open-policy-agent/opa/internal/admission"
 	bundleUtils "github.com/open-policy-agent/opa/internal/bundle"
 	"github.com/open-policy-agent/opa/internal/ref"
 	"github.com/open-policy-agent/opa/v1/ast"
@@ -28,7 +29,9 @@ import (
 	"github.com/open-policy-agent/opa/v1/logging"
 	"github.com/open-policy-agent/opa/v1/metrics"
 	"github.com/open-policy-agent/opa/v1/plugins"
+	"github.com/open-policy-agent/opa/v1/rego"
 	"github.com/open-policy-agent/opa/v1/storage"
+	"github.com/open-policy-agent/opa/v1/storage/inmem"
 )
 
 // maxActivationRetry represents the maximum number of attempts
@@ -64,12 +67,14 @@ type Plugin struct {
 	listeners         map[any]func(Status)             // listeners to send status updates to
 	bulkListeners     map[any]func(map[string]*Status) // listeners to send aggregated status updates to
 	downloaders       map[string]Loader
+	candidates        map[string]Loader // loaders of candidate bundles
 	logger            logging.Logger
 	mtx               sync.Mutex
 	cfgMtx            sync.RWMutex
 	ready             bool
 	bundlePersistPath string
 	stopped           bool
+	internPool        *ast.InternPool // strings shared by bundles read since the last activation
 }
 
 // New returns a new Plugin with the given config.
@@ -86,9 +91,11 @@ func New(parsedConfig *Config, manager *plugins.Manager) *Plugin {
 		config:      *parsedConfig,
 		status:      initialStatus,
 		downloaders: make(map[string]Loader),
+		candidates:  make(map[string]Loader),
 		etags:       make(map[string]string),
 		ready:       false,
 		logger:      manager.Logger(),
+		internPool:  ast.NewInternPool(),
 	}
 
 	manager.UpdatePluginStatus(Name, &plugins.Status{State: plugins.StateNotReady})
@@ -108,11 +115,20 @@ func Lookup(manager *plugins.Manager) *Plugin {
 
 // Start runs the plugin. The plugin will periodically try to download bundles
 // from the configured service. When a new bundle is downloaded, the data and
-// policies are extracted and inserted into storage.
+// policies are extracted and inserted into storage. A stopped plugin can be
+// started again, e.g., by the plugin watchdog.
 func (p *Plugin) Start(ctx context.Context) error {
 	p.mtx.Lock()
 	defer p.mtx.Unlock()
 
+	if p.downloaders == nil {
+		p.downloaders = make(map[string]Loader)
+	}
+	if p.candidates == nil {
+		p.candidates = make(map[string]Loader)
+	}
+	p.stopped = false
+
 	var err error
 
 	p.bundlePersistPath, err = p.getBundlePersistPath()
@@ -127,15 +143,24 @@ func (p *Plugin) Start(ctx context.Context) error {
 		p.log(name).Info("Starting bundle loader.")
 		dl.Start(ctx)
 	}
+	for name, dl := range p.candidates {
+		p.log(name).Info("Starting candidate bundle loader.")
+		dl.Start(ctx)
+	}
 	return nil
 }
 
-// Stop stops the plugin.
+// Stop stops the plugin. An in-flight bundle activation is completed before
+// the bundle loaders are stopped, while bundles downloaded afterwards are not
+// activated anymore.
 func (p *Plugin) Stop(ctx context.Context) {
 	p.mtx.Lock()
 	stopDownloaders := map[string]Loader{}
 	maps.Copy(stopDownloaders, p.downloaders)
+	stopCandidates := map[string]Loader{}
+	maps.Copy(stopCandidates, p.candidates)
 	p.downloaders = nil
+	p.candidates = nil
 	p.stopped = true
 	p.mtx.Unlock()
 
@@ -143,6 +168,11 @@ func (p *Plugin) Stop(ctx context.Context) {
 		p.log(name).Info("Stopping bundle loader.")
 		dl.Stop(ctx)
 	}
+
+	for name, dl := range stopCandidates {
+		p.log(name).Info("Stopping candidate bundle loader.")
+		dl.Stop(ctx)
+	}
 }
 
 // Reconfigure notifies the plugin that it's configuration has changed.
@@ -175,12 +205,28 @@ func (p *Plugin) Reconfigure(ctx context.Context, config any) {
 			dl.Stop(ctx)
 		}
 	}
+	for name, dl := range p.candidates {
+		_, updated := updatedBundles[name]
+		_, deleted := deletedBundles[name]
+		if updated || deleted {
+			dl.Stop(ctx)
+		}
+	}
 
 	// Only lock p.mtx once we start changing the internal maps
 	// and downloader configs.
 	p.mtx.Lock()
 	defer p.mtx.Unlock()
 
+	// Cleanup existing candidate downloaders that are updated or deleted
+	for name := range p.candidates {
+		_, updated := updatedBundles[name]
+		_, deleted := deletedBundles[name]
+		if updated || deleted {
+			delete(p.candidates, name)
+		}
+	}
+
 	// Cleanup existing downloaders that are deleted
 	for name := range p.downloaders {
 		if _, deleted := deletedBundles[name]; deleted {
@@ -239,6 +285,13 @@ func (p *Plugin) Reconfigure(ctx context.Context, config any) {
 			p.etags[name] = etag
 			p.downloaders[name].Start(ctx)
 
+			p.status[name].Candidate = nil
+			if source.Candidate != nil {
+				p.log(name).Info("Starting candidate bundle loader.")
+				p.candidates[name] = p.newCandidateDownloader(name, source)
+				p.candidates[name].Start(ctx)
+			}
+
 			readyNow = false
 		}
 	}
@@ -262,6 +315,8 @@ func (p *Plugin) Trigger(ctx context.Context) error {
 	p.mtx.Lock()
 	downloaders := map[string]Loader{}
 	maps.Copy(downloaders, p.downloaders)
+	candidates := map[string]Loader{}
+	maps.Copy(candidates, p.candidates)
 	p.mtx.Unlock()
 
 	for name, d := range downloaders {
@@ -276,6 +331,13 @@ func (p *Plugin) Trigger(ctx context.Context) error {
 			}
 		}
 	}
+
+	// Failures to download or verify candidates are reported in the bundle
+	// status, but do not fail the trigger.
+	for _, d := range candidates {
+		_ = d.Trigger(ctx)
+	}
+
 	if len(errs) > 0 {
 		return errs
 	}
@@ -346,6 +408,10 @@ func (p *Plugin) initDownloaders(ctx context.Context) {
 
 		p.downloaders[name] = downloader
 		p.etags[name] = etag
+
+		if source.Candidate != nil {
+			p.candidates[name] = p.newCandidateDownloader(name, source)
+		}
 	}
 }
 
@@ -381,7 +447,7 @@ func (p *Plugin) loadAndActivateBundlesFromDisk(ctx context.Context) {
 
 	for name, src := range bundles {
 		if p.persistBundle(name, bundles) {
-			b, err := p.loadBundleFromDisk(p.bundlePersistPath, name, src)
+			b, err := p.loadBundleFromDisk(ctx, p.bundlePersistPath, name, src)
 			if err != nil {
 				p.log(name).Error("Failed to load bundle from disk: %v", err)
 				p.status[name].SetError(err)
@@ -430,6 +496,24 @@ func (p *Plugin) loadAndActivateBundlesFromDisk(ctx context.Context) {
 }
 
 func (p *Plugin) newDownloader(name string, source *Source, bundles map[string]*Source) Loader {
+	return p.newLoader(name, source, p.persistBundle(name, bundles), true, p.oneShot)
+}
+
+// newCandidateDownloader returns the loader of the candidate bundles of the
+// named bundle. Candidates are never persisted, and are always parsed, as they
+// are compiled in isolation.
+func (p *Plugin) newCandidateDownloader(name string, source *Source) Loader {
+	candidate := &Source{
+		Config:         source.Candidate.Config,
+		Service:        source.Candidate.Service,
+		Resource:       source.Candidate.Resource,
+		Signing:        source.Signing,
+		SizeLimitBytes: source.SizeLimitBytes,
+	}
+	return p.newLoader(name, candidate, false, false, p.candidateOneShot)
+}
+
+func (p *Plugin) newLoader(name string, source *Source, persist bool, lazy bool, f func(context.Context, string, download.Update)) Loader {
 
 	if u, err := url.Parse(source.Resource); err == nil && u.Scheme == "file" {
 		return &fileLoader{
@@ -437,8 +521,8 @@ func (p *Plugin) newDownloader(name string, source *Source, bundles map[string]*
 			path:             u.Path,
 			bvc:              source.Signing,
 			sizeLimitBytes:   source.SizeLimitBytes,
-			f:                p.oneShot,
-			bundleParserOpts: p.manager.ParserOptions(),
+			f:                f,
+			bundleParserOpts: p.parserOptions(),
 		}
 	}
 
@@ -447,7 +531,7 @@ func (p *Plugin) newDownloader(name string, source *Source, bundles map[string]*
 	path := source.Resource
 	callback := func(ctx context.Context, u download.Update) {
 		// wrap the callback to include the name of the bundle that was updated
-		p.oneShot(ctx, name, u)
+		f(ctx, name, u)
 	}
 	if strings.ToLower(client.Config().Type) == "oci" {
 		ociStorePath := filepath.Join(os.TempDir(), "opa", "oci") // use temporary folder /tmp/opa/oci
@@ -458,17 +542,27 @@ func (p *Plugin) newDownloader(name string, source *Source, bundles map[string]*
 			WithCallback(callback).
 			WithBundleVerificationConfig(source.Signing).
 			WithSizeLimitBytes(source.SizeLimitBytes).
-			WithBundlePersistence(p.persistBundle(name, bundles)).
-			WithBundleParserOpts(p.manager.ParserOptions())
+			WithBundlePersistence(persist).
+			WithBundleParserOpts(p.parserOptions()).
+			WithJitterSeed(p.manager.ID)
 	}
 	return download.New(conf, client, path).
 		WithCallback(callback).
 		WithBundleVerificationConfig(source.Signing).
 		WithSizeLimitBytes(source.SizeLimitBytes).
-		WithBundlePersistence(p.persistBundle(name, bundles)).
-		WithLazyLoadingMode(true).
+		WithBundlePersistence(persist).
+		WithLazyLoadingMode(lazy).
 		WithBundleName(name).
-		WithBundleParserOpts(p.manager.ParserOptions())
+		WithBundleParserOpts(p.parserOptions()).
+		WithJitterSeed(p.manager.ID)
+}
+
+// parserOptions returns the manager's parser options, with the plugin's intern
+// pool set so that strings are deduplicated across bundle reads.
+func (p *Plugin) parserOptions() ast.ParserOptions {
+	opts := p.manager.ParserOptions()
+	opts.InternPool = p.internPool
+	return opts
 }
 
 func (p *Plugin) oneShot(ctx context.Context, name string, u download.Update) {
@@ -476,7 +570,10 @@ func (p *Plugin) oneShot(ctx context.Context, name string, u download.Update) {
 	defer p.mtx.Unlock()
 
 	p.process(ctx, name, u)
+	p.notifyListeners(name)
+}
 
+func (p *Plugin) notifyListeners(name string) {
 	for _, listener := range p.listeners {
 		listener(*p.status[name])
 	}
@@ -521,6 +618,11 @@ func (p *Plugin) process(ctx context.Context, name string, u download.Update) {
 		p.status[name].Type = u.Bundle.Type()
 		p.status[name].LastSuccessfulDownload = p.status[name].LastSuccessfulRequest
 
+		if p.stopped {
+			p.log(name).Debug("Bundle activation skipped, plugin is stopped.")
+			return
+		}
+
 		p.status[name].Metrics.Timer(metrics.RegoLoadBundles).Start()
 		defer p.status[name].Metrics.Timer(metrics.RegoLoadBundles).Stop()
 
@@ -541,7 +643,7 @@ func (p *Plugin) process(ctx context.Context, name string, u download.Update) {
 		if u.Bundle.Type() == bundle.SnapshotBundleType && p.persistBundle(name, p.getBundlesCpy()) {
 			p.log(name).Debug("Persisting bundle to disk in progress.")
 
-			err := p.saveBundleToDisk(name, u.Raw)
+			err := p.saveBundleToDisk(ctx, name, u.Raw)
 			if err != nil {
 				p.log(name).Error("Persisting bundle to disk failed: %v", err)
 				p.status[name].SetError(err)
@@ -565,6 +667,10 @@ func (p *Plugin) process(ctx context.Context, name string, u download.Update) {
 		}
 		p.etags[name] = u.ETag
 
+		// The activated bundle holds on to the strings it needs, so drop the
+		// pool's references to keep it from growing across bundle updates.
+		p.internPool.Reset()
+
 		// If the plugin wasn't ready yet then check if we are now after activating this bundle.
 		p.checkPluginReadiness()
 		return
@@ -597,27 +703,79 @@ func (p *Plugin) checkPluginReadiness() {
 	}
 }
 
+// compileBundle compiles the bundle b with bundle.Compile, along with the
+// active policies outside of its roots, and checks its data against the
+// schemas declared in its manifest.
+func (p *Plugin) compileBundle(b *bundle.Bundle) error {
+	compiler := ast.NewCompiler().
+		WithCapabilities(p.manager.ParserOptions().Capabilities).
+		WithEnablePrintStatements(p.manager.EnablePrintStatements())
+
+	if active := p.manager.GetCompiler(); active != nil && b.Manifest.Roots != nil {
+		for id, module := range active.Modules {
+			if path, err := module.Package.Path.Ptr(); err == nil && !bundle.RootPathsContain(*b.Manifest.Roots, path) {
+				compiler.Modules[id] = module
+			}
+		}
+	}
+
+	if errs := bundle.Compile(compiler, b); len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
 func (p *Plugin) activate(ctx context.Context, name string, b *bundle.Bundle, isMultiBundle bool) error {
 	p.log(name).Debug("Bundle activation in progress (%v). Opening storage transaction.", b.Manifest.Revision)
 
 	params := storage.WriteParams
 	params.Context = storage.NewContext().WithMetrics(p.status[name].Metrics)
 
+	// A snapshot bundle with the same modules as the active one only changes
+	// data, so its activation can skip recompiling the policies. The modules
+	// hash of the active bundle is kept in the store, so that this also holds
+	// for the first activation after a restart with a persistent store. Custom
+	// activators always take the regular path.
+	var modulesHash string
+	if b.Type() == bundle.SnapshotBundleType && !bundle.HasExtension() {
+		modulesHash = bundle.ModulesHash(b)
+	}
+
+	// Snapshot bundles that declare data schemas are compiled on their own to
+	// check their data before anything is written to the store.
+	if b.Type() == bundle.SnapshotBundleType && len(b.Manifest.Schemas) > 0 {
+		if err := p.compileBundle(b); err != nil {
+			return err
+		}
+	}
+
 	err := storage.Txn(ctx, p.manager.Store, params, func(txn storage.Transaction) error {
 		p.log(name).Debug("Opened storage transaction (%v).", txn.ID())
 		defer p.log(name).Debug("Closing storage transaction (%v).", txn.ID())
 
 		// Compile the bundle modules with a new compiler and set it on the
 		// transaction params for use by onCommit hooks.
-		// If activating a delta bundle, use the manager's compiler which should have
-		// the polices compiled on it.
+		// If activating a delta bundle, or a snapshot bundle that only changes
+		// data, use the manager's compiler which should have the polices
+		// compiled on it.
+		var dataOnly bool
+		if modulesHash != "" {
+			active, err := bundle.ReadBundleModulesHashFromStore(ctx, p.manager.Store, txn, name)
+			if err != nil && !storage.IsNotFound(err) {
+				return err
+			}
+			dataOnly = active == modulesHash
+		}
+
 		var compiler *ast.Compiler
-		if b.Type() == bundle.DeltaBundleType {
+		if b.Type() == bundle.DeltaBundleType || dataOnly {
 			compiler = p.manager.GetCompiler()
 		}
 
 		if compiler == nil {
 			compiler = ast.NewCompiler()
+			dataOnly = false
 		}
 
 		compiler = compiler.WithPathConflictsCheck(storage.NonEmpty(ctx, p.manager.Store, txn)).
@@ -638,6 +796,11 @@ func (p *Plugin) activate(ctx context.Context, name string, b *bundle.Bundle, is
 			Metrics:       p.status[name].Metrics,
 			Bundles:       map[string]*bundle.Bundle{name: b},
 			ParserOptions: p.manager.ParserOptions(),
+			DataOnly:      dataOnly,
+		}
+
+		if dataOnly {
+			p.log(name).Debug("Bundle only changes data (%v). Skipping policy compilation.", b.Manifest.Revision)
 		}
 
 		if p.manager.Info != nil {
@@ -654,12 +817,30 @@ func (p *Plugin) activate(ctx context.Context, name string, b *bundle.Bundle, is
 			}
 		}
 
+		if decision := p.manager.Config.PolicyAdmissionDecisionRef(); decision != nil {
+			checker := &admission.Checker{
+				Decision: decision,
+				Compiler: p.manager.GetCompiler(),
+				Store:    p.manager.Store,
+				Txn:      txn,
+			}
+			opts.AdmitModule = checker.Check
+		}
+
 		if isMultiBundle {
 			activateErr = bundle.Activate(opts)
 		} else {
 			activateErr = bundle.ActivateLegacy(opts)
 		}
 
+		if activateErr == nil {
+			if modulesHash != "" {
+				activateErr = bundle.WriteModulesHashToStore(ctx, p.manager.Store, txn, name, modulesHash)
+			} else {
+				activateErr = bundle.EraseModulesHashFromStore(ctx, p.manager.Store, txn, name)
+			}
+		}
+
 		plugins.SetCompilerOnContext(params.Context, compiler)
 
 		resolvers, err := bundleUtils.LoadWasmResolversFromStore(ctx, p.manager.Store, txn, nil)
@@ -675,6 +856,114 @@ func (p *Plugin) activate(ctx context.Context, name string, b *bundle.Bundle, is
 	return err
 }
 
+// candidateOneShot records the result of downloading and verifying a candidate
+// of the named bundle in its status. Candidates are verified without holding
+// p.mtx, as they are never activated.
+func (p *Plugin) candidateOneShot(ctx context.Context, name string, u download.Update) {
+	var verifyErr error
+	if u.Error == nil && u.Bundle != nil {
+		verifyErr = p.verifyCandidate(ctx, name, u.Bundle)
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	status, ok := p.status[name]
+	if !ok {
+		return
+	}
+
+	// Status updates are sent to listeners as shallow copies, so the candidate
+	// status is replaced rather than modified.
+	var cs CandidateStatus
+	if status.Candidate != nil {
+		cs = *status.Candidate
+	}
+	cs.LastRequest = time.Now().UTC()
+
+	switch {
+	case u.Error != nil:
+		p.log(name).Error("Candidate bundle load failed: %v", u.Error)
+		cs.Ready = false
+		cs.SetError(u.Error)
+	case u.Bundle == nil:
+		p.log(name).Debug("Candidate bundle load skipped, server replied with not modified.")
+	case verifyErr != nil:
+		p.log(name).Error("Candidate bundle verification failed (%v): %v", u.Bundle.Manifest.Revision, verifyErr)
+		cs.LastSuccessfulDownload = cs.LastRequest
+		cs.Revision = u.Bundle.Manifest.Revision
+		cs.Ready = false
+		cs.SetError(verifyErr)
+	default:
+		p.log(name).Info("Candidate bundle verified successfully (%v).", u.Bundle.Manifest.Revision)
+		cs.LastSuccessfulDownload = cs.LastRequest
+		cs.LastSuccessfulValidation = cs.LastRequest
+		cs.Revision = u.Bundle.Manifest.Revision
+		cs.Ready = true
+		cs.SetError(nil)
+	}
+
+	status.Candidate = &cs
+	p.notifyListeners(name)
+}
+
+// verifyCandidate compiles the candidate b of the named bundle in isolation,
+// i.e., activates it in a new in-memory store, and evaluates the configured
+// smoke tests against it.
+func (p *Plugin) verifyCandidate(ctx context.Context, name string, b *bundle.Bundle) error {
+	source, ok := p.getBundlesCpy()[name]
+	if !ok || source.Candidate == nil {
+		return nil
+	}
+
+	store := inmem.New()
+	capabilities := p.manager.ParserOptions().Capabilities
+	compiler := ast.NewCompiler().
+		WithCapabilities(capabilities).
+		WithEnablePrintStatements(p.manager.EnablePrintStatements())
+
+	params := storage.WriteParams
+	params.Context = storage.NewContext()
+
+	err := storage.Txn(ctx, store, params, func(txn storage.Transaction) error {
+		return bundle.Activate(&bundle.ActivateOpts{
+			Ctx:           ctx,
+			Store:         store,
+			Txn:           txn,
+			TxnCtx:        params.Context,
+			Compiler:      compiler,
+			Metrics:       metrics.New(),
+			Bundles:       map[string]*bundle.Bundle{name: b},
+			ParserOptions: p.manager.ParserOptions(),
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, test := range source.Candidate.SmokeTests {
+		opts := []func(*rego.Rego){
+			rego.Query(test.Query),
+			rego.Compiler(compiler),
+			rego.Store(store),
+			rego.Capabilities(capabilities),
+		}
+		if test.Input != nil {
+			opts = append(opts, rego.Input(test.Input))
+		}
+
+		rs, err := rego.New(opts...).Eval(ctx)
+		if err != nil {
+			return fmt.Errorf("smoke test %q failed: %w", test.Query, err)
+		}
+		if len(rs) == 0 {
+			return fmt.Errorf("smoke test %q failed: undefined", test.Query)
+		}
+	}
+
+	return nil
+}
+
 func (*Plugin) persistBundle(name string, bundles map[string]*Source) bool {
 	bundleSrc := bundles[name]
 
@@ -710,14 +999,14 @@ func (p *Plugin) configDelta(newConfig *Config) (map[string]*Source, map[string]
 	return newBundles, updatedBundles, deletedBundles
 }
 
-func (p *Plugin) saveBundleToDisk(name string, raw io.Reader) error {
+func (p *Plugin) saveBundleToDisk(ctx context.Context, name string, raw io.Reader) error {
 
 	bundleName := getNormalizedBundleName(name)
 
 	bundleDir := filepath.Join(p.bundlePersistPath, bundleName)
 	bundleFile := filepath.Join(bundleDir, "bundle.tar.gz")
 
-	tmpFile, saveErr := saveCurrentBundleToDisk(bundleDir, raw)
+	tmpFile, saveErr := saveCurrentBundleToDisk(ctx, bundleDir, raw, p.manager.PersistenceKeyProvider())
 	if saveErr != nil {
 		p.log(name).Error("Failed to save new bundle to disk: %v", saveErr)
 
@@ -735,17 +1024,18 @@ func (p *Plugin) saveBundleToDisk(name string, raw io.Reader) error {
 	return os.Rename(tmpFile, bundleFile)
 }
 
-func saveCurrentBundleToDisk(path string, raw io.Reader) (string, error) {
-	return bundleUtils.SaveBundleToDisk(path, raw)
+func saveCurrentBundleToDisk(ctx context.Context, path string, raw io.Reader, kp storage.KeyProvider) (string, error) {
+	return bundleUtils.SaveEncryptedBundleToDisk(ctx, path, raw, kp)
 }
 
-func (p *Plugin) loadBundleFromDisk(path, name string, src *Source) (*bundle.Bundle, error) {
+func (p *Plugin) loadBundleFromDisk(ctx context.Context, path, name string, src *Source) (*bundle.Bundle, error) {
 	bundleName := getNormalizedBundleName(name)
 
+	var bvc *bundle.VerificationConfig
 	if src != nil {
-		return bundleUtils.LoadBundleFromDiskForRegoVersion(p.manager.ParserOptions().RegoVersion, path, bundleName, src.Signing)
+		bvc = src.Signing
 	}
-	return bundleUtils.LoadBundleFromDiskForRegoVersion(p.manager.ParserOptions().RegoVersion, path, bundleName, nil)
+	return bundleUtils.LoadEncryptedBundleFromDisk(ctx, p.manager.ParserOptions().RegoVersion, path, bundleName, bvc, p.manager.PersistenceKeyProvider())
 }
 
 func (p *Plugin) log(name string) logging.Logger {
@@ -866,6 +1156,7 @@ func (fl *fileLoader) oneShot(ctx context.Context) {
 		WithLazyLoadingMode(bundle.HasExtension()).
 		WithSizeLimitBytes(fl.sizeLimitBytes).
 		WithRegoVersion(fl.bundleParserOpts.RegoVersion).
+		WithInternPool(fl.bundleParserOpts.InternPool).
 		Read()
 	u.Error = err
 	if err == nil {
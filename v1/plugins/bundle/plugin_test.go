@@ -20,6 +20,7 @@ import (
 	"slices"
 	"sort"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -33,6 +34,8 @@ import (
 	"github.com/open-policy-agent/opa/v1/logging"
 	"github.com/open-policy-agent/opa/v1/metrics"
 	"github.com/open-policy-agent/opa/v1/plugins"
+	"github.com/open-policy-agent/opa/v1/rego"
+	"github.com/open-policy-agent/opa/v1/server/types"
 	"github.com/open-policy-agent/opa/v1/storage"
 	"github.com/open-policy-agent/opa/v1/storage/disk"
 	"github.com/open-policy-agent/opa/v1/storage/inmem"
@@ -106,12 +109,12 @@ func TestPluginOneShot(t *testing.T) {
 	}
 
 	data, err := manager.Store.Read(ctx, txn, storage.Path{})
-	expData := util.MustUnmarshalJSON([]byte(`{
+	expData := util.MustUnmarshalJSON(fmt.Appendf(nil, `{
 		"foo": {"bar": 1, "baz": "qux"},
 		"system": {
-			"bundles": {"test-bundle": {"etag": "foo", "manifest": {"revision": "quickbrownfaux", "roots": [""]}}}
+			"bundles": {"test-bundle": {"etag": "foo", "manifest": {"revision": "quickbrownfaux", "roots": [""]}, "modules_hash": %q}}
 		}
-	}`))
+	}`, bundle.ModulesHash(&b)))
 	if err != nil {
 		t.Fatal(err)
 	} else if !reflect.DeepEqual(data, expData) {
@@ -119,6 +122,537 @@ func TestPluginOneShot(t *testing.T) {
 	}
 }
 
+func TestPluginOneShotDataOnlyActivation(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	manager := getTestManager()
+	if err := manager.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Stop(ctx)
+
+	plugin := New(&Config{}, manager)
+	bundleName := "test-bundle"
+	plugin.status[bundleName] = &Status{Name: bundleName, Metrics: metrics.New()}
+	plugin.downloaders[bundleName] = download.New(download.Config{}, plugin.manager.Client(""), bundleName)
+
+	activate := func(revision, module, data string) {
+		t.Helper()
+
+		b := bundle.Bundle{
+			Manifest: bundle.Manifest{Revision: revision, Roots: &[]string{"foo"}},
+			Data:     util.MustUnmarshalJSON([]byte(data)).(map[string]any),
+			Modules: []bundle.ModuleFile{
+				{
+					Path:   "/foo/policy.rego",
+					Parsed: ast.MustParseModule(module),
+					Raw:    []byte(module),
+				},
+			},
+		}
+		b.Manifest.Init()
+
+		plugin.oneShot(ctx, bundleName, download.Update{Bundle: &b, Metrics: metrics.New()})
+
+		if msg := plugin.status[bundleName].Message; msg != "" {
+			t.Fatal(msg)
+		}
+	}
+
+	eval := func(exp string) {
+		t.Helper()
+
+		txn := storage.NewTransactionOrDie(ctx, manager.Store)
+		defer manager.Store.Abort(ctx, txn)
+
+		rs, err := rego.New(
+			rego.Query("data.foo.p"),
+			rego.Compiler(manager.GetCompiler()),
+			rego.Store(manager.Store),
+			rego.Transaction(txn),
+		).Eval(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if act := fmt.Sprint(rs[0].Expressions[0].Value); act != exp {
+			t.Fatalf("expected %v but got %v", exp, act)
+		}
+	}
+
+	module := "package foo\n\np := data.foo.x"
+
+	activate("rev1", module, `{"foo": {"x": 1}}`)
+	eval("1")
+	compiler := manager.GetCompiler()
+
+	// Only the data changes, the policies are not recompiled.
+	activate("rev2", module, `{"foo": {"x": 2}}`)
+	eval("2")
+	if manager.GetCompiler() != compiler {
+		t.Fatal("expected compiler to be reused")
+	}
+
+	// The policy changes, a new compiler is created.
+	activate("rev3", module+" + 1", `{"foo": {"x": 2}}`)
+	eval("3")
+	if manager.GetCompiler() == compiler {
+		t.Fatal("expected new compiler")
+	}
+}
+
+func TestPluginDataOnlyActivationDownloadedBundle(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	var mtx sync.Mutex
+	module := "package foo\n\np := data.foo.x"
+	data := map[string]any{"foo": map[string]any{"x": 1}}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		b := bundle.Bundle{
+			Manifest: bundle.Manifest{Roots: &[]string{"foo"}},
+			Data:     data,
+			Modules:  []bundle.ModuleFile{{URL: "/foo/policy.rego", Path: "/foo/policy.rego", Raw: []byte(module)}},
+		}
+		if err := bundle.NewWriter(w).Write(b); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer ts.Close()
+
+	manager := getTestManagerWithOpts(fmt.Appendf(nil, `{"services": {"default": {"url": %q}}}`, ts.URL))
+	if err := manager.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Stop(ctx)
+
+	var mode plugins.TriggerMode = "manual"
+
+	newPlugin := func() (*Plugin, chan map[string]*Status) {
+		plugin := New(&Config{
+			Bundles: map[string]*Source{
+				"test": {
+					Service:        "default",
+					SizeLimitBytes: int64(bundle.DefaultSizeLimitBytes),
+					Config:         download.Config{Trigger: &mode},
+				},
+			},
+		}, manager)
+
+		statusCh := make(chan map[string]*Status, 1)
+		plugin.RegisterBulkListener("test", func(st map[string]*Status) {
+			statusCh <- st
+		})
+
+		if err := plugin.Start(ctx); err != nil {
+			t.Fatal(err)
+		}
+		return plugin, statusCh
+	}
+
+	update := func(newModule string, x int) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		module = newModule
+		data = map[string]any{"foo": map[string]any{"x": x}}
+	}
+
+	activate := func(plugin *Plugin, statusCh chan map[string]*Status, exp string) {
+		t.Helper()
+
+		if err := plugin.Trigger(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if st := <-statusCh; st["test"].Message != "" {
+			t.Fatal(st["test"].Message)
+		}
+
+		txn := storage.NewTransactionOrDie(ctx, manager.Store)
+		defer manager.Store.Abort(ctx, txn)
+
+		rs, err := rego.New(
+			rego.Query("data.foo.p"),
+			rego.Compiler(manager.GetCompiler()),
+			rego.Store(manager.Store),
+			rego.Transaction(txn),
+		).Eval(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if act := fmt.Sprint(rs[0].Expressions[0].Value); act != exp {
+			t.Fatalf("expected %v but got %v", exp, act)
+		}
+	}
+
+	plugin, statusCh := newPlugin()
+	activate(plugin, statusCh, "1")
+	compiler := manager.GetCompiler()
+
+	// Only the data changes, the policies are not recompiled.
+	update(module, 2)
+	activate(plugin, statusCh, "2")
+	if manager.GetCompiler() != compiler {
+		t.Fatal("expected compiler to be reused")
+	}
+
+	// The modules hash is kept in the store, so a restarted plugin does not
+	// recompile the policies either.
+	plugin.Stop(ctx)
+	plugin, statusCh = newPlugin()
+	defer plugin.Stop(ctx)
+
+	update(module, 3)
+	activate(plugin, statusCh, "3")
+	if manager.GetCompiler() != compiler {
+		t.Fatal("expected compiler to be reused after restart")
+	}
+
+	// The policy changes, a new compiler is created.
+	update(module+" + 1", 3)
+	activate(plugin, statusCh, "4")
+	if manager.GetCompiler() == compiler {
+		t.Fatal("expected new compiler")
+	}
+}
+
+func TestPluginOneShotPolicyAdmission(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	manager := getTestManagerWithOpts([]byte(`{"policy_admission_decision": "/system/policy_admission/deny"}`))
+	if err := manager.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Stop(ctx)
+
+	plugin := New(&Config{}, manager)
+
+	activate := func(name string, module string) {
+		t.Helper()
+
+		plugin.status[name] = &Status{Name: name, Metrics: metrics.New()}
+		plugin.downloaders[name] = download.New(download.Config{}, plugin.manager.Client(""), name)
+
+		parsed := ast.MustParseModule(module)
+		root, _ := parsed.Package.Path.Ptr()
+
+		b := bundle.Bundle{
+			Manifest: bundle.Manifest{Revision: "r1", Roots: &[]string{root}},
+			Modules: []bundle.ModuleFile{
+				{Path: "/policy.rego", Parsed: parsed, Raw: []byte(module)},
+			},
+		}
+
+		plugin.oneShot(ctx, name, download.Update{Bundle: &b, Metrics: metrics.New()})
+	}
+
+	activate("admission", `package system.policy_admission
+
+deny contains "imports of input are not allowed" if {
+	some imp in input.module.imports
+	imp.path.value[0].value == "input"
+}`)
+
+	if errs := plugin.status["admission"].Errors; len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	activate("app", "package app\nimport input.x\np := x")
+
+	status := plugin.status["app"]
+	if status.Message == "" || !strings.Contains(status.Message, "imports of input are not allowed") {
+		t.Fatalf("expected admission error, got %v", status.Message)
+	}
+
+	activate("app", "package app\np := input.x")
+
+	if errs := plugin.status["app"].Errors; len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+}
+
+func TestPluginOneShotDataSchemas(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	manager := getTestManager()
+	if err := manager.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+	plugin := New(&Config{}, manager)
+
+	activate := func(name string, b bundle.Bundle) *Status {
+		t.Helper()
+
+		plugin.status[name] = &Status{Name: name, Metrics: metrics.New()}
+		plugin.downloaders[name] = download.New(download.Config{}, plugin.manager.Client(""), name)
+
+		b.Manifest.Init()
+		plugin.oneShot(ctx, name, download.Update{Bundle: &b, Metrics: metrics.New()})
+
+		return plugin.status[name]
+	}
+
+	lib := "package lib\n\nallowed(user) if user != \"mallory\""
+	if status := activate("lib", bundle.Bundle{
+		Manifest: bundle.Manifest{Revision: "r1", Roots: &[]string{"lib"}},
+		Modules: []bundle.ModuleFile{
+			{Path: "/lib/lib.rego", Parsed: ast.MustParseModule(lib), Raw: []byte(lib)},
+		},
+	}); len(status.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", status.Errors)
+	}
+
+	app := "package app\n\nallowed_users contains user if {\n\tsome user in data.app.users\n\tdata.lib.allowed(user)\n}"
+	newApp := func(users string) bundle.Bundle {
+		return bundle.Bundle{
+			Manifest: bundle.Manifest{
+				Revision: "r1",
+				Roots:    &[]string{"app"},
+				Schemas:  map[string]any{"app/users": map[string]any{"type": "array", "items": map[string]any{"type": "string"}}},
+			},
+			Data: util.MustUnmarshalJSON([]byte(`{"app": {"users": ` + users + `}}`)).(map[string]any),
+			Modules: []bundle.ModuleFile{
+				{Path: "/app/app.rego", Parsed: ast.MustParseModule(app), Raw: []byte(app)},
+			},
+		}
+	}
+
+	status := activate("app", newApp(`["alice", 7]`))
+	if len(status.Errors) != 1 || !strings.Contains(status.Errors[0].Error(), "data at path 'app/users' does not match schema") {
+		t.Fatalf("expected schema error, got %v", status.Errors)
+	}
+
+	if status := activate("app", newApp(`["alice", "bob"]`)); len(status.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", status.Errors)
+	}
+
+	// Downloaded bundles are read in lazy loading mode, their data is checked
+	// all the same.
+	lazy := func(b bundle.Bundle) bundle.Bundle {
+		t.Helper()
+
+		var buf bytes.Buffer
+		if err := bundle.NewWriter(&buf).UseModulePath(true).Write(b); err != nil {
+			t.Fatal(err)
+		}
+		lb, err := bundle.NewReader(&buf).WithLazyLoadingMode(true).Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return lb
+	}
+
+	status = activate("app", lazy(newApp(`["alice", 7]`)))
+	if len(status.Errors) != 1 || !strings.Contains(status.Errors[0].Error(), "data at path 'app/users' does not match schema") {
+		t.Fatalf("expected schema error, got %v", status.Errors)
+	}
+
+	if status := activate("app", lazy(newApp(`["alice", "carol"]`))); len(status.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", status.Errors)
+	}
+}
+
+func TestPluginOneShotDataSchemasCapabilities(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	caps := ast.CapabilitiesForThisVersion()
+	caps.Builtins = slices.DeleteFunc(caps.Builtins, func(bi *ast.Builtin) bool {
+		return bi.Name == ast.Count.Name
+	})
+
+	manager, err := plugins.New(nil, "test-instance-id", inmemtst.New(), plugins.WithParserOptions(ast.ParserOptions{Capabilities: caps}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	plugin := New(&Config{}, manager)
+	name := "app"
+	plugin.status[name] = &Status{Name: name, Metrics: metrics.New()}
+	plugin.downloaders[name] = download.New(download.Config{}, plugin.manager.Client(""), name)
+
+	module := "package app\n\nn := count(data.app.users)"
+	b := bundle.Bundle{
+		Manifest: bundle.Manifest{
+			Revision: "r1",
+			Roots:    &[]string{"app"},
+			Schemas:  map[string]any{"app/users": map[string]any{"type": "array"}},
+		},
+		Data: map[string]any{"app": map[string]any{"users": []any{"alice"}}},
+		Modules: []bundle.ModuleFile{
+			{Path: "/app/app.rego", Parsed: ast.MustParseModule(module), Raw: []byte(module)},
+		},
+	}
+	b.Manifest.Init()
+
+	plugin.oneShot(ctx, name, download.Update{Bundle: &b, Metrics: metrics.New()})
+
+	status := plugin.status[name]
+	if len(status.Errors) != 1 || !strings.Contains(status.Errors[0].Error(), "undefined function count") {
+		t.Fatalf("expected undefined function error, got %v", status.Errors)
+	}
+}
+
+func TestPluginOneShotAfterStop(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	manager := getTestManager()
+	plugin := New(&Config{}, manager)
+	bundleName := "test-bundle"
+	plugin.status[bundleName] = &Status{Name: bundleName, Metrics: metrics.New()}
+
+	plugin.Stop(ctx)
+
+	module := "package foo\n\ncorge=1"
+	b := bundle.Bundle{
+		Manifest: bundle.Manifest{Revision: "quickbrownfaux"},
+		Modules: []bundle.ModuleFile{
+			{Path: "/foo/bar", Parsed: ast.MustParseModule(module), Raw: []byte(module)},
+		},
+	}
+	b.Manifest.Init()
+
+	plugin.oneShot(ctx, bundleName, download.Update{Bundle: &b, Metrics: metrics.New()})
+
+	if status := plugin.status[bundleName]; !status.LastSuccessfulActivation.IsZero() {
+		t.Fatalf("expected bundle not to be activated after stop, got %v", status)
+	}
+
+	txn := storage.NewTransactionOrDie(ctx, manager.Store)
+	defer manager.Store.Abort(ctx, txn)
+
+	if ids, err := manager.Store.ListPolicies(ctx, txn); err != nil {
+		t.Fatal(err)
+	} else if len(ids) != 0 {
+		t.Fatalf("expected no policies, got %v", ids)
+	}
+}
+
+func TestPluginRestart(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	var mtx sync.Mutex
+	data := map[string]any{"p": "x1"}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		if err := bundle.NewWriter(w).Write(bundle.Bundle{Data: data}); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer ts.Close()
+
+	manager := getTestManagerWithOpts(fmt.Appendf(nil, `{"services": {"default": {"url": %q}}}`, ts.URL))
+	defer manager.Stop(ctx)
+
+	var mode plugins.TriggerMode = "manual"
+
+	plugin := New(&Config{
+		Bundles: map[string]*Source{
+			"test": {
+				Service:        "default",
+				SizeLimitBytes: int64(bundle.DefaultSizeLimitBytes),
+				Config:         download.Config{Trigger: &mode},
+			},
+		},
+	}, manager)
+
+	statusCh := make(chan map[string]*Status, 1)
+	plugin.RegisterBulkListener("test", func(st map[string]*Status) {
+		statusCh <- st
+	})
+
+	activate := func(exp string) {
+		t.Helper()
+
+		if err := plugin.Trigger(ctx); err != nil {
+			t.Fatal(err)
+		}
+		<-statusCh
+
+		result, err := storage.ReadOne(ctx, manager.Store, storage.Path{"p"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result != exp {
+			t.Fatalf("expected data to be %v but got %v", exp, result)
+		}
+	}
+
+	if err := plugin.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	activate("x1")
+
+	// Restart the plugin, like the plugin watchdog does.
+	plugin.Stop(ctx)
+	if err := plugin.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	mtx.Lock()
+	data = map[string]any{"p": "x2"}
+	mtx.Unlock()
+
+	activate("x2")
+
+	plugin.Stop(ctx)
+}
+
+func TestPluginOneShotResetsInternPool(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	manager := getTestManager()
+	plugin := New(&Config{}, manager)
+	bundleName := "test-bundle"
+	plugin.status[bundleName] = &Status{Name: bundleName, Metrics: metrics.New()}
+	plugin.downloaders[bundleName] = download.New(download.Config{}, plugin.manager.Client(""), bundleName)
+
+	if opts := plugin.parserOptions(); opts.InternPool != plugin.internPool {
+		t.Fatal("expected parser options to use the plugin's intern pool")
+	}
+
+	module := "package foo\n\ncorge=1"
+	var buf bytes.Buffer
+	if err := bundle.NewWriter(&buf).Write(bundle.Bundle{
+		Data:    util.MustUnmarshalJSON([]byte(`{"foo": {"bar": 1, "baz": "qux"}}`)).(map[string]any),
+		Modules: []bundle.ModuleFile{{URL: "/foo/bar.rego", Path: "/foo/bar.rego", Raw: []byte(module)}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := bundle.NewReader(&buf).WithInternPool(plugin.internPool).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if plugin.internPool.Len() == 0 {
+		t.Fatal("expected bundle read to populate the intern pool")
+	}
+
+	plugin.oneShot(ctx, bundleName, download.Update{Bundle: &b, Metrics: metrics.New(), Size: snapshotBundleSize})
+
+	ensurePluginState(t, plugin, plugins.StateOK)
+
+	if n := plugin.internPool.Len(); n != 0 {
+		t.Fatalf("expected intern pool to be reset on activation, got %d strings", n)
+	}
+}
+
 func TestPluginOneShotWithAstStore(t *testing.T) {
 	t.Parallel()
 
@@ -156,7 +690,7 @@ func TestPluginOneShotWithAstStore(t *testing.T) {
 	defer manager.Store.Abort(ctx, txn)
 
 	data, err := manager.Store.Read(ctx, txn, storage.Path{})
-	expData := ast.MustParseTerm(`{"foo": {"bar": 1, "baz": "qux"}, "system": {"bundles": {"test-bundle": {"etag": "foo", "manifest": {"revision": "quickbrownfaux", "roots": [""]}}}}}`)
+	expData := ast.MustParseTerm(fmt.Sprintf(`{"foo": {"bar": 1, "baz": "qux"}, "system": {"bundles": {"test-bundle": {"etag": "foo", "manifest": {"revision": "quickbrownfaux", "roots": [""]}, "modules_hash": %q}}}}`, bundle.ModulesHash(&b)))
 	if err != nil {
 		t.Fatal(err)
 	} else if ast.Compare(data, expData) != 0 {
@@ -962,12 +1496,12 @@ func TestPluginStartLazyLoadInMem(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			expected := `{
+			expected := fmt.Sprintf(`{
 				"p": "x1", "q": "x2",
 				"system": {
-					"bundles": {"test-1": {"etag": "", "manifest": {"revision": "", "roots": ["p", "authz"]}}, "test-2": {"etag": "", "manifest": {"revision": "", "roots": ["q"]}}}
+					"bundles": {"test-1": {"etag": "", "manifest": {"revision": "", "roots": ["p", "authz"]}, "modules_hash": %q}, "test-2": {"etag": "", "manifest": {"revision": "", "roots": ["q"]}, "modules_hash": %q}}
 				}
-			}`
+			}`, bundle.ModulesHash(&mockBundle1), bundle.ModulesHash(&mockBundle2))
 			if rm.readAst {
 				expData := ast.MustParseTerm(expected)
 				if ast.Compare(data, expData) != 0 {
@@ -1034,15 +1568,15 @@ func TestPluginOneShotDiskStorageMetrics(t *testing.T) {
 			t.Errorf("%s: expected %v, got %v", name, exp, act)
 		}
 		name = "disk_written_keys"
-		if exp, act := 6, met.Counter(name).Value(); act.(uint64) != uint64(exp) {
+		if exp, act := 7, met.Counter(name).Value(); act.(uint64) != uint64(exp) {
 			t.Errorf("%s: expected %v, got %v", name, exp, act)
 		}
 		name = "disk_read_keys"
-		if exp, act := 13, met.Counter(name).Value(); act.(uint64) != uint64(exp) {
+		if exp, act := 15, met.Counter(name).Value(); act.(uint64) != uint64(exp) {
 			t.Errorf("%s: expected %v, got %v", name, exp, act)
 		}
 		name = "disk_read_bytes"
-		if exp, act := 269, met.Counter(name).Value(); act.(uint64) != uint64(exp) {
+		if exp, act := 580, met.Counter(name).Value(); act.(uint64) != uint64(exp) {
 			t.Errorf("%s: expected %v, got %v", name, exp, act)
 		}
 		for _, timer := range []string{
@@ -1080,12 +1614,12 @@ func TestPluginOneShotDiskStorageMetrics(t *testing.T) {
 		}
 
 		data, err := manager.Store.Read(ctx, txn, storage.Path{})
-		expData := util.MustUnmarshalJSON([]byte(`{
+		expData := util.MustUnmarshalJSON(fmt.Appendf(nil, `{
 			"foo": {"bar": 1, "baz": "qux"},
 			"system": {
-				"bundles": {"test-bundle": {"etag": "", "manifest": {"revision": "quickbrownfaux", "roots": [""]}}}
+				"bundles": {"test-bundle": {"etag": "", "manifest": {"revision": "quickbrownfaux", "roots": [""]}, "modules_hash": %q}}
 			}
-		}`))
+		}`, bundle.ModulesHash(&b)))
 		if err != nil {
 			t.Fatal(err)
 		} else if !reflect.DeepEqual(data, expData) {
@@ -1447,7 +1981,7 @@ func TestPluginOneShotBundlePersistence(t *testing.T) {
 
 	ensurePluginState(t, plugin, plugins.StateOK)
 
-	result, err := plugin.loadBundleFromDisk(plugin.bundlePersistPath, bundleName, nil)
+	result, err := plugin.loadBundleFromDisk(ctx, plugin.bundlePersistPath, bundleName, nil)
 	if err != nil {
 		t.Fatal("unexpected error:", err)
 	}
@@ -1480,12 +2014,12 @@ func TestPluginOneShotBundlePersistence(t *testing.T) {
 	}
 
 	data, err := manager.Store.Read(ctx, txn, storage.Path{})
-	expData := util.MustUnmarshalJSON([]byte(`{
+	expData := util.MustUnmarshalJSON(fmt.Appendf(nil, `{
 		"foo": {"bar": 1, "baz": "qux"},
 		"system": {
-			"bundles": {"test-bundle": {"etag": "foo", "manifest": {"revision": "quickbrownfaux", "roots": [""]}}}
+			"bundles": {"test-bundle": {"etag": "foo", "manifest": {"revision": "quickbrownfaux", "roots": [""]}, "modules_hash": %q}}
 		}
-	}`))
+	}`, bundle.ModulesHash(&b)))
 	if err != nil {
 		t.Fatal(err)
 	} else if !reflect.DeepEqual(data, expData) {
@@ -1636,7 +2170,7 @@ corge contains 1 if {
 			} else {
 				ensurePluginState(t, plugin, plugins.StateOK)
 
-				result, err := plugin.loadBundleFromDisk(plugin.bundlePersistPath, bundleName, nil)
+				result, err := plugin.loadBundleFromDisk(ctx, plugin.bundlePersistPath, bundleName, nil)
 				if err != nil {
 					t.Fatal("unexpected error:", err)
 				}
@@ -1668,12 +2202,12 @@ corge contains 1 if {
 					t.Fatalf("Bad policy content. Exp:\n%v\n\nGot:\n\n%v", string(exp), string(bs))
 				}
 
-				expData := util.MustUnmarshalJSON([]byte(`{
+				expData := util.MustUnmarshalJSON(fmt.Appendf(nil, `{
 					"foo": {"bar": 1, "baz": "qux"},
 					"system": {
-						"bundles": {"test-bundle": {"etag": "foo", "manifest": {"revision": "quickbrownfaux", "roots": [""]}}}
+						"bundles": {"test-bundle": {"etag": "foo", "manifest": {"revision": "quickbrownfaux", "roots": [""]}, "modules_hash": %q}}
 					}
-				}`))
+				}`, bundle.ModulesHash(&b)))
 
 				data, err := manager.Store.Read(ctx, txn, storage.Path{})
 				if err != nil {
@@ -1929,7 +2463,7 @@ corge contains 1 if {
 			} else {
 				ensurePluginState(t, plugin, plugins.StateOK)
 
-				result, err := plugin.loadBundleFromDisk(plugin.bundlePersistPath, bundleName, nil)
+				result, err := plugin.loadBundleFromDisk(ctx, plugin.bundlePersistPath, bundleName, nil)
 				if err != nil {
 					t.Fatal("unexpected error:", err)
 				}
@@ -1976,10 +2510,10 @@ corge contains 1 if {
 				expData := util.MustUnmarshalJSON(fmt.Appendf(nil, `{
 					"foo": {"bar": 1, "baz": "qux"},
 					"system": {
-						"bundles": {"test-bundle": {"etag": "foo", "manifest": {"revision": "quickbrownfaux"%s, "roots": [""]}}}%s
+						"bundles": {"test-bundle": {"etag": "foo", "manifest": {"revision": "quickbrownfaux"%s, "roots": [""]}, "modules_hash": %q}}%s
 					}
 				}`,
-					manifestRegoVersion, moduleRegoVersion))
+					manifestRegoVersion, bundle.ModulesHash(&b), moduleRegoVersion))
 
 				if err != nil {
 					t.Fatal(err)
@@ -2053,7 +2587,7 @@ func TestPluginOneShotSignedBundlePersistence(t *testing.T) {
 	ensurePluginState(t, plugin, plugins.StateOK)
 
 	// load signed bundle from disk
-	result, err := plugin.loadBundleFromDisk(plugin.bundlePersistPath, bundleName, bundles[bundleName])
+	result, err := plugin.loadBundleFromDisk(ctx, plugin.bundlePersistPath, bundleName, bundles[bundleName])
 	if err != nil {
 		t.Fatal("unexpected error:", err)
 	}
@@ -2082,7 +2616,7 @@ func TestPluginOneShotSignedBundlePersistence(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	expData := util.MustUnmarshalJSON([]byte(`{"example1": {"foo": "bar"}, "example2": {"x": true}, "system": {"bundles": {"test-bundle": {"etag": "foo", "manifest": {"revision": "quickbrownfaux", "roots": [""]}}}}}`))
+	expData := util.MustUnmarshalJSON(fmt.Appendf(nil, `{"example1": {"foo": "bar"}, "example2": {"x": true}, "system": {"bundles": {"test-bundle": {"etag": "foo", "manifest": {"revision": "quickbrownfaux", "roots": [""]}, "modules_hash": %q}}}}`, bundle.ModulesHash(&b)))
 	if !reflect.DeepEqual(data, expData) {
 		t.Fatalf("Bad data content. Exp:\n%v\n\nGot:\n\n%v", expData, data)
 	}
@@ -2136,7 +2670,7 @@ func TestLoadAndActivateBundlesFromDisk(t *testing.T) {
 		t.Fatal("unexpected error:", err)
 	}
 
-	err := plugin.saveBundleToDisk(bundleName, &buf)
+	err := plugin.saveBundleToDisk(ctx, bundleName, &buf)
 	if err != nil {
 		t.Fatalf("unexpected error %v", err)
 	}
@@ -2162,12 +2696,12 @@ func TestLoadAndActivateBundlesFromDisk(t *testing.T) {
 	}
 
 	data, err := manager.Store.Read(ctx, txn, storage.Path{})
-	expData := util.MustUnmarshalJSON([]byte(`{
+	expData := util.MustUnmarshalJSON(fmt.Appendf(nil, `{
 		"foo": {"bar": 1, "baz": "qux"},
 		"system": {
-			"bundles": {"test-bundle": {"etag": "", "manifest": {"revision": "quickbrownfaux", "roots": [""]}}}
+			"bundles": {"test-bundle": {"etag": "", "manifest": {"revision": "quickbrownfaux", "roots": [""]}, "modules_hash": %q}}
 		}
-	}`))
+	}`, bundle.ModulesHash(&b)))
 	if err != nil {
 		t.Fatal(err)
 	} else if !reflect.DeepEqual(data, expData) {
@@ -2221,7 +2755,7 @@ func TestLoadAndActivateBundlesFromDiskReservedChars(t *testing.T) {
 		t.Fatal("unexpected error:", err)
 	}
 
-	err := plugin.saveBundleToDisk(bundleName, &buf)
+	err := plugin.saveBundleToDisk(ctx, bundleName, &buf)
 	if err != nil {
 		t.Fatalf("unexpected error %v", err)
 	}
@@ -2247,12 +2781,12 @@ func TestLoadAndActivateBundlesFromDiskReservedChars(t *testing.T) {
 	}
 
 	data, err := manager.Store.Read(ctx, txn, storage.Path{})
-	expData := util.MustUnmarshalJSON([]byte(`{
+	expData := util.MustUnmarshalJSON(fmt.Appendf(nil, `{
 		"foo": {"bar": 1, "baz": "qux"},
 		"system": {
-			"bundles": {"test?bundle=opa": {"etag": "", "manifest": {"revision": "quickbrownfaux", "roots": [""]}}}
+			"bundles": {"test?bundle=opa": {"etag": "", "manifest": {"revision": "quickbrownfaux", "roots": [""]}, "modules_hash": %q}}
 		}
-	}`))
+	}`, bundle.ModulesHash(&b)))
 	if err != nil {
 		t.Fatal(err)
 	} else if !reflect.DeepEqual(data, expData) {
@@ -2443,7 +2977,7 @@ corge contains 2 if {
 					t.Fatal("unexpected error:", err)
 				}
 
-				err = plugin.saveBundleToDisk(bundleName, &buf)
+				err = plugin.saveBundleToDisk(ctx, bundleName, &buf)
 				if err != nil {
 					t.Fatalf("unexpected error %v", err)
 				}
@@ -2495,12 +3029,12 @@ corge contains 2 if {
 						}
 					}
 
-					expData := util.MustUnmarshalJSON([]byte(`{
+					expData := util.MustUnmarshalJSON(fmt.Appendf(nil, `{
 						"foo": {"bar": 1, "baz": "qux"},
 						"system": {
-							"bundles": {"test-bundle": {"etag": "", "manifest": {"revision": "quickbrownfaux", "roots": [""]}}}
+							"bundles": {"test-bundle": {"etag": "", "manifest": {"revision": "quickbrownfaux", "roots": [""]}, "modules_hash": %q}}
 						}
-					}`))
+					}`, bundle.ModulesHash(&b)))
 
 					data, err := manager.Store.Read(ctx, txn, storage.Path{})
 					if err != nil {
@@ -2666,7 +3200,7 @@ corge contains 1 if {
 				t.Fatal("unexpected error:", err)
 			}
 
-			err = plugin.saveBundleToDisk(bundleName, &buf)
+			err = plugin.saveBundleToDisk(ctx, bundleName, &buf)
 			if err != nil {
 				t.Fatalf("unexpected error %v", err)
 			}
@@ -2733,19 +3267,19 @@ corge contains 1 if {
 					expData = util.MustUnmarshalJSON(fmt.Appendf(nil, `{
 						"foo": {"bar": 1, "baz": "qux"},
 						"system": {
-							"bundles": {"test-bundle": {"etag": "", "manifest": {"revision": "quickbrownfaux"%s, "roots": [""]}}},
+							"bundles": {"test-bundle": {"etag": "", "manifest": {"revision": "quickbrownfaux"%s, "roots": [""]}, "modules_hash": %q}},
 							"modules": {"test-bundle/foo/bar.rego": {"rego_version": %d}}
 						}
 					}`,
-						manifestRegoVersionStr, moduleRegoVersion))
+						manifestRegoVersionStr, bundle.ModulesHash(&b), moduleRegoVersion))
 				} else {
 					expData = util.MustUnmarshalJSON(fmt.Appendf(nil, `{
 						"foo": {"bar": 1, "baz": "qux"},
 						"system": {
-							"bundles": {"test-bundle": {"etag": "", "manifest": {"revision": "quickbrownfaux"%s, "roots": [""]}}}
+							"bundles": {"test-bundle": {"etag": "", "manifest": {"revision": "quickbrownfaux"%s, "roots": [""]}, "modules_hash": %q}}
 						}
 					}`,
-						manifestRegoVersionStr))
+						manifestRegoVersionStr, bundle.ModulesHash(&b)))
 				}
 
 				if err != nil {
@@ -2854,7 +3388,7 @@ is_one(x) if {
 		t.Fatal("unexpected error:", err)
 	}
 
-	err := plugin.saveBundleToDisk(bundleName, &buf1)
+	err := plugin.saveBundleToDisk(ctx, bundleName, &buf1)
 	if err != nil {
 		t.Fatalf("unexpected error %v", err)
 	}
@@ -2864,7 +3398,7 @@ is_one(x) if {
 		t.Fatal("unexpected error:", err)
 	}
 
-	err = plugin.saveBundleToDisk(bundleNameOther, &buf2)
+	err = plugin.saveBundleToDisk(ctx, bundleNameOther, &buf2)
 	if err != nil {
 		t.Fatalf("unexpected error %v", err)
 	}
@@ -2933,7 +3467,7 @@ allow if {
 		t.Fatal("unexpected error:", err)
 	}
 
-	err := plugin.saveBundleToDisk(bundleName, &buf)
+	err := plugin.saveBundleToDisk(ctx, bundleName, &buf)
 	if err != nil {
 		t.Fatalf("unexpected error %v", err)
 	}
@@ -4888,7 +5422,7 @@ func TestSaveBundleToDiskNew(t *testing.T) {
 	plugin := New(&Config{Bundles: bundles}, manager)
 	plugin.bundlePersistPath = filepath.Join(dir, ".opa")
 
-	err := plugin.saveBundleToDisk("foo", getTestRawBundle(t))
+	err := plugin.saveBundleToDisk(context.Background(), "foo", getTestRawBundle(t))
 	if err != nil {
 		t.Fatalf("unexpected error %v", err)
 	}
@@ -4909,7 +5443,7 @@ func TestSaveBundleToDiskNewConfiguredPersistDir(t *testing.T) {
 		t.Fatalf("unexpected error %v", err)
 	}
 
-	err = plugin.saveBundleToDisk("foo", getTestRawBundle(t))
+	err = plugin.saveBundleToDisk(context.Background(), "foo", getTestRawBundle(t))
 	if err != nil {
 		t.Fatalf("unexpected error %v", err)
 	}
@@ -4967,12 +5501,12 @@ func TestSaveBundleToDiskOverWrite(t *testing.T) {
 		t.Fatal("unexpected error:", err)
 	}
 
-	err = plugin.saveBundleToDisk("foo", &buf)
+	err = plugin.saveBundleToDisk(context.Background(), "foo", &buf)
 	if err != nil {
 		t.Fatalf("unexpected error %v", err)
 	}
 
-	actual, err := plugin.loadBundleFromDisk(plugin.bundlePersistPath, "foo", nil)
+	actual, err := plugin.loadBundleFromDisk(context.Background(), plugin.bundlePersistPath, "foo", nil)
 	if err != nil {
 		t.Fatalf("unexpected error %v", err)
 	}
@@ -4982,12 +5516,67 @@ func TestSaveBundleToDiskOverWrite(t *testing.T) {
 	}
 }
 
+func TestSaveBundleToDiskEncrypted(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	newPlugin := func(kp storage.KeyProvider) *Plugin {
+		t.Helper()
+		manager, err := plugins.New(nil, "test-instance-id", inmemtst.New(), plugins.WithPersistenceKeyProvider(kp))
+		if err != nil {
+			t.Fatal(err)
+		}
+		plugin := New(&Config{Bundles: map[string]*Source{}}, manager)
+		plugin.bundlePersistPath = dir
+		return plugin
+	}
+
+	key := storage.StaticKey([]byte("0123456789abcdef0123456789abcdef"))
+	plugin := newPlugin(key)
+
+	if err := plugin.saveBundleToDisk(ctx, "foo", getTestRawBundle(t)); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	bs, err := os.ReadFile(filepath.Join(dir, "foo", "bundle.tar.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.HasPrefix(bs, []byte{0x1f, 0x8b}) {
+		t.Fatal("expected bundle to be persisted encrypted, got gzipped tarball")
+	}
+
+	actual, err := plugin.loadBundleFromDisk(ctx, dir, "foo", nil)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !actual.Equal(getTestBundle(t)) {
+		t.Fatalf("expected the persisted bundle, got %v", actual)
+	}
+
+	for _, kp := range []storage.KeyProvider{nil, storage.StaticKey([]byte("fedcba9876543210fedcba9876543210"))} {
+		if _, err := newPlugin(kp).loadBundleFromDisk(ctx, dir, "foo", nil); err == nil {
+			t.Fatal("expected error loading the encrypted bundle without its key")
+		}
+	}
+
+	// Bundles persisted before encryption was enabled are still loaded.
+	if err := newPlugin(nil).saveBundleToDisk(ctx, "bar", getTestRawBundle(t)); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := plugin.loadBundleFromDisk(ctx, dir, "bar", nil); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
 func TestSaveCurrentBundleToDisk(t *testing.T) {
 	t.Parallel()
 
 	srcDir := t.TempDir()
 
-	bundlePath, err := saveCurrentBundleToDisk(srcDir, getTestRawBundle(t))
+	bundlePath, err := saveCurrentBundleToDisk(context.Background(), srcDir, getTestRawBundle(t), nil)
 	if err != nil {
 		t.Fatalf("unexpected error %v", err)
 	}
@@ -4996,7 +5585,7 @@ func TestSaveCurrentBundleToDisk(t *testing.T) {
 		t.Fatalf("unexpected error %v", err)
 	}
 
-	_, err = saveCurrentBundleToDisk(srcDir, nil)
+	_, err = saveCurrentBundleToDisk(context.Background(), srcDir, nil, nil)
 	if err == nil {
 		t.Fatal("expected error but got nil")
 	}
@@ -5014,7 +5603,7 @@ func TestLoadBundleFromDisk(t *testing.T) {
 	plugin := New(&Config{}, manager)
 
 	// no bundle on disk
-	_, err := plugin.loadBundleFromDisk("foo", "bar", nil)
+	_, err := plugin.loadBundleFromDisk(context.Background(), "foo", "bar", nil)
 	if err != nil {
 		t.Fatalf("unexpected error %v", err)
 	}
@@ -5032,7 +5621,7 @@ func TestLoadBundleFromDisk(t *testing.T) {
 
 	b := writeTestBundleToDisk(t, bundleDir, false)
 
-	result, err := plugin.loadBundleFromDisk(dir, bundleName, nil)
+	result, err := plugin.loadBundleFromDisk(context.Background(), dir, bundleName, nil)
 	if err != nil {
 		t.Fatal("unexpected error:", err)
 	}
@@ -5094,7 +5683,7 @@ p contains 1 if {
 		t.Fatalf("unexpected error %v", err)
 	}
 
-	result, err := plugin.loadBundleFromDisk(dir, bundleName, nil)
+	result, err := plugin.loadBundleFromDisk(context.Background(), dir, bundleName, nil)
 	if err != nil {
 		t.Fatal("unexpected error:", err)
 	}
@@ -5111,7 +5700,7 @@ func TestLoadSignedBundleFromDisk(t *testing.T) {
 	plugin := New(&Config{}, manager)
 
 	// no bundle on disk
-	_, err := plugin.loadBundleFromDisk("foo", "bar", nil)
+	_, err := plugin.loadBundleFromDisk(context.Background(), "foo", "bar", nil)
 	if err != nil {
 		t.Fatalf("unexpected error %v", err)
 	}
@@ -5133,7 +5722,7 @@ func TestLoadSignedBundleFromDisk(t *testing.T) {
 		Signing: bundle.NewVerificationConfig(map[string]*keys.Config{"foo": {Key: "secret", Algorithm: "HS256"}}, "foo", "", nil),
 	}
 
-	result, err := plugin.loadBundleFromDisk(dir, bundleName, &src)
+	result, err := plugin.loadBundleFromDisk(context.Background(), dir, bundleName, &src)
 	if err != nil {
 		t.Fatal("unexpected error:", err)
 	}
@@ -5237,6 +5826,158 @@ func TestPluginUsingFileLoader(t *testing.T) {
 	})
 }
 
+func TestPluginCandidateBundle(t *testing.T) {
+	t.Parallel()
+
+	// Capabilities of the manager without the startswith built-in function.
+	caps := ast.CapabilitiesForThisVersion()
+	caps.Builtins = slices.DeleteFunc(caps.Builtins, func(bi *ast.Builtin) bool {
+		return bi.Name == ast.StartsWith.Name
+	})
+
+	tests := []struct {
+		note         string
+		module       string
+		smokeTests   []SmokeTest
+		capabilities *ast.Capabilities
+		expReady     bool
+		expErr       string
+	}{
+		{
+			note:       "smoke tests pass",
+			module:     "package test\n\nallow if input.user == \"alice\"",
+			smokeTests: []SmokeTest{{Query: "data.test.allow", Input: map[string]any{"user": "alice"}}},
+			expReady:   true,
+		},
+		{
+			note:       "smoke test undefined",
+			module:     "package test\n\nallow if input.user == \"alice\"",
+			smokeTests: []SmokeTest{{Query: "data.test.allow", Input: map[string]any{"user": "bob"}}},
+			expErr:     `smoke test "data.test.allow" failed: undefined`,
+		},
+		{
+			note:   "compile error",
+			module: "package test\n\nallow if undefined_function(input)",
+			expErr: types.MsgCompileModuleError,
+		},
+		{
+			note:         "built-in function not in capabilities",
+			module:       "package test\n\nallow if startswith(input.user, \"a\")",
+			capabilities: caps,
+			expErr:       types.MsgCompileModuleError,
+		},
+		{
+			note:         "smoke test calls built-in function not in capabilities",
+			module:       "package test\n\nallow if input.user == \"alice\"",
+			smokeTests:   []SmokeTest{{Query: `startswith("alice", "a")`}},
+			capabilities: caps,
+			expErr:       types.MsgCompileModuleError,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			t.Parallel()
+
+			test.WithTempFS(map[string]string{}, func(dir string) {
+				write := func(file string, b bundle.Bundle) string {
+					name := path.Join(dir, file)
+					f, err := os.Create(name)
+					if err != nil {
+						t.Fatal(err)
+					}
+					defer f.Close()
+					if err := bundle.NewWriter(f).Write(b); err != nil {
+						t.Fatal(err)
+					}
+					return "file://" + name
+				}
+
+				active := write("active.tar.gz", bundle.Bundle{
+					Data: map[string]any{},
+					Modules: []bundle.ModuleFile{
+						{URL: "test.rego", Raw: []byte("package test\n\nallow := false")},
+					},
+				})
+				candidate := write("candidate.tar.gz", bundle.Bundle{
+					Manifest: bundle.Manifest{Revision: "v2"},
+					Data:     map[string]any{},
+					Modules: []bundle.ModuleFile{
+						{URL: "test.rego", Raw: []byte(tc.module)},
+					},
+				})
+
+				mgr := getTestManager()
+				if tc.capabilities != nil {
+					var err error
+					mgr, err = plugins.New(nil, "test-instance-id", inmemtst.New(), plugins.WithParserOptions(ast.ParserOptions{Capabilities: tc.capabilities}))
+					if err != nil {
+						t.Fatal(err)
+					}
+				}
+				p := New(&Config{Bundles: map[string]*Source{
+					"test": {
+						SizeLimitBytes: 1e5,
+						Resource:       active,
+						Candidate: &CandidateSource{
+							Resource:   candidate,
+							SmokeTests: tc.smokeTests,
+						},
+					},
+				}}, mgr)
+
+				ch := make(chan Status, 2)
+				p.Register("test", func(s Status) {
+					ch <- s
+				})
+
+				ctx := context.Background()
+				if err := p.Start(ctx); err != nil {
+					t.Fatal(err)
+				}
+				defer p.Stop(ctx)
+
+				// Wait for both the bundle and its candidate to be processed.
+				var cs *CandidateStatus
+				var activated bool
+				for cs == nil || !activated {
+					select {
+					case s := <-ch:
+						cs = s.Candidate
+						activated = !s.LastSuccessfulActivation.IsZero()
+					case <-time.After(10 * time.Second):
+						t.Fatal("timed out waiting for bundle status")
+					}
+				}
+
+				if cs.Revision != "v2" || cs.Ready != tc.expReady || !strings.Contains(cs.Message, tc.expErr) {
+					t.Fatalf("unexpected candidate status: %+v", cs)
+				}
+				if tc.expReady && cs.LastSuccessfulValidation.IsZero() {
+					t.Fatal("expected successful validation")
+				}
+
+				// The candidate is never activated.
+				txn := storage.NewTransactionOrDie(ctx, mgr.Store)
+				defer mgr.Store.Abort(ctx, txn)
+				if ids, err := mgr.Store.ListPolicies(ctx, txn); err != nil {
+					t.Fatal(err)
+				} else {
+					for _, id := range ids {
+						bs, err := mgr.Store.GetPolicy(ctx, txn, id)
+						if err != nil {
+							t.Fatal(err)
+						}
+						if string(bs) == tc.module {
+							t.Fatalf("expected candidate not to be activated, found %v", id)
+						}
+					}
+				}
+			})
+		})
+	}
+}
+
 func TestPluginUsingFileLoaderV1Compatible(t *testing.T) {
 	t.Parallel()
 
@@ -6841,12 +7582,12 @@ func TestPluginManualTriggerMultipleDiskStorage(t *testing.T) {
 		}
 
 		data, err := manager.Store.Read(ctx, txn, storage.Path{})
-		expData := util.MustUnmarshalJSON([]byte(`{
+		expData := util.MustUnmarshalJSON(fmt.Appendf(nil, `{
 			"p": "x1", "q": "x2",
 			"system": {
-				"bundles": {"test-1": {"etag": "", "manifest": {"revision": "", "roots": ["p", "authz"]}}, "test-2": {"etag": "", "manifest": {"revision": "", "roots": ["q"]}}}
+				"bundles": {"test-1": {"etag": "", "manifest": {"revision": "", "roots": ["p", "authz"]}, "modules_hash": %q}, "test-2": {"etag": "", "manifest": {"revision": "", "roots": ["q"]}, "modules_hash": %q}}
 			}
-		}`))
+		}`, bundle.ModulesHash(&mockBundle1), bundle.ModulesHash(&mockBundle2)))
 		if err != nil {
 			t.Fatal(err)
 		} else if !reflect.DeepEqual(data, expData) {
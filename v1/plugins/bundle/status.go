@@ -23,19 +23,63 @@ const (
 
 // Status represents the status of processing a bundle.
 type Status struct {
-	Name                     string          `json:"name"`
-	ActiveRevision           string          `json:"active_revision,omitempty"`
-	LastSuccessfulActivation time.Time       `json:"last_successful_activation,omitempty"`
-	Type                     string          `json:"type,omitempty"`
-	Size                     int             `json:"size,omitempty"`
-	LastSuccessfulDownload   time.Time       `json:"last_successful_download,omitempty"`
-	LastSuccessfulRequest    time.Time       `json:"last_successful_request,omitempty"`
-	LastRequest              time.Time       `json:"last_request,omitempty"`
-	Code                     string          `json:"code,omitempty"`
-	Message                  string          `json:"message,omitempty"`
-	Errors                   []error         `json:"errors,omitempty"`
-	Metrics                  metrics.Metrics `json:"metrics,omitempty"`
-	HTTPCode                 json.Number     `json:"http_code,omitempty"`
+	Name                     string           `json:"name"`
+	ActiveRevision           string           `json:"active_revision,omitempty"`
+	LastSuccessfulActivation time.Time        `json:"last_successful_activation,omitempty"`
+	Type                     string           `json:"type,omitempty"`
+	Size                     int              `json:"size,omitempty"`
+	LastSuccessfulDownload   time.Time        `json:"last_successful_download,omitempty"`
+	LastSuccessfulRequest    time.Time        `json:"last_successful_request,omitempty"`
+	LastRequest              time.Time        `json:"last_request,omitempty"`
+	Code                     string           `json:"code,omitempty"`
+	Message                  string           `json:"message,omitempty"`
+	Errors                   []error          `json:"errors,omitempty"`
+	Metrics                  metrics.Metrics  `json:"metrics,omitempty"`
+	HTTPCode                 json.Number      `json:"http_code,omitempty"`
+	ETag                     string           `json:"etag,omitempty"`
+	VerificationKeyID        string           `json:"verification_key_id,omitempty"`
+	Candidate                *CandidateStatus `json:"candidate,omitempty"`
+}
+
+// CandidateStatus represents the status of verifying the candidate revision of
+// a bundle, see CandidateSource. Ready is true if the last downloaded candidate
+// compiled and passed its smoke tests.
+type CandidateStatus struct {
+	Revision                 string      `json:"revision,omitempty"`
+	Ready                    bool        `json:"ready"`
+	LastSuccessfulValidation time.Time   `json:"last_successful_validation,omitempty"`
+	LastSuccessfulDownload   time.Time   `json:"last_successful_download,omitempty"`
+	LastRequest              time.Time   `json:"last_request,omitempty"`
+	Code                     string      `json:"code,omitempty"`
+	Message                  string      `json:"message,omitempty"`
+	Errors                   []error     `json:"errors,omitempty"`
+	HTTPCode                 json.Number `json:"http_code,omitempty"`
+}
+
+// SetError updates the candidate status object to reflect a failure to
+// download or verify the candidate. If err is nil, the error status is
+// cleared.
+func (s *CandidateStatus) SetError(err error) {
+	var status Status
+	status.SetError(err)
+	s.Code, s.HTTPCode, s.Message, s.Errors = status.Code, status.HTTPCode, status.Message, status.Errors
+}
+
+func (s *CandidateStatus) Equal(other *CandidateStatus) bool {
+	if s == nil || other == nil {
+		return s == nil && other == nil
+	}
+
+	equal := s.Revision == other.Revision &&
+		s.Ready == other.Ready &&
+		s.Code == other.Code &&
+		s.Message == other.Message &&
+		s.HTTPCode == other.HTTPCode &&
+		s.LastSuccessfulValidation.Equal(other.LastSuccessfulValidation) &&
+		s.LastSuccessfulDownload.Equal(other.LastSuccessfulDownload) &&
+		s.LastRequest.Equal(other.LastRequest)
+
+	return equal && equalErrors(s.Errors, other.Errors)
 }
 
 // SetActivateSuccess updates the status object to reflect a successful
@@ -109,23 +153,17 @@ func (s *Status) Equal(other *Status) bool {
 		s.Message == other.Message &&
 		s.HTTPCode == other.HTTPCode &&
 		s.ActiveRevision == other.ActiveRevision &&
+		s.ETag == other.ETag &&
+		s.VerificationKeyID == other.VerificationKeyID &&
 		s.LastSuccessfulActivation.Equal(other.LastSuccessfulActivation) &&
 		s.LastSuccessfulDownload.Equal(other.LastSuccessfulDownload) &&
 		s.LastSuccessfulRequest.Equal(other.LastSuccessfulRequest) &&
-		s.LastRequest.Equal(other.LastRequest)
-
-	if !equal {
-		return false
-	}
+		s.LastRequest.Equal(other.LastRequest) &&
+		s.Candidate.Equal(other.Candidate)
 
-	if len(s.Errors) != len(other.Errors) {
+	if !equal || !equalErrors(s.Errors, other.Errors) {
 		return false
 	}
-	for i := range s.Errors {
-		if s.Errors[i].Error() != other.Errors[i].Error() {
-			return false
-		}
-	}
 
 	if s.Metrics != nil && other.Metrics != nil && s.Metrics.All() != nil && other.Metrics.All() != nil {
 		return reflect.DeepEqual(s.Metrics.All(), other.Metrics.All())
@@ -133,3 +171,15 @@ func (s *Status) Equal(other *Status) bool {
 
 	return s.Metrics == nil && other.Metrics == nil
 }
+
+func equalErrors(a, b []error) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Error() != b[i].Error() {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,343 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package plugins
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/util"
+)
+
+// DecodeConfig decodes the raw JSON or YAML configuration of the plugin
+// named name into the value pointed to by v. Keys are matched to struct
+// fields by their json tags, and fields may declare how they are defaulted
+// and validated with the default and validate tags:
+//
+//	Mode    string        `json:"mode" default:"fast" validate:"oneof=fast slow"`
+//	Limit   *int64        `json:"limit" default:"100" validate:"min=1,max=1000"`
+//	Service string        `json:"service" validate:"required"`
+//	Timeout time.Duration `json:"timeout" default:"30s"`
+//
+// The default tag holds the value used when the key is not set, written as
+// it would be in the configuration. Defaults of nested struct fields apply
+// even if the parent key is not set, unless the parent is a pointer. The validate tag holds a comma-separated
+// list of rules: required, oneof=<space-separated values>, min=<n> and
+// max=<n>. Fields of type time.Duration accept duration strings, such as
+// "1m30s", as well as integers in nanoseconds.
+//
+// Errors refer to the offending value by its key path, prefixed with name,
+// e.g., "decision_logs.reporting.buffer_type: must be one of ...".
+func DecodeConfig(name string, raw []byte, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("%v: cannot decode into non-pointer %T", name, v)
+	}
+
+	var doc any
+	if err := util.Unmarshal(raw, &doc); err != nil {
+		return fmt.Errorf("%v: %w", name, err)
+	}
+
+	doc, err := prepareConfigValue(name, rv.Type().Elem(), doc)
+	if err != nil {
+		return err
+	}
+
+	bs, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("%v: %w", name, err)
+	}
+
+	if err := util.NewJSONDecoder(bytes.NewReader(bs)).Decode(v); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return fmt.Errorf("%v: expected %v but got %v", configPath(name, typeErr.Field), typeErr.Type, typeErr.Value)
+		}
+		return fmt.Errorf("%v: %w", name, err)
+	}
+
+	return nil
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// prepareConfigValue returns a copy of the decoded configuration value x at
+// path, with defaults injected, durations converted to nanoseconds and
+// validation rules checked, according to type t. Values that do not match t
+// are returned unchanged and reported when decoding into t.
+func prepareConfigValue(path string, t reflect.Type, x any) (any, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if x == nil {
+		return nil, nil
+	}
+
+	if t == durationType {
+		s, ok := x.(string)
+		if !ok {
+			return x, nil
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("%v: invalid duration %q", path, s)
+		}
+		return json.Number(strconv.FormatInt(int64(d), 10)), nil
+	}
+
+	// Types that decode themselves are left alone.
+	if pt := reflect.PointerTo(t); pt.Implements(jsonUnmarshalerType) || pt.Implements(textUnmarshalerType) {
+		return x, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := x.(map[string]any)
+		if !ok {
+			return x, nil
+		}
+		cpy := maps.Clone(obj)
+		if err := prepareConfigFields(path, t, cpy); err != nil {
+			return nil, err
+		}
+		return cpy, nil
+	case reflect.Slice, reflect.Array:
+		arr, ok := x.([]any)
+		if !ok {
+			return x, nil
+		}
+		cpy := make([]any, len(arr))
+		for i := range arr {
+			var err error
+			cpy[i], err = prepareConfigValue(fmt.Sprintf("%v[%d]", path, i), t.Elem(), arr[i])
+			if err != nil {
+				return nil, err
+			}
+		}
+		return cpy, nil
+	case reflect.Map:
+		obj, ok := x.(map[string]any)
+		if !ok {
+			return x, nil
+		}
+		cpy := make(map[string]any, len(obj))
+		for k, elem := range obj {
+			var err error
+			cpy[k], err = prepareConfigValue(configPath(path, k), t.Elem(), elem)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return cpy, nil
+	}
+
+	return x, nil
+}
+
+func prepareConfigFields(path string, t reflect.Type, obj map[string]any) error {
+	for i := range t.NumField() {
+		f := t.Field(i)
+
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+
+		// Fields of embedded structs are promoted, like encoding/json does.
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if err := prepareConfigFields(path, ft, obj); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		key := configKey(obj, name)
+		p := configPath(path, key)
+		rules := parseConfigRules(f.Tag.Get("validate"))
+
+		val := obj[key]
+		if val == nil {
+			def, ok := f.Tag.Lookup("default")
+			switch {
+			case ok:
+				var err error
+				if val, err = configLiteral(f.Type, def); err != nil {
+					return fmt.Errorf("%v: invalid default %q: %w", p, def, err)
+				}
+			case rules.required:
+				return fmt.Errorf("%v: missing required value", p)
+			case isConfigStruct(f.Type):
+				// Defaults of nested structs apply even if the struct is not set.
+				val = map[string]any{}
+			default:
+				continue
+			}
+		}
+
+		val, err := prepareConfigValue(p, f.Type, val)
+		if err != nil {
+			return err
+		}
+
+		if err := rules.check(p, f.Type, val); err != nil {
+			return err
+		}
+
+		obj[key] = val
+	}
+
+	return nil
+}
+
+// isConfigStruct reports if t is a struct, not behind a pointer, that is
+// decoded field by field.
+func isConfigStruct(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct || t == durationType {
+		return false
+	}
+	pt := reflect.PointerTo(t)
+	return !pt.Implements(jsonUnmarshalerType) && !pt.Implements(textUnmarshalerType)
+}
+
+// configKey returns the key in obj that matches the field name. Like
+// encoding/json, exact matches are preferred over case-insensitive ones.
+func configKey(obj map[string]any, name string) string {
+	if _, ok := obj[name]; ok {
+		return name
+	}
+	for k := range obj {
+		if strings.EqualFold(k, name) {
+			return k
+		}
+	}
+	return name
+}
+
+func configPath(path, key string) string {
+	if key == "" {
+		return path
+	}
+	return path + "." + key
+}
+
+// configLiteral returns the value of s, as written in the configuration, for
+// a field of type t. Strings need not be quoted.
+func configLiteral(t reflect.Type, s string) (any, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.String {
+		return s, nil
+	}
+	var x any
+	if err := util.Unmarshal([]byte(s), &x); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type configRules struct {
+	required bool
+	oneof    []string
+	min, max string
+}
+
+func parseConfigRules(tag string) configRules {
+	var r configRules
+	for _, rule := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch name {
+		case "required":
+			r.required = true
+		case "oneof":
+			r.oneof = strings.Fields(arg)
+		case "min":
+			r.min = arg
+		case "max":
+			r.max = arg
+		}
+	}
+	return r
+}
+
+func (r configRules) check(path string, t reflect.Type, val any) error {
+	if len(r.oneof) > 0 {
+		if s := fmt.Sprint(val); !slices.Contains(r.oneof, s) {
+			quoted := make([]string, len(r.oneof))
+			for i, o := range r.oneof {
+				quoted[i] = strconv.Quote(o)
+			}
+			return fmt.Errorf("%v: must be one of %v but got %q", path, strings.Join(quoted, ", "), s)
+		}
+	}
+
+	if r.min == "" && r.max == "" {
+		return nil
+	}
+
+	n, ok := configNumber(val)
+	if !ok {
+		return nil
+	}
+
+	for _, bound := range []struct {
+		arg, op string
+		fail    func(n, b float64) bool
+	}{
+		{r.min, ">=", func(n, b float64) bool { return n < b }},
+		{r.max, "<=", func(n, b float64) bool { return n > b }},
+	} {
+		if bound.arg == "" {
+			continue
+		}
+		x, err := configLiteral(t, bound.arg)
+		if err == nil {
+			x, err = prepareConfigValue(path, t, x)
+		}
+		b, ok := configNumber(x)
+		if err != nil || !ok {
+			return fmt.Errorf("%v: invalid bound %q", path, bound.arg)
+		}
+		if bound.fail(n, b) {
+			return fmt.Errorf("%v: must be %v %v", path, bound.op, bound.arg)
+		}
+	}
+
+	return nil
+}
+
+func configNumber(x any) (float64, bool) {
+	num, ok := x.(json.Number)
+	if !ok {
+		return 0, false
+	}
+	f, err := num.Float64()
+	return f, err == nil
+}
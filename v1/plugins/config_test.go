@@ -0,0 +1,170 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package plugins
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testDecodeSinkConfig struct {
+	Path  string `json:"path" validate:"required"`
+	Level string `json:"level" default:"info" validate:"oneof=debug info error"`
+}
+
+type testDecodeOutputConfig struct {
+	Format string `json:"format" default:"json" validate:"oneof=json text"`
+}
+
+type testDecodeEmbedded struct {
+	Retries int `json:"retries" default:"3" validate:"min=0,max=10"`
+}
+
+type testDecodeConfig struct {
+	testDecodeEmbedded
+
+	Mode     string                           `json:"mode" default:"fast" validate:"oneof=fast slow"`
+	Limit    *int64                           `json:"limit" default:"100" validate:"min=1"`
+	Enabled  bool                             `json:"enabled" default:"true"`
+	Timeout  time.Duration                    `json:"timeout" default:"30s" validate:"max=1m"`
+	Interval *time.Duration                   `json:"interval,omitempty"`
+	Trigger  *TriggerMode                     `json:"trigger" default:"periodic"`
+	Sinks    []testDecodeSinkConfig           `json:"sinks"`
+	Named    map[string]*testDecodeSinkConfig `json:"named"`
+	Output   testDecodeOutputConfig           `json:"output"`
+}
+
+func TestDecodeConfig(t *testing.T) {
+	limit := int64(5)
+	interval := 1500 * time.Millisecond
+	trigger := TriggerManual
+
+	tests := []struct {
+		note   string
+		config string
+		exp    testDecodeConfig
+	}{
+		{
+			note:   "defaults",
+			config: `{}`,
+			exp: testDecodeConfig{
+				testDecodeEmbedded: testDecodeEmbedded{Retries: 3},
+				Mode:               "fast",
+				Limit:              func() *int64 { x := int64(100); return &x }(),
+				Enabled:            true,
+				Timeout:            30 * time.Second,
+				Trigger:            func() *TriggerMode { x := TriggerPeriodic; return &x }(),
+				Output:             testDecodeOutputConfig{Format: "json"},
+			},
+		},
+		{
+			note: "explicit values",
+			config: `
+retries: 0
+mode: slow
+limit: 5
+enabled: false
+timeout: 1000
+interval: 1.5s
+trigger: manual
+sinks:
+- path: /a
+named:
+  b:
+    path: /b
+    level: error
+output:
+  format: text
+`,
+			exp: testDecodeConfig{
+				Mode:     "slow",
+				Limit:    &limit,
+				Timeout:  1000,
+				Interval: &interval,
+				Trigger:  &trigger,
+				Sinks:    []testDecodeSinkConfig{{Path: "/a", Level: "info"}},
+				Named:    map[string]*testDecodeSinkConfig{"b": {Path: "/b", Level: "error"}},
+				Output:   testDecodeOutputConfig{Format: "text"},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			var c testDecodeConfig
+			if err := DecodeConfig("test", []byte(tc.config), &c); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(c, tc.exp) {
+				t.Fatalf("expected %+v but got %+v", tc.exp, c)
+			}
+		})
+	}
+}
+
+func TestDecodeConfigErrors(t *testing.T) {
+	tests := []struct {
+		note   string
+		config string
+		exp    string
+	}{
+		{
+			note:   "oneof",
+			config: `{"mode": "medium"}`,
+			exp:    `test.mode: must be one of "fast", "slow" but got "medium"`,
+		},
+		{
+			note:   "min",
+			config: `{"limit": 0}`,
+			exp:    `test.limit: must be >= 1`,
+		},
+		{
+			note:   "max in embedded struct",
+			config: `{"retries": 11}`,
+			exp:    `test.retries: must be <= 10`,
+		},
+		{
+			note:   "max duration",
+			config: `{"timeout": "2m"}`,
+			exp:    `test.timeout: must be <= 1m`,
+		},
+		{
+			note:   "invalid duration",
+			config: `{"interval": "soon"}`,
+			exp:    `test.interval: invalid duration "soon"`,
+		},
+		{
+			note:   "required in slice",
+			config: `{"sinks": [{"path": "/a"}, {"level": "debug"}]}`,
+			exp:    `test.sinks[1].path: missing required value`,
+		},
+		{
+			note:   "oneof in map",
+			config: `{"named": {"b": {"path": "/b", "level": "trace"}}}`,
+			exp:    `test.named.b.level: must be one of "debug", "info", "error" but got "trace"`,
+		},
+		{
+			note:   "oneof in nested struct",
+			config: `{"output": {"format": "xml"}}`,
+			exp:    `test.output.format: must be one of "json", "text" but got "xml"`,
+		},
+		{
+			note:   "type mismatch",
+			config: `{"enabled": "yes"}`,
+			exp:    `test.enabled: expected bool but got string`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			var c testDecodeConfig
+			err := DecodeConfig("test", []byte(tc.config), &c)
+			if err == nil || err.Error() != tc.exp {
+				t.Fatalf("expected error %q but got %v", tc.exp, err)
+			}
+		})
+	}
+}
@@ -17,19 +17,20 @@ import (
 
 	"github.com/open-policy-agent/opa/v1/ast"
 	"github.com/open-policy-agent/opa/v1/download"
-	"github.com/open-policy-agent/opa/v1/util"
+	"github.com/open-policy-agent/opa/v1/plugins"
 )
 
 // Config represents the configuration for the discovery feature.
 type Config struct {
 	download.Config                            // bundle downloader configuration
-	Name            *string                    `json:"name"`               // Deprecated: name of the discovery bundle, use `Resource` instead.
-	Prefix          *string                    `json:"prefix,omitempty"`   // Deprecated: use `Resource` instead.
-	Decision        *string                    `json:"decision"`           // the name of the query to run on the bundle to get the config
-	Service         string                     `json:"service"`            // the name of the service used to download discovery bundle from
-	Resource        *string                    `json:"resource,omitempty"` // the resource path which will be downloaded from the service
-	Signing         *bundle.VerificationConfig `json:"signing,omitempty"`  // configuration used to verify a signed bundle
-	Persist         bool                       `json:"persist"`            // control whether to persist activated discovery bundle to disk
+	Name            *string                    `json:"name"`                               // Deprecated: name of the discovery bundle, use `Resource` instead.
+	Prefix          *string                    `json:"prefix,omitempty" default:"bundles"` // Deprecated: use `Resource` instead.
+	Decision        *string                    `json:"decision"`                           // the name of the query to run on the bundle to get the config
+	Service         string                     `json:"service"`                            // the name of the service used to download discovery bundle from
+	Resource        *string                    `json:"resource,omitempty"`                 // the resource path which will be downloaded from the service
+	Signing         *bundle.VerificationConfig `json:"signing,omitempty"`                  // configuration used to verify a signed bundle
+	Persist         bool                       `json:"persist"`                            // control whether to persist activated discovery bundle to disk
+	Scopes          []string                   `json:"scopes,omitempty"`                   // portions of the discovered configuration to apply, e.g. `bundles` or `plugins.my_plugin`
 
 	service string
 	path    string
@@ -74,7 +75,7 @@ func (b *ConfigBuilder) Parse() (*Config, error) {
 
 	var result Config
 
-	if err := util.Unmarshal(b.raw, &result); err != nil {
+	if err := plugins.DecodeConfig("discovery", b.raw, &result); err != nil {
 		return nil, err
 	}
 
@@ -92,6 +93,12 @@ func (c *Config) validateAndInjectDefaults(services []string, confKeys map[strin
 		return errors.New("missing required discovery.resource field")
 	}
 
+	for _, scope := range c.Scopes {
+		if scope == "" || scope == "discovery" || strings.HasPrefix(scope, "discovery.") || slices.Contains(strings.Split(scope, "."), "") {
+			return fmt.Errorf("invalid discovery.scopes entry %q", scope)
+		}
+	}
+
 	// make a copy of the keys map
 	cpy := map[string]*keys.Config{}
 	maps.Copy(cpy, confKeys)
@@ -108,11 +115,6 @@ func (c *Config) validateAndInjectDefaults(services []string, confKeys map[strin
 	if c.Resource != nil {
 		c.path = *c.Resource
 	} else {
-		if c.Prefix == nil {
-			s := defaultDiscoveryPathPrefix
-			c.Prefix = &s
-		}
-
 		c.path = fmt.Sprintf("%v/%v", strings.Trim(*c.Prefix, "/"), strings.Trim(*c.Name, "/"))
 	}
 
@@ -146,7 +148,3 @@ func (*Config) getServiceFromList(service string, services []string) (string, er
 	}
 	return service, fmt.Errorf("service name %q not found", service)
 }
-
-const (
-	defaultDiscoveryPathPrefix = "bundles"
-)
@@ -62,6 +62,21 @@ func TestConfigValidation(t *testing.T) {
 			services: []string{"s1"},
 			wantErr:  true,
 		},
+		{
+			input:    `{"name": "a/b/c", "scopes": ["bundles", "plugins.my_plugin"]}`,
+			services: []string{"s1"},
+			wantErr:  false,
+		},
+		{
+			input:    `{"name": "a/b/c", "scopes": ["discovery"]}`,
+			services: []string{"s1"},
+			wantErr:  true,
+		},
+		{
+			input:    `{"name": "a/b/c", "scopes": ["plugins..my_plugin"]}`,
+			services: []string{"s1"},
+			wantErr:  true,
+		},
 	}
 
 	keys := map[string]*keys.Config{"foo": {Key: "secret"}}
@@ -15,8 +15,10 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"slices"
 	"strings"
 	"sync"
+	"time"
 
 	bundleUtils "github.com/open-policy-agent/opa/internal/bundle"
 	cfg "github.com/open-policy-agent/opa/internal/config"
@@ -33,6 +35,7 @@ import (
 	"github.com/open-policy-agent/opa/v1/plugins/logs"
 	"github.com/open-policy-agent/opa/v1/plugins/status"
 	"github.com/open-policy-agent/opa/v1/rego"
+	"github.com/open-policy-agent/opa/v1/storage"
 	"github.com/open-policy-agent/opa/v1/storage/inmem"
 	"github.com/open-policy-agent/opa/v1/util"
 )
@@ -67,6 +70,30 @@ type Discovery struct {
 	hooks                hooks.Hooks
 	bootConfig           map[string]any
 	overriddenConfigKeys []string
+	appliedConfigKeys    []string
+	infoMtx              sync.Mutex // lock for info
+	info                 *Info      // provenance of the active discovery bundle
+}
+
+// Info describes the provenance of the active discovery bundle, e.g., for
+// auditing the configuration of a fleet of OPAs.
+type Info struct {
+	Revision string `json:"revision,omitempty"`
+	ETag     string `json:"etag,omitempty"`
+
+	// Downloaded is when the bundle was downloaded. It is zero for bundles
+	// loaded from disk.
+	Downloaded time.Time `json:"downloaded,omitempty"`
+
+	// VerificationKeyID is the ID of the key the bundle signature was verified
+	// with. It is empty if bundle verification is not configured.
+	VerificationKeyID string `json:"verification_key_id,omitempty"`
+
+	// AppliedKeys are the top-level keys of the discovered configuration,
+	// including those set to defaults, and OverriddenKeys the keys of it
+	// overridden by the boot configuration.
+	AppliedKeys    []string `json:"applied_keys,omitempty"`
+	OverriddenKeys []string `json:"overridden_keys,omitempty"`
 }
 
 // Factories provides a set of factory functions to use for
@@ -136,13 +163,15 @@ func New(manager *plugins.Manager, opts ...func(*Discovery)) (*Discovery, error)
 			WithCallback(result.oneShot).
 			WithBundleVerificationConfig(config.Signing).
 			WithBundlePersistence(config.Persist).
-			WithBundleParserOpts(manager.ParserOptions())
+			WithBundleParserOpts(manager.ParserOptions()).
+			WithJitterSeed(manager.ID)
 	} else {
 		d := download.New(config.Config, restClient, config.path).
 			WithCallback(result.oneShot).
 			WithBundleVerificationConfig(config.Signing).
 			WithBundlePersistence(config.Persist).
-			WithBundleParserOpts(manager.ParserOptions())
+			WithBundleParserOpts(manager.ParserOptions()).
+			WithJitterSeed(manager.ID)
 		result.downloader = d
 	}
 	result.status = &bundle.Status{
@@ -188,6 +217,22 @@ func (c *Discovery) Stop(ctx context.Context) {
 func (*Discovery) Reconfigure(context.Context, any) {
 }
 
+// Info returns the provenance of the active discovery bundle, or nil if no
+// discovery bundle has been activated yet.
+func (c *Discovery) Info() *Info {
+	c.infoMtx.Lock()
+	defer c.infoMtx.Unlock()
+
+	if c.info == nil {
+		return nil
+	}
+
+	info := *c.info
+	info.AppliedKeys = slices.Clone(info.AppliedKeys)
+	info.OverriddenKeys = slices.Clone(info.OverriddenKeys)
+	return &info
+}
+
 // Lookup returns the discovery plugin registered with the manager.
 func Lookup(manager *plugins.Manager) *Discovery {
 	if p := manager.Plugin(Name); p != nil {
@@ -203,6 +248,10 @@ func (c *Discovery) TriggerMode() *plugins.TriggerMode {
 	return c.config.Trigger
 }
 
+// Trigger forces an immediate discovery download, e.g. in response to a
+// webhook, and returns once the downloaded bundle has been processed. With
+// periodic polling, the download happens out-of-band and the polling
+// interval is not otherwise affected.
 func (c *Discovery) Trigger(ctx context.Context) error {
 	if c.downloader == nil {
 		return nil
@@ -241,7 +290,7 @@ func (c *Discovery) getBundlePersistPath() (string, error) {
 func (c *Discovery) loadAndActivateBundleFromDisk(ctx context.Context) {
 
 	if c.config != nil && c.config.Persist {
-		b, err := c.loadBundleFromDisk()
+		b, err := c.loadBundleFromDisk(ctx)
 		if err != nil {
 			c.logger.Error("Failed to load discovery bundle from disk: %v", err)
 			c.status.SetError(err)
@@ -275,6 +324,7 @@ func (c *Discovery) loadAndActivateBundleFromDisk(ctx context.Context) {
 
 			c.status.SetError(nil)
 			c.status.SetActivateSuccess(b.Manifest.Revision)
+			c.setInfo(b, "", time.Time{})
 
 			// On the first activation success mark the plugin as being in OK state
 			c.readyOnce.Do(func() {
@@ -287,17 +337,17 @@ func (c *Discovery) loadAndActivateBundleFromDisk(ctx context.Context) {
 	}
 }
 
-func (c *Discovery) loadBundleFromDisk() (*bundleApi.Bundle, error) {
-	return bundleUtils.LoadBundleFromDiskForRegoVersion(c.manager.ParserOptions().RegoVersion,
-		c.bundlePersistPath, c.discoveryBundleDirName(), c.config.Signing)
+func (c *Discovery) loadBundleFromDisk(ctx context.Context) (*bundleApi.Bundle, error) {
+	return bundleUtils.LoadEncryptedBundleFromDisk(ctx, c.manager.ParserOptions().RegoVersion,
+		c.bundlePersistPath, c.discoveryBundleDirName(), c.config.Signing, c.manager.PersistenceKeyProvider())
 }
 
-func (c *Discovery) saveBundleToDisk(raw io.Reader) error {
+func (c *Discovery) saveBundleToDisk(ctx context.Context, raw io.Reader) error {
 
 	bundleDir := filepath.Join(c.bundlePersistPath, c.discoveryBundleDirName())
 	bundleFile := filepath.Join(bundleDir, "bundle.tar.gz")
 
-	tmpFile, saveErr := saveCurrentBundleToDisk(bundleDir, raw)
+	tmpFile, saveErr := saveCurrentBundleToDisk(ctx, bundleDir, raw, c.manager.PersistenceKeyProvider())
 	if saveErr != nil {
 		c.logger.Error("Failed to save new discovery bundle to disk: %v", saveErr)
 
@@ -315,13 +365,15 @@ func (c *Discovery) saveBundleToDisk(raw io.Reader) error {
 	return os.Rename(tmpFile, bundleFile)
 }
 
-func saveCurrentBundleToDisk(path string, raw io.Reader) (string, error) {
-	return bundleUtils.SaveBundleToDisk(path, raw)
+func saveCurrentBundleToDisk(ctx context.Context, path string, raw io.Reader, kp storage.KeyProvider) (string, error) {
+	return bundleUtils.SaveEncryptedBundleToDisk(ctx, path, raw, kp)
 }
 
 func (c *Discovery) oneShot(ctx context.Context, u download.Update) {
 
+	ctx, span := c.startCycle(ctx, u)
 	c.processUpdate(ctx, u)
+	c.endCycle(span, u)
 
 	if p := status.Lookup(c.manager); p != nil {
 		p.UpdateDiscoveryStatus(*c.status)
@@ -362,7 +414,7 @@ func (c *Discovery) processUpdate(ctx context.Context, u download.Update) {
 		if c.config != nil && c.config.Persist {
 			c.logger.Debug("Persisting discovery bundle to disk in progress.")
 
-			err := c.saveBundleToDisk(u.Raw)
+			err := c.saveBundleToDisk(ctx, u.Raw)
 			if err != nil {
 				c.logger.Error("Persisting discovery bundle to disk failed: %v", err)
 				c.status.SetError(err)
@@ -374,6 +426,7 @@ func (c *Discovery) processUpdate(ctx context.Context, u download.Update) {
 
 		c.status.SetError(nil)
 		c.status.SetActivateSuccess(u.Bundle.Manifest.Revision)
+		c.setInfo(u.Bundle, u.ETag, c.status.LastSuccessfulDownload)
 
 		// include the local overrides in the status update
 		if len(c.overriddenConfigKeys) != 0 {
@@ -404,6 +457,34 @@ func (c *Discovery) processUpdate(ctx context.Context, u download.Update) {
 	}
 }
 
+// setInfo records the provenance of the activated discovery bundle b, and
+// includes it in the status.
+func (c *Discovery) setInfo(b *bundleApi.Bundle, etag string, downloaded time.Time) {
+	var keyID string
+	if c.config != nil && c.config.Signing != nil && len(b.Signatures.Signatures) > 0 {
+		var err error
+		keyID, err = bundleApi.VerificationKeyID(b.Signatures.Signatures[0], c.config.Signing)
+		if err != nil {
+			c.logger.Debug("Failed to determine discovery bundle verification key ID: %v", err)
+		}
+	}
+
+	c.status.ETag = etag
+	c.status.VerificationKeyID = keyID
+
+	c.infoMtx.Lock()
+	defer c.infoMtx.Unlock()
+
+	c.info = &Info{
+		Revision:          b.Manifest.Revision,
+		ETag:              etag,
+		Downloaded:        downloaded,
+		VerificationKeyID: keyID,
+		AppliedKeys:       c.appliedConfigKeys,
+		OverriddenKeys:    c.overriddenConfigKeys,
+	}
+}
+
 func (c *Discovery) reconfigure(ctx context.Context, u download.Update) error {
 
 	ps, err := c.processBundle(ctx, u.Bundle)
@@ -411,17 +492,19 @@ func (c *Discovery) reconfigure(ctx context.Context, u download.Update) error {
 		return err
 	}
 
-	for _, p := range ps.Start {
-		if err := p.Start(ctx); err != nil {
-			return err
+	return c.phase(ctx, "start", func(ctx context.Context) error {
+		for _, p := range ps.Start {
+			if err := p.Start(ctx); err != nil {
+				return err
+			}
 		}
-	}
 
-	for _, p := range ps.Reconfig {
-		p.Plugin.Reconfigure(ctx, p.Config)
-	}
+		for _, p := range ps.Reconfig {
+			p.Plugin.Reconfigure(ctx, p.Config)
+		}
 
-	return nil
+		return nil
+	})
 }
 
 func (c *Discovery) applyLocalPluginConfigOverride(conf *config.Config) (*config.Config, []string, error) {
@@ -436,6 +519,14 @@ func (c *Discovery) applyLocalPluginConfigOverride(conf *config.Config) (*config
 		return nil, nil, err
 	}
 
+	c.appliedConfigKeys = nil
+	for _, key := range util.KeysSorted(newConfig) {
+		// The discovery configuration is that of the boot configuration.
+		if key != "discovery" {
+			c.appliedConfigKeys = append(c.appliedConfigKeys, key)
+		}
+	}
+
 	_, overriddenKeys := mergeValuesAndListOverrides(newConfig, c.bootConfig, "")
 
 	bs, err := json.Marshal(newConfig)
@@ -453,78 +544,106 @@ func (c *Discovery) applyLocalPluginConfigOverride(conf *config.Config) (*config
 
 func (c *Discovery) processBundle(ctx context.Context, b *bundleApi.Bundle) (*pluginSet, error) {
 
-	config, err := evaluateBundle(ctx, c.manager.ID, c.manager.Info, b, c.config.query)
+	var conf *config.Config
+	err := c.phase(ctx, "evaluate", func(ctx context.Context) (err error) {
+		conf, err = evaluateBundle(ctx, c.manager.ID, c.manager.Info, b, c.config.query)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var overriddenConfig *config.Config
+	var overriddenKeys []string
+	err = c.phase(ctx, "validate", func(ctx context.Context) (err error) {
+		overriddenConfig, overriddenKeys, err = c.validate(ctx, conf)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var ps *pluginSet
+	err = c.phase(ctx, "apply", func(context.Context) (err error) {
+		if err = c.manager.Reconfigure(overriddenConfig); err != nil {
+			return err
+		}
+		ps, err = getPluginSet(c.factories, c.manager, overriddenConfig, c.metrics, c.logger, c.config.Trigger)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	c.overriddenConfigKeys = overriddenKeys
+
+	return ps, nil
+}
+
+// validate scopes the discovered config, passes it to the config discovery
+// hooks, checks that it does not update the discovery service and the keys
+// of the boot configuration, and applies the local overrides to it.
+func (c *Discovery) validate(ctx context.Context, conf *config.Config) (*config.Config, []string, error) {
+	var err error
+
+	if len(c.config.Scopes) > 0 {
+		conf, err = scopeConfig(conf, c.config.Scopes, c.manager.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
 	c.hooks.Each(func(h hooks.Hook) {
 		if f, ok := h.(hooks.ConfigDiscoveryHook); ok {
-			if c, e := f.OnConfigDiscovery(ctx, config); e != nil {
+			if c, e := f.OnConfigDiscovery(ctx, conf); e != nil {
 				err = errors.Join(err, e)
 			} else {
-				config = c
+				conf = c
 			}
 		}
 	})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Note: We don't currently support changes to the discovery
 	// configuration. These changes are risky because errors would be
 	// unrecoverable (without keeping track of changes and rolling back...)
-	config.Discovery = c.manager.Config.Discovery
+	conf.Discovery = c.manager.Config.Discovery
 
 	// check for updates to the discovery service
-	opts := c.manager.DefaultServiceOpts(config)
+	opts := c.manager.DefaultServiceOpts(conf)
 	opts.Logger = c.logger.WithFields(c.manager.Client(c.config.service).LoggerFields())
 
 	services, err := cfg.ParseServicesConfig(opts)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if client, ok := services[c.config.service]; ok {
 		dClient := c.manager.Client(c.config.service)
 		if !client.Config().Equal(dClient.Config()) {
-			return nil, errors.New("updates to the discovery service are not allowed")
+			return nil, nil, errors.New("updates to the discovery service are not allowed")
 		}
 	}
 
 	// check for updates to the keys provided in the boot config
-	keys, err := keys.ParseKeysConfig(config.Keys)
+	keys, err := keys.ParseKeysConfig(conf.Keys)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if c.config.Signing != nil {
 		for key, kc := range keys {
 			if curr, ok := c.config.Signing.PublicKeys[key]; ok {
 				if !curr.Equal(kc) {
-					return nil, errors.New("updates to keys specified in the boot configuration are not allowed")
+					return nil, nil, errors.New("updates to keys specified in the boot configuration are not allowed")
 				}
 			}
 		}
 	}
 
-	overriddenConfig, overriddenKeys, err := c.applyLocalPluginConfigOverride(config)
-	if err != nil {
-		return nil, err
-	}
-
-	if err := c.manager.Reconfigure(overriddenConfig); err != nil {
-		return nil, err
-	}
-
-	ps, err := getPluginSet(c.factories, c.manager, overriddenConfig, c.metrics, c.logger, c.config.Trigger)
-	if err != nil {
-		return nil, err
-	}
-
-	c.overriddenConfigKeys = overriddenKeys
-
-	return ps, nil
+	return c.applyLocalPluginConfigOverride(conf)
 }
 
 // discoveryBundleDirName returns the name of the directory where the discovery bundle will be persisted.
@@ -580,6 +699,8 @@ func evaluateBundle(ctx context.Context, id string, info *ast.Term, b *bundleApi
 type pluginSet struct {
 	Start    []plugins.Plugin
 	Reconfig []pluginreconfig
+
+	startNames []string // names of the plugins in Start
 }
 
 type pluginreconfig struct {
@@ -599,7 +720,9 @@ func getPluginSet(factories map[string]plugins.Factory, manager *plugins.Manager
 	pluginNames := []string{}
 	pluginFactories := []pluginfactory{}
 
-	for k := range config.Plugins {
+	// Iterate in a stable order so that plugins without dependencies between
+	// them are always registered, and started, in the same order.
+	for _, k := range util.KeysSorted(config.Plugins) {
 		f, ok := factories[k]
 		if !ok {
 			return nil, fmt.Errorf("plugin %q not registered", k)
@@ -648,14 +771,22 @@ func getPluginSet(factories map[string]plugins.Factory, manager *plugins.Manager
 		return nil, err
 	}
 
+	// Check plugin dependencies before any plugin gets registered, so that an
+	// invalid configuration does not leave plugins registered but not started.
+	if err := validateDependencies(manager, pluginFactories, bundleConfig != nil, decisionLogsConfig != nil, statusConfig != nil); err != nil {
+		return nil, err
+	}
+
 	// Accumulate plugins to start or reconfigure.
 	starts := []plugins.Plugin{}
+	startNames := []string{}
 	reconfigs := []pluginreconfig{}
 
 	if bundleConfig != nil {
 		p, created := getBundlePlugin(manager, bundleConfig)
 		if created {
 			starts = append(starts, p)
+			startNames = append(startNames, bundle.Name)
 		} else if p != nil {
 			reconfigs = append(reconfigs, pluginreconfig{bundleConfig, p})
 		}
@@ -665,6 +796,7 @@ func getPluginSet(factories map[string]plugins.Factory, manager *plugins.Manager
 		p, created := getDecisionLogsPlugin(manager, decisionLogsConfig, m)
 		if created {
 			starts = append(starts, p)
+			startNames = append(startNames, logs.Name)
 		} else if p != nil {
 			reconfigs = append(reconfigs, pluginreconfig{decisionLogsConfig, p})
 		}
@@ -674,18 +806,77 @@ func getPluginSet(factories map[string]plugins.Factory, manager *plugins.Manager
 		p, created := getStatusPlugin(manager, statusConfig, m)
 		if created {
 			starts = append(starts, p)
+			startNames = append(startNames, status.Name)
 		} else if p != nil {
 			reconfigs = append(reconfigs, pluginreconfig{statusConfig, p})
 		}
 	}
 
-	result := &pluginSet{starts, reconfigs}
+	result := &pluginSet{Start: starts, Reconfig: reconfigs, startNames: startNames}
 
 	getCustomPlugins(manager, pluginFactories, result)
 
+	if err := sortStarts(manager, result); err != nil {
+		return nil, err
+	}
+
 	return result, nil
 }
 
+func validateDependencies(manager *plugins.Manager, factories []pluginfactory, hasBundle, hasLogs, hasStatus bool) error {
+	known := map[string]bool{
+		bundle.Name: hasBundle,
+		logs.Name:   hasLogs,
+		status.Name: hasStatus,
+	}
+	for _, name := range manager.Plugins() {
+		known[name] = true
+	}
+	for _, pf := range factories {
+		known[pf.name] = true
+	}
+
+	deps := map[string][]string{}
+	for _, pf := range factories {
+		df, ok := pf.factory.(plugins.DependentFactory)
+		if !ok {
+			continue
+		}
+		deps[pf.name] = df.Dependencies()
+		for _, dep := range deps[pf.name] {
+			if !known[dep] {
+				return fmt.Errorf("plugin %q depends on unknown plugin %q", pf.name, dep)
+			}
+		}
+	}
+
+	return plugins.ValidateDependencies(deps)
+}
+
+// sortStarts orders the plugins to be started so that every plugin is started
+// after the plugins it depends on.
+func sortStarts(manager *plugins.Manager, ps *pluginSet) error {
+	if len(ps.Start) < 2 {
+		return nil
+	}
+
+	byName := make(map[string]plugins.Plugin, len(ps.Start))
+	for i, name := range ps.startNames {
+		byName[name] = ps.Start[i]
+	}
+
+	ordered, err := manager.DependencyOrder(ps.startNames)
+	if err != nil {
+		return err
+	}
+
+	for i, name := range ordered {
+		ps.Start[i] = byName[name]
+	}
+	ps.startNames = ordered
+	return nil
+}
+
 func getBundlePlugin(m *plugins.Manager, config *bundle.Config) (plugin *bundle.Plugin, created bool) {
 	plugin = bundle.Lookup(m)
 	if plugin == nil {
@@ -727,8 +918,13 @@ func getCustomPlugins(manager *plugins.Manager, factories []pluginfactory, resul
 			result.Reconfig = append(result.Reconfig, pluginreconfig{pf.config, plugin})
 		} else {
 			plugin := pf.factory.New(manager, pf.config)
-			manager.Register(pf.name, plugin)
+			var deps []string
+			if df, ok := pf.factory.(plugins.DependentFactory); ok {
+				deps = df.Dependencies()
+			}
+			manager.RegisterWithDependencies(pf.name, plugin, deps...)
 			result.Start = append(result.Start, plugin)
+			result.startNames = append(result.startNames, pf.name)
 		}
 	}
 }
@@ -750,6 +946,58 @@ func registerBundleStatusUpdates(m *plugins.Manager) {
 	}
 }
 
+// scopeConfig returns the portions of the discovered configuration selected by
+// the scopes, which are dot-separated paths into the configuration. The
+// remaining configuration is taken from the boot configuration only.
+func scopeConfig(conf *config.Config, scopes []string, id string) (*config.Config, error) {
+	raw, err := json.Marshal(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	var discovered map[string]any
+	if err := util.Unmarshal(raw, &discovered); err != nil {
+		return nil, err
+	}
+
+	scoped := map[string]any{}
+	for _, scope := range scopes {
+		copyScope(scoped, discovered, strings.Split(scope, "."))
+	}
+
+	bs, err := json.Marshal(scoped)
+	if err != nil {
+		return nil, err
+	}
+
+	return config.ParseConfig(bs, id)
+}
+
+func copyScope(dest map[string]any, src map[string]any, path []string) {
+	v, ok := src[path[0]]
+	if !ok {
+		return
+	}
+
+	if len(path) == 1 {
+		dest[path[0]] = v
+		return
+	}
+
+	next, ok := v.(map[string]any)
+	if !ok {
+		return
+	}
+
+	destMap, ok := dest[path[0]].(map[string]any)
+	if !ok {
+		destMap = map[string]any{}
+		dest[path[0]] = destMap
+	}
+
+	copyScope(destMap, next, path[1:])
+}
+
 // mergeValuesAndListOverrides will merge source and destination map, preferring values from the source map.
 // It will also return a list of keys in the destination map which were overridden by those in the source map
 func mergeValuesAndListOverrides(dest map[string]any, src map[string]any, prefix string) (map[string]any, []string) {
@@ -20,10 +20,17 @@ import (
 	"path"
 	"path/filepath"
 	"reflect"
+	"slices"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
 	"github.com/open-policy-agent/opa/v1/ast"
 	bundleApi "github.com/open-policy-agent/opa/v1/bundle"
 	"github.com/open-policy-agent/opa/v1/download"
@@ -572,6 +579,116 @@ func (r *reconfigureTestPlugin) Reconfigure(_ context.Context, _ any) {
 	r.counts["reconfig"]++
 }
 
+type dependentTestFactory struct {
+	deps []string
+}
+
+func (dependentTestFactory) Validate(*plugins.Manager, []byte) (any, error) {
+	return nil, nil
+}
+
+func (dependentTestFactory) New(*plugins.Manager, any) plugins.Plugin {
+	return &reconfigureTestPlugin{counts: map[string]int{}}
+}
+
+func (f dependentTestFactory) Dependencies() []string {
+	return f.deps
+}
+
+func TestProcessBundleDependencyOrder(t *testing.T) {
+
+	ctx := context.Background()
+
+	manager, err := plugins.New([]byte(`{
+		"services": {
+			"default": {
+				"url": "http://localhost:8181"
+			}
+		},
+		"discovery": {"name": "config"}
+	}`), "test-id", inmem.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	disco, err := New(manager, Factories(map[string]plugins.Factory{
+		"a_plugin": dependentTestFactory{deps: []string{"b_plugin", "bundle"}},
+		"b_plugin": dependentTestFactory{deps: []string{"c_plugin"}},
+		"c_plugin": dependentTestFactory{},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ps, err := disco.processBundle(ctx, makeDataBundle(1, `
+		{
+			"config": {
+				"bundles": {"test1": {"service": "default"}},
+				"plugins": {"a_plugin": {}, "b_plugin": {}, "c_plugin": {}}
+			}
+		}
+	`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []string{"bundle", "c_plugin", "b_plugin", "a_plugin"}
+	var act []string
+	for _, p := range ps.Start {
+		for _, name := range manager.Plugins() {
+			if manager.Plugin(name) == p {
+				act = append(act, name)
+			}
+		}
+	}
+
+	if !reflect.DeepEqual(exp, act) {
+		t.Fatalf("expected start order %v, got %v", exp, act)
+	}
+}
+
+func TestProcessBundleDependencyCycle(t *testing.T) {
+
+	ctx := context.Background()
+
+	manager, err := plugins.New([]byte(`{
+		"services": {
+			"default": {
+				"url": "http://localhost:8181"
+			}
+		},
+		"discovery": {"name": "config"}
+	}`), "test-id", inmem.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	disco, err := New(manager, Factories(map[string]plugins.Factory{
+		"a_plugin": dependentTestFactory{deps: []string{"b_plugin"}},
+		"b_plugin": dependentTestFactory{deps: []string{"a_plugin"}},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = disco.processBundle(ctx, makeDataBundle(1, `
+		{
+			"config": {
+				"plugins": {"a_plugin": {}, "b_plugin": {}}
+			}
+		}
+	`))
+
+	exp := "plugin dependency cycle: a_plugin -> b_plugin -> a_plugin"
+	if err == nil || err.Error() != exp {
+		t.Fatalf("expected error %q, got %v", exp, err)
+	}
+
+	if manager.Plugin("a_plugin") != nil || manager.Plugin("b_plugin") != nil {
+		t.Fatal("expected plugins not to be registered")
+	}
+}
+
 func TestStartWithBundlePersistence(t *testing.T) {
 	dir := t.TempDir()
 
@@ -717,7 +834,7 @@ func TestOneShotWithBundlePersistence(t *testing.T) {
 
 	ensurePluginState(t, disco, plugins.StateOK)
 
-	result, err := disco.loadBundleFromDisk()
+	result, err := disco.loadBundleFromDisk(ctx)
 	if err != nil {
 		t.Fatal("unexpected error:", err)
 	}
@@ -803,7 +920,7 @@ func TestLoadAndActivateBundleFromDisk(t *testing.T) {
 		t.Fatal("unexpected error:", err)
 	}
 
-	err = disco.saveBundleToDisk(&buf)
+	err = disco.saveBundleToDisk(ctx, &buf)
 	if err != nil {
 		t.Fatalf("unexpected error %v", err)
 	}
@@ -899,7 +1016,7 @@ func TestLoadAndActivateSignedBundleFromDisk(t *testing.T) {
 		t.Fatal("unexpected error:", err)
 	}
 
-	err = disco.saveBundleToDisk(&buf)
+	err = disco.saveBundleToDisk(ctx, &buf)
 	if err != nil {
 		t.Fatalf("unexpected error %v", err)
 	}
@@ -991,7 +1108,7 @@ func TestLoadAndActivateBundleFromDiskMaxAttempts(t *testing.T) {
 		t.Fatal("unexpected error:", err)
 	}
 
-	err = disco.saveBundleToDisk(&buf)
+	err = disco.saveBundleToDisk(ctx, &buf)
 	if err != nil {
 		t.Fatalf("unexpected error %v", err)
 	}
@@ -1099,7 +1216,7 @@ bundles.authz.service := v if {
 				t.Fatal("unexpected error:", err)
 			}
 
-			err = disco.saveBundleToDisk(&buf)
+			err = disco.saveBundleToDisk(ctx, &buf)
 			if err != nil {
 				t.Fatalf("unexpected error %v", err)
 			}
@@ -1269,7 +1386,7 @@ bundles.authz.service := v if {
 				t.Fatal("unexpected error:", err)
 			}
 
-			err = disco.saveBundleToDisk(&buf)
+			err = disco.saveBundleToDisk(ctx, &buf)
 			if err != nil {
 				t.Fatalf("unexpected error %v", err)
 			}
@@ -1347,7 +1464,7 @@ func TestSaveBundleToDiskNew(t *testing.T) {
 		t.Fatal("unexpected error:", err)
 	}
 
-	err = disco.saveBundleToDisk(&buf)
+	err = disco.saveBundleToDisk(context.Background(), &buf)
 	if err != nil {
 		t.Fatalf("unexpected error %v", err)
 	}
@@ -1405,7 +1522,7 @@ func TestSaveBundleToDiskNewConfiguredPersistDir(t *testing.T) {
 		t.Fatal("unexpected error:", err)
 	}
 
-	err = disco.saveBundleToDisk(&buf)
+	err = disco.saveBundleToDisk(context.Background(), &buf)
 	if err != nil {
 		t.Fatalf("unexpected error %v", err)
 	}
@@ -1519,6 +1636,107 @@ func TestReconfigure(t *testing.T) {
 	}
 }
 
+func TestDiscoveryTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	manager, err := plugins.New([]byte(`{
+		"services": {
+			"localhost": {
+				"url": "http://localhost:9999"
+			}
+		},
+		"discovery": {"name": "config"},
+	}`), "test-id", inmem.New(), plugins.WithTracerProvider(tp))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testPlugin := &reconfigureTestPlugin{counts: map[string]int{}}
+	disco, err := New(manager, Factories(map[string]plugins.Factory{"test_plugin": testFactory{p: testPlugin}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	tests := []struct {
+		note    string
+		update  download.Update
+		spans   []string
+		outcome string
+		failed  bool
+	}{
+		{
+			note: "activated",
+			update: download.Update{Bundle: makeDataBundle(1, `{
+				"config": {"plugins": {"test_plugin": {"a": "b"}}}
+			}`), ETag: "etag-1", Size: snapshotBundleSize},
+			spans:   []string{"discovery.download", "discovery.evaluate", "discovery.validate", "discovery.apply", "discovery.start", "discovery"},
+			outcome: outcomeActivated,
+		},
+		{
+			note:    "not modified",
+			update:  download.Update{ETag: "etag-1"},
+			spans:   []string{"discovery.download", "discovery"},
+			outcome: outcomeNotModified,
+		},
+		{
+			note:    "download failed",
+			update:  download.Update{Error: errors.New("unknown error")},
+			spans:   []string{"discovery.download", "discovery"},
+			outcome: outcomeDownloadFailed,
+			failed:  true,
+		},
+		{
+			note: "validation failed",
+			update: download.Update{Bundle: makeDataBundle(2, `{
+				"config": {"services": {"localhost": {"url": "http://localhost:8888"}}}
+			}`)},
+			spans:   []string{"discovery.download", "discovery.evaluate", "discovery.validate", "discovery"},
+			outcome: outcomeFailed,
+			failed:  true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			before := len(recorder.Ended())
+			disco.oneShot(ctx, tc.update)
+			spans := recorder.Ended()[before:]
+
+			names := make([]string, len(spans))
+			for i, span := range spans {
+				names[i] = span.Name()
+			}
+			if !slices.Equal(names, tc.spans) {
+				t.Fatalf("expected spans %v but got %v", tc.spans, names)
+			}
+
+			cycle := spans[len(spans)-1]
+			for _, span := range spans[:len(spans)-1] {
+				if span.Parent().SpanID() != cycle.SpanContext().SpanID() {
+					t.Errorf("expected span %v to be a child of the discovery span", span.Name())
+				}
+			}
+
+			var outcome string
+			for _, kv := range cycle.Attributes() {
+				if kv.Key == attribute.Key("opa.discovery.outcome") {
+					outcome = kv.Value.AsString()
+				}
+			}
+			if outcome != tc.outcome {
+				t.Errorf("expected outcome %q but got %q", tc.outcome, outcome)
+			}
+
+			if failed := cycle.Status().Code == codes.Error; failed != tc.failed {
+				t.Errorf("expected failed to be %v but got status %v", tc.failed, cycle.Status())
+			}
+		})
+	}
+}
+
 func TestReconfigureV1Compatible(t *testing.T) {
 	popts := ast.ParserOptions{RegoVersion: ast.RegoV1}
 
@@ -2045,6 +2263,74 @@ func TestReconfigureWithLocalOverride(t *testing.T) {
 	}
 }
 
+func TestReconfigureWithScopes(t *testing.T) {
+	ctx := context.Background()
+
+	bootConfigRaw := []byte(`{
+		"labels": {"x": "y"},
+		"services": {
+			"localhost": {
+				"url": "http://localhost:9999"
+			}
+		},
+		"discovery": {"name": "config", "scopes": ["labels"]},
+		"decision_logs": {"console": true}
+	}`)
+
+	manager, err := plugins.New(bootConfigRaw, "test-id", inmem.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var bootConfig map[string]any
+	err = util.Unmarshal(bootConfigRaw, &bootConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	disco, err := New(manager, BootConfig(bootConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serviceBundle := makeDataBundle(1, `
+		{
+			"config": {
+				"labels": {"z": "new label"},
+				"default_decision": "/http/example/authz/allow",
+				"decision_logs": {"console": false}
+			}
+		}
+	`)
+
+	disco.oneShot(ctx, download.Update{Bundle: serviceBundle})
+
+	if disco.status == nil {
+		t.Fatal("Expected to find status, found nil")
+	} else if disco.status.Message != "" {
+		t.Fatalf("Expected no error but got %v", disco.status.Message)
+	}
+
+	exp := map[string]string{"x": "y", "z": "new label", "id": "test-id", "version": version.Version}
+	if !maps.Equal(manager.Labels(), exp) {
+		t.Errorf("Expected labels (%v) but got %v", exp, manager.Labels())
+	}
+
+	if *manager.Config.DefaultDecision == "/http/example/authz/allow" {
+		t.Errorf("Expected default decision outside of scopes to be ignored")
+	}
+
+	var dlConfig map[string]any
+	err = util.Unmarshal(manager.Config.DecisionLogs, &dlConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dlConfig["console"] != true {
+		t.Errorf("Expected decision log config outside of scopes to be ignored")
+	}
+}
+
 func TestMergeValuesAndListOverrides(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -2746,6 +3032,58 @@ func TestProcessBundleWithNoSigningConfig(t *testing.T) {
 	}
 }
 
+func TestTriggerPeriodic(t *testing.T) {
+	var requests atomic.Int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		n := int(requests.Add(1))
+		b := makeDataBundle(n, `{"config": {"labels": {"x": "y"}}}`)
+		if err := bundleApi.NewWriter(w).Write(*b); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer ts.Close()
+
+	// Poll rarely enough that any further downloads must come from triggers.
+	manager, err := plugins.New(fmt.Appendf(nil, `{
+			"labels": {"x": "y"},
+			"services": {
+				"localhost": {
+					"url": %q
+				}
+			},
+			"discovery": {
+				"name": "config",
+				"polling": {"min_delay_seconds": 3600, "max_delay_seconds": 7200}
+			},
+		}`, ts.URL), "test-id", inmem.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	disco, err := New(manager)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	if err := disco.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer disco.Stop(ctx)
+
+	for i := 2; i <= 3; i++ {
+		if err := disco.Trigger(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		if exp, act := fmt.Sprintf("test-revision-%d", i), disco.status.ActiveRevision; exp != act {
+			t.Fatalf("expected active revision %q but got %q", exp, act)
+		}
+	}
+}
+
 type testServer struct {
 	t       *testing.T
 	server  *httptest.Server
@@ -4289,3 +4627,71 @@ func ensurePluginState(t *testing.T, d *Discovery, state plugins.State) {
 		t.Fatalf("Unexpected status state found in plugin manager for %s:\n\n\tFound:%+v\n\n\tExpected: %s", Name, status.State, state)
 	}
 }
+
+func TestDiscoveryInfo(t *testing.T) {
+	ctx := context.Background()
+
+	bootConfigRaw := []byte(`{
+		"labels": {"x": "y"},
+		"services": {
+			"localhost": {
+				"url": "http://localhost:9999"
+			}
+		},
+		"discovery": {"name": "config", "signing": {"keyid": "my_global_key"}},
+		"keys": {"my_global_key": {"algorithm": "HS256", "key": "secret"}}
+	}`)
+
+	manager, err := plugins.New(bootConfigRaw, "test-id", inmem.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var bootConfig map[string]any
+	if err := util.Unmarshal(bootConfigRaw, &bootConfig); err != nil {
+		t.Fatal(err)
+	}
+
+	disco, err := New(manager, BootConfig(bootConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info := disco.Info(); info != nil {
+		t.Fatalf("Expected no info before activation but got %v", info)
+	}
+
+	b := makeDataBundle(1, `{
+		"config": {
+			"labels": {"x": "z"}
+		}
+	}`)
+	b.Signatures.Signatures = []string{"eyJhbGciOiJIUzI1NiJ9.e30.c2ln"} // header {"alg":"HS256"}, payload {}
+
+	disco.oneShot(ctx, download.Update{ETag: "etag-1", Bundle: b})
+
+	info := disco.Info()
+	if info == nil {
+		t.Fatal("Expected info but got nil")
+	}
+
+	if info.Revision != "test-revision-1" || info.ETag != "etag-1" || info.VerificationKeyID != "my_global_key" {
+		t.Errorf("Unexpected info %+v", info)
+	}
+
+	if !info.Downloaded.Equal(disco.status.LastSuccessfulDownload) || info.Downloaded.IsZero() {
+		t.Errorf("Expected download time %v but got %v", disco.status.LastSuccessfulDownload, info.Downloaded)
+	}
+
+	if !slices.Contains(info.AppliedKeys, "labels") || slices.Contains(info.AppliedKeys, "discovery") {
+		t.Errorf("Unexpected applied keys %v", info.AppliedKeys)
+	}
+
+	if exp := []string{"labels.x"}; !slices.Equal(info.OverriddenKeys, exp) {
+		t.Errorf("Expected overridden keys %v but got %v", exp, info.OverriddenKeys)
+	}
+
+	if disco.status.ETag != "etag-1" || disco.status.VerificationKeyID != "my_global_key" {
+		t.Errorf("Unexpected status %+v", disco.status)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package discovery
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/open-policy-agent/opa/v1/download"
+	"github.com/open-policy-agent/opa/v1/metrics"
+)
+
+const tracerName = "github.com/open-policy-agent/opa/v1/plugins/discovery"
+
+// Outcomes of discovery cycles, recorded by their spans.
+const (
+	outcomeActivated      = "activated"
+	outcomeNotModified    = "not_modified"
+	outcomeDownloadFailed = "download_failed"
+	outcomeFailed         = "failed"
+)
+
+// tracer returns the tracer of the discovery spans. Without distributed
+// tracing, the global tracer provider is used, which does not record spans
+// unless set up by the application embedding OPA.
+func (c *Discovery) tracer() trace.Tracer {
+	if tp := c.manager.TracerProvider(); tp != nil {
+		return tp.Tracer(tracerName)
+	}
+	return otel.GetTracerProvider().Tracer(tracerName)
+}
+
+// startCycle starts the span of the discovery cycle processing u, and the
+// span of its download phase. Since the downloader invokes the callback after
+// downloading, both spans are backdated by the download time recorded in the
+// metrics of u.
+func (c *Discovery) startCycle(ctx context.Context, u download.Update) (context.Context, trace.Span) {
+	now := time.Now()
+	start := now
+	if u.Metrics != nil {
+		start = start.Add(-time.Duration(u.Metrics.Timer(metrics.BundleRequest).Int64() +
+			u.Metrics.Timer(metrics.RegoLoadBundles).Int64()))
+	}
+
+	ctx, span := c.tracer().Start(ctx, "discovery", trace.WithTimestamp(start))
+	if c.config != nil {
+		span.SetAttributes(attribute.String("opa.discovery.service", c.config.service))
+	}
+
+	_, dl := c.tracer().Start(ctx, "discovery.download", trace.WithTimestamp(start))
+	dl.SetAttributes(attribute.Bool("opa.discovery.not_modified", u.Error == nil && u.Bundle == nil))
+	if u.ETag != "" {
+		dl.SetAttributes(attribute.String("opa.discovery.etag", u.ETag))
+	}
+	if u.Bundle != nil {
+		dl.SetAttributes(attribute.Int("opa.discovery.size", u.Size))
+	}
+	endSpan(dl, u.Error, trace.WithTimestamp(now))
+
+	return ctx, span
+}
+
+// endCycle ends the span of the discovery cycle that processed u, recording
+// its outcome from the discovery status.
+func (c *Discovery) endCycle(span trace.Span, u download.Update) {
+	var outcome string
+	var err error
+
+	switch {
+	case u.Error != nil:
+		outcome, err = outcomeDownloadFailed, u.Error
+	case c.status.Code != "":
+		outcome, err = outcomeFailed, errors.New(c.status.Message)
+	case u.Bundle == nil:
+		outcome = outcomeNotModified
+	default:
+		outcome = outcomeActivated
+		span.SetAttributes(attribute.String("opa.discovery.revision", c.status.ActiveRevision))
+	}
+
+	span.SetAttributes(attribute.String("opa.discovery.outcome", outcome))
+	endSpan(span, err)
+}
+
+// phase runs f in a child span of the discovery cycle named after the phase.
+func (c *Discovery) phase(ctx context.Context, name string, f func(context.Context) error) error {
+	ctx, span := c.tracer().Start(ctx, "discovery."+name)
+	err := f(ctx)
+	endSpan(span, err)
+	return err
+}
+
+func endSpan(span trace.Span, err error, opts ...trace.SpanEndOption) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End(opts...)
+}
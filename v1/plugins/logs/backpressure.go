@@ -0,0 +1,214 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package logs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+)
+
+const (
+	backpressureModeReject = "reject"
+	backpressureModeSample = "sample"
+	backpressureModeBlock  = "block"
+
+	defaultBackpressureHighWatermark = 0.8
+	defaultBackpressureLowWatermark  = 0.5
+	defaultBackpressureSampleRate    = 0.1
+
+	logBufferDepthHistogramName           = "decision_logs_buffer_fill_percent"
+	logBackpressureDropCounterName        = "decision_logs_dropped_backpressure"
+	logBackpressureRejectCounterName      = "decision_logs_rejected_backpressure"
+	logBackpressureBlockCounterName       = "decision_logs_blocked_backpressure"
+	logBackpressureActivationsCounterName = "decision_logs_backpressure_activations"
+)
+
+// ErrBackpressure is returned by Log for decisions rejected because uploads
+// fall behind, i.e., the buffer is above the high watermark.
+var ErrBackpressure = errors.New("decision log buffer above high watermark")
+
+// BackpressureConfig represents the configuration of the backpressure the
+// plugin signals to the evaluator when uploads fall behind. Backpressure
+// starts when the buffer fills up to the high watermark, and stops when it
+// drains to the low watermark. The watermarks are ratios of the buffer size
+// limit.
+type BackpressureConfig struct {
+	Mode          string   `json:"mode"`                     // reject, sample or block decisions under backpressure
+	HighWatermark *float64 `json:"high_watermark,omitempty"` // buffer fill ratio at which backpressure starts
+	LowWatermark  *float64 `json:"low_watermark,omitempty"`  // buffer fill ratio at which backpressure stops
+	SampleRate    *float64 `json:"sample_rate,omitempty"`    // ratio of decisions logged under backpressure in sample mode
+}
+
+func (c *BackpressureConfig) validateAndInjectDefaults(r *ReportingConfig) error {
+	switch c.Mode {
+	case backpressureModeReject, backpressureModeSample, backpressureModeBlock:
+	default:
+		return fmt.Errorf("invalid decision_log config, 'backpressure.mode' must be one of %q, %q or %q",
+			backpressureModeReject, backpressureModeSample, backpressureModeBlock)
+	}
+
+	if r.BufferType == sizeBufferType && *r.BufferSizeLimitBytes <= 0 {
+		return fmt.Errorf("invalid decision_log config, 'backpressure' requires 'buffer_size_limit_bytes' for the %v buffer type", sizeBufferType)
+	}
+
+	high := defaultBackpressureHighWatermark
+	if c.HighWatermark != nil {
+		high = *c.HighWatermark
+	}
+	low := min(defaultBackpressureLowWatermark, high)
+	if c.LowWatermark != nil {
+		low = *c.LowWatermark
+	}
+	if low <= 0 || low > high || high > 1 {
+		return errors.New("invalid decision_log config, 'backpressure' watermarks must satisfy 0 < low_watermark <= high_watermark <= 1")
+	}
+	c.HighWatermark, c.LowWatermark = &high, &low
+
+	rate := defaultBackpressureSampleRate
+	if c.SampleRate != nil {
+		rate = *c.SampleRate
+	}
+	if rate < 0 || rate > 1 {
+		return errors.New("invalid decision_log config, 'backpressure.sample_rate' must be between 0 and 1")
+	}
+	c.SampleRate = &rate
+
+	return nil
+}
+
+// backpressure tracks whether the buffer is under backpressure, i.e., whether
+// it has filled up to the high watermark and not drained to the low watermark
+// since.
+type backpressure struct {
+	config   BackpressureConfig
+	mtx      sync.Mutex
+	active   bool
+	released chan struct{} // closed when backpressure stops
+	rnd      func() float64
+}
+
+func newBackpressure(config BackpressureConfig) *backpressure {
+	return &backpressure{config: config, rnd: rand.Float64}
+}
+
+// update updates the state given the fill ratio of the buffer. It returns
+// true if backpressure started.
+func (b *backpressure) update(fill float64) bool {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	switch {
+	case !b.active && fill >= *b.config.HighWatermark:
+		b.active = true
+		b.released = make(chan struct{})
+		return true
+	case b.active && fill <= *b.config.LowWatermark:
+		b.release()
+	}
+	return false
+}
+
+// state returns whether the buffer is under backpressure, and a channel that
+// is closed when it stops.
+func (b *backpressure) state() (bool, chan struct{}) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.active, b.released
+}
+
+// Close releases the decisions blocked under backpressure.
+func (b *backpressure) Close() {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if b.active {
+		b.release()
+	}
+}
+
+func (b *backpressure) release() {
+	b.active = false
+	close(b.released)
+}
+
+// applyBackpressure updates the backpressure state from the buffer, and
+// returns true if the decision should be buffered. Under backpressure,
+// decisions are rejected with ErrBackpressure, sampled, or blocked until the
+// buffer drains or ctx is done, depending on the mode. When backpressure
+// starts, an upload is requested to drain the buffer early.
+func (p *Plugin) applyBackpressure(ctx context.Context) (bool, error) {
+	// blocked decisions must not hold the lock, so that reconfiguration can
+	// release them
+	p.reconfigMtx.RLock()
+	bp := p.backpressure
+	p.updateBackpressure()
+	p.reconfigMtx.RUnlock()
+
+	if bp == nil {
+		return true, nil
+	}
+
+	active, released := bp.state()
+	if !active {
+		return true, nil
+	}
+
+	switch bp.config.Mode {
+	case backpressureModeReject:
+		p.incrMetric(logBackpressureRejectCounterName)
+		return false, ErrBackpressure
+	case backpressureModeSample:
+		if bp.rnd() < *bp.config.SampleRate {
+			return true, nil
+		}
+		p.incrMetric(logBackpressureDropCounterName)
+		return false, nil
+	default:
+		p.incrMetric(logBackpressureBlockCounterName)
+		select {
+		case <-released:
+			return true, nil
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	}
+}
+
+// updateBackpressure updates the backpressure state from the fill ratio of
+// the buffer, and records the fill ratio in the buffer depth metric. The
+// caller must hold the read lock of reconfigMtx.
+func (p *Plugin) updateBackpressure() {
+	bp := p.backpressure
+	if bp == nil {
+		return
+	}
+
+	var fill float64
+	if p.runningBuffer == eventBufferType {
+		fill = float64(len(p.eventBuffer.buffer)) / float64(cap(p.eventBuffer.buffer))
+	} else {
+		p.mtx.Lock()
+		// the buffer of a previous config may be unlimited until the next upload
+		if p.buffer.limit > 0 {
+			fill = float64(p.buffer.usage) / float64(p.buffer.limit)
+		}
+		p.mtx.Unlock()
+	}
+
+	if p.metrics != nil {
+		p.metrics.Histogram(logBufferDepthHistogramName).Update(int64(fill * 100))
+	}
+
+	if bp.update(fill) {
+		p.incrMetric(logBackpressureActivationsCounterName)
+		p.logger.Warn("Decision log buffer reached the high watermark, applying backpressure (%v) until uploads catch up.", bp.config.Mode)
+		select {
+		case p.drain <- struct{}{}:
+		default:
+		}
+	}
+}
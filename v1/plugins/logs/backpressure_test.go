@@ -0,0 +1,224 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+//go:build slow
+
+package logs
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/metrics"
+	"github.com/open-policy-agent/opa/v1/plugins"
+	"github.com/open-policy-agent/opa/v1/server"
+)
+
+func TestParseConfigBackpressure(t *testing.T) {
+	tests := []struct {
+		note   string
+		config string
+		err    string
+	}{
+		{
+			note:   "defaults",
+			config: `{"reporting": {"buffer_type": "event", "backpressure": {"mode": "reject"}}}`,
+		},
+		{
+			note:   "size buffer with limit",
+			config: `{"reporting": {"buffer_size_limit_bytes": 1000, "backpressure": {"mode": "sample", "sample_rate": 0.5}}}`,
+		},
+		{
+			note:   "invalid mode",
+			config: `{"reporting": {"buffer_type": "event", "backpressure": {"mode": "drop"}}}`,
+			err:    "'backpressure.mode' must be one of",
+		},
+		{
+			note:   "unlimited size buffer",
+			config: `{"reporting": {"backpressure": {"mode": "block"}}}`,
+			err:    "'backpressure' requires 'buffer_size_limit_bytes'",
+		},
+		{
+			note:   "low above high watermark",
+			config: `{"reporting": {"buffer_type": "event", "backpressure": {"mode": "block", "high_watermark": 0.5, "low_watermark": 0.6}}}`,
+			err:    "watermarks must satisfy",
+		},
+		{
+			note:   "invalid sample rate",
+			config: `{"reporting": {"buffer_type": "event", "backpressure": {"mode": "sample", "sample_rate": 2}}}`,
+			err:    "'backpressure.sample_rate' must be between 0 and 1",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			config, err := ParseConfig([]byte(tc.config), []string{"s0"}, nil)
+			switch {
+			case tc.err == "" && err != nil:
+				t.Fatal(err)
+			case tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)):
+				t.Fatalf("expected error containing %q, got %v", tc.err, err)
+			case tc.err == "":
+				bp := config.Reporting.Backpressure
+				if bp.HighWatermark == nil || bp.LowWatermark == nil || bp.SampleRate == nil {
+					t.Fatalf("expected defaults to be injected, got %+v", bp)
+				}
+			}
+		})
+	}
+}
+
+func newBackpressureFixture(t *testing.T, mode string) testFixture {
+	t.Helper()
+
+	fixture := newTestFixture(t, testFixtureOptions{
+		ExtraConfig: map[string]any{
+			"reporting": map[string]any{
+				"buffer_type":              "event",
+				"buffer_size_limit_events": 10,
+				"backpressure": map[string]any{
+					"mode":           mode,
+					"high_watermark": 0.5,
+					"low_watermark":  0.2,
+					"sample_rate":    0,
+				},
+			},
+		},
+	})
+	tr := plugins.TriggerManual
+	fixture.plugin.config.Reporting.Trigger = &tr
+	fixture.plugin.WithMetrics(metrics.New())
+	t.Cleanup(fixture.server.stop)
+
+	return fixture
+}
+
+func logDecisions(t *testing.T, p *Plugin, n int) {
+	t.Helper()
+	for range n {
+		if err := p.Log(context.Background(), &server.Info{Path: "test"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPluginBackpressureReject(t *testing.T) {
+	fixture := newBackpressureFixture(t, backpressureModeReject)
+	logDecisions(t, fixture.plugin, 5)
+
+	err := fixture.plugin.Log(context.Background(), &server.Info{Path: "test"})
+	if !errors.Is(err, ErrBackpressure) {
+		t.Fatalf("expected backpressure error, got %v", err)
+	}
+
+	if exp, act := uint64(1), fixture.plugin.metrics.Counter(logBackpressureRejectCounterName).Value(); exp != act {
+		t.Fatalf("expected %v rejected decisions, got %v", exp, act)
+	}
+	if exp, act := 5, len(fixture.plugin.eventBuffer.buffer); exp != act {
+		t.Fatalf("expected %v buffered decisions, got %v", exp, act)
+	}
+}
+
+func TestPluginBackpressureSample(t *testing.T) {
+	fixture := newBackpressureFixture(t, backpressureModeSample)
+	logDecisions(t, fixture.plugin, 8)
+
+	if exp, act := uint64(3), fixture.plugin.metrics.Counter(logBackpressureDropCounterName).Value(); exp != act {
+		t.Fatalf("expected %v dropped decisions, got %v", exp, act)
+	}
+	if exp, act := 5, len(fixture.plugin.eventBuffer.buffer); exp != act {
+		t.Fatalf("expected %v buffered decisions, got %v", exp, act)
+	}
+}
+
+func TestPluginBackpressureBlock(t *testing.T) {
+	fixture := newBackpressureFixture(t, backpressureModeBlock)
+	fixture.server.ch = make(chan []EventV1, 1)
+
+	ctx := context.Background()
+	if err := fixture.plugin.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer fixture.plugin.Stop(ctx)
+
+	logDecisions(t, fixture.plugin, 5)
+
+	done := make(chan error)
+	go func() {
+		done <- fixture.plugin.Log(ctx, &server.Info{Path: "test"})
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("expected decision to be blocked, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := fixture.plugin.Trigger(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if events := <-fixture.server.ch; len(events) != 5 {
+		t.Fatalf("expected 5 uploaded decisions, got %v", len(events))
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected decision to be released after upload")
+	}
+
+	if exp, act := 1, len(fixture.plugin.eventBuffer.buffer); exp != act {
+		t.Fatalf("expected %v buffered decisions, got %v", exp, act)
+	}
+}
+
+func TestPluginBackpressureBlockContextDone(t *testing.T) {
+	fixture := newBackpressureFixture(t, backpressureModeBlock)
+	logDecisions(t, fixture.plugin, 5)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := fixture.plugin.Log(ctx, &server.Info{Path: "test"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestPluginBackpressureReconfigureReleases(t *testing.T) {
+	fixture := newBackpressureFixture(t, backpressureModeBlock)
+	ctx := context.Background()
+
+	if err := fixture.plugin.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer fixture.plugin.Stop(ctx)
+
+	logDecisions(t, fixture.plugin, 5)
+
+	done := make(chan error)
+	go func() {
+		done <- fixture.plugin.Log(ctx, &server.Info{Path: "test"})
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+
+	config := fixture.plugin.config
+	config.Reporting.Backpressure = nil
+	fixture.plugin.Reconfigure(ctx, &config)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected decision to be released on reconfiguration")
+	}
+}
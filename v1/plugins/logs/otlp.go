@@ -0,0 +1,127 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package logs
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/open-policy-agent/opa/internal/distributedtracing"
+	"github.com/open-policy-agent/opa/v1/util"
+)
+
+const (
+	otlpScopeName = "github.com/open-policy-agent/opa/v1/plugins/logs"
+
+	logOTLPDropCounterName = "decision_logs_dropped_otlp_queue_full"
+)
+
+// startOTLP starts exporting decision events as OTLP log records to the
+// endpoint configured for distributed tracing. The caller must hold the
+// reconfigMtx lock.
+func (p *Plugin) startOTLP() {
+	if p.otlp != nil {
+		return
+	}
+
+	var raw []byte
+	if p.manager.Config != nil {
+		raw = p.manager.Config.DistributedTracing
+	}
+
+	exporter, err := distributedtracing.NewLogExporter(raw, otlpScopeName)
+	if err != nil {
+		p.logger.Error("Failed to start OTLP decision log exporter: %v.", err)
+		return
+	}
+
+	if exporter == nil {
+		p.logger.Warn("Decision logs are not exported with OTLP, as distributed tracing is not enabled.")
+		return
+	}
+
+	exporter.Start()
+	p.otlp = exporter
+}
+
+// stopOTLP exports the queued decision events, and stops the exporter. The
+// caller must hold the reconfigMtx lock.
+func (p *Plugin) stopOTLP(ctx context.Context) {
+	if p.otlp == nil {
+		return
+	}
+
+	if err := p.otlp.Shutdown(ctx); err != nil {
+		p.logger.Error("Failed to stop OTLP decision log exporter: %v.", err)
+	}
+	p.otlp = nil
+}
+
+func (p *Plugin) exportEvent(event EventV1) error {
+	p.reconfigMtx.RLock()
+	defer p.reconfigMtx.RUnlock()
+
+	if p.otlp == nil {
+		return nil
+	}
+
+	record, err := otlpRecord(event)
+	if err != nil {
+		return err
+	}
+
+	if !p.otlp.Emit(record) {
+		p.incrMetric(logOTLPDropCounterName)
+		p.logger.Error("OTLP log record queue full, dropping decision %v.", event.DecisionID)
+	}
+
+	return nil
+}
+
+// otlpRecord returns the OTLP log record of a decision event. The body of
+// the record holds the event, and its decision ID, trace and span IDs, and
+// path or query are also set as attributes, so that decisions can be
+// correlated with the traces of the requests that made them.
+func otlpRecord(event EventV1) (distributedtracing.LogRecord, error) {
+	bs, err := json.Marshal(&event)
+	if err != nil {
+		return distributedtracing.LogRecord{}, err
+	}
+
+	var body any
+	if err := util.UnmarshalJSON(bs, &body); err != nil {
+		return distributedtracing.LogRecord{}, err
+	}
+
+	attrs := map[string]string{
+		"type":        "openpolicyagent.org/decision_logs",
+		"decision_id": event.DecisionID,
+	}
+
+	for k, v := range map[string]string{
+		"trace_id": event.TraceID,
+		"span_id":  event.SpanID,
+		"path":     event.Path,
+		"query":    event.Query,
+	} {
+		if v != "" {
+			attrs[k] = v
+		}
+	}
+
+	severity := distributedtracing.SeverityInfo
+	if event.Error != nil {
+		severity = distributedtracing.SeverityError
+	}
+
+	return distributedtracing.LogRecord{
+		Timestamp:  event.Timestamp,
+		Severity:   severity,
+		Body:       body,
+		Attributes: attrs,
+		TraceID:    event.TraceID,
+		SpanID:     event.SpanID,
+	}, nil
+}
@@ -0,0 +1,187 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package logs
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/open-policy-agent/opa/v1/plugins"
+	"github.com/open-policy-agent/opa/v1/server"
+	inmem "github.com/open-policy-agent/opa/v1/storage/inmem/test"
+)
+
+func TestPluginOTLP(t *testing.T) {
+	var mtx sync.Mutex
+	var requests [][]byte
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/logs" || r.Header.Get("Content-Type") != "application/x-protobuf" {
+			t.Errorf("unexpected request: %v %v", r.URL.Path, r.Header.Get("Content-Type"))
+		}
+		bs, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		mtx.Lock()
+		requests = append(requests, bs)
+		mtx.Unlock()
+	}))
+	defer ts.Close()
+
+	raw := []byte(`{
+		"distributed_tracing": {
+			"type": "http",
+			"address": "` + strings.TrimPrefix(ts.URL, "http://") + `",
+			"service_name": "opa-test"
+		}
+	}`)
+
+	manager, err := plugins.New(raw, "test-instance-id", inmem.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := ParseConfig([]byte(`{"otlp": true}`), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	plugin := New(config, manager)
+	if err := plugin.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var result any = true
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	spanID := "00f067aa0ba902b7"
+
+	err = plugin.Log(ctx, &server.Info{
+		DecisionID: "abc",
+		TraceID:    traceID,
+		SpanID:     spanID,
+		Path:       "authz/allow",
+		Results:    &result,
+		Timestamp:  time.Unix(1, 0),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stopCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	plugin.Stop(stopCtx)
+
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	if len(requests) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(requests))
+	}
+
+	var req collogspb.ExportLogsServiceRequest
+	if err := proto.Unmarshal(requests[0], &req); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(req.ResourceLogs) != 1 {
+		t.Fatalf("expected 1 resource logs, got %d", len(req.ResourceLogs))
+	}
+
+	resourceLogs := req.ResourceLogs[0]
+	serviceName := ""
+	for _, kv := range resourceLogs.GetResource().GetAttributes() {
+		if kv.Key == "service.name" {
+			serviceName = kv.Value.GetStringValue()
+		}
+	}
+	if serviceName != "opa-test" {
+		t.Fatalf("expected resource with service name, got %v", resourceLogs.GetResource())
+	}
+
+	if len(resourceLogs.ScopeLogs) != 1 {
+		t.Fatalf("expected 1 scope logs, got %d", len(resourceLogs.ScopeLogs))
+	}
+
+	scopeLogs := resourceLogs.ScopeLogs[0]
+	if scopeLogs.GetScope().GetName() != otlpScopeName {
+		t.Errorf("expected scope %v, got %v", otlpScopeName, scopeLogs.GetScope().GetName())
+	}
+
+	if len(scopeLogs.LogRecords) != 1 {
+		t.Fatalf("expected 1 log record, got %d", len(scopeLogs.LogRecords))
+	}
+
+	record := scopeLogs.LogRecords[0]
+
+	if id := hex.EncodeToString(record.TraceId); id != traceID {
+		t.Errorf("expected trace ID %v, got %v", traceID, id)
+	}
+
+	if id := hex.EncodeToString(record.SpanId); id != spanID {
+		t.Errorf("expected span ID %v, got %v", spanID, id)
+	}
+
+	attrs := map[string]string{}
+	for _, kv := range record.Attributes {
+		attrs[kv.Key] = kv.Value.GetStringValue()
+	}
+
+	for k, v := range map[string]string{"decision_id": "abc", "trace_id": traceID, "span_id": spanID, "path": "authz/allow"} {
+		if attrs[k] != v {
+			t.Errorf("expected attribute %v to be %q, got %q", k, v, attrs[k])
+		}
+	}
+
+	body := record.GetBody()
+
+	fields := map[string]*commonpb.AnyValue{}
+	for _, kv := range body.GetKvlistValue().GetValues() {
+		fields[kv.Key] = kv.Value
+	}
+
+	if fields["decision_id"].GetStringValue() != "abc" || !fields["result"].GetBoolValue() {
+		t.Errorf("unexpected body: %v", body)
+	}
+}
+
+func TestPluginOTLPWithoutDistributedTracing(t *testing.T) {
+	manager, err := plugins.New(nil, "test-instance-id", inmem.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := ParseConfig([]byte(`{"otlp": true}`), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	plugin := New(config, manager)
+	if err := plugin.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer plugin.Stop(ctx)
+
+	if plugin.otlp != nil {
+		t.Fatal("expected no OTLP exporter")
+	}
+
+	if err := plugin.Log(ctx, &server.Info{DecisionID: "abc"}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -21,6 +21,7 @@ import (
 
 	"golang.org/x/time/rate"
 
+	"github.com/open-policy-agent/opa/internal/distributedtracing"
 	"github.com/open-policy-agent/opa/internal/ref"
 	"github.com/open-policy-agent/opa/v1/ast"
 	"github.com/open-policy-agent/opa/v1/logging"
@@ -274,14 +275,15 @@ const (
 
 // ReportingConfig represents configuration for the plugin's reporting behaviour.
 type ReportingConfig struct {
-	BufferType            string               `json:"buffer_type,omitempty"`              // toggles how the buffer stores events, defaults to using bytes
-	BufferSizeLimitBytes  *int64               `json:"buffer_size_limit_bytes,omitempty"`  // max size of in-memory size buffer
-	BufferSizeLimitEvents *int64               `json:"buffer_size_limit_events,omitempty"` // max size of in-memory event channel buffer
-	UploadSizeLimitBytes  *int64               `json:"upload_size_limit_bytes,omitempty"`  // max size of upload payload
-	MinDelaySeconds       *int64               `json:"min_delay_seconds,omitempty"`        // min amount of time to wait between successful poll attempts
-	MaxDelaySeconds       *int64               `json:"max_delay_seconds,omitempty"`        // max amount of time to wait between poll attempts
-	MaxDecisionsPerSecond *float64             `json:"max_decisions_per_second,omitempty"` // max number of decision logs to buffer per second
-	Trigger               *plugins.TriggerMode `json:"trigger,omitempty"`                  // trigger mode
+	BufferType            string               `json:"buffer_type,omitempty" default:"size" validate:"oneof=size event"` // toggles how the buffer stores events, defaults to using bytes
+	BufferSizeLimitBytes  *int64               `json:"buffer_size_limit_bytes,omitempty" validate:"min=1"`               // max size of in-memory size buffer
+	BufferSizeLimitEvents *int64               `json:"buffer_size_limit_events,omitempty" validate:"min=1"`              // max size of in-memory event channel buffer
+	UploadSizeLimitBytes  *int64               `json:"upload_size_limit_bytes,omitempty"`                                // max size of upload payload
+	MinDelaySeconds       *int64               `json:"min_delay_seconds,omitempty"`                                      // min amount of time to wait between successful poll attempts
+	MaxDelaySeconds       *int64               `json:"max_delay_seconds,omitempty"`                                      // max amount of time to wait between poll attempts
+	MaxDecisionsPerSecond *float64             `json:"max_decisions_per_second,omitempty"`                               // max number of decision logs to buffer per second
+	Trigger               *plugins.TriggerMode `json:"trigger,omitempty"`                                                // trigger mode
+	Backpressure          *BackpressureConfig  `json:"backpressure,omitempty"`                                           // backpressure signaled when uploads fall behind
 }
 
 type RequestContextConfig struct {
@@ -299,11 +301,12 @@ type Config struct {
 	PartitionName   string               `json:"partition_name,omitempty"`
 	Reporting       ReportingConfig      `json:"reporting"`
 	RequestContext  RequestContextConfig `json:"request_context"`
-	MaskDecision    *string              `json:"mask_decision"`
-	DropDecision    *string              `json:"drop_decision"`
+	MaskDecision    *string              `json:"mask_decision" default:"/system/log/mask"`
+	DropDecision    *string              `json:"drop_decision" default:"/system/log/drop"`
 	ConsoleLogs     bool                 `json:"console"`
 	Resource        *string              `json:"resource"`
 	NDBuiltinCache  bool                 `json:"nd_builtin_cache,omitempty"`
+	OTLP            bool                 `json:"otlp,omitempty"`
 	maskDecisionRef ast.Ref
 	dropDecisionRef ast.Ref
 }
@@ -384,12 +387,6 @@ func (c *Config) validateAndInjectDefaults(services []string, pluginsList []stri
 		c.Reporting.UploadSizeLimitBytes = &uploadLimit
 	}
 
-	if c.Reporting.BufferType == "" {
-		c.Reporting.BufferType = sizeBufferType
-	} else if c.Reporting.BufferType != eventBufferType && c.Reporting.BufferType != sizeBufferType {
-		return fmt.Errorf("invalid buffer type %q, expected %q or %q", c.Reporting.BufferType, eventBufferType, sizeBufferType)
-	}
-
 	if c.Reporting.BufferType == eventBufferType && c.Reporting.BufferSizeLimitBytes != nil {
 		return fmt.Errorf("invalid decision_log config, 'buffer_size_limit_bytes' isn't supported for the %v buffer type", eventBufferType)
 	}
@@ -404,25 +401,20 @@ func (c *Config) validateAndInjectDefaults(services []string, pluginsList []stri
 	// default the buffer size limit
 	sizeBufferLimit := defaultBufferSizeLimitBytes
 	if c.Reporting.BufferSizeLimitBytes != nil {
-		if *c.Reporting.BufferSizeLimitBytes <= int64(0) {
-			return errors.New("invalid decision_log config, 'buffer_size_limit_bytes' must be higher than 0")
-		}
 		sizeBufferLimit = *c.Reporting.BufferSizeLimitBytes
 	}
 	c.Reporting.BufferSizeLimitBytes = &sizeBufferLimit
 
 	eventBufferLimit := defaultBufferSizeLimitEvents
 	if c.Reporting.BufferSizeLimitEvents != nil {
-		if *c.Reporting.BufferSizeLimitEvents <= int64(0) {
-			return errors.New("invalid decision_log config, 'buffer_size_limit_entries' must be higher than 0")
-		}
 		eventBufferLimit = *c.Reporting.BufferSizeLimitEvents
 	}
 	c.Reporting.BufferSizeLimitEvents = &eventBufferLimit
 
-	if c.MaskDecision == nil {
-		maskDecision := defaultMaskDecisionPath
-		c.MaskDecision = &maskDecision
+	if c.Reporting.Backpressure != nil {
+		if err := c.Reporting.Backpressure.validateAndInjectDefaults(&c.Reporting); err != nil {
+			return err
+		}
 	}
 
 	c.maskDecisionRef, err = ref.ParseDataPath(*c.MaskDecision)
@@ -430,11 +422,6 @@ func (c *Config) validateAndInjectDefaults(services []string, pluginsList []stri
 		return fmt.Errorf("invalid mask_decision in decision_logs: %w", err)
 	}
 
-	if c.DropDecision == nil {
-		dropDecision := defaultDropDecisionPath
-		c.DropDecision = &dropDecision
-	}
-
 	c.dropDecisionRef, err = ref.ParseDataPath(*c.DropDecision)
 	if err != nil {
 		return fmt.Errorf("invalid drop_decision in decision_logs: %w", err)
@@ -474,6 +461,9 @@ type Plugin struct {
 	metrics       metrics.Metrics
 	logger        logging.Logger
 	status        *lstat.Status
+	otlp          *distributedtracing.LogExporter
+	backpressure  *backpressure // nil if backpressure is not configured
+	drain         chan struct{} // signals the loop to upload before the next polling interval
 }
 
 type prepareOnce struct {
@@ -566,11 +556,11 @@ func (b *ConfigBuilder) Parse() (*Config, error) {
 
 	var parsedConfig Config
 
-	if err := util.Unmarshal(b.raw, &parsedConfig); err != nil {
+	if err := plugins.DecodeConfig("decision_logs", b.raw, &parsedConfig); err != nil {
 		return nil, err
 	}
 
-	if parsedConfig.Plugin == nil && parsedConfig.Service == "" && len(b.services) == 0 && !parsedConfig.ConsoleLogs {
+	if parsedConfig.Plugin == nil && parsedConfig.Service == "" && len(b.services) == 0 && !parsedConfig.ConsoleLogs && !parsedConfig.OTLP {
 		// Nothing to validate or inject
 		return nil, nil
 	}
@@ -591,6 +581,7 @@ func New(parsedConfig *Config, manager *plugins.Manager) *Plugin {
 		stop:         make(chan chan struct{}),
 		enc:          newChunkEncoder(*parsedConfig.Reporting.UploadSizeLimitBytes),
 		reconfig:     make(chan reconfigure),
+		drain:        make(chan struct{}, 1),
 		logger:       manager.Logger().WithFields(map[string]any{"plugin": Name}),
 		status:       &lstat.Status{},
 		preparedDrop: *newPrepareOnce(),
@@ -613,6 +604,10 @@ func New(parsedConfig *Config, manager *plugins.Manager) *Plugin {
 		plugin.runningBuffer = sizeBufferType
 	}
 
+	if parsedConfig.Reporting.Backpressure != nil {
+		plugin.backpressure = newBackpressure(*parsedConfig.Reporting.Backpressure)
+	}
+
 	if parsedConfig.Reporting.MaxDecisionsPerSecond != nil {
 		limit := *parsedConfig.Reporting.MaxDecisionsPerSecond
 		plugin.limiter = rate.NewLimiter(rate.Limit(limit), int(math.Max(1, limit)))
@@ -646,6 +641,11 @@ func Lookup(manager *plugins.Manager) *Plugin {
 // Start starts the plugin.
 func (p *Plugin) Start(_ context.Context) error {
 	p.logger.Info("Starting decision logger.")
+	if p.config.OTLP {
+		p.reconfigMtx.Lock()
+		p.startOTLP()
+		p.reconfigMtx.Unlock()
+	}
 	go p.loop()
 	p.manager.UpdatePluginStatus(Name, &plugins.Status{State: plugins.StateOK})
 	return nil
@@ -664,6 +664,11 @@ func (p *Plugin) Stop(ctx context.Context) {
 	done := make(chan struct{})
 	p.stop <- done
 	<-done
+
+	p.reconfigMtx.Lock()
+	p.stopOTLP(ctx)
+	p.reconfigMtx.Unlock()
+
 	p.manager.UpdatePluginStatus(Name, &plugins.Status{State: plugins.StateNotReady})
 }
 
@@ -782,8 +787,20 @@ func (p *Plugin) Log(ctx context.Context, decision *server.Info) error {
 		}
 	}
 
+	if p.config.OTLP {
+		if err := p.exportEvent(event); err != nil {
+			p.logger.Error("Failed to export decision with OTLP: %v.", err)
+		}
+	}
+
 	if p.config.Service != "" {
-		p.encodeAndBufferEvent(event)
+		buffer, err := p.applyBackpressure(ctx)
+		if err != nil {
+			return err
+		}
+		if buffer {
+			p.encodeAndBufferEvent(event)
+		}
 	}
 
 	if p.config.Plugin != nil {
@@ -817,6 +834,11 @@ func (p *Plugin) Trigger(ctx context.Context) error {
 	go func() {
 		if p.config.Service != "" {
 			err := p.doOneShot(ctx)
+
+			p.reconfigMtx.RLock()
+			p.updateBackpressure()
+			p.reconfigMtx.RUnlock()
+
 			if err != nil {
 				if ctx.Err() == nil {
 					done <- err
@@ -853,6 +875,10 @@ func (p *Plugin) loop() {
 		if *p.config.Reporting.Trigger == plugins.TriggerPeriodic && p.config.Service != "" {
 			err := p.doOneShot(ctx)
 
+			p.reconfigMtx.RLock()
+			p.updateBackpressure()
+			p.reconfigMtx.RUnlock()
+
 			var delay time.Duration
 
 			if err == nil {
@@ -860,7 +886,7 @@ func (p *Plugin) loop() {
 				max := float64(*p.config.Reporting.MaxDelaySeconds)
 				delay = time.Duration(((max - min) * rand.Float64()) + min)
 			} else {
-				delay = util.DefaultBackoff(float64(minRetryDelay), float64(*p.config.Reporting.MaxDelaySeconds), retry)
+				delay = rest.Backoff(minRetryDelay, time.Duration(*p.config.Reporting.MaxDelaySeconds), retry, err)
 			}
 
 			p.logger.Debug("Waiting %v before next upload/retry.", delay)
@@ -884,6 +910,7 @@ func (p *Plugin) loop() {
 
 		select {
 		case <-waitC:
+		case <-p.drain:
 		case update := <-p.reconfig:
 			p.reconfigure(ctx, update.config)
 			update.done <- struct{}{}
@@ -992,6 +1019,20 @@ func (p *Plugin) reconfigure(ctx context.Context, config any) {
 	p.reconfigMtx.Lock()
 	defer p.reconfigMtx.Unlock()
 
+	if p.config.OTLP {
+		p.startOTLP()
+	} else {
+		p.stopOTLP(ctx)
+	}
+
+	if p.backpressure != nil {
+		p.backpressure.Close()
+		p.backpressure = nil
+	}
+	if p.config.Reporting.Backpressure != nil {
+		p.backpressure = newBackpressure(*p.config.Reporting.Backpressure)
+	}
+
 	switch newConfig.Reporting.BufferType {
 	case eventBufferType:
 		if p.eventBuffer == nil {
@@ -1185,7 +1226,8 @@ func uploadChunk(ctx context.Context, client rest.Client, uploadPath string, dat
 	defer util.Close(resp)
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return lstat.HTTPError{StatusCode: resp.StatusCode}
+		retryAfter, _ := rest.RetryAfter(resp)
+		return lstat.HTTPError{StatusCode: resp.StatusCode, RetryAfterDelay: retryAfter}
 	}
 
 	return nil
@@ -147,9 +147,9 @@ func BenchmarkMaskingNop(b *testing.B) {
 		b.Fatal(err)
 	}
 
-	cfg := &Config{Service: "svc"}
 	t := plugins.DefaultTriggerMode
-	if err := cfg.validateAndInjectDefaults([]string{"svc"}, nil, &t, nil); err != nil {
+	cfg, err := NewConfigBuilder().WithBytes([]byte(`{"service": "svc"}`)).WithServices([]string{"svc"}).WithTriggerMode(&t).Parse()
+	if err != nil {
 		b.Fatal(err)
 	}
 	plugin := New(cfg, manager)
@@ -185,9 +185,9 @@ func BenchmarkMaskingRuleCountsNop(b *testing.B) {
 		b.Fatal(err)
 	}
 
-	cfg := &Config{Service: "svc"}
 	t := plugins.DefaultTriggerMode
-	if err := cfg.validateAndInjectDefaults([]string{"svc"}, nil, &t, nil); err != nil {
+	cfg, err := NewConfigBuilder().WithBytes([]byte(`{"service": "svc"}`)).WithServices([]string{"svc"}).WithTriggerMode(&t).Parse()
+	if err != nil {
 		b.Fatal(err)
 	}
 	plugin := New(cfg, manager)
@@ -238,9 +238,9 @@ func BenchmarkMaskingErase(b *testing.B) {
 		b.Fatal(err)
 	}
 
-	cfg := &Config{Service: "svc"}
 	t := plugins.DefaultTriggerMode
-	if err := cfg.validateAndInjectDefaults([]string{"svc"}, nil, &t, nil); err != nil {
+	cfg, err := NewConfigBuilder().WithBytes([]byte(`{"service": "svc"}`)).WithServices([]string{"svc"}).WithTriggerMode(&t).Parse()
+	if err != nil {
 		b.Fatal(err)
 	}
 	plugin := New(cfg, manager)
@@ -2585,9 +2585,9 @@ func TestPluginMasking(t *testing.T) {
 			}
 
 			// Instantiate the plugin.
-			cfg := &Config{Service: "svc"}
 			trigger := plugins.DefaultTriggerMode
-			if err := cfg.validateAndInjectDefaults([]string{"svc"}, nil, &trigger, nil); err != nil {
+			cfg, err := NewConfigBuilder().WithBytes([]byte(`{"service": "svc"}`)).WithServices([]string{"svc"}).WithTriggerMode(&trigger).Parse()
+			if err != nil {
 				t.Fatal(err)
 			}
 
@@ -2637,9 +2637,8 @@ func TestPluginMasking(t *testing.T) {
 			// if reconfigure in test is on
 			if tc.reconfigure {
 				// Reconfigure and ensure that mask is invalidated.
-				maskDecision := "dead/beef"
-				newConfig := &Config{Service: "svc", MaskDecision: &maskDecision}
-				if err := newConfig.validateAndInjectDefaults([]string{"svc"}, nil, &trigger, nil); err != nil {
+				newConfig, err := NewConfigBuilder().WithBytes([]byte(`{"service": "svc", "mask_decision": "dead/beef"}`)).WithServices([]string{"svc"}).WithTriggerMode(&trigger).Parse()
+				if err != nil {
 					t.Fatal(err)
 				}
 
@@ -2737,9 +2736,9 @@ func TestPluginDrop(t *testing.T) {
 			}
 
 			// Instantiate the plugin.
-			cfg := &Config{Service: "svc"}
 			trigger := plugins.DefaultTriggerMode
-			if err := cfg.validateAndInjectDefaults([]string{"svc"}, nil, &trigger, nil); err != nil {
+			cfg, err := NewConfigBuilder().WithBytes([]byte(`{"service": "svc"}`)).WithServices([]string{"svc"}).WithTriggerMode(&trigger).Parse()
+			if err != nil {
 				t.Fatal(err)
 			}
 
@@ -2810,9 +2809,9 @@ func TestPluginMaskErrorHandling(t *testing.T) {
 	}
 
 	// Instantiate the plugin.
-	cfg := &Config{Service: "svc"}
 	trigger := plugins.DefaultTriggerMode
-	if err := cfg.validateAndInjectDefaults([]string{"svc"}, nil, &trigger, nil); err != nil {
+	cfg, err := NewConfigBuilder().WithBytes([]byte(`{"service": "svc"}`)).WithServices([]string{"svc"}).WithTriggerMode(&trigger).Parse()
+	if err != nil {
 		t.Fatal(err)
 	}
 
@@ -2888,9 +2887,9 @@ func TestPluginDropErrorHandling(t *testing.T) {
 	}
 
 	// Instantiate the plugin.
-	cfg := &Config{Service: "svc"}
 	trigger := plugins.DefaultTriggerMode
-	if err := cfg.validateAndInjectDefaults([]string{"svc"}, nil, &trigger, nil); err != nil {
+	cfg, err := NewConfigBuilder().WithBytes([]byte(`{"service": "svc"}`)).WithServices([]string{"svc"}).WithTriggerMode(&trigger).Parse()
+	if err != nil {
 		t.Fatal(err)
 	}
 
@@ -3755,14 +3754,14 @@ func TestConfigUploadLimit(t *testing.T) {
 
 			testLogger := test.New()
 
-			cfg := &Config{
-				Service: "svc",
-				Reporting: ReportingConfig{
-					UploadSizeLimitBytes: &tc.limit,
-				},
-			}
 			trigger := plugins.DefaultTriggerMode
-			if err := cfg.validateAndInjectDefaults([]string{"svc"}, nil, &trigger, testLogger); err != nil {
+			cfg, err := NewConfigBuilder().
+				WithBytes(fmt.Appendf(nil, `{"service": "svc", "reporting": {"upload_size_limit_bytes": %d}}`, tc.limit)).
+				WithServices([]string{"svc"}).
+				WithTriggerMode(&trigger).
+				WithLogger(testLogger).
+				Parse()
+			if err != nil {
 				if tc.expectedErr != "" {
 					if tc.expectedErr != err.Error() {
 						t.Fatalf("Expected error to be `%s` but got `%s`", tc.expectedErr, err.Error())
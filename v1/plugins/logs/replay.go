@@ -0,0 +1,234 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package logs
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/rego"
+	"github.com/open-policy-agent/opa/v1/storage"
+	"github.com/open-policy-agent/opa/v1/topdown/builtins"
+	"github.com/open-policy-agent/opa/v1/util"
+)
+
+// ReplayOptions configures how logged decisions are re-evaluated.
+type ReplayOptions struct {
+	Compiler *ast.Compiler // compiler holding the policies to replay the decisions against
+	Store    storage.Store // store holding the data to replay the decisions against
+}
+
+// ReplayResult is the outcome of re-evaluating a logged decision.
+type ReplayResult struct {
+	DecisionID string `json:"decision_id,omitempty"`
+	Path       string `json:"path,omitempty"`
+	Query      string `json:"query,omitempty"`
+	Expected   *any   `json:"expected,omitempty"` // result recorded in the decision log
+	Actual     *any   `json:"actual,omitempty"`   // result of the replay
+	Changed    bool   `json:"changed"`
+	Skipped    string `json:"skipped,omitempty"` // reason the decision was not replayed
+	Error      string `json:"error,omitempty"`   // error of the replay
+}
+
+// replayEvent decodes the error of a logged decision, which cannot be
+// decoded into the error interface of EventV1.
+type replayEvent struct {
+	EventV1
+	Error json.RawMessage `json:"error,omitempty"`
+}
+
+// Replay reads the decision log events from r and re-evaluates each decision
+// against the policies and data in opts, calling f with the result. The events
+// may be newline-delimited, as written to the console, or JSON arrays, as
+// uploaded to the decision log service, optionally gzip compressed.
+//
+// The non-deterministic builtin results recorded with a decision are reused
+// in the replay. Decisions whose input or result were erased, and decisions
+// that failed, are skipped.
+func Replay(ctx context.Context, r io.Reader, opts ReplayOptions, f func(ReplayResult) error) error {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer gr.Close()
+		br = bufio.NewReader(gr)
+	}
+
+	decoder := util.NewJSONDecoder(br)
+
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("failed to read decision log: %w", err)
+		}
+
+		var events []replayEvent
+		if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("[")) {
+			if err := util.UnmarshalJSON(raw, &events); err != nil {
+				return fmt.Errorf("failed to read decision log: %w", err)
+			}
+		} else {
+			var event replayEvent
+			if err := util.UnmarshalJSON(raw, &event); err != nil {
+				return fmt.Errorf("failed to read decision log: %w", err)
+			}
+			events = append(events, event)
+		}
+
+		for i := range events {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := f(replay(ctx, &events[i], opts)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func replay(ctx context.Context, event *replayEvent, opts ReplayOptions) ReplayResult {
+	result := ReplayResult{
+		DecisionID: event.DecisionID,
+		Path:       event.Path,
+		Query:      event.Query,
+		Expected:   event.Result,
+	}
+
+	switch {
+	case len(event.Error) > 0 && string(event.Error) != "null":
+		result.Skipped = "decision failed"
+		return result
+	case slices.Contains(event.Erased, "/input"):
+		result.Skipped = "input erased"
+		return result
+	case slices.Contains(event.Erased, "/result"):
+		result.Skipped = "result erased"
+		return result
+	case event.Path == "" && event.Query == "":
+		result.Skipped = "no path or query"
+		return result
+	}
+
+	args := []func(*rego.Rego){
+		rego.Compiler(opts.Compiler),
+		rego.Store(opts.Store),
+	}
+
+	if event.Path != "" {
+		path, ok := storage.ParsePathEscaped("/" + strings.Trim(event.Path, "/"))
+		if !ok {
+			result.Error = fmt.Sprintf("invalid path %q", event.Path)
+			return result
+		}
+		args = append(args, rego.ParsedQuery(ast.NewBody(ast.NewExpr(ast.NewTerm(path.Ref(ast.DefaultRootDocument))))))
+	} else {
+		args = append(args, rego.Query(event.Query))
+	}
+
+	if event.Input != nil {
+		args = append(args, rego.Input(*event.Input))
+	}
+
+	if event.NDBuiltinCache != nil {
+		cache, err := ndbCacheFromLog(*event.NDBuiltinCache)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		args = append(args, rego.NDBuiltinCache(cache))
+	}
+
+	rs, err := rego.New(args...).Eval(ctx)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	// Build the result like the server logs it: the value of the document
+	// for path decisions, the bindings of each result for query decisions.
+	var actual any
+	if len(rs) > 0 {
+		if event.Path != "" {
+			actual = rs[0].Expressions[0].Value
+		} else {
+			bindings := make([]any, 0, len(rs))
+			for _, r := range rs {
+				bindings = append(bindings, r.Bindings.WithoutWildcards())
+			}
+			actual = bindings
+		}
+	}
+
+	if actual != nil {
+		if err := util.RoundTrip(&actual); err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		result.Actual = &actual
+	}
+
+	switch {
+	case result.Expected == nil || result.Actual == nil:
+		result.Changed = result.Expected != result.Actual
+	default:
+		result.Changed = util.Compare(*result.Expected, *result.Actual) != 0
+	}
+
+	return result
+}
+
+// ndbCacheFromLog rebuilds the non-deterministic builtin cache of a logged
+// decision. The arguments of the builtin calls are logged as JSON encoded
+// object keys.
+func ndbCacheFromLog(x any) (builtins.NDBCache, error) {
+	builtinCalls, ok := x.(map[string]any)
+	if !ok {
+		return nil, errors.New("invalid nd_builtin_cache")
+	}
+
+	cache := builtins.NDBCache{}
+
+	for name, v := range builtinCalls {
+		calls, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("invalid nd_builtin_cache entry for %v", name)
+		}
+
+		for key, value := range calls {
+			var operands any
+			if err := util.UnmarshalJSON([]byte(key), &operands); err != nil {
+				return nil, fmt.Errorf("invalid nd_builtin_cache entry for %v: %w", name, err)
+			}
+
+			k, err := ast.InterfaceToValue(operands)
+			if err != nil {
+				return nil, err
+			}
+
+			v, err := ast.InterfaceToValue(value)
+			if err != nil {
+				return nil, err
+			}
+
+			cache.Put(name, k, v)
+		}
+	}
+
+	return cache, nil
+}
@@ -0,0 +1,121 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package logs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	inmem "github.com/open-policy-agent/opa/v1/storage/inmem/test"
+)
+
+func TestReplay(t *testing.T) {
+	compiler := ast.MustCompileModules(map[string]string{
+		"authz.rego": `package authz
+
+allow if input.user in data.admins
+
+now := time.now_ns()`,
+	})
+
+	store := inmem.NewFromObject(map[string]any{"admins": []any{"alice"}})
+
+	log := strings.Join([]string{
+		`{"decision_id": "1", "path": "authz/allow", "input": {"user": "alice"}, "result": true}`,
+		`{"decision_id": "2", "path": "authz/allow", "input": {"user": "bob"}, "result": true}`,
+		`{"decision_id": "3", "query": "x := data.authz.allow", "input": {"user": "alice"}, "result": [{"x": true}]}`,
+		`{"decision_id": "4", "query": "data.authz.allow", "input": {"user": "bob"}}`,
+		`{"decision_id": "5", "path": "authz/now", "nd_builtin_cache": {"time.now_ns": {"[]": 42}}, "result": 42}`,
+		`{"decision_id": "6", "path": "authz/allow", "erased": ["/input"], "result": true}`,
+		`{"decision_id": "7", "path": "authz/allow", "error": {"code": "internal_error"}}`,
+		`{"level": "info", "msg": "Decision Log", "decision_id": "8", "path": "authz/allow", "input": {"user": "alice"}, "result": false}`,
+	}, "\n")
+
+	exp := []ReplayResult{
+		{DecisionID: "1", Path: "authz/allow"},
+		{DecisionID: "2", Path: "authz/allow", Changed: true},
+		{DecisionID: "3", Query: "x := data.authz.allow"},
+		{DecisionID: "4", Query: "data.authz.allow"},
+		{DecisionID: "5", Path: "authz/now"},
+		{DecisionID: "6", Path: "authz/allow", Skipped: "input erased"},
+		{DecisionID: "7", Path: "authz/allow", Skipped: "decision failed"},
+		{DecisionID: "8", Path: "authz/allow", Changed: true},
+	}
+
+	var results []ReplayResult
+	err := Replay(context.Background(), strings.NewReader(log), ReplayOptions{Compiler: compiler, Store: store}, func(r ReplayResult) error {
+		results = append(results, r)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != len(exp) {
+		t.Fatalf("expected %d results, got %d: %+v", len(exp), len(results), results)
+	}
+
+	for i := range exp {
+		act := results[i]
+		if act.DecisionID != exp[i].DecisionID || act.Path != exp[i].Path || act.Query != exp[i].Query ||
+			act.Changed != exp[i].Changed || act.Skipped != exp[i].Skipped || act.Error != "" {
+			t.Errorf("expected result %+v, got %+v", exp[i], act)
+		}
+	}
+
+	if results[1].Actual != nil || results[1].Expected == nil || *results[1].Expected != true {
+		t.Errorf("expected replay of decision 2 to be undefined, got %+v", results[1])
+	}
+}
+
+func TestReplayUploadedChunk(t *testing.T) {
+	compiler := ast.MustCompileModules(map[string]string{
+		"authz.rego": `package authz
+
+allow if input.user == "alice"`,
+	})
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, _ = w.Write([]byte(`[
+		{"decision_id": "1", "path": "authz/allow", "input": {"user": "alice"}, "result": true},
+		{"decision_id": "2", "path": "authz/allow", "input": {"user": "bob"}, "result": true}
+	]`))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var changed []string
+	err := Replay(context.Background(), &buf, ReplayOptions{Compiler: compiler, Store: inmem.New()}, func(r ReplayResult) error {
+		if r.Changed {
+			changed = append(changed, r.DecisionID)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(changed) != 1 || changed[0] != "2" {
+		t.Fatalf("expected decision 2 to change, got %v", changed)
+	}
+}
+
+func TestReplayInvalidLog(t *testing.T) {
+	err := Replay(context.Background(), strings.NewReader(`{"decision_id": "1"} nope`), ReplayOptions{}, func(r ReplayResult) error {
+		if r.Skipped != "no path or query" {
+			t.Errorf("expected decision without path or query to be skipped, got %+v", r)
+		}
+		return nil
+	})
+
+	if err == nil || !strings.Contains(err.Error(), "failed to read decision log") {
+		t.Fatalf("expected read error, got %v", err)
+	}
+}
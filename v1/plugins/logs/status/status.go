@@ -11,6 +11,7 @@ import (
 	"net/http"
 	"reflect"
 	"strconv"
+	"time"
 
 	"github.com/open-policy-agent/opa/v1/metrics"
 )
@@ -55,9 +56,15 @@ func (s *Status) Equal(other *Status) bool {
 }
 
 type HTTPError struct {
-	StatusCode int
+	StatusCode      int
+	RetryAfterDelay time.Duration // delay the server asked for in a Retry-After header
 }
 
 func (e HTTPError) Error() string {
 	return fmt.Sprintf("log upload failed, server replied with HTTP %v %v", e.StatusCode, http.StatusText(e.StatusCode))
 }
+
+// RetryAfter returns the delay the server asked for in a Retry-After header.
+func (e HTTPError) RetryAfter() time.Duration {
+	return e.RetryAfterDelay
+}
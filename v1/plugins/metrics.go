@@ -0,0 +1,115 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package plugins
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/open-policy-agent/opa/v1/logging"
+)
+
+// PluginMetrics creates the Prometheus metrics of a plugin, which are
+// exported under the opa_plugin_<name>_ prefix, e.g., by the /metrics
+// endpoint of the server. The namespace and subsystem of the options passed to
+// its methods are overwritten accordingly.
+//
+// Creating a metric that has already been created returns the existing
+// metric, so that plugins can create their metrics whenever they are started
+// or reconfigured. If no Prometheus registerer is configured, the metrics are
+// created but not exported.
+type PluginMetrics struct {
+	subsystem string
+	reg       prometheus.Registerer
+	logger    logging.Logger
+}
+
+// MetricsFor returns the factory for the Prometheus metrics of the named
+// plugin. Characters of the name that are not allowed in metric names are
+// replaced by underscores.
+func (m *Manager) MetricsFor(name string) *PluginMetrics {
+	return &PluginMetrics{
+		subsystem: "plugin_" + metricNameFragment(name),
+		reg:       m.prometheusRegister,
+		logger:    m.logger,
+	}
+}
+
+// NewCounter creates a counter, or returns the existing one of the same name.
+func (pm *PluginMetrics) NewCounter(opts prometheus.CounterOpts) prometheus.Counter {
+	opts.Namespace, opts.Subsystem = "opa", pm.subsystem
+	return register(pm, prometheus.NewCounter(opts))
+}
+
+// NewCounterVec creates a counter vector, or returns the existing one of the
+// same name.
+func (pm *PluginMetrics) NewCounterVec(opts prometheus.CounterOpts, labels []string) *prometheus.CounterVec {
+	opts.Namespace, opts.Subsystem = "opa", pm.subsystem
+	return register(pm, prometheus.NewCounterVec(opts, labels))
+}
+
+// NewGauge creates a gauge, or returns the existing one of the same name.
+func (pm *PluginMetrics) NewGauge(opts prometheus.GaugeOpts) prometheus.Gauge {
+	opts.Namespace, opts.Subsystem = "opa", pm.subsystem
+	return register(pm, prometheus.NewGauge(opts))
+}
+
+// NewGaugeVec creates a gauge vector, or returns the existing one of the same
+// name.
+func (pm *PluginMetrics) NewGaugeVec(opts prometheus.GaugeOpts, labels []string) *prometheus.GaugeVec {
+	opts.Namespace, opts.Subsystem = "opa", pm.subsystem
+	return register(pm, prometheus.NewGaugeVec(opts, labels))
+}
+
+// NewHistogram creates a histogram, or returns the existing one of the same
+// name.
+func (pm *PluginMetrics) NewHistogram(opts prometheus.HistogramOpts) prometheus.Histogram {
+	opts.Namespace, opts.Subsystem = "opa", pm.subsystem
+	return register(pm, prometheus.NewHistogram(opts))
+}
+
+// NewHistogramVec creates a histogram vector, or returns the existing one of
+// the same name.
+func (pm *PluginMetrics) NewHistogramVec(opts prometheus.HistogramOpts, labels []string) *prometheus.HistogramVec {
+	opts.Namespace, opts.Subsystem = "opa", pm.subsystem
+	return register(pm, prometheus.NewHistogramVec(opts, labels))
+}
+
+// register registers c, and returns it, or the collector registered before
+// under its name. Other registration errors are logged, and c is returned
+// without being exported.
+func register[T prometheus.Collector](pm *PluginMetrics, c T) T {
+	if pm.reg == nil {
+		return c
+	}
+
+	err := pm.reg.Register(c)
+	if err == nil {
+		return c
+	}
+
+	var are prometheus.AlreadyRegisteredError
+	if errors.As(err, &are) {
+		if existing, ok := are.ExistingCollector.(T); ok {
+			return existing
+		}
+	}
+
+	pm.logger.Warn("Failed to register %s metric: %v", pm.subsystem, err)
+	return c
+}
+
+// metricNameFragment replaces the characters of s that are not allowed in
+// Prometheus metric names by underscores.
+func metricNameFragment(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, s)
+}
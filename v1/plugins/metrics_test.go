@@ -0,0 +1,48 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package plugins
+
+import (
+	"testing"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	inmem "github.com/open-policy-agent/opa/v1/storage/inmem/test"
+)
+
+func TestManagerMetricsFor(t *testing.T) {
+	registry := prom.NewRegistry()
+	m, err := New([]byte{}, "test", inmem.New(), WithPrometheusRegister(registry))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metrics := m.MetricsFor("my-plugin")
+	metrics.NewCounter(prom.CounterOpts{Name: "events_total", Help: "Events."}).Inc()
+
+	// Creating the counter again, e.g., on reconfiguration, returns the existing one.
+	metrics.NewCounter(prom.CounterOpts{Name: "events_total", Help: "Events."}).Inc()
+
+	metrics.NewGaugeVec(prom.GaugeOpts{Name: "queue_size", Help: "Queue size."}, []string{"queue"}).WithLabelValues("a").Set(3)
+
+	if n, err := testutil.GatherAndCount(registry, "opa_plugin_my_plugin_events_total", "opa_plugin_my_plugin_queue_size"); err != nil {
+		t.Fatal(err)
+	} else if n != 2 {
+		t.Fatalf("expected 2 metrics, got %d", n)
+	}
+
+	counter := metrics.NewCounter(prom.CounterOpts{Name: "events_total", Help: "Events."})
+	if v := testutil.ToFloat64(counter); v != 2 {
+		t.Fatalf("expected counter value 2, got %v", v)
+	}
+
+	// Without a registerer, metrics are created but not exported.
+	m, err = New([]byte{}, "test", inmem.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.MetricsFor("my-plugin").NewHistogram(prom.HistogramOpts{Name: "latency_seconds"}).Observe(1)
+}
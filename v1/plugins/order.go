@@ -0,0 +1,182 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package plugins
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/open-policy-agent/opa/v1/util"
+)
+
+// DependencyOrder returns the given registered plugin names sorted so that
+// every plugin comes after the plugins it depends on. Plugins that do not
+// depend on each other keep their registration order. An error is returned if
+// the dependencies of the registered plugins form a cycle, or refer to a
+// plugin that has not been registered.
+func (m *Manager) DependencyOrder(names []string) ([]string, error) {
+	m.mtx.Lock()
+	ordered, err := dependencyOrder(m.plugins)
+	m.mtx.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	want := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		want[name] = struct{}{}
+	}
+
+	result := make([]string, 0, len(names))
+	for _, np := range ordered {
+		if _, ok := want[np.name]; ok {
+			result = append(result, np.name)
+			delete(want, np.name)
+		}
+	}
+
+	// Names not registered with the manager have no known dependencies.
+	for _, name := range names {
+		if _, ok := want[name]; ok {
+			result = append(result, name)
+		}
+	}
+
+	return result, nil
+}
+
+// ValidateDependencies returns an error if the dependencies between plugins,
+// given as a map from plugin name to the names of the plugins it depends on,
+// form a cycle. Dependencies on plugins not present in deps are not checked.
+func ValidateDependencies(deps map[string][]string) error {
+	ps := make([]namedplugin, 0, len(deps))
+	for _, name := range util.KeysSorted(deps) {
+		ps = append(ps, namedplugin{name: name, deps: deps[name]})
+	}
+	_, err := sortByDependencies(ps, true)
+	return err
+}
+
+func startOrder(ps []namedplugin) ([]Plugin, error) {
+	ordered, err := dependencyOrder(ps)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Plugin, len(ordered))
+	for i := range ordered {
+		result[i] = ordered[i].plugin
+	}
+	return result, nil
+}
+
+// stopOrder returns the plugins in ps so that every plugin comes before the
+// plugins it depends on. Unknown dependencies and cycles are ignored, so that
+// all plugins are always stopped.
+func stopOrder(ps []namedplugin) []namedplugin {
+	index := make(map[string]int, len(ps))
+	for i := range ps {
+		index[ps[i].name] = i
+	}
+
+	dependents := make([][]int, len(ps))
+	for i := range ps {
+		for _, dep := range ps[i].deps {
+			if j, ok := index[dep]; ok {
+				dependents[j] = append(dependents[j], i)
+			}
+		}
+	}
+
+	visited := make([]bool, len(ps))
+	result := make([]namedplugin, 0, len(ps))
+
+	var visit func(int)
+	visit = func(i int) {
+		if visited[i] {
+			return
+		}
+		visited[i] = true
+		// Dependents are appended first, so that they are stopped first.
+		for _, d := range dependents[i] {
+			visit(d)
+		}
+		result = append(result, ps[i])
+	}
+
+	for i := range ps {
+		visit(i)
+	}
+
+	return result
+}
+
+// dependencyOrder sorts ps topologically by their dependencies, keeping the
+// registration order for plugins that do not depend on each other.
+func dependencyOrder(ps []namedplugin) ([]namedplugin, error) {
+	return sortByDependencies(ps, false)
+}
+
+func sortByDependencies(ps []namedplugin, ignoreUnknown bool) ([]namedplugin, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	index := make(map[string]int, len(ps))
+	for i := range ps {
+		index[ps[i].name] = i
+	}
+
+	state := make([]int, len(ps))
+	result := make([]namedplugin, 0, len(ps))
+	var path []string
+
+	var visit func(int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case visited:
+			return nil
+		case visiting:
+			start := 0
+			for k := range path {
+				if path[k] == ps[i].name {
+					start = k
+					break
+				}
+			}
+			cycle := append(path[start:len(path):len(path)], ps[i].name)
+			return fmt.Errorf("plugin dependency cycle: %s", strings.Join(cycle, " -> "))
+		}
+
+		state[i] = visiting
+		path = append(path, ps[i].name)
+
+		for _, dep := range ps[i].deps {
+			j, ok := index[dep]
+			if !ok && ignoreUnknown {
+				continue
+			} else if !ok {
+				return fmt.Errorf("plugin %q depends on unknown plugin %q", ps[i].name, dep)
+			}
+			if err := visit(j); err != nil {
+				return err
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[i] = visited
+		result = append(result, ps[i])
+		return nil
+	}
+
+	for i := range ps {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
@@ -12,6 +12,7 @@ import (
 	"maps"
 	mr "math/rand"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -89,6 +90,15 @@ type Factory interface {
 	New(manager *Manager, config any) Plugin
 }
 
+// DependentFactory can be implemented by a Factory whose plugin requires other
+// plugins (e.g., the bundle plugin) to be started first. Dependencies returns
+// the names of those plugins. The manager starts plugins so that every plugin
+// is started after its dependencies, and stops them in the reverse order.
+type DependentFactory interface {
+	Factory
+	Dependencies() []string
+}
+
 // Plugin defines the interface OPA uses to manage your plugin.
 //
 // When OPA starts it will start all of the plugins it was configured
@@ -206,9 +216,13 @@ type Manager struct {
 	serverInitializedOnce        sync.Once
 	printHook                    print.Hook
 	enablePrintStatements        bool
+	queryCacheSize               int
 	router                       *http.ServeMux
 	prometheusRegister           prometheus.Registerer
+	retryMetrics                 *prometheus.CounterVec
+	sharedCache                  *rest.SharedCache
 	tracerProvider               *trace.TracerProvider
+	persistenceKeyProvider       storage.KeyProvider
 	distributedTacingOpts        tracing.Options
 	registeredNDCacheTriggers    []func(bool)
 	registeredTelemetryGatherers map[string]report.Gatherer
@@ -223,6 +237,8 @@ type Manager struct {
 	extraMiddlewares             []func(http.Handler) http.Handler
 	extraAuthorizerRoutes        []func(string, []any) bool
 	bundleActivatorPlugin        string
+	watchdogConfig               *WatchdogConfig
+	watchdog                     *watchdog
 }
 
 type managerContextKey string
@@ -307,6 +323,7 @@ func ValidateAndInjectDefaultsForTriggerMode(a, b *TriggerMode) (*TriggerMode, e
 type namedplugin struct {
 	name   string
 	plugin Plugin
+	deps   []string
 }
 
 // Info sets the runtime information on the manager. The runtime information is
@@ -367,6 +384,15 @@ func EnablePrintStatements(yes bool) func(*Manager) {
 	}
 }
 
+// WithQueryCacheSize enables caching up to size compiled queries in the
+// compilers activated by the manager, see ast.Compiler.WithQueryCache. The
+// cache of a compiler is dropped with it when policies change.
+func WithQueryCacheSize(size int) func(*Manager) {
+	return func(m *Manager) {
+		m.queryCacheSize = size
+	}
+}
+
 func PrintHook(h print.Hook) func(*Manager) {
 	return func(m *Manager) {
 		m.printHook = h
@@ -393,6 +419,14 @@ func WithTracerProvider(tracerProvider *trace.TracerProvider) func(*Manager) {
 	}
 }
 
+// WithPersistenceKeyProvider sets the storage.KeyProvider of the key that
+// encrypts the bundles persisted to disk by plugins.
+func WithPersistenceKeyProvider(kp storage.KeyProvider) func(*Manager) {
+	return func(m *Manager) {
+		m.persistenceKeyProvider = kp
+	}
+}
+
 // WithDistributedTracingOpts sets the options to be used by distributed tracing.
 func WithDistributedTracingOpts(tr tracing.Options) func(*Manager) {
 	return func(m *Manager) {
@@ -436,6 +470,14 @@ func WithBundleActivatorPlugin(bundleActivatorPlugin string) func(*Manager) {
 	}
 }
 
+// WithSharedCache sets the cache the service clients share with the clients
+// of other plugin managers, e.g., to download the same bundles only once.
+func WithSharedCache(c *rest.SharedCache) func(*Manager) {
+	return func(m *Manager) {
+		m.sharedCache = c
+	}
+}
+
 // New creates a new Manager using config.
 func New(raw []byte, id string, store storage.Store, opts ...func(*Manager)) (*Manager, error) {
 
@@ -473,6 +515,8 @@ func New(raw []byte, id string, store storage.Store, opts ...func(*Manager)) (*M
 		m.consoleLogger = logging.New()
 	}
 
+	m.retryMetrics = rest.NewRetryMetrics()
+
 	m.hooks.Each(func(h hooks.Hook) {
 		if f, ok := h.(hooks.ConfigHook); ok {
 			if c, e := f.OnConfig(context.Background(), parsedConfig); e != nil {
@@ -497,6 +541,18 @@ func New(raw []byte, id string, store storage.Store, opts ...func(*Manager)) (*M
 		return nil, err
 	}
 
+	m.watchdogConfig, err = parseWatchdogConfig(parsedConfig.Watchdog)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.persistenceKeyProvider == nil && parsedConfig.PersistenceEncryption != nil {
+		m.persistenceKeyProvider, err = parsePersistenceEncryptionConfig(parsedConfig.PersistenceEncryption)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	serviceOpts := m.DefaultServiceOpts(parsedConfig)
 
 	m.services, err = cfg.ParseServicesConfig(serviceOpts)
@@ -607,11 +663,18 @@ func (m *Manager) InterQueryBuiltinCacheConfig() *cache.Config {
 // Register adds a plugin to the manager. When the manager is started, all of
 // the plugins will be started.
 func (m *Manager) Register(name string, plugin Plugin) {
+	m.RegisterWithDependencies(name, plugin)
+}
+
+// RegisterWithDependencies adds a plugin to the manager that must only be
+// started once the plugins named by deps have been started.
+func (m *Manager) RegisterWithDependencies(name string, plugin Plugin, deps ...string) {
 	m.mtx.Lock()
 	defer m.mtx.Unlock()
 	m.plugins = append(m.plugins, namedplugin{
 		name:   name,
 		plugin: plugin,
+		deps:   deps,
 	})
 	if _, ok := m.pluginStatus[name]; !ok {
 		m.pluginStatus[name] = &Status{State: StateNotReady}
@@ -748,6 +811,7 @@ func (m *Manager) setWasmResolvers(rs []*wasm.Resolver) {
 }
 
 // Start starts the manager. Init() should be called once before Start().
+// If the watchdog is configured, it is started once the plugins have started.
 func (m *Manager) Start(ctx context.Context) error {
 
 	if m == nil {
@@ -761,22 +825,29 @@ func (m *Manager) Start(ctx context.Context) error {
 	}
 
 	var toStart []Plugin
+	var err error
 
 	func() {
 		m.mtx.Lock()
 		defer m.mtx.Unlock()
-		toStart = make([]Plugin, len(m.plugins))
-		for i := range m.plugins {
-			toStart[i] = m.plugins[i].plugin
-		}
+		toStart, err = startOrder(m.plugins)
 	}()
 
+	if err != nil {
+		return err
+	}
+
 	for i := range toStart {
 		if err := toStart[i].Start(ctx); err != nil {
 			return err
 		}
 	}
 
+	if m.watchdogConfig != nil && m.watchdog == nil {
+		m.watchdog = newWatchdog(m, *m.watchdogConfig)
+		m.watchdog.Start(ctx)
+	}
+
 	return nil
 }
 
@@ -786,15 +857,14 @@ func (m *Manager) Start(ctx context.Context) error {
 // Note that a graceful shutdown period configured with the Manager instance
 // will override the timeout of the passed in context (if applicable).
 func (m *Manager) Stop(ctx context.Context) {
-	var toStop []Plugin
+	m.stopWatchdog()
+
+	var toStop []namedplugin
 
 	func() {
 		m.mtx.Lock()
 		defer m.mtx.Unlock()
-		toStop = make([]Plugin, len(m.plugins))
-		for i := range m.plugins {
-			toStop[i] = m.plugins[i].plugin
-		}
+		toStop = stopOrder(m.plugins)
 	}()
 
 	var cancel context.CancelFunc
@@ -805,8 +875,88 @@ func (m *Manager) Stop(ctx context.Context) {
 	}
 	defer cancel()
 	for i := range toStop {
-		toStop[i].Stop(ctx)
+		toStop[i].plugin.Stop(ctx)
 	}
+	m.stopStoreAndLoop(ctx)
+}
+
+// StopTimeoutError is returned by StopWithTimeout if plugins did not stop
+// before the deadline.
+type StopTimeoutError struct {
+	Plugins []string // names of the plugins that did not stop in time, in stop order
+}
+
+func (e *StopTimeoutError) Error() string {
+	return "plugins did not stop in time: " + strings.Join(e.Plugins, ", ")
+}
+
+// StopWithTimeout stops the manager like Stop, but gives the plugins at most
+// d to stop. Plugins are stopped one at a time, before the plugins they depend
+// on, with a context that expires after d. The decision log plugin uses the
+// deadline to flush buffered decisions, and the bundle plugin waits for an
+// in-flight bundle activation to complete before stopping.
+//
+// If a plugin has not stopped once the deadline has expired, StopWithTimeout
+// stops the remaining plugins without waiting for them, and returns a
+// *StopTimeoutError naming the plugins that did not stop in time, including
+// the remaining plugins. The store is closed afterwards, with a context that
+// again expires after d, so that it is closed even if the plugins used up
+// their time.
+func (m *Manager) StopWithTimeout(ctx context.Context, d time.Duration) error {
+	m.stopWatchdog()
+
+	var toStop []namedplugin
+
+	func() {
+		m.mtx.Lock()
+		defer m.mtx.Unlock()
+		toStop = stopOrder(m.plugins)
+	}()
+
+	stopCtx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+
+	var failed []string
+	for i := range toStop {
+		done := make(chan struct{})
+		go func(p Plugin) {
+			defer close(done)
+			p.Stop(stopCtx)
+		}(toStop[i].plugin)
+
+		if stopCtx.Err() != nil {
+			failed = append(failed, toStop[i].name)
+			continue
+		}
+
+		select {
+		case <-done:
+		case <-stopCtx.Done():
+			failed = append(failed, toStop[i].name)
+		}
+	}
+
+	cleanupCtx, cancelCleanup := context.WithTimeout(context.WithoutCancel(ctx), d)
+	defer cancelCleanup()
+
+	m.stopStoreAndLoop(cleanupCtx)
+
+	if len(failed) > 0 {
+		m.logger.Warn("Plugins did not stop in time: %v", strings.Join(failed, ", "))
+		return &StopTimeoutError{Plugins: failed}
+	}
+
+	return nil
+}
+
+func (m *Manager) stopWatchdog() {
+	if m.watchdog != nil {
+		m.watchdog.Stop()
+		m.watchdog = nil
+	}
+}
+
+func (m *Manager) stopStoreAndLoop(ctx context.Context) {
 	if c, ok := m.Store.(interface{ Close(context.Context) error }); ok {
 		if err := c.Close(ctx); err != nil {
 			m.logger.Error("Error closing store: %v", err)
@@ -827,6 +977,8 @@ func (m *Manager) DefaultServiceOpts(config *config.Config) cfg.ServiceOptions {
 		Logger:                m.logger,
 		Keys:                  m.keys,
 		DistributedTacingOpts: m.distributedTacingOpts,
+		RetryMetrics:          m.retryMetrics,
+		SharedCache:           m.sharedCache,
 	}
 }
 
@@ -958,6 +1110,9 @@ func (m *Manager) onCommit(ctx context.Context, txn storage.Transaction, event s
 	}
 
 	if compiler != nil {
+		if m.queryCacheSize > 0 && compiler != m.GetCompiler() {
+			compiler.WithQueryCache(m.queryCacheSize)
+		}
 		m.setCompiler(compiler)
 
 		if m.enableTelemetry && event.PolicyChanged() {
@@ -1120,6 +1275,12 @@ func (m *Manager) RegisterCacheTrigger(trigger func(*cache.Config)) {
 	m.registeredCacheTriggers = append(m.registeredCacheTriggers, trigger)
 }
 
+// RetryMetrics returns the counter of requests to services retried by the
+// service clients of this plugin manager.
+func (m *Manager) RetryMetrics() *prometheus.CounterVec {
+	return m.retryMetrics
+}
+
 // PrometheusRegister gets the prometheus.Registerer for this plugin manager.
 func (m *Manager) PrometheusRegister() prometheus.Registerer {
 	return m.prometheusRegister
@@ -1130,6 +1291,25 @@ func (m *Manager) TracerProvider() *trace.TracerProvider {
 	return m.tracerProvider
 }
 
+// PersistenceKeyProvider gets the storage.KeyProvider of the key that encrypts
+// the bundles persisted to disk, or nil if they are not encrypted.
+func (m *Manager) PersistenceKeyProvider() storage.KeyProvider {
+	return m.persistenceKeyProvider
+}
+
+func parsePersistenceEncryptionConfig(raw []byte) (storage.KeyProvider, error) {
+	var c storage.EncryptionConfig
+	if err := DecodeConfig("persistence_encryption", raw, &c); err != nil {
+		return nil, err
+	}
+
+	kp, err := c.KeyProvider()
+	if err != nil {
+		return nil, fmt.Errorf("persistence_encryption: %w", err)
+	}
+	return kp, nil
+}
+
 func (m *Manager) RegisterNDCacheTrigger(trigger func(bool)) {
 	m.mtx.Lock()
 	defer m.mtx.Unlock()
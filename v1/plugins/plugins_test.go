@@ -9,15 +9,20 @@ import (
 	"errors"
 	"net/http"
 	"reflect"
+	"slices"
 	"testing"
+	"time"
 
 	internal_tracing "github.com/open-policy-agent/opa/internal/distributedtracing"
 	"github.com/open-policy-agent/opa/internal/storage/mock"
+	"github.com/open-policy-agent/opa/v1/ast"
 	"github.com/open-policy-agent/opa/v1/logging"
 	"github.com/open-policy-agent/opa/v1/logging/test"
 	"github.com/open-policy-agent/opa/v1/plugins/rest"
+	"github.com/open-policy-agent/opa/v1/storage"
 	inmem "github.com/open-policy-agent/opa/v1/storage/inmem/test"
 	"github.com/open-policy-agent/opa/v1/topdown/cache"
+	"github.com/open-policy-agent/opa/v1/util"
 	prom "github.com/prometheus/client_golang/prometheus"
 )
 
@@ -222,6 +227,51 @@ func TestPluginManagerLazyInitBeforePluginStart(t *testing.T) {
 
 }
 
+func TestPluginManagerQueryCache(t *testing.T) {
+	ctx := context.Background()
+
+	m, err := New([]byte(`{}`), "test", inmem.New(), WithQueryCacheSize(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	upsert := func(policy string) {
+		t.Helper()
+		err := storage.Txn(ctx, m.Store, storage.WriteParams, func(txn storage.Transaction) error {
+			return m.Store.UpsertPolicy(ctx, txn, "test.rego", []byte(policy))
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	compile := func() ast.Body {
+		t.Helper()
+		query, err := m.GetCompiler().QueryCompiler().Compile(ast.MustParseBody(`data.test.p = x`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return query
+	}
+
+	upsert("package test\n\np := 1")
+
+	first := compile()
+	if second := compile(); &first[0] != &second[0] {
+		t.Fatal("expected compiled query to be cached")
+	}
+
+	upsert("package test\n\np := 2")
+
+	if third := compile(); &first[0] == &third[0] {
+		t.Fatal("expected compiled query not to be cached after policy change")
+	}
+}
+
 func TestPluginManagerInitBeforePluginStart(t *testing.T) {
 
 	m, err := New([]byte(`{"plugins": {"someplugin": {}}}`), "test", inmem.New())
@@ -303,6 +353,46 @@ func TestManagerWithCachingConfig(t *testing.T) {
 	}
 }
 
+func TestManagerWithPersistenceEncryptionConfig(t *testing.T) {
+	ctx := context.Background()
+
+	// base64 of 0123456789abcdef
+	config := []byte(`{"persistence_encryption": {"key": "MDEyMzQ1Njc4OWFiY2RlZg=="}}`)
+
+	m, err := New(config, "test", inmem.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := storage.EncryptionKey(ctx, m.PersistenceKeyProvider())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := "0123456789abcdef"; string(key) != exp {
+		t.Fatalf("expected key %q, got %q", exp, key)
+	}
+
+	// the key provider option takes precedence
+	m, err = New(config, "test", inmem.New(), WithPersistenceKeyProvider(storage.StaticKey([]byte("fedcba9876543210"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err = storage.EncryptionKey(ctx, m.PersistenceKeyProvider())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := "fedcba9876543210"; string(key) != exp {
+		t.Fatalf("expected key %q, got %q", exp, key)
+	}
+
+	// config error
+	_, err = New([]byte(`{"persistence_encryption": {}}`), "test", inmem.New())
+	if err == nil || err.Error() != "persistence_encryption: key or key_file must be set" {
+		t.Fatal("expected error but got:", err)
+	}
+}
+
 func TestManagerWithNDCachingConfig(t *testing.T) {
 	m, err := New([]byte(`{"nd_builtin_cache": true}`), "test", inmem.New())
 	if err != nil {
@@ -337,6 +427,194 @@ func (m *mockForInitStartOrdering) Start(_ context.Context) error {
 func (*mockForInitStartOrdering) Stop(context.Context)             {}
 func (*mockForInitStartOrdering) Reconfigure(context.Context, any) {}
 
+func TestPluginManagerDependencyOrder(t *testing.T) {
+	m, err := New([]byte{}, "test", inmem.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events []string
+	register := func(name string, deps ...string) {
+		m.RegisterWithDependencies(name, &orderRecordingPlugin{name: name, events: &events}, deps...)
+	}
+
+	register("custom", "bundle", "status")
+	register("other")
+	register("status", "bundle")
+	register("bundle")
+
+	order, err := m.DependencyOrder([]string{"status", "custom", "bundle"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := []string{"bundle", "status", "custom"}; !slices.Equal(order, exp) {
+		t.Fatalf("expected order %v, got %v", exp, order)
+	}
+
+	ctx := context.Background()
+	if err := m.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	m.Stop(ctx)
+
+	exp := []string{
+		"start bundle", "start status", "start custom", "start other",
+		"stop custom", "stop other", "stop status", "stop bundle",
+	}
+	if !slices.Equal(events, exp) {
+		t.Fatalf("expected events %v, got %v", exp, events)
+	}
+}
+
+func TestPluginManagerDependencyErrors(t *testing.T) {
+	tests := []struct {
+		note string
+		deps map[string][]string
+		exp  string
+	}{
+		{
+			note: "cycle",
+			deps: map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"a"}},
+			exp:  "plugin dependency cycle: a -> b -> c -> a",
+		},
+		{
+			note: "self",
+			deps: map[string][]string{"a": {"a"}},
+			exp:  "plugin dependency cycle: a -> a",
+		},
+		{
+			note: "unknown",
+			deps: map[string][]string{"a": {"missing"}},
+			exp:  `plugin "a" depends on unknown plugin "missing"`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			m, err := New([]byte{}, "test", inmem.New())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var events []string
+			for _, name := range util.KeysSorted(tc.deps) {
+				m.RegisterWithDependencies(name, &orderRecordingPlugin{name: name, events: &events}, tc.deps[name]...)
+			}
+
+			err = m.Start(context.Background())
+			if err == nil || err.Error() != tc.exp {
+				t.Fatalf("expected error %q, got %v", tc.exp, err)
+			}
+			if len(events) != 0 {
+				t.Fatalf("expected no plugins to be started, got %v", events)
+			}
+		})
+	}
+}
+
+type orderRecordingPlugin struct {
+	name   string
+	events *[]string
+}
+
+func (p *orderRecordingPlugin) Start(context.Context) error {
+	*p.events = append(*p.events, "start "+p.name)
+	return nil
+}
+
+func (p *orderRecordingPlugin) Stop(context.Context) {
+	*p.events = append(*p.events, "stop "+p.name)
+}
+
+func (*orderRecordingPlugin) Reconfigure(context.Context, any) {}
+
+func TestPluginManagerStopWithTimeout(t *testing.T) {
+	m, err := New([]byte{}, "test", inmem.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events []string
+	m.RegisterWithDependencies("custom", &orderRecordingPlugin{name: "custom", events: &events}, "bundle")
+	m.RegisterWithDependencies("bundle", &orderRecordingPlugin{name: "bundle", events: &events})
+
+	ctx := context.Background()
+	if err := m.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.StopWithTimeout(ctx, time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []string{"start bundle", "start custom", "stop custom", "stop bundle"}
+	if !slices.Equal(events, exp) {
+		t.Fatalf("expected events %v, got %v", exp, events)
+	}
+}
+
+func TestPluginManagerStopWithTimeoutExpired(t *testing.T) {
+	store := &closeRecordingStore{Store: inmem.New()}
+	m, err := New([]byte{}, "test", store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+
+	var events []string
+	m.RegisterWithDependencies("slow", &blockingStopPlugin{release: release})
+	m.RegisterWithDependencies("custom", &orderRecordingPlugin{name: "custom", events: &events}, "slow")
+
+	ctx := context.Background()
+	if err := m.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.StopWithTimeout(ctx, 10*time.Millisecond)
+
+	var stopErr *StopTimeoutError
+	if !errors.As(err, &stopErr) || !slices.Equal(stopErr.Plugins, []string{"slow"}) {
+		t.Fatalf("expected slow plugin to not stop in time, got %v", err)
+	}
+
+	if exp := []string{"start custom", "stop custom"}; !slices.Equal(events, exp) {
+		t.Fatalf("expected events %v, got %v", exp, events)
+	}
+
+	if !store.closed || store.closeErr != nil {
+		t.Fatalf("expected store to be closed with a live context, got closed=%v, err=%v", store.closed, store.closeErr)
+	}
+}
+
+// closeRecordingStore records whether the store was closed, and the error of
+// the context it was closed with.
+type closeRecordingStore struct {
+	storage.Store
+	closed   bool
+	closeErr error
+}
+
+func (s *closeRecordingStore) Close(ctx context.Context) error {
+	s.closed = true
+	s.closeErr = ctx.Err()
+	return nil
+}
+
+// blockingStopPlugin does not stop before it is released.
+type blockingStopPlugin struct {
+	release chan struct{}
+}
+
+func (*blockingStopPlugin) Start(context.Context) error { return nil }
+
+func (p *blockingStopPlugin) Stop(context.Context) {
+	<-p.release
+}
+
+func (*blockingStopPlugin) Reconfigure(context.Context, any) {}
+
 func TestPluginManagerAuthPlugin(t *testing.T) {
 	m, err := New([]byte(`{"plugins": {"someplugin": {}}}`), "test", inmem.New())
 	if err != nil {
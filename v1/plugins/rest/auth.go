@@ -962,6 +962,21 @@ func (ap *awsSigningAuthPlugin) awsCredentialService() awsCredentialService {
 	return &chain
 }
 
+// region returns the region configured for the credential service, if any.
+func (ap *awsSigningAuthPlugin) region() string {
+	switch {
+	case ap.AWSMetadataCredentials != nil:
+		return ap.AWSMetadataCredentials.RegionName
+	case ap.AWSAssumeRoleCredentials != nil:
+		return ap.AWSAssumeRoleCredentials.RegionName
+	case ap.AWSWebIdentityCredentials != nil:
+		return ap.AWSWebIdentityCredentials.RegionName
+	case ap.AWSProfileCredentials != nil:
+		return ap.AWSProfileCredentials.RegionName
+	}
+	return ""
+}
+
 func (ap *awsSigningAuthPlugin) NewClient(c Config) (*http.Client, error) {
 	t, err := DefaultTLSConfig(c)
 	if err != nil {
@@ -0,0 +1,219 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultResponseCacheMaxEntries = 16
+
+// ResponseCacheConfig configures the caching of responses to GET requests in
+// memory, for as long as their Cache-Control and Age headers allow. This saves
+// downloading unchanged bundles from services that do not support ETags.
+type ResponseCacheConfig struct {
+	MaxEntries *int `json:"max_entries,omitempty"`
+}
+
+func (c *ResponseCacheConfig) maxEntries() int {
+	if c.MaxEntries == nil {
+		return defaultResponseCacheMaxEntries
+	}
+	return max(*c.MaxEntries, 1)
+}
+
+type cachedResponse struct {
+	status  string
+	code    int
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+type responseCache struct {
+	mtx        sync.Mutex
+	maxEntries int
+	entries    map[string]*cachedResponse
+	now        func() time.Time
+}
+
+func newResponseCache(config *ResponseCacheConfig) *responseCache {
+	return &responseCache{
+		maxEntries: config.maxEntries(),
+		entries:    map[string]*cachedResponse{},
+		now:        time.Now,
+	}
+}
+
+// isLongPoll returns true if the headers ask the server to wait for a change
+// before responding, e.g., "Prefer: modes=long-poll;wait=300".
+func isLongPoll(headers map[string]string) bool {
+	for k, v := range headers {
+		if http.CanonicalHeaderKey(k) == "Prefer" && strings.Contains(v, "wait=") {
+			return true
+		}
+	}
+	return false
+}
+
+// responseCacheKey returns the key of the response to a request with headers.
+// Conditional request headers are not part of the key, as cached responses
+// are revalidated against them.
+func responseCacheKey(url string, headers map[string]string) string {
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		if http.CanonicalHeaderKey(k) != "If-None-Match" {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+
+	var sb strings.Builder
+	sb.WriteString(url)
+	for _, k := range keys {
+		sb.WriteString("\n" + k + ": " + headers[k])
+	}
+	return sb.String()
+}
+
+// get returns the fresh response cached for key, if any. If etag matches the
+// ETag of the cached response, a Not Modified response is returned instead.
+func (c *responseCache) get(key, etag string) *http.Response {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+
+	if !c.now().Before(e.expires) {
+		delete(c.entries, key)
+		return nil
+	}
+
+	resp := &http.Response{
+		Status:        e.status,
+		StatusCode:    e.code,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+	}
+
+	if etag != "" && etag == e.header.Get("ETag") {
+		resp.Status = "304 Not Modified"
+		resp.StatusCode = http.StatusNotModified
+		resp.Body = http.NoBody
+		resp.ContentLength = 0
+	}
+
+	return resp
+}
+
+// put caches resp for key if its headers allow it. The body of resp is read
+// and replaced.
+func (c *responseCache) put(key string, resp *http.Response) error {
+	if resp.StatusCode != http.StatusOK {
+		return nil
+	}
+
+	ttl, ok := freshnessLifetime(resp.Header)
+	if !ok || ttl <= 0 {
+		return nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	now := c.now()
+
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.maxEntries {
+		c.evict(now)
+	}
+
+	c.entries[key] = &cachedResponse{
+		status:  resp.Status,
+		code:    resp.StatusCode,
+		header:  resp.Header.Clone(),
+		body:    body,
+		expires: now.Add(ttl),
+	}
+
+	return nil
+}
+
+// evict removes the expired entries or, if there are none, the entry expiring
+// first.
+func (c *responseCache) evict(now time.Time) {
+	var first string
+	for key, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, key)
+			continue
+		}
+		if first == "" || e.expires.Before(c.entries[first].expires) {
+			first = key
+		}
+	}
+
+	if len(c.entries) >= c.maxEntries {
+		delete(c.entries, first)
+	}
+}
+
+// freshnessLifetime returns how long a response with header may be served from
+// the cache, from its Cache-Control max-age directive less its Age. It returns
+// false if the response must not be cached.
+func freshnessLifetime(header http.Header) (time.Duration, bool) {
+	var maxAge time.Duration
+	var found bool
+
+	for _, v := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(v, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			switch strings.ToLower(name) {
+			case "no-store", "no-cache":
+				return 0, false
+			case "max-age":
+				secs, err := strconv.ParseInt(strings.Trim(value, `"`), 10, 64)
+				if err != nil {
+					return 0, false
+				}
+				maxAge, found = time.Duration(secs)*time.Second, true
+			}
+		}
+	}
+
+	if !found {
+		return 0, false
+	}
+
+	if v := header.Get("Age"); v != "" {
+		secs, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		maxAge -= time.Duration(secs) * time.Second
+	}
+
+	return maxAge, true
+}
@@ -0,0 +1,174 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestResponseCache(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		note         string
+		cacheControl string
+		age          string
+		method       string
+		headers      map[string]string
+		expCalls     int32
+	}{
+		{
+			note:         "fresh",
+			cacheControl: "public, max-age=60",
+			expCalls:     1,
+		},
+		{
+			note:         "stale",
+			cacheControl: "max-age=60",
+			age:          "60",
+			expCalls:     2,
+		},
+		{
+			note:     "no cache control",
+			expCalls: 2,
+		},
+		{
+			note:         "no-store",
+			cacheControl: "max-age=60, no-store",
+			expCalls:     2,
+		},
+		{
+			note:         "no-cache",
+			cacheControl: "no-cache, max-age=60",
+			expCalls:     2,
+		},
+		{
+			note:         "not a GET request",
+			cacheControl: "max-age=60",
+			method:       http.MethodPost,
+			expCalls:     2,
+		},
+		{
+			note:         "long poll",
+			cacheControl: "public, max-age=60",
+			headers:      map[string]string{"Prefer": "modes=long-poll;wait=300"},
+			expCalls:     2,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			t.Parallel()
+
+			var calls atomic.Int32
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				n := calls.Add(1)
+				if tc.cacheControl != "" {
+					w.Header().Set("Cache-Control", tc.cacheControl)
+				}
+				if tc.age != "" {
+					w.Header().Set("Age", tc.age)
+				}
+				fmt.Fprintf(w, "response %d", n)
+			}))
+			defer ts.Close()
+
+			client, err := New(fmt.Appendf(nil, `{"name": "foo", "url": %q, "response_cache": {}}`, ts.URL), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			method := http.MethodGet
+			if tc.method != "" {
+				method = tc.method
+			}
+
+			for k, v := range tc.headers {
+				client = client.WithHeader(k, v)
+			}
+
+			for range 2 {
+				resp, err := client.Do(context.Background(), method, "/bundle")
+				if err != nil {
+					t.Fatal(err)
+				}
+				bs, _ := io.ReadAll(resp.Body)
+				resp.Body.Close()
+
+				if resp.StatusCode != http.StatusOK {
+					t.Fatalf("unexpected response %d: %s", resp.StatusCode, bs)
+				}
+			}
+
+			if calls.Load() != tc.expCalls {
+				t.Errorf("expected %d calls, got %d", tc.expCalls, calls.Load())
+			}
+		})
+	}
+}
+
+func TestResponseCacheExpiryAndRevalidation(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("ETag", "v1")
+		fmt.Fprint(w, "bundle")
+	}))
+	defer ts.Close()
+
+	client, err := New(fmt.Appendf(nil, `{"name": "foo", "url": %q, "response_cache": {"max_entries": 1}}`, ts.URL), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	client.responseCache.now = func() time.Time { return now }
+
+	get := func(path, etag string) int {
+		t.Helper()
+		resp, err := client.WithHeader("If-None-Match", etag).Do(context.Background(), http.MethodGet, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return resp.StatusCode
+	}
+
+	if code := get("/a", ""); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+
+	// Served from the cache, and revalidated against the ETag.
+	if code := get("/a", "v1"); code != http.StatusNotModified {
+		t.Fatalf("expected 304, got %d", code)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected 1 call, got %d", calls.Load())
+	}
+
+	// Evicts /a, as the cache holds a single entry.
+	get("/b", "")
+	get("/a", "")
+	if calls.Load() != 3 {
+		t.Fatalf("expected 3 calls, got %d", calls.Load())
+	}
+
+	now = now.Add(time.Minute)
+	get("/a", "")
+	if calls.Load() != 4 {
+		t.Fatalf("expected 4 calls, got %d", calls.Load())
+	}
+}
@@ -15,9 +15,12 @@ import (
 	"maps"
 	"net/http"
 	"net/http/httputil"
+	"os"
 	"reflect"
 	"strings"
 
+	"github.com/prometheus/client_golang/prometheus"
+
 	"github.com/open-policy-agent/opa/internal/version"
 	"github.com/open-policy-agent/opa/v1/keys"
 	"github.com/open-policy-agent/opa/v1/logging"
@@ -62,9 +65,11 @@ type Config struct {
 		AzureManagedIdentity *azureManagedIdentitiesAuthPlugin  `json:"azure_managed_identity,omitempty"`
 		Plugin               *string                            `json:"plugin,omitempty"`
 	} `json:"credentials"`
-	Type   string `json:"type,omitempty"`
-	keys   map[string]*keys.Config
-	logger logging.Logger
+	Type          string               `json:"type,omitempty"`
+	Retry         *RetryConfig         `json:"retry,omitempty"`
+	ResponseCache *ResponseCacheConfig `json:"response_cache,omitempty"`
+	keys          map[string]*keys.Config
+	logger        logging.Logger
 }
 
 // Equal returns true if this client config is equal to the other.
@@ -74,6 +79,18 @@ func (c *Config) Equal(other *Config) bool {
 	return reflect.DeepEqual(c, &otherWithoutLogger)
 }
 
+// AWSRegion returns the AWS region of the s3_signing credentials of the
+// service or, if they do not set one, the region of the AWS_REGION
+// environment variable.
+func (c *Config) AWSRegion() string {
+	if ap := c.Credentials.S3Signing; ap != nil {
+		if region := ap.region(); region != "" {
+			return region
+		}
+	}
+	return os.Getenv(awsRegionEnvVar)
+}
+
 // An AuthPluginLookupFunc can lookup auth plugins by their name.
 type AuthPluginLookupFunc func(name string) HTTPAuthPlugin
 
@@ -140,6 +157,10 @@ type Client struct {
 	logger                logging.Logger
 	loggerFields          map[string]any
 	distributedTacingOpts tracing.Options
+	retryMetrics          *prometheus.CounterVec
+	retryable             bool
+	responseCache         *responseCache
+	shared                *SharedCache
 }
 
 // Name returns an option that overrides the service name on the client.
@@ -193,6 +214,10 @@ func New(config []byte, keys map[string]*keys.Config, opts ...func(*Client)) (Cl
 		config: parsedConfig,
 	}
 
+	if parsedConfig.ResponseCache != nil {
+		client.responseCache = newResponseCache(parsedConfig.ResponseCache)
+	}
+
 	for _, f := range opts {
 		f(&client)
 	}
@@ -228,6 +253,13 @@ func (c Client) SetResponseHeaderTimeout(timeout *int64) Client {
 	return c
 }
 
+// WithURL returns a shallow copy of the client sending requests to url instead
+// of the URL of the service.
+func (c Client) WithURL(url string) Client {
+	c.config.URL = strings.TrimRight(url, "/")
+	return c
+}
+
 // Logger returns the logger assigned to the Client
 func (c Client) Logger() logging.Logger {
 	return c.logger
@@ -267,7 +299,17 @@ func (c Client) WithBytes(body []byte) Client {
 	return c
 }
 
-// Do executes a request using the client.
+// WithRetryable returns a shallow copy of the client that retries requests
+// regardless of their method, if retries are configured. Callers set this for
+// requests with non-idempotent methods, like POST, that are safe to repeat.
+func (c Client) WithRetryable(yes bool) Client {
+	c.retryable = yes
+	return c
+}
+
+// Do executes a request using the client. If retries are configured, requests
+// with idempotent methods, or marked as retryable with WithRetryable, that fail
+// with a network error or a retryable HTTP status are retried.
 func (c Client) Do(ctx context.Context, method, path string) (*http.Response, error) {
 
 	httpClient, err := c.config.authHTTPClient(c.authPluginLookup)
@@ -275,26 +317,91 @@ func (c Client) Do(ctx context.Context, method, path string) (*http.Response, er
 		return nil, err
 	}
 
+	if c.shared != nil {
+		httpClient.Transport = c.shared.transport(&c.config, httpClient.Transport)
+	}
+
 	if len(c.distributedTacingOpts) > 0 {
 		httpClient.Transport = tracing.NewTransport(httpClient.Transport, c.distributedTacingOpts)
 	}
 
 	path = strings.Trim(path, "/")
 
-	var body io.Reader
+	var body []byte
 
 	if c.bytes != nil {
-		body = bytes.NewReader(*c.bytes)
+		body = *c.bytes
 	} else if c.json != nil {
 		var buf bytes.Buffer
 		if err := json.NewEncoder(&buf).Encode(*c.json); err != nil {
 			return nil, err
 		}
-		body = &buf
+		body = buf.Bytes()
 	}
 
 	url := c.config.URL + "/" + path
-	req, err := http.NewRequestWithContext(ctx, method, url, body)
+
+	// Long-poll requests are not answered from the caches: the server holds
+	// them until there is a change, so cached responses would make clients poll
+	// without waiting.
+	cacheable := method == http.MethodGet && body == nil && !isLongPoll(c.headers)
+
+	var cacheKey string
+	if c.responseCache != nil && cacheable {
+		cacheKey = responseCacheKey(url, c.headers)
+		if resp := c.responseCache.get(cacheKey, c.headers["If-None-Match"]); resp != nil {
+			c.logger.Debug("Serving cached response to request to service %q.", c.config.Name)
+			return resp, nil
+		}
+	}
+
+	var sharedKey string
+	var shared *sharedResponse
+	if c.shared != nil && cacheable {
+		sharedKey = c.shared.key(&c.config, url, c.headers)
+	}
+	if sharedKey != "" {
+		// revalidate the shared response, unless the caller already has it
+		if shared = c.shared.get(sharedKey); shared != nil {
+			if etag := shared.header.Get("ETag"); etag == c.headers["If-None-Match"] {
+				shared = nil
+			} else {
+				c.headers = maps.Clone(c.headers)
+				c = c.WithHeader("If-None-Match", etag)
+			}
+		}
+	}
+
+	resp, err := c.doWithRetry(ctx, method, func() (*http.Response, error) {
+		return c.do(ctx, httpClient, method, url, body)
+	})
+
+	if sharedKey != "" && err == nil {
+		if shared != nil && resp.StatusCode == http.StatusNotModified {
+			c.logger.Debug("Serving shared response to request to service %q.", c.config.Name)
+			resp.Body.Close()
+			resp = shared.response()
+		} else if err := c.shared.put(sharedKey, resp); err != nil {
+			return nil, err
+		}
+	}
+
+	if cacheKey != "" && err == nil {
+		if err := c.responseCache.put(cacheKey, resp); err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, err
+}
+
+func (c Client) do(ctx context.Context, httpClient *http.Client, method, url string, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,202 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/open-policy-agent/opa/v1/util"
+)
+
+const (
+	defaultRetryMaxAttempts = 3
+	defaultRetryMinDelayMS  = int64(100)
+	defaultRetryMaxDelayMS  = int64(10000)
+	defaultRetryJitter      = 0.2
+	retryBackoffFactor      = 1.6
+)
+
+// RetryConfig configures retries of requests that failed with a network error
+// or a retryable HTTP status (429, 502, 503 and 504). Only requests with
+// idempotent methods are retried, unless the client is marked as retryable.
+type RetryConfig struct {
+	MaxAttempts *int     `json:"max_attempts,omitempty"`
+	MinDelayMS  *int64   `json:"min_delay_ms,omitempty"`
+	MaxDelayMS  *int64   `json:"max_delay_ms,omitempty"`
+	Jitter      *float64 `json:"jitter,omitempty"`
+}
+
+func (c *RetryConfig) maxAttempts() int {
+	if c == nil {
+		return 1
+	}
+	if c.MaxAttempts == nil {
+		return defaultRetryMaxAttempts
+	}
+	return max(*c.MaxAttempts, 1)
+}
+
+func (c *RetryConfig) delays() (minDelay, maxDelay time.Duration, jitter float64) {
+	minMS, maxMS, jitter := defaultRetryMinDelayMS, defaultRetryMaxDelayMS, defaultRetryJitter
+	if c.MinDelayMS != nil {
+		minMS = *c.MinDelayMS
+	}
+	if c.MaxDelayMS != nil {
+		maxMS = *c.MaxDelayMS
+	}
+	if c.Jitter != nil {
+		jitter = *c.Jitter
+	}
+	return time.Duration(minMS) * time.Millisecond, time.Duration(maxMS) * time.Millisecond, jitter
+}
+
+// RetryAfterError is implemented by errors that carry the delay a server asked
+// for in a Retry-After header.
+type RetryAfterError interface {
+	error
+	RetryAfter() time.Duration
+}
+
+// Backoff returns the delay before retrying an operation that failed with err
+// for the retries'th consecutive time. The delay grows exponentially with
+// jitter from minDelay up to maxDelay. If err is a RetryAfterError, the delay
+// is at least the delay the server asked for.
+func Backoff(minDelay, maxDelay time.Duration, retries int, err error) time.Duration {
+	delay := util.DefaultBackoff(float64(minDelay), float64(maxDelay), retries)
+
+	var rae RetryAfterError
+	if errors.As(err, &rae) {
+		delay = max(delay, rae.RetryAfter())
+	}
+
+	return delay
+}
+
+// RetryAfter returns the delay requested by the Retry-After header of resp,
+// which can either be a number of seconds or an HTTP date.
+func RetryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+
+	if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return max(time.Duration(secs)*time.Second, 0), true
+	}
+
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0), true
+	}
+
+	return 0, false
+}
+
+// NewRetryMetrics returns a counter of retried requests for use with the
+// RetryMetrics option.
+func NewRetryMetrics() *prometheus.CounterVec {
+	return prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "rest_client_retries_total",
+		Help: "Number of requests to services that were retried, by service and reason.",
+	}, []string{"service", "reason"})
+}
+
+// RetryMetrics sets the counter incremented when the client retries a request.
+// The counter must have been created by NewRetryMetrics.
+func RetryMetrics(c *prometheus.CounterVec) func(*Client) {
+	return func(client *Client) {
+		client.retryMetrics = c
+	}
+}
+
+// retryReason returns why the request that returned resp and err should be
+// retried, or false if it should not.
+func retryReason(ctx context.Context, resp *http.Response, err error) (string, bool) {
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", false
+		}
+		return "error", true
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return strconv.Itoa(resp.StatusCode), true
+	}
+
+	return "", false
+}
+
+// idempotentMethod reports if repeating a request with method has the same
+// effect as sending it once, see RFC 9110, Section 9.2.2.
+func idempotentMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// doWithRetry calls do until it succeeds, the request is not retryable or the
+// configured attempts are exhausted, and returns the last result.
+func (c Client) doWithRetry(ctx context.Context, method string, do func() (*http.Response, error)) (*http.Response, error) {
+	attempts := 1
+	if c.retryable || idempotentMethod(method) {
+		attempts = c.config.Retry.maxAttempts()
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := do()
+		if attempt >= attempts {
+			return resp, err
+		}
+
+		reason, ok := retryReason(ctx, resp, err)
+		if !ok {
+			return resp, err
+		}
+
+		minDelay, maxDelay, jitter := c.config.Retry.delays()
+		delay := util.Backoff(float64(minDelay), float64(maxDelay), jitter, retryBackoffFactor, attempt)
+
+		if retryAfter, ok := RetryAfter(resp); ok {
+			// Give up rather than wait longer than configured, the caller
+			// handles the response as it would without retries.
+			if retryAfter > maxDelay {
+				return resp, err
+			}
+			delay = max(delay, retryAfter)
+		}
+
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		if c.retryMetrics != nil {
+			c.retryMetrics.WithLabelValues(c.config.Name, reason).Inc()
+		}
+
+		c.logger.Debug("Retrying request to service %q in %v (attempt %d of %d, reason: %v).", c.config.Name, delay, attempt+1, attempts, reason)
+
+		timer, timerCancel := util.TimerWithCancel(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timerCancel()
+			return nil, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestDoWithRetry(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		note      string
+		retry     string
+		method    string
+		retryable bool
+		responses []int
+		header    string
+		expStatus int
+		expCalls  int32
+		expRetry  float64
+	}{
+		{
+			note:      "no retry config",
+			responses: []int{503, 200},
+			expStatus: 503,
+			expCalls:  1,
+		},
+		{
+			note:      "retries until success",
+			retry:     `{"min_delay_ms": 1, "max_delay_ms": 10}`,
+			responses: []int{503, 429, 200},
+			expStatus: 200,
+			expCalls:  3,
+			expRetry:  2,
+		},
+		{
+			note:      "max attempts",
+			retry:     `{"max_attempts": 2, "min_delay_ms": 1, "max_delay_ms": 10}`,
+			responses: []int{502, 504, 200},
+			expStatus: 504,
+			expCalls:  2,
+			expRetry:  1,
+		},
+		{
+			note:      "not retryable",
+			retry:     `{"min_delay_ms": 1, "max_delay_ms": 10}`,
+			responses: []int{500, 200},
+			expStatus: 500,
+			expCalls:  1,
+		},
+		{
+			note:      "retry-after within max delay",
+			retry:     `{"min_delay_ms": 1, "max_delay_ms": 2000}`,
+			responses: []int{429, 200},
+			header:    "1",
+			expStatus: 200,
+			expCalls:  2,
+			expRetry:  1,
+		},
+		{
+			note:      "retry-after exceeds max delay",
+			retry:     `{"min_delay_ms": 1, "max_delay_ms": 10}`,
+			responses: []int{429, 200},
+			header:    "60",
+			expStatus: 429,
+			expCalls:  1,
+		},
+		{
+			note:      "non-idempotent method",
+			retry:     `{"min_delay_ms": 1, "max_delay_ms": 10}`,
+			method:    http.MethodPost,
+			responses: []int{503, 200},
+			expStatus: 503,
+			expCalls:  1,
+		},
+		{
+			note:      "non-idempotent method marked as retryable",
+			retry:     `{"min_delay_ms": 1, "max_delay_ms": 10}`,
+			method:    http.MethodPost,
+			retryable: true,
+			responses: []int{503, 200},
+			expStatus: 200,
+			expCalls:  2,
+			expRetry:  1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			t.Parallel()
+
+			var calls atomic.Int32
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := calls.Add(1)
+
+				// The request body is sent with every attempt.
+				if bs, _ := io.ReadAll(r.Body); string(bs) != "body" {
+					t.Errorf("unexpected body %q on attempt %d", bs, n)
+				}
+
+				if tc.header != "" {
+					w.Header().Set("Retry-After", tc.header)
+				}
+				w.WriteHeader(tc.responses[n-1])
+			}))
+			defer ts.Close()
+
+			config := fmt.Sprintf(`{"name": "foo", "url": %q}`, ts.URL)
+			if tc.retry != "" {
+				config = fmt.Sprintf(`{"name": "foo", "url": %q, "retry": %s}`, ts.URL, tc.retry)
+			}
+
+			metrics := NewRetryMetrics()
+			client, err := New([]byte(config), nil, RetryMetrics(metrics))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			method := tc.method
+			if method == "" {
+				method = http.MethodPut
+			}
+
+			resp, err := client.WithBytes([]byte("body")).WithRetryable(tc.retryable).Do(context.Background(), method, "/")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tc.expStatus {
+				t.Errorf("expected status %d, got %d", tc.expStatus, resp.StatusCode)
+			}
+
+			if calls.Load() != tc.expCalls {
+				t.Errorf("expected %d calls, got %d", tc.expCalls, calls.Load())
+			}
+
+			if retries := sumCounters(t, metrics); retries != tc.expRetry {
+				t.Errorf("expected %v retries in metrics, got %v", tc.expRetry, retries)
+			}
+		})
+	}
+}
+
+func sumCounters(t *testing.T, c prometheus.Collector) float64 {
+	t.Helper()
+
+	ch := make(chan prometheus.Metric, 16)
+	c.Collect(ch)
+	close(ch)
+
+	var sum float64
+	for m := range ch {
+		var pb dto.Metric
+		if err := m.Write(&pb); err != nil {
+			t.Fatal(err)
+		}
+		sum += pb.GetCounter().GetValue()
+	}
+
+	return sum
+}
+
+func TestDoWithRetryCanceled(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	config := fmt.Sprintf(`{"name": "foo", "url": %q, "retry": {"min_delay_ms": 60000, "max_delay_ms": 60000}}`, ts.URL)
+	client, err := New([]byte(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := client.Do(ctx, http.MethodGet, "/"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		note   string
+		header string
+		exp    time.Duration
+		ok     bool
+	}{
+		{note: "missing"},
+		{note: "seconds", header: "120", exp: 120 * time.Second, ok: true},
+		{note: "date in the past", header: "Wed, 21 Oct 2015 07:28:00 GMT", exp: 0, ok: true},
+		{note: "invalid", header: "soon"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}}
+			if tc.header != "" {
+				resp.Header.Set("Retry-After", tc.header)
+			}
+
+			d, ok := RetryAfter(resp)
+			if d != tc.exp || ok != tc.ok {
+				t.Fatalf("expected (%v, %v), got (%v, %v)", tc.exp, tc.ok, d, ok)
+			}
+		})
+	}
+}
+
+type retryAfterErr time.Duration
+
+func (e retryAfterErr) Error() string             { return "retry later" }
+func (e retryAfterErr) RetryAfter() time.Duration { return time.Duration(e) }
+
+func TestBackoff(t *testing.T) {
+	t.Parallel()
+
+	if d := Backoff(time.Millisecond, 10*time.Millisecond, 3, errors.New("fail")); d > 12*time.Millisecond {
+		t.Fatalf("expected delay of at most max delay with jitter, got %v", d)
+	}
+
+	err := fmt.Errorf("wrapped: %w", retryAfterErr(time.Minute))
+	if d := Backoff(time.Millisecond, 10*time.Millisecond, 3, err); d != time.Minute {
+		t.Fatalf("expected delay requested by server, got %v", d)
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+)
+
+const (
+	defaultSharedCacheMaxEntries = 256
+	defaultSharedCacheMaxBytes   = 256 * 1024 * 1024
+)
+
+// SharedCache is shared by clients, possibly of different OPA instances, so
+// that they download the responses to the same GET requests, e.g., bundles,
+// only once, and reuse the connections to the same services.
+//
+// Responses with an ETag are stored in the cache, and requests for them are
+// revalidated with the service: if the service responds with Not Modified,
+// the cached response is returned instead. Responses are only shared by
+// clients with the same credentials, so that services that vary responses by
+// client are not confused.
+type SharedCache struct {
+	mtx        sync.Mutex
+	maxEntries int
+	maxBytes   int64
+	size       int64 // total size of the response bodies in the cache
+	responses  map[string]*sharedResponse
+	order      []string // keys of the responses, in the order they were stored
+	transports map[string]*http.Transport
+}
+
+type sharedResponse struct {
+	status string
+	header http.Header
+	body   []byte
+}
+
+// NewSharedCache returns a new SharedCache storing at most maxEntries
+// responses, with bodies of at most maxBytes in total. Larger responses are
+// not stored. If maxEntries or maxBytes is less than one, a default of 256
+// responses or 256 MiB is used.
+func NewSharedCache(maxEntries int, maxBytes int64) *SharedCache {
+	if maxEntries < 1 {
+		maxEntries = defaultSharedCacheMaxEntries
+	}
+	if maxBytes < 1 {
+		maxBytes = defaultSharedCacheMaxBytes
+	}
+	return &SharedCache{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		responses:  map[string]*sharedResponse{},
+		transports: map[string]*http.Transport{},
+	}
+}
+
+// UseSharedCache sets the cache shared with other clients.
+func UseSharedCache(s *SharedCache) func(*Client) {
+	return func(c *Client) {
+		c.shared = s
+	}
+}
+
+// Len returns the number of responses in the cache.
+func (s *SharedCache) Len() int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return len(s.responses)
+}
+
+// key returns the key of the response to a request with headers to url by a
+// client configured with config. The key identifies the credentials of the
+// client without containing them. If the credentials cannot be identified,
+// an empty key is returned, and the response must not be shared.
+func (*SharedCache) key(config *Config, url string, headers map[string]string) string {
+	bs, err := json.Marshal([]any{config.Headers, config.Credentials})
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(bs)
+	return responseCacheKey(url, headers) + "\n" + hex.EncodeToString(sum[:])
+}
+
+func (s *SharedCache) get(key string) *sharedResponse {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.responses[key]
+}
+
+// put stores resp for key if it has an ETag. The body of resp is read and
+// replaced.
+func (s *SharedCache) put(key string, resp *http.Response) error {
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("ETag") == "" {
+		return nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if e, ok := s.responses[key]; ok {
+		s.order = slices.DeleteFunc(s.order, func(k string) bool { return k == key })
+		delete(s.responses, key)
+		s.size -= int64(len(e.body))
+	}
+
+	size := int64(len(body))
+	if size > s.maxBytes {
+		return nil
+	}
+
+	for len(s.order) > 0 && (len(s.responses) >= s.maxEntries || s.size+size > s.maxBytes) {
+		s.size -= int64(len(s.responses[s.order[0]].body))
+		delete(s.responses, s.order[0])
+		s.order = s.order[1:]
+	}
+
+	s.responses[key] = &sharedResponse{
+		status: resp.Status,
+		header: resp.Header.Clone(),
+		body:   body,
+	}
+	s.order = append(s.order, key)
+	s.size += size
+
+	return nil
+}
+
+// response returns a response for e.
+func (e *sharedResponse) response() *http.Response {
+	return &http.Response{
+		Status:        e.status,
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+	}
+}
+
+// transport returns the transport shared by the clients of services with the
+// same TLS configuration as config, or t if it cannot be shared. Transports
+// configured with client certificates or by custom auth plugins are not
+// shared.
+func (s *SharedCache) transport(config *Config, t http.RoundTripper) http.RoundTripper {
+	tr, ok := t.(*http.Transport)
+	if !ok || config.Credentials.ClientTLS != nil || config.Credentials.Plugin != nil {
+		return t
+	}
+
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return t
+	}
+
+	bs, err := json.Marshal([]any{u.Scheme, config.AllowInsecureTLS, config.TLS, config.ResponseHeaderTimeoutSeconds})
+	if err != nil {
+		return t
+	}
+	key := string(bs)
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if shared, ok := s.transports[key]; ok {
+		return shared
+	}
+	s.transports[key] = tr
+	return tr
+}
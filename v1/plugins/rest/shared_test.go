@@ -0,0 +1,157 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSharedCache(t *testing.T) {
+	t.Parallel()
+
+	var full, notModified int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", "v1")
+		if r.Header.Get("If-None-Match") == "v1" {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		full++
+		fmt.Fprint(w, "bundle")
+	}))
+	defer ts.Close()
+
+	shared := NewSharedCache(0, 0)
+
+	newClient := func(token string) Client {
+		t.Helper()
+		config := fmt.Sprintf(`{"name": "test", "url": %q, "credentials": {"bearer": {"token": %q}}}`, ts.URL, token)
+		client, err := New([]byte(config), nil, UseSharedCache(shared))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return client
+	}
+
+	get := func(client Client) (int, string) {
+		t.Helper()
+		resp, err := client.Do(context.Background(), http.MethodGet, "/bundle.tar.gz")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, string(body)
+	}
+
+	a, b, c := newClient("a"), newClient("a"), newClient("c")
+
+	for _, client := range []Client{a, b, c} {
+		if code, body := get(client); code != http.StatusOK || body != "bundle" {
+			t.Fatalf("expected bundle, got %v %q", code, body)
+		}
+	}
+
+	// responses are only shared by clients with the same credentials
+	if full != 2 || notModified != 1 {
+		t.Fatalf("expected two downloads and one revalidation, got %v and %v", full, notModified)
+	}
+
+	// callers with the current ETag get Not Modified
+	if code, _ := get(b.WithHeader("If-None-Match", "v1")); code != http.StatusNotModified {
+		t.Fatalf("expected not modified, got %v", code)
+	}
+
+	if shared.Len() != 2 {
+		t.Fatalf("expected two shared responses, got %v", shared.Len())
+	}
+}
+
+func TestSharedCacheEviction(t *testing.T) {
+	t.Parallel()
+
+	shared := NewSharedCache(2, 0)
+
+	for _, key := range []string{"a", "b", "a", "c"} {
+		resp := &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Etag": []string{key}},
+			Body:       http.NoBody,
+		}
+		if err := shared.put(key, resp); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if shared.get("b") != nil || shared.get("a") == nil || shared.get("c") == nil {
+		t.Fatalf("expected the least recently stored response to be evicted, got %v", shared.order)
+	}
+}
+
+func TestSharedCacheMaxBytes(t *testing.T) {
+	t.Parallel()
+
+	shared := NewSharedCache(0, 10)
+
+	for _, tc := range []struct{ key, body string }{
+		{"a", "1234"},
+		{"b", "1234"},
+		{"c", "1234"},        // evicts a
+		{"d", "12345678901"}, // too large to be stored
+	} {
+		resp := &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Etag": []string{tc.key}},
+			Body:       io.NopCloser(strings.NewReader(tc.body)),
+		}
+		if err := shared.put(tc.key, resp); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if shared.get("a") != nil || shared.get("b") == nil || shared.get("c") == nil || shared.get("d") != nil {
+		t.Fatalf("expected responses to be limited by size, got %v", shared.order)
+	}
+}
+
+func TestSharedCacheTransport(t *testing.T) {
+	t.Parallel()
+
+	shared := NewSharedCache(0, 0)
+
+	transport := func(config string) http.RoundTripper {
+		t.Helper()
+		client, err := New([]byte(config), nil, UseSharedCache(shared))
+		if err != nil {
+			t.Fatal(err)
+		}
+		httpClient, err := client.config.authHTTPClient(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return shared.transport(&client.config, httpClient.Transport)
+	}
+
+	a := transport(`{"name": "a", "url": "https://a.example.com"}`)
+	b := transport(`{"name": "b", "url": "https://b.example.com", "credentials": {"bearer": {"token": "secret"}}}`)
+	c := transport(`{"name": "c", "url": "https://c.example.com", "allow_insecure_tls": true}`)
+
+	if a != b {
+		t.Fatal("expected transports with the same TLS configuration to be shared")
+	}
+	if a == c {
+		t.Fatal("expected transports with different TLS configurations not to be shared")
+	}
+}
@@ -0,0 +1,129 @@
+// Package cors implements the configuration of the Cross-Origin Resource
+// Sharing (CORS) headers of the server, which allow browser-based tools, like
+// policy playgrounds and admin UIs, served from other origins to call the
+// APIs of OPA directly, i.e., without a proxy adding the headers.
+package cors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/open-policy-agent/opa/v1/util"
+)
+
+var (
+	defaultAllowedMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	defaultAllowedHeaders = []string{"Authorization", "Content-Type", "Content-Encoding", "Accept-Encoding"}
+)
+
+// Config represents the configuration for the Server.CORS settings
+type Config struct {
+	AllowedOrigins   []string `json:"allowed_origins"`             // origins allowed to call the APIs, or "*" for any origin.
+	AllowedMethods   []string `json:"allowed_methods,omitempty"`   // methods allowed in cross-origin requests.
+	AllowedHeaders   []string `json:"allowed_headers,omitempty"`   // request headers allowed in cross-origin requests.
+	ExposedHeaders   []string `json:"exposed_headers,omitempty"`   // response headers exposed to the scripts of other origins.
+	AllowCredentials bool     `json:"allow_credentials,omitempty"` // allow cross-origin requests with credentials, e.g., cookies.
+	MaxAgeSeconds    int      `json:"max_age_seconds,omitempty"`   // time browsers may cache the results of preflight requests.
+}
+
+// ConfigBuilder assists in the construction of the plugin configuration.
+type ConfigBuilder struct {
+	raw []byte
+}
+
+// NewConfigBuilder returns a new ConfigBuilder to build and parse the server config
+func NewConfigBuilder() *ConfigBuilder {
+	return &ConfigBuilder{}
+}
+
+// WithBytes sets the raw server config
+func (b *ConfigBuilder) WithBytes(config []byte) *ConfigBuilder {
+	b.raw = config
+	return b
+}
+
+// Parse returns a valid Config object with defaults injected. If there is no
+// config, nil is returned, i.e., no CORS headers are sent.
+func (b *ConfigBuilder) Parse() (*Config, error) {
+	if b.raw == nil {
+		return nil, nil
+	}
+
+	var result Config
+
+	if err := util.Unmarshal(b.raw, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, result.validateAndInjectDefaults()
+}
+
+// AllowsAnyOrigin returns true if requests from any origin are allowed.
+func (c *Config) AllowsAnyOrigin() bool {
+	return slices.Contains(c.AllowedOrigins, "*")
+}
+
+// AllowsOrigin returns true if requests from origin are allowed.
+func (c *Config) AllowsOrigin(origin string) bool {
+	return c.AllowsAnyOrigin() || slices.ContainsFunc(c.AllowedOrigins, func(o string) bool {
+		return strings.EqualFold(o, origin)
+	})
+}
+
+// AllowsMethod returns true if cross-origin requests with method are allowed.
+func (c *Config) AllowsMethod(method string) bool {
+	return slices.Contains(c.AllowedMethods, method)
+}
+
+// AllowsHeader returns true if cross-origin requests with the header are
+// allowed. Header names are case-insensitive.
+func (c *Config) AllowsHeader(header string) bool {
+	return slices.ContainsFunc(c.AllowedHeaders, func(h string) bool {
+		return h == "*" || strings.EqualFold(h, header)
+	})
+}
+
+// validateAndInjectDefaults populates defaults if the fields are nil, then
+// validates the config values.
+func (c *Config) validateAndInjectDefaults() error {
+	if len(c.AllowedOrigins) == 0 {
+		return errors.New("missing value for server.cors.allowed_origins field, should list at least one origin")
+	}
+
+	for i, o := range c.AllowedOrigins {
+		if o == "*" {
+			continue
+		}
+		o = strings.TrimSuffix(o, "/")
+		u, err := url.Parse(o)
+		if err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" || u.RawQuery != "" {
+			return fmt.Errorf("invalid value for server.cors.allowed_origins[%d] field, should be '*' or an origin like 'https://example.com'", i)
+		}
+		c.AllowedOrigins[i] = o
+	}
+
+	if c.AllowCredentials && c.AllowsAnyOrigin() {
+		return errors.New("invalid value for server.cors.allow_credentials field, credentials cannot be allowed for any origin")
+	}
+
+	if c.AllowedMethods == nil {
+		c.AllowedMethods = slices.Clone(defaultAllowedMethods)
+	}
+	for i := range c.AllowedMethods {
+		c.AllowedMethods[i] = strings.ToUpper(c.AllowedMethods[i])
+	}
+
+	if c.AllowedHeaders == nil {
+		c.AllowedHeaders = slices.Clone(defaultAllowedHeaders)
+	}
+
+	if c.MaxAgeSeconds < 0 {
+		return errors.New("invalid value for server.cors.max_age_seconds field, should be a positive number")
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package cors
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestConfigValidation(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantErr bool
+	}{
+		{
+			input:   `{}`,
+			wantErr: true,
+		},
+		{
+			input:   `{"allowed_origins": ["*"]}`,
+			wantErr: false,
+		},
+		{
+			input:   `{"allowed_origins": ["https://playground.example.com/", "http://localhost:8080"]}`,
+			wantErr: false,
+		},
+		{
+			input:   `{"allowed_origins": ["playground.example.com"]}`,
+			wantErr: true,
+		},
+		{
+			input:   `{"allowed_origins": ["https://example.com/admin"]}`,
+			wantErr: true,
+		},
+		{
+			input:   `{"allowed_origins": ["*"], "allow_credentials": true}`,
+			wantErr: true,
+		},
+		{
+			input:   `{"allowed_origins": ["https://example.com"], "allow_credentials": true, "max_age_seconds": 600}`,
+			wantErr: false,
+		},
+		{
+			input:   `{"allowed_origins": ["https://example.com"], "max_age_seconds": -1}`,
+			wantErr: true,
+		},
+		{
+			input:   `{"allowed_origins": "https://example.com"}`,
+			wantErr: true,
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("TestConfigValidation_case_%d", i), func(t *testing.T) {
+			_, err := NewConfigBuilder().WithBytes([]byte(test.input)).Parse()
+			if err != nil && !test.wantErr {
+				t.Fatalf("Unexpected error: %s", err.Error())
+			}
+			if err == nil && test.wantErr {
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestConfigAllows(t *testing.T) {
+	config, err := NewConfigBuilder().WithBytes([]byte(`{"allowed_origins": ["https://Example.com/"], "allowed_methods": ["get", "post"]}`)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !config.AllowsOrigin("https://example.com") || config.AllowsOrigin("https://example.org") {
+		t.Fatalf("Unexpected allowed origins: %v", config.AllowedOrigins)
+	}
+	if !config.AllowsMethod("POST") || config.AllowsMethod("DELETE") {
+		t.Fatalf("Unexpected allowed methods: %v", config.AllowedMethods)
+	}
+	if !config.AllowsHeader("content-type") || config.AllowsHeader("X-Custom") {
+		t.Fatalf("Unexpected allowed headers: %v", config.AllowedHeaders)
+	}
+}
@@ -0,0 +1,141 @@
+// Package ratelimit implements the configuration of the rate limits the server
+// enforces on incoming requests. Requests are limited per limit and per
+// client identity, so that noisy callers of a shared OPA cannot starve the
+// others. The limit of a request is, in order of precedence:
+//
+//  1. the limit returned by the policy decision, if configured and defined,
+//  2. the limit of the longest path prefix matching the URL path,
+//  3. the default limit.
+//
+// Requests without a limit are not rate limited.
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/open-policy-agent/opa/v1/util"
+)
+
+// Config represents the configuration for the Server.RateLimits settings
+type Config struct {
+	Default  *Limit      `json:"default,omitempty"`  // limit of requests to paths without a limit.
+	Paths    []PathLimit `json:"paths,omitempty"`    // limits of requests to URL paths with a prefix.
+	Decision string      `json:"decision,omitempty"` // path of the policy decision returning the limit of a request.
+}
+
+// Limit represents a rate limit, as a token bucket refilled at
+// RequestsPerSecond holding up to Burst requests.
+type Limit struct {
+	RequestsPerSecond float64 `json:"requests_per_second"`
+	Burst             int     `json:"burst,omitempty"`
+}
+
+// PathLimit represents the rate limit of requests to URL paths with a prefix.
+type PathLimit struct {
+	Path string `json:"path"`
+	Limit
+}
+
+// ConfigBuilder assists in the construction of the plugin configuration.
+type ConfigBuilder struct {
+	raw []byte
+}
+
+// NewConfigBuilder returns a new ConfigBuilder to build and parse the server config
+func NewConfigBuilder() *ConfigBuilder {
+	return &ConfigBuilder{}
+}
+
+// WithBytes sets the raw server config
+func (b *ConfigBuilder) WithBytes(config []byte) *ConfigBuilder {
+	b.raw = config
+	return b
+}
+
+// Parse returns a valid Config object with defaults injected. If there is no
+// config, nil is returned, i.e., requests are not rate limited.
+func (b *ConfigBuilder) Parse() (*Config, error) {
+	if b.raw == nil {
+		return nil, nil
+	}
+
+	var result Config
+
+	if err := util.Unmarshal(b.raw, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, result.validateAndInjectDefaults()
+}
+
+// Match returns the limit of the longest path prefix matching path, or the
+// default limit. It returns nil if neither exists.
+func (c *Config) Match(path string) *Limit {
+	if match := c.MatchPath(path); match != nil {
+		return &match.Limit
+	}
+	return c.Default
+}
+
+// MatchPath returns the path limit with the longest prefix matching path, or
+// nil if there is none.
+func (c *Config) MatchPath(path string) *PathLimit {
+	var match *PathLimit
+	for i := range c.Paths {
+		p := &c.Paths[i]
+		if pathHasPrefix(path, p.Path) && (match == nil || len(p.Path) > len(match.Path)) {
+			match = p
+		}
+	}
+	return match
+}
+
+// pathHasPrefix returns true if the segments of path start with those of
+// prefix, e.g., /v1/data/a/b has the prefix /v1/data/a but not /v1/data/ab.
+func pathHasPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return path == prefix || strings.HasPrefix(path, prefix+"/") || prefix == ""
+}
+
+// validateAndInjectDefaults populates defaults if the fields are nil, then
+// validates the config values.
+func (c *Config) validateAndInjectDefaults() error {
+	if c.Default != nil {
+		if err := c.Default.ValidateAndInjectDefaults(); err != nil {
+			return fmt.Errorf("invalid value for server.rate_limits.default field: %w", err)
+		}
+	}
+
+	for i := range c.Paths {
+		if !strings.HasPrefix(c.Paths[i].Path, "/") {
+			return fmt.Errorf("invalid value for server.rate_limits.paths[%d].path field, should start with '/'", i)
+		}
+		if err := c.Paths[i].ValidateAndInjectDefaults(); err != nil {
+			return fmt.Errorf("invalid value for server.rate_limits.paths[%d] field: %w", i, err)
+		}
+	}
+
+	c.Decision = strings.Trim(c.Decision, "/")
+
+	return nil
+}
+
+// ValidateAndInjectDefaults defaults the burst to the requests per second,
+// rounded up, and validates the limit.
+func (l *Limit) ValidateAndInjectDefaults() error {
+	if l.RequestsPerSecond <= 0 || math.IsInf(l.RequestsPerSecond, 0) {
+		return errors.New("requests_per_second should be a positive number")
+	}
+
+	if l.Burst == 0 {
+		l.Burst = int(math.Ceil(l.RequestsPerSecond))
+	}
+	if l.Burst < 0 {
+		return errors.New("burst should be a positive number")
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package ratelimit
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestConfigValidation(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantErr bool
+	}{
+		{
+			input:   `{}`,
+			wantErr: false,
+		},
+		{
+			input:   `{"default": {"requests_per_second": 10}}`,
+			wantErr: false,
+		},
+		{
+			input:   `{"default": {"requests_per_second": 0}}`,
+			wantErr: true,
+		},
+		{
+			input:   `{"default": {"requests_per_second": "10"}}`,
+			wantErr: true,
+		},
+		{
+			input:   `{"default": {"requests_per_second": 10, "burst": -1}}`,
+			wantErr: true,
+		},
+		{
+			input:   `{"paths": [{"path": "/v1/data/expensive", "requests_per_second": 0.5}]}`,
+			wantErr: false,
+		},
+		{
+			input:   `{"paths": [{"path": "v1/data/expensive", "requests_per_second": 0.5}]}`,
+			wantErr: true,
+		},
+		{
+			input:   `{"paths": [{"path": "/v1/data/expensive"}]}`,
+			wantErr: true,
+		},
+		{
+			input:   `{"decision": "/system/rate_limits/limit"}`,
+			wantErr: false,
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("TestConfigValidation_case_%d", i), func(t *testing.T) {
+			_, err := NewConfigBuilder().WithBytes([]byte(test.input)).Parse()
+			if err != nil && !test.wantErr {
+				t.Fatalf("Unexpected error: %s", err.Error())
+			}
+			if err == nil && test.wantErr {
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestConfigMatch(t *testing.T) {
+	config, err := NewConfigBuilder().WithBytes([]byte(`{
+		"default": {"requests_per_second": 100},
+		"paths": [
+			{"path": "/v1/data", "requests_per_second": 10, "burst": 20},
+			{"path": "/v1/data/expensive/", "requests_per_second": 0.5}
+		]
+	}`)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path     string
+		expRate  float64
+		expBurst int
+	}{
+		{path: "/health", expRate: 100, expBurst: 100},
+		{path: "/v1/data", expRate: 10, expBurst: 20},
+		{path: "/v1/data/cheap", expRate: 10, expBurst: 20},
+		{path: "/v1/data/expensive", expRate: 0.5, expBurst: 1},
+		{path: "/v1/data/expensive/allow", expRate: 0.5, expBurst: 1},
+		{path: "/v1/data/expensiveish", expRate: 10, expBurst: 20},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			limit := config.Match(test.path)
+			if limit.RequestsPerSecond != test.expRate || limit.Burst != test.expBurst {
+				t.Fatalf("Expected %v requests per second with burst %d, got %v", test.expRate, test.expBurst, *limit)
+			}
+		})
+	}
+
+	if config, err := NewConfigBuilder().WithBytes(nil).Parse(); config != nil || err != nil {
+		t.Fatalf("Expected no config, got %v, %v", config, err)
+	}
+}
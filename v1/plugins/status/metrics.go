@@ -43,9 +43,11 @@ type collectors struct {
 	lastSuccessfulDownload   *prometheus.GaugeVec
 	lastSuccessfulRequest    *prometheus.GaugeVec
 	bundleLoadDuration       *prometheus.HistogramVec
+	bundleStalenessBreached  *prometheus.GaugeVec
+	serviceRetries           *prometheus.CounterVec
 }
 
-func newCollectors(prometheusConfig *PrometheusConfig) *collectors {
+func newCollectors(prometheusConfig *PrometheusConfig, serviceRetries *prometheus.CounterVec) *collectors {
 	opaInfo := prometheus.NewGauge(
 		prometheus.GaugeOpts{
 			Name:        "opa_info",
@@ -105,6 +107,14 @@ func newCollectors(prometheusConfig *PrometheusConfig) *collectors {
 		[]string{"name"},
 	)
 
+	bundleStalenessBreached := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "bundle_staleness_slo_breached",
+			Help: "Gauge for the bundles in breach of their staleness SLO.",
+		},
+		[]string{"name"},
+	)
+
 	bundleLoadDuration := newBundleLoadDurationCollector(prometheusConfig)
 
 	return &collectors{
@@ -117,6 +127,8 @@ func newCollectors(prometheusConfig *PrometheusConfig) *collectors {
 		lastSuccessfulDownload:   lastSuccessfulDownload,
 		lastSuccessfulRequest:    lastSuccessfulRequest,
 		bundleLoadDuration:       bundleLoadDuration,
+		bundleStalenessBreached:  bundleStalenessBreached,
+		serviceRetries:           serviceRetries,
 	}
 }
 
@@ -160,7 +172,7 @@ func (c *collectors) ReregisterBundleLoadDuration(register prometheus.Registerer
 
 // helper function
 func (c *collectors) toList() []prometheus.Collector {
-	return []prometheus.Collector{
+	list := []prometheus.Collector{
 		c.opaInfo,
 		c.pluginStatus,
 		c.loaded,
@@ -170,5 +182,10 @@ func (c *collectors) toList() []prometheus.Collector {
 		c.lastSuccessfulDownload,
 		c.lastSuccessfulRequest,
 		c.bundleLoadDuration,
+		c.bundleStalenessBreached,
+	}
+	if c.serviceRetries != nil {
+		list = append(list, c.serviceRetries)
 	}
+	return list
 }
@@ -14,6 +14,7 @@ import (
 	"net/http"
 	"reflect"
 	"slices"
+	"time"
 
 	lstat "github.com/open-policy-agent/opa/v1/plugins/logs/status"
 
@@ -46,6 +47,10 @@ type UpdateRequestV1 struct {
 	DecisionLogs *lstat.Status              `json:"decision_logs,omitempty"`
 	Metrics      map[string]any             `json:"metrics,omitempty"`
 	Plugins      map[string]*plugins.Status `json:"plugins,omitempty"`
+
+	// BundleStaleness reports the staleness of the bundles with an SLO, see
+	// Config.BundleStaleness.
+	BundleStaleness map[string]*BundleStaleness `json:"bundle_staleness,omitempty"`
 }
 
 // Plugin implements status reporting. Updates can be triggered by the caller.
@@ -73,13 +78,17 @@ type Plugin struct {
 
 // Config contains configuration for the plugin.
 type Config struct {
-	Plugin           *string              `json:"plugin"`
-	Service          string               `json:"service"`
-	PartitionName    string               `json:"partition_name,omitempty"`
-	ConsoleLogs      bool                 `json:"console"`
-	Prometheus       bool                 `json:"prometheus"`
-	PrometheusConfig *PrometheusConfig    `json:"prometheus_config,omitempty"`
-	Trigger          *plugins.TriggerMode `json:"trigger,omitempty"` // trigger mode
+	Plugin           *string                `json:"plugin"`
+	Service          string                 `json:"service"`
+	PartitionName    string                 `json:"partition_name,omitempty"`
+	ConsoleLogs      bool                   `json:"console"`
+	Prometheus       bool                   `json:"prometheus"`
+	PrometheusConfig *PrometheusConfig      `json:"prometheus_config,omitempty"`
+	Trigger          *plugins.TriggerMode   `json:"trigger,omitempty"` // trigger mode
+	File             *FileSinkConfig        `json:"file,omitempty"`
+	OTLP             *OTLPSinkConfig        `json:"otlp,omitempty"`
+	Sinks            []string               `json:"sinks,omitempty"` // names of sinks registered with RegisterSink
+	BundleStaleness  *BundleStalenessConfig `json:"bundle_staleness,omitempty"`
 }
 
 // BundleLoadDurationNanoseconds represents the configuration for the status.prometheus_config.bundle_loading_duration_ns settings
@@ -100,7 +109,7 @@ type trigger struct {
 func (c *Config) validateAndInjectDefaults(services []string, pluginsList []string, trigger *plugins.TriggerMode) error {
 	if c.Plugin != nil && !slices.Contains(pluginsList, *c.Plugin) {
 		return fmt.Errorf("invalid plugin name %q in status", *c.Plugin)
-	} else if c.Service == "" && len(services) != 0 && !(c.ConsoleLogs || c.Prometheus) {
+	} else if c.Service == "" && len(services) != 0 && !(c.ConsoleLogs || c.Prometheus || c.hasSinks()) {
 		// For backwards compatibility allow defaulting to the first
 		// service listed, but only if console logging is disabled. If enabled
 		// we can't tell if the deployer wanted to use only console logs or
@@ -110,6 +119,16 @@ func (c *Config) validateAndInjectDefaults(services []string, pluginsList []stri
 		return fmt.Errorf("invalid service name %q in status", c.Service)
 	}
 
+	if err := c.validateSinks(services); err != nil {
+		return err
+	}
+
+	if c.BundleStaleness != nil {
+		if err := c.BundleStaleness.validate(); err != nil {
+			return err
+		}
+	}
+
 	t, err := plugins.ValidateAndInjectDefaultsForTriggerMode(trigger, c.Trigger)
 	if err != nil {
 		return fmt.Errorf("invalid status config: %w", err)
@@ -172,11 +191,11 @@ func (b *ConfigBuilder) Parse() (*Config, error) {
 
 	var parsedConfig Config
 
-	if err := util.Unmarshal(b.raw, &parsedConfig); err != nil {
+	if err := plugins.DecodeConfig("status", b.raw, &parsedConfig); err != nil {
 		return nil, err
 	}
 
-	if parsedConfig.Plugin == nil && parsedConfig.Service == "" && len(b.services) == 0 && !parsedConfig.ConsoleLogs && !parsedConfig.Prometheus {
+	if parsedConfig.Plugin == nil && parsedConfig.Service == "" && len(b.services) == 0 && !parsedConfig.ConsoleLogs && !parsedConfig.Prometheus && !parsedConfig.hasSinks() {
 		// Nothing to validate or inject
 		return nil, nil
 	}
@@ -205,7 +224,7 @@ func New(parsedConfig *Config, manager *plugins.Manager) *Plugin {
 		queryCh:        make(chan chan *UpdateRequestV1),
 		logger:         manager.Logger().WithFields(map[string]any{"plugin": Name}),
 		trigger:        make(chan trigger),
-		collectors:     newCollectors(parsedConfig.PrometheusConfig),
+		collectors:     newCollectors(parsedConfig.PrometheusConfig, manager.RetryMetrics()),
 	}
 
 	p.manager.UpdatePluginStatus(Name, &plugins.Status{State: plugins.StateNotReady})
@@ -485,28 +504,40 @@ func (p *Plugin) oneShot(ctx context.Context) error {
 		p.updatePrometheusMetrics(req)
 	}
 
+	sinkErr := p.writeSinks(ctx, req)
+
 	if p.config.Plugin != nil {
 		proxy, ok := p.manager.Plugin(*p.config.Plugin).(Logger)
 		if !ok {
-			return errors.New("plugin does not implement Logger interface")
+			return errors.Join(sinkErr, errors.New("plugin does not implement Logger interface"))
 		}
-		return proxy.Log(ctx, req)
+		return errors.Join(sinkErr, proxy.Log(ctx, req))
 	}
 
 	if p.config.Service != "" {
-		resp, err := p.manager.Client(p.config.Service).
-			WithJSON(req).
-			Do(ctx, "POST", fmt.Sprintf("/status/%v", p.config.PartitionName))
-		if err != nil {
-			return fmt.Errorf("status update failed: %w", err)
-		}
+		return errors.Join(sinkErr, p.upload(ctx, req))
+	}
 
-		defer util.Close(resp)
+	return sinkErr
+}
 
-		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-			return fmt.Errorf("status update failed, server replied with HTTP %v %v", resp.StatusCode, http.StatusText(resp.StatusCode))
-		}
+func (p *Plugin) upload(ctx context.Context, req *UpdateRequestV1) error {
+	// Status updates replace the previous status of the instance, so they
+	// are safe to repeat.
+	resp, err := p.manager.Client(p.config.Service).
+		WithJSON(req).
+		WithRetryable(true).
+		Do(ctx, "POST", fmt.Sprintf("/status/%v", p.config.PartitionName))
+	if err != nil {
+		return fmt.Errorf("status update failed: %w", err)
+	}
+
+	defer util.Close(resp)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("status update failed, server replied with HTTP %v %v", resp.StatusCode, http.StatusText(resp.StatusCode))
 	}
+
 	return nil
 }
 
@@ -541,6 +572,8 @@ func (p *Plugin) snapshot() *UpdateRequestV1 {
 		Bundle:       p.lastBundleStatus,
 		Bundles:      p.lastBundleStatuses,
 		Plugins:      p.lastPluginStatuses,
+
+		BundleStaleness: bundleStaleness(p.config.BundleStaleness, p.lastBundleStatuses, time.Now()),
 	}
 
 	if p.metrics != nil {
@@ -571,6 +604,14 @@ func (p *Plugin) updatePrometheusMetrics(u *UpdateRequestV1) {
 	for name, plugin := range u.Plugins {
 		p.collectors.pluginStatus.WithLabelValues(name, string(plugin.State)).Set(1)
 	}
+	p.collectors.bundleStalenessBreached.Reset()
+	for name, staleness := range u.BundleStaleness {
+		var breached float64
+		if staleness.Breached {
+			breached = 1
+		}
+		p.collectors.bundleStalenessBreached.WithLabelValues(name).Set(breached)
+	}
 	p.collectors.lastSuccessfulActivation.Reset()
 	for _, bundle := range u.Bundles {
 		if bundle.Code == "" && !bundle.LastSuccessfulActivation.IsZero() {
@@ -601,6 +642,7 @@ func (u UpdateRequestV1) Equal(other UpdateRequestV1) bool {
 		u.Bundle.Equal(other.Bundle) &&
 		u.Discovery.Equal(other.Discovery) &&
 		u.DecisionLogs.Equal(other.DecisionLogs) &&
+		maps.EqualFunc(u.BundleStaleness, other.BundleStaleness, equalBundleStaleness) &&
 		nullSafeDeepEqual(u.Metrics, other.Metrics)
 }
 
@@ -207,8 +207,11 @@ func TestPluginPrometheus(t *testing.T) {
 	if registerMock.Collectors[fixture.plugin.collectors.bundleLoadDuration] != true {
 		t.Fatalf("Bundle Load Duration metric was not registered on prometheus")
 	}
-	if len(registerMock.Collectors) != 9 {
-		t.Fatalf("Number of collectors expected (%v), got %v", 9, len(registerMock.Collectors))
+	if registerMock.Collectors[fixture.plugin.collectors.serviceRetries] != true {
+		t.Fatalf("Service retries metric was not registered on prometheus")
+	}
+	if len(registerMock.Collectors) != 11 {
+		t.Fatalf("Number of collectors expected (%v), got %v", 11, len(registerMock.Collectors))
 	}
 
 	lastRequestMetricResult := time.UnixMilli(int64(testutil.ToFloat64(fixture.plugin.collectors.lastRequest) / 1e6))
@@ -264,8 +267,87 @@ func TestPluginPrometheus(t *testing.T) {
 	fixture.plugin.Reconfigure(ctx, prometheusReenabledConfig)
 	eventually(t, func() bool { return fixture.plugin.config.Prometheus == true })
 
-	if len(registerMock.Collectors) != 9 {
-		t.Fatalf("Number of collectors expected (%v), got %v", 9, len(registerMock.Collectors))
+	if registerMock.Collectors[fixture.plugin.collectors.serviceRetries] != true {
+		t.Fatalf("Service retries metric was not registered on prometheus")
+	}
+	if len(registerMock.Collectors) != 11 {
+		t.Fatalf("Number of collectors expected (%v), got %v", 11, len(registerMock.Collectors))
+	}
+}
+
+func TestPluginBundleStaleness(t *testing.T) {
+	fixture := newTestFixture(t, nil, func(c *Config) {
+		c.Prometheus = true
+		c.BundleStaleness = &BundleStalenessConfig{
+			SLOSeconds: 60,
+			Bundles:    map[string]int64{"fresh": 3600},
+		}
+	})
+	fixture.server.ch = make(chan UpdateRequestV1)
+	defer fixture.server.stop()
+
+	ctx := context.Background()
+
+	err := fixture.plugin.Start(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fixture.plugin.Stop(ctx)
+	<-fixture.server.ch
+
+	fresh := testStatus()
+	fresh.LastSuccessfulActivation = time.Now().Add(-time.Minute)
+
+	fixture.plugin.BulkUpdateBundleStatus(map[string]*bundle.Status{
+		"stale":    testStatus(),
+		"fresh":    fresh,
+		"inactive": {Name: "inactive"},
+	})
+	result := <-fixture.server.ch
+
+	exp := map[string]*BundleStaleness{
+		"stale":    {SLOSeconds: 60, Breached: true},
+		"fresh":    {SLOSeconds: 3600, Breached: false},
+		"inactive": {SLOSeconds: 60, Breached: true},
+	}
+	if !maps.EqualFunc(exp, result.BundleStaleness, equalBundleStaleness) {
+		t.Fatalf("Expected bundle staleness %v but got %v", exp, result.BundleStaleness)
+	}
+
+	if result.BundleStaleness["inactive"].StalenessSeconds != nil {
+		t.Fatalf("Expected no staleness for inactive bundle but got %v", *result.BundleStaleness["inactive"].StalenessSeconds)
+	}
+	if s := result.BundleStaleness["fresh"].StalenessSeconds; s == nil || *s < 60 || *s > 3600 {
+		t.Fatalf("Unexpected staleness for fresh bundle: %v", s)
+	}
+
+	for name, staleness := range exp {
+		var want float64
+		if staleness.Breached {
+			want = 1
+		}
+		if got := testutil.ToFloat64(fixture.plugin.collectors.bundleStalenessBreached.WithLabelValues(name)); got != want {
+			t.Fatalf("Expected staleness SLO breach metric %v for bundle %v but got %v", want, name, got)
+		}
+	}
+}
+
+func TestBundleStalenessConfig(t *testing.T) {
+	for _, input := range []string{
+		`{"service": "example", "bundle_staleness": {"slo_seconds": -1}}`,
+		`{"service": "example", "bundle_staleness": {"bundles": {"authz": 0}}}`,
+	} {
+		if _, err := ParseConfig([]byte(input), []string{"example"}, nil); err == nil {
+			t.Fatalf("Expected error for config %v", input)
+		}
+	}
+
+	config, err := ParseConfig([]byte(`{"service": "example", "bundle_staleness": {"slo_seconds": 300}}`), []string{"example"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.BundleStaleness.SLOSeconds != 300 {
+		t.Fatalf("Expected SLO of 300 seconds but got %v", config.BundleStaleness.SLOSeconds)
 	}
 }
 
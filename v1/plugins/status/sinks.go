@@ -0,0 +1,195 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/util"
+)
+
+// Sink receives the status updates of the plugin in addition to the
+// configured service, console or plugin.
+type Sink func(context.Context, *UpdateRequestV1) error
+
+var registeredSinks = struct {
+	sync.RWMutex
+	m map[string]Sink
+}{m: map[string]Sink{}}
+
+// RegisterSink registers a sink under name. Registered sinks are enabled by
+// listing their names in the `sinks` option of the plugin configuration, so
+// they must be registered before the configuration is parsed.
+func RegisterSink(name string, sink Sink) {
+	registeredSinks.Lock()
+	defer registeredSinks.Unlock()
+	registeredSinks.m[name] = sink
+}
+
+func lookupSink(name string) (Sink, bool) {
+	registeredSinks.RLock()
+	defer registeredSinks.RUnlock()
+	s, ok := registeredSinks.m[name]
+	return s, ok
+}
+
+// FileSinkConfig configures a sink that writes the latest status update to a
+// local file, for example for node agents to pick up.
+type FileSinkConfig struct {
+	Path string `json:"path"`
+}
+
+// OTLPSinkConfig configures a sink that exports status updates as OTLP log
+// records to a service over OTLP/HTTP with JSON encoding.
+type OTLPSinkConfig struct {
+	Service  string `json:"service"`
+	Resource string `json:"resource,omitempty" default:"/v1/logs"` // path of the OTLP logs endpoint
+}
+
+func (c *Config) hasSinks() bool {
+	return c.File != nil || c.OTLP != nil || len(c.Sinks) > 0
+}
+
+func (c *Config) validateSinks(services []string) error {
+	if c.File != nil && c.File.Path == "" {
+		return errors.New("missing path for status file sink")
+	}
+
+	if c.OTLP != nil && !slices.Contains(services, c.OTLP.Service) {
+		return fmt.Errorf("invalid service name %q in status otlp sink", c.OTLP.Service)
+	}
+
+	for _, name := range c.Sinks {
+		if _, ok := lookupSink(name); !ok {
+			return fmt.Errorf("invalid sink name %q in status", name)
+		}
+	}
+
+	return nil
+}
+
+// writeSinks writes the status update to all configured sinks, and returns
+// the errors of the sinks that failed.
+func (p *Plugin) writeSinks(ctx context.Context, req *UpdateRequestV1) error {
+	var errs []error
+
+	if p.config.File != nil {
+		if err := writeFileSink(p.config.File.Path, req); err != nil {
+			errs = append(errs, fmt.Errorf("status file sink failed: %w", err))
+		}
+	}
+
+	if p.config.OTLP != nil {
+		if err := p.writeOTLPSink(ctx, req); err != nil {
+			errs = append(errs, fmt.Errorf("status otlp sink failed: %w", err))
+		}
+	}
+
+	for _, name := range p.config.Sinks {
+		sink, ok := lookupSink(name)
+		if !ok {
+			continue
+		}
+		if err := sink(ctx, req); err != nil {
+			errs = append(errs, fmt.Errorf("status sink %q failed: %w", name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// writeFileSink replaces the file at path with the status update, so that
+// readers never see a partially written update.
+func writeFileSink(path string, req *UpdateRequestV1) error {
+	bs, err := json.MarshalIndent(req, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(bs); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+func (p *Plugin) writeOTLPSink(ctx context.Context, req *UpdateRequestV1) error {
+	bs, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	attrs := []otlpKeyValue{{Key: "service.name", Value: otlpValue{StringValue: "opa"}}}
+	for _, k := range util.KeysSorted(req.Labels) {
+		attrs = append(attrs, otlpKeyValue{Key: "opa.labels." + k, Value: otlpValue{StringValue: req.Labels[k]}})
+	}
+
+	payload := map[string]any{
+		"resourceLogs": []any{
+			map[string]any{
+				"resource": map[string]any{"attributes": attrs},
+				"scopeLogs": []any{
+					map[string]any{
+						"scope": map[string]any{"name": "github.com/open-policy-agent/opa/plugins/status"},
+						"logRecords": []any{
+							map[string]any{
+								"timeUnixNano":   strconv.FormatInt(time.Now().UnixNano(), 10),
+								"severityNumber": 9,
+								"severityText":   "INFO",
+								"eventName":      "opa.status",
+								"body":           otlpValue{StringValue: string(bs)},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	resp, err := p.manager.Client(p.config.OTLP.Service).
+		WithJSON(payload).
+		Do(ctx, "POST", p.config.OTLP.Resource)
+	if err != nil {
+		return err
+	}
+
+	defer util.Close(resp)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("server replied with HTTP %v %v", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+
+	return nil
+}
@@ -0,0 +1,191 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package status
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/plugins"
+	"github.com/open-policy-agent/opa/v1/plugins/bundle"
+	inmem "github.com/open-policy-agent/opa/v1/storage/inmem/test"
+	"github.com/open-policy-agent/opa/v1/util"
+)
+
+func TestParseConfigSinks(t *testing.T) {
+	RegisterSink("test-parse", func(context.Context, *UpdateRequestV1) error { return nil })
+
+	tests := []struct {
+		note       string
+		config     string
+		expService string
+		expErr     string
+	}{
+		{
+			note:   "file sink",
+			config: `{"file": {"path": "/tmp/status.json"}}`,
+		},
+		{
+			note:   "file sink without path",
+			config: `{"file": {}}`,
+			expErr: "missing path for status file sink",
+		},
+		{
+			note:   "otlp sink",
+			config: `{"otlp": {"service": "s1"}}`,
+		},
+		{
+			note:   "otlp sink with unknown service",
+			config: `{"otlp": {"service": "missing"}}`,
+			expErr: `invalid service name "missing" in status otlp sink`,
+		},
+		{
+			note:   "registered sink",
+			config: `{"sinks": ["test-parse"]}`,
+		},
+		{
+			note:   "unregistered sink",
+			config: `{"sinks": ["missing"]}`,
+			expErr: `invalid sink name "missing" in status`,
+		},
+		{
+			note:       "sink and service",
+			config:     `{"service": "s0", "sinks": ["test-parse"]}`,
+			expService: "s0",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			config, err := ParseConfig([]byte(tc.config), []string{"s0", "s1"}, nil)
+			if tc.expErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.expErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Sinks disable defaulting to the first service.
+			if config.Service != tc.expService {
+				t.Fatalf("expected service %q, got %q", tc.expService, config.Service)
+			}
+		})
+	}
+}
+
+func TestPluginFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "status.json")
+
+	fixture := newTestFixture(t, nil, func(c *Config) {
+		c.File = &FileSinkConfig{Path: path}
+	})
+	defer fixture.server.stop()
+
+	fixture.plugin.lastBundleStatuses = map[string]*bundle.Status{"test": testStatus()}
+
+	if err := fixture.plugin.oneShot(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var status UpdateRequestV1
+	if err := util.NewJSONDecoder(f).Decode(&status); err != nil {
+		t.Fatal(err)
+	}
+
+	if status.Labels["app"] != "example-app" || status.Bundles["test"] == nil {
+		t.Fatalf("unexpected status in file: %+v", status)
+	}
+}
+
+func TestPluginRegisteredSink(t *testing.T) {
+	var received []*UpdateRequestV1
+	RegisterSink("test-registered", func(_ context.Context, req *UpdateRequestV1) error {
+		received = append(received, req)
+		return nil
+	})
+	RegisterSink("test-failing", func(context.Context, *UpdateRequestV1) error {
+		return errors.New("boom")
+	})
+
+	fixture := newTestFixture(t, nil, func(c *Config) {
+		c.Sinks = []string{"test-registered", "test-failing"}
+	})
+	defer fixture.server.stop()
+
+	fixture.plugin.lastBundleStatuses = map[string]*bundle.Status{}
+
+	err := fixture.plugin.oneShot(context.Background())
+	if err == nil || err.Error() != `status sink "test-failing" failed: boom` {
+		t.Fatalf("expected error of failing sink, got %v", err)
+	}
+
+	if len(received) != 1 || received[0].Labels["id"] != "test-instance-id" {
+		t.Fatalf("expected registered sink to receive status update, got %v", received)
+	}
+}
+
+func TestPluginOTLPSink(t *testing.T) {
+	bodies := make(chan string, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/logs" {
+			t.Errorf("unexpected path %v", r.URL.Path)
+		}
+
+		var payload map[string]any
+		if err := util.NewJSONDecoder(r.Body).Decode(&payload); err != nil {
+			t.Error(err)
+		}
+
+		logs := payload["resourceLogs"].([]any)[0].(map[string]any)
+		record := logs["scopeLogs"].([]any)[0].(map[string]any)["logRecords"].([]any)[0].(map[string]any)
+		bodies <- record["body"].(map[string]any)["stringValue"].(string)
+	}))
+	defer ts.Close()
+
+	manager, err := plugins.New(fmt.Appendf(nil, `{
+		"labels": {"app": "example-app"},
+		"services": [{"name": "otlp", "url": %q}]
+	}`, ts.URL), "test-instance-id", inmem.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := ParseConfig([]byte(`{"otlp": {"service": "otlp"}}`), manager.Services(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := New(config, manager)
+	p.lastBundleStatuses = map[string]*bundle.Status{}
+
+	if err := p.oneShot(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var status UpdateRequestV1
+	if err := util.UnmarshalJSON([]byte(<-bodies), &status); err != nil {
+		t.Fatal(err)
+	}
+
+	if status.Labels["app"] != "example-app" {
+		t.Fatalf("unexpected status in log record: %+v", status)
+	}
+}
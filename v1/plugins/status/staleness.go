@@ -0,0 +1,83 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package status
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/plugins/bundle"
+)
+
+// BundleStalenessConfig configures the service level objective (SLO) for the
+// staleness of bundles, i.e., the time since their last successful activation.
+type BundleStalenessConfig struct {
+	SLOSeconds int64            `json:"slo_seconds,omitempty" validate:"min=0"` // maximum staleness of bundles
+	Bundles    map[string]int64 `json:"bundles,omitempty"`                      // maximum staleness of particular bundles, in seconds
+}
+
+// BundleStaleness describes the staleness of a bundle against its SLO.
+type BundleStaleness struct {
+	StalenessSeconds *float64 `json:"staleness_seconds,omitempty"` // unset if the bundle was never activated
+	SLOSeconds       int64    `json:"slo_seconds"`
+	Breached         bool     `json:"slo_breached"`
+}
+
+func (c *BundleStalenessConfig) validate() error {
+	for name, slo := range c.Bundles {
+		if slo <= 0 {
+			return fmt.Errorf("invalid bundle staleness slo_seconds %d for bundle %q in status", slo, name)
+		}
+	}
+
+	return nil
+}
+
+// slo returns the SLO of the named bundle in seconds, or 0 if it has none.
+func (c *BundleStalenessConfig) slo(name string) int64 {
+	if slo, ok := c.Bundles[name]; ok {
+		return slo
+	}
+	return c.SLOSeconds
+}
+
+// bundleStaleness computes the staleness of the bundles with an SLO at now.
+// Bundles that were never activated are in breach of their SLO.
+func bundleStaleness(c *BundleStalenessConfig, statuses map[string]*bundle.Status, now time.Time) map[string]*BundleStaleness {
+	if c == nil {
+		return nil
+	}
+
+	result := map[string]*BundleStaleness{}
+	for name, s := range statuses {
+		slo := c.slo(name)
+		if slo == 0 || s == nil {
+			continue
+		}
+
+		staleness := &BundleStaleness{SLOSeconds: slo, Breached: true}
+		if !s.LastSuccessfulActivation.IsZero() {
+			seconds := now.Sub(s.LastSuccessfulActivation).Seconds()
+			staleness.StalenessSeconds = &seconds
+			staleness.Breached = seconds > float64(slo)
+		}
+		result[name] = staleness
+	}
+
+	if len(result) == 0 {
+		return nil
+	}
+
+	return result
+}
+
+// equalBundleStaleness compares the staleness of a bundle, ignoring the
+// staleness itself, which changes with every update.
+func equalBundleStaleness(a, b *BundleStaleness) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.SLOSeconds == b.SLOSeconds && a.Breached == b.Breached
+}
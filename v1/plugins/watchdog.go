@@ -0,0 +1,186 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package plugins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/util"
+)
+
+// WatchdogConfig represents the configuration of the watchdog that restarts
+// plugins which stay unhealthy, i.e., in the ERROR or NOT_READY state. The
+// watchdog is enabled by the watchdog key of the configuration file.
+type WatchdogConfig struct {
+	// Plugins are the names of the monitored plugins. All plugins are
+	// monitored if empty.
+	Plugins []string `json:"plugins,omitempty"`
+
+	// Timeout is how long a plugin may stay unhealthy before it is restarted.
+	Timeout time.Duration `json:"timeout" default:"5m" validate:"min=1s"`
+
+	// MinRetryDelay and MaxRetryDelay bound the delay between consecutive
+	// restarts of a plugin that stays unhealthy.
+	MinRetryDelay time.Duration `json:"min_retry_delay" default:"10s" validate:"min=1s"`
+	MaxRetryDelay time.Duration `json:"max_retry_delay" default:"10m" validate:"min=1s"`
+}
+
+const watchdogListenerName = "watchdog"
+
+// watchdogCheckInterval is how often the watchdog looks for plugins to restart.
+var watchdogCheckInterval = time.Second
+
+type watchdogState struct {
+	since    time.Time // when the plugin became unhealthy
+	restarts int       // restarts since the plugin became unhealthy
+	next     time.Time // earliest time of the next restart
+}
+
+type watchdog struct {
+	manager   *Manager
+	config    WatchdogConfig
+	mtx       sync.Mutex
+	unhealthy map[string]*watchdogState
+	stop      chan chan struct{}
+}
+
+func parseWatchdogConfig(raw []byte) (*WatchdogConfig, error) {
+	if raw == nil {
+		return nil, nil
+	}
+
+	var c WatchdogConfig
+	if err := DecodeConfig("watchdog", raw, &c); err != nil {
+		return nil, err
+	}
+
+	if c.MinRetryDelay > c.MaxRetryDelay {
+		return nil, errors.New("watchdog.min_retry_delay: must be <= watchdog.max_retry_delay")
+	}
+
+	return &c, nil
+}
+
+func newWatchdog(m *Manager, config WatchdogConfig) *watchdog {
+	return &watchdog{
+		manager:   m,
+		config:    config,
+		unhealthy: map[string]*watchdogState{},
+		stop:      make(chan chan struct{}),
+	}
+}
+
+func (w *watchdog) Start(ctx context.Context) {
+	w.onStatus(w.manager.PluginStatus())
+	w.manager.RegisterPluginStatusListener(watchdogListenerName, w.onStatus)
+	go w.loop(ctx)
+}
+
+func (w *watchdog) Stop() {
+	w.manager.UnregisterPluginStatusListener(watchdogListenerName)
+	done := make(chan struct{})
+	w.stop <- done
+	<-done
+}
+
+func (w *watchdog) onStatus(statuses map[string]*Status) {
+	now := time.Now()
+
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	for name, status := range statuses {
+		if len(w.config.Plugins) > 0 && !slices.Contains(w.config.Plugins, name) {
+			continue
+		}
+		if status != nil && (status.State == StateErr || status.State == StateNotReady) {
+			if _, ok := w.unhealthy[name]; !ok {
+				w.unhealthy[name] = &watchdogState{since: now}
+			}
+		} else {
+			delete(w.unhealthy, name)
+		}
+	}
+}
+
+func (w *watchdog) loop(ctx context.Context) {
+	ticker := time.NewTicker(watchdogCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.check(ctx, time.Now())
+		case done := <-w.stop:
+			close(done)
+			return
+		}
+	}
+}
+
+func (w *watchdog) check(ctx context.Context, now time.Time) {
+	type restart struct {
+		name    string
+		attempt int
+		since   time.Time
+	}
+
+	var restarts []restart
+
+	func() {
+		w.mtx.Lock()
+		defer w.mtx.Unlock()
+
+		for name, s := range w.unhealthy {
+			if now.Sub(s.since) < w.config.Timeout || now.Before(s.next) {
+				continue
+			}
+			s.restarts++
+			s.next = now.Add(util.DefaultBackoff(float64(w.config.MinRetryDelay), float64(w.config.MaxRetryDelay), s.restarts))
+			restarts = append(restarts, restart{name: name, attempt: s.restarts, since: s.since})
+		}
+	}()
+
+	slices.SortFunc(restarts, func(a, b restart) int {
+		return a.since.Compare(b.since)
+	})
+
+	for _, r := range restarts {
+		w.restart(ctx, r.name, r.attempt, now.Sub(r.since))
+	}
+}
+
+// restart stops and starts the plugin. The outcome is reported as the status
+// of the plugin, and thereby surfaced by the status API, until the plugin
+// updates its status.
+func (w *watchdog) restart(ctx context.Context, name string, attempt int, unhealthy time.Duration) {
+	p := w.manager.Plugin(name)
+	if p == nil {
+		return
+	}
+
+	w.manager.logger.Warn("Plugin %q has been unhealthy for %v, restarting it (attempt %d).", name, unhealthy.Round(time.Second), attempt)
+
+	p.Stop(ctx)
+
+	status := &Status{
+		State:   StateNotReady,
+		Message: fmt.Sprintf("restarted by watchdog after being unhealthy for %v (attempt %d)", unhealthy.Round(time.Second), attempt),
+	}
+	if err := p.Start(ctx); err != nil {
+		w.manager.logger.Error("Failed to restart plugin %q: %v", name, err)
+		status = &Status{
+			State:   StateErr,
+			Message: fmt.Sprintf("restart by watchdog failed (attempt %d): %v", attempt, err),
+		}
+	}
+
+	w.manager.UpdatePluginStatus(name, status)
+}
@@ -0,0 +1,103 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package plugins
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	inmem "github.com/open-policy-agent/opa/v1/storage/inmem/test"
+)
+
+func TestWatchdogRestartsUnhealthyPlugins(t *testing.T) {
+	config := `{"watchdog": {"plugins": ["p1"], "timeout": "1m", "min_retry_delay": "10s", "max_retry_delay": "1m"}}`
+	m, err := New([]byte(config), "test", inmem.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events []string
+	m.Register("p1", &orderRecordingPlugin{name: "p1", events: &events})
+	m.Register("p2", &orderRecordingPlugin{name: "p2", events: &events})
+
+	ctx := context.Background()
+	if err := m.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Stop(ctx)
+
+	if m.watchdog == nil {
+		t.Fatal("expected watchdog to be started")
+	}
+
+	now := time.Now()
+	m.UpdatePluginStatus("p1", &Status{State: StateErr, Message: "download failed"})
+	m.UpdatePluginStatus("p2", &Status{State: StateErr, Message: "not monitored"})
+
+	m.watchdog.check(ctx, now)
+	m.watchdog.check(ctx, now.Add(2*time.Minute))
+
+	exp := []string{"start p1", "start p2", "stop p1", "start p1"}
+	if !slices.Equal(events, exp) {
+		t.Fatalf("expected events %v, got %v", exp, events)
+	}
+
+	status := m.PluginStatus()["p1"]
+	if status.State != StateNotReady || !strings.Contains(status.Message, "restarted by watchdog") || !strings.Contains(status.Message, "attempt 1") {
+		t.Fatalf("unexpected status: %v", status)
+	}
+
+	// The next restart is delayed by the backoff.
+	m.watchdog.check(ctx, now.Add(2*time.Minute+time.Second))
+	if len(events) != len(exp) {
+		t.Fatalf("expected no restart during backoff, got events %v", events)
+	}
+
+	m.watchdog.check(ctx, now.Add(4*time.Minute))
+	exp = append(exp, "stop p1", "start p1")
+	if !slices.Equal(events, exp) {
+		t.Fatalf("expected events %v, got %v", exp, events)
+	}
+
+	// Healthy plugins are not restarted.
+	m.UpdatePluginStatus("p1", &Status{State: StateOK})
+	m.watchdog.check(ctx, now.Add(time.Hour))
+	if !slices.Equal(events, exp) {
+		t.Fatalf("expected events %v, got %v", exp, events)
+	}
+}
+
+func TestWatchdogConfig(t *testing.T) {
+	m, err := New([]byte(`{}`), "test", inmem.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.watchdogConfig != nil {
+		t.Fatalf("expected watchdog to be disabled, got %+v", m.watchdogConfig)
+	}
+
+	m, err = New([]byte(`{"watchdog": {}}`), "test", inmem.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := WatchdogConfig{Timeout: 5 * time.Minute, MinRetryDelay: 10 * time.Second, MaxRetryDelay: 10 * time.Minute}
+	if m.watchdogConfig == nil || !reflect.DeepEqual(*m.watchdogConfig, exp) {
+		t.Fatalf("expected %+v, got %+v", exp, m.watchdogConfig)
+	}
+
+	for config, exp := range map[string]string{
+		`{"watchdog": {"timeout": "10ms"}}`:                                 "watchdog.timeout: must be >= 1s",
+		`{"watchdog": {"min_retry_delay": "1m", "max_retry_delay": "30s"}}`: "watchdog.min_retry_delay: must be <= watchdog.max_retry_delay",
+	} {
+		_, err := New([]byte(config), "test", inmem.New())
+		if err == nil || err.Error() != exp {
+			t.Fatalf("expected error %q, got %v", exp, err)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package rego
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/open-policy-agent/opa/v1/types"
+)
+
+// ErrUndefinedResult is returned by DecodeResult if the result set is empty,
+// i.e., if the query is undefined.
+var ErrUndefinedResult = errors.New("undefined result")
+
+// DecodeOption configures DecodeResult.
+type DecodeOption func(*decodeOptions)
+
+type decodeOptions struct {
+	tpe types.Type
+}
+
+// DecodeType makes DecodeResult validate the result against tpe before
+// decoding it, e.g., against the type the compiler inferred for the rule the
+// query refers to:
+//
+//	tpe := compiler.TypeEnv.GetByRef(ast.MustParseRef("data.authz.decision"))
+//
+// Types inferred from schema annotations are validated like any other type.
+func DecodeType(tpe types.Type) DecodeOption {
+	return func(o *decodeOptions) {
+		o.tpe = tpe
+	}
+}
+
+// DecodeResult decodes the value of the single expression of the single
+// result in rs into out, which must be a pointer, like encoding/json decodes
+// the JSON representation of the value: fields of structs are matched by their
+// json tags. This replaces type assertions on results for queries referring to
+// a single decision, like `data.authz.decision`. If rs is empty,
+// ErrUndefinedResult is returned.
+func DecodeResult(rs ResultSet, out any, opts ...DecodeOption) error {
+	var o decodeOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if len(rs) == 0 {
+		return ErrUndefinedResult
+	}
+	if len(rs) > 1 || len(rs[0].Expressions) != 1 {
+		return fmt.Errorf("cannot decode %d results with %d expressions: expected a single result with a single expression",
+			len(rs), len(rs[0].Expressions))
+	}
+
+	value := rs[0].Expressions[0].Value
+
+	if o.tpe != nil && !conforms(value, o.tpe) {
+		return fmt.Errorf("cannot decode result: value does not conform to type %v", types.Sprint(o.tpe))
+	}
+
+	bs, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("cannot decode result: %w", err)
+	}
+	if err := json.Unmarshal(bs, out); err != nil {
+		return fmt.Errorf("cannot decode result: %w", err)
+	}
+
+	return nil
+}
+
+// conforms returns true if the result value x has type tpe. Sets are
+// represented by arrays in results.
+func conforms(x any, tpe types.Type) bool {
+	switch tpe := tpe.(type) {
+	case *types.NamedType:
+		return conforms(x, tpe.Type)
+	case types.Any:
+		for _, t := range tpe {
+			if conforms(x, t) {
+				return true
+			}
+		}
+		return len(tpe) == 0
+	case types.Null:
+		return x == nil
+	case types.Boolean:
+		_, ok := x.(bool)
+		return ok
+	case types.Number:
+		switch x.(type) {
+		case json.Number, int, int64, uint64, float64:
+			return true
+		}
+		return false
+	case types.String:
+		_, ok := x.(string)
+		return ok
+	case *types.Array:
+		a, ok := x.([]any)
+		if !ok || len(a) < tpe.Len() {
+			return false
+		}
+		for i := range a {
+			if t := tpe.Select(i); t == nil || !conforms(a[i], t) {
+				return false
+			}
+		}
+		return true
+	case *types.Set:
+		a, ok := x.([]any)
+		if !ok {
+			return false
+		}
+		for i := range a {
+			if !conforms(a[i], tpe.Of()) {
+				return false
+			}
+		}
+		return true
+	case *types.Object:
+		obj, ok := x.(map[string]any)
+		if !ok {
+			return false
+		}
+		for k, v := range obj {
+			if t := tpe.Select(k); t == nil || !conforms(v, t) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
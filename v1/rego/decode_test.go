@@ -0,0 +1,94 @@
+package rego_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/rego"
+	"github.com/open-policy-agent/opa/v1/types"
+)
+
+func TestDecodeResult(t *testing.T) {
+	type decision struct {
+		Allow   bool     `json:"allow"`
+		Reasons []string `json:"reasons"`
+		Limit   int      `json:"limit"`
+	}
+
+	ctx := context.Background()
+
+	compiler := ast.MustCompileModules(map[string]string{
+		"authz.rego": `package authz
+
+decision := {"allow": allow, "reasons": reasons, "limit": 10}
+
+default allow := false
+
+allow if input.user == "alice"
+
+reasons contains "not alice" if not allow
+
+users := ["alice", "bob"]`,
+	})
+
+	eval := func(query string, input any) rego.ResultSet {
+		t.Helper()
+		rs, err := rego.New(rego.Query(query), rego.Compiler(compiler), rego.Input(input)).Eval(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rs
+	}
+
+	tpe := compiler.TypeEnv.GetByRef(ast.MustParseRef("data.authz.decision"))
+	if tpe == nil {
+		t.Fatal("expected inferred type")
+	}
+
+	t.Run("struct", func(t *testing.T) {
+		var d decision
+		if err := rego.DecodeResult(eval("data.authz.decision", map[string]any{"user": "bob"}), &d, rego.DecodeType(tpe)); err != nil {
+			t.Fatal(err)
+		}
+		exp := decision{Allow: false, Reasons: []string{"not alice"}, Limit: 10}
+		if !reflect.DeepEqual(d, exp) {
+			t.Fatalf("expected %+v, got %+v", exp, d)
+		}
+	})
+
+	t.Run("undefined", func(t *testing.T) {
+		var d decision
+		err := rego.DecodeResult(eval("data.authz.missing", nil), &d)
+		if !errors.Is(err, rego.ErrUndefinedResult) {
+			t.Fatalf("expected undefined result error, got %v", err)
+		}
+	})
+
+	t.Run("multiple results", func(t *testing.T) {
+		var user string
+		err := rego.DecodeResult(eval("data.authz.users[_]", nil), &user)
+		if err == nil || !strings.Contains(err.Error(), "expected a single result") {
+			t.Fatalf("expected error, got %v", err)
+		}
+	})
+
+	t.Run("type mismatch", func(t *testing.T) {
+		var d decision
+		err := rego.DecodeResult(eval("data.authz.decision", nil), &d, rego.DecodeType(types.NewObject(nil, types.NewDynamicProperty(types.S, types.B))))
+		if err == nil || !strings.Contains(err.Error(), "does not conform to type") {
+			t.Fatalf("expected error, got %v", err)
+		}
+	})
+
+	t.Run("decoding error", func(t *testing.T) {
+		var allow string
+		err := rego.DecodeResult(eval("data.authz.allow", nil), &allow)
+		if err == nil || !strings.Contains(err.Error(), "cannot decode result") {
+			t.Fatalf("expected error, got %v", err)
+		}
+	})
+}
@@ -1,5 +1,9 @@
 package rego
 
+import (
+	wasm_errors "github.com/open-policy-agent/opa/internal/wasm/sdk/opa/errors"
+)
+
 // HaltError is an error type to return from a custom function implementation
 // that will abort the evaluation process (analogous to topdown.Halt).
 type HaltError struct {
@@ -22,3 +26,10 @@ func NewHaltError(err error) error {
 type ErrorDetails interface {
 	Lines() []string
 }
+
+// IsWasmMemoryLimit returns true if err was caused by the evaluation of a
+// policy compiled to Wasm exceeding its memory limit, see WasmMemoryLimit and
+// EvalWasmMemoryLimit.
+func IsWasmMemoryLimit(err error) bool {
+	return wasm_errors.IsMemoryLimit(err)
+}
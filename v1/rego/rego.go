@@ -8,10 +8,12 @@ package rego
 import (
 	"bytes"
 	"context"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
 	"maps"
+	"math/rand/v2"
 	"strings"
 	"time"
 
@@ -99,6 +101,7 @@ type EvalContext struct {
 	hasInput                    bool
 	time                        time.Time
 	seed                        io.Reader
+	randSeed                    *int64
 	rawInput                    *any
 	parsedInput                 ast.Value
 	metrics                     metrics.Metrics
@@ -117,14 +120,26 @@ type EvalContext struct {
 	interQueryBuiltinCache      cache.InterQueryCache
 	interQueryBuiltinValueCache cache.InterQueryValueCache
 	ndBuiltinCache              builtins.NDBCache
+	recordNDBuiltinCalls        bool
+	decisionID                  string
+	recordBundleRevisions       bool
+	bundleRevisions             map[string]string
 	resolvers                   []refResolver
 	httpRoundTripper            topdown.CustomizeRoundTripper
+	allowNet                    []string
+	requestMetadata             map[string]string
+	checkpointInterval          uint64
+	checkpointFunc              topdown.CheckpointFunc
+	dataSnapshot                map[string]any
+	wasmMemoryLimit             uint32
 	sortSets                    bool
 	copyMaps                    bool
 	printHook                   print.Hook
 	capabilities                *ast.Capabilities
 	strictBuiltinErrors         bool
 	virtualCache                topdown.VirtualCache
+	sharedVirtualCache          *topdown.SharedVirtualCache
+	sharedVirtualCacheKey       string
 	baseCache                   topdown.BaseCache
 	tracing                     tracing.Options
 	externalCancel              topdown.Cancel // Note(philip): If non-nil, the cancellation is handled outside of this package.
@@ -318,6 +333,16 @@ func EvalSeed(r io.Reader) EvalOption {
 	}
 }
 
+// EvalRandSeed makes the randomization required by built-in functions, e.g.
+// rand.intn and uuid.rfc4122, deterministic for the given seed: evaluating the
+// same query with the same seed produces the same values. The seed is recorded
+// in the RandSeed field of each Result. It takes precedence over EvalSeed.
+func EvalRandSeed(seed int64) EvalOption {
+	return func(e *EvalContext) {
+		e.randSeed = &seed
+	}
+}
+
 // EvalInterQueryBuiltinCache sets the inter-query cache that built-in functions can utilize
 // during evaluation.
 func EvalInterQueryBuiltinCache(c cache.InterQueryCache) EvalOption {
@@ -342,6 +367,44 @@ func EvalNDBuiltinCache(c builtins.NDBCache) EvalOption {
 	}
 }
 
+// EvalRecordNDBuiltinCalls records the non-deterministic built-in function
+// calls made during evaluation, e.g. time.now_ns or http.send, and their results
+// into the NDBuiltinCalls field of each Result. The calls are recorded into
+// the cache set with EvalNDBuiltinCache or EvalReplayNDBuiltinCalls, if any.
+func EvalRecordNDBuiltinCalls(yes bool) EvalOption {
+	return func(e *EvalContext) {
+		e.recordNDBuiltinCalls = yes
+	}
+}
+
+// EvalDecisionID sets the ID of the decision made by the evaluation, e.g., the
+// ID of the decision logged for it, into the DecisionID field of each Result.
+func EvalDecisionID(id string) EvalOption {
+	return func(e *EvalContext) {
+		e.decisionID = id
+	}
+}
+
+// EvalRecordBundleRevisions records the revisions of the bundles activated in
+// the store the query is evaluated against into the BundleRevisions field of
+// each Result.
+func EvalRecordBundleRevisions(yes bool) EvalOption {
+	return func(e *EvalContext) {
+		e.recordBundleRevisions = yes
+	}
+}
+
+// EvalReplayNDBuiltinCalls re-uses the results of the non-deterministic
+// built-in function calls recorded by a previous evaluation, e.g. with
+// EvalRecordNDBuiltinCalls, so that the evaluation reproduces its result.
+// Calls that were not recorded are evaluated. Unlike EvalNDBuiltinCache, c is
+// not modified by the evaluation.
+func EvalReplayNDBuiltinCalls(c builtins.NDBCache) EvalOption {
+	return func(e *EvalContext) {
+		e.ndBuiltinCache = c.Copy()
+	}
+}
+
 // EvalResolver sets a Resolver for a specified ref path for this evaluation.
 func EvalResolver(ref ast.Ref, r resolver.Resolver) EvalOption {
 	return func(e *EvalContext) {
@@ -356,6 +419,58 @@ func EvalHTTPRoundTripper(t topdown.CustomizeRoundTripper) EvalOption {
 	}
 }
 
+// EvalAllowNet restricts the hosts that built-in functions like http.send and
+// net.lookup_ip_addr may connect to in this evaluation, overriding the allow_net
+// capability. If hosts is empty, no host can be connected to.
+func EvalAllowNet(hosts []string) EvalOption {
+	return func(e *EvalContext) {
+		e.allowNet = hosts
+	}
+}
+
+// EvalRequestMetadata sets the metadata of the request the policy is evaluated
+// for, e.g., the calling service or trace ID, which policies can read with the
+// runtime.request built-in function.
+func EvalRequestMetadata(metadata map[string]string) EvalOption {
+	return func(e *EvalContext) {
+		e.requestMetadata = metadata
+	}
+}
+
+// EvalCheckpoint sets a function to call every interval operations of the
+// evaluation. The function can pause evaluation by blocking, e.g., until the
+// next frame of a host evaluating policies within a time budget per frame,
+// and abort evaluation by returning an error.
+func EvalCheckpoint(interval uint64, f topdown.CheckpointFunc) EvalOption {
+	return func(e *EvalContext) {
+		e.checkpointInterval = interval
+		e.checkpointFunc = f
+	}
+}
+
+// EvalDataSnapshot causes the query to be evaluated as if it was evaluated
+// `with data as <data>`, i.e., with rules disabled and base documents read from
+// data. The data is loaded into a transient in-memory store rather than
+// converted into a term, which keeps replacing large data documents cheap.
+// Partial evaluation does not support this option.
+func EvalDataSnapshot(data map[string]any) EvalOption {
+	return func(e *EvalContext) {
+		e.dataSnapshot = data
+	}
+}
+
+// EvalWasmMemoryLimit sets the maximum number of bytes the heap of a policy
+// compiled to Wasm may grow by during the evaluation. If it is exceeded, the
+// evaluation is aborted with an error for which IsWasmMemoryLimit returns true.
+// The limit is checked when built-in functions are called and when the
+// evaluation completes, see WasmMemoryLimit for a limit that is enforced
+// whenever the memory grows. It only applies to the Wasm target, see Target.
+func EvalWasmMemoryLimit(limit uint32) EvalOption {
+	return func(e *EvalContext) {
+		e.wasmMemoryLimit = limit
+	}
+}
+
 // EvalSortSets causes the evaluator to sort sets before returning them as JSON arrays.
 func EvalSortSets(yes bool) EvalOption {
 	return func(e *EvalContext) {
@@ -385,6 +500,18 @@ func EvalVirtualCache(vc topdown.VirtualCache) EvalOption {
 	}
 }
 
+// EvalSharedVirtualCache sets the topdown.SharedVirtualCache that stores the
+// values of virtual documents that only depend on data across evaluations.
+// The key must identify the policy and data the evaluation runs against,
+// e.g., the bundle revisions and a hash of the data, so that values computed
+// from a previous version are not reused.
+func EvalSharedVirtualCache(c *topdown.SharedVirtualCache, key string) EvalOption {
+	return func(e *EvalContext) {
+		e.sharedVirtualCache = c
+		e.sharedVirtualCacheKey = key
+	}
+}
+
 // EvalBaseCache sets the topdown.BaseCache to use for evaluation.
 // This is optional, and if not set, the default cache is used.
 func EvalBaseCache(bc topdown.BaseCache) EvalOption {
@@ -464,6 +591,14 @@ func (pq preparedQuery) newEvalContext(ctx context.Context, options []EvalOption
 		ectx.instrumentation = topdown.NewInstrumentation(ectx.metrics)
 	}
 
+	if ectx.recordNDBuiltinCalls && ectx.ndBuiltinCache == nil {
+		ectx.ndBuiltinCache = builtins.NDBCache{}
+	}
+
+	if ectx.randSeed != nil {
+		ectx.seed = seededReader(*ectx.randSeed)
+	}
+
 	// Default to an empty "finish" function
 	finishFunc := func(context.Context) {}
 
@@ -483,6 +618,13 @@ func (pq preparedQuery) newEvalContext(ctx context.Context, options []EvalOption
 		}
 	}
 
+	if ectx.recordBundleRevisions {
+		ectx.bundleRevisions, err = readBundleRevisions(ctx, pq.r.store, ectx.txn)
+		if err != nil {
+			return nil, finishFunc, err
+		}
+	}
+
 	// If we didn't get an input specified in the Eval options
 	// then fall back to the Rego object's input fields.
 	if !ectx.hasInput {
@@ -532,6 +674,26 @@ func (pq PreparedEvalQuery) Eval(ctx context.Context, options ...EvalOption) (Re
 	return pq.r.eval(ctx, ectx)
 }
 
+// Iter evaluates this PreparedEvalQuery's Rego object like Eval, but calls
+// iter with each result as it is produced instead of collecting them into a
+// ResultSet. This keeps memory usage bounded for queries that produce a large
+// number of results. If iter returns an error, evaluation stops and the error
+// is returned.
+//
+// Results of queries evaluated by other targets than the default "rego"
+// target are produced all at once, and only then passed to iter.
+func (pq PreparedEvalQuery) Iter(ctx context.Context, iter func(Result) error, options ...EvalOption) error {
+	ectx, finish, err := pq.newEvalContext(ctx, options)
+	if err != nil {
+		return err
+	}
+	defer finish(ctx)
+
+	ectx.compiledQuery = pq.r.compiledQueries[evalQueryType]
+
+	return pq.r.iter(ctx, ectx, iter)
+}
+
 // PreparedPartialQuery holds the prepared Rego state that has been pre-processed
 // for partial evaluations.
 type PreparedPartialQuery struct {
@@ -640,6 +802,7 @@ type Rego struct {
 	runtime                     *ast.Term
 	time                        time.Time
 	seed                        io.Reader
+	randSeed                    *int64
 	capabilities                *ast.Capabilities
 	builtinDecls                map[string]*ast.Builtin
 	builtinFuncs                map[string]*topdown.Builtin
@@ -653,12 +816,20 @@ type Rego struct {
 	interQueryBuiltinCache      cache.InterQueryCache
 	interQueryBuiltinValueCache cache.InterQueryValueCache
 	ndBuiltinCache              builtins.NDBCache
+	recordNDBuiltinCalls        bool
+	recordBundleRevisions       bool
+	allowNet                    []string
+	requestMetadata             map[string]string
+	checkpointInterval          uint64
+	checkpointFunc              topdown.CheckpointFunc
 	strictBuiltinErrors         bool
+	sortedIteration             bool
 	builtinErrorList            *[]topdown.Error
 	resolvers                   []refResolver
 	schemaSet                   *ast.SchemaSet
 	target                      string // target type (wasm, rego, etc.)
 	opa                         opa.EvalEngine
+	wasmMemoryLimit             uint32
 	generateJSON                func(*ast.Term, *EvalContext) (any, error)
 	printHook                   print.Hook
 	enablePrintStatements       bool
@@ -670,6 +841,8 @@ type Rego struct {
 	regoVersion                 ast.RegoVersion
 	compilerHook                func(*ast.Compiler)
 	evalMode                    *ast.CompilerEvalMode
+	builtinMocks                []builtinMock
+	dataMocks                   []dataMock
 }
 
 func (r *Rego) RegoVersion() ast.RegoVersion {
@@ -683,6 +856,10 @@ type Function struct {
 	Decl             *types.Function
 	Memoize          bool
 	Nondeterministic bool
+
+	// OutputArgs holds the zero-based positions of the arguments that the
+	// function binds. See ast.Builtin.
+	OutputArgs []int
 }
 
 // BuiltinContext contains additional attributes from the evaluator that
@@ -713,6 +890,7 @@ func RegisterBuiltin1(decl *Function, impl Builtin1) {
 		Description:      decl.Description,
 		Decl:             decl.Decl,
 		Nondeterministic: decl.Nondeterministic,
+		OutputArgs:       decl.OutputArgs,
 	})
 	topdown.RegisterBuiltinFunc(decl.Name, func(bctx BuiltinContext, terms []*ast.Term, iter func(*ast.Term) error) error {
 		result, err := memoize(decl, bctx, terms, func() (*ast.Term, error) { return impl(bctx, terms[0]) })
@@ -727,6 +905,7 @@ func RegisterBuiltin2(decl *Function, impl Builtin2) {
 		Description:      decl.Description,
 		Decl:             decl.Decl,
 		Nondeterministic: decl.Nondeterministic,
+		OutputArgs:       decl.OutputArgs,
 	})
 	topdown.RegisterBuiltinFunc(decl.Name, func(bctx BuiltinContext, terms []*ast.Term, iter func(*ast.Term) error) error {
 		result, err := memoize(decl, bctx, terms, func() (*ast.Term, error) { return impl(bctx, terms[0], terms[1]) })
@@ -741,6 +920,7 @@ func RegisterBuiltin3(decl *Function, impl Builtin3) {
 		Description:      decl.Description,
 		Decl:             decl.Decl,
 		Nondeterministic: decl.Nondeterministic,
+		OutputArgs:       decl.OutputArgs,
 	})
 	topdown.RegisterBuiltinFunc(decl.Name, func(bctx BuiltinContext, terms []*ast.Term, iter func(*ast.Term) error) error {
 		result, err := memoize(decl, bctx, terms, func() (*ast.Term, error) { return impl(bctx, terms[0], terms[1], terms[2]) })
@@ -755,6 +935,7 @@ func RegisterBuiltin4(decl *Function, impl Builtin4) {
 		Description:      decl.Description,
 		Decl:             decl.Decl,
 		Nondeterministic: decl.Nondeterministic,
+		OutputArgs:       decl.OutputArgs,
 	})
 	topdown.RegisterBuiltinFunc(decl.Name, func(bctx BuiltinContext, terms []*ast.Term, iter func(*ast.Term) error) error {
 		result, err := memoize(decl, bctx, terms, func() (*ast.Term, error) { return impl(bctx, terms[0], terms[1], terms[2], terms[3]) })
@@ -769,6 +950,7 @@ func RegisterBuiltinDyn(decl *Function, impl BuiltinDyn) {
 		Description:      decl.Description,
 		Decl:             decl.Decl,
 		Nondeterministic: decl.Nondeterministic,
+		OutputArgs:       decl.OutputArgs,
 	})
 	topdown.RegisterBuiltinFunc(decl.Name, func(bctx BuiltinContext, terms []*ast.Term, iter func(*ast.Term) error) error {
 		result, err := memoize(decl, bctx, terms, func() (*ast.Term, error) { return impl(bctx, terms) })
@@ -826,8 +1008,9 @@ func FunctionDecl(decl *Function) func(*Rego) {
 func newDecl(decl *Function) func(*Rego) {
 	return func(r *Rego) {
 		r.builtinDecls[decl.Name] = &ast.Builtin{
-			Name: decl.Name,
-			Decl: decl.Decl,
+			Name:       decl.Name,
+			Decl:       decl.Decl,
+			OutputArgs: decl.OutputArgs,
 		}
 	}
 }
@@ -1159,6 +1342,36 @@ func Seed(r io.Reader) func(*Rego) {
 	}
 }
 
+// RandSeed makes the randomization required by built-in functions
+// deterministic for the given seed. See EvalRandSeed.
+func RandSeed(seed int64) func(*Rego) {
+	return func(r *Rego) {
+		r.randSeed = &seed
+	}
+}
+
+// Mock replaces the built-in function named by name with the implementation
+// f for every evaluation of the query. The replacement follows the semantics
+// of the `with` keyword, i.e., the query is evaluated as if each expression
+// carried `with <name> as <mock>`. The built-in function (or a custom function
+// provided via the FunctionN options) must be known when New is called. This
+// option has no effect if the caller supplies the ast.Compiler instance.
+func Mock(name string, f BuiltinDyn) func(r *Rego) {
+	return func(r *Rego) {
+		r.builtinMocks = append(r.builtinMocks, builtinMock{name: name, f: f})
+	}
+}
+
+// MockData replaces the document at path (e.g., "data.roles") with value for
+// every evaluation of the query. The replacement follows the semantics of the
+// `with` keyword, i.e., the query is evaluated as if each expression carried
+// `with <path> as <value>`.
+func MockData(path string, value any) func(r *Rego) {
+	return func(r *Rego) {
+		r.dataMocks = append(r.dataMocks, dataMock{path: path, value: value})
+	}
+}
+
 // PrintTrace is a helper function to write a human-readable version of the
 // trace to the writer w.
 func PrintTrace(w io.Writer, r *Rego) {
@@ -1234,6 +1447,57 @@ func NDBuiltinCache(c builtins.NDBCache) func(r *Rego) {
 	}
 }
 
+// RecordNDBuiltinCalls records the non-deterministic built-in function calls
+// made during evaluation and their results into the NDBuiltinCalls field of
+// each Result. See EvalRecordNDBuiltinCalls.
+func RecordNDBuiltinCalls(yes bool) func(r *Rego) {
+	return func(r *Rego) {
+		r.recordNDBuiltinCalls = yes
+	}
+}
+
+// RecordBundleRevisions records the revisions of the bundles activated in the
+// store into the BundleRevisions field of each Result. See
+// EvalRecordBundleRevisions.
+func RecordBundleRevisions(yes bool) func(r *Rego) {
+	return func(r *Rego) {
+		r.recordBundleRevisions = yes
+	}
+}
+
+// ReplayNDBuiltinCalls re-uses the results of recorded non-deterministic
+// built-in function calls. See EvalReplayNDBuiltinCalls.
+func ReplayNDBuiltinCalls(c builtins.NDBCache) func(r *Rego) {
+	return func(r *Rego) {
+		r.ndBuiltinCache = c.Copy()
+	}
+}
+
+// AllowNet restricts the hosts that built-in functions like http.send and
+// net.lookup_ip_addr may connect to. See EvalAllowNet.
+func AllowNet(hosts []string) func(r *Rego) {
+	return func(r *Rego) {
+		r.allowNet = hosts
+	}
+}
+
+// RequestMetadata sets the metadata of the request the policy is evaluated
+// for. See EvalRequestMetadata.
+func RequestMetadata(metadata map[string]string) func(r *Rego) {
+	return func(r *Rego) {
+		r.requestMetadata = metadata
+	}
+}
+
+// Checkpoint sets a function to call every interval operations of the
+// evaluation. See EvalCheckpoint.
+func Checkpoint(interval uint64, f topdown.CheckpointFunc) func(r *Rego) {
+	return func(r *Rego) {
+		r.checkpointInterval = interval
+		r.checkpointFunc = f
+	}
+}
+
 // StrictBuiltinErrors tells the evaluator to treat all built-in function errors as fatal errors.
 func StrictBuiltinErrors(yes bool) func(r *Rego) {
 	return func(r *Rego) {
@@ -1241,6 +1505,16 @@ func StrictBuiltinErrors(yes bool) func(r *Rego) {
 	}
 }
 
+// SortedIteration tells the evaluator to iterate over documents defined by both
+// base documents and rules in sorted key order, so that results and traces do
+// not depend on where documents are defined. This has a performance cost, and
+// is not supported by the Wasm target.
+func SortedIteration(yes bool) func(r *Rego) {
+	return func(r *Rego) {
+		r.sortedIteration = yes
+	}
+}
+
 // BuiltinErrorList supplies an error slice to store built-in function errors.
 func BuiltinErrorList(list *[]topdown.Error) func(r *Rego) {
 	return func(r *Rego) {
@@ -1278,6 +1552,18 @@ func Target(t string) func(r *Rego) {
 	}
 }
 
+// WasmMemoryLimit sets the maximum number of bytes of memory of the instances
+// evaluating a policy compiled to Wasm. The memory is not grown beyond the
+// limit, and evaluations that need more memory are aborted with an error for
+// which IsWasmMemoryLimit returns true. Unlike EvalWasmMemoryLimit, this limit
+// applies to all evaluations of the prepared query, and is enforced whenever
+// the memory grows. It only applies to the Wasm target, see Target.
+func WasmMemoryLimit(limit uint32) func(r *Rego) {
+	return func(r *Rego) {
+		r.wasmMemoryLimit = limit
+	}
+}
+
 // GenerateJSON sets the AST to JSON converter for the results.
 func GenerateJSON(f func(*ast.Term, *EvalContext) (any, error)) func(r *Rego) {
 	return func(r *Rego) {
@@ -1353,6 +1639,8 @@ func New(options ...func(r *Rego)) *Rego {
 		option(r)
 	}
 
+	r.registerBuiltinMocks()
+
 	callHook := r.compiler == nil // call hook only if we created the compiler here
 
 	if r.compiler == nil {
@@ -1457,12 +1745,30 @@ func (r *Rego) Eval(ctx context.Context) (ResultSet, error) {
 		EvalInterQueryBuiltinCache(r.interQueryBuiltinCache),
 		EvalInterQueryBuiltinValueCache(r.interQueryBuiltinValueCache),
 		EvalSeed(r.seed),
+		EvalRecordNDBuiltinCalls(r.recordNDBuiltinCalls),
+		EvalRecordBundleRevisions(r.recordBundleRevisions),
 	}
 
 	if r.ndBuiltinCache != nil {
 		evalArgs = append(evalArgs, EvalNDBuiltinCache(r.ndBuiltinCache))
 	}
 
+	if r.randSeed != nil {
+		evalArgs = append(evalArgs, EvalRandSeed(*r.randSeed))
+	}
+
+	if r.allowNet != nil {
+		evalArgs = append(evalArgs, EvalAllowNet(r.allowNet))
+	}
+
+	if r.requestMetadata != nil {
+		evalArgs = append(evalArgs, EvalRequestMetadata(r.requestMetadata))
+	}
+
+	if r.checkpointFunc != nil {
+		evalArgs = append(evalArgs, EvalCheckpoint(r.checkpointInterval, r.checkpointFunc))
+	}
+
 	for _, qt := range r.queryTracers {
 		evalArgs = append(evalArgs, EvalQueryTracer(qt))
 	}
@@ -1540,6 +1846,18 @@ func (r *Rego) Partial(ctx context.Context) (*PartialQueries, error) {
 		evalArgs = append(evalArgs, EvalNDBuiltinCache(r.ndBuiltinCache))
 	}
 
+	if r.allowNet != nil {
+		evalArgs = append(evalArgs, EvalAllowNet(r.allowNet))
+	}
+
+	if r.requestMetadata != nil {
+		evalArgs = append(evalArgs, EvalRequestMetadata(r.requestMetadata))
+	}
+
+	if r.checkpointFunc != nil {
+		evalArgs = append(evalArgs, EvalCheckpoint(r.checkpointInterval, r.checkpointFunc))
+	}
+
 	for _, t := range r.queryTracers {
 		evalArgs = append(evalArgs, EvalQueryTracer(t))
 	}
@@ -1801,13 +2119,18 @@ func (r *Rego) PrepareForEval(ctx context.Context, opts ...PrepareOption) (Prepa
 		}
 
 		// nolint: staticcheck // SA4006 false positive
-		data, err := r.store.Read(ctx, r.txn, storage.Path{})
+		data, err := storage.ReadJSON(ctx, r.store, r.txn, storage.Path{})
 		if err != nil {
 			_ = txnClose(ctx, err) // Ignore error
 			return PreparedEvalQuery{}, err
 		}
 
-		o, err := e.New().WithPolicyBytes(cr.Bytes).WithDataJSON(data).Init()
+		eng := e.New().WithPolicyBytes(cr.Bytes).WithDataJSON(data)
+		if r.wasmMemoryLimit > 0 {
+			eng = eng.WithMemoryLimit(r.wasmMemoryLimit)
+		}
+
+		o, err := eng.Init()
 		if err != nil {
 			_ = txnClose(ctx, err) // Ignore error
 			return PreparedEvalQuery{}, err
@@ -2227,6 +2550,11 @@ func (r *Rego) compileQuery(query ast.Body, imports []*ast.Import, _ metrics.Met
 		qc = qc.WithStageAfter(extra.after, extra.stage)
 	}
 
+	query, err := r.applyMocks(query)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	compiled, err := qc.Compile(query)
 
 	return qc, compiled, err
@@ -2234,22 +2562,59 @@ func (r *Rego) compileQuery(query ast.Body, imports []*ast.Import, _ metrics.Met
 }
 
 func (r *Rego) eval(ctx context.Context, ectx *EvalContext) (ResultSet, error) {
+	var rs ResultSet
+	err := r.iter(ctx, ectx, func(result Result) error {
+		rs = append(rs, result)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rs) == 0 {
+		return nil, nil
+	}
+
+	return rs, nil
+}
+
+func (r *Rego) iter(ctx context.Context, ectx *EvalContext, iter func(Result) error) error {
+	var rs ResultSet
+	var err error
+
 	switch {
 	case r.targetPrepState != nil: // target plugin flow
 		var val ast.Value
 		if r.runtime != nil {
 			val = r.runtime.Value
 		}
-		s, err := r.targetPrepState.Eval(ctx, ectx, val)
-		if err != nil {
-			return nil, err
+		var s ast.Value
+		s, err = r.targetPrepState.Eval(ctx, ectx, val)
+		if err == nil {
+			rs, err = r.valueToQueryResult(s, ectx)
 		}
-		return r.valueToQueryResult(s, ectx)
 	case r.target == targetWasm:
-		return r.evalWasm(ctx, ectx)
-	case r.target == targetRego: // continue
+		rs, err = r.evalWasm(ctx, ectx)
+	default:
+		return r.iterRego(ctx, ectx, iter)
+	}
+
+	if err != nil {
+		return err
 	}
 
+	for _, result := range rs {
+		if err := iter(result); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *Rego) iterRego(ctx context.Context, ectx *EvalContext, iter func(Result) error) error {
+
 	q := topdown.NewQuery(ectx.compiledQuery.query).
 		WithQueryCompiler(ectx.compiledQuery.compiler).
 		WithCompiler(r.compiler).
@@ -2264,6 +2629,7 @@ func (r *Rego) eval(ctx context.Context, ectx *EvalContext) (ResultSet, error) {
 		WithInterQueryBuiltinCache(ectx.interQueryBuiltinCache).
 		WithInterQueryBuiltinValueCache(ectx.interQueryBuiltinValueCache).
 		WithStrictBuiltinErrors(r.strictBuiltinErrors).
+		WithSortedIteration(r.sortedIteration).
 		WithBuiltinErrorList(r.builtinErrorList).
 		WithSeed(ectx.seed).
 		WithPrintHook(ectx.printHook).
@@ -2271,6 +2637,10 @@ func (r *Rego) eval(ctx context.Context, ectx *EvalContext) (ResultSet, error) {
 		WithVirtualCache(ectx.virtualCache).
 		WithBaseCache(ectx.baseCache)
 
+	if ectx.sharedVirtualCache != nil {
+		q = q.WithSharedVirtualCache(ectx.sharedVirtualCache, ectx.sharedVirtualCacheKey)
+	}
+
 	if !ectx.time.IsZero() {
 		q = q.WithTime(ectx.time)
 	}
@@ -2291,6 +2661,22 @@ func (r *Rego) eval(ctx context.Context, ectx *EvalContext) (ResultSet, error) {
 		q = q.WithHTTPRoundTripper(ectx.httpRoundTripper)
 	}
 
+	if ectx.allowNet != nil {
+		q = q.WithAllowNet(ectx.allowNet)
+	}
+
+	if ectx.requestMetadata != nil {
+		q = q.WithRequestMetadata(ectx.requestMetadata)
+	}
+
+	if ectx.checkpointFunc != nil {
+		q = q.WithCheckpoint(ectx.checkpointInterval, ectx.checkpointFunc)
+	}
+
+	if ectx.dataSnapshot != nil {
+		q = q.WithDataSnapshot(ectx.dataSnapshot)
+	}
+
 	for i := range ectx.resolvers {
 		q = q.WithResolver(ectx.resolvers[i].ref, ectx.resolvers[i].r)
 	}
@@ -2310,25 +2696,13 @@ func (r *Rego) eval(ctx context.Context, ectx *EvalContext) (ResultSet, error) {
 		q = q.WithCancel(ectx.externalCancel)
 	}
 
-	var rs ResultSet
-	err := q.Iter(ctx, func(qr topdown.QueryResult) error {
+	return q.Iter(ctx, func(qr topdown.QueryResult) error {
 		result, err := r.generateResult(qr, ectx)
 		if err != nil {
 			return err
 		}
-		rs = append(rs, result)
-		return nil
+		return iter(result)
 	})
-
-	if err != nil {
-		return nil, err
-	}
-
-	if len(rs) == 0 {
-		return nil, nil
-	}
-
-	return rs, nil
 }
 
 func (r *Rego) evalWasm(ctx context.Context, ectx *EvalContext) (ResultSet, error) {
@@ -2346,6 +2720,7 @@ func (r *Rego) evalWasm(ctx context.Context, ectx *EvalContext) (ResultSet, erro
 		NDBuiltinCache:         ectx.ndBuiltinCache,
 		PrintHook:              ectx.printHook,
 		Capabilities:           ectx.capabilities,
+		MemoryLimit:            ectx.wasmMemoryLimit,
 	})
 	if err != nil {
 		return nil, err
@@ -2428,6 +2803,22 @@ func (r *Rego) generateResult(qr topdown.QueryResult, ectx *EvalContext) (Result
 		}
 
 	}
+
+	if ectx.recordNDBuiltinCalls {
+		result.NDBuiltinCalls = ectx.ndBuiltinCache.Copy()
+	}
+
+	if ectx.randSeed != nil {
+		seed := *ectx.randSeed
+		result.RandSeed = &seed
+	}
+
+	result.DecisionID = ectx.decisionID
+
+	if ectx.recordBundleRevisions {
+		result.BundleRevisions = maps.Clone(ectx.bundleRevisions)
+	}
+
 	return result, nil
 }
 
@@ -2566,6 +2957,7 @@ func (r *Rego) partial(ctx context.Context, ectx *EvalContext) (*PartialQueries,
 		WithInterQueryBuiltinCache(ectx.interQueryBuiltinCache).
 		WithInterQueryBuiltinValueCache(ectx.interQueryBuiltinValueCache).
 		WithStrictBuiltinErrors(ectx.strictBuiltinErrors).
+		WithSortedIteration(r.sortedIteration).
 		WithSeed(ectx.seed).
 		WithPrintHook(ectx.printHook)
 
@@ -2577,6 +2969,22 @@ func (r *Rego) partial(ctx context.Context, ectx *EvalContext) (*PartialQueries,
 		q = q.WithNDBuiltinCache(ectx.ndBuiltinCache)
 	}
 
+	if ectx.allowNet != nil {
+		q = q.WithAllowNet(ectx.allowNet)
+	}
+
+	if ectx.requestMetadata != nil {
+		q = q.WithRequestMetadata(ectx.requestMetadata)
+	}
+
+	if ectx.checkpointFunc != nil {
+		q = q.WithCheckpoint(ectx.checkpointInterval, ectx.checkpointFunc)
+	}
+
+	if ectx.dataSnapshot != nil {
+		q = q.WithDataSnapshot(ectx.dataSnapshot)
+	}
+
 	for i := range ectx.queryTracers {
 		q = q.WithQueryTracer(ectx.queryTracers[i])
 	}
@@ -2883,6 +3291,97 @@ type extraStage struct {
 	stage ast.QueryCompilerStageDefinition
 }
 
+type builtinMock struct {
+	name string
+	f    BuiltinDyn
+	decl *ast.Builtin // replacement declaration, nil if name is unknown
+}
+
+type dataMock struct {
+	path  string
+	value any
+}
+
+// mockBuiltinPrefix is the root of the names the replacement functions for
+// mocked built-ins are registered under.
+const mockBuiltinPrefix = "__mock__"
+
+// registerBuiltinMocks declares a replacement function for each mocked
+// built-in. It must be called before the compiler is created so that the
+// replacements are known to it.
+func (r *Rego) registerBuiltinMocks() {
+	for i := range r.builtinMocks {
+		m := &r.builtinMocks[i]
+		bi, ok := ast.BuiltinMap[m.name]
+		if !ok {
+			bi, ok = r.builtinDecls[m.name]
+		}
+		if !ok {
+			continue
+		}
+		decl := &Function{
+			Name:             mockBuiltinPrefix + "." + m.name,
+			Decl:             bi.Decl,
+			Nondeterministic: bi.Nondeterministic,
+		}
+		f := m.f
+		newFunction(decl, func(bctx BuiltinContext, terms []*ast.Term, iter func(*ast.Term) error) error {
+			result, err := f(bctx, terms)
+			return finishFunction(m.name, bctx, result, err, iter)
+		})(r)
+		m.decl = r.builtinDecls[decl.Name]
+	}
+}
+
+// applyMocks returns a copy of query where every expression carries the with
+// modifiers for the configured built-in and data mocks. If no mocks are
+// configured, query is returned unchanged.
+func (r *Rego) applyMocks(query ast.Body) (ast.Body, error) {
+	if len(r.builtinMocks) == 0 && len(r.dataMocks) == 0 {
+		return query, nil
+	}
+
+	withs := make([]*ast.With, 0, len(r.builtinMocks)+len(r.dataMocks))
+
+	for _, m := range r.builtinMocks {
+		if m.decl == nil {
+			return nil, fmt.Errorf("mock: unknown built-in function %q", m.name)
+		}
+		target, err := ast.ParseRef(m.name)
+		if err != nil {
+			return nil, fmt.Errorf("mock: %w", err)
+		}
+		value, err := ast.ParseRef(m.decl.Name)
+		if err != nil {
+			return nil, fmt.Errorf("mock: %w", err)
+		}
+		withs = append(withs, &ast.With{Target: ast.NewTerm(target), Value: ast.NewTerm(value)})
+	}
+
+	for _, m := range r.dataMocks {
+		target, err := ast.ParseRef(m.path)
+		if err != nil {
+			return nil, fmt.Errorf("mock data: %w", err)
+		}
+		if !target.HasPrefix(ast.DefaultRootRef) {
+			return nil, fmt.Errorf("mock data: path must refer to %v: %v", ast.DefaultRootDocument, target)
+		}
+		value, err := ast.InterfaceToValue(m.value)
+		if err != nil {
+			return nil, fmt.Errorf("mock data: %v: %w", target, err)
+		}
+		withs = append(withs, &ast.With{Target: ast.NewTerm(target), Value: ast.NewTerm(value)})
+	}
+
+	cpy := query.Copy()
+	for _, expr := range cpy {
+		for _, w := range withs {
+			expr.With = append(expr.With, w.Copy())
+		}
+	}
+	return cpy, nil
+}
+
 type refResolver struct {
 	ref ast.Ref
 	r   resolver.Resolver
@@ -2923,6 +3422,33 @@ func iteration(x any) bool {
 	return stopped
 }
 
+// seededReader returns a deterministic stream of random bytes for seed.
+func seededReader(seed int64) io.Reader {
+	var key [32]byte
+	binary.LittleEndian.PutUint64(key[:], uint64(seed))
+	return rand.NewChaCha8(key)
+}
+
+// readBundleRevisions returns the revisions of the bundles activated in store
+// by name.
+func readBundleRevisions(ctx context.Context, store storage.Store, txn storage.Transaction) (map[string]string, error) {
+	names, err := bundle.ReadBundleNamesFromStore(ctx, store, txn)
+	if err != nil && !storage.IsNotFound(err) {
+		return nil, err
+	}
+
+	revisions := make(map[string]string, len(names))
+	for _, name := range names {
+		r, err := bundle.ReadBundleRevisionFromStore(ctx, store, txn, name)
+		if err != nil && !storage.IsNotFound(err) {
+			return nil, err
+		}
+		revisions[name] = r
+	}
+
+	return revisions, nil
+}
+
 func parseStringsToRefs(s []string) ([]ast.Ref, error) {
 	if len(s) == 0 {
 		return nil, nil
@@ -2983,6 +3509,7 @@ func newFunction(decl *Function, f topdown.BuiltinFunc) func(*Rego) {
 			Name:             decl.Name,
 			Decl:             decl.Decl,
 			Nondeterministic: decl.Nondeterministic,
+			OutputArgs:       decl.OutputArgs,
 		}
 		r.builtinFuncs[decl.Name] = &topdown.Builtin{
 			Decl: r.builtinDecls[decl.Name],
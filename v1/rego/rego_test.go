@@ -649,6 +649,83 @@ func TestRegoCustomBuiltinHalt(t *testing.T) {
 	}
 }
 
+func TestRegoCustomBuiltinOutputArgs(t *testing.T) {
+	funOpt := FunctionDyn(
+		&Function{
+			Name:       "split_pair",
+			Decl:       types.NewFunction(types.Args(types.S, types.S, types.S), nil),
+			OutputArgs: []int{1, 2},
+		},
+		func(_ BuiltinContext, terms []*ast.Term) (*ast.Term, error) {
+			s, ok := terms[0].Value.(ast.String)
+			if !ok {
+				return nil, nil
+			}
+			a, b, ok := strings.Cut(string(s), ":")
+			if !ok {
+				return nil, nil
+			}
+			return ast.ArrayTerm(ast.StringTerm(a), ast.StringTerm(b)), nil
+		},
+	)
+
+	tests := []struct {
+		note  string
+		query string
+		exp   string
+	}{
+		{
+			note:  "bind output args",
+			query: `split_pair("a:b", x, y)`,
+			exp:   `[{"x": "a", "y": "b"}]`,
+		},
+		{
+			note:  "reorder for safety",
+			query: `z := concat("", [x, y]); split_pair(s, x, y); s = "a:b"`,
+			exp:   `[{"s": "a:b", "x": "a", "y": "b", "z": "ab"}]`,
+		},
+		{
+			note:  "ground output args",
+			query: `split_pair("a:b", "a", y)`,
+			exp:   `[{"y": "b"}]`,
+		},
+		{
+			note:  "mismatched output args",
+			query: `split_pair("a:b", "b", y)`,
+			exp:   `[]`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			rs, err := New(Query(tc.query), funOpt).Eval(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			bindings := make([]Vars, 0, len(rs))
+			for _, r := range rs {
+				bindings = append(bindings, r.Bindings)
+			}
+
+			var exp []Vars
+			if err := util.UnmarshalJSON([]byte(tc.exp), &exp); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(exp, bindings) {
+				t.Fatalf("expected %v but got %v", exp, bindings)
+			}
+		})
+	}
+
+	// The input argument must still be safe.
+	_, err := New(Query(`split_pair(s, x, y)`), funOpt).Eval(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "var s is unsafe") {
+		t.Fatalf("expected safety error but got %v", err)
+	}
+}
+
 func TestRegoMetrics(t *testing.T) {
 	m := metrics.New()
 	r := New(Query("foo = 1"), Module("foo.rego", "package x"), Metrics(m))
@@ -1148,6 +1225,72 @@ func TestPrepareAndEvalNewInput(t *testing.T) {
 	}, "[[1]]")
 }
 
+func TestPrepareAndIter(t *testing.T) {
+	module := `
+	package test
+	xs := numbers.range(1, 5)
+	`
+
+	r := New(
+		Query("x := data.test.xs[_]"),
+		Module("", module),
+	)
+
+	pq, err := r.PrepareForEval(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	var xs []any
+	err = pq.Iter(context.Background(), func(result Result) error {
+		xs = append(xs, result.Bindings["x"])
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	exp := []any{json.Number("1"), json.Number("2"), json.Number("3"), json.Number("4"), json.Number("5")}
+	if !reflect.DeepEqual(xs, exp) {
+		t.Fatalf("Expected %v but got %v", exp, xs)
+	}
+
+	// Returning an error from the callback stops evaluation.
+	stop := errors.New("stop")
+	var n int
+	err = pq.Iter(context.Background(), func(Result) error {
+		n++
+		if n == 2 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) {
+		t.Fatalf("Expected stop error but got: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("Expected evaluation to stop after 2 results, got %d", n)
+	}
+
+	// Options are applied like with Eval.
+	pq, err = New(Query("x := input[_]")).PrepareForEval(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	n = 0
+	err = pq.Iter(context.Background(), func(Result) error {
+		n++
+		return nil
+	}, EvalInput([]int{1, 2, 3}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if n != 3 {
+		t.Fatalf("Expected 3 results, got %d", n)
+	}
+}
+
 func TestPrepareAndEvalNewMetrics(t *testing.T) {
 	module := `
 	package test
@@ -2996,6 +3139,196 @@ func TestNDBCacheMarshalUnmarshalJSON(t *testing.T) {
 	if !bytes.Equal(jOriginal, jOther) {
 		t.Fatalf("JSONified values of NDBCaches do not match; expected %s, got %s", string(jOriginal), string(jOther))
 	}
+
+	// Check that the cached calls can be looked up by their operands.
+	if _, ok := other.Get("time.now_ns", ast.NewArray()); !ok {
+		t.Fatalf("expected deserialized NDBCache to hold time.now_ns call, got %v", other)
+	}
+}
+
+func TestRecordAndReplayNDBuiltinCalls(t *testing.T) {
+	ctx := context.Background()
+	module := `package test
+
+p := {"now": time.now_ns(), "rand": rand.intn("x", 1000000)}`
+
+	rs, err := New(Query("data.test.p"), Module("test.rego", module), RecordNDBuiltinCalls(true)).Eval(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 1 || len(rs[0].NDBuiltinCalls) != 2 {
+		t.Fatalf("expected one result with two recorded built-in functions, got %+v", rs)
+	}
+
+	// The recorded calls survive the round trip through JSON, e.g. in a decision log.
+	bs, err := json.Marshal(rs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var logged Result
+	if err := json.Unmarshal(bs, &logged); err != nil {
+		t.Fatal(err)
+	}
+
+	pq, err := New(Query("data.test.p"), Module("test.rego", module)).PrepareForEval(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replayed, err := pq.Eval(ctx, EvalReplayNDBuiltinCalls(logged.NDBuiltinCalls), EvalRecordNDBuiltinCalls(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rs[0].Expressions[0].Value, replayed[0].Expressions[0].Value) {
+		t.Fatalf("expected replayed result %v, got %v", rs[0].Expressions[0].Value, replayed[0].Expressions[0].Value)
+	}
+	if exp, act := util.MustMarshalJSON(rs[0].NDBuiltinCalls), util.MustMarshalJSON(replayed[0].NDBuiltinCalls); !bytes.Equal(exp, act) {
+		t.Fatalf("expected replayed calls %v, got %v", rs[0].NDBuiltinCalls, replayed[0].NDBuiltinCalls)
+	}
+
+	// Calls that were not recorded are evaluated, without modifying the
+	// recorded calls.
+	partial := builtins.NDBCache{"time.now_ns": logged.NDBuiltinCalls["time.now_ns"]}
+	replayed, err = pq.Eval(ctx, EvalReplayNDBuiltinCalls(partial), EvalRecordNDBuiltinCalls(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(partial) != 1 || len(replayed[0].NDBuiltinCalls) != 2 {
+		t.Fatalf("expected recorded calls to be left unmodified, got %v and %v", partial, replayed[0].NDBuiltinCalls)
+	}
+
+	// Without recording, results hold no calls.
+	rs, err = pq.Eval(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs[0].NDBuiltinCalls != nil {
+		t.Fatalf("expected no recorded calls, got %v", rs[0].NDBuiltinCalls)
+	}
+}
+
+func TestEvalAllowNet(t *testing.T) {
+	ctx := context.Background()
+
+	pq, err := New(Query(`net.lookup_ip_addr("example.com")`), StrictBuiltinErrors(true)).PrepareForEval(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = pq.Eval(ctx, EvalAllowNet([]string{"example.org"}))
+	if err == nil || !strings.Contains(err.Error(), "unallowed host: example.com") {
+		t.Fatalf("expected unallowed host error, got %v", err)
+	}
+
+	_, err = New(Query(`net.lookup_ip_addr("example.com")`), StrictBuiltinErrors(true), AllowNet([]string{})).Eval(ctx)
+	if err == nil || !strings.Contains(err.Error(), "unallowed host: example.com") {
+		t.Fatalf("expected unallowed host error, got %v", err)
+	}
+}
+
+func TestEvalRequestMetadata(t *testing.T) {
+	ctx := context.Background()
+
+	module := `package test
+
+allow if runtime.request().caller == "billing"`
+
+	pq, err := New(Query("data.test.allow"), Module("test.rego", module)).PrepareForEval(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rs, err := pq.Eval(ctx, EvalRequestMetadata(map[string]string{"caller": "billing"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rs.Allowed() {
+		t.Fatalf("expected allowed, got %v", rs)
+	}
+
+	rs, err = pq.Eval(ctx, EvalRequestMetadata(map[string]string{"caller": "search"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 0 {
+		t.Fatalf("expected undefined result, got %v", rs)
+	}
+
+	rs, err = New(Query("data.test.allow"), Module("test.rego", module), RequestMetadata(map[string]string{"caller": "billing"})).Eval(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rs.Allowed() {
+		t.Fatalf("expected allowed, got %v", rs)
+	}
+}
+
+func TestEvalCheckpoint(t *testing.T) {
+	ctx := context.Background()
+
+	module := `package test
+
+allow if count([x | some x in numbers.range(1, 50); x > 25]) == 25`
+
+	pq, err := New(Query("data.test.allow"), Module("test.rego", module)).PrepareForEval(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var checkpoints int
+	rs, err := pq.Eval(ctx, EvalCheckpoint(10, func(context.Context, topdown.CheckpointStats) error {
+		checkpoints++
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rs.Allowed() || checkpoints == 0 {
+		t.Fatalf("expected allowed after checkpoints, got %v after %d checkpoints", rs, checkpoints)
+	}
+
+	errBudget := errors.New("budget exceeded")
+	_, err = New(Query("data.test.allow"), Module("test.rego", module), Checkpoint(10, func(context.Context, topdown.CheckpointStats) error {
+		return errBudget
+	})).Eval(ctx)
+	if !topdown.IsCancel(err) || !errors.Is(err, errBudget) {
+		t.Fatalf("expected cancellation wrapping budget error, got %v", err)
+	}
+}
+
+func TestEvalDataSnapshot(t *testing.T) {
+	ctx := context.Background()
+
+	module := `package test
+
+allow if data.roles[input.user] == "admin"`
+
+	pq, err := New(Query("x = data.roles[input.user]; y = data.test.allow"), Module("test.rego", module)).PrepareForEval(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot := map[string]any{"roles": map[string]any{"alice": "admin"}}
+	rs, err := pq.Eval(ctx, EvalInput(map[string]any{"user": "alice"}), EvalDataSnapshot(snapshot))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 0 {
+		t.Fatalf("expected rules to be disabled, got %v", rs)
+	}
+
+	pq, err = New(Query("x = data.roles[input.user]"), Module("test.rego", module)).PrepareForEval(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rs, err = pq.Eval(ctx, EvalInput(map[string]any{"user": "alice"}), EvalDataSnapshot(snapshot))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 1 || rs[0].Bindings["x"] != "admin" {
+		t.Fatalf("expected result from snapshot, got %v", rs)
+	}
 }
 
 func TestStrictBuiltinErrors(t *testing.T) {
@@ -3017,6 +3350,36 @@ func TestStrictBuiltinErrors(t *testing.T) {
 	}
 }
 
+func TestSortedIteration(t *testing.T) {
+	ctx := context.Background()
+	store := inmem.NewFromObject(map[string]any{
+		"x": map[string]any{"b": 1, "d": 2},
+	})
+
+	for _, tc := range []struct {
+		sorted bool
+		exp    []any
+	}{
+		{sorted: false, exp: []any{"b", "d", "a", "c"}},
+		{sorted: true, exp: []any{"a", "b", "c", "d"}},
+	} {
+		rs, err := New(
+			Query(`ks := [k | data.x[k]]`),
+			Module("a.rego", "package x.a\nv := 3"),
+			Module("c.rego", "package x.c\nv := 4"),
+			Store(store),
+			SortedIteration(tc.sorted),
+		).Eval(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if act := rs[0].Bindings["ks"]; !reflect.DeepEqual(act, tc.exp) {
+			t.Fatalf("sorted iteration %v: expected %v, got %v", tc.sorted, tc.exp, act)
+		}
+	}
+}
+
 func TestBuiltinErrorList(t *testing.T) {
 	var buf []topdown.Error
 
@@ -3070,6 +3433,79 @@ func TestTimeSeedingOptions(t *testing.T) {
 
 }
 
+func TestRandSeed(t *testing.T) {
+	ctx := context.Background()
+	query := `x := rand.intn("a", 1000000); y := rand.intn("b", 1000000); z := uuid.rfc4122("c")`
+
+	rs, err := New(Query(query), RandSeed(42)).Eval(ctx)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(rs) != 1 || rs[0].RandSeed == nil || *rs[0].RandSeed != 42 {
+		t.Fatalf("expected result with seed 42 but got %v", rs)
+	}
+
+	// Check that the same seed produces the same values for prepared queries.
+	pq, err := New(Query(query)).PrepareForEval(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rs2, err := pq.Eval(ctx, EvalRandSeed(42))
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(rs[0].Bindings, rs2[0].Bindings) {
+		t.Fatalf("expected %v but got %v", rs[0].Bindings, rs2[0].Bindings)
+	}
+
+	// Check that a different seed produces different values.
+	rs3, err := pq.Eval(ctx, EvalRandSeed(43))
+	if err != nil {
+		t.Fatal(err)
+	} else if reflect.DeepEqual(rs[0].Bindings, rs3[0].Bindings) {
+		t.Fatal("expected different values for different seeds")
+	}
+
+	// Check that the seed is not recorded without the option.
+	rs4, err := pq.Eval(ctx)
+	if err != nil {
+		t.Fatal(err)
+	} else if rs4[0].RandSeed != nil {
+		t.Fatalf("expected no seed but got %v", *rs4[0].RandSeed)
+	}
+}
+
+func TestEvalDecisionIDAndBundleRevisions(t *testing.T) {
+	ctx := context.Background()
+
+	store := inmem.New()
+	txn := storage.NewTransactionOrDie(ctx, store, storage.WriteParams)
+	if err := bundle.WriteManifestToStore(ctx, store, txn, "test", bundle.Manifest{Revision: "r1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Commit(ctx, txn); err != nil {
+		t.Fatal(err)
+	}
+
+	rs, err := New(Query("x := 1"), Store(store), RecordBundleRevisions(true)).Eval(ctx)
+	if err != nil {
+		t.Fatal(err)
+	} else if exp := map[string]string{"test": "r1"}; len(rs) != 1 || !reflect.DeepEqual(rs[0].BundleRevisions, exp) {
+		t.Fatalf("expected result with bundle revisions %v but got %v", exp, rs)
+	}
+
+	pq, err := New(Query("x := 1"), Store(store)).PrepareForEval(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rs, err = pq.Eval(ctx, EvalDecisionID("abc"))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(rs) != 1 || rs[0].DecisionID != "abc" || rs[0].BundleRevisions != nil {
+		t.Fatalf("expected result with decision ID and no bundle revisions but got %v", rs)
+	}
+}
+
 func int64ToJSONNumber(i int64) json.Number {
 	return json.Number(strconv.FormatInt(i, 10))
 }
@@ -3449,3 +3885,68 @@ result := test.module("policy.rego")
 		}
 	})
 }
+
+func TestMockBuiltinAndData(t *testing.T) {
+	module := `package test
+
+allow if {
+	resp := http.send({"method": "GET", "url": "https://example.com/users"})
+	resp.body.admin == input.user
+	time.now_ns() < data.limits.expires
+}`
+
+	r := New(
+		Query("data.test.allow"),
+		Module("test.rego", module),
+		Mock("http.send", func(_ BuiltinContext, _ []*ast.Term) (*ast.Term, error) {
+			return ast.MustParseTerm(`{"status_code": 200, "body": {"admin": "alice"}}`), nil
+		}),
+		Mock("time.now_ns", func(_ BuiltinContext, _ []*ast.Term) (*ast.Term, error) {
+			return ast.IntNumberTerm(10), nil
+		}),
+		MockData("data.limits", map[string]any{"expires": 20}),
+	)
+
+	pq, err := r.PrepareForEval(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	assertPreparedEvalQueryEval(t, pq, []EvalOption{
+		EvalInput(map[string]any{"user": "alice"}),
+	}, "[[true]]")
+
+	assertPreparedEvalQueryEval(t, pq, []EvalOption{
+		EvalInput(map[string]any{"user": "bob"}),
+	}, "[]")
+}
+
+func TestMockErrors(t *testing.T) {
+	tests := []struct {
+		note string
+		opt  func(*Rego)
+		exp  string
+	}{
+		{
+			note: "unknown builtin",
+			opt: Mock("foo.bar", func(_ BuiltinContext, _ []*ast.Term) (*ast.Term, error) {
+				return nil, nil
+			}),
+			exp: `mock: unknown built-in function "foo.bar"`,
+		},
+		{
+			note: "non-data path",
+			opt:  MockData("input.x", 1),
+			exp:  "mock data: path must refer to data: input.x",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			_, err := New(Query("true"), tc.opt).PrepareForEval(context.Background())
+			if err == nil || err.Error() != tc.exp {
+				t.Fatalf("expected error %q, got %v", tc.exp, err)
+			}
+		})
+	}
+}
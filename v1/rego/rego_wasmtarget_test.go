@@ -122,6 +122,37 @@ func TestPrepareAndEvalWithWasmTargetModulesOnCompiler(t *testing.T) {
 	}, "[[true]]")
 }
 
+func TestPrepareAndEvalWithWasmTargetReadASTValuesStore(t *testing.T) {
+	t.Parallel()
+
+	mod := `
+	package test
+	default p = false
+	p if {
+		input.x == data.x.p
+	}
+	`
+
+	ctx := context.Background()
+
+	pq, err := New(
+		Query("data.test.p"),
+		Target("wasm"),
+		Module("a.rego", mod),
+		Store(inmem.NewFromObjectWithOpts(map[string]any{
+			"x": map[string]any{"p": 1},
+		}, inmem.OptReturnASTValuesOnRead(true))),
+	).PrepareForEval(ctx)
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	assertPreparedEvalQueryEval(t, pq, []EvalOption{
+		EvalInput(map[string]int{"x": 1}),
+	}, "[[true]]")
+}
+
 func TestWasmTimeOfDay(t *testing.T) {
 	t.Parallel()
 
@@ -138,6 +169,34 @@ func TestWasmTimeOfDay(t *testing.T) {
 	}, "[[1615397269000000000]]")
 }
 
+func TestWasmMemoryLimit(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	pq, err := New(
+		Query("x := [y | some y in numbers.range(1, input.n)]"),
+		Target("wasm"),
+		WasmMemoryLimit(2*1024*1024),
+	).PrepareForEval(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := pq.Eval(ctx, EvalInput(map[string]any{"n": 1})); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = pq.Eval(ctx, EvalInput(map[string]any{"n": 1000000}))
+	if !IsWasmMemoryLimit(err) {
+		t.Fatalf("expected memory limit error, got %v", err)
+	}
+
+	_, err = pq.Eval(ctx, EvalInput(map[string]any{"n": 1000}), EvalWasmMemoryLimit(1024))
+	if !IsWasmMemoryLimit(err) {
+		t.Fatalf("expected memory limit error, got %v", err)
+	}
+}
+
 func TestEvalWithContextTimeout(t *testing.T) {
 	t.Parallel()
 	test.Skip(t)
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/topdown/builtins"
 )
 
 // ResultSet represents a collection of output from Rego evaluation. An empty
@@ -30,6 +31,25 @@ func (v Vars) WithoutWildcards() Vars {
 type Result struct {
 	Expressions []*ExpressionValue `json:"expressions"`
 	Bindings    Vars               `json:"bindings,omitempty"`
+
+	// NDBuiltinCalls holds the non-deterministic built-in function calls made
+	// until the result was produced, if recorded with RecordNDBuiltinCalls or
+	// EvalRecordNDBuiltinCalls. Pass it to ReplayNDBuiltinCalls or
+	// EvalReplayNDBuiltinCalls to reproduce the result.
+	NDBuiltinCalls builtins.NDBCache `json:"nd_builtin_calls,omitempty"`
+
+	// RandSeed holds the seed that made the randomization required by
+	// built-in functions deterministic, if set with RandSeed or EvalRandSeed.
+	RandSeed *int64 `json:"rand_seed,omitempty"`
+
+	// DecisionID holds the ID of the decision made by the evaluation, if set
+	// with EvalDecisionID, to correlate the result with decision logs.
+	DecisionID string `json:"decision_id,omitempty"`
+
+	// BundleRevisions holds the revisions of the bundles activated in the
+	// store the result was evaluated against by name, if recorded with
+	// RecordBundleRevisions or EvalRecordBundleRevisions.
+	BundleRevisions map[string]string `json:"bundle_revisions,omitempty"`
 }
 
 func newResult() Result {
@@ -36,3 +36,10 @@ type stop struct{}
 func (stop) Error() string {
 	return "<stop>"
 }
+
+// IsStop returns true if err indicates that the REPL session should end, e.g.,
+// because the exit command was entered.
+func IsStop(err error) bool {
+	_, ok := err.(stop)
+	return ok
+}
@@ -68,6 +68,7 @@ type REPL struct {
 	unknowns          []*ast.Term
 	bufferDisabled    bool
 	undefinedDisabled bool
+	fsDisabled        bool
 	errLimit          int
 	prettyLimit       int
 	report            [][2]string
@@ -164,7 +165,7 @@ func (r *REPL) initModule(ctx context.Context) error {
 
 func (r *REPL) WithStderrWriter(w io.Writer) *REPL {
 	r.stderr = w
-	return nil
+	return r
 }
 
 // Loop will run until the user enters "exit", Ctrl+C, Ctrl+D, or an unexpected error occurs.
@@ -348,6 +349,19 @@ func (r *REPL) DisableUndefinedOutput(yes bool) *REPL {
 	return r
 }
 
+// DisableFileSystemAccess causes the REPL to reject commands that access the
+// file system, e.g., dumping the data to a file.
+func (r *REPL) DisableFileSystemAccess(yes bool) *REPL {
+	r.fsDisabled = yes
+	return r
+}
+
+// Prompt returns the prompt for the next line: the initial prompt, or the
+// buffer prompt if a multi-line statement is being buffered.
+func (r *REPL) Prompt() string {
+	return r.getPrompt()
+}
+
 // WithRuntime sets the runtime data to provide to the evaluation engine.
 func (r *REPL) WithRuntime(term *ast.Term) *REPL {
 	r.runtime = term
@@ -445,6 +459,9 @@ func (r *REPL) cmdDumpOutput(ctx context.Context) error {
 }
 
 func (r *REPL) cmdDumpPath(ctx context.Context, filename string) error {
+	if r.fsDisabled {
+		return newBadArgsErr("dump to file is disabled")
+	}
 	f, err := os.Create(filename)
 	if err != nil {
 		return err
@@ -1426,7 +1443,7 @@ func newCommand(line string) *command {
 }
 
 func dumpStorage(ctx context.Context, store storage.Store, txn storage.Transaction, w io.Writer) error {
-	data, err := store.Read(ctx, txn, storage.Path{})
+	data, err := storage.ReadJSON(ctx, store, txn, storage.Path{})
 	if err != nil {
 		return err
 	}
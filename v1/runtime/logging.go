@@ -5,37 +5,19 @@
 package runtime
 
 import (
+	"bufio"
 	"bytes"
 	"compress/gzip"
 	"io"
+	"net"
 	"net/http"
 	"strings"
 	"sync/atomic"
 	"time"
 
 	"github.com/open-policy-agent/opa/v1/logging"
-	"github.com/open-policy-agent/opa/v1/topdown/print"
 )
 
-type loggingPrintHook struct {
-	logger logging.Logger
-}
-
-func (h loggingPrintHook) Print(pctx print.Context, msg string) error {
-	// NOTE(tsandall): if the request context is not present then do not panic,
-	// just log the print message without the additional context.
-	var fields map[string]any
-	rctx, ok := logging.FromContext(pctx.Context)
-	if ok {
-		fields = rctx.Fields()
-	} else {
-		fields = make(map[string]any, 1)
-	}
-	fields["line"] = pctx.Location.String()
-	h.logger.WithFields(fields).Info(msg)
-	return nil
-}
-
 // LoggingHandler returns an http.Handler that will print log messages
 // containing the request information as well as response status and latency.
 type LoggingHandler struct {
@@ -262,6 +244,18 @@ func (r *recorder) Flush() {
 	}
 }
 
+var _ http.Hijacker = (*recorder)(nil)
+
+// Hijack lets the handler take over the connection, e.g., to upgrade it to a
+// WebSocket connection. The response is then logged as switching protocols.
+func (r *recorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(r.inner).Hijack()
+	if err == nil {
+		r.statusCode = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
 func readBody(r io.ReadCloser) ([]byte, io.ReadCloser, error) {
 	if r == http.NoBody {
 		return nil, r, nil
@@ -226,7 +226,7 @@ func TestRequestLogging(t *testing.T) {
 		{ // the data handler on GET will compress the response if response is above server.encoding.gzip.min_length in size
 			path:             "/v1/data",
 			acceptEncoding:   "gzip",
-			expected:         "{\"result\":{}}",
+			expected:         "\"result\":{}}",
 			expectedEncoding: "gzip",
 			contentEncoding:  "",
 			requestBody:      nil,
@@ -234,7 +234,7 @@ func TestRequestLogging(t *testing.T) {
 		{ // the data handler on POST can compress the response if response is above server.encoding.gzip.min_length in size
 			path:             "/v1/data",
 			acceptEncoding:   "gzip",
-			expected:         "{\"result\":{}}",
+			expected:         "\"result\":{}}",
 			expectedEncoding: "gzip",
 			contentEncoding:  "",
 			requestBody:      &dataEndpointBody,
@@ -242,7 +242,7 @@ func TestRequestLogging(t *testing.T) {
 		{ // the data handler on POST can consume compressed request
 			path:             "/v1/data",
 			acceptEncoding:   "gzip",
-			expected:         "{\"result\":{}}",
+			expected:         "\"result\":{}}",
 			expectedEncoding: "gzip",
 			contentEncoding:  "gzip",
 			requestBody:      &dataEndpointCompressedBody,
@@ -250,7 +250,7 @@ func TestRequestLogging(t *testing.T) {
 		{ // the compile handler will compress the response if response is above server.encoding.gzip.min_length in size
 			path:             "/v1/compile",
 			acceptEncoding:   "gzip",
-			expected:         "{\"result\":{}}",
+			expected:         "\"result\":{}}",
 			expectedEncoding: "gzip",
 			contentEncoding:  "",
 			requestBody:      &compileEndpointBody,
@@ -258,7 +258,7 @@ func TestRequestLogging(t *testing.T) {
 		{ // the compile handler can consume compressed request
 			path:             "/v1/compile",
 			acceptEncoding:   "gzip",
-			expected:         "{\"result\":{}}",
+			expected:         "\"result\":{}}",
 			expectedEncoding: "gzip",
 			contentEncoding:  "gzip",
 			requestBody:      &compileEndpointCompressedBody,
@@ -266,7 +266,7 @@ func TestRequestLogging(t *testing.T) {
 		{ // the handlers return plain data
 			path:             "/v1/data",
 			acceptEncoding:   "*/*",
-			expected:         "{\"result\":{}}",
+			expected:         "\"result\":{}}",
 			expectedEncoding: "",
 			contentEncoding:  "",
 			requestBody:      nil,
@@ -54,9 +54,12 @@ import (
 	metrics_config "github.com/open-policy-agent/opa/v1/plugins/server/metrics"
 	"github.com/open-policy-agent/opa/v1/repl"
 	"github.com/open-policy-agent/opa/v1/server"
+	"github.com/open-policy-agent/opa/v1/server/identifier"
 	"github.com/open-policy-agent/opa/v1/storage"
 	"github.com/open-policy-agent/opa/v1/storage/disk"
 	"github.com/open-policy-agent/opa/v1/storage/inmem"
+	"github.com/open-policy-agent/opa/v1/topdown"
+	"github.com/open-policy-agent/opa/v1/topdown/print"
 	"github.com/open-policy-agent/opa/v1/tracing"
 	"github.com/open-policy-agent/opa/v1/util"
 	"github.com/open-policy-agent/opa/v1/version"
@@ -65,6 +68,9 @@ import (
 var (
 	registeredPlugins    map[string]plugins.Factory
 	registeredPluginsMux sync.Mutex
+
+	registeredAuthenticators    map[string]identifier.Authenticator
+	registeredAuthenticatorsMux sync.Mutex
 )
 
 const (
@@ -84,6 +90,16 @@ func RegisterPlugin(name string, factory plugins.Factory) {
 	registeredPlugins[name] = factory
 }
 
+// RegisterAuthenticator registers an authenticator with the runtime package.
+// The authenticator is used by the server if the authentication scheme is
+// server.AuthenticationPlugin and it is selected by name, see
+// Params.Authenticator. This function is idempotent.
+func RegisterAuthenticator(name string, authenticator identifier.Authenticator) {
+	registeredAuthenticatorsMux.Lock()
+	defer registeredAuthenticatorsMux.Unlock()
+	registeredAuthenticators[name] = authenticator
+}
+
 // Params stores the configuration for an OPA instance.
 type Params struct {
 	// Globally unique identifier for this OPA instance. If an ID is not specified,
@@ -104,6 +120,10 @@ type Params struct {
 	// Authentication is the type of authentication scheme to use.
 	Authentication server.AuthenticationScheme
 
+	// Authenticator is the name of the registered authenticator to use if the
+	// authentication scheme is server.AuthenticationPlugin.
+	Authenticator string
+
 	// Authorization is the type of authorization scheme to use.
 	Authorization server.AuthorizationScheme
 
@@ -161,6 +181,16 @@ type Params struct {
 	// PprofEnabled flag controls whether pprof endpoints are enabled
 	PprofEnabled bool
 
+	// REPLEnabled flag controls whether the REPL API is enabled. The REPL API
+	// hosts read-only REPL sessions over WebSocket connections.
+	REPLEnabled bool
+
+	// REPLMaxSessions sets the maximum number of concurrent REPL API sessions,
+	// and REPLIdleTimeout the time after which sessions without input are
+	// closed. Zero selects the defaults, negative values disable the limits.
+	REPLMaxSessions int
+	REPLIdleTimeout time.Duration
+
 	// DecisionIDFactory generates decision IDs to include in API responses
 	// sent by the server (in response to Data API queries.)
 	DecisionIDFactory func() string
@@ -197,6 +227,17 @@ type Params struct {
 	// ShutdownWaitPeriod is the time (in seconds) to wait before initiating shutdown.
 	ShutdownWaitPeriod int
 
+	// QueryCacheSize is the maximum number of compiled queries, e.g., of the
+	// ad-hoc query API, that are cached until policies change. Zero disables
+	// the cache.
+	QueryCacheSize int
+
+	// SharedVirtualCacheSize is the maximum number of values of virtual
+	// documents that only depend on data, which the data APIs share across
+	// evaluations until policies or data change. Zero disables the cache,
+	// negative values do not limit the number of values.
+	SharedVirtualCacheSize int
+
 	// EnableVersionCheck flag controls whether OPA will report its version to an external service.
 	// If this flag is true, OPA will report its version to the external service
 	EnableVersionCheck bool
@@ -231,6 +272,11 @@ type Params struct {
 	// It can also be enabled via config, and this runtime field takes precedence.
 	DiskStorage *disk.Options
 
+	// PersistenceKeyProvider, if set, provides the key that encrypts the bundles
+	// persisted to disk. If it is not set, the key of the persistence_encryption
+	// config is used, or, without one, the key of the disk store, if encrypted.
+	PersistenceKeyProvider storage.KeyProvider
+
 	// StoreBuilder allows passing a storage backend builder
 	StoreBuilder func(_ context.Context, _ logging.Logger, _ prometheus_sdk.Registerer, config []byte, id string) (storage.Store, error)
 
@@ -270,6 +316,14 @@ type Params struct {
 	// NDBCacheEnabled allows enabling the non-deterministic builtin cache globally.
 	NDBCacheEnabled bool
 
+	// TenantsEnabled enables multi-tenancy in the server: tenants with isolated
+	// stores and compilers can be created and evicted through the Tenant API.
+	TenantsEnabled bool
+
+	// MaxTenants limits the number of tenants when TenantsEnabled is set. Zero
+	// means no limit.
+	MaxTenants int
+
 	Brand string
 }
 
@@ -293,6 +347,7 @@ type LoggingConfig struct {
 	Level           string
 	Format          string
 	TimestampFormat string
+	PrintLevels     bool // log outputs of print calls at the level set by their first argument, e.g., "warn:"
 }
 
 // NewParams returns a new Params object.
@@ -322,6 +377,7 @@ type Runtime struct {
 
 	logger            logging.Logger
 	server            *server.Server
+	authenticator     identifier.Authenticator
 	metrics           *prometheus.Provider
 	reporter          report.Reporter
 	traceExporter     *otlptrace.Exporter
@@ -374,6 +430,16 @@ func NewRuntime(ctx context.Context, params Params) (*Runtime, error) {
 		return nil, err
 	}
 
+	var authenticator identifier.Authenticator
+	if params.Authentication == server.AuthenticationPlugin {
+		registeredAuthenticatorsMux.Lock()
+		authenticator = registeredAuthenticators[params.Authenticator]
+		registeredAuthenticatorsMux.Unlock()
+		if authenticator == nil {
+			return nil, fmt.Errorf("authenticator %q not registered", params.Authenticator)
+		}
+	}
+
 	var filePaths []string
 	urlPathCount := 0
 	for _, path := range params.Paths {
@@ -459,15 +525,24 @@ func NewRuntime(ctx context.Context, params Params) (*Runtime, error) {
 			inmem.OptReturnASTValuesOnRead(params.ReadAstValuesFromStore))
 	}
 
+	// The key configured with persistence_encryption takes precedence over the
+	// key of the disk store, and is set by the plugin manager.
+	persistenceKeyProvider := params.PersistenceKeyProvider
+	if persistenceKeyProvider == nil && params.DiskStorage != nil && !hasPersistenceEncryption(config, params.ID) {
+		persistenceKeyProvider = params.DiskStorage.KeyProvider
+	}
+
 	traceExporter, tracerProvider, _, err := internal_tracing.Init(ctx, config, params.ID)
 	if err != nil {
 		return nil, fmt.Errorf("config error: %w", err)
 	}
+	printHook := print.NewLoggingHook(logger, print.WithLevels(params.Logging.PrintLevels))
 	if tracerProvider != nil {
 		params.DistributedTracingOpts = tracing.NewOptions(
 			otelhttp.WithTracerProvider(tracerProvider),
 			otelhttp.WithPropagators(propagation.TraceContext{}),
 		)
+		printHook = print.Multi(printHook, print.NewSpanEventHook())
 	}
 
 	manager, err := plugins.New(config,
@@ -481,10 +556,12 @@ func NewRuntime(ctx context.Context, params Params) (*Runtime, error) {
 		plugins.ConsoleLogger(consoleLogger),
 		plugins.Logger(logger),
 		plugins.EnablePrintStatements(logger.GetLevel() >= logging.Info),
-		plugins.PrintHook(loggingPrintHook{logger: logger}),
+		plugins.PrintHook(printHook),
+		plugins.WithQueryCacheSize(params.QueryCacheSize),
 		plugins.WithRouter(params.Router),
 		plugins.WithPrometheusRegister(metrics),
 		plugins.WithTracerProvider(tracerProvider),
+		plugins.WithPersistenceKeyProvider(persistenceKeyProvider),
 		plugins.WithEnableTelemetry(params.EnableVersionCheck),
 		plugins.WithParserOptions(params.parserOptions()),
 		plugins.WithDistributedTracingOpts(params.DistributedTracingOpts),
@@ -535,6 +612,7 @@ func NewRuntime(ctx context.Context, params Params) (*Runtime, error) {
 		serverStatus:      ServerNotStarted,
 		traceExporter:     traceExporter,
 		loadedPathsResult: loaded,
+		authenticator:     authenticator,
 	}
 
 	return rt, nil
@@ -573,6 +651,15 @@ func (rt *Runtime) ServerStatus() ServerStatus {
 	return rt.serverStatus
 }
 
+// sharedVirtualCache returns the cache of values of virtual documents shared
+// by the evaluations of the server, or nil if it is disabled.
+func (rt *Runtime) sharedVirtualCache() *topdown.SharedVirtualCache {
+	if rt.Params.SharedVirtualCacheSize == 0 {
+		return nil
+	}
+	return topdown.NewSharedVirtualCache(rt.Params.SharedVirtualCacheSize)
+}
+
 // StartServer starts the runtime in server mode. This function will block the
 // calling goroutine.
 func (rt *Runtime) StartServer(ctx context.Context) {
@@ -642,12 +729,16 @@ func (rt *Runtime) Serve(ctx context.Context) error {
 		WithManager(rt.Manager).
 		WithCompilerErrorLimit(rt.Params.ErrorLimit).
 		WithPprofEnabled(rt.Params.PprofEnabled).
+		WithREPLEnabled(rt.Params.REPLEnabled).
+		WithREPLLimits(rt.Params.REPLMaxSessions, rt.Params.REPLIdleTimeout).
+		WithSharedVirtualCache(rt.sharedVirtualCache()).
 		WithAddresses(*rt.Params.Addrs).
 		WithH2CEnabled(rt.Params.H2CEnabled).
 		// always use the initial values for the certificate and ca pool, reloading behavior is configured below
 		WithCertificate(rt.Params.Certificate).
 		WithCertPool(rt.Params.CertPool).
 		WithAuthentication(rt.Params.Authentication).
+		WithAuthenticator(rt.authenticator).
 		WithAuthorization(rt.Params.Authorization).
 		WithDecisionIDFactory(rt.decisionIDFactory).
 		WithDecisionLoggerWithErr(rt.decisionLogger).
@@ -668,6 +759,12 @@ func (rt *Runtime) Serve(ctx context.Context) error {
 		rt.server = rt.server.WithDiagnosticAddresses(*rt.Params.DiagnosticAddrs)
 	}
 
+	if rt.Params.TenantsEnabled {
+		tenants := server.NewTenantManager().WithMaxTenants(rt.Params.MaxTenants)
+		rt.server = rt.server.WithTenantManager(tenants)
+		defer tenants.Stop(ctx)
+	}
+
 	if rt.Params.UnixSocketPerm != nil {
 		rt.server = rt.server.WithUnixSocketPermission(rt.Params.UnixSocketPerm)
 	}
@@ -882,14 +979,14 @@ func (rt *Runtime) checkOPAUpdateLoopDurations(ctx context.Context, done chan st
 	}
 }
 
+// decisionIDFactory returns a decision ID for every decision, even when
+// decision logging is disabled, so that clients can correlate responses with
+// logs and bundle revisions.
 func (rt *Runtime) decisionIDFactory() string {
 	if rt.Params.DecisionIDFactory != nil {
 		return rt.Params.DecisionIDFactory()
 	}
-	if logs.Lookup(rt.Manager) != nil {
-		return generateDecisionID()
-	}
-	return ""
+	return generateDecisionID()
 }
 
 func (rt *Runtime) decisionLogger(ctx context.Context, event *server.Info) error {
@@ -1075,4 +1172,12 @@ func verifyAuthorizationPolicySchema(m *plugins.Manager) error {
 
 func init() {
 	registeredPlugins = make(map[string]plugins.Factory)
+	registeredAuthenticators = make(map[string]identifier.Authenticator)
+}
+
+// hasPersistenceEncryption returns true if the config sets the key that
+// encrypts the bundles persisted to disk.
+func hasPersistenceEncryption(config []byte, id string) bool {
+	c, err := opa_config.ParseConfig(config, id)
+	return err == nil && c.PersistenceEncryption != nil
 }
@@ -45,6 +45,7 @@ import (
 	testLog "github.com/open-policy-agent/opa/v1/logging/test"
 	sdktest "github.com/open-policy-agent/opa/v1/sdk/test"
 	"github.com/open-policy-agent/opa/v1/server"
+	"github.com/open-policy-agent/opa/v1/server/identifier"
 	"github.com/open-policy-agent/opa/v1/storage"
 	topdown_cache "github.com/open-policy-agent/opa/v1/topdown/cache"
 	"github.com/open-policy-agent/opa/v1/util"
@@ -924,6 +925,30 @@ func TestCheckAuthIneffective(t *testing.T) {
 
 }
 
+func TestRegisterAuthenticator(t *testing.T) {
+	RegisterAuthenticator("test", identifier.AuthenticatorFunc(func(*http.Request) (string, map[string]any, error) {
+		return "alice", nil, nil
+	}))
+
+	params := NewParams()
+	params.Authentication = server.AuthenticationPlugin
+	params.Authenticator = "test"
+
+	rt, err := NewRuntime(context.Background(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rt.authenticator == nil {
+		t.Fatal("expected authenticator to be set")
+	}
+
+	params.Authenticator = "missing"
+	_, err = NewRuntime(context.Background(), params)
+	if err == nil || err.Error() != `authenticator "missing" not registered` {
+		t.Fatalf("expected authenticator not registered error but got: %v", err)
+	}
+}
+
 func TestServerInitialized(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Millisecond)
 	defer cancel() // NOTE(sr): The timeout will have been reached by the time `done` is closed.
@@ -1653,6 +1678,58 @@ func TestRuntimeWithExplicitMetricConfiguration(t *testing.T) {
 	})
 }
 
+func TestRuntimePersistenceEncryption(t *testing.T) {
+	ctx := context.Background()
+
+	// base64 of 0123456789abcdef and fedcba9876543210
+	disk := `"storage": {"disk": {"directory": %q, "auto_create": true, "encryption": {"key": "MDEyMzQ1Njc4OWFiY2RlZg=="}}}`
+	persistence := `"persistence_encryption": {"key": "ZmVkY2JhOTg3NjU0MzIxMA=="}`
+
+	tests := []struct {
+		note   string
+		config string
+		exp    string
+	}{
+		{
+			note:   "disk store key",
+			config: "{" + disk + "}",
+			exp:    "0123456789abcdef",
+		},
+		{
+			note:   "persistence encryption key",
+			config: "{" + disk + ", " + persistence + "}",
+			exp:    "fedcba9876543210",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			dir := t.TempDir()
+			cfg := filepath.Join(dir, "config.json")
+			if err := os.WriteFile(cfg, fmt.Appendf(nil, tc.config, filepath.Join(dir, "store")), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			params := NewParams()
+			params.ConfigFile = cfg
+
+			rt, err := NewRuntime(ctx, params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rt.Store.(interface{ Close(context.Context) error }).Close(ctx)
+
+			key, err := storage.EncryptionKey(ctx, rt.Manager.PersistenceKeyProvider())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(key) != tc.exp {
+				t.Fatalf("expected key %q, got %q", tc.exp, key)
+			}
+		})
+	}
+}
+
 func TestRuntimeWithExplicitBadMetricConfiguration(t *testing.T) {
 	fs := map[string]string{
 		"/config.yaml": `{"server": {"metrics": {"prom": {"http_request_duration_seconds": {"buckets": "would-not-work"}}}}}`,
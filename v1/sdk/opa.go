@@ -12,7 +12,9 @@ import (
 	"crypto/rand"
 	"fmt"
 	"maps"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/open-policy-agent/opa/internal/ref"
@@ -52,6 +54,10 @@ type OPA struct {
 	config      []byte
 	regoVersion ast.RegoVersion
 	managerOpts []func(*plugins.Manager)
+
+	sharedVirtualCache *topdown.SharedVirtualCache
+	commits            atomic.Uint64 // number of commits to the store, keys the shared virtual cache
+	countedStore       storage.Store // store whose commits are counted
 }
 
 type state struct {
@@ -78,6 +84,7 @@ func New(ctx context.Context, nopts Options) (*OPA, error) {
 
 	opts.ManagerOpts = append(opts.ManagerOpts, defaultOptions.ManagerOpts...)
 	opts.ManagerOpts = append(opts.ManagerOpts, nopts.ManagerOpts...)
+	opts.SharedVirtualCacheSize = nopts.SharedVirtualCacheSize
 
 	opts.Logger = cmp.Or(nopts.Logger, defaultOptions.Logger)
 	hs := make([]hooks.Hook, 0, opts.Hooks.Len()+nopts.Hooks.Len())
@@ -124,6 +131,9 @@ func New(ctx context.Context, nopts Options) (*OPA, error) {
 	opa.console = opts.ConsoleLogger
 	opa.plugins = opts.Plugins
 	opa.managerOpts = opts.ManagerOpts
+	if opts.SharedVirtualCacheSize != 0 {
+		opa.sharedVirtualCache = topdown.NewSharedVirtualCache(opts.SharedVirtualCacheSize)
+	}
 
 	opa.regoVersion = opts.regoVersion()
 
@@ -174,7 +184,7 @@ func (opa *OPA) configure(ctx context.Context, bs []byte, ready chan struct{}, b
 		plugins.ConsoleLogger(opa.console),
 		plugins.WithParserOptions(ast.ParserOptions{RegoVersion: opa.regoVersion}),
 		plugins.EnablePrintStatements(opa.logger.GetLevel() >= logging.Info),
-		plugins.PrintHook(loggingPrintHook{logger: opa.logger}),
+		plugins.PrintHook(print.NewLoggingHook(opa.logger)),
 		plugins.WithHooks(opa.hooks),
 	}
 	opts = append(opts, opa.managerOpts...)
@@ -196,6 +206,23 @@ func (opa *OPA) configure(ctx context.Context, bs []byte, ready chan struct{}, b
 		return err
 	}
 
+	// Every commit may change policies or data, so values of virtual documents
+	// cached by earlier decisions are not shared with later ones.
+	if opa.sharedVirtualCache != nil && store != opa.countedStore {
+		err := storage.Txn(ctx, store, storage.WriteParams, func(txn storage.Transaction) error {
+			_, err := store.Register(ctx, txn, storage.TriggerConfig{
+				OnCommit: func(context.Context, storage.Transaction, storage.TriggerEvent) {
+					opa.commits.Add(1)
+				},
+			})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		opa.countedStore = store
+	}
+
 	manager.RegisterCompilerTrigger(func(storage.Transaction) {
 		opa.mtx.Lock()
 		opa.state.queryCache.Clear()
@@ -333,6 +360,8 @@ func (opa *OPA) Decision(ctx context.Context, options DecisionOptions) (*Decisio
 				tracer:                      options.Tracer,
 				profiler:                    options.Profiler,
 				instrument:                  options.Instrument,
+				sharedVirtualCache:          opa.sharedVirtualCache,
+				sharedVirtualCacheKey:       strconv.FormatUint(opa.commits.Load(), 10),
 			})
 			if record.Error == nil {
 				record.Results = &result.Result
@@ -563,6 +592,8 @@ type evalArgs struct {
 	tracer                      topdown.QueryTracer
 	profiler                    topdown.QueryTracer
 	instrument                  bool
+	sharedVirtualCache          *topdown.SharedVirtualCache
+	sharedVirtualCacheKey       string
 }
 
 func evaluate(ctx context.Context, args evalArgs) (any, types.ProvenanceV1, ast.Value, map[string]server.BundleInfo, error) {
@@ -627,6 +658,7 @@ func evaluate(ctx context.Context, args evalArgs) (any, types.ProvenanceV1, ast.
 		rego.EvalMetrics(args.m),
 		rego.EvalQueryTracer(args.profiler),
 		rego.EvalInstrument(args.instrument),
+		rego.EvalSharedVirtualCache(args.sharedVirtualCache, args.sharedVirtualCacheKey),
 	)
 	if err != nil {
 		return nil, provenance, inputAST, bundles, err
@@ -748,12 +780,3 @@ func bundles(ctx context.Context, store storage.Store, txn storage.Transaction)
 	}
 	return bundles, nil
 }
-
-type loggingPrintHook struct {
-	logger logging.Logger
-}
-
-func (h loggingPrintHook) Print(pctx print.Context, msg string) error {
-	h.logger.WithFields(map[string]any{"line": pctx.Location.String()}).Info(msg)
-	return nil
-}
@@ -14,6 +14,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -38,6 +39,8 @@ import (
 	"github.com/open-policy-agent/opa/v1/sdk"
 	sdktest "github.com/open-policy-agent/opa/v1/sdk/test"
 	"github.com/open-policy-agent/opa/v1/server/types"
+	"github.com/open-policy-agent/opa/v1/storage"
+	"github.com/open-policy-agent/opa/v1/storage/inmem"
 	"github.com/open-policy-agent/opa/v1/topdown"
 	"github.com/open-policy-agent/opa/v1/topdown/builtins"
 	"github.com/open-policy-agent/opa/v1/topdown/lineage"
@@ -815,6 +818,68 @@ main = data.foo
 
 }
 
+func TestDecisionWithSharedVirtualCache(t *testing.T) {
+
+	ctx := context.Background()
+
+	server := sdktest.MustNewServer(
+		sdktest.MockBundle("/bundles/bundle.tar.gz", map[string]string{
+			"main.rego": `
+package system
+
+main := q
+
+q := data.y.x + 1
+`,
+		}),
+	)
+
+	defer server.Stop()
+
+	config := fmt.Sprintf(`{
+		"services": {
+			"test": {
+				"url": %q
+			}
+		},
+		"bundles": {
+			"test": {
+				"resource": "/bundles/bundle.tar.gz"
+			}
+		}
+	}`, server.URL())
+
+	store := inmem.New()
+
+	opa, err := sdk.New(ctx, sdk.Options{
+		Config:                 strings.NewReader(config),
+		Store:                  store,
+		SharedVirtualCacheSize: 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer opa.Stop(ctx)
+
+	for _, x := range []int{1, 2} {
+		// Values computed before data changes are not shared.
+		if err := storage.WriteOne(ctx, store, storage.AddOp, storage.MustParsePath("/y"), map[string]any{"x": x}); err != nil {
+			t.Fatal(err)
+		}
+
+		for range 2 {
+			result, err := opa.Decision(ctx, sdk.DecisionOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if exp := json.Number(strconv.Itoa(x + 1)); result.Result != exp {
+				t.Fatalf("expected %v but got %v", exp, result.Result)
+			}
+		}
+	}
+}
+
 func TestDecisionWithConfigurableID(t *testing.T) {
 	ctx := context.Background()
 
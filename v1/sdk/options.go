@@ -90,6 +90,12 @@ type Options struct {
 	// overriding them.
 	ManagerOpts []func(manager *plugins.Manager)
 
+	// SharedVirtualCacheSize enables sharing the values of virtual documents
+	// that only depend on data across decisions, until policies or data
+	// change, and sets the maximum number of shared values. Zero disables
+	// sharing, negative values do not limit the number of values.
+	SharedVirtualCacheSize int
+
 	config []byte
 	block  bool
 }
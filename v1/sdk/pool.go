@@ -0,0 +1,133 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/plugins"
+	"github.com/open-policy-agent/opa/v1/plugins/rest"
+)
+
+// PoolOptions contains parameters to setup a Pool.
+type PoolOptions struct {
+
+	// MaxCachedResponses sets the maximum number of responses, e.g., bundles,
+	// in the cache shared by the instances of the pool. By default, 256
+	// responses are cached.
+	MaxCachedResponses int
+
+	// MaxCachedBytes sets the maximum total size of the responses in the cache
+	// shared by the instances of the pool. Larger responses are not cached. By
+	// default, 256 MiB of responses are cached.
+	MaxCachedBytes int64
+
+	// RegoVersion sets the Rego version of the instances added to the pool
+	// without one.
+	RegoVersion ast.RegoVersion
+}
+
+// Pool manages several isolated OPA instances, e.g., one per tenant or policy
+// set, that share the bundles they download and the connections to the
+// services they download them from. Instances can be added and removed while
+// the pool is in use. Pool is safe for concurrent use.
+type Pool struct {
+	mtx         sync.Mutex
+	instances   map[string]*OPA // nil for instances being added
+	cache       *rest.SharedCache
+	regoVersion ast.RegoVersion
+}
+
+// NewPool returns a new, empty Pool.
+func NewPool(opts PoolOptions) *Pool {
+	return &Pool{
+		instances:   map[string]*OPA{},
+		cache:       rest.NewSharedCache(opts.MaxCachedResponses, opts.MaxCachedBytes),
+		regoVersion: opts.RegoVersion,
+	}
+}
+
+// Add creates a new OPA instance with opts and adds it to the pool under
+// name. Like New, Add blocks until the instance is ready unless opts.Ready is
+// set. An error is returned if the pool already has an instance named name.
+func (p *Pool) Add(ctx context.Context, name string, opts Options) (*OPA, error) {
+	p.mtx.Lock()
+	if _, ok := p.instances[name]; ok {
+		p.mtx.Unlock()
+		return nil, fmt.Errorf("instance %q already exists", name)
+	}
+	p.instances[name] = nil
+	p.mtx.Unlock()
+
+	if opts.RegoVersion == ast.RegoUndefined {
+		opts.RegoVersion = p.regoVersion
+	}
+	opts.ManagerOpts = append(slices.Clip(opts.ManagerOpts), plugins.WithSharedCache(p.cache))
+
+	opa, err := New(ctx, opts)
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if err != nil {
+		delete(p.instances, name)
+		return nil, err
+	}
+
+	p.instances[name] = opa
+	return opa, nil
+}
+
+// Get returns the instance named name, or nil if the pool has none.
+func (p *Pool) Get(name string) *OPA {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return p.instances[name]
+}
+
+// Names returns the sorted names of the instances in the pool.
+func (p *Pool) Names() []string {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	names := make([]string, 0, len(p.instances))
+	for name, opa := range p.instances {
+		if opa != nil {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+// Remove stops the instance named name and removes it from the pool. It
+// returns false if the pool has no such instance.
+func (p *Pool) Remove(ctx context.Context, name string) bool {
+	p.mtx.Lock()
+	opa := p.instances[name]
+	if opa != nil {
+		delete(p.instances, name)
+	}
+	p.mtx.Unlock()
+
+	if opa == nil {
+		return false
+	}
+
+	opa.Stop(ctx)
+	return true
+}
+
+// Stop stops all instances and removes them from the pool.
+func (p *Pool) Stop(ctx context.Context) {
+	for _, name := range p.Names() {
+		p.Remove(ctx, name)
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package sdk_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/bundle"
+	"github.com/open-policy-agent/opa/v1/sdk"
+)
+
+func TestPool(t *testing.T) {
+
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	if err := bundle.NewWriter(&buf).Write(bundle.Bundle{
+		Data: map[string]any{},
+		Modules: []bundle.ModuleFile{
+			{
+				URL:    "/main.rego",
+				Parsed: ast.MustParseModule("package system\n\nmain := input.tenant"),
+				Raw:    []byte("package system\n\nmain := input.tenant"),
+			},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var mtx sync.Mutex
+	var downloads int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", "v1")
+		if r.Header.Get("If-None-Match") == "v1" {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		mtx.Lock()
+		downloads++
+		mtx.Unlock()
+		_, _ = w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	config := fmt.Sprintf(`{
+		"services": {
+			"test": {
+				"url": %q
+			}
+		},
+		"bundles": {
+			"test": {
+				"resource": "/bundles/bundle.tar.gz"
+			}
+		}
+	}`, server.URL)
+
+	pool := sdk.NewPool(sdk.PoolOptions{})
+	defer pool.Stop(ctx)
+
+	for _, name := range []string{"a", "b"} {
+		if _, err := pool.Add(ctx, name, sdk.Options{Config: strings.NewReader(config)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := pool.Add(ctx, "a", sdk.Options{Config: strings.NewReader(config)}); err == nil {
+		t.Fatal("expected error adding instance twice")
+	}
+
+	if exp, act := []string{"a", "b"}, pool.Names(); !reflect.DeepEqual(exp, act) {
+		t.Fatalf("expected instances %v, got %v", exp, act)
+	}
+
+	for _, name := range pool.Names() {
+		result, err := pool.Get(name).Decision(ctx, sdk.DecisionOptions{Input: map[string]any{"tenant": name}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Result != name {
+			t.Fatalf("expected %v, got %v", name, result.Result)
+		}
+	}
+
+	mtx.Lock()
+	if downloads != 1 {
+		t.Fatalf("expected bundle to be downloaded once, got %v", downloads)
+	}
+	mtx.Unlock()
+
+	if !pool.Remove(ctx, "a") {
+		t.Fatal("expected instance to be removed")
+	}
+	if pool.Remove(ctx, "a") {
+		t.Fatal("expected instance to be removed once")
+	}
+	if pool.Get("a") != nil {
+		t.Fatal("expected no instance after removal")
+	}
+	if exp, act := []string{"b"}, pool.Names(); !reflect.DeepEqual(exp, act) {
+		t.Fatalf("expected instances %v, got %v", exp, act)
+	}
+}
@@ -218,6 +218,11 @@ func makeInput(r *http.Request, extraPaths []func(string, []any) bool) (*http.Re
 		input["identity"] = identity
 	}
 
+	identityClaims, ok := identifier.IdentityClaims(r)
+	if ok {
+		input["identity_claims"] = identityClaims
+	}
+
 	clientCertificates, ok := identifier.ClientCertificates(r)
 	if ok {
 		input["client_certificates"] = clientCertificates
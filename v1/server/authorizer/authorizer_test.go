@@ -308,6 +308,7 @@ func TestMakeInput(t *testing.T) {
 	req.URL.RawQuery = query.Encode()
 
 	req = identifier.SetIdentity(req, "bob")
+	req = identifier.SetIdentityClaims(req, map[string]any{"sub": "bob", "groups": []any{"admins"}})
 
 	_, result, err := makeInput(req, nil)
 	if err != nil {
@@ -319,6 +320,7 @@ func TestMakeInput(t *testing.T) {
 		  "path": ["foo","bar"],
 		  "method": "GET",
 		  "identity": "bob",
+		  "identity_claims": {"sub": "bob", "groups": ["admins"]},
 		  "headers": {
 			"X-Custom": ["foo", "bar"],
 			"X-Custom-2": ["baz"],
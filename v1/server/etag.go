@@ -0,0 +1,54 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// etag returns a strong entity tag for the JSON encoding of x.
+func etag(x any) (string, error) {
+	bs, err := json.Marshal(x)
+	if err != nil {
+		return "", err
+	}
+	return bytesETag(bs), nil
+}
+
+// bytesETag returns a strong entity tag for bs.
+func bytesETag(bs []byte) string {
+	sum := sha256.Sum256(bs)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// notModified sets the ETag header of the response to tag. If tag matches
+// the If-None-Match header of the request, 304 Not Modified is written and
+// true is returned.
+func notModified(w http.ResponseWriter, r *http.Request, tag string) bool {
+	w.Header().Set("ETag", tag)
+
+	if match := r.Header.Get("If-None-Match"); match != "" && etagMatches(match, tag) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+
+	return false
+}
+
+// etagMatches reports if tag is in the list of entity tags of an If-None-Match
+// header, using the weak comparison required for If-None-Match.
+func etagMatches(header, tag string) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
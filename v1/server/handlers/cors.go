@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/open-policy-agent/opa/v1/plugins/server/cors"
+)
+
+// CORSHandler returns a handler that adds the CORS headers allowing requests
+// from the origins of the config, and that answers their preflight requests.
+// Preflight requests carry no credentials, so the handler must run before
+// authentication. Requests from other origins are passed on without CORS
+// headers, which makes browsers reject their responses.
+func CORSHandler(handler http.Handler, config *cors.Config) http.Handler {
+	allowedMethods := strings.Join(config.AllowedMethods, ", ")
+	allowedHeaders := strings.Join(config.AllowedHeaders, ", ")
+	exposedHeaders := strings.Join(config.ExposedHeaders, ", ")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Add("Vary", "Origin")
+
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		if preflight {
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		if !config.AllowsOrigin(origin) {
+			if preflight {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		if config.AllowsAnyOrigin() {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			h.Set("Access-Control-Allow-Origin", origin)
+		}
+		if config.AllowCredentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if exposedHeaders != "" {
+				h.Set("Access-Control-Expose-Headers", exposedHeaders)
+			}
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		// The preflight request is allowed if its method and all of its headers
+		// are. Otherwise, the Access-Control-Allow-* headers are left out, and
+		// the browser rejects the actual request.
+		if allowedPreflight(config, r) {
+			h.Set("Access-Control-Allow-Methods", allowedMethods)
+			if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+				if allowedHeaders == "*" {
+					h.Set("Access-Control-Allow-Headers", requested)
+				} else {
+					h.Set("Access-Control-Allow-Headers", allowedHeaders)
+				}
+			}
+			if config.MaxAgeSeconds > 0 {
+				h.Set("Access-Control-Max-Age", strconv.Itoa(config.MaxAgeSeconds))
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func allowedPreflight(config *cors.Config, r *http.Request) bool {
+	if !config.AllowsMethod(r.Header.Get("Access-Control-Request-Method")) {
+		return false
+	}
+	for _, header := range strings.Split(r.Header.Get("Access-Control-Request-Headers"), ",") {
+		if header = strings.TrimSpace(header); header != "" && !config.AllowsHeader(header) {
+			return false
+		}
+	}
+	return true
+}
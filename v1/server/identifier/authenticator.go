@@ -0,0 +1,84 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package identifier
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/open-policy-agent/opa/v1/server/types"
+	"github.com/open-policy-agent/opa/v1/server/writer"
+)
+
+// Authenticator establishes the identity of the caller of a request, e.g., by
+// verifying an OIDC token or the SPIFFE ID of a client certificate.
+type Authenticator interface {
+	// Authenticate returns the identity of the caller and the claims made about
+	// it. Requests without credentials should yield an empty identity and a nil
+	// error, in which case they are passed on to the authorization policy
+	// unauthenticated. Requests with invalid credentials should yield an error,
+	// in which case they are rejected.
+	Authenticate(r *http.Request) (string, map[string]any, error)
+}
+
+// AuthenticatorFunc is an adapter to allow the use of ordinary functions as
+// Authenticators.
+type AuthenticatorFunc func(r *http.Request) (string, map[string]any, error)
+
+// Authenticate calls f(r).
+func (f AuthenticatorFunc) Authenticate(r *http.Request) (string, map[string]any, error) {
+	return f(r)
+}
+
+// AuthenticatorBased delegates authentication of requests to an Authenticator.
+type AuthenticatorBased struct {
+	inner         http.Handler
+	authenticator Authenticator
+}
+
+// NewAuthenticatorBased returns a new AuthenticatorBased object.
+func NewAuthenticatorBased(inner http.Handler, authenticator Authenticator) *AuthenticatorBased {
+	return &AuthenticatorBased{
+		inner:         inner,
+		authenticator: authenticator,
+	}
+}
+
+func (h *AuthenticatorBased) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Client certificates are made available to the authenticator, e.g., to
+	// extract SPIFFE IDs from, as well as to the authorization policy.
+	if tls := r.TLS; tls != nil && len(tls.PeerCertificates) > 0 {
+		r = SetClientCertificates(r, tls.PeerCertificates)
+	}
+
+	id, claims, err := h.authenticator.Authenticate(r)
+	if err != nil {
+		writer.ErrorString(w, http.StatusUnauthorized, types.CodeUnauthorized, err)
+		return
+	}
+
+	if id != "" {
+		r = SetIdentity(r, id)
+	}
+
+	if claims != nil {
+		r = SetIdentityClaims(r, claims)
+	}
+
+	h.inner.ServeHTTP(w, r)
+}
+
+const identityClaims = identityKey("org.openpolicyagent/identity-claims")
+
+// IdentityClaims returns the claims made about the caller associated with ctx.
+func IdentityClaims(r *http.Request) (map[string]any, bool) {
+	v, ok := r.Context().Value(identityClaims).(map[string]any)
+	return v, ok
+}
+
+// SetIdentityClaims returns a new http.Request with the identity claims set to v.
+func SetIdentityClaims(r *http.Request, v map[string]any) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), identityClaims, v))
+}
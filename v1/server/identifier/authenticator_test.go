@@ -0,0 +1,99 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package identifier_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/server/identifier"
+)
+
+type claimsHandler struct {
+	identity       string
+	identityClaims map[string]any
+	claimsDefined  bool
+}
+
+func (h *claimsHandler) ServeHTTP(_ http.ResponseWriter, r *http.Request) {
+	h.identity, _ = identifier.Identity(r)
+	h.identityClaims, h.claimsDefined = identifier.IdentityClaims(r)
+}
+
+func TestAuthenticatorBased(t *testing.T) {
+	authenticator := identifier.AuthenticatorFunc(func(r *http.Request) (string, map[string]any, error) {
+		switch r.Header.Get("Authorization") {
+		case "":
+			return "", nil, nil
+		case "Bearer valid":
+			return "alice", map[string]any{"sub": "alice", "iss": "https://issuer.example.com"}, nil
+		default:
+			return "", nil, errors.New("invalid token")
+		}
+	})
+
+	tests := []struct {
+		note           string
+		authorization  string
+		status         int
+		identity       string
+		identityClaims map[string]any
+	}{
+		{
+			note:   "no credentials",
+			status: http.StatusOK,
+		},
+		{
+			note:           "valid credentials",
+			authorization:  "Bearer valid",
+			status:         http.StatusOK,
+			identity:       "alice",
+			identityClaims: map[string]any{"sub": "alice", "iss": "https://issuer.example.com"},
+		},
+		{
+			note:          "invalid credentials",
+			authorization: "Bearer invalid",
+			status:        http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			inner := &claimsHandler{}
+			handler := identifier.NewAuthenticatorBased(inner, authenticator)
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/data", nil)
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tc.status {
+				t.Fatalf("Expected status %d but got %d", tc.status, w.Code)
+			}
+
+			if tc.status == http.StatusUnauthorized {
+				if !strings.Contains(w.Body.String(), "invalid token") {
+					t.Fatalf("Expected error in response but got: %s", w.Body.String())
+				}
+				return
+			}
+
+			if inner.identity != tc.identity {
+				t.Fatalf("Expected identity %q but got %q", tc.identity, inner.identity)
+			}
+
+			if inner.claimsDefined != (tc.identityClaims != nil) || !reflect.DeepEqual(inner.identityClaims, tc.identityClaims) {
+				t.Fatalf("Expected identity claims %v but got %v", tc.identityClaims, inner.identityClaims)
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+// Package ratelimiter provides rate limiting handlers to the server.
+package ratelimiter
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/plugins/server/ratelimit"
+	"github.com/open-policy-agent/opa/v1/rego"
+	"github.com/open-policy-agent/opa/v1/server/identifier"
+	"github.com/open-policy-agent/opa/v1/server/types"
+	"github.com/open-policy-agent/opa/v1/server/writer"
+	"github.com/open-policy-agent/opa/v1/storage"
+	"github.com/open-policy-agent/opa/v1/util"
+)
+
+// maxBuckets is the maximum number of token buckets kept by a limiter. The
+// buckets of the least recently seen clients are dropped first, but only once
+// they have been refilled: dropping a bucket that is not full would reset the
+// limit of its client. Until then, the limiter keeps more buckets.
+const maxBuckets = 10000
+
+// Limiter enforces rate limits on requests per limit and client identity.
+// Requests exceeding their limit are rejected with 429 Too Many Requests and
+// a Retry-After header.
+type Limiter struct {
+	inner    http.Handler
+	config   *ratelimit.Config
+	compiler func() *ast.Compiler
+	store    storage.Store
+	runtime  *ast.Term
+	decision ast.Ref
+	now      func() time.Time
+
+	mtx     sync.Mutex
+	buckets map[bucketKey]*list.Element
+	lru     *list.List // buckets, most recently used first
+
+	pqMtx      sync.Mutex
+	pq         *rego.PreparedEvalQuery
+	pqCompiler *ast.Compiler // compiler pq was prepared with
+}
+
+// bucketKey identifies the token bucket of a request. Requests share a
+// bucket if their client identity is the same, and their limit comes from the
+// same source: the same configured path prefix, the default limit, or the
+// decision with the same key or, without a key, for the same URL path. Keying
+// buckets of prefix limits by the URL path instead would let clients escape
+// them by varying the paths they request.
+type bucketKey struct {
+	scope    string
+	identity string
+}
+
+type bucket struct {
+	key     bucketKey
+	limiter *rate.Limiter
+}
+
+// Runtime returns an argument that sets the runtime on the limiter.
+func Runtime(term *ast.Term) func(*Limiter) {
+	return func(l *Limiter) {
+		l.runtime = term
+	}
+}
+
+// NewLimiter returns a new Limiter enforcing the limits of config. The
+// compiler and store are used to evaluate the decision of config, if any.
+func NewLimiter(inner http.Handler, config *ratelimit.Config, compiler func() *ast.Compiler, store storage.Store, opts ...func(*Limiter)) (*Limiter, error) {
+	l := &Limiter{
+		inner:    inner,
+		config:   config,
+		compiler: compiler,
+		store:    store,
+		now:      time.Now,
+		buckets:  map[bucketKey]*list.Element{},
+		lru:      list.New(),
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	if config.Decision != "" {
+		ref, err := ast.PtrRef(ast.DefaultRootDocument, config.Decision)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate limits decision: %w", err)
+		}
+		l.decision = ref
+
+		if _, err := l.preparedDecision(context.Background()); err != nil {
+			return nil, fmt.Errorf("invalid rate limits decision: %w", err)
+		}
+	}
+
+	return l, nil
+}
+
+func (l *Limiter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	identity := clientIdentity(r)
+
+	limit, scope, err := l.limit(r, identity)
+	if err != nil {
+		writer.ErrorAuto(w, err)
+		return
+	}
+
+	if limit != nil {
+		if delay, ok := l.reserve(bucketKey{scope: scope, identity: identity}, limit); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			writer.Error(w, http.StatusTooManyRequests, types.NewErrorV1(types.CodeTooManyRequests, types.MsgTooManyRequestsError))
+			return
+		}
+	}
+
+	l.inner.ServeHTTP(w, r)
+}
+
+// limit returns the limit of the request and the scope of its bucket, or nil
+// if the request is not rate limited.
+func (l *Limiter) limit(r *http.Request, identity string) (*ratelimit.Limit, string, error) {
+	if l.decision != nil {
+		limit, key, err := l.evalDecision(r, identity)
+		if err != nil {
+			return nil, "", err
+		}
+		if limit != nil && key != "" {
+			return limit, "decision-key:" + key, nil
+		} else if limit != nil {
+			return limit, "decision-path:" + r.URL.Path, nil
+		}
+	}
+
+	if match := l.config.MatchPath(r.URL.Path); match != nil {
+		return &match.Limit, "path:" + match.Path, nil
+	}
+
+	if l.config.Default != nil {
+		return l.config.Default, "default", nil
+	}
+
+	return nil, "", nil
+}
+
+// preparedDecision returns the decision query prepared with the current
+// compiler. The query is only prepared again when the compiler changes, i.e.,
+// when policies are updated.
+func (l *Limiter) preparedDecision(ctx context.Context) (*rego.PreparedEvalQuery, error) {
+	l.pqMtx.Lock()
+	defer l.pqMtx.Unlock()
+
+	compiler := l.compiler()
+	if l.pq != nil && l.pqCompiler == compiler {
+		return l.pq, nil
+	}
+
+	pq, err := rego.New(
+		rego.Query(l.decision.String()),
+		rego.Compiler(compiler),
+		rego.Store(l.store),
+		rego.Runtime(l.runtime),
+	).PrepareForEval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	l.pq, l.pqCompiler = &pq, compiler
+	return l.pq, nil
+}
+
+// evalDecision returns the limit of the request returned by the decision and
+// the key of its bucket, if any, or nil if the decision is undefined.
+func (l *Limiter) evalDecision(r *http.Request, identity string) (*ratelimit.Limit, string, error) {
+	input := map[string]any{
+		"path":     r.URL.Path,
+		"method":   r.Method,
+		"identity": identity,
+	}
+
+	pq, err := l.preparedDecision(r.Context())
+	if err != nil {
+		return nil, "", err
+	}
+
+	rs, err := pq.Eval(r.Context(), rego.EvalInput(input))
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(rs) == 0 {
+		return nil, "", nil
+	}
+
+	bs, err := json.Marshal(rs[0].Expressions[0].Value)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var decision struct {
+		ratelimit.Limit
+		Key string `json:"key"`
+	}
+	if err := util.UnmarshalJSON(bs, &decision); err != nil {
+		return nil, "", types.NewErrorV1(types.CodeInternal, "invalid rate limit decision: %v", err)
+	}
+	if err := decision.ValidateAndInjectDefaults(); err != nil {
+		return nil, "", types.NewErrorV1(types.CodeInternal, "invalid rate limit decision: %v", err)
+	}
+
+	return &decision.Limit, decision.Key, nil
+}
+
+// reserve takes a token from the bucket of key, adjusted to limit. If there
+// is none, it returns how long to wait for one.
+func (l *Limiter) reserve(key bucketKey, limit *ratelimit.Limit) (time.Duration, bool) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	now := l.now()
+
+	var b *rate.Limiter
+	if e, ok := l.buckets[key]; ok {
+		l.lru.MoveToFront(e)
+		b = e.Value.(*bucket).limiter
+	} else {
+		for l.lru.Len() >= maxBuckets {
+			oldest := l.lru.Back()
+			if ob := oldest.Value.(*bucket).limiter; ob.TokensAt(now) < float64(ob.Burst()) {
+				break
+			}
+			delete(l.buckets, oldest.Value.(*bucket).key)
+			l.lru.Remove(oldest)
+		}
+		b = rate.NewLimiter(rate.Limit(limit.RequestsPerSecond), limit.Burst)
+		l.buckets[key] = l.lru.PushFront(&bucket{key: key, limiter: b})
+	}
+
+	if b.Limit() != rate.Limit(limit.RequestsPerSecond) || b.Burst() != limit.Burst {
+		b.SetLimitAt(now, rate.Limit(limit.RequestsPerSecond))
+		b.SetBurstAt(now, limit.Burst)
+	}
+
+	res := b.ReserveN(now, 1)
+	if !res.OK() {
+		return time.Second, false
+	}
+
+	if delay := res.DelayFrom(now); delay > 0 {
+		res.CancelAt(now)
+		return delay, false
+	}
+
+	return 0, true
+}
+
+// clientIdentity returns the identity established by the authentication
+// handler or, if there is none, the IP address of the client.
+func clientIdentity(r *http.Request) string {
+	if identity, ok := identifier.Identity(r); ok {
+		return identity
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,273 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ratelimiter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/plugins/server/ratelimit"
+	"github.com/open-policy-agent/opa/v1/server/identifier"
+	"github.com/open-policy-agent/opa/v1/storage/inmem"
+)
+
+func newTestLimiter(t *testing.T, config string, module string) *Limiter {
+	t.Helper()
+
+	c, err := ratelimit.NewConfigBuilder().WithBytes([]byte(config)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	compiler := ast.NewCompiler()
+	if module != "" {
+		compiler = ast.MustCompileModules(map[string]string{"test.rego": module})
+	}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	l, err := NewLimiter(ok, c, func() *ast.Compiler { return compiler }, inmem.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func serve(l *Limiter, path, identity string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, path, nil)
+	if identity != "" {
+		r = identifier.SetIdentity(r, identity)
+	}
+	w := httptest.NewRecorder()
+	l.ServeHTTP(w, r)
+	return w
+}
+
+func TestLimiterPerPathAndIdentity(t *testing.T) {
+	l := newTestLimiter(t, `{
+		"paths": [{"path": "/v1/data/expensive", "requests_per_second": 1, "burst": 2}]
+	}`, "")
+
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	for i := range 2 {
+		if w := serve(l, "/v1/data/expensive", "alice"); w.Code != http.StatusOK {
+			t.Fatalf("expected request %d to be allowed, got %d", i, w.Code)
+		}
+	}
+
+	w := serve(l, "/v1/data/expensive", "alice")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", w.Code)
+	}
+	if exp, act := "1", w.Header().Get("Retry-After"); exp != act {
+		t.Fatalf("expected Retry-After %v, got %v", exp, act)
+	}
+	if exp, act := `"code": "too_many_requests"`, w.Body.String(); !strings.Contains(act, exp) {
+		t.Fatalf("expected body containing %v, got %v", exp, act)
+	}
+
+	// Other identities have their own limits, and paths with the same prefix
+	// share the limit.
+	if w := serve(l, "/v1/data/expensive", "bob"); w.Code != http.StatusOK {
+		t.Fatalf("expected request of other identity to be allowed, got %d", w.Code)
+	}
+	if w := serve(l, "/v1/data/expensive/allow", "alice"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected request to path with the same prefix to be limited, got %d", w.Code)
+	}
+	for range 10 {
+		if w := serve(l, "/v1/data/cheap", "alice"); w.Code != http.StatusOK {
+			t.Fatalf("expected request to path without limit to be allowed, got %d", w.Code)
+		}
+	}
+
+	now = now.Add(time.Second)
+	if w := serve(l, "/v1/data/expensive", "alice"); w.Code != http.StatusOK {
+		t.Fatalf("expected request to be allowed after refill, got %d", w.Code)
+	}
+}
+
+func TestLimiterDefaultSharedByPaths(t *testing.T) {
+	l := newTestLimiter(t, `{"default": {"requests_per_second": 1}}`, "")
+
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	if w := serve(l, "/v1/data/a", "alice"); w.Code != http.StatusOK {
+		t.Fatalf("expected first request to be allowed, got %d", w.Code)
+	}
+	if w := serve(l, "/v1/data/b", "alice"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected request to other path to be limited, got %d", w.Code)
+	}
+}
+
+func TestLimiterDecision(t *testing.T) {
+	l := newTestLimiter(t, `{
+		"default": {"requests_per_second": 100},
+		"decision": "/system/rate_limits/limit"
+	}`, `package system.rate_limits
+
+limit := {"requests_per_second": 1} if input.identity == "noisy"
+
+limit := {"requests_per_second": "invalid"} if input.identity == "broken"`)
+
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	if w := serve(l, "/v1/data/x", "noisy"); w.Code != http.StatusOK {
+		t.Fatalf("expected first request to be allowed, got %d", w.Code)
+	}
+	if w := serve(l, "/v1/data/x", "noisy"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", w.Code)
+	}
+
+	// The decision is undefined, so the default limit applies.
+	for range 10 {
+		if w := serve(l, "/v1/data/x", "quiet"); w.Code != http.StatusOK {
+			t.Fatalf("expected request to be allowed, got %d", w.Code)
+		}
+	}
+
+	if w := serve(l, "/v1/data/x", "broken"); w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", w.Code)
+	}
+}
+
+func TestLimiterDecisionBuckets(t *testing.T) {
+	l := newTestLimiter(t, `{"decision": "/system/rate_limits/limit"}`, `package system.rate_limits
+
+limit := {"requests_per_second": 1, "key": "reports"} if startswith(input.path, "/v1/data/reports")
+
+limit := {"requests_per_second": 1} if not startswith(input.path, "/v1/data/reports")`)
+
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	// Requests with the same key share their bucket.
+	if w := serve(l, "/v1/data/reports/a", "alice"); w.Code != http.StatusOK {
+		t.Fatalf("expected first request to be allowed, got %d", w.Code)
+	}
+	if w := serve(l, "/v1/data/reports/b", "alice"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected request with the same key to be limited, got %d", w.Code)
+	}
+
+	// Requests without a key share the bucket of their path only.
+	if w := serve(l, "/v1/data/x", "alice"); w.Code != http.StatusOK {
+		t.Fatalf("expected first request to path to be allowed, got %d", w.Code)
+	}
+	if w := serve(l, "/v1/data/y", "alice"); w.Code != http.StatusOK {
+		t.Fatalf("expected first request to other path to be allowed, got %d", w.Code)
+	}
+	if w := serve(l, "/v1/data/x", "alice"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected request to the same path to be limited, got %d", w.Code)
+	}
+}
+
+func TestLimiterDecisionPreparedOnce(t *testing.T) {
+	compiler := ast.MustCompileModules(map[string]string{"test.rego": `package system.rate_limits
+
+limit := {"requests_per_second": 1}`})
+
+	c, err := ratelimit.NewConfigBuilder().WithBytes([]byte(`{"decision": "/system/rate_limits/limit"}`)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	l, err := NewLimiter(ok, c, func() *ast.Compiler { return compiler }, inmem.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	pq := l.pq
+	if pq == nil {
+		t.Fatal("expected decision to be prepared")
+	}
+
+	serve(l, "/v1/data/x", "alice")
+	serve(l, "/v1/data/x", "bob")
+	if l.pq != pq {
+		t.Fatal("expected decision to be prepared once")
+	}
+
+	compiler = ast.MustCompileModules(map[string]string{"test.rego": `package system.rate_limits
+
+limit := {"requests_per_second": 100}`})
+
+	// The bucket of the path is kept, and refilled at the new rate.
+	now = now.Add(time.Second)
+	if w := serve(l, "/v1/data/x", "alice"); w.Code != http.StatusOK {
+		t.Fatalf("expected limit of new policy to apply, got %d", w.Code)
+	}
+	if l.pq == pq {
+		t.Fatal("expected decision to be prepared again for new compiler")
+	}
+}
+
+func TestLimiterDropsLeastRecentlyUsedBuckets(t *testing.T) {
+	l := newTestLimiter(t, `{"default": {"requests_per_second": 1}}`, "")
+
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	for i := range maxBuckets {
+		l.reserve(bucketKey{scope: "default", identity: strconv.Itoa(i)}, l.config.Default)
+	}
+
+	// The buckets are empty, so none of them is dropped.
+	l.reserve(bucketKey{scope: "default", identity: "new"}, l.config.Default)
+
+	if len(l.buckets) != maxBuckets+1 || l.lru.Len() != maxBuckets+1 {
+		t.Fatalf("expected %d buckets, got %d", maxBuckets+1, len(l.buckets))
+	}
+	if _, ok := serveAt(l, bucketKey{scope: "default", identity: "0"}); ok {
+		t.Fatal("expected the limit of the oldest bucket to be kept")
+	}
+
+	// Once refilled, the least recently used buckets are dropped.
+	now = now.Add(time.Second)
+	l.reserve(bucketKey{scope: "default", identity: "0"}, l.config.Default)
+	l.reserve(bucketKey{scope: "default", identity: "newer"}, l.config.Default)
+
+	if len(l.buckets) != maxBuckets || l.lru.Len() != maxBuckets {
+		t.Fatalf("expected %d buckets, got %d", maxBuckets, len(l.buckets))
+	}
+	if _, ok := l.buckets[bucketKey{scope: "default", identity: "1"}]; ok {
+		t.Fatal("expected least recently used bucket to be dropped")
+	}
+	if _, ok := l.buckets[bucketKey{scope: "default", identity: "0"}]; !ok {
+		t.Fatal("expected recently used bucket to be kept")
+	}
+}
+
+func serveAt(l *Limiter, key bucketKey) (time.Duration, bool) {
+	return l.reserve(key, l.config.Default)
+}
+
+func TestClientIdentity(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	if exp, act := "192.0.2.1", clientIdentity(r); exp != act {
+		t.Fatalf("expected %v, got %v", exp, act)
+	}
+
+	if exp, act := "alice", clientIdentity(identifier.SetIdentity(r, "alice")); exp != act {
+		t.Fatalf("expected %v, got %v", exp, act)
+	}
+}
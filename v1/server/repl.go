@@ -0,0 +1,131 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+
+	"github.com/open-policy-agent/opa/v1/repl"
+	"github.com/open-policy-agent/opa/v1/server/types"
+	"github.com/open-policy-agent/opa/v1/server/writer"
+)
+
+const (
+	defaultREPLMaxSessions = 8
+	defaultREPLIdleTimeout = 10 * time.Minute
+)
+
+// v1REPLGet upgrades the connection to a WebSocket connection hosting a REPL
+// session. Clients send one types.REPLRequestV1 message per line of input and
+// receive one types.REPLResponseV1 message in return, after an initial
+// message carrying the prompt. The session ends when the connection is closed
+// or the exit command is entered.
+//
+// Like every other API, the upgrade request is subject to the authentication
+// and authorization schemes of the server. Requests from browsers are
+// rejected if their origin does not match the host of the server, so that
+// pages on other sites cannot open sessions on behalf of their visitors.
+//
+// The number of concurrent sessions is limited, further upgrade requests are
+// answered with 429. Sessions without input for the idle timeout are closed.
+func (s *Server) v1REPLGet(w http.ResponseWriter, r *http.Request) {
+	maxSessions := s.replMaxSessions
+	if maxSessions == 0 {
+		maxSessions = defaultREPLMaxSessions
+	}
+
+	if n := s.replSessions.Add(1); maxSessions > 0 && n > int64(maxSessions) {
+		s.replSessions.Add(-1)
+		writer.Error(w, http.StatusTooManyRequests, types.NewErrorV1(types.CodeTooManyRequests, "too many REPL sessions: at most %d sessions allowed", maxSessions))
+		return
+	}
+	defer s.replSessions.Add(-1)
+
+	// Accept verifies the origin of the request, and answers with an error if
+	// the request is not a valid upgrade request.
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.CloseNow()
+
+	if err := s.serveREPL(r.Context(), conn); err != nil {
+		return
+	}
+	conn.Close(websocket.StatusNormalClosure, "")
+}
+
+func (s *Server) serveREPL(ctx context.Context, conn *websocket.Conn) error {
+	// Sessions never write to the store: every line is evaluated in a read
+	// transaction, and rules defined in a session are only visible to it.
+	var buf bytes.Buffer
+	popts := s.manager.ParserOptions()
+	r := repl.New(s.store, "", &buf, "pretty", s.errLimit, "").
+		WithStderrWriter(&buf).
+		WithRuntime(s.runtime).
+		WithRegoVersion(popts.RegoVersion).
+		WithCapabilities(popts.Capabilities).
+		DisableFileSystemAccess(true)
+
+	idleTimeout := s.replIdleTimeout
+	if idleTimeout == 0 {
+		idleTimeout = defaultREPLIdleTimeout
+	}
+
+	if err := wsjson.Write(ctx, conn, types.REPLResponseV1{Prompt: r.Prompt()}); err != nil {
+		return err
+	}
+
+	for {
+		var req types.REPLRequestV1
+		if err := readREPLRequest(ctx, conn, idleTimeout, &req); err != nil {
+			return err
+		}
+
+		err := r.OneShot(ctx, req.Line)
+		if repl.IsStop(err) {
+			return nil
+		}
+
+		resp := types.REPLResponseV1{Output: buf.String(), Prompt: r.Prompt()}
+		if err != nil {
+			resp.Error = err.Error()
+		}
+		buf.Reset()
+
+		if err := wsjson.Write(ctx, conn, resp); err != nil {
+			return err
+		}
+	}
+}
+
+// readREPLRequest reads the next line of input of a session. If there is no
+// input for timeout, the connection is closed and an error is returned. A
+// negative timeout means no timeout.
+func readREPLRequest(ctx context.Context, conn *websocket.Conn, timeout time.Duration, req *types.REPLRequestV1) error {
+	if timeout < 0 {
+		return wsjson.Read(ctx, conn, req)
+	}
+
+	// Cancelling the read would drop the connection without a close frame, so
+	// the connection is closed from a timer instead, which makes the pending
+	// read fail.
+	idle := time.AfterFunc(timeout, func() {
+		conn.Close(websocket.StatusPolicyViolation, "session idle timeout")
+	})
+
+	err := wsjson.Read(ctx, conn, req)
+	if !idle.Stop() && err == nil {
+		return errors.New("session idle timeout")
+	}
+	return err
+}
@@ -0,0 +1,276 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/plugins"
+	"github.com/open-policy-agent/opa/v1/server/types"
+	"github.com/open-policy-agent/opa/v1/storage"
+	"github.com/open-policy-agent/opa/v1/storage/inmem"
+)
+
+func TestREPL(t *testing.T) {
+	t.Parallel()
+
+	f := newFixture(t, func(s *Server) {
+		s.WithREPLEnabled(true)
+	})
+
+	if err := f.v1TestRequests([]tr{
+		{http.MethodPut, "/policies/test", "package test\nallow if data.x == 1", 200, ""},
+		{http.MethodPut, "/data/x", "1", 204, ""},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(f.server.Handler)
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	conn, _, err := websocket.Dial(ctx, ts.URL+"/v1/repl", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.CloseNow()
+
+	var resp types.REPLResponseV1
+	if err := wsjson.Read(ctx, conn, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Prompt != "> " {
+		t.Fatalf("unexpected initial message: %+v", resp)
+	}
+
+	tests := []struct {
+		line   string
+		output string
+		error  string
+		prompt string
+	}{
+		{line: "data.test.allow", output: "true\n"},
+		{line: "y := data.x + 1", output: "Rule 'y' defined in package repl. Type 'show' to see rules.\n"},
+		{line: "y", output: "2\n"},
+		{line: "dump /tmp/data.json", error: "dump to file is disabled"},
+		{line: "z := [", prompt: "| "},
+		{line: "", error: "rego_parse_error"},
+	}
+
+	for _, tc := range tests {
+		if err := wsjson.Write(ctx, conn, types.REPLRequestV1{Line: tc.line}); err != nil {
+			t.Fatal(err)
+		}
+		var resp types.REPLResponseV1
+		if err := wsjson.Read(ctx, conn, &resp); err != nil {
+			t.Fatal(err)
+		}
+		if tc.prompt == "" {
+			tc.prompt = "> "
+		}
+		if resp.Output != tc.output || resp.Prompt != tc.prompt || !strings.Contains(resp.Error, tc.error) || (tc.error == "") != (resp.Error == "") {
+			t.Fatalf("%v: unexpected response: %+v", tc.line, resp)
+		}
+	}
+
+	// Rules defined in the session are not written to the store.
+	if err := f.v1(http.MethodGet, "/data/repl", "", 200, `{}`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := wsjson.Write(ctx, conn, types.REPLRequestV1{Line: "exit"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := wsjson.Read(ctx, conn, &resp); websocket.CloseStatus(err) != websocket.StatusNormalClosure {
+		t.Fatalf("expected connection to be closed, got %v", err)
+	}
+}
+
+func TestREPLCapabilities(t *testing.T) {
+	t.Parallel()
+
+	caps := ast.CapabilitiesForThisVersion()
+	caps.Builtins = slices.DeleteFunc(caps.Builtins, func(bi *ast.Builtin) bool {
+		return bi.Name == ast.Count.Name
+	})
+
+	f := newFixture(t, func(s *Server) {
+		s.WithREPLEnabled(true)
+	}, plugins.WithParserOptions(ast.ParserOptions{Capabilities: caps}))
+
+	ts := httptest.NewServer(f.server.Handler)
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	conn, _, err := websocket.Dial(ctx, ts.URL+"/v1/repl", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.CloseNow()
+
+	var resp types.REPLResponseV1
+	if err := wsjson.Read(ctx, conn, &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := wsjson.Write(ctx, conn, types.REPLRequestV1{Line: "count([1])"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := wsjson.Read(ctx, conn, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(resp.Error, "undefined function count") {
+		t.Fatalf("expected undefined function error, got %+v", resp)
+	}
+}
+
+func TestREPLLimits(t *testing.T) {
+	t.Parallel()
+
+	f := newFixture(t, func(s *Server) {
+		s.WithREPLEnabled(true).WithREPLLimits(1, 100*time.Millisecond)
+	})
+
+	ts := httptest.NewServer(f.server.Handler)
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	conn, _, err := websocket.Dial(ctx, ts.URL+"/v1/repl", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.CloseNow()
+
+	var resp types.REPLResponseV1
+	if err := wsjson.Read(ctx, conn, &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only one session is allowed at a time.
+	_, httpResp, err := websocket.Dial(ctx, ts.URL+"/v1/repl", nil)
+	if err == nil {
+		t.Fatal("expected second session to be rejected")
+	}
+	if httpResp == nil || httpResp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected too many requests response, got %v", httpResp)
+	}
+
+	// The session is closed after it has been idle for the timeout.
+	if err := wsjson.Read(ctx, conn, &resp); websocket.CloseStatus(err) != websocket.StatusPolicyViolation {
+		t.Fatalf("expected connection to be closed, got %v", err)
+	}
+
+	// Closed sessions make room for new ones. The session is released shortly
+	// after the connection has been closed.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		conn, _, err = websocket.Dial(ctx, ts.URL+"/v1/repl", nil)
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	conn.Close(websocket.StatusNormalClosure, "")
+}
+
+func TestREPLCrossOrigin(t *testing.T) {
+	t.Parallel()
+
+	f := newFixture(t, func(s *Server) {
+		s.WithREPLEnabled(true)
+	})
+
+	ts := httptest.NewServer(f.server.Handler)
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	_, resp, err := websocket.Dial(ctx, ts.URL+"/v1/repl", &websocket.DialOptions{
+		HTTPHeader: http.Header{"Origin": []string{"https://example.com"}},
+	})
+	if err == nil {
+		t.Fatal("expected cross-origin request to be rejected")
+	}
+	if resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected forbidden response, got %v", resp)
+	}
+
+	// Requests from pages served by OPA itself are accepted.
+	conn, _, err := websocket.Dial(ctx, ts.URL+"/v1/repl", &websocket.DialOptions{
+		HTTPHeader: http.Header{"Origin": []string{ts.URL}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close(websocket.StatusNormalClosure, "")
+}
+
+func TestREPLAuthorization(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := inmem.New()
+	txn := storage.NewTransactionOrDie(ctx, store, storage.WriteParams)
+	authzPolicy := `package system.authz
+
+default allow := false
+
+allow if input.path != ["v1", "repl"]
+`
+
+	if err := store.UpsertPolicy(ctx, txn, "authz", []byte(authzPolicy)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Commit(ctx, txn); err != nil {
+		t.Fatal(err)
+	}
+
+	f := newFixture(t, func(s *Server) {
+		s.WithStore(store).WithREPLEnabled(true).WithAuthorization(AuthorizationBasic)
+	})
+
+	// Other APIs are authorized by the same policy.
+	if err := f.v1(http.MethodGet, "/data", "", 200, `{"result":{}}`); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(f.server.Handler)
+	defer ts.Close()
+
+	_, resp, err := websocket.Dial(ctx, ts.URL+"/v1/repl", nil)
+	if err == nil {
+		t.Fatal("expected unauthorized request to be rejected")
+	}
+	if resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected unauthorized response, got %v", resp)
+	}
+}
+
+func TestREPLDisabled(t *testing.T) {
+	t.Parallel()
+
+	f := newFixture(t)
+
+	if err := f.v1(http.MethodGet, "/repl", "", 404, ""); err != nil {
+		t.Fatal(err)
+	}
+}
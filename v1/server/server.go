@@ -23,11 +23,14 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/open-policy-agent/opa/v1/hooks"
+	serverCORSPlugin "github.com/open-policy-agent/opa/v1/plugins/server/cors"
 	serverDecodingPlugin "github.com/open-policy-agent/opa/v1/plugins/server/decoding"
 	serverEncodingPlugin "github.com/open-policy-agent/opa/v1/plugins/server/encoding"
+	serverRateLimitPlugin "github.com/open-policy-agent/opa/v1/plugins/server/ratelimit"
 
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
@@ -35,6 +38,7 @@ import (
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/h2c"
 
+	"github.com/open-policy-agent/opa/internal/admission"
 	"github.com/open-policy-agent/opa/internal/json/patch"
 	"github.com/open-policy-agent/opa/v1/ast"
 	"github.com/open-policy-agent/opa/v1/bundle"
@@ -47,6 +51,7 @@ import (
 	"github.com/open-policy-agent/opa/v1/server/authorizer"
 	"github.com/open-policy-agent/opa/v1/server/handlers"
 	"github.com/open-policy-agent/opa/v1/server/identifier"
+	"github.com/open-policy-agent/opa/v1/server/ratelimiter"
 	"github.com/open-policy-agent/opa/v1/server/types"
 	"github.com/open-policy-agent/opa/v1/server/writer"
 	"github.com/open-policy-agent/opa/v1/storage"
@@ -68,6 +73,7 @@ const (
 	AuthenticationOff AuthenticationScheme = iota
 	AuthenticationToken
 	AuthenticationTLS
+	AuthenticationPlugin
 )
 
 // AuthorizationScheme enumerates the supported authorization schemes. The authorization
@@ -80,6 +86,14 @@ const (
 	AuthorizationBasic
 )
 
+// Set of response headers that correlate Data API responses with decision logs
+// and bundle revisions.
+const (
+	DecisionIDHeader      = "X-Opa-Decision-Id"
+	BundleRevisionsHeader = "X-Opa-Bundle-Revisions"
+	BundleRevisionHeader  = "X-Opa-Bundle-Revision"
+)
+
 const (
 	defaultMinTLSVersion = tls.VersionTLS12
 
@@ -91,6 +105,8 @@ const (
 	PromHandlerV1Compile  = "v1/compile"
 	PromHandlerV1Config   = "v1/config"
 	PromHandlerV1Status   = "v1/status"
+	PromHandlerV1Tenants  = "v1/tenants"
+	PromHandlerV1REPL     = "v1/repl"
 	PromHandlerIndex      = "index"
 	PromHandlerCatch      = "catchall"
 	PromHandlerHealth     = "health"
@@ -120,6 +136,7 @@ type Server struct {
 	diagAddrs                   []string
 	h2cEnabled                  bool
 	authentication              AuthenticationScheme
+	authenticator               identifier.Authenticator
 	authorization               AuthorizationScheme
 	cert                        *tls.Certificate
 	tlsConfigMtx                sync.RWMutex
@@ -141,6 +158,10 @@ type Server struct {
 	logger                      func(context.Context, *Info) error
 	errLimit                    int
 	pprofEnabled                bool
+	replEnabled                 bool
+	replMaxSessions             int
+	replIdleTimeout             time.Duration
+	replSessions                atomic.Int64
 	runtime                     *ast.Term
 	httpListeners               []httpListener
 	metrics                     Metrics
@@ -150,9 +171,14 @@ type Server struct {
 	allPluginsOkOnce            bool
 	distributedTracingOpts      tracing.Options
 	ndbCacheEnabled             bool
+	sharedVirtualCache          *topdown.SharedVirtualCache
+	sharedVirtualCacheKey       string
+	commits                     uint64
 	unixSocketPerm              *string
 	cipherSuites                *[]uint16
 	hooks                       hooks.Hooks
+	tenants                     *TenantManager
+	maxPolicies                 int
 }
 
 // Metrics defines the interface that the server requires for recording HTTP
@@ -191,6 +217,52 @@ func New() *Server {
 // Init initializes the server. This function MUST be called before starting any loops
 // from s.Listeners().
 func (s *Server) Init(ctx context.Context) (*Server, error) {
+	if s.authentication == AuthenticationPlugin && s.authenticator == nil {
+		return nil, errors.New("authentication scheme requires an authenticator")
+	}
+
+	if err := s.init(ctx); err != nil {
+		return nil, err
+	}
+
+	var err error
+
+	// rate limits are enforced after authentication, to limit requests per
+	// client identity
+	s.Handler, err = s.initHandlerRateLimits(s.Handler)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Handler = s.initHandlerAuthn(s.Handler)
+
+	// compression handler
+	s.Handler, err = s.initHandlerCompression(s.Handler)
+	if err != nil {
+		return nil, err
+	}
+	s.DiagnosticHandler = s.initHandlerAuthn(s.DiagnosticHandler)
+
+	s.Handler, err = s.initHandlerDecodingLimits(s.Handler)
+	if err != nil {
+		return nil, err
+	}
+
+	// CORS preflight requests are answered before authentication, as they
+	// carry no credentials
+	s.Handler, err = s.initHandlerCORS(s.Handler)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// init sets up the routers, caches and store triggers of the server, leaving
+// s.Handler without the authentication, compression and decoding limits
+// handlers. Tenant servers are initialized this way, as those handlers are
+// applied by the server that routes requests to them.
+func (s *Server) init(ctx context.Context) error {
 	s.initRouters(ctx)
 	var err error
 	s.hooks.Each(func(h hooks.Hook) {
@@ -206,12 +278,12 @@ func (s *Server) Init(ctx context.Context) (*Server, error) {
 		}
 	})
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	txn, err := s.store.NewTransaction(ctx, storage.WriteParams)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	// Register triggers so that if runtime reloads the policies, the
@@ -221,29 +293,16 @@ func (s *Server) Init(ctx context.Context) (*Server, error) {
 	}
 	if _, err := s.store.Register(ctx, txn, config); err != nil {
 		s.store.Abort(ctx, txn)
-		return nil, err
+		return err
 	}
 
 	s.partials = map[string]rego.PartialResult{}
 	s.preparedEvalQueries = newCache(pqMaxCacheSize)
 	s.defaultDecisionPath = s.generateDefaultDecisionPath()
+	s.sharedVirtualCacheKey = strconv.FormatUint(s.commits, 10)
 	s.manager.RegisterNDCacheTrigger(s.updateNDCache)
 
-	s.Handler = s.initHandlerAuthn(s.Handler)
-
-	// compression handler
-	s.Handler, err = s.initHandlerCompression(s.Handler)
-	if err != nil {
-		return nil, err
-	}
-	s.DiagnosticHandler = s.initHandlerAuthn(s.DiagnosticHandler)
-
-	s.Handler, err = s.initHandlerDecodingLimits(s.Handler)
-	if err != nil {
-		return nil, err
-	}
-
-	return s, s.store.Commit(ctx, txn)
+	return s.store.Commit(ctx, txn)
 }
 
 // Shutdown will attempt to gracefully shutdown each of the http servers
@@ -265,6 +324,13 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		}
 	}
 
+	// Persist the inter-query cache once no more requests are served.
+	if p, ok := s.interQueryBuiltinCache.(iCache.Persister); ok {
+		if err := p.Persist(); err != nil {
+			s.manager.Logger().Error("Failed to persist inter-query cache: %v", err)
+		}
+	}
+
 	if len(errorList) > 0 {
 		errMsg := "error while shutting down: "
 		for i, err := range errorList {
@@ -294,6 +360,13 @@ func (s *Server) WithAuthentication(scheme AuthenticationScheme) *Server {
 	return s
 }
 
+// WithAuthenticator sets the authenticator to use on the server when the
+// authentication scheme is AuthenticationPlugin.
+func (s *Server) WithAuthenticator(authenticator identifier.Authenticator) *Server {
+	s.authenticator = authenticator
+	return s
+}
+
 // WithAuthorization sets authorization scheme to use on the server.
 func (s *Server) WithAuthorization(scheme AuthorizationScheme) *Server {
 	s.authorization = scheme
@@ -360,12 +433,42 @@ func (s *Server) WithCompilerErrorLimit(limit int) *Server {
 	return s
 }
 
+// WithTenantManager enables multi-tenancy on the server. Requests carrying the
+// tenant header, or prefixed with /tenants/<id>, are served from the isolated
+// store and compiler of that tenant, and tenants can be created and evicted
+// through the /v1/tenants API. A tenant manager can only be attached to a
+// single server.
+func (s *Server) WithTenantManager(tm *TenantManager) *Server {
+	s.tenants = tm
+	if tm != nil {
+		tm.server = s
+	}
+	return s
+}
+
 // WithPprofEnabled sets whether pprof endpoints are enabled
 func (s *Server) WithPprofEnabled(pprofEnabled bool) *Server {
 	s.pprofEnabled = pprofEnabled
 	return s
 }
 
+// WithREPLEnabled sets whether the REPL API is enabled. The REPL API hosts
+// interactive REPL sessions over WebSocket connections. Sessions evaluate
+// against the policies and data in the server's store, but cannot modify them.
+func (s *Server) WithREPLEnabled(replEnabled bool) *Server {
+	s.replEnabled = replEnabled
+	return s
+}
+
+// WithREPLLimits sets the maximum number of concurrent REPL sessions, and the
+// time after which sessions without input are closed. Zero values select the
+// defaults of 8 sessions and 10 minutes, negative values disable the limits.
+func (s *Server) WithREPLLimits(maxSessions int, idleTimeout time.Duration) *Server {
+	s.replMaxSessions = maxSessions
+	s.replIdleTimeout = idleTimeout
+	return s
+}
+
 // WithH2CEnabled sets whether h2c ("HTTP/2 cleartext") is enabled for the http listener
 func (s *Server) WithH2CEnabled(enabled bool) *Server {
 	s.h2cEnabled = enabled
@@ -434,6 +537,15 @@ func (s *Server) WithNDBCacheEnabled(ndbCacheEnabled bool) *Server {
 	return s
 }
 
+// WithSharedVirtualCache sets the cache that stores the values of virtual
+// documents that only depend on data across the evaluations of the data APIs.
+// Its entries are keyed by the commits the server has seen, so that they are
+// dropped whenever policies, data or bundle revisions change.
+func (s *Server) WithSharedVirtualCache(c *topdown.SharedVirtualCache) *Server {
+	s.sharedVirtualCache = c
+	return s
+}
+
 // WithCipherSuites sets the list of enabled TLS 1.0–1.2 cipher suites.
 func (s *Server) WithCipherSuites(cipherSuites *[]uint16) *Server {
 	s.cipherSuites = cipherSuites
@@ -680,8 +792,13 @@ func (s *Server) getListenerForHTTPSServer(u *url.URL, h http.Handler, t httpLis
 				ClientCAs:      s.certPool,
 			}
 
-			if s.authentication == AuthenticationTLS {
+			switch s.authentication {
+			case AuthenticationTLS:
 				cfg.ClientAuth = tls.RequireAndVerifyClientCert
+			case AuthenticationPlugin:
+				// Client certificates are optional; the authenticator
+				// decides whether requests without them are acceptable.
+				cfg.ClientAuth = tls.VerifyClientCertIfGiven
 			}
 
 			if s.minTLSVersion != 0 {
@@ -751,6 +868,8 @@ func (s *Server) initHandlerAuthn(handler http.Handler) http.Handler {
 		handler = identifier.NewTokenBased(handler)
 	case AuthenticationTLS:
 		handler = identifier.NewTLSBased(handler)
+	case AuthenticationPlugin:
+		handler = identifier.NewAuthenticatorBased(handler, s.authenticator)
 	}
 
 	return handler
@@ -797,6 +916,36 @@ func (s *Server) initHandlerDecodingLimits(handler http.Handler) (http.Handler,
 	return decodingHandler, nil
 }
 
+// Enforces the rate limits of the server config on incoming requests, if any.
+func (s *Server) initHandlerRateLimits(handler http.Handler) (http.Handler, error) {
+	var rateLimitsRawConfig json.RawMessage
+	serverConfig := s.manager.Config.Server
+	if serverConfig != nil {
+		rateLimitsRawConfig = serverConfig.RateLimits
+	}
+	rateLimitsConfig, err := serverRateLimitPlugin.NewConfigBuilder().WithBytes(rateLimitsRawConfig).Parse()
+	if err != nil || rateLimitsConfig == nil {
+		return handler, err
+	}
+
+	return ratelimiter.NewLimiter(handler, rateLimitsConfig, s.getCompiler, s.store, ratelimiter.Runtime(s.runtime))
+}
+
+// Adds the CORS headers of the server config to responses, if any.
+func (s *Server) initHandlerCORS(handler http.Handler) (http.Handler, error) {
+	var corsRawConfig json.RawMessage
+	serverConfig := s.manager.Config.Server
+	if serverConfig != nil {
+		corsRawConfig = serverConfig.CORS
+	}
+	corsConfig, err := serverCORSPlugin.NewConfigBuilder().WithBytes(corsRawConfig).Parse()
+	if err != nil || corsConfig == nil {
+		return handler, err
+	}
+
+	return handlers.CORSHandler(handler, corsConfig), nil
+}
+
 func (s *Server) initHandlerCompression(handler http.Handler) (http.Handler, error) {
 	var encodingRawConfig json.RawMessage
 	serverConfig := s.manager.Config.Server
@@ -831,7 +980,12 @@ func (s *Server) initRouters(ctx context.Context) {
 
 	// Add authorization handler. This must come BEFORE authentication handler
 	// so that the latter can run first.
-	handlerAuthz := s.initHandlerAuthz(mainRouter)
+	var handlerAuthz http.Handler
+	if s.tenants != nil {
+		handlerAuthz = s.initHandlerAuthz(s.tenants.route(mainRouter))
+	} else {
+		handlerAuthz = s.initHandlerAuthz(mainRouter)
+	}
 
 	handlerAuthzDiag := s.initHandlerAuthz(diagRouter)
 
@@ -876,6 +1030,7 @@ func (s *Server) initRouters(ctx context.Context) {
 	mainRouter.Handle("GET /v1/data", s.instrumentHandler(s.v1DataGet, PromHandlerV1Data))
 	mainRouter.Handle("PATCH /v1/data/{path...}", s.instrumentHandler(s.v1DataPatch, PromHandlerV1Data))
 	mainRouter.Handle("PATCH /v1/data", s.instrumentHandler(s.v1DataPatch, PromHandlerV1Data))
+	mainRouter.Handle("POST /v1/data:batch", s.instrumentHandler(s.v1DataBatch, PromHandlerV1Data))
 	mainRouter.Handle("POST /v1/data/{path...}", s.instrumentHandler(s.v1DataPost, PromHandlerV1Data))
 	mainRouter.Handle("POST /v1/data", s.instrumentHandler(s.v1DataPost, PromHandlerV1Data))
 	mainRouter.Handle("GET /v1/policies", s.instrumentHandler(s.v1PoliciesList, PromHandlerV1Policies))
@@ -887,18 +1042,35 @@ func (s *Server) initRouters(ctx context.Context) {
 	mainRouter.Handle("POST /v1/compile", s.instrumentHandler(s.v1CompilePost, PromHandlerV1Compile))
 	mainRouter.Handle("GET /v1/config", s.instrumentHandler(s.v1ConfigGet, PromHandlerV1Config))
 	mainRouter.Handle("GET /v1/status", s.instrumentHandler(s.v1StatusGet, PromHandlerV1Status))
+	if s.replEnabled {
+		mainRouter.Handle("GET /v1/repl", s.instrumentHandler(s.v1REPLGet, PromHandlerV1REPL))
+	}
+	if s.tenants != nil {
+		mainRouter.Handle("GET /v1/tenants", s.instrumentHandler(s.v1TenantsList, PromHandlerV1Tenants))
+		mainRouter.Handle("PUT /v1/tenants/{id}", s.instrumentHandler(s.v1TenantsPut, PromHandlerV1Tenants))
+		mainRouter.Handle("DELETE /v1/tenants/{id}", s.instrumentHandler(s.v1TenantsDelete, PromHandlerV1Tenants))
+	}
 	mainRouter.Handle("POST /{$}", s.instrumentHandler(s.unversionedPost, PromHandlerIndex))
 	mainRouter.Handle("GET /{$}", s.instrumentHandler(s.indexGet, PromHandlerIndex))
 
-	// These are catch all handlers that respond http.StatusMethodNotAllowed for resources that exist but the method is not allowed
-	mainRouter.Handle("/v0/data/{path...}", s.methodNotAllowedHandler())
-	mainRouter.Handle("/v0/data", s.methodNotAllowedHandler())
-	mainRouter.Handle("/v1/data/{path...}", s.methodNotAllowedHandler())
-	mainRouter.Handle("/v1/data", s.methodNotAllowedHandler())
-	mainRouter.Handle("/v1/policies", s.methodNotAllowedHandler())
-	mainRouter.Handle("/v1/policies/{path...}", s.methodNotAllowedHandler())
+	// These are catch all handlers that respond http.StatusMethodNotAllowed for resources that exist but the method is not allowed,
+	// and that answer OPTIONS requests for them, listing the allowed methods. GET handlers also serve HEAD requests.
+	mainRouter.Handle("/v0/data/{path...}", s.methodNotAllowedHandler(http.MethodPost))
+	mainRouter.Handle("/v0/data", s.methodNotAllowedHandler(http.MethodPost))
+	mainRouter.Handle("/v1/data:batch", s.methodNotAllowedHandler(http.MethodPost))
+	mainRouter.Handle("/v1/data/{path...}", s.methodNotAllowedHandler(http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete))
+	mainRouter.Handle("/v1/data", s.methodNotAllowedHandler(http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch))
+	mainRouter.Handle("/v1/policies", s.methodNotAllowedHandler(http.MethodGet, http.MethodHead))
+	mainRouter.Handle("/v1/policies/{path...}", s.methodNotAllowedHandler(http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete))
 	mainRouter.Handle("/v1/query/{path...}", s.methodNotAllowedHandler())
-	mainRouter.Handle("/v1/query", s.methodNotAllowedHandler())
+	mainRouter.Handle("/v1/query", s.methodNotAllowedHandler(http.MethodGet, http.MethodHead, http.MethodPost))
+	mainRouter.Handle("/v1/compile", s.methodNotAllowedHandler(http.MethodPost))
+	mainRouter.Handle("/v1/config", s.methodNotAllowedHandler(http.MethodGet, http.MethodHead))
+	mainRouter.Handle("/v1/status", s.methodNotAllowedHandler(http.MethodGet, http.MethodHead))
+	if s.tenants != nil {
+		mainRouter.Handle("/v1/tenants", s.methodNotAllowedHandler(http.MethodGet, http.MethodHead))
+		mainRouter.Handle("/v1/tenants/{id}", s.methodNotAllowedHandler(http.MethodPut, http.MethodDelete))
+	}
 
 	// Add authorization handler in the end so that it can run first
 	s.Handler = handlerAuthz
@@ -916,7 +1088,7 @@ func createMiddleware(mw ...func(http.Handler) http.Handler) func(http.Handler)
 }
 
 func (s *Server) instrumentHandler(handler func(http.ResponseWriter, *http.Request), label string) http.Handler {
-	httpHandler := handlers.DefaultHandler(createMiddleware(
+	httpHandler := handlers.TrailingSlashRedirectHandler(createMiddleware(
 		s.manager.ExtraMiddlewares()...,
 	)(http.HandlerFunc(handler)))
 	if len(s.distributedTracingOpts) > 0 {
@@ -928,8 +1100,21 @@ func (s *Server) instrumentHandler(handler func(http.ResponseWriter, *http.Reque
 	return httpHandler
 }
 
-func (s *Server) methodNotAllowedHandler() http.Handler {
-	return s.instrumentHandler(writer.HTTPStatus(http.StatusMethodNotAllowed), PromHandlerCatch)
+// methodNotAllowedHandler responds http.StatusMethodNotAllowed, and answers
+// OPTIONS requests with http.StatusNoContent if any methods are allowed. Both
+// list the allowed methods in the Allow header.
+func (s *Server) methodNotAllowedHandler(allowed ...string) http.Handler {
+	allow := strings.Join(allowed, ", ")
+	return s.instrumentHandler(func(w http.ResponseWriter, r *http.Request) {
+		if allow != "" {
+			w.Header().Set("Allow", allow+", "+http.MethodOptions)
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		writer.HTTPStatus(http.StatusMethodNotAllowed)(w, r)
+	}, PromHandlerCatch)
 }
 
 func (s *Server) execQuery(ctx context.Context, br bundleRevisions, txn storage.Transaction, parsedQuery ast.Body, input ast.Value, rawInput *any, m metrics.Metrics, explainMode types.ExplainModeV1, includeMetrics, includeInstrumentation, pretty bool) (*types.QueryResponseV1, error) {
@@ -1059,6 +1244,11 @@ func (s *Server) reload(context.Context, storage.Transaction, storage.TriggerEve
 	s.partials = map[string]rego.PartialResult{}
 	s.preparedEvalQueries = newCache(pqMaxCacheSize)
 	s.defaultDecisionPath = s.generateDefaultDecisionPath()
+
+	// Every commit may change policies or data, so values of virtual documents
+	// cached by earlier evaluations are not shared with later ones.
+	s.commits++
+	s.sharedVirtualCacheKey = strconv.FormatUint(s.commits, 10)
 }
 
 func (s *Server) unversionedPost(w http.ResponseWriter, r *http.Request) {
@@ -1149,6 +1339,8 @@ func (s *Server) v0QueryPath(w http.ResponseWriter, r *http.Request, urlPath str
 		rego.EvalInterQueryBuiltinCache(s.interQueryBuiltinCache),
 		rego.EvalInterQueryBuiltinValueCache(s.interQueryBuiltinValueCache),
 		rego.EvalNDBuiltinCache(ndbCache),
+		rego.EvalDecisionID(decisionID),
+		rego.EvalSharedVirtualCache(s.sharedVirtualCache, s.sharedVirtualCacheKey),
 	}
 
 	rs, err := preparedQuery.Eval(
@@ -1422,34 +1614,43 @@ func (s *Server) v1CompilePost(w http.ResponseWriter, r *http.Request) {
 		buf = topdown.NewBufferTracer()
 	}
 
-	eval := rego.New(
-		rego.Compiler(s.getCompiler()),
-		rego.Store(s.store),
-		rego.Transaction(txn),
-		rego.ParsedQuery(request.Query),
-		rego.ParsedInput(request.Input),
-		rego.ParsedUnknowns(request.Unknowns),
-		rego.DisableInlining(request.Options.DisableInlining),
-		rego.NondeterministicBuiltins(request.Options.NondeterminsiticBuiltins),
-		rego.QueryTracer(buf),
-		rego.Instrument(includeInstrumentation),
-		rego.Metrics(m),
-		rego.Runtime(s.runtime),
-		rego.UnsafeBuiltins(unsafeBuiltinsMap),
-		rego.InterQueryBuiltinCache(s.interQueryBuiltinCache),
-		rego.InterQueryBuiltinValueCache(s.interQueryBuiltinValueCache),
-		rego.PrintHook(s.manager.PrintHook()),
-	)
-
-	pq, err := eval.Partial(ctx)
-	if err != nil {
-		switch err := err.(type) {
-		case ast.Errors:
-			writer.Error(w, http.StatusBadRequest, types.NewErrorV1(types.CodeInvalidParameter, types.MsgCompileModuleError).WithASTErrors(err))
-		default:
-			writer.ErrorAuto(w, err)
+	// All queries share the transaction, and thereby see the same data.
+	results := make([]types.PartialEvaluationResultV1, 0, len(request.Queries))
+	for _, query := range request.Queries {
+		eval := rego.New(
+			rego.Compiler(s.getCompiler()),
+			rego.Store(s.store),
+			rego.Transaction(txn),
+			rego.ParsedQuery(query),
+			rego.ParsedInput(request.Input),
+			rego.ParsedUnknowns(request.Unknowns),
+			rego.DisableInlining(request.Options.DisableInlining),
+			rego.NondeterministicBuiltins(request.Options.NondeterminsiticBuiltins),
+			rego.QueryTracer(buf),
+			rego.Instrument(includeInstrumentation),
+			rego.Metrics(m),
+			rego.Runtime(s.runtime),
+			rego.UnsafeBuiltins(unsafeBuiltinsMap),
+			rego.InterQueryBuiltinCache(s.interQueryBuiltinCache),
+			rego.InterQueryBuiltinValueCache(s.interQueryBuiltinValueCache),
+			rego.PrintHook(s.manager.PrintHook()),
+		)
+
+		pq, err := eval.Partial(ctx)
+		if err != nil {
+			switch err := err.(type) {
+			case ast.Errors:
+				writer.Error(w, http.StatusBadRequest, types.NewErrorV1(types.CodeInvalidParameter, types.MsgCompileModuleError).WithASTErrors(err))
+			default:
+				writer.ErrorAuto(w, err)
+			}
+			return
 		}
-		return
+
+		results = append(results, types.PartialEvaluationResultV1{
+			Queries: pq.Queries,
+			Support: pq.Support,
+		})
 	}
 
 	m.Timer(metrics.ServerHandler).Stop()
@@ -1464,9 +1665,11 @@ func (s *Server) v1CompilePost(w http.ResponseWriter, r *http.Request) {
 		result.Explanation = s.getExplainResponse(explainMode, *buf, pretty(r))
 	}
 
-	var i any = types.PartialEvaluationResultV1{
-		Queries: pq.Queries,
-		Support: pq.Support,
+	var i any
+	if request.Multiple {
+		i = results
+	} else {
+		i = results[0]
 	}
 
 	result.Result = &i
@@ -1482,6 +1685,7 @@ func (s *Server) v1DataGet(w http.ResponseWriter, r *http.Request) {
 	decisionID := s.generateDecisionID()
 	ctx := logging.WithDecisionID(r.Context(), decisionID)
 	annotateSpan(ctx, decisionID)
+	setDecisionIDHeader(w, decisionID)
 
 	urlPath := escapedPathValue(r, "path")
 	explainMode := getExplain(r.URL, types.ExplainOffV1)
@@ -1489,6 +1693,12 @@ func (s *Server) v1DataGet(w http.ResponseWriter, r *http.Request) {
 	provenance := getBoolParam(r.URL, types.ParamProvenanceV1, true)
 	strictBuiltinErrors := getBoolParam(r.URL, types.ParamStrictBuiltinErrors, true)
 
+	shape, err := getResponseShape(r.URL)
+	if err != nil {
+		writer.ErrorAuto(w, err)
+		return
+	}
+
 	m.Timer(metrics.RegoInputParse).Start()
 
 	inputs := r.URL.Query()[types.ParamInputV1]
@@ -1521,6 +1731,7 @@ func (s *Server) v1DataGet(w http.ResponseWriter, r *http.Request) {
 		writer.ErrorAuto(w, err)
 		return
 	}
+	setRevisionHeaders(w, br)
 
 	ctx, logger := s.getDecisionLogger(ctx, br)
 
@@ -1579,6 +1790,7 @@ func (s *Server) v1DataGet(w http.ResponseWriter, r *http.Request) {
 		rego.EvalInterQueryBuiltinValueCache(s.interQueryBuiltinValueCache),
 		rego.EvalInstrument(includeInstrumentation),
 		rego.EvalNDBuiltinCache(ndbCache),
+		rego.EvalSharedVirtualCache(s.sharedVirtualCache, s.sharedVirtualCacheKey),
 	}
 
 	rs, err := preparedQuery.Eval(
@@ -1620,7 +1832,7 @@ func (s *Server) v1DataGet(w http.ResponseWriter, r *http.Request) {
 			writer.ErrorAuto(w, err)
 			return
 		}
-		writer.JSONOK(w, result, pretty(r))
+		s.writeDataGetResponse(w, r, result)
 		return
 	}
 
@@ -1634,7 +1846,40 @@ func (s *Server) v1DataGet(w http.ResponseWriter, r *http.Request) {
 		writer.ErrorAuto(w, err)
 		return
 	}
-	writer.JSONOK(w, result, pretty(r))
+
+	if shape != nil {
+		shaped := shape.apply(*result.Result)
+		result.Result = &shaped
+	}
+
+	s.writeDataGetResponse(w, r, result)
+}
+
+// writeDataGetResponse writes the response of a GET /v1/data request, tagged
+// with an ETag of the response body. The decision ID and metrics differ
+// between requests, so responses that include them are not tagged.
+func (*Server) writeDataGetResponse(w http.ResponseWriter, r *http.Request, result types.DataResponseV1) {
+	if result.DecisionID != "" || result.Metrics != nil {
+		writer.JSONOK(w, result, pretty(r))
+		return
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	if pretty(r) {
+		enc.SetIndent("", "  ")
+	}
+	if err := enc.Encode(result); err != nil {
+		writer.ErrorAuto(w, err)
+		return
+	}
+
+	if notModified(w, r, bytesETag(buf.Bytes())) {
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	_, _ = w.Write(buf.Bytes())
 }
 
 func (s *Server) v1DataPatch(w http.ResponseWriter, r *http.Request) {
@@ -1708,6 +1953,13 @@ func (s *Server) v1DataPost(w http.ResponseWriter, r *http.Request) {
 	decisionID := s.generateDecisionID()
 	ctx := logging.WithDecisionID(r.Context(), decisionID)
 	annotateSpan(ctx, decisionID)
+	setDecisionIDHeader(w, decisionID)
+
+	shape, err := getResponseShape(r.URL)
+	if err != nil {
+		writer.ErrorAuto(w, err)
+		return
+	}
 
 	m.Timer(metrics.RegoInputParse).Start()
 
@@ -1730,17 +1982,16 @@ func (s *Server) v1DataPost(w http.ResponseWriter, r *http.Request) {
 	provenance := getBoolParam(r.URL, types.ParamProvenanceV1, true)
 
 	var logger decisionLogger
-	var br bundleRevisions
 
-	if s.logger != nil || provenance {
-		br, err = getRevisions(ctx, s.store, txn)
-		if err != nil {
-			writer.ErrorAuto(w, err)
-			return
-		}
-		if s.logger != nil {
-			ctx, logger = s.getDecisionLogger(ctx, br)
-		}
+	br, err := getRevisions(ctx, s.store, txn)
+	if err != nil {
+		writer.ErrorAuto(w, err)
+		return
+	}
+	setRevisionHeaders(w, br)
+
+	if s.logger != nil {
+		ctx, logger = s.getDecisionLogger(ctx, br)
 	}
 
 	var buf *topdown.BufferTracer
@@ -1806,6 +2057,8 @@ func (s *Server) v1DataPost(w http.ResponseWriter, r *http.Request) {
 		rego.EvalInterQueryBuiltinValueCache(s.interQueryBuiltinValueCache),
 		rego.EvalInstrument(includeInstrumentation),
 		rego.EvalNDBuiltinCache(ndbCache),
+		rego.EvalSharedVirtualCache(s.sharedVirtualCache, s.sharedVirtualCacheKey),
+		rego.EvalDecisionID(decisionID),
 	)
 
 	m.Timer(metrics.ServerHandler).Stop()
@@ -1859,6 +2112,12 @@ func (s *Server) v1DataPost(w http.ResponseWriter, r *http.Request) {
 		writer.ErrorAuto(w, err)
 		return
 	}
+
+	if shape != nil {
+		shaped := shape.apply(*result.Result)
+		result.Result = &shaped
+	}
+
 	writer.JSONOK(w, result, pretty(r))
 }
 
@@ -2014,6 +2273,116 @@ func (s *Server) v1DataDelete(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+func (s *Server) v1DataBatch(w http.ResponseWriter, r *http.Request) {
+	m := metrics.New()
+	m.Timer(metrics.ServerHandler).Start()
+
+	ctx := r.Context()
+	var ops []types.DataBatchOperationV1
+
+	m.Timer(metrics.RegoInputParse).Start()
+	if err := util.NewJSONDecoder(r.Body).Decode(&ops); err != nil {
+		writer.ErrorString(w, http.StatusBadRequest, types.CodeInvalidParameter, err)
+		return
+	}
+	m.Timer(metrics.RegoInputParse).Stop()
+
+	// All operations are written in one transaction, so that either all or
+	// none of them are committed, and the policies and caches depending on
+	// the data are updated once.
+	params := storage.WriteParams
+	params.Context = storage.NewContext().WithMetrics(m)
+	txn, err := s.store.NewTransaction(ctx, params)
+	if err != nil {
+		writer.ErrorAuto(w, err)
+		return
+	}
+
+	for i, op := range ops {
+		if err := s.writeDataBatchOperation(ctx, txn, op); err != nil {
+			if types.IsBadRequest(err) {
+				err = types.BadRequestErr(fmt.Sprintf("operation %d: %v", i, err))
+			}
+			s.abortAuto(ctx, txn, w, err)
+			return
+		}
+	}
+
+	if err := ast.CheckPathConflicts(s.getCompiler(), storage.NonEmpty(ctx, s.store, txn)); len(err) > 0 {
+		s.store.Abort(ctx, txn)
+		writer.ErrorString(w, http.StatusBadRequest, types.CodeInvalidParameter, err)
+		return
+	}
+
+	if err := s.store.Commit(ctx, txn); err != nil {
+		writer.ErrorAuto(w, err)
+		return
+	}
+
+	m.Timer(metrics.ServerHandler).Stop()
+
+	if includeMetrics(r) {
+		result := types.DataResponseV1{
+			Metrics: m.All(),
+		}
+		writer.JSONOK(w, result, false)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeDataBatchOperation writes op of a bulk data write in txn, like the
+// PUT, PATCH and DELETE methods of the Data API.
+func (s *Server) writeDataBatchOperation(ctx context.Context, txn storage.Transaction, op types.DataBatchOperationV1) error {
+	if op.Op == "patch" {
+		patches, err := s.prepareV1PatchSlice(op.Path, op.Patch)
+		if err != nil {
+			return err
+		}
+		for _, patch := range patches {
+			if err := s.checkPathScope(ctx, txn, patch.path); err != nil {
+				return err
+			}
+			if err := s.store.Write(ctx, txn, patch.op, patch.path, patch.value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	path, ok := storage.ParsePathEscaped("/" + strings.Trim(op.Path, "/"))
+	if !ok {
+		return types.BadRequestErr(fmt.Sprintf("bad path: %v", op.Path))
+	}
+
+	if err := s.checkPathScope(ctx, txn, path); err != nil {
+		return err
+	}
+
+	switch op.Op {
+	case "put":
+		if _, err := s.store.Read(ctx, txn, path); err != nil {
+			if !storage.IsNotFound(err) {
+				return err
+			}
+			if len(path) > 0 {
+				if err := storage.MakeDir(ctx, s.store, txn, path[:len(path)-1]); err != nil {
+					return err
+				}
+			}
+		}
+		return s.store.Write(ctx, txn, storage.AddOp, path, op.Value)
+	case "delete":
+		if _, err := s.store.Read(ctx, txn, path); err != nil {
+			return err
+		}
+		return s.store.Write(ctx, txn, storage.RemoveOp, path, nil)
+	default:
+		return types.BadRequestErr(fmt.Sprintf("bad operation: %v", op.Op))
+	}
+}
+
 func (s *Server) v1PoliciesDelete(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("path")
 
@@ -2087,6 +2456,16 @@ func (s *Server) v1PoliciesGet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	tag, err := etag([]any{path, string(bs), pretty(r)})
+	if err != nil {
+		writer.ErrorAuto(w, err)
+		return
+	}
+
+	if notModified(w, r, tag) {
+		return
+	}
+
 	resp := types.PolicyGetResponseV1{
 		Result: types.PolicyV1{
 			ID:  path,
@@ -2111,6 +2490,7 @@ func (s *Server) v1PoliciesList(w http.ResponseWriter, r *http.Request) {
 	defer s.store.Abort(ctx, txn)
 
 	policies := []types.PolicyV1{}
+	raws := []string{}
 	c := s.getCompiler()
 
 	// Only return policies from the store, the compiler
@@ -2132,6 +2512,17 @@ func (s *Server) v1PoliciesList(w http.ResponseWriter, r *http.Request) {
 			AST: c.Modules[id],
 		}
 		policies = append(policies, policy)
+		raws = append(raws, id, policy.Raw)
+	}
+
+	tag, err := etag([]any{raws, pretty(r)})
+	if err != nil {
+		writer.ErrorAuto(w, err)
+		return
+	}
+
+	if notModified(w, r, tag) {
+		return
 	}
 
 	writer.JSONOK(w, types.PolicyListResponseV1{Result: policies}, pretty(r))
@@ -2172,6 +2563,10 @@ func (s *Server) v1PoliciesPut(w http.ResponseWriter, r *http.Request) {
 			s.abortAuto(ctx, txn, w, err)
 			return
 		}
+		if err := s.checkPolicyQuota(ctx, txn); err != nil {
+			s.abort(ctx, txn, func() { writeQuotaError(w, err) })
+			return
+		}
 	} else if bytes.Equal(buf, bs) {
 		s.store.Abort(ctx, txn)
 		resp := types.PolicyPutResponseV1{}
@@ -2208,6 +2603,11 @@ func (s *Server) v1PoliciesPut(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := s.checkPolicyAdmission(ctx, txn, id, parsedMod); err != nil {
+		s.abort(ctx, txn, func() { writeAdmissionError(w, err) })
+		return
+	}
+
 	modules, err := s.loadModules(ctx, txn)
 	if err != nil {
 		s.abortAuto(ctx, txn, w, err)
@@ -2436,6 +2836,27 @@ func (s *Server) checkPolicyPackageScope(ctx context.Context, txn storage.Transa
 	return s.checkPathScope(ctx, txn, spath)
 }
 
+// checkPolicyAdmission evaluates the configured policy admission decision
+// against the module. Policies are admitted if no decision is configured.
+func (s *Server) checkPolicyAdmission(ctx context.Context, txn storage.Transaction, id string, module *ast.Module) error {
+	checker := &admission.Checker{
+		Decision: s.manager.Config.PolicyAdmissionDecisionRef(),
+		Compiler: s.getCompiler(),
+		Store:    s.store,
+		Txn:      txn,
+	}
+	return checker.Check(ctx, id, module)
+}
+
+func writeAdmissionError(w http.ResponseWriter, err error) {
+	var ae *admission.Error
+	if errors.As(err, &ae) {
+		writer.ErrorString(w, http.StatusBadRequest, types.CodeInvalidParameter, err)
+		return
+	}
+	writer.ErrorAuto(w, err)
+}
+
 func (s *Server) getMetrics(r *http.Request) metrics.Metrics {
 	metricsInQuery := getBoolParam(r.URL, types.ParamMetricsV1, true)
 	instrumentationInQuery := getBoolParam(r.URL, types.ParamInstrumentV1, true)
@@ -2699,6 +3120,36 @@ func (s *Server) generateDecisionID() string {
 	return ""
 }
 
+// setDecisionIDHeader sets the DecisionIDHeader of a Data API response, so
+// that clients can correlate it with the decision logs.
+func setDecisionIDHeader(w http.ResponseWriter, decisionID string) {
+	if decisionID != "" {
+		w.Header().Set(DecisionIDHeader, decisionID)
+	}
+}
+
+// setRevisionHeaders sets the revisions of the bundles a Data API response
+// was evaluated against. BundleRevisionsHeader lists the URL-encoded
+// name=revision pairs of the active bundles sorted by name, and
+// BundleRevisionHeader holds the revision of a legacy bundle, if any.
+func setRevisionHeaders(w http.ResponseWriter, br bundleRevisions) {
+	if br.LegacyRevision != "" {
+		w.Header().Set(BundleRevisionHeader, br.LegacyRevision)
+	}
+
+	if len(br.Revisions) == 0 {
+		return
+	}
+
+	names := util.KeysSorted(br.Revisions)
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, url.QueryEscape(name)+"="+url.QueryEscape(br.Revisions[name]))
+	}
+
+	w.Header().Set(BundleRevisionsHeader, strings.Join(pairs, ","))
+}
+
 func (s *Server) getProvenance(br bundleRevisions) *types.ProvenanceV1 {
 
 	p := &types.ProvenanceV1{
@@ -2947,7 +3398,8 @@ func readInputPostV1(r *http.Request) (ast.Value, *any, error) {
 }
 
 type compileRequest struct {
-	Query    ast.Body
+	Queries  []ast.Body
+	Multiple bool // queries were sent as an array, and results are returned as one
 	Input    ast.Value
 	Unknowns []*ast.Term
 	Options  compileRequestOptions
@@ -2966,16 +3418,27 @@ func readInputCompilePostV1(reqBytes []byte, queryParserOptions ast.ParserOption
 		return nil, types.NewErrorV1(types.CodeInvalidParameter, "error(s) occurred while decoding request: %v", err.Error())
 	}
 
-	query, err := ast.ParseBodyWithOpts(request.Query, queryParserOptions)
-	if err != nil {
-		switch err := err.(type) {
-		case ast.Errors:
-			return nil, types.NewErrorV1(types.CodeInvalidParameter, types.MsgParseQueryError).WithASTErrors(err)
-		default:
-			return nil, types.NewErrorV1(types.CodeInvalidParameter, "%v: %v", types.MsgParseQueryError, err)
+	var queries []ast.Body
+	switch {
+	case request.Queries != nil && request.Query != "":
+		return nil, types.NewErrorV1(types.CodeInvalidParameter, "only one of 'query' and 'queries' may be set")
+	case request.Queries != nil:
+		if len(request.Queries) == 0 {
+			return nil, types.NewErrorV1(types.CodeInvalidParameter, "missing required 'queries' value")
 		}
-	} else if len(query) == 0 {
-		return nil, types.NewErrorV1(types.CodeInvalidParameter, "missing required 'query' value")
+		for _, q := range request.Queries {
+			query, errV1 := parseCompileQuery(q, queryParserOptions)
+			if errV1 != nil {
+				return nil, errV1
+			}
+			queries = append(queries, query)
+		}
+	default:
+		query, errV1 := parseCompileQuery(request.Query, queryParserOptions)
+		if errV1 != nil {
+			return nil, errV1
+		}
+		queries = append(queries, query)
 	}
 
 	var input ast.Value
@@ -2998,7 +3461,8 @@ func readInputCompilePostV1(reqBytes []byte, queryParserOptions ast.ParserOption
 	}
 
 	return &compileRequest{
-		Query:    query,
+		Queries:  queries,
+		Multiple: request.Queries != nil,
 		Input:    input,
 		Unknowns: unknowns,
 		Options: compileRequestOptions{
@@ -3008,6 +3472,21 @@ func readInputCompilePostV1(reqBytes []byte, queryParserOptions ast.ParserOption
 	}, nil
 }
 
+func parseCompileQuery(q string, queryParserOptions ast.ParserOptions) (ast.Body, *types.ErrorV1) {
+	query, err := ast.ParseBodyWithOpts(q, queryParserOptions)
+	if err != nil {
+		switch err := err.(type) {
+		case ast.Errors:
+			return nil, types.NewErrorV1(types.CodeInvalidParameter, types.MsgParseQueryError).WithASTErrors(err)
+		default:
+			return nil, types.NewErrorV1(types.CodeInvalidParameter, "%v: %v", types.MsgParseQueryError, err)
+		}
+	} else if len(query) == 0 {
+		return nil, types.NewErrorV1(types.CodeInvalidParameter, "missing required 'query' value")
+	}
+	return query, nil
+}
+
 var indexHTML, _ = template.New("index").Parse(`
 <html>
 <head>
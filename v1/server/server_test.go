@@ -57,6 +57,7 @@ import (
 	"github.com/open-policy-agent/opa/v1/storage"
 	"github.com/open-policy-agent/opa/v1/storage/disk"
 	"github.com/open-policy-agent/opa/v1/storage/inmem"
+	"github.com/open-policy-agent/opa/v1/topdown"
 	"github.com/open-policy-agent/opa/v1/util"
 	"github.com/open-policy-agent/opa/v1/util/test"
 	"github.com/open-policy-agent/opa/v1/version"
@@ -785,32 +786,28 @@ func Test405StatusCodev1(t *testing.T) {
 		reqs []tr
 	}{
 		{"v1 data one level 405", []tr{
-			{http.MethodHead, "/data/lvl1", "", 405, ""},
 			{http.MethodConnect, "/data/lvl1", "", 405, ""},
-			{http.MethodOptions, "/data/lvl1", "", 405, ""},
+			{http.MethodOptions, "/data/lvl1", "", 204, ""},
 			{http.MethodTrace, "/data/lvl1", "", 405, ""},
 		}},
 		{"v1 data 405", []tr{
-			{http.MethodHead, "/data", "", 405, ""},
 			{http.MethodConnect, "/data", "", 405, ""},
-			{http.MethodOptions, "/data", "", 405, ""},
+			{http.MethodOptions, "/data", "", 204, ""},
 			{http.MethodTrace, "/data", "", 405, ""},
 			{http.MethodDelete, "/data", "", 405, ""},
 		}},
 		{"v1 policies 405", []tr{
-			{http.MethodHead, "/policies", "", 405, ""},
 			{http.MethodConnect, "/policies", "", 405, ""},
 			{http.MethodDelete, "/policies", "", 405, ""},
-			{http.MethodOptions, "/policies", "", 405, ""},
+			{http.MethodOptions, "/policies", "", 204, ""},
 			{http.MethodTrace, "/policies", "", 405, ""},
 			{http.MethodPost, "/policies", "", 405, ""},
 			{http.MethodPut, "/policies", "", 405, ""},
 			{http.MethodPatch, "/policies", "", 405, ""},
 		}},
 		{"v1 policies one level 405", []tr{
-			{http.MethodHead, "/policies/lvl1", "", 405, ""},
 			{http.MethodConnect, "/policies/lvl1", "", 405, ""},
-			{http.MethodOptions, "/policies/lvl1", "", 405, ""},
+			{http.MethodOptions, "/policies/lvl1", "", 204, ""},
 			{http.MethodTrace, "/policies/lvl1", "", 405, ""},
 			{http.MethodPost, "/policies/lvl1", "", 405, ""},
 		}},
@@ -825,10 +822,9 @@ func Test405StatusCodev1(t *testing.T) {
 			{http.MethodPatch, "/query/lvl1", "", 405, ""},
 		}},
 		{"v1 query 405", []tr{
-			{http.MethodHead, "/query", "", 405, ""},
 			{http.MethodConnect, "/query", "", 405, ""},
 			{http.MethodDelete, "/query", "", 405, ""},
-			{http.MethodOptions, "/query", "", 405, ""},
+			{http.MethodOptions, "/query", "", 204, ""},
 			{http.MethodTrace, "/query", "", 405, ""},
 			{http.MethodPut, "/query", "", 405, ""},
 			{http.MethodPatch, "/query", "", 405, ""},
@@ -853,7 +849,7 @@ func Test405StatusCodev0(t *testing.T) {
 			{http.MethodHead, "/data/lvl2", "", 405, ""},
 			{http.MethodConnect, "/data/lvl2", "", 405, ""},
 			{http.MethodDelete, "/data/lvl2", "", 405, ""},
-			{http.MethodOptions, "/data/lvl2", "", 405, ""},
+			{http.MethodOptions, "/data/lvl2", "", 204, ""},
 			{http.MethodTrace, "/data/lvl2", "", 405, ""},
 			{http.MethodGet, "/data/lvl2", "", 405, ""},
 			{http.MethodPatch, "/data/lvl2", "", 405, ""},
@@ -863,7 +859,7 @@ func Test405StatusCodev0(t *testing.T) {
 			{http.MethodHead, "/data", "", 405, ""},
 			{http.MethodConnect, "/data", "", 405, ""},
 			{http.MethodDelete, "/data", "", 405, ""},
-			{http.MethodOptions, "/data", "", 405, ""},
+			{http.MethodOptions, "/data", "", 204, ""},
 			{http.MethodTrace, "/data", "", 405, ""},
 			{http.MethodGet, "/data", "", 405, ""},
 			{http.MethodPatch, "/data", "", 405, ""},
@@ -987,7 +983,8 @@ func TestCompileV1(t *testing.T) {
 					"file": "",
 					"row": 1
 					},
-					"message": "%s"
+					"message": "%s",
+					"suggestion": "import future.keywords.in"
 				}`, "var in is unsafe (hint: `import future.keywords.in` to import a future keyword)"))},
 			},
 		},
@@ -1148,6 +1145,18 @@ func TestCompileV1(t *testing.T) {
 				{http.MethodPost, "/compile", `{"query": "1 = 1"}`, 200, `{"result": {"queries": [[]]}}`},
 			},
 		},
+		{
+			note: "multiple queries",
+			trs: []tr{
+				{http.MethodPut, "/policies/test", v1mod, 200, ""},
+				{http.MethodPost, "/compile", `{
+					"unknowns": ["data.a"],
+					"queries": ["data.test.q = true", "1 = 2", "data.test.p = true"],
+					"input": { "x": 1 }
+				}`, 200, fmt.Sprintf(`{"result": [{"queries": [%v]}, {}, {"queries": [[]]}]}`,
+					string(util.MustMarshalJSON(ast.MustParseBody("1 = data.a[i1]"))))},
+			},
+		},
 		{
 			note: "error: bad request",
 			trs:  []tr{{http.MethodPost, "/compile", `{"input": [{]}`, 400, ``}},
@@ -1160,6 +1169,18 @@ func TestCompileV1(t *testing.T) {
 			note: "error: bad query",
 			trs:  []tr{{http.MethodPost, "/compile", `{"query": "x %!> 9"}`, 400, ""}},
 		},
+		{
+			note: "error: empty queries",
+			trs:  []tr{{http.MethodPost, "/compile", `{"queries": []}`, 400, ""}},
+		},
+		{
+			note: "error: bad query in queries",
+			trs:  []tr{{http.MethodPost, "/compile", `{"queries": ["true", "x %!> 9"]}`, 400, ""}},
+		},
+		{
+			note: "error: query and queries",
+			trs:  []tr{{http.MethodPost, "/compile", `{"query": "true", "queries": ["true"]}`, 400, ""}},
+		},
 		{
 			note: "error: bad unknown",
 			trs:  []tr{{http.MethodPost, "/compile", `{"unknowns": ["input."], "query": "true"}`, 400, ""}},
@@ -1707,6 +1728,44 @@ p = true if { false }`
 	}
 }
 
+func TestDataV1SharedVirtualCache(t *testing.T) {
+	t.Parallel()
+
+	c := topdown.NewSharedVirtualCache(10)
+	f := newFixture(t, func(s *Server) {
+		s.WithSharedVirtualCache(c)
+	})
+
+	policy := "package test\n\nq := data.x + 1\n\np := q"
+	if err := f.v1(http.MethodPut, "/policies/test", policy, 200, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	err := f.v1TestRequests([]tr{
+		{http.MethodPut, "/data/x", "1", 204, ""},
+		{http.MethodGet, "/data/test/p", "", 200, `{"result": 2}`},
+		{http.MethodPost, "/data/test/p", `{"input": {}}`, 200, `{"result": 2}`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Len() == 0 {
+		t.Fatal("expected values to be shared")
+	}
+
+	// Values computed before data changes are not shared.
+	err = f.v1TestRequests([]tr{
+		{http.MethodPut, "/data/x", "2", 204, ""},
+		{http.MethodGet, "/data/test/p", "", 200, `{"result": 3}`},
+		{http.MethodPost, "/data/test/p", `{"input": {}}`, 200, `{"result": 3}`},
+		{http.MethodPost, "/data/test/p", `{"input": {"x": 7}}`, 200, `{"result": 3}`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestDataV1Metrics(t *testing.T) {
 	t.Parallel()
 
@@ -1880,6 +1939,161 @@ func TestDataPutV1IfNoneMatch(t *testing.T) {
 	}
 }
 
+func TestDataBatchV1(t *testing.T) {
+	t.Parallel()
+
+	executeRequests(t, []tr{
+		{http.MethodPut, "/data/x", `{"a": 1, "b": [1]}`, 204, ""},
+		{http.MethodPost, "/data:batch", `[
+			{"op": "put", "path": "/y/z", "value": {"c": 2}},
+			{"op": "patch", "path": "/x", "patch": [{"op": "add", "path": "/b/-", "value": 2}, {"op": "replace", "path": "/a", "value": 3}]},
+			{"op": "delete", "path": "/y/z/c"}
+		]`, 204, ""},
+		{http.MethodGet, "/data", "", 200, `{"result": {"x": {"a": 3, "b": [1, 2]}, "y": {"z": {}}}}`},
+
+		// A failing operation aborts all operations.
+		{http.MethodPost, "/data:batch", `[
+			{"op": "put", "path": "/x/a", "value": 4},
+			{"op": "delete", "path": "/nope"}
+		]`, 404, `{"code": "resource_not_found", "message": "storage_not_found_error: /nope: document does not exist"}`},
+		{http.MethodPost, "/data:batch", `[
+			{"op": "put", "path": "/x/a", "value": 4},
+			{"op": "move", "path": "/x/a"}
+		]`, 400, `{"code": "invalid_parameter", "message": "operation 1: bad operation: move"}`},
+		{http.MethodPost, "/data:batch", `[{"op": "patch", "path": "/x", "patch": [{"op": "move", "path": "/a"}]}]`, 400, `{"code": "invalid_parameter", "message": "operation 0: bad patch operation: move"}`},
+		{http.MethodPost, "/data:batch", `{"op": "put"}`, 400, ""},
+		{http.MethodGet, "/data/x/a", "", 200, `{"result": 3}`},
+		{http.MethodGet, "/data:batch", "", 405, ""},
+	})
+}
+
+func TestDataV1ResponseShape(t *testing.T) {
+	t.Parallel()
+
+	executeRequests(t, []tr{
+		{http.MethodPut, "/data/x", `{"a": {"b": 1, "c": 2}, "d": [{"e": 1, "f": 2}, {"e": 3}], "g": 4}`, 204, ""},
+		{http.MethodGet, "/data/x?fields=a.b,g", "", 200, `{"result": {"a": {"b": 1}, "g": 4}}`},
+		{http.MethodGet, "/data/x?fields=a.b&fields=d.e", "", 200, `{"result": {"a": {"b": 1}, "d": [{"e": 1}, {"e": 3}]}}`},
+		{http.MethodGet, "/data/x?exclude=a,d.f", "", 200, `{"result": {"d": [{"e": 1}, {"e": 3}], "g": 4}}`},
+		{http.MethodGet, "/data/x?fields=a&exclude=a.c", "", 200, `{"result": {"a": {"b": 1}}}`},
+		{http.MethodGet, "/data/x?fields=nope", "", 200, `{"result": {}}`},
+		{http.MethodPost, "/data/x?fields=g", `{"input": {}}`, 200, `{"result": {"g": 4}}`},
+		{http.MethodGet, "/data/x?fields=a..b", "", 400, `{"code": "invalid_parameter", "message": "invalid fields parameter: \"a..b\""}`},
+		{http.MethodPost, "/data/x?exclude=,a", `{"input": {}}`, 400, `{"code": "invalid_parameter", "message": "invalid exclude parameter: \",a\""}`},
+
+		// The stored document is not modified.
+		{http.MethodGet, "/data/x/a", "", 200, `{"result": {"b": 1, "c": 2}}`},
+	})
+}
+
+func TestDataGetV1ETag(t *testing.T) {
+	t.Parallel()
+
+	f := newFixture(t)
+	if err := f.v1(http.MethodPut, "/data/a/b", `{"c": 1}`, 204, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.v1(http.MethodGet, "/data/a/b", "", 200, `{"result": {"c": 1}}`); err != nil {
+		t.Fatal(err)
+	}
+
+	tag := f.recorder.Header().Get("ETag")
+	if tag == "" {
+		t.Fatal("expected ETag header")
+	}
+
+	req := newReqV1(http.MethodGet, "/data/a/b", "")
+	req.Header.Set("If-None-Match", `"other", W/`+tag)
+	if err := f.executeRequest(req, 304, ""); err != nil {
+		t.Fatal(err)
+	}
+	if f.recorder.Body.Len() != 0 || f.recorder.Header().Get("ETag") != tag {
+		t.Fatalf("expected empty 304 response with ETag %v, got: %+v", tag, f.recorder)
+	}
+
+	// Different inputs and documents have different tags.
+	req = newReqV1(http.MethodGet, "/data/a/b/c", "")
+	req.Header.Set("If-None-Match", tag)
+	if err := f.executeRequest(req, 200, `{"result": 1}`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.v1(http.MethodPut, "/data/a/b/c", "2", 204, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	req = newReqV1(http.MethodGet, "/data/a/b", "")
+	req.Header.Set("If-None-Match", tag)
+	if err := f.executeRequest(req, 200, `{"result": {"c": 2}}`); err != nil {
+		t.Fatal(err)
+	}
+	if f.recorder.Header().Get("ETag") == tag {
+		t.Fatal("expected ETag to change with the document")
+	}
+	tag = f.recorder.Header().Get("ETag")
+
+	// Formatting changes the tag, and responses with metrics are not tagged.
+	req = newReqV1(http.MethodGet, "/data/a/b?pretty", "")
+	req.Header.Set("If-None-Match", tag)
+	if err := f.executeRequest(req, 200, `{"result": {"c": 2}}`); err != nil {
+		t.Fatal(err)
+	}
+	if f.recorder.Header().Get("ETag") == tag {
+		t.Fatal("expected ETag to change with the formatting")
+	}
+
+	req = newReqV1(http.MethodGet, "/data/a/b?metrics", "")
+	req.Header.Set("If-None-Match", tag)
+	if err := f.executeRequest(req, 200, ""); err != nil {
+		t.Fatal(err)
+	}
+	if f.recorder.Header().Get("ETag") != "" {
+		t.Fatalf("expected response without ETag, got: %+v", f.recorder)
+	}
+}
+
+func TestPoliciesV1ETag(t *testing.T) {
+	t.Parallel()
+
+	f := newFixture(t)
+	if err := f.v1(http.MethodPut, "/policies/test", "package test\np := 1", 200, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"/policies/test", "/policies"} {
+		if err := f.v1(http.MethodGet, path, "", 200, ""); err != nil {
+			t.Fatal(err)
+		}
+
+		tag := f.recorder.Header().Get("ETag")
+		if tag == "" {
+			t.Fatalf("expected ETag header from %v", path)
+		}
+
+		req := newReqV1(http.MethodGet, path, "")
+		req.Header.Set("If-None-Match", tag)
+		if err := f.executeRequest(req, 304, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := f.v1(http.MethodGet, "/policies", "", 200, ""); err != nil {
+		t.Fatal(err)
+	}
+	tag := f.recorder.Header().Get("ETag")
+
+	if err := f.v1(http.MethodPut, "/policies/test", "package test\np := 2", 200, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	req := newReqV1(http.MethodGet, "/policies", "")
+	req.Header.Set("If-None-Match", tag)
+	if err := f.executeRequest(req, 200, ""); err != nil {
+		t.Fatal(err)
+	}
+}
+
 // Ensure JSON payload is compressed with gzip.
 func mustGZIPPayload(payload []byte) []byte {
 	var compressedPayload bytes.Buffer
@@ -2222,6 +2436,156 @@ allow if {
 	}
 }
 
+func TestServerRateLimits(t *testing.T) {
+	t.Parallel()
+
+	f := newFixtureWithConfig(t, `{"server": {"rate_limits": {"paths": [{"path": "/v1/data/limited", "requests_per_second": 0.001, "burst": 1}]}}}`)
+
+	for i, exp := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		f.reset()
+		f.server.Handler.ServeHTTP(f.recorder, newReqV1(http.MethodPost, "/data/limited", ""))
+		if f.recorder.Code != exp {
+			t.Fatalf("Expected status %d for request %d, got %d: %s", exp, i, f.recorder.Code, f.recorder.Body)
+		}
+	}
+
+	if exp, act := "1000", f.recorder.Header().Get("Retry-After"); exp != act {
+		t.Fatalf("Expected Retry-After %v, got %v", exp, act)
+	}
+
+	f.reset()
+	f.server.Handler.ServeHTTP(f.recorder, newReqV1(http.MethodPost, "/data/unlimited", ""))
+	if f.recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", f.recorder.Code)
+	}
+
+	m, err := plugins.New([]byte(`{"server": {"rate_limits": {"default": {"requests_per_second": -1}}}}`), "test", inmem.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = New().WithStore(inmem.New()).WithManager(m).Init(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "requests_per_second should be a positive number") {
+		t.Fatalf("Expected invalid rate limits error, got %v", err)
+	}
+}
+
+func TestHeadAndOptionsV1(t *testing.T) {
+	t.Parallel()
+
+	f := newFixture(t)
+
+	f.server.Handler.ServeHTTP(f.recorder, newReqV1(http.MethodHead, "/data", ""))
+	if f.recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 for HEAD request, got %d", f.recorder.Code)
+	}
+
+	tests := []struct {
+		path  string
+		code  int
+		allow string
+	}{
+		{"/data/a", http.StatusNoContent, "GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS"},
+		{"/data:batch", http.StatusNoContent, "POST, OPTIONS"},
+		{"/query", http.StatusNoContent, "GET, HEAD, POST, OPTIONS"},
+		{"/compile", http.StatusNoContent, "POST, OPTIONS"},
+		{"/query/a", http.StatusMethodNotAllowed, ""},
+	}
+
+	for _, tc := range tests {
+		f.reset()
+		f.server.Handler.ServeHTTP(f.recorder, newReqV1(http.MethodOptions, tc.path, ""))
+		if f.recorder.Code != tc.code {
+			t.Errorf("Expected status %d for OPTIONS %v, got %d", tc.code, tc.path, f.recorder.Code)
+		}
+		if act := f.recorder.Header().Get("Allow"); act != tc.allow {
+			t.Errorf("Expected Allow header %q for OPTIONS %v, got %q", tc.allow, tc.path, act)
+		}
+	}
+
+	f.reset()
+	f.server.Handler.ServeHTTP(f.recorder, newReqV1(http.MethodPatch, "/query", ""))
+	if f.recorder.Code != http.StatusMethodNotAllowed || f.recorder.Header().Get("Allow") != "GET, HEAD, POST, OPTIONS" {
+		t.Fatalf("Expected status 405 with Allow header, got %d and %q", f.recorder.Code, f.recorder.Header().Get("Allow"))
+	}
+}
+
+func TestServerCORS(t *testing.T) {
+	t.Parallel()
+
+	f := newFixtureWithConfig(t, `{"server": {"cors": {
+		"allowed_origins": ["https://playground.example.com"],
+		"exposed_headers": ["X-Request-Id"],
+		"allow_credentials": true,
+		"max_age_seconds": 600
+	}}}`)
+
+	// Preflight requests are answered before authentication and routing.
+	req := newReqV1(http.MethodOptions, "/data/a", "")
+	req.Header.Set("Origin", "https://playground.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	req.Header.Set("Access-Control-Request-Headers", "content-type, authorization")
+	f.server.Handler.ServeHTTP(f.recorder, req)
+
+	if f.recorder.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204 for preflight request, got %d", f.recorder.Code)
+	}
+	for k, exp := range map[string]string{
+		"Access-Control-Allow-Origin":      "https://playground.example.com",
+		"Access-Control-Allow-Methods":     "GET, HEAD, POST, PUT, PATCH, DELETE",
+		"Access-Control-Allow-Headers":     "Authorization, Content-Type, Content-Encoding, Accept-Encoding",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Max-Age":           "600",
+	} {
+		if act := f.recorder.Header().Get(k); act != exp {
+			t.Errorf("Expected %v header %q, got %q", k, exp, act)
+		}
+	}
+
+	f.reset()
+	req = newReqV1(http.MethodOptions, "/data/a", "")
+	req.Header.Set("Origin", "https://playground.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	req.Header.Set("Access-Control-Request-Headers", "x-custom")
+	f.server.Handler.ServeHTTP(f.recorder, req)
+
+	if act := f.recorder.Header().Get("Access-Control-Allow-Methods"); act != "" {
+		t.Fatalf("Expected no allowed methods for disallowed header, got %q", act)
+	}
+
+	f.reset()
+	req = newReqV1(http.MethodPost, "/data/a", "")
+	req.Header.Set("Origin", "https://playground.example.com")
+	f.server.Handler.ServeHTTP(f.recorder, req)
+
+	if f.recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", f.recorder.Code)
+	}
+	if act := f.recorder.Header().Get("Access-Control-Allow-Origin"); act != "https://playground.example.com" {
+		t.Fatalf("Expected allowed origin, got %q", act)
+	}
+	if act := f.recorder.Header().Get("Access-Control-Expose-Headers"); act != "X-Request-Id" {
+		t.Fatalf("Expected exposed headers, got %q", act)
+	}
+
+	f.reset()
+	req = newReqV1(http.MethodPost, "/data/a", "")
+	req.Header.Set("Origin", "https://evil.example.com")
+	f.server.Handler.ServeHTTP(f.recorder, req)
+
+	if act := f.recorder.Header().Get("Access-Control-Allow-Origin"); act != "" {
+		t.Fatalf("Expected no allowed origin for other origins, got %q", act)
+	}
+
+	m, err := plugins.New([]byte(`{"server": {"cors": {"allowed_origins": ["*"], "allow_credentials": true}}}`), "test", inmem.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = New().WithStore(inmem.New()).WithManager(m).Init(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "credentials cannot be allowed for any origin") {
+		t.Fatalf("Expected invalid CORS config error, got %v", err)
+	}
+}
+
 func TestDataPostV0CompressedResponse(t *testing.T) {
 	t.Parallel()
 
@@ -3749,6 +4113,44 @@ func TestPoliciesPutV1Noop(t *testing.T) {
 
 }
 
+func TestPoliciesPutV1Admission(t *testing.T) {
+	t.Parallel()
+
+	f := newFixture(t, func(m *plugins.Manager) {
+		decision := "/system/policy_admission/deny"
+		m.Config.PolicyAdmissionDecision = &decision
+	})
+
+	// Policies are admitted until the admission policy is defined.
+	admission := `package system.policy_admission
+
+deny contains msg if {
+	some rule in input.module.rules
+	rule.head.name == "allow"
+	not rule.default
+	not default_allow
+	msg := sprintf("%s: allow rules require a default", [input.id])
+}
+
+default_allow if {
+	some rule in input.module.rules
+	rule.head.name == "allow"
+	rule.default
+}`
+
+	if err := f.v1TestRequests([]tr{
+		{http.MethodPut, "/policies/admission", admission, 200, ""},
+		{http.MethodPut, "/policies/p", "package p\nallow if input.x", 400, `{
+			"code": "invalid_parameter",
+			"message": "policy \"p\" rejected by admission policy: p: allow rules require a default"
+		}`},
+		{http.MethodPut, "/policies/p", "package p\ndefault allow := false\nallow if input.x", 200, ""},
+		{http.MethodGet, "/policies/p", "", 200, ""},
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestPoliciesListV1(t *testing.T) {
 	t.Parallel()
 
@@ -4285,6 +4687,56 @@ func TestDecisionIDs(t *testing.T) {
 	}
 }
 
+func TestDataCorrelationHeaders(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	f := newFixture(t)
+
+	ctr := 0
+	f.server = f.server.WithDecisionIDFactory(func() string {
+		ctr++
+		return strconv.Itoa(ctr)
+	})
+
+	txn := storage.NewTransactionOrDie(ctx, f.server.store, storage.WriteParams)
+
+	for name, revision := range map[string]string{"b": "r 2", "a": "r1"} {
+		if err := bundle.WriteManifestToStore(ctx, f.server.store, txn, name, bundle.Manifest{
+			Revision: revision,
+			Roots:    &[]string{name},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := f.server.store.Commit(ctx, txn); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, method := range []string{"GET", "POST"} {
+		f.reset()
+		f.server.Handler.ServeHTTP(f.recorder, newReqV1(method, "/data/a", ""))
+
+		if f.recorder.Code != http.StatusOK {
+			t.Fatalf("%v: expected HTTP 200 but got %v", method, f.recorder)
+		}
+
+		if exp, act := strconv.Itoa(i+1), f.recorder.Header().Get(DecisionIDHeader); exp != act {
+			t.Errorf("%v: expected decision ID %q, got %q", method, exp, act)
+		}
+
+		if exp, act := "a=r1,b=r+2", f.recorder.Header().Get(BundleRevisionsHeader); exp != act {
+			t.Errorf("%v: expected bundle revisions %q, got %q", method, exp, act)
+		}
+
+		if act := f.recorder.Header().Get(BundleRevisionHeader); act != "" {
+			t.Errorf("%v: expected no legacy bundle revision, got %q", method, act)
+		}
+	}
+}
+
 func TestDecisionLoggingWithHTTPRequestContext(t *testing.T) {
 	t.Parallel()
 
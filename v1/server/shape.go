@@ -0,0 +1,128 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/open-policy-agent/opa/v1/server/types"
+)
+
+// responseShape holds the projections requested with the fields and exclude
+// URL parameters of the data API. Paths are dot-separated keys into the
+// result. Projections descend into the elements of arrays and leave values
+// other than objects and arrays unchanged.
+type responseShape struct {
+	fields  [][]string
+	exclude [][]string
+}
+
+// getResponseShape returns the projections requested in the URL, or nil if
+// there are none.
+func getResponseShape(url *url.URL) (*responseShape, error) {
+	fields, err := getPathsParam(url, types.ParamFieldsV1)
+	if err != nil {
+		return nil, err
+	}
+
+	exclude, err := getPathsParam(url, types.ParamExcludeV1)
+	if err != nil {
+		return nil, err
+	}
+
+	if fields == nil && exclude == nil {
+		return nil, nil
+	}
+
+	return &responseShape{fields: fields, exclude: exclude}, nil
+}
+
+func getPathsParam(url *url.URL, name string) ([][]string, error) {
+	var paths [][]string
+	for _, p := range getStringSliceParam(url, name) {
+		for _, s := range strings.Split(p, ",") {
+			path := strings.Split(strings.TrimSpace(s), ".")
+			if slices.Contains(path, "") {
+				return nil, types.BadRequestErr(fmt.Sprintf("invalid %v parameter: %q", name, p))
+			}
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
+
+// apply returns the shaped copy of x. The values in x are not modified.
+func (s *responseShape) apply(x any) any {
+	if s == nil {
+		return x
+	}
+
+	if s.fields != nil {
+		x = projectFields(x, s.fields)
+	}
+
+	for _, path := range s.exclude {
+		x = excludePath(x, path)
+	}
+
+	return x
+}
+
+func projectFields(x any, paths [][]string) any {
+	switch v := x.(type) {
+	case map[string]any:
+		groups := map[string][][]string{}
+		for _, path := range paths {
+			if len(path) == 0 {
+				return x
+			}
+			groups[path[0]] = append(groups[path[0]], path[1:])
+		}
+		result := make(map[string]any, len(groups))
+		for key, rest := range groups {
+			if val, ok := v[key]; ok {
+				result[key] = projectFields(val, rest)
+			}
+		}
+		return result
+	case []any:
+		result := make([]any, len(v))
+		for i := range v {
+			result[i] = projectFields(v[i], paths)
+		}
+		return result
+	}
+
+	return x
+}
+
+func excludePath(x any, path []string) any {
+	switch v := x.(type) {
+	case map[string]any:
+		val, ok := v[path[0]]
+		if !ok {
+			return x
+		}
+		result := maps.Clone(v)
+		if len(path) == 1 {
+			delete(result, path[0])
+		} else {
+			result[path[0]] = excludePath(val, path[1:])
+		}
+		return result
+	case []any:
+		result := make([]any, len(v))
+		for i := range v {
+			result[i] = excludePath(v[i], path)
+		}
+		return result
+	}
+
+	return x
+}
@@ -0,0 +1,305 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/open-policy-agent/opa/v1/plugins"
+	"github.com/open-policy-agent/opa/v1/server/types"
+	"github.com/open-policy-agent/opa/v1/server/writer"
+	"github.com/open-policy-agent/opa/v1/storage"
+	"github.com/open-policy-agent/opa/v1/storage/inmem"
+	"github.com/open-policy-agent/opa/v1/util"
+)
+
+// TenantHeader is the request header used to route a request to a tenant.
+const TenantHeader = "X-Opa-Tenant"
+
+// tenantPathPrefix is the path prefix used to route a request to a tenant, as
+// an alternative to TenantHeader: /tenants/<id>/v1/data is served as
+// /v1/data by the tenant <id>.
+const tenantPathPrefix = "/tenants/"
+
+var tenantIDRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+var (
+	// ErrTenantExists is returned when creating a tenant that already exists.
+	ErrTenantExists = errors.New("tenant already exists")
+
+	// ErrTenantNotFound is returned when evicting a tenant that does not exist.
+	ErrTenantNotFound = errors.New("tenant not found")
+)
+
+// TenantConfig contains the quotas of a tenant.
+type TenantConfig struct {
+	// MaxPolicies limits the number of policies the tenant can store. Zero
+	// means no limit.
+	MaxPolicies int `json:"max_policies,omitempty"`
+}
+
+type tenant struct {
+	config  TenantConfig
+	store   storage.Store
+	manager *plugins.Manager
+	handler http.Handler
+}
+
+// TenantManager manages tenants of a server. Every tenant has its own store,
+// compiler and plugin manager, so that tenants with disjoint policies and data
+// can be served by a single OPA process without seeing each other.
+type TenantManager struct {
+	mtx        sync.RWMutex
+	tenants    map[string]*tenant
+	maxTenants int
+	server     *Server
+}
+
+// quotaError is returned when an operation would exceed a quota.
+type quotaError struct {
+	msg string
+}
+
+func (e *quotaError) Error() string {
+	return e.msg
+}
+
+// NewTenantManager returns a new TenantManager.
+func NewTenantManager() *TenantManager {
+	return &TenantManager{
+		tenants: map[string]*tenant{},
+	}
+}
+
+// WithMaxTenants sets the maximum number of tenants that can exist at the same
+// time. Zero means no limit.
+func (tm *TenantManager) WithMaxTenants(n int) *TenantManager {
+	tm.maxTenants = n
+	return tm
+}
+
+// Create creates a tenant with an empty store. The tenant inherits the
+// compiler and parser settings of the server the manager is attached to.
+func (tm *TenantManager) Create(ctx context.Context, id string, config TenantConfig) error {
+	if !tenantIDRegexp.MatchString(id) {
+		return types.BadRequestErr(fmt.Sprintf("invalid tenant id %q", id))
+	}
+
+	if config.MaxPolicies < 0 {
+		return types.BadRequestErr("tenant policy quota must not be negative")
+	}
+
+	tm.mtx.Lock()
+	defer tm.mtx.Unlock()
+
+	if tm.server == nil {
+		return errors.New("tenant manager is not attached to a server")
+	}
+
+	if _, ok := tm.tenants[id]; ok {
+		return ErrTenantExists
+	}
+
+	if tm.maxTenants > 0 && len(tm.tenants) >= tm.maxTenants {
+		return &quotaError{msg: fmt.Sprintf("tenant quota exceeded: at most %d tenants allowed", tm.maxTenants)}
+	}
+
+	t, err := tm.newTenant(ctx, id, config)
+	if err != nil {
+		return err
+	}
+
+	tm.tenants[id] = t
+	return nil
+}
+
+func (tm *TenantManager) newTenant(ctx context.Context, id string, config TenantConfig) (*tenant, error) {
+	parent := tm.server
+	store := inmem.New()
+
+	manager, err := plugins.New([]byte{}, id, store,
+		plugins.Logger(parent.manager.Logger()),
+		plugins.ConsoleLogger(parent.manager.ConsoleLogger()),
+		plugins.WithParserOptions(parent.manager.ParserOptions()))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := manager.Start(ctx); err != nil {
+		return nil, err
+	}
+
+	s := New().
+		WithStore(store).
+		WithManager(manager).
+		WithCompilerErrorLimit(parent.errLimit).
+		WithDecisionIDFactory(parent.decisionIDFactory).
+		WithRuntime(parent.runtime).
+		WithDistributedTracingOpts(parent.distributedTracingOpts)
+	s.maxPolicies = config.MaxPolicies
+
+	if err := s.init(ctx); err != nil {
+		manager.Stop(ctx)
+		return nil, err
+	}
+
+	return &tenant{
+		config:  config,
+		store:   store,
+		manager: manager,
+		handler: s.Handler,
+	}, nil
+}
+
+// Evict removes a tenant and stops its plugins. Requests to the tenant that
+// are in flight are allowed to complete.
+func (tm *TenantManager) Evict(ctx context.Context, id string) error {
+	tm.mtx.Lock()
+	t, ok := tm.tenants[id]
+	delete(tm.tenants, id)
+	tm.mtx.Unlock()
+
+	if !ok {
+		return ErrTenantNotFound
+	}
+
+	t.manager.Stop(ctx)
+	return nil
+}
+
+// Tenants returns the IDs of all tenants, sorted.
+func (tm *TenantManager) Tenants() []string {
+	tm.mtx.RLock()
+	defer tm.mtx.RUnlock()
+	return util.KeysSorted(tm.tenants)
+}
+
+// Store returns the store of a tenant.
+func (tm *TenantManager) Store(id string) (storage.Store, bool) {
+	t, ok := tm.get(id)
+	if !ok {
+		return nil, false
+	}
+	return t.store, true
+}
+
+// Stop evicts all tenants.
+func (tm *TenantManager) Stop(ctx context.Context) {
+	for _, id := range tm.Tenants() {
+		_ = tm.Evict(ctx, id)
+	}
+}
+
+func (tm *TenantManager) get(id string) (*tenant, bool) {
+	tm.mtx.RLock()
+	defer tm.mtx.RUnlock()
+	t, ok := tm.tenants[id]
+	return t, ok
+}
+
+// route dispatches requests carrying the tenant header or path prefix to the
+// handler of the tenant, and all other requests to next.
+func (tm *TenantManager) route(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(TenantHeader)
+		prefix := ""
+
+		if id == "" {
+			rest, ok := strings.CutPrefix(r.URL.Path, tenantPathPrefix)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			id, _, _ = strings.Cut(rest, "/")
+			prefix = tenantPathPrefix + id
+		}
+
+		t, ok := tm.get(id)
+		if !ok {
+			writer.ErrorString(w, http.StatusNotFound, types.CodeResourceNotFound, fmt.Errorf("tenant %q not found", id))
+			return
+		}
+
+		if prefix == "" {
+			t.handler.ServeHTTP(w, r)
+			return
+		}
+
+		http.StripPrefix(prefix, t.handler).ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) checkPolicyQuota(ctx context.Context, txn storage.Transaction) error {
+	if s.maxPolicies <= 0 {
+		return nil
+	}
+
+	ids, err := s.store.ListPolicies(ctx, txn)
+	if err != nil {
+		return err
+	}
+
+	if len(ids) >= s.maxPolicies {
+		return &quotaError{msg: fmt.Sprintf("policy quota exceeded: at most %d policies allowed", s.maxPolicies)}
+	}
+
+	return nil
+}
+
+func writeQuotaError(w http.ResponseWriter, err error) {
+	var qe *quotaError
+	if errors.As(err, &qe) {
+		writer.ErrorString(w, http.StatusForbidden, types.CodeInvalidOperation, err)
+		return
+	}
+	writer.ErrorAuto(w, err)
+}
+
+func (s *Server) v1TenantsList(w http.ResponseWriter, r *http.Request) {
+	result := []types.TenantV1{}
+	for _, id := range s.tenants.Tenants() {
+		if t, ok := s.tenants.get(id); ok {
+			result = append(result, types.TenantV1{ID: id, MaxPolicies: t.config.MaxPolicies})
+		}
+	}
+
+	writer.JSONOK(w, types.TenantListResponseV1{Result: result}, pretty(r))
+}
+
+func (s *Server) v1TenantsPut(w http.ResponseWriter, r *http.Request) {
+	var config TenantConfig
+
+	if r.ContentLength != 0 {
+		if err := util.NewJSONDecoder(r.Body).Decode(&config); err != nil {
+			writer.ErrorString(w, http.StatusBadRequest, types.CodeInvalidParameter, err)
+			return
+		}
+	}
+
+	err := s.tenants.Create(r.Context(), r.PathValue("id"), config)
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusNoContent)
+	case errors.Is(err, ErrTenantExists):
+		writer.ErrorString(w, http.StatusConflict, types.CodeResourceConflict, err)
+	default:
+		writeQuotaError(w, err)
+	}
+}
+
+func (s *Server) v1TenantsDelete(w http.ResponseWriter, r *http.Request) {
+	if err := s.tenants.Evict(r.Context(), r.PathValue("id")); err != nil {
+		writer.ErrorString(w, http.StatusNotFound, types.CodeResourceNotFound, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
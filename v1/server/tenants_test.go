@@ -0,0 +1,102 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestTenants(t *testing.T) {
+	t.Parallel()
+
+	tenants := NewTenantManager().WithMaxTenants(2)
+	f := newFixture(t, func(s *Server) {
+		s.WithTenantManager(tenants)
+	})
+
+	if err := f.v1TestRequests([]tr{
+		{http.MethodPut, "/tenants/acme", `{"max_policies": 1}`, 204, ""},
+		{http.MethodPut, "/tenants/globex", "", 204, ""},
+		{http.MethodPut, "/tenants/acme", "", 409, ""},
+		{http.MethodPut, "/tenants/initech", "", 403, ""},
+		{http.MethodPut, "/tenants/-bad", "", 400, ""},
+		{http.MethodGet, "/tenants", "", 200, `{"result": [{"id": "acme", "max_policies": 1}, {"id": "globex"}]}`},
+		{http.MethodPut, "/data/x", `"default"`, 204, ""},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tenantReq := func(id, method, path, body string, code int, resp string) {
+		t.Helper()
+
+		req := newReqV1(method, path, body)
+		req.Header.Set(TenantHeader, id)
+		if err := f.executeRequest(req, code, resp); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Tenants are isolated from each other, and from the default store.
+	tenantReq("acme", http.MethodPut, "/policies/p", "package p\nallow := data.x == \"acme\"", 200, "")
+	tenantReq("acme", http.MethodPut, "/data/x", `"acme"`, 204, "")
+	tenantReq("acme", http.MethodGet, "/data/p/allow", "", 200, `{"result": true}`)
+	tenantReq("globex", http.MethodGet, "/data/p/allow", "", 200, `{}`)
+	tenantReq("globex", http.MethodGet, "/data/x", "", 200, `{}`)
+
+	if err := f.v1(http.MethodGet, "/data/x", "", 200, `{"result": "default"}`); err != nil {
+		t.Fatal(err)
+	}
+
+	// Requests can also be routed by path prefix.
+	if err := f.executeRequest(newReqUnversioned(http.MethodGet, "/tenants/acme/v1/data/x", ""), 200, `{"result": "acme"}`); err != nil {
+		t.Fatal(err)
+	}
+
+	// Updating a policy does not count against the quota, adding one does.
+	tenantReq("acme", http.MethodPut, "/policies/p", "package p\nallow := true", 200, "")
+	tenantReq("acme", http.MethodPut, "/policies/q", "package q", 403, "")
+	tenantReq("globex", http.MethodPut, "/policies/q", "package q", 200, "")
+
+	if err := f.v1(http.MethodDelete, "/tenants/acme", "", 204, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	tenantReq("acme", http.MethodGet, "/data/x", "", 404, "")
+
+	if err := f.executeRequest(newReqUnversioned(http.MethodGet, "/tenants/acme/v1/data/x", ""), 404, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.v1(http.MethodDelete, "/tenants/acme", "", 404, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// Eviction frees up quota for new tenants.
+	if err := tenants.Create(context.Background(), "initech", TenantConfig{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := tenants.Store("initech"); !ok {
+		t.Fatal("expected store for tenant")
+	}
+}
+
+func TestTenantsDisabled(t *testing.T) {
+	t.Parallel()
+
+	f := newFixture(t)
+
+	if err := f.v1(http.MethodGet, "/tenants", "", 404, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	req := newReqV1(http.MethodGet, "/data", "")
+	req.Header.Set(TenantHeader, "acme")
+	if err := f.executeRequest(req, 200, `{"result": {}}`); err != nil {
+		t.Fatal(err)
+	}
+}
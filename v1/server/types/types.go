@@ -26,6 +26,7 @@ const (
 	CodeResourceNotFound  = "resource_not_found"
 	CodeResourceConflict  = "resource_conflict"
 	CodeUndefinedDocument = "undefined_document"
+	CodeTooManyRequests   = "too_many_requests"
 )
 
 // ErrorV1 models an error response sent to the client.
@@ -83,6 +84,7 @@ const (
 	MsgPluginConfigError          = "error(s) occurred while configuring plugin(s)"
 	MsgDecodingLimitError         = "request body too large"
 	MsgDecodingGzipLimitError     = "compressed request body too large"
+	MsgTooManyRequestsError       = "request rate limit exceeded"
 )
 
 // PatchV1 models a single patch operation against a document.
@@ -92,6 +94,17 @@ type PatchV1 struct {
 	Value any    `json:"value"`
 }
 
+// DataBatchOperationV1 models a single write of a bulk data write. Op is one
+// of "put", "patch" and "delete", which write to the document at Path like the
+// PUT, PATCH and DELETE methods of the Data API, respectively. Value is the
+// document written by "put", Patch the operations applied by "patch".
+type DataBatchOperationV1 struct {
+	Op    string    `json:"op"`
+	Path  string    `json:"path"`
+	Value any       `json:"value,omitempty"`
+	Patch []PatchV1 `json:"patch,omitempty"`
+}
+
 // PolicyListResponseV1 models the response message for the Policy API list operation.
 type PolicyListResponseV1 struct {
 	Result []PolicyV1 `json:"result"`
@@ -112,6 +125,17 @@ type PolicyDeleteResponseV1 struct {
 	Metrics MetricsV1 `json:"metrics,omitempty"`
 }
 
+// TenantListResponseV1 models the response message for the Tenant API list operation.
+type TenantListResponseV1 struct {
+	Result []TenantV1 `json:"result"`
+}
+
+// TenantV1 models a tenant managed by the server.
+type TenantV1 struct {
+	ID          string `json:"id"`
+	MaxPolicies int    `json:"max_policies,omitempty"`
+}
+
 // PolicyV1 models a policy module in OPA.
 type PolicyV1 struct {
 	ID  string      `json:"id"`
@@ -372,6 +396,7 @@ func NewBindingsV1(locals *ast.ValueMap) (result []*BindingV1) {
 type CompileRequestV1 struct {
 	Input    *any      `json:"input"`
 	Query    string    `json:"query"`
+	Queries  []string  `json:"queries,omitempty"`
 	Unknowns *[]string `json:"unknowns"`
 	Options  struct {
 		DisableInlining          []string `json:"disableInlining,omitempty"`
@@ -414,6 +439,20 @@ type HealthResponseV1 struct {
 	Error string `json:"error,omitempty"`
 }
 
+// REPLRequestV1 models the messages sent by clients of the REPL API. Each
+// message holds one line of input.
+type REPLRequestV1 struct {
+	Line string `json:"line"`
+}
+
+// REPLResponseV1 models the messages sent to clients of the REPL API: one
+// when the session starts and one for every line of input.
+type REPLResponseV1 struct {
+	Output string `json:"output"`
+	Error  string `json:"error,omitempty"`
+	Prompt string `json:"prompt"`
+}
+
 const (
 	// ParamQueryV1 defines the name of the HTTP URL parameter that specifies
 	// values for the request query.
@@ -466,6 +505,16 @@ const (
 	// of the health API for the specified plugin(s)
 	ParamExcludePluginV1 = "exclude-plugin"
 
+	// ParamFieldsV1 defines the name of the HTTP URL parameter that specifies
+	// the comma-separated, dot-separated paths of the result to include in the
+	// response of the data API.
+	ParamFieldsV1 = "fields"
+
+	// ParamExcludeV1 defines the name of the HTTP URL parameter that specifies
+	// the comma-separated, dot-separated paths of the result to omit from the
+	// response of the data API.
+	ParamExcludeV1 = "exclude"
+
 	// ParamStrictBuiltinErrors names the HTTP URL parameter that indicates the client
 	// wants built-in function errors to be treated as fatal.
 	ParamStrictBuiltinErrors = "strict-builtin-errors"
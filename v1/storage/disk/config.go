@@ -5,6 +5,7 @@
 package disk
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -20,10 +21,13 @@ type cfg struct {
 	AutoCreate bool     `json:"auto_create"`
 	Partitions []string `json:"partitions"`
 	Badger     string   `json:"badger"`
+	Encryption *storage.EncryptionConfig `json:"encryption"`
 }
 
 var ErrInvalidPartitionPath = errors.New("invalid storage path")
 
+var ErrInvalidEncryptionConfig = errors.New("invalid encryption config")
+
 // OptionsFromConfig parses the passed config, extracts the disk storage
 // settings, validates it, and returns a *Options struct pointer on success.
 func OptionsFromConfig(raw []byte, id string) (*Options, error) {
@@ -62,6 +66,13 @@ func OptionsFromConfig(raw []byte, id string) (*Options, error) {
 		opts.Partitions = append(opts.Partitions, p)
 	}
 
+	if c.Encryption != nil {
+		opts.KeyProvider, err = c.Encryption.KeyProvider()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidEncryptionConfig, err)
+		}
+	}
+
 	return &opts, nil
 }
 
@@ -80,3 +91,26 @@ func badgerConfigFromOptions(opts Options) (badger.Options, error) {
 			WithDetectConflicts(false), // We only allow one write txn at a time; so conflicts cannot happen.
 		nil
 }
+
+// encryptedIndexCacheSize is the size of the index cache of encrypted stores,
+// unless configured otherwise.
+const encryptedIndexCacheSize = 64 << 20
+
+// withEncryption enables the encryption of the badger options with the key of
+// kp. Badger caches decrypted blocks and table indexes, and requires both
+// caches when encryption is enabled.
+func withEncryption(ctx context.Context, options badger.Options, kp storage.KeyProvider) (badger.Options, error) {
+	key, err := storage.EncryptionKey(ctx, kp)
+	if err != nil {
+		return options, err
+	}
+
+	if options.BlockCacheSize == 0 {
+		return options, errors.New("encryption requires a block cache, blockcachesize must not be 0")
+	}
+	if options.IndexCacheSize == 0 {
+		options = options.WithIndexCacheSize(encryptedIndexCacheSize)
+	}
+
+	return options.WithEncryptionKey(key), nil
+}
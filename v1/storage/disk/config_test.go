@@ -19,6 +19,10 @@ func TestNewFromConfig(t *testing.T) {
 	t.Parallel()
 
 	tmpdir := t.TempDir()
+	keyFile := filepath.Join(tmpdir, "key")
+	if err := os.WriteFile(keyFile, []byte("0123456789abcdef"), 0o600); err != nil {
+		t.Fatal(err)
+	}
 
 	for _, tc := range []struct {
 		note    string
@@ -89,6 +93,70 @@ storage:
     directory: "` + tmpdir + `"
 `,
 		},
+		{
+			note: "encryption key",
+			config: `
+storage:
+  disk:
+    directory: "` + tmpdir + `"
+    encryption:
+      key: MDEyMzQ1Njc4OWFiY2RlZg==
+`,
+		},
+		{
+			note: "encryption key file",
+			config: `
+storage:
+  disk:
+    directory: "` + tmpdir + `"
+    encryption:
+      key_file: "` + keyFile + `"
+`,
+		},
+		{
+			note: "encryption key not base64",
+			config: `
+storage:
+  disk:
+    directory: "` + tmpdir + `"
+    encryption:
+      key: "not base64"
+`,
+			err: ErrInvalidEncryptionConfig,
+		},
+		{
+			note: "encryption key file does not exist",
+			config: `
+storage:
+  disk:
+    directory: "` + tmpdir + `"
+    encryption:
+      key_file: "` + tmpdir + `/nokey"
+`,
+			err: os.ErrNotExist,
+		},
+		{
+			note: "encryption key and key file",
+			config: `
+storage:
+  disk:
+    directory: "` + tmpdir + `"
+    encryption:
+      key: MDEyMzQ1Njc4OWFiY2RlZg==
+      key_file: "` + keyFile + `"
+`,
+			err: ErrInvalidEncryptionConfig,
+		},
+		{
+			note: "encryption without key",
+			config: `
+storage:
+  disk:
+    directory: "` + tmpdir + `"
+    encryption: {}
+`,
+			err: ErrInvalidEncryptionConfig,
+		},
 	} {
 		t.Run(tc.note, func(t *testing.T) {
 			d, err := OptionsFromConfig([]byte(tc.config), "id")
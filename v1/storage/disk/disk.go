@@ -92,6 +92,11 @@ type Options struct {
 	Dir        string         // specifies directory to store data inside of
 	Partitions []storage.Path // data prefixes that enable efficient layout
 	Badger     string         // badger-internal configurables
+
+	// KeyProvider, if set, provides the key that encrypts the data and policies
+	// at rest. Once created with a key, the store can only be opened with the
+	// same key.
+	KeyProvider storage.KeyProvider
 }
 
 // Store provides a disk-based implementation of the storage.Store interface.
@@ -161,6 +166,13 @@ func New(ctx context.Context, logger logging.Logger, prom prometheus.Registerer,
 		return nil, wrapError(err)
 	}
 
+	if opts.KeyProvider != nil {
+		options, err = withEncryption(ctx, options, opts.KeyProvider)
+		if err != nil {
+			return nil, wrapError(err)
+		}
+	}
+
 	options = options.WithLogger(&wrap{logger})
 	db, err := badger.Open(options)
 	if err != nil {
@@ -488,7 +500,9 @@ func (db *Store) Commit(ctx context.Context, txn storage.Transaction) error {
 		xid := atomic.AddUint64(&db.xid, uint64(1))
 		readTxn := newTransaction(xid, write, readOnly, nil, db.pm, db.partitions, db)
 		for h := range db.triggers {
-			h.cb(ctx, readTxn, event)
+			if e, ok := h.config.Filter(event); ok {
+				h.config.OnCommit(ctx, readTxn, e)
+			}
 		}
 
 		// cleanup backup db
@@ -601,7 +615,7 @@ func (db *Store) Register(_ context.Context, txn storage.Transaction, config sto
 			Message: "triggers must be registered with a write transaction",
 		}
 	}
-	h := &handle{db: db, cb: config.OnCommit}
+	h := &handle{db: db, config: config}
 	db.triggers[h] = struct{}{}
 	return h, nil
 }
@@ -652,8 +666,8 @@ func (db *Store) underlying(txn storage.Transaction) (*transaction, error) {
 }
 
 type handle struct {
-	db *Store
-	cb func(context.Context, storage.Transaction, storage.TriggerEvent)
+	db     *Store
+	config storage.TriggerConfig
 }
 
 func (h *handle) Unregister(_ context.Context, txn storage.Transaction) {
@@ -8,7 +8,9 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -170,6 +172,77 @@ func TestPolicies(t *testing.T) {
 	})
 }
 
+func TestEncryption(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	key := storage.StaticKey([]byte("0123456789abcdef0123456789abcdef"))
+
+	s, err := New(ctx, logging.NewNoOpLogger(), nil, Options{Dir: dir, KeyProvider: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = storage.Txn(ctx, s, storage.WriteParams, func(txn storage.Transaction) error {
+		if err := s.Write(ctx, txn, storage.AddOp, storage.MustParsePath("/foo"), map[string]any{"secret": "plaintext-data"}); err != nil {
+			return err
+		}
+		return s.UpsertPolicy(ctx, txn, "foo.rego", []byte(`package plaintext_policy`))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		bs, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for _, plain := range []string{"plaintext-data", "plaintext_policy"} {
+			if bytes.Contains(bs, []byte(plain)) {
+				t.Errorf("expected %v to be encrypted, found %q", path, plain)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, kp := range []storage.KeyProvider{
+		nil,
+		storage.StaticKey([]byte("fedcba9876543210fedcba9876543210")),
+		storage.KeyProviderFunc(func(context.Context) ([]byte, error) { return nil, errors.New("kms unavailable") }),
+		storage.StaticKey([]byte("short")),
+	} {
+		if s, err := New(ctx, logging.NewNoOpLogger(), nil, Options{Dir: dir, KeyProvider: kp}); err == nil {
+			s.Close(ctx)
+			t.Fatal("expected error opening the store without its key")
+		}
+	}
+
+	s, err = New(ctx, logging.NewNoOpLogger(), nil, Options{Dir: dir, KeyProvider: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close(ctx)
+
+	act, err := storage.ReadOne(ctx, s, storage.MustParsePath("/foo/secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if act != "plaintext-data" {
+		t.Fatalf("expected plaintext-data, got %v", act)
+	}
+}
+
 func TestTruncateAbsoluteStoragePath(t *testing.T) {
 	t.Parallel()
 
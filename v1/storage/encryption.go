@@ -0,0 +1,88 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// KeyProvider provides the key that encrypts data at rest, e.g., the data of
+// the disk store and the bundles persisted to disk. Keys are AES keys, i.e.,
+// they are 16, 24, or 32 bytes long.
+type KeyProvider interface {
+	Key(ctx context.Context) ([]byte, error)
+}
+
+// KeyProviderFunc adapts a function, e.g., one that fetches the key from a key
+// management service, to a KeyProvider.
+type KeyProviderFunc func(ctx context.Context) ([]byte, error)
+
+// Key implements the KeyProvider interface.
+func (f KeyProviderFunc) Key(ctx context.Context) ([]byte, error) {
+	return f(ctx)
+}
+
+type staticKey []byte
+
+func (k staticKey) Key(context.Context) ([]byte, error) {
+	return k, nil
+}
+
+// StaticKey returns a KeyProvider that always provides key.
+func StaticKey(key []byte) KeyProvider {
+	return staticKey(key)
+}
+
+// EncryptionKey returns the key of the provider, or an error if the provider
+// fails or the key is not a valid AES key.
+func EncryptionKey(ctx context.Context, kp KeyProvider) ([]byte, error) {
+	key, err := kp.Key(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("encryption key: %w", err)
+	}
+
+	switch len(key) {
+	case 16, 24, 32:
+		return key, nil
+	}
+	return nil, fmt.Errorf("encryption key: invalid length %d, must be 16, 24, or 32 bytes", len(key))
+}
+
+// EncryptionConfig represents the configuration of an encryption key, either
+// inline and base64-encoded, e.g., from an environment variable, or in a file
+// containing the raw key.
+type EncryptionConfig struct {
+	Key     string `json:"key"`
+	KeyFile string `json:"key_file"`
+}
+
+// KeyProvider returns a KeyProvider of the configured key.
+func (c *EncryptionConfig) KeyProvider() (KeyProvider, error) {
+	var key []byte
+	var err error
+
+	switch {
+	case c.Key != "" && c.KeyFile != "":
+		return nil, errors.New("only one of key and key_file may be set")
+	case c.Key != "":
+		key, err = base64.StdEncoding.DecodeString(c.Key)
+		if err != nil {
+			return nil, fmt.Errorf("key: %w", err)
+		}
+	case c.KeyFile != "":
+		key, err = os.ReadFile(c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("key_file: %w", err)
+		}
+	default:
+		return nil, errors.New("key or key_file must be set")
+	}
+
+	return StaticKey(key), nil
+}
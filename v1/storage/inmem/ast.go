@@ -29,11 +29,11 @@ func (u *updateAST) Remove() bool {
 }
 
 func (u *updateAST) Set(v any) {
-	if v, ok := v.(ast.Value); ok {
-		u.value = v
-	} else {
-		panic("illegal value type") // FIXME: do conversion?
+	value, err := interfaceToValue(v)
+	if err != nil {
+		panic(err)
 	}
+	u.value = value
 }
 
 func (u *updateAST) Value() any {
@@ -376,7 +376,9 @@ func (db *store) runOnCommitTriggers(ctx context.Context, txn storage.Transactio
 	}
 
 	for _, t := range db.triggers {
-		t.OnCommit(ctx, txn, event)
+		if e, ok := t.Filter(event); ok {
+			t.OnCommit(ctx, txn, e)
+		}
 	}
 }
 
@@ -1350,3 +1350,49 @@ func TestOptRoundTripOnWrite(t *testing.T) {
 		})
 	}
 }
+
+func TestInMemoryTriggersFilter(t *testing.T) {
+	ctx := context.Background()
+	store := NewFromObject(loadSmallTestData())
+	writeTxn := storage.NewTransactionOrDie(ctx, store, storage.WriteParams)
+
+	var events []storage.TriggerEvent
+	_, err := store.Register(ctx, writeTxn, storage.TriggerConfig{
+		OnCommit: func(_ context.Context, _ storage.Transaction, evt storage.TriggerEvent) {
+			events = append(events, evt)
+		},
+		Kinds: storage.TriggerData,
+		Paths: []storage.Path{storage.MustParsePath("/a")},
+	})
+	if err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+	if err := store.Commit(ctx, writeTxn); err != nil {
+		t.Fatalf("Unexpected commit error: %v", err)
+	}
+
+	writes := []struct {
+		path string
+		data any
+	}{
+		{"/b", "ignored"},
+		{"/a", "hello"},
+	}
+
+	for _, w := range writes {
+		writeTxn := storage.NewTransactionOrDie(ctx, store, storage.WriteParams)
+		if err := store.Write(ctx, writeTxn, storage.AddOp, storage.MustParsePath(w.path), w.data); err != nil {
+			t.Fatalf("Unexpected write error: %v", err)
+		}
+		if err := store.UpsertPolicy(ctx, writeTxn, "test", []byte("package abc")); err != nil {
+			t.Fatalf("Unexpected upsert error: %v", err)
+		}
+		if err := store.Commit(ctx, writeTxn); err != nil {
+			t.Fatalf("Unexpected commit error: %v", err)
+		}
+	}
+
+	if len(events) != 1 || events[0].PolicyChanged() || len(events[0].Data) != 1 || !events[0].Data[0].Path.Equal(storage.MustParsePath("/a")) {
+		t.Fatalf("Expected single data event at /a but got: %v", events)
+	}
+}
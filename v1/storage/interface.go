@@ -208,6 +208,17 @@ func (e TriggerEvent) DataChanged() bool {
 	return len(e.Data) > 0
 }
 
+// TriggerKind identifies the kinds of changes a trigger is invoked for.
+type TriggerKind int
+
+const (
+	// TriggerPolicy selects policy changes.
+	TriggerPolicy TriggerKind = 1 << iota
+
+	// TriggerData selects base data document changes.
+	TriggerData
+)
+
 // TriggerConfig contains the trigger registration configuration.
 type TriggerConfig struct {
 
@@ -215,6 +226,56 @@ type TriggerConfig struct {
 	// callback is invoked with a handle to the write transaction that
 	// successfully committed before other clients see the changes.
 	OnCommit func(context.Context, Transaction, TriggerEvent)
+
+	// Kinds restricts the trigger to the given kinds of changes, e.g.,
+	// TriggerData. If zero, the trigger is invoked for all kinds of changes.
+	Kinds TriggerKind
+
+	// Paths restricts the trigger to data changes affecting the documents
+	// under any of the given paths: changes to documents under a path, and
+	// changes to documents containing a path. If empty, the trigger is invoked
+	// for data changes at any path.
+	Paths []Path
+}
+
+// Filter returns the event restricted to the changes selected by the trigger
+// configuration, and whether the trigger should be invoked for it. Triggers
+// without Kinds and Paths are invoked for every event, including events
+// without changes; other triggers are only invoked if a selected change
+// remains. Stores supporting triggers call Filter before invoking OnCommit.
+func (c TriggerConfig) Filter(event TriggerEvent) (TriggerEvent, bool) {
+	if c.Kinds == 0 && len(c.Paths) == 0 {
+		return event, true
+	}
+
+	filtered := TriggerEvent{Context: event.Context}
+
+	if c.Kinds == 0 || c.Kinds&TriggerPolicy != 0 {
+		filtered.Policy = event.Policy
+	}
+
+	if c.Kinds == 0 || c.Kinds&TriggerData != 0 {
+		if len(c.Paths) == 0 {
+			filtered.Data = event.Data
+		} else {
+			for _, e := range event.Data {
+				if c.matchesPath(e.Path) {
+					filtered.Data = append(filtered.Data, e)
+				}
+			}
+		}
+	}
+
+	return filtered, !filtered.IsZero()
+}
+
+func (c TriggerConfig) matchesPath(path Path) bool {
+	for _, p := range c.Paths {
+		if path.HasPrefix(p) || p.HasPrefix(path) {
+			return true
+		}
+	}
+	return false
 }
 
 // Trigger defines the interface that stores implement to register for change
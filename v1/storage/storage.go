@@ -34,6 +34,35 @@ func ReadOne(ctx context.Context, store Store, path Path) (any, error) {
 	return store.Read(ctx, txn, path)
 }
 
+// ReadValue reads the document at path as an AST value. Values of stores that
+// return AST values on read, like the inmem store created with
+// OptReturnASTValuesOnRead, are returned without conversion.
+func ReadValue(ctx context.Context, store Store, txn Transaction, path Path) (ast.Value, error) {
+	v, err := store.Read(ctx, txn, path)
+	if err != nil {
+		return nil, err
+	}
+	if v, ok := v.(ast.Value); ok {
+		return v, nil
+	}
+	return ast.InterfaceToValue(v)
+}
+
+// ReadJSON reads the document at path as a JSON-compatible Go value, e.g., a
+// map[string]any, converting AST values returned by the store. Callers that
+// type-check the documents they read should use ReadJSON, so that they work
+// regardless of the value representation used by the store.
+func ReadJSON(ctx context.Context, store Store, txn Transaction, path Path) (any, error) {
+	v, err := store.Read(ctx, txn, path)
+	if err != nil {
+		return nil, err
+	}
+	if v, ok := v.(ast.Value); ok {
+		return ast.JSON(v)
+	}
+	return v, nil
+}
+
 // WriteOne is a convenience function to write a single value to the provided Store. It
 // will create a new Transaction to perform the write with, and clean up after itself
 // should an error occur.
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/open-policy-agent/opa/v1/ast"
 	"github.com/open-policy-agent/opa/v1/storage"
 	"github.com/open-policy-agent/opa/v1/storage/inmem"
 )
@@ -102,3 +103,110 @@ func TestNonEmptyer(t *testing.T) {
 		}
 	}
 }
+
+func TestTriggerConfigFilter(t *testing.T) {
+	event := storage.TriggerEvent{
+		Policy: []storage.PolicyEvent{{ID: "x.rego"}},
+		Data: []storage.DataEvent{
+			{Path: storage.MustParsePath("/a/b/c")},
+			{Path: storage.MustParsePath("/a")},
+			{Path: storage.MustParsePath("/d/e")},
+		},
+	}
+
+	cases := []struct {
+		note   string
+		config storage.TriggerConfig
+		policy int
+		data   []string
+	}{
+		{
+			note:   "no filter",
+			policy: 1,
+			data:   []string{"/a/b/c", "/a", "/d/e"},
+		},
+		{
+			note:   "policy only",
+			config: storage.TriggerConfig{Kinds: storage.TriggerPolicy},
+			policy: 1,
+		},
+		{
+			note:   "data only",
+			config: storage.TriggerConfig{Kinds: storage.TriggerData},
+			data:   []string{"/a/b/c", "/a", "/d/e"},
+		},
+		{
+			note:   "paths",
+			config: storage.TriggerConfig{Paths: []storage.Path{storage.MustParsePath("/a/b")}},
+			policy: 1,
+			data:   []string{"/a/b/c", "/a"},
+		},
+		{
+			note:   "data under paths",
+			config: storage.TriggerConfig{Kinds: storage.TriggerData, Paths: []storage.Path{storage.MustParsePath("/d"), storage.MustParsePath("/x")}},
+			data:   []string{"/d/e"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.note, func(t *testing.T) {
+			filtered, ok := tc.config.Filter(event)
+			if !ok {
+				t.Fatal("expected trigger to be invoked")
+			}
+			if len(filtered.Policy) != tc.policy {
+				t.Fatalf("expected %d policy events but got %v", tc.policy, filtered.Policy)
+			}
+			if len(filtered.Data) != len(tc.data) {
+				t.Fatalf("expected data events at %v but got %v", tc.data, filtered.Data)
+			}
+			for i := range tc.data {
+				if filtered.Data[i].Path.String() != tc.data[i] {
+					t.Fatalf("expected data events at %v but got %v", tc.data, filtered.Data)
+				}
+			}
+		})
+	}
+
+	config := storage.TriggerConfig{Kinds: storage.TriggerData, Paths: []storage.Path{storage.MustParsePath("/x")}}
+	if _, ok := config.Filter(event); ok {
+		t.Fatal("expected trigger not to be invoked")
+	}
+
+	// Unfiltered triggers are invoked on every commit.
+	if _, ok := (storage.TriggerConfig{}).Filter(storage.TriggerEvent{}); !ok {
+		t.Fatal("expected trigger to be invoked")
+	}
+}
+
+func TestReadJSONAndValue(t *testing.T) {
+	ctx := context.Background()
+	data := map[string]any{"a": map[string]any{"b": []any{"c"}}}
+
+	for _, readAST := range []bool{false, true} {
+		store := inmem.NewFromObjectWithOpts(data, inmem.OptReturnASTValuesOnRead(readAST))
+		txn := storage.NewTransactionOrDie(ctx, store)
+
+		v, err := storage.ReadJSON(ctx, store, txn, storage.MustParsePath("/a"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b, ok := v.(map[string]any)["b"].([]any); !ok || len(b) != 1 || b[0] != "c" {
+			t.Fatalf("read AST %v: expected JSON value but got %#v", readAST, v)
+		}
+
+		value, err := storage.ReadValue(ctx, store, txn, storage.MustParsePath("/a/b"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value.Compare(ast.NewArray(ast.StringTerm("c"))) != 0 {
+			t.Fatalf("read AST %v: expected AST value but got %v", readAST, value)
+		}
+
+		if _, err := storage.ReadJSON(ctx, store, txn, storage.MustParsePath("/x")); !storage.IsNotFound(err) {
+			t.Fatalf("read AST %v: expected not found error but got %v", readAST, err)
+		}
+
+		store.Abort(ctx, txn)
+	}
+}
@@ -11,7 +11,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/fs"
 	"maps"
+	"os"
+	"path/filepath"
 	"regexp"
 	"runtime"
 	"slices"
@@ -20,6 +23,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/sergi/go-diff/diffmatchpatch"
+
 	wasm_errors "github.com/open-policy-agent/opa/internal/wasm/sdk/opa/errors"
 	"github.com/open-policy-agent/opa/v1/ast"
 	"github.com/open-policy-agent/opa/v1/bundle"
@@ -266,6 +271,8 @@ type Runner struct {
 	customBuiltins        []*Builtin
 	defaultRegoVersion    ast.RegoVersion
 	parallel              int
+	clock                 time.Time
+	updateSnapshots       bool
 }
 
 // NewRunner returns a new runner.
@@ -378,6 +385,40 @@ func (r *Runner) SetTimeout(timout time.Duration) *Runner {
 	return r
 }
 
+// SetClock freezes the wall clock time observed by tests (e.g., through
+// time.now_ns()) at t. Tests annotated with a `test.clock` custom annotation
+// use the annotated time instead:
+//
+//	# METADATA
+//	# custom:
+//	#   test:
+//	#     clock: "2024-01-01T00:00:00Z"
+//	test_token_expired if { ... }
+//
+// The annotated value is either an RFC3339 timestamp or the number of
+// nanoseconds since the Unix epoch.
+func (r *Runner) SetClock(t time.Time) *Runner {
+	r.clock = t
+	return r
+}
+
+// SetUpdateSnapshots toggles the update mode of snapshot tests. Snapshot tests
+// are annotated with a `test.snapshot` custom annotation naming a golden JSON
+// file, relative to the file of the test:
+//
+//	# METADATA
+//	# custom:
+//	#   test:
+//	#     snapshot: testdata/report.json
+//	test_report := report
+//
+// Snapshot tests pass if the value of the test rule equals the contents of the
+// golden file. In update mode, the golden files are written instead.
+func (r *Runner) SetUpdateSnapshots(yes bool) *Runner {
+	r.updateSnapshots = yes
+	return r
+}
+
 // SetModules will add modules to the Runner which will be compiled then used
 // for discovering and evaluating tests.
 func (r *Runner) SetModules(modules map[string]*ast.Module) *Runner {
@@ -911,6 +952,20 @@ func (r *Runner) runTest(ctx context.Context, txn storage.Transaction, mod *ast.
 		bufferTracer = &t.BufferTracer
 	}
 
+	clock, err := r.testClock(rule)
+	if err != nil {
+		tr := newResult(rule.Loc(), mod.Package.Path.String(), ruleRef.String(), 0*time.Second, nil, nil)
+		tr.Error = err
+		return tr, false
+	}
+
+	snapshot, err := r.testSnapshot(rule)
+	if err != nil {
+		tr := newResult(rule.Loc(), mod.Package.Path.String(), ruleRef.String(), 0*time.Second, nil, nil)
+		tr.Error = err
+		return tr, false
+	}
+
 	printbuf := bytes.NewBuffer(nil)
 	var builtinErrors []topdown.Error
 	queryPath := rule.Module.Package.Path.Extend(ruleRef)
@@ -924,6 +979,7 @@ func (r *Runner) runTest(ctx context.Context, txn storage.Transaction, mod *ast.
 		rego.Target(r.target),
 		rego.PrintHook(topdown.NewPrintHook(printbuf)),
 		rego.BuiltinErrorList(&builtinErrors),
+		rego.Time(clock),
 	}
 
 	for _, t := range tracers {
@@ -968,6 +1024,8 @@ func (r *Runner) runTest(ctx context.Context, txn storage.Transaction, mod *ast.
 		}
 	} else if len(rs) == 0 {
 		tr.Fail = true
+	} else if snapshot != "" {
+		r.checkSnapshot(tr, snapshot, rs[0].Expressions[0].Value)
 	} else if rule.Head.DocKind() == ast.PartialObjectDoc {
 		tr.Fail, tr.SubResults = subResults(rs[0].Expressions[0].Value, trace)
 	} else if b, ok := rs[0].Expressions[0].Value.(bool); !ok || !b {
@@ -977,6 +1035,176 @@ func (r *Runner) runTest(ctx context.Context, txn storage.Transaction, mod *ast.
 	return tr, stop
 }
 
+// testClock returns the wall clock time to evaluate the test rule with. The
+// closest `test.clock` custom annotation wins over the runner's clock. A zero
+// time means the actual wall clock time is used.
+func (r *Runner) testClock(rule *ast.Rule) (time.Time, error) {
+	as := r.compiler.GetAnnotationSet()
+	if as == nil {
+		return r.clock, nil
+	}
+
+	for _, ref := range as.Chain(rule) {
+		if ref.Annotations == nil {
+			continue
+		}
+		test, ok := ref.Annotations.Custom["test"].(map[string]any)
+		if !ok {
+			continue
+		}
+		v, ok := test["clock"]
+		if !ok {
+			continue
+		}
+		t, err := parseTestClock(v)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%v: invalid test.clock annotation: %w", ref.Annotations.Location, err)
+		}
+		return t, nil
+	}
+
+	return r.clock, nil
+}
+
+// testSnapshot returns the path of the golden file of the test rule, from the
+// `test.snapshot` custom annotation of the rule, or an empty string if the
+// rule is not a snapshot test.
+func (r *Runner) testSnapshot(rule *ast.Rule) (string, error) {
+	as := r.compiler.GetAnnotationSet()
+	if as == nil {
+		return "", nil
+	}
+
+	for _, ref := range as.Chain(rule) {
+		if ref.Annotations == nil || (ref.Annotations.Scope != "rule" && ref.Annotations.Scope != "document") {
+			continue
+		}
+		test, ok := ref.Annotations.Custom["test"].(map[string]any)
+		if !ok {
+			continue
+		}
+		v, ok := test["snapshot"]
+		if !ok {
+			continue
+		}
+		path, ok := v.(string)
+		if !ok || path == "" {
+			return "", fmt.Errorf("%v: invalid test.snapshot annotation: expected file path", ref.Annotations.Location)
+		}
+		if !filepath.IsAbs(path) && rule.Location != nil && rule.Location.File != "" {
+			path = filepath.Join(filepath.Dir(rule.Location.File), path)
+		}
+		return path, nil
+	}
+
+	return "", nil
+}
+
+// checkSnapshot compares the value of a snapshot test with its golden file, or
+// writes the golden file in update mode. Mismatches are reported as a diff in
+// the output of the test.
+func (r *Runner) checkSnapshot(tr *Result, path string, value any) {
+	if r.updateSnapshots {
+		bs, err := json.MarshalIndent(value, "", "  ")
+		if err == nil {
+			err = os.MkdirAll(filepath.Dir(path), 0o755)
+		}
+		if err == nil {
+			err = os.WriteFile(path, append(bs, '\n'), 0o644)
+		}
+		if err != nil {
+			tr.Error = fmt.Errorf("update snapshot: %w", err)
+		}
+		return
+	}
+
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			err = fmt.Errorf("snapshot %v does not exist, run with --update-snapshots to create it", path)
+		}
+		tr.Error = err
+		return
+	}
+
+	var golden any
+	if err := util.UnmarshalJSON(bs, &golden); err != nil {
+		tr.Error = fmt.Errorf("snapshot %v: %w", path, err)
+		return
+	}
+
+	exp, err := ast.InterfaceToValue(golden)
+	if err != nil {
+		tr.Error = fmt.Errorf("snapshot %v: %w", path, err)
+		return
+	}
+	actual, err := ast.InterfaceToValue(value)
+	if err != nil {
+		tr.Error = err
+		return
+	}
+
+	if exp.Compare(actual) == 0 {
+		return
+	}
+
+	tr.Fail = true
+
+	a, _ := json.MarshalIndent(golden, "", "  ")
+	b, _ := json.MarshalIndent(value, "", "  ")
+	diff := fmt.Sprintf("snapshot %v does not match (-snapshot +actual):\n%s", path, lineDiff(string(a), string(b)))
+	tr.Output = append(tr.Output, diff...)
+}
+
+// lineDiff returns the line-by-line differences of a and b, with removed
+// lines prefixed by "-" and added lines prefixed by "+".
+func lineDiff(a, b string) string {
+	dmp := diffmatchpatch.New()
+	ca, cb, lines := dmp.DiffLinesToChars(a, b)
+	diffs := dmp.DiffCharsToLines(dmp.DiffMain(ca, cb, false), lines)
+
+	var sb strings.Builder
+	for _, d := range diffs {
+		prefix := " "
+		switch d.Type {
+		case diffmatchpatch.DiffDelete:
+			prefix = "-"
+		case diffmatchpatch.DiffInsert:
+			prefix = "+"
+		}
+		for _, line := range strings.SplitAfter(strings.TrimSuffix(d.Text, "\n"), "\n") {
+			sb.WriteString(prefix)
+			sb.WriteString(line)
+			if !strings.HasSuffix(line, "\n") {
+				sb.WriteString("\n")
+			}
+		}
+	}
+	return sb.String()
+}
+
+func parseTestClock(v any) (time.Time, error) {
+	switch v := v.(type) {
+	case string:
+		return time.Parse(time.RFC3339Nano, v)
+	case json.Number:
+		ns, err := v.Int64()
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(0, ns), nil
+	case int:
+		return time.Unix(0, int64(v)), nil
+	case int64:
+		return time.Unix(0, v), nil
+	case uint64:
+		return time.Unix(0, int64(v)), nil
+	case float64:
+		return time.Unix(0, int64(v)), nil
+	}
+	return time.Time{}, fmt.Errorf("expected RFC3339 timestamp or nanoseconds, got %v", v)
+}
+
 func subResults(v any, trace []*topdown.Event) (bool, map[string]*SubResult) {
 	if v == nil {
 		return true, map[string]*SubResult{}
@@ -1074,6 +1302,12 @@ func (r *Runner) runBenchmark(ctx context.Context, txn storage.Transaction, mod
 
 	var stop bool
 
+	clock, err := r.testClock(rule)
+	if err != nil {
+		tr.Error = err
+		return tr, stop
+	}
+
 	t0 := time.Now()
 
 	br := testing.Benchmark(func(b *testing.B) {
@@ -1100,7 +1334,7 @@ func (r *Runner) runBenchmark(ctx context.Context, txn storage.Transaction, mod
 		}
 
 		for range b.N {
-			opts := []rego.EvalOption{rego.EvalTransaction(txn), rego.EvalMetrics(m)}
+			opts := []rego.EvalOption{rego.EvalTransaction(txn), rego.EvalMetrics(m), rego.EvalTime(clock)}
 
 			var tracer *TestQueryTracer
 			if rule.Head.DocKind() == ast.PartialObjectDoc {
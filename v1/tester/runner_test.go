@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"io"
 	"maps"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -653,6 +654,167 @@ func TestRunnerPrintOutput(t *testing.T) {
 	})
 }
 
+func TestRunnerClock(t *testing.T) {
+
+	files := map[string]string{
+		"/test.rego": `package test
+
+# runner clock
+test_runner_clock if time.now_ns() == 1704067200000000000
+
+# METADATA
+# custom:
+#   test:
+#     clock: "2024-06-01T00:00:00Z"
+test_annotated_timestamp if time.now_ns() == 1717200000000000000
+
+# METADATA
+# custom:
+#   test:
+#     clock: 42
+test_annotated_ns if time.now_ns() == 42
+
+# METADATA
+# custom:
+#   test:
+#     clock: "yesterday"
+test_invalid if true`,
+	}
+
+	ctx := context.Background()
+
+	test.WithTempFS(files, func(d string) {
+		modules, store, err := tester.Load([]string{d}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		txn := storage.NewTransactionOrDie(ctx, store)
+		runner := tester.NewRunner().
+			SetStore(store).
+			SetModules(modules).
+			SetClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+		ch, err := runner.RunTests(ctx, txn)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for r := range ch {
+			switch r.Name {
+			case "test_invalid":
+				if r.Error == nil || !strings.Contains(r.Error.Error(), "invalid test.clock annotation") {
+					t.Errorf("expected invalid annotation error, got %v", r.Error)
+				}
+			default:
+				if !r.Pass() {
+					t.Errorf("expected %v to pass, got: %v", r.Name, r)
+				}
+			}
+		}
+	})
+}
+
+func TestRunnerSnapshots(t *testing.T) {
+
+	files := map[string]string{
+		"/test.rego": `package test
+
+report := {"allowed": true, "reasons": {"b", "a"}, "score": 1}
+
+# METADATA
+# custom:
+#   test:
+#     snapshot: testdata/report.json
+test_report := report
+
+# METADATA
+# custom:
+#   test:
+#     snapshot: testdata/missing.json
+test_missing := report
+
+# METADATA
+# custom:
+#   test:
+#     snapshot: testdata/changed.json
+test_changed := report
+
+# METADATA
+# custom:
+#   test:
+#     snapshot: testdata/undefined.json
+test_undefined := report if false`,
+		"/testdata/report.json":  `{"allowed": true, "reasons": ["a", "b"], "score": 1.0}`,
+		"/testdata/changed.json": `{"allowed": false, "reasons": ["a", "b"], "score": 1}`,
+	}
+
+	ctx := context.Background()
+
+	test.WithTempFS(files, func(d string) {
+		run := func(update bool) map[string]*tester.Result {
+			modules, store, err := tester.Load([]string{filepath.Join(d, "test.rego")}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			txn := storage.NewTransactionOrDie(ctx, store)
+			defer store.Abort(ctx, txn)
+
+			ch, err := tester.NewRunner().
+				SetStore(store).
+				SetModules(modules).
+				SetUpdateSnapshots(update).
+				RunTests(ctx, txn)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			results := map[string]*tester.Result{}
+			for r := range ch {
+				results[r.Name] = r
+			}
+			return results
+		}
+
+		results := run(false)
+
+		if !results["test_report"].Pass() {
+			t.Errorf("expected test_report to pass, got: %v", results["test_report"])
+		}
+		if r := results["test_missing"]; r.Error == nil || !strings.Contains(r.Error.Error(), "does not exist") {
+			t.Errorf("expected missing snapshot error, got: %v", r)
+		}
+		if r := results["test_changed"]; !r.Fail || !strings.Contains(string(r.Output), `-  "allowed": false,`) || !strings.Contains(string(r.Output), `+  "allowed": true,`) {
+			t.Errorf("expected snapshot diff, got: %v: %s", r, r.Output)
+		}
+		if r := results["test_undefined"]; !r.Fail {
+			t.Errorf("expected test_undefined to fail, got: %v", r)
+		}
+
+		results = run(true)
+		for _, name := range []string{"test_report", "test_missing", "test_changed"} {
+			if !results[name].Pass() {
+				t.Errorf("expected %v to pass in update mode, got: %v", name, results[name])
+			}
+		}
+
+		bs, err := os.ReadFile(filepath.Join(d, "testdata", "missing.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp := "{\n  \"allowed\": true,\n  \"reasons\": [\n    \"a\",\n    \"b\"\n  ],\n  \"score\": 1\n}\n"; string(bs) != exp {
+			t.Fatalf("expected snapshot:\n%s\ngot:\n%s", exp, bs)
+		}
+
+		results = run(false)
+		for _, name := range []string{"test_report", "test_missing", "test_changed"} {
+			if !results[name].Pass() {
+				t.Errorf("expected %v to pass after update, got: %v", name, results[name])
+			}
+		}
+	})
+}
+
 func registerSleepBuiltin() {
 	ast.RegisterBuiltin(&ast.Builtin{
 		Name: "test.sleep",
@@ -7,6 +7,7 @@ package topdown
 import (
 	"math/big"
 
+	"github.com/open-policy-agent/opa/internal/ref"
 	"github.com/open-policy-agent/opa/v1/ast"
 	"github.com/open-policy-agent/opa/v1/topdown/builtins"
 )
@@ -288,6 +289,150 @@ func builtinMemberWithKey(_ BuiltinContext, operands []*ast.Term, iter func(*ast
 	return iter(ast.InternedTerm(false))
 }
 
+// groupElements calls f with the elements of the collection operand and the
+// values at the key path in them, skipping elements lacking the key.
+func groupElements(operands []*ast.Term, f func(elem *ast.Term, key ast.Value) error) error {
+	var elems []*ast.Term
+	switch c := operands[0].Value.(type) {
+	case *ast.Array:
+		elems = make([]*ast.Term, 0, c.Len())
+		c.Foreach(func(x *ast.Term) { elems = append(elems, x) })
+	case ast.Set:
+		elems = c.Slice()
+	case ast.Object:
+		elems = make([]*ast.Term, 0, c.Len())
+		c.Foreach(func(_, v *ast.Term) { elems = append(elems, v) })
+	default:
+		return builtins.NewOperandTypeErr(1, operands[0].Value, "array", "object", "set")
+	}
+
+	path := groupPath(operands[1])
+
+	for _, elem := range elems {
+		key, err := elem.Value.Find(path)
+		if err != nil {
+			continue
+		}
+		if err := f(elem, key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// groupPath returns the path of a key operand, which is either an array path
+// or a single key.
+func groupPath(key *ast.Term) ast.Ref {
+	if path, ok := key.Value.(*ast.Array); ok {
+		return ref.ArrayPath(path)
+	}
+	return ast.Ref{key}
+}
+
+// groupIndex numbers the distinct keys of groups, in order of appearance.
+type groupIndex struct {
+	keys    []*ast.Term
+	indices *ast.ValueMap
+}
+
+func newGroupIndex() *groupIndex {
+	return &groupIndex{indices: ast.NewValueMap()}
+}
+
+// index returns the number of the group of key, and whether it is new.
+func (g *groupIndex) index(key ast.Value) (int, bool) {
+	if i := g.indices.Get(key); i != nil {
+		n, _ := i.(ast.Number).Int()
+		return n, false
+	}
+	g.indices.Put(key, ast.InternedTerm(len(g.keys)).Value)
+	g.keys = append(g.keys, ast.NewTerm(key))
+	return len(g.keys) - 1, true
+}
+
+// object returns the object mapping the keys of the groups to their values.
+func (g *groupIndex) object(value func(i int) *ast.Term) *ast.Term {
+	result := ast.NewObject()
+	for i, key := range g.keys {
+		result.Insert(key, value(i))
+	}
+	return ast.NewTerm(result)
+}
+
+func builtinAggregateGroupBy(_ BuiltinContext, operands []*ast.Term, iter func(*ast.Term) error) error {
+	g := newGroupIndex()
+	var groups [][]*ast.Term
+
+	err := groupElements(operands, func(elem *ast.Term, key ast.Value) error {
+		i, ok := g.index(key)
+		if ok {
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], elem)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return iter(g.object(func(i int) *ast.Term {
+		return ast.ArrayTerm(groups[i]...)
+	}))
+}
+
+func builtinAggregateGroupCount(_ BuiltinContext, operands []*ast.Term, iter func(*ast.Term) error) error {
+	g := newGroupIndex()
+	var counts []int
+
+	err := groupElements(operands, func(_ *ast.Term, key ast.Value) error {
+		i, ok := g.index(key)
+		if ok {
+			counts = append(counts, 0)
+		}
+		counts[i]++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return iter(g.object(func(i int) *ast.Term {
+		return ast.InternedTerm(counts[i])
+	}))
+}
+
+func builtinAggregateGroupSum(_ BuiltinContext, operands []*ast.Term, iter func(*ast.Term) error) error {
+	g := newGroupIndex()
+	var sums []*big.Float
+	valuePath := groupPath(operands[2])
+
+	err := groupElements(operands, func(elem *ast.Term, key ast.Value) error {
+		value, err := elem.Value.Find(valuePath)
+		if err != nil {
+			return nil
+		}
+		n, ok := value.(ast.Number)
+		if !ok {
+			return builtins.NewOperandElementErr(1, operands[0].Value, value, "number")
+		}
+
+		i, ok := g.index(key)
+		if ok {
+			sums = append(sums, big.NewFloat(0))
+		}
+		sums[i] = new(big.Float).Add(sums[i], builtins.NumberToFloat(n))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return iter(g.object(func(i int) *ast.Term {
+		return ast.NewTerm(builtins.FloatToNumber(sums[i]))
+	}))
+}
+
 func init() {
 	RegisterBuiltinFunc(ast.Count.Name, builtinCount)
 	RegisterBuiltinFunc(ast.Sum.Name, builtinSum)
@@ -299,4 +444,7 @@ func init() {
 	RegisterBuiltinFunc(ast.All.Name, builtinAll)
 	RegisterBuiltinFunc(ast.Member.Name, builtinMember)
 	RegisterBuiltinFunc(ast.MemberWithKey.Name, builtinMemberWithKey)
+	RegisterBuiltinFunc(ast.AggregateGroupBy.Name, builtinAggregateGroupBy)
+	RegisterBuiltinFunc(ast.AggregateGroupCount.Name, builtinAggregateGroupCount)
+	RegisterBuiltinFunc(ast.AggregateGroupSum.Name, builtinAggregateGroupSum)
 }
@@ -41,6 +41,7 @@ type (
 		Time                        *ast.Term                  // wall clock time
 		Cancel                      Cancel                     // atomic value that signals evaluation to halt
 		Runtime                     *ast.Term                  // runtime information on the OPA instance
+		RequestMetadata             *ast.Term                  // metadata of the request the query is evaluated for
 		Cache                       builtins.Cache             // built-in function state cache
 		InterQueryBuiltinCache      cache.InterQueryCache      // cross-query built-in function state cache
 		InterQueryBuiltinValueCache cache.InterQueryValueCache // cross-query built-in function state value cache. this cache is useful for scenarios where the entry size cannot be calculated
@@ -55,6 +56,8 @@ type (
 		RoundTripper                CustomizeRoundTripper      // customize transport to use for HTTP requests
 		DistributedTracingOpts      tracing.Options            // options to be used by distributed tracing.
 		rand                        *rand.Rand                 // randomization source for non-security-sensitive operations
+		rule                        *ast.Rule                  // rule containing the call, if any
+		ruleDepth                   int                        // number of nested rule evaluations
 		Capabilities                *ast.Capabilities
 	}
 
@@ -222,3 +225,25 @@ func getResult(fn BuiltinFunc, operands ...*ast.Term) (*ast.Term, error) {
 	}
 	return result, nil
 }
+
+func init() {
+	ast.RegisterBuiltinEvaluator(evalBuiltinCall)
+}
+
+// evalBuiltinCall evaluates a call to a built-in function for the compiler's
+// constant folding stage. Since the stage runs before type checking, panics
+// caused by operands of unexpected types are returned as errors.
+func evalBuiltinCall(bi *ast.Builtin, operands []*ast.Term) (result *ast.Term, err error) {
+	fn := builtinFunctions[bi.Name]
+	if fn == nil {
+		return nil, nil
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, fmt.Errorf("%v: %v", bi.Name, r)
+		}
+	}()
+
+	return getResult(fn, operands...)
+}
@@ -95,7 +95,11 @@ func (c *NDBCache) UnmarshalJSON(data []byte) error {
 	if source, ok := nestedObject.(ast.Object); ok {
 		err = source.Iter(func(k, v *ast.Term) error {
 			if obj, ok := v.Value.(ast.Object); ok {
-				out[string(k.Value.(ast.String))] = obj
+				calls, err := unmarshalNDBCalls(obj)
+				if err != nil {
+					return err
+				}
+				out[string(k.Value.(ast.String))] = calls
 				return nil
 			}
 			return errors.New("expected Object, got other Value type in conversion")
@@ -110,6 +114,40 @@ func (c *NDBCache) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// unmarshalNDBCalls restores the operands of the calls of a built-in, which
+// are serialized as JSON encoded arrays in the object keys.
+func unmarshalNDBCalls(obj ast.Object) (ast.Object, error) {
+	out := ast.NewObject()
+	err := obj.Iter(func(k, v *ast.Term) error {
+		if s, ok := k.Value.(ast.String); ok {
+			var operands []any
+			if err := util.UnmarshalJSON([]byte(s), &operands); err == nil {
+				arr, err := ast.InterfaceToValue(operands)
+				if err != nil {
+					return err
+				}
+				k = ast.NewTerm(arr)
+			}
+		}
+		out.Insert(k, v)
+		return nil
+	})
+	return out, err
+}
+
+// Copy returns a copy of c. Later calls recorded into c are not recorded into
+// the copy.
+func (c NDBCache) Copy() NDBCache {
+	if c == nil {
+		return nil
+	}
+	cpy := make(NDBCache, len(c))
+	for name, obj := range c {
+		cpy[name] = obj.Copy()
+	}
+	return cpy
+}
+
 // ErrOperand represents an invalid operand has been passed to a built-in
 // function. Built-ins should return ErrOperand to indicate a type error has
 // occurred.
@@ -43,3 +43,74 @@ func TestCustomBuiltinIterator(t *testing.T) {
 		t.Fatal("Expected x to be 2 but got:", rs[0])
 	}
 }
+
+func TestConstantFolding(t *testing.T) {
+	t.Parallel()
+
+	module := `package test
+
+p := lower("ADMIN")
+
+q := concat(".", ["a", upper("b")])
+
+r := lower(input.x)
+
+s := to_number("nope")
+
+u := time.now_ns()
+
+v := trim_space(" x ")
+
+test_v if {
+	v == "y" with trim_space as "y"
+}`
+
+	c := ast.NewCompiler().WithConstantFolding(true)
+	c.Compile(map[string]*ast.Module{"test.rego": ast.MustParseModule(module)})
+	if c.Failed() {
+		t.Fatal(c.Errors)
+	}
+
+	heads := map[string]ast.Value{}
+	for _, rule := range c.Modules["test.rego"].Rules {
+		heads[rule.Head.Name.String()] = rule.Head.Value.Value
+	}
+
+	exp := map[string]ast.Value{
+		"p": ast.String("admin"),
+		"q": ast.String("a.B"),
+	}
+
+	for name, v := range heads {
+		if e, ok := exp[name]; ok {
+			if v.Compare(e) != 0 {
+				t.Errorf("expected %v to be folded to %v but got %v", name, e, v)
+			}
+		} else if _, ok := v.(ast.Var); !ok && name != "test_v" {
+			t.Errorf("expected %v not to be folded but got %v", name, v)
+		}
+	}
+
+	for _, name := range []string{"lower", "concat", "upper"} {
+		found := false
+		for _, bi := range c.Required.Builtins {
+			if bi.Name == name {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected %v in required builtins", name)
+		}
+	}
+
+	ctx := context.Background()
+
+	for _, q := range []string{`data.test.p = "admin"`, `data.test.q = "a.B"`, `data.test.test_v = true`} {
+		rs, err := NewQuery(ast.MustParseBody(q)).WithCompiler(c).Run(ctx)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(rs) != 1 {
+			t.Fatalf("expected one result for %v but got %v", q, rs)
+		}
+	}
+}
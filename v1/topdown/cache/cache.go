@@ -22,6 +22,8 @@ const (
 	defaultMaxSizeBytes                      = int64(0)   // unlimited
 	defaultForcedEvictionThresholdPercentage = int64(100) // trigger at max_size_bytes
 	defaultStaleEntryEvictionPeriodSeconds   = int64(0)   // never
+	defaultCircuitBreakerFailureThreshold    = 5
+	defaultCircuitBreakerOpenDurationSeconds = int64(30)
 )
 
 var interQueryBuiltinValueCacheDefaultConfigs = map[string]*NamedValueCacheConfig{}
@@ -41,6 +43,23 @@ func RegisterDefaultInterQueryBuiltinValueCacheConfig(name string, config *Named
 type Config struct {
 	InterQueryBuiltinCache      InterQueryBuiltinCacheConfig      `json:"inter_query_builtin_cache"`
 	InterQueryBuiltinValueCache InterQueryBuiltinValueCacheConfig `json:"inter_query_builtin_value_cache"`
+	HTTPSend                    *HTTPSendConfig                   `json:"http_send,omitempty"`
+}
+
+// HTTPSendConfig represents the limits applied to the requests issued by the http.send built-in function.
+// MaxConcurrentRequests - max number of in-flight requests across all evaluations sharing the cache, unlimited if unset
+// CircuitBreaker - per-host circuit breaker, disabled if unset
+type HTTPSendConfig struct {
+	MaxConcurrentRequests *int                          `json:"max_concurrent_requests,omitempty"`
+	CircuitBreaker        *HTTPSendCircuitBreakerConfig `json:"circuit_breaker,omitempty"`
+}
+
+// HTTPSendCircuitBreakerConfig represents the configuration of the per-host circuit breaker of http.send.
+// FailureThreshold - number of consecutive failed requests to a host after which the breaker opens
+// OpenDurationSeconds - time period the breaker stays open before a trial request is let through
+type HTTPSendCircuitBreakerConfig struct {
+	FailureThreshold    *int   `json:"failure_threshold,omitempty"`
+	OpenDurationSeconds *int64 `json:"open_duration_seconds,omitempty"`
 }
 
 // NamedValueCacheConfig represents the configuration of a named cache that built-in functions can utilize.
@@ -60,10 +79,12 @@ type InterQueryBuiltinValueCacheConfig struct {
 // MaxSizeBytes - max capacity of cache in bytes
 // ForcedEvictionThresholdPercentage - capacity usage in percentage after which forced FIFO eviction starts
 // StaleEntryEvictionPeriodSeconds - time period between end of previous and start of new stale entry eviction routine
+// Persistence - persists the cache to disk, disabled if unset
 type InterQueryBuiltinCacheConfig struct {
-	MaxSizeBytes                      *int64 `json:"max_size_bytes,omitempty"`
-	ForcedEvictionThresholdPercentage *int64 `json:"forced_eviction_threshold_percentage,omitempty"`
-	StaleEntryEvictionPeriodSeconds   *int64 `json:"stale_entry_eviction_period_seconds,omitempty"`
+	MaxSizeBytes                      *int64                                   `json:"max_size_bytes,omitempty"`
+	ForcedEvictionThresholdPercentage *int64                                   `json:"forced_eviction_threshold_percentage,omitempty"`
+	StaleEntryEvictionPeriodSeconds   *int64                                   `json:"stale_entry_eviction_period_seconds,omitempty"`
+	Persistence                       *InterQueryBuiltinCachePersistenceConfig `json:"persistence,omitempty"`
 }
 
 // ParseCachingConfig returns the config for the inter-query cache.
@@ -130,6 +151,11 @@ func (c *Config) validateAndInjectDefaults() error {
 			return fmt.Errorf("invalid stale_entry_eviction_period_seconds %v", period)
 		}
 	}
+	if p := c.InterQueryBuiltinCache.Persistence; p != nil {
+		if err := p.validateAndInjectDefaults(); err != nil {
+			return err
+		}
+	}
 
 	if c.InterQueryBuiltinValueCache.MaxNumEntries == nil {
 		maxSize := new(int)
@@ -149,6 +175,26 @@ func (c *Config) validateAndInjectDefaults() error {
 		}
 	}
 
+	if c.HTTPSend != nil {
+		if n := c.HTTPSend.MaxConcurrentRequests; n != nil && *n < 0 {
+			return fmt.Errorf("invalid http_send max_concurrent_requests %v", *n)
+		}
+		if cb := c.HTTPSend.CircuitBreaker; cb != nil {
+			if cb.FailureThreshold == nil {
+				threshold := defaultCircuitBreakerFailureThreshold
+				cb.FailureThreshold = &threshold
+			} else if *cb.FailureThreshold <= 0 {
+				return fmt.Errorf("invalid http_send circuit_breaker failure_threshold %v", *cb.FailureThreshold)
+			}
+			if cb.OpenDurationSeconds == nil {
+				period := defaultCircuitBreakerOpenDurationSeconds
+				cb.OpenDurationSeconds = &period
+			} else if *cb.OpenDurationSeconds <= 0 {
+				return fmt.Errorf("invalid http_send circuit_breaker open_duration_seconds %v", *cb.OpenDurationSeconds)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -181,6 +227,8 @@ func NewInterQueryCache(config *Config) InterQueryCache {
 // The cache uses a combination of FIFO eviction policy when it reaches the forced eviction threshold
 // and a periodic cleanup routine to remove stale entries that exceed their expiration time, if specified.
 // If configured with a zero stale_entry_eviction_period_seconds value, the stale entry cleanup routine is disabled.
+// If configured with persistence, the cache is re-loaded from its snapshot on disk, and persisted periodically
+// and once more when ctx is done.
 //
 // Parameters:
 //
@@ -188,6 +236,7 @@ func NewInterQueryCache(config *Config) InterQueryCache {
 //	config - to configure the InterQueryCache
 func NewInterQueryCacheWithContext(ctx context.Context, config *Config) InterQueryCache {
 	iqCache := newCache(config)
+	iqCache.startPersistence(ctx)
 	if iqCache.staleEntryEvictionTimePeriodSeconds() > 0 {
 		go func() {
 			cleanupTicker := time.NewTicker(time.Duration(iqCache.staleEntryEvictionTimePeriodSeconds()) * time.Second)
@@ -218,19 +267,21 @@ type cacheItem struct {
 }
 
 type cache struct {
-	items  map[string]cacheItem
-	usage  int64
-	config *Config
-	l      *list.List
-	mtx    sync.Mutex
+	items    map[string]cacheItem
+	usage    int64
+	config   *Config
+	l        *list.List
+	mtx      sync.Mutex
+	httpSend *HTTPSendLimiter
 }
 
 func newCache(config *Config) *cache {
 	return &cache{
-		items:  map[string]cacheItem{},
-		usage:  0,
-		config: config,
-		l:      list.New(),
+		items:    map[string]cacheItem{},
+		usage:    0,
+		config:   config,
+		l:        list.New(),
+		httpSend: newHTTPSendLimiter(config),
 	}
 }
 
@@ -273,6 +324,13 @@ func (c *cache) UpdateConfig(config *Config) {
 	c.mtx.Lock()
 	defer c.mtx.Unlock()
 	c.config = config
+	c.httpSend.configure(config)
+}
+
+// HTTPSendLimiter returns the limiter enforcing the http.send limits of the
+// cache's configuration.
+func (c *cache) HTTPSendLimiter() *HTTPSendLimiter {
+	return c.httpSend
 }
 
 func (c *cache) Clone(value InterQueryCacheValue) (InterQueryCacheValue, error) {
@@ -98,6 +98,75 @@ func TestParseCachingConfig(t *testing.T) {
 	}
 }
 
+func TestParseCachingConfigHTTPSend(t *testing.T) {
+	t.Parallel()
+
+	config, err := ParseCachingConfig([]byte(`{"http_send": {"max_concurrent_requests": 10, "circuit_breaker": {}}}`))
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if exp, act := 10, *config.HTTPSend.MaxConcurrentRequests; exp != act {
+		t.Fatalf("expected max_concurrent_requests %d, got %d", exp, act)
+	}
+	cb := config.HTTPSend.CircuitBreaker
+	if exp, act := defaultCircuitBreakerFailureThreshold, *cb.FailureThreshold; exp != act {
+		t.Fatalf("expected failure_threshold %d, got %d", exp, act)
+	}
+	if exp, act := defaultCircuitBreakerOpenDurationSeconds, *cb.OpenDurationSeconds; exp != act {
+		t.Fatalf("expected open_duration_seconds %d, got %d", exp, act)
+	}
+
+	for _, in := range []string{
+		`{"http_send": {"max_concurrent_requests": -1}}`,
+		`{"http_send": {"circuit_breaker": {"failure_threshold": 0}}}`,
+		`{"http_send": {"circuit_breaker": {"open_duration_seconds": -5}}}`,
+	} {
+		if _, err := ParseCachingConfig([]byte(in)); err == nil {
+			t.Fatalf("Expected error for %s but got nil", in)
+		}
+	}
+}
+
+func TestHTTPSendLimiterPerCache(t *testing.T) {
+	t.Parallel()
+
+	config1, err := ParseCachingConfig([]byte(`{"http_send": {"circuit_breaker": {"failure_threshold": 1}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config2, err := ParseCachingConfig([]byte(`{"http_send": {"circuit_breaker": {"failure_threshold": 3}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l1 := NewInterQueryCache(config1).(HTTPSendLimiterProvider).HTTPSendLimiter()
+	l2 := NewInterQueryCache(config2).(HTTPSendLimiterProvider).HTTPSendLimiter()
+
+	now := time.Now()
+	l1.Record("example.com", true, now)
+	l2.Record("example.com", true, now)
+
+	if err := l1.Allow("example.com", now); err == nil {
+		t.Fatal("expected breaker of first cache to be open")
+	}
+	if err := l2.Allow("example.com", now); err != nil {
+		t.Fatalf("expected breaker of second cache to be closed, got %v", err)
+	}
+
+	// Updating the config of a cache only affects its own limiter.
+	c := NewInterQueryCache(config2)
+	c.UpdateConfig(config1)
+	l3 := c.(HTTPSendLimiterProvider).HTTPSendLimiter()
+	l3.Record("example.com", true, now)
+	if err := l3.Allow("example.com", now); err == nil {
+		t.Fatal("expected breaker of updated cache to be open")
+	}
+	if err := l2.Allow("example.com", now); err != nil {
+		t.Fatalf("expected breaker of second cache to be closed, got %v", err)
+	}
+}
+
 func TestInterValueCache_DefaultConfiguration(t *testing.T) {
 	t.Run("default config not set", func(t *testing.T) {
 		config := Config{
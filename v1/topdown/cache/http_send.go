@@ -0,0 +1,161 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// HTTPSendLimiter holds the state backing the http.send limits of an
+// inter-query cache: the semaphore bounding the number of in-flight requests
+// and the per-host circuit breakers. Each cache has its own limiter, so that
+// caches created with different configurations do not share their limits.
+type HTTPSendLimiter struct {
+	mtx      sync.Mutex
+	config   *HTTPSendConfig
+	sem      chan struct{}
+	breakers map[string]*circuitBreaker
+}
+
+// HTTPSendLimiterProvider is implemented by inter-query caches that enforce
+// the http.send limits of their configuration.
+type HTTPSendLimiterProvider interface {
+	HTTPSendLimiter() *HTTPSendLimiter
+}
+
+// circuitBreaker tracks consecutive failures for a single host. The breaker
+// opens once the failure threshold is reached; after the open duration has
+// passed, one trial request is let through (half-open) which either closes the
+// breaker again or re-opens it.
+type circuitBreaker struct {
+	failures  int
+	openUntil time.Time
+	trial     bool
+}
+
+func newHTTPSendLimiter(config *Config) *HTTPSendLimiter {
+	l := &HTTPSendLimiter{breakers: map[string]*circuitBreaker{}}
+	l.configure(config)
+	return l
+}
+
+// configure applies the http.send limits of config. In-flight requests keep a
+// reference to the semaphore they acquired, so replacing it here is safe.
+func (l *HTTPSendLimiter) configure(config *Config) {
+	var hc *HTTPSendConfig
+	if config != nil {
+		hc = config.HTTPSend
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	var size int
+	if hc != nil && hc.MaxConcurrentRequests != nil {
+		size = *hc.MaxConcurrentRequests
+	}
+
+	if size == 0 {
+		l.sem = nil
+	} else if l.sem == nil || cap(l.sem) != size {
+		l.sem = make(chan struct{}, size)
+	}
+
+	if hc == nil || hc.CircuitBreaker == nil {
+		clear(l.breakers)
+	}
+
+	l.config = hc
+}
+
+// Enabled returns true if any http.send limits are configured.
+func (l *HTTPSendLimiter) Enabled() bool {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.sem != nil || (l.config != nil && l.config.CircuitBreaker != nil)
+}
+
+// Allow returns an error if the circuit breaker for host is open.
+func (l *HTTPSendLimiter) Allow(host string, now time.Time) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if l.config == nil || l.config.CircuitBreaker == nil {
+		return nil
+	}
+
+	b, ok := l.breakers[host]
+	if !ok || b.failures < *l.config.CircuitBreaker.FailureThreshold {
+		return nil
+	}
+
+	if now.Before(b.openUntil) || b.trial {
+		return fmt.Errorf("circuit breaker open for host %v", host)
+	}
+
+	// half-open: let a single trial request through
+	b.trial = true
+	return nil
+}
+
+// Record updates the circuit breaker for host with the outcome of a request.
+func (l *HTTPSendLimiter) Record(host string, failed bool, now time.Time) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if l.config == nil || l.config.CircuitBreaker == nil {
+		return
+	}
+
+	if !failed {
+		delete(l.breakers, host)
+		return
+	}
+
+	b, ok := l.breakers[host]
+	if !ok {
+		b = &circuitBreaker{}
+		l.breakers[host] = b
+	}
+
+	b.failures++
+	b.trial = false
+	if b.failures >= *l.config.CircuitBreaker.FailureThreshold {
+		b.openUntil = now.Add(time.Duration(*l.config.CircuitBreaker.OpenDurationSeconds) * time.Second)
+	}
+}
+
+// Release gives up a trial request to host that was never sent.
+func (l *HTTPSendLimiter) Release(host string) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if b, ok := l.breakers[host]; ok {
+		b.trial = false
+	}
+}
+
+// Acquire waits for an in-flight request slot, and returns the function
+// releasing it. If the number of in-flight requests is not limited, it returns
+// immediately.
+func (l *HTTPSendLimiter) Acquire(ctx context.Context) (func(), error) {
+	l.mtx.Lock()
+	sem := l.sem
+	l.mtx.Unlock()
+
+	if sem == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case sem <- struct{}{}:
+		return func() { <-sem }, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for in-flight request slot: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/util"
+)
+
+const (
+	defaultSnapshotPeriodSeconds = int64(60)
+
+	// SnapshotFileName is the name of the file in the persistence directory
+	// holding the snapshot of the inter-query cache.
+	SnapshotFileName = "inter_query_builtin_cache.json"
+)
+
+// InterQueryBuiltinCachePersistenceConfig represents the configuration for persisting the
+// inter-query cache to disk, so that it can be re-loaded when OPA restarts.
+// Directory - directory holding the snapshot of the cache
+// SnapshotPeriodSeconds - time period between snapshots of the cache
+// HTTPSend - persist http.send responses, which are written to the snapshot unencrypted
+type InterQueryBuiltinCachePersistenceConfig struct {
+	Directory             string `json:"directory"`
+	SnapshotPeriodSeconds *int64 `json:"snapshot_period_seconds,omitempty"`
+	HTTPSend              bool   `json:"http_send,omitempty"`
+}
+
+func (c *InterQueryBuiltinCachePersistenceConfig) validateAndInjectDefaults() error {
+	if c.Directory == "" {
+		return errors.New("missing inter_query_builtin_cache persistence directory")
+	}
+	if c.SnapshotPeriodSeconds == nil {
+		period := defaultSnapshotPeriodSeconds
+		c.SnapshotPeriodSeconds = &period
+	} else if *c.SnapshotPeriodSeconds <= 0 {
+		return errors.New("invalid inter_query_builtin_cache persistence snapshot_period_seconds")
+	}
+	return nil
+}
+
+// PersistableValue is implemented by inter-query cache values that can be
+// written to the snapshot of a persisted cache. Values are read back with the
+// decoder registered for their kind with RegisterValueDecoder. Values that do
+// not implement PersistableValue are not persisted.
+type PersistableValue interface {
+	InterQueryCacheValue
+	PersistedKind() string
+	MarshalPersisted() ([]byte, error)
+}
+
+// Persister is implemented by inter-query caches that persist their entries.
+type Persister interface {
+	// Persist writes a snapshot of the cache to disk. Persist is a no-op if
+	// persistence is not configured.
+	Persist() error
+}
+
+var (
+	valueDecodersMtx sync.RWMutex
+	valueDecoders    = map[string]func([]byte) (InterQueryCacheValue, error){}
+)
+
+// RegisterValueDecoder registers the function decoding persisted cache values
+// of the given kind.
+func RegisterValueDecoder(kind string, decode func([]byte) (InterQueryCacheValue, error)) {
+	valueDecodersMtx.Lock()
+	defer valueDecodersMtx.Unlock()
+	valueDecoders[kind] = decode
+}
+
+func valueDecoder(kind string) func([]byte) (InterQueryCacheValue, error) {
+	valueDecodersMtx.RLock()
+	defer valueDecodersMtx.RUnlock()
+	return valueDecoders[kind]
+}
+
+type snapshot struct {
+	Entries []snapshotEntry `json:"entries"`
+}
+
+type snapshotEntry struct {
+	Key       string     `json:"key"`
+	Kind      string     `json:"kind"`
+	Value     []byte     `json:"value"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+func (c *cache) persistence() *InterQueryBuiltinCachePersistenceConfig {
+	if c.config == nil {
+		return nil
+	}
+	return c.config.InterQueryBuiltinCache.Persistence
+}
+
+// persisted returns true if values of the given kind are persisted. Responses
+// of http.send may hold credentials or personal data, so they are only
+// persisted if enabled explicitly.
+func (p *InterQueryBuiltinCachePersistenceConfig) persisted(kind string) bool {
+	return kind != ast.HTTPSend.Name || p.HTTPSend
+}
+
+// Persist writes the entries of the cache that have not expired to the
+// snapshot file in the configured persistence directory. The snapshot is only
+// readable by the owner.
+func (c *cache) Persist() error {
+	c.mtx.Lock()
+	p := c.persistence()
+	if p == nil {
+		c.mtx.Unlock()
+		return nil
+	}
+
+	now := time.Now()
+	var s snapshot
+	for e := c.l.Front(); e != nil; e = e.Next() {
+		k := e.Value.(ast.Value)
+		item := c.items[k.String()]
+		if !item.expiresAt.IsZero() && item.expiresAt.Before(now) {
+			continue
+		}
+
+		v, ok := item.value.(PersistableValue)
+		if !ok || !p.persisted(v.PersistedKind()) {
+			continue
+		}
+
+		bs, err := v.MarshalPersisted()
+		if err != nil {
+			c.mtx.Unlock()
+			return err
+		}
+
+		entry := snapshotEntry{Key: k.String(), Kind: v.PersistedKind(), Value: bs}
+		if !item.expiresAt.IsZero() {
+			entry.ExpiresAt = &item.expiresAt
+		}
+		s.Entries = append(s.Entries, entry)
+	}
+	dir := p.Directory
+	c.mtx.Unlock()
+
+	bs, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, so that a crash never leaves a partial
+	// snapshot behind.
+	tmp, err := os.CreateTemp(dir, SnapshotFileName+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if _, err := tmp.Write(bs); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filepath.Join(dir, SnapshotFileName))
+}
+
+// restore inserts the entries of the snapshot in the configured persistence
+// directory that have not expired yet. Entries of unknown kinds are skipped.
+func (c *cache) restore() error {
+	p := c.persistence()
+	if p == nil {
+		return nil
+	}
+
+	bs, err := os.ReadFile(filepath.Join(p.Directory, SnapshotFileName))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	var s snapshot
+	if err := util.UnmarshalJSON(bs, &s); err != nil {
+		return err
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	now := time.Now()
+	for _, entry := range s.Entries {
+		var expiresAt time.Time
+		if entry.ExpiresAt != nil {
+			if entry.ExpiresAt.Before(now) {
+				continue
+			}
+			expiresAt = *entry.ExpiresAt
+		}
+
+		decode := valueDecoder(entry.Kind)
+		if decode == nil || !p.persisted(entry.Kind) {
+			continue
+		}
+
+		k, err := ast.ParseTerm(entry.Key)
+		if err != nil {
+			return err
+		}
+
+		v, err := decode(entry.Value)
+		if err != nil {
+			return err
+		}
+
+		c.unsafeInsert(k.Value, v, expiresAt)
+	}
+
+	return nil
+}
+
+// startPersistence restores the cache from its snapshot and persists it
+// periodically, and once more when ctx is done. Persistence is best-effort:
+// a snapshot that cannot be read is ignored, and failed snapshots are retried
+// with the next period.
+func (c *cache) startPersistence(ctx context.Context) {
+	p := c.persistence()
+	if p == nil {
+		return
+	}
+
+	_ = c.restore()
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(*p.SnapshotPeriodSeconds) * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				_ = c.Persist()
+			case <-ctx.Done():
+				_ = c.Persist()
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,163 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+)
+
+func TestParseCachingConfigPersistence(t *testing.T) {
+	t.Parallel()
+
+	config, err := ParseCachingConfig([]byte(`{"inter_query_builtin_cache": {"persistence": {"directory": "/tmp/cache"}}}`))
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	p := config.InterQueryBuiltinCache.Persistence
+	if p.Directory != "/tmp/cache" {
+		t.Fatalf("expected directory /tmp/cache, got %v", p.Directory)
+	}
+	if exp, act := defaultSnapshotPeriodSeconds, *p.SnapshotPeriodSeconds; exp != act {
+		t.Fatalf("expected snapshot_period_seconds %d, got %d", exp, act)
+	}
+
+	for _, in := range []string{
+		`{"inter_query_builtin_cache": {"persistence": {}}}`,
+		`{"inter_query_builtin_cache": {"persistence": {"directory": "/tmp/cache", "snapshot_period_seconds": 0}}}`,
+	} {
+		if _, err := ParseCachingConfig([]byte(in)); err == nil {
+			t.Fatalf("Expected error for %s but got nil", in)
+		}
+	}
+}
+
+func TestInterQueryCachePersistence(t *testing.T) {
+	RegisterValueDecoder("test", func(bs []byte) (InterQueryCacheValue, error) {
+		return &persistedTestValue{data: string(bs)}, nil
+	})
+
+	dir := t.TempDir()
+	config, err := ParseCachingConfig([]byte(`{"inter_query_builtin_cache": {"persistence": {"directory": "` + dir + `"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := NewInterQueryCacheWithContext(ctx, config)
+
+	c.InsertWithExpiry(ast.String("fresh"), &persistedTestValue{data: "a"}, time.Now().Add(time.Hour))
+	c.InsertWithExpiry(ast.String("expired"), &persistedTestValue{data: "b"}, time.Now().Add(-time.Hour))
+	c.Insert(ast.MustParseTerm(`{"url": "https://example.com", "method": "get"}`).Value, &persistedTestValue{data: "c"})
+	c.Insert(ast.String("transient"), newInterQueryCacheValue(ast.String("d"), 1))
+	c.Insert(ast.String("response"), &persistedTestValue{data: "e", kind: ast.HTTPSend.Name})
+
+	// Cancelling the context persists the cache one last time.
+	cancel()
+
+	path := filepath.Join(dir, SnapshotFileName)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(path); err == nil {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatalf("expected snapshot at %v: %v", path, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if fi, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	} else if runtime.GOOS != "windows" && fi.Mode().Perm() != 0o600 {
+		t.Fatalf("expected snapshot to be written with mode 0600, got %v", fi.Mode().Perm())
+	}
+
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(bs), ast.HTTPSend.Name) {
+		t.Fatalf("expected http.send responses not to be persisted by default, got %s", bs)
+	}
+
+	restored := NewInterQueryCacheWithContext(context.Background(), config)
+
+	for k, exp := range map[ast.Value]string{
+		ast.String("fresh"): "a",
+		ast.MustParseTerm(`{"method": "get", "url": "https://example.com"}`).Value: "c",
+	} {
+		v, ok := restored.Get(k)
+		if !ok {
+			t.Fatalf("expected %v to be restored", k)
+		}
+		if act := v.(*persistedTestValue).data; act != exp {
+			t.Fatalf("expected %v to be restored with %v, got %v", k, exp, act)
+		}
+	}
+
+	for _, k := range []ast.Value{ast.String("expired"), ast.String("transient")} {
+		if _, ok := restored.Get(k); ok {
+			t.Fatalf("expected %v not to be restored", k)
+		}
+	}
+}
+
+func TestInterQueryCachePersistInvalidSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, SnapshotFileName), []byte("nope"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := ParseCachingConfig([]byte(`{"inter_query_builtin_cache": {"persistence": {"directory": "` + dir + `"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := newCache(config)
+	if err := c.restore(); err == nil {
+		t.Fatal("expected error restoring invalid snapshot")
+	}
+
+	// An invalid snapshot is ignored, and overwritten by the next snapshot.
+	c.Insert(ast.String("k"), &persistedTestValue{data: "v"})
+	if err := c.Persist(); err != nil {
+		t.Fatal(err)
+	}
+	if err := newCache(config).restore(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type persistedTestValue struct {
+	data string
+	kind string
+}
+
+func (p *persistedTestValue) SizeInBytes() int64 {
+	return int64(len(p.data))
+}
+
+func (p *persistedTestValue) Clone() (InterQueryCacheValue, error) {
+	return &persistedTestValue{data: p.data, kind: p.kind}, nil
+}
+
+func (p *persistedTestValue) PersistedKind() string {
+	if p.kind != "" {
+		return p.kind
+	}
+	return "test"
+}
+
+func (p *persistedTestValue) MarshalPersisted() ([]byte, error) {
+	return []byte(p.data), nil
+}
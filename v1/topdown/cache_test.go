@@ -5,9 +5,13 @@
 package topdown
 
 import (
+	"context"
+	"slices"
 	"testing"
 
 	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/storage"
+	"github.com/open-policy-agent/opa/v1/storage/inmem"
 )
 
 func TestVirtualCacheCompositeKey(t *testing.T) {
@@ -59,3 +63,91 @@ func TestBaseCacheGetExactMatch(t *testing.T) {
 		t.Fatalf("Expected bar but got %v", result)
 	}
 }
+
+func TestSharedVirtualCache(t *testing.T) {
+	t.Parallel()
+
+	module := `package test
+
+	p contains x if some x in data.xs
+	q if input.y
+	r := rand.intn("r", 100)
+	f(x) := x + count(data.xs)
+	s := n if n := count(p) with data.xs as [1]`
+
+	compiler := ast.MustCompileModules(map[string]string{"test.rego": module})
+	query, err := compiler.QueryCompiler().Compile(ast.MustParseBody(`data.test.p = p; data.test.q = q; data.test.r = r; data.test.f(1, f); data.test.s = s`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eval := func(t *testing.T, cache *SharedVirtualCache, key string, xs []any) QueryResult {
+		t.Helper()
+		ctx := context.Background()
+		store := inmem.NewFromObject(map[string]any{"xs": xs})
+		txn := storage.NewTransactionOrDie(ctx, store)
+		defer store.Abort(ctx, txn)
+
+		qrs, err := NewQuery(query).
+			WithCompiler(compiler).
+			WithStore(store).
+			WithTransaction(txn).
+			WithInput(ast.MustParseTerm(`{"y": true}`)).
+			WithSharedVirtualCache(cache, key).
+			Run(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(qrs) != 1 {
+			t.Fatalf("expected one result but got %v", qrs)
+		}
+		return qrs[0]
+	}
+
+	t.Run("shared", func(t *testing.T) {
+		cache := NewSharedVirtualCache(0)
+		eval(t, cache, "rev1", []any{1, 2})
+
+		var keys []string
+		for k := range cache.entries {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		if exp := []string{"data.test.f[1]", "data.test.p", "data.test.s"}; !slices.Equal(keys, exp) {
+			t.Fatalf("expected cached keys %v but got %v", exp, keys)
+		}
+
+		// The values computed for rev1 are reused, although the data differs.
+		qr := eval(t, cache, "rev1", []any{3})
+		if exp := ast.MustParseTerm(`{1, 2}`); !qr[ast.Var("p")].Equal(exp) {
+			t.Fatalf("expected p to be %v but got %v", exp, qr[ast.Var("p")])
+		}
+		if exp := ast.IntNumberTerm(3); !qr[ast.Var("f")].Equal(exp) {
+			t.Fatalf("expected f(1) to be %v but got %v", exp, qr[ast.Var("f")])
+		}
+
+		qr = eval(t, cache, "rev2", []any{3})
+		if exp := ast.MustParseTerm(`{3}`); !qr[ast.Var("p")].Equal(exp) {
+			t.Fatalf("expected p to be %v but got %v", exp, qr[ast.Var("p")])
+		}
+		if exp := ast.IntNumberTerm(2); !qr[ast.Var("f")].Equal(exp) {
+			t.Fatalf("expected f(1) to be %v but got %v", exp, qr[ast.Var("f")])
+		}
+		if exp := ast.IntNumberTerm(1); !qr[ast.Var("s")].Equal(exp) {
+			t.Fatalf("expected s to be %v but got %v", exp, qr[ast.Var("s")])
+		}
+	})
+
+	t.Run("eviction", func(t *testing.T) {
+		cache := NewSharedVirtualCache(1)
+		eval(t, cache, "rev1", []any{1, 2})
+		if exp, act := 1, cache.Len(); exp != act {
+			t.Fatalf("expected %d entries but got %d", exp, act)
+		}
+
+		cache.Clear()
+		if exp, act := 0, cache.Len(); exp != act {
+			t.Fatalf("expected %d entries but got %d", exp, act)
+		}
+	})
+}
@@ -0,0 +1,63 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package topdown
+
+import (
+	"context"
+	"time"
+)
+
+// CheckpointStats describes the evaluation of a query at a checkpoint.
+type CheckpointStats struct {
+	Operations uint64        // number of expressions evaluated so far
+	Elapsed    time.Duration // time since evaluation started, including pauses
+}
+
+// CheckpointFunc is called at evaluation checkpoints. Evaluation is paused
+// until the function returns, and resumes if it returns nil. If it returns an
+// error, evaluation is aborted with a cancellation error wrapping it.
+//
+// Checkpoints let hosts evaluate policies cooperatively within a time budget,
+// e.g., by blocking until the next frame once the budget of the current one
+// is spent, which cancellation alone does not allow for.
+type CheckpointFunc func(ctx context.Context, stats CheckpointStats) error
+
+// checkpoints calls f every interval operations of an evaluation.
+type checkpoints struct {
+	f        CheckpointFunc
+	interval uint64
+	ops      uint64
+	start    time.Time
+}
+
+func newCheckpoints(interval uint64, f CheckpointFunc) *checkpoints {
+	return &checkpoints{
+		f:        f,
+		interval: max(interval, 1),
+		start:    time.Now(),
+	}
+}
+
+// step counts an operation, and calls f if a checkpoint is reached.
+func (c *checkpoints) step(ctx context.Context) error {
+	c.ops++
+	if c.ops%c.interval != 0 {
+		return nil
+	}
+
+	err := c.f(ctx, CheckpointStats{
+		Operations: c.ops,
+		Elapsed:    time.Since(c.start),
+	})
+	if err != nil {
+		return &Error{
+			Code:    CancelErr,
+			Message: "evaluation aborted at checkpoint: " + err.Error(),
+			err:     err,
+		}
+	}
+
+	return nil
+}
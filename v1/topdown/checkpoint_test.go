@@ -0,0 +1,98 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package topdown
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/storage"
+	"github.com/open-policy-agent/opa/v1/storage/inmem"
+)
+
+func TestQueryCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	compiler := compileModules([]string{
+		`
+		package test
+
+		p contains x if { some x in numbers.range(1, 100); x % 10 == 0 }
+		`,
+	})
+
+	store := inmem.New()
+	txn := storage.NewTransactionOrDie(ctx, store)
+	defer store.Abort(ctx, txn)
+
+	newQuery := func(interval uint64, f CheckpointFunc) *Query {
+		return NewQuery(ast.MustParseBody("data.test.p")).
+			WithCompiler(compiler).
+			WithStore(store).
+			WithTransaction(txn).
+			WithCheckpoint(interval, f)
+	}
+
+	t.Run("resume", func(t *testing.T) {
+		var stats []CheckpointStats
+		qrs, err := newQuery(10, func(_ context.Context, s CheckpointStats) error {
+			stats = append(stats, s)
+			return nil
+		}).Run(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(qrs) != 1 {
+			t.Fatalf("expected one result, got %v", qrs)
+		}
+
+		if len(stats) == 0 {
+			t.Fatal("expected checkpoints")
+		}
+		for i, s := range stats {
+			if exp := uint64(10 * (i + 1)); s.Operations != exp {
+				t.Fatalf("expected %v operations at checkpoint %d, got %v", exp, i, s.Operations)
+			}
+		}
+	})
+
+	t.Run("abort", func(t *testing.T) {
+		errBudget := errors.New("frame budget exceeded")
+		var calls int
+		_, err := newQuery(5, func(context.Context, CheckpointStats) error {
+			calls++
+			if calls == 3 {
+				return errBudget
+			}
+			return nil
+		}).Run(ctx)
+
+		if !IsCancel(err) || !errors.Is(err, errBudget) {
+			t.Fatalf("expected cancellation wrapping budget error, got %v", err)
+		}
+		if calls != 3 {
+			t.Fatalf("expected evaluation to stop at the third checkpoint, got %d calls", calls)
+		}
+	})
+
+	t.Run("partial", func(t *testing.T) {
+		var calls int
+		_, _, err := newQuery(1, func(context.Context, CheckpointStats) error {
+			calls++
+			return nil
+		}).WithUnknowns([]*ast.Term{ast.MustParseTerm("input")}).PartialRun(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if calls == 0 {
+			t.Fatal("expected checkpoints during partial evaluation")
+		}
+	})
+}
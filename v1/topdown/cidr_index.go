@@ -0,0 +1,207 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package topdown
+
+import (
+	"fmt"
+	"net/netip"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/topdown/builtins"
+	"github.com/open-policy-agent/opa/v1/topdown/cache"
+)
+
+const cidrMatchIndexCacheName = "net_cidr_match_index"
+
+// cidrMatchIndexCacheKey is the key of the per-query cache holding the
+// indices built by net.cidr_match_index, bucketed by the hash of the
+// collection they were built from.
+type cidrMatchIndexCacheKey struct{}
+
+// cidrIndex is a pair of binary prefix tries (IPv4 and IPv6) over a collection
+// of CIDRs. Every node holds the entries whose prefix ends at that node, so a
+// lookup collects all matches by walking the bits of an address once.
+type cidrIndex struct {
+	cidrs ast.Value
+	v4    cidrTrieNode
+	v6    cidrTrieNode
+}
+
+type cidrTrieNode struct {
+	children [2]*cidrTrieNode
+	entries  []*ast.Term
+}
+
+func newCIDRIndex(cidrs ast.Value) (*cidrIndex, error) {
+	idx := &cidrIndex{cidrs: cidrs}
+
+	var err error
+	foreachVertex(ast.NewTerm(cidrs), func(t *ast.Term) {
+		if err != nil {
+			return
+		}
+		s, ok := t.Value.(ast.String)
+		if !ok {
+			err = builtins.NewOperandElementErr(1, cidrs, t.Value, "string")
+			return
+		}
+		prefix, e := parseCIDROrIP(string(s))
+		if e != nil {
+			err = fmt.Errorf("operand 1: %w", e)
+			return
+		}
+		idx.root(prefix.Addr()).insert(prefix.Addr().AsSlice(), prefix.Bits(), t)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return idx, nil
+}
+
+// parseCIDROrIP parses a CIDR, or an IP address as a single host network.
+// Like net.IPNet.Contains, IPv4-mapped IPv6 CIDRs are treated as IPv4 CIDRs.
+func parseCIDROrIP(s string) (netip.Prefix, error) {
+	if prefix, err := netip.ParsePrefix(s); err == nil {
+		prefix = prefix.Masked()
+		if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+			return netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96), nil
+		}
+		return prefix, nil
+	}
+	addr, ok := parseIP(s)
+	if !ok {
+		return netip.Prefix{}, fmt.Errorf("not a valid textual representation of an IP address or CIDR: %s", s)
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// parseIP parses an IP address without zone, unmapping IPv4-mapped IPv6
+// addresses to IPv4 addresses.
+func parseIP(s string) (netip.Addr, bool) {
+	addr, err := netip.ParseAddr(s)
+	if err != nil || addr.Zone() != "" {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+func (idx *cidrIndex) root(addr netip.Addr) *cidrTrieNode {
+	if addr.Is4() {
+		return &idx.v4
+	}
+	return &idx.v6
+}
+
+func (idx *cidrIndex) lookup(addr netip.Addr) ast.Set {
+	result := ast.NewSet()
+	ip := addr.AsSlice()
+	node := idx.root(addr)
+	for i := 0; node != nil; i++ {
+		for _, t := range node.entries {
+			result.Add(t)
+		}
+		if i == len(ip)*8 {
+			break
+		}
+		node = node.children[ipBit(ip, i)]
+	}
+	return result
+}
+
+func (n *cidrTrieNode) insert(ip []byte, ones int, entry *ast.Term) {
+	for i := range ones {
+		b := ipBit(ip, i)
+		if n.children[b] == nil {
+			n.children[b] = &cidrTrieNode{}
+		}
+		n = n.children[b]
+	}
+	n.entries = append(n.entries, entry)
+}
+
+func ipBit(ip []byte, i int) int {
+	return int(ip[i/8]>>(7-uint(i%8))) & 1
+}
+
+// getCIDRIndex returns the index for cidrs from the per-query or inter-query
+// cache, building (and caching) it if necessary.
+func getCIDRIndex(bctx BuiltinContext, cidrs ast.Value) (*cidrIndex, error) {
+	var buckets map[int][]*cidrIndex
+	if bctx.Cache != nil {
+		if v, ok := bctx.Cache.Get(cidrMatchIndexCacheKey{}); ok {
+			buckets = v.(map[int][]*cidrIndex)
+		} else {
+			buckets = map[int][]*cidrIndex{}
+			bctx.Cache.Put(cidrMatchIndexCacheKey{}, buckets)
+		}
+	}
+
+	hash := cidrs.Hash()
+	for _, idx := range buckets[hash] {
+		if idx.cidrs.Compare(cidrs) == 0 {
+			return idx, nil
+		}
+	}
+
+	var c cache.InterQueryValueCacheBucket
+	if bctx.InterQueryBuiltinValueCache != nil {
+		c = bctx.InterQueryBuiltinValueCache.GetCache(cidrMatchIndexCacheName)
+	}
+
+	var idx *cidrIndex
+	if c != nil {
+		if v, ok := c.Get(cidrs); ok {
+			idx, _ = v.(*cidrIndex)
+		}
+	}
+
+	if idx == nil {
+		var err error
+		if idx, err = newCIDRIndex(cidrs); err != nil {
+			return nil, err
+		}
+		if c != nil {
+			c.Insert(cidrs, idx)
+		}
+	}
+
+	if buckets != nil {
+		buckets[hash] = append(buckets[hash], idx)
+	}
+
+	return idx, nil
+}
+
+func builtinNetCIDRMatchIndex(bctx BuiltinContext, operands []*ast.Term, iter func(*ast.Term) error) error {
+	switch cidrs := operands[0].Value.(type) {
+	case *ast.Array, ast.Set:
+	default:
+		return builtins.NewOperandTypeErr(1, cidrs, "array", "set")
+	}
+
+	s, err := builtins.StringOperand(operands[1].Value, 2)
+	if err != nil {
+		return err
+	}
+	ip, ok := parseIP(string(s))
+	if !ok {
+		return fmt.Errorf("operand 2: not a valid textual representation of an IP address: %s", string(s))
+	}
+
+	idx, err := getCIDRIndex(bctx, operands[0].Value)
+	if err != nil {
+		return err
+	}
+
+	return iter(ast.NewTerm(idx.lookup(ip)))
+}
+
+func init() {
+	// By default, the inter-query cache for CIDR indices is disabled.
+	cache.RegisterDefaultInterQueryBuiltinValueCacheConfig(cidrMatchIndexCacheName, nil)
+
+	RegisterBuiltinFunc(ast.NetCIDRMatchIndex.Name, builtinNetCIDRMatchIndex)
+}
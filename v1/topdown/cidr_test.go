@@ -8,6 +8,8 @@ import (
 	"github.com/open-policy-agent/opa/v1/ast"
 	"github.com/open-policy-agent/opa/v1/storage"
 	inmem "github.com/open-policy-agent/opa/v1/storage/inmem/test"
+	"github.com/open-policy-agent/opa/v1/topdown/builtins"
+	"github.com/open-policy-agent/opa/v1/topdown/cache"
 )
 
 func TestNetCIDRExpandCancellation(t *testing.T) {
@@ -44,3 +46,66 @@ func TestNetCIDRExpandCancellation(t *testing.T) {
 		t.Fatalf("Expected cancel error but got: %v (err: %v)", qrs, err)
 	}
 }
+
+func TestNetCIDRMatchIndexCache(t *testing.T) {
+	t.Parallel()
+
+	cidrs := ast.MustParseTerm(`{"10.0.0.0/8", "10.1.0.0/16", "192.168.0.1", "2001:db8::/32"}`).Value
+	other := ast.MustParseTerm(`["10.0.0.0/8"]`).Value
+
+	config, err := cache.ParseCachingConfig([]byte(`{"inter_query_builtin_value_cache": {"named": {"net_cidr_match_index": {"max_num_entries": 10}}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	iqvc := cache.NewInterQueryValueCache(context.Background(), config)
+
+	bctx := BuiltinContext{Cache: builtins.Cache{}, InterQueryBuiltinValueCache: iqvc}
+	idx1, err := getCIDRIndex(bctx, cidrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// same collection, same query
+	idx2, err := getCIDRIndex(bctx, ast.MustParseTerm(`{"2001:db8::/32", "192.168.0.1", "10.1.0.0/16", "10.0.0.0/8"}`).Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idx1 != idx2 {
+		t.Fatal("expected index to be reused within the query")
+	}
+
+	// different collection
+	idx3, err := getCIDRIndex(bctx, other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idx1 == idx3 {
+		t.Fatal("expected different index for different collection")
+	}
+
+	// same collection, next query
+	idx4, err := getCIDRIndex(BuiltinContext{Cache: builtins.Cache{}, InterQueryBuiltinValueCache: iqvc}, cidrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idx1 != idx4 {
+		t.Fatal("expected index to be reused across queries")
+	}
+
+	for ip, exp := range map[string]string{
+		"10.1.2.3":        `{"10.0.0.0/8", "10.1.0.0/16"}`,
+		"10.2.0.1":        `{"10.0.0.0/8"}`,
+		"192.168.0.1":     `{"192.168.0.1"}`,
+		"192.168.0.2":     `set()`,
+		"2001:db8::1":     `{"2001:db8::/32"}`,
+		"::ffff:10.0.0.1": `{"10.0.0.0/8"}`,
+	} {
+		addr, ok := parseIP(ip)
+		if !ok {
+			t.Fatalf("invalid IP %s", ip)
+		}
+		if act := idx1.lookup(addr); act.Compare(ast.MustParseTerm(exp).Value) != 0 {
+			t.Errorf("%s: expected %v, got %v", ip, exp, act)
+		}
+	}
+}
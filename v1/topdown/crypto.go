@@ -71,6 +71,8 @@ func builtinCryptoX509ParseCertificates(_ BuiltinContext, operands []*ast.Term,
 type extendedCert struct {
 	x509.Certificate
 	URIStrings []string
+	PolicyOIDs []string                     `json:",omitempty"`
+	SCTs       []signedCertificateTimestamp `json:",omitempty"`
 }
 
 func extendCertificates(certs []*x509.Certificate) []extendedCert {
@@ -85,6 +87,8 @@ func extendCertificates(certs []*x509.Certificate) []extendedCert {
 				processedCerts[i].URIStrings[j] = uri.String()
 			}
 		}
+		processedCerts[i].PolicyOIDs = policyOIDs(cert)
+		processedCerts[i].SCTs = embeddedSCTs(cert)
 	}
 	return processedCerts
 }
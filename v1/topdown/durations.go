@@ -0,0 +1,224 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package topdown
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/topdown/builtins"
+)
+
+const (
+	day  = 24 * time.Hour
+	week = 7 * day
+)
+
+var durationUnits = map[string]time.Duration{
+	"ns": time.Nanosecond,
+	"us": time.Microsecond,
+	"µs": time.Microsecond, // U+00B5 = micro symbol
+	"μs": time.Microsecond, // U+03BC = Greek letter mu
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+	"d":  day,
+	"w":  week,
+}
+
+// canonicalDurationUnits are the units of normalized durations, largest first.
+var canonicalDurationUnits = []struct {
+	name string
+	unit time.Duration
+}{
+	{"d", day},
+	{"h", time.Hour},
+	{"m", time.Minute},
+	{"s", time.Second},
+	{"ms", time.Millisecond},
+	{"us", time.Microsecond},
+	{"ns", time.Nanosecond},
+}
+
+// parseDuration parses durations like time.ParseDuration, but also accepts
+// days and weeks. Fractions of nanoseconds are truncated.
+func parseDuration(name, s string) (time.Duration, error) {
+	orig := s
+
+	neg := false
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+
+	if s == "0" {
+		return 0, nil
+	}
+	if s == "" {
+		return 0, fmt.Errorf("%s: invalid duration %q", name, orig)
+	}
+
+	var total big.Rat
+	for s != "" {
+		i := 0
+		for i < len(s) && (s[i] == '.' || s[i] >= '0' && s[i] <= '9') {
+			i++
+		}
+		num := s[:i]
+		s = s[i:]
+
+		j := 0
+		for j < len(s) && s[j] != '.' && (s[j] < '0' || s[j] > '9') {
+			j++
+		}
+		unit := s[:j]
+		s = s[j:]
+
+		var x big.Rat
+		if num == "" || num == "." || strings.Count(num, ".") > 1 {
+			return 0, fmt.Errorf("%s: invalid duration %q", name, orig)
+		}
+		if _, ok := x.SetString(num); !ok {
+			return 0, fmt.Errorf("%s: invalid duration %q", name, orig)
+		}
+		if unit == "" {
+			return 0, fmt.Errorf("%s: missing unit in duration %q", name, orig)
+		}
+		u, ok := durationUnits[unit]
+		if !ok {
+			return 0, fmt.Errorf("%s: unknown unit %q in duration %q", name, unit, orig)
+		}
+
+		total.Add(&total, x.Mul(&x, new(big.Rat).SetInt64(int64(u))))
+	}
+
+	if neg {
+		total.Neg(&total)
+	}
+
+	ns := new(big.Int).Quo(total.Num(), total.Denom())
+	if !ns.IsInt64() {
+		return 0, fmt.Errorf("%s: duration %q out of range", name, orig)
+	}
+
+	return time.Duration(ns.Int64()), nil
+}
+
+// durationOperand returns the duration of a string operand, or of an integer
+// number of nanoseconds.
+func durationOperand(name string, x ast.Value, pos int) (time.Duration, error) {
+	switch x := x.(type) {
+	case ast.String:
+		return parseDuration(name, string(x))
+	case ast.Number:
+		ns, ok := x.Int64()
+		if !ok {
+			return 0, fmt.Errorf("%s: operand %d must be an integer number of nanoseconds", name, pos)
+		}
+		return time.Duration(ns), nil
+	}
+	return 0, builtins.NewOperandTypeErr(pos, x, "string", "number")
+}
+
+// formatDuration returns the canonical string of d, which uses the largest
+// units first and leaves out zero amounts.
+func formatDuration(d time.Duration) string {
+	if d == 0 {
+		return "0s"
+	}
+
+	var sb strings.Builder
+
+	// Work on the unsigned magnitude, as -d overflows for the smallest duration.
+	u := uint64(d)
+	if d < 0 {
+		sb.WriteByte('-')
+		u = -u
+	}
+
+	for _, c := range canonicalDurationUnits {
+		if n := u / uint64(c.unit); n > 0 {
+			fmt.Fprintf(&sb, "%d%s", n, c.name)
+			u %= uint64(c.unit)
+		}
+	}
+
+	return sb.String()
+}
+
+func builtinUnitsParseDuration(_ BuiltinContext, operands []*ast.Term, iter func(*ast.Term) error) error {
+	s, err := builtins.StringOperand(operands[0].Value, 1)
+	if err != nil {
+		return err
+	}
+
+	d, err := parseDuration(ast.UnitsParseDuration.Name, string(s))
+	if err != nil {
+		return err
+	}
+
+	return iter(ast.NumberTerm(int64ToJSONNumber(int64(d))))
+}
+
+func builtinUnitsAddDurations(_ BuiltinContext, operands []*ast.Term, iter func(*ast.Term) error) error {
+	name := ast.UnitsAddDurations.Name
+
+	a, err := durationOperand(name, operands[0].Value, 1)
+	if err != nil {
+		return err
+	}
+	b, err := durationOperand(name, operands[1].Value, 2)
+	if err != nil {
+		return err
+	}
+
+	sum := a + b
+	if (b > 0 && sum < a) || (b < 0 && sum > a) {
+		return fmt.Errorf("%s: sum of durations out of range", name)
+	}
+
+	return iter(ast.NumberTerm(int64ToJSONNumber(int64(sum))))
+}
+
+func builtinUnitsCompareDurations(_ BuiltinContext, operands []*ast.Term, iter func(*ast.Term) error) error {
+	name := ast.UnitsCompareDurations.Name
+
+	a, err := durationOperand(name, operands[0].Value, 1)
+	if err != nil {
+		return err
+	}
+	b, err := durationOperand(name, operands[1].Value, 2)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case a < b:
+		return iter(ast.InternedTerm(-1))
+	case a > b:
+		return iter(ast.InternedTerm(1))
+	}
+	return iter(ast.InternedTerm(0))
+}
+
+func builtinUnitsNormalizeDuration(_ BuiltinContext, operands []*ast.Term, iter func(*ast.Term) error) error {
+	d, err := durationOperand(ast.UnitsNormalizeDuration.Name, operands[0].Value, 1)
+	if err != nil {
+		return err
+	}
+
+	return iter(ast.StringTerm(formatDuration(d)))
+}
+
+func init() {
+	RegisterBuiltinFunc(ast.UnitsParseDuration.Name, builtinUnitsParseDuration)
+	RegisterBuiltinFunc(ast.UnitsAddDurations.Name, builtinUnitsAddDurations)
+	RegisterBuiltinFunc(ast.UnitsCompareDurations.Name, builtinUnitsCompareDurations)
+	RegisterBuiltinFunc(ast.UnitsNormalizeDuration.Name, builtinUnitsNormalizeDuration)
+}
@@ -11,11 +11,11 @@ import (
 	"sync"
 
 	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/ast/transform"
 	"github.com/open-policy-agent/opa/v1/metrics"
 	"github.com/open-policy-agent/opa/v1/storage"
 	"github.com/open-policy-agent/opa/v1/topdown/builtins"
 	"github.com/open-policy-agent/opa/v1/topdown/cache"
-	"github.com/open-policy-agent/opa/v1/topdown/copypropagation"
 	"github.com/open-policy-agent/opa/v1/topdown/print"
 	"github.com/open-policy-agent/opa/v1/tracing"
 	"github.com/open-policy-agent/opa/v1/types"
@@ -68,6 +68,7 @@ type eval struct {
 	metrics                     metrics.Metrics
 	seed                        io.Reader
 	cancel                      Cancel
+	checkpoints                 *checkpoints
 	queryCompiler               ast.QueryCompiler
 	store                       storage.Store
 	txn                         storage.Transaction
@@ -82,6 +83,7 @@ type eval struct {
 	caller                      *eval
 	bindings                    *bindings
 	compiler                    *ast.Compiler
+	capabilities                *ast.Capabilities
 	input                       *ast.Term
 	data                        *ast.Term
 	external                    *resolverTrie
@@ -99,6 +101,7 @@ type eval struct {
 	saveNamespace               *ast.Term
 	inliningControl             *inliningControl
 	runtime                     *ast.Term
+	requestMetadata             *ast.Term
 	builtinErrors               *builtinErrors
 	roundTripper                CustomizeRoundTripper
 	genvarprefix                string
@@ -115,7 +118,11 @@ type eval struct {
 	skipSaveNamespace           bool
 	findOne                     bool
 	strictObjects               bool
+	sortedIteration             bool
+	dataSnapshot                bool // data is replaced by the store, see Query.WithDataSnapshot
 	defined                     bool
+	rule                        *ast.Rule // rule whose body is evaluated, if any
+	ruleDepth                   int       // number of nested rule evaluations
 }
 
 type evp struct {
@@ -203,6 +210,13 @@ func (e *eval) child(query ast.Body, cpy *eval) {
 	cpy.findOne = false
 }
 
+// ruleChild is like child, for evaluating the body of rule.
+func (e *eval) ruleChild(rule *ast.Rule, cpy *eval) {
+	e.child(rule.Body, cpy)
+	cpy.rule = rule
+	cpy.ruleDepth++
+}
+
 func (e *eval) next(iter evalIterator) error {
 	e.index++
 	err := e.evalExpr(iter)
@@ -399,6 +413,13 @@ func (e *eval) evalExpr(iter evalIterator) error {
 		}
 		return nil
 	}
+
+	if e.checkpoints != nil {
+		if err := e.checkpoints.step(e.ctx); err != nil {
+			return err
+		}
+	}
+
 	expr := e.query[e.index]
 
 	e.traceEval(expr)
@@ -775,10 +796,10 @@ func (e *eval) evalNotPartial(iter evalIterator) error {
 	// query onto the save stack to avoid mutating the current save query. If
 	// shallow inlining is not enabled, run copy propagation to further simplify
 	// the result.
-	var cp *copypropagation.CopyPropagator
+	var cp *transform.CopyPropagator
 
 	if !e.inliningControl.shallow {
-		cp = copypropagation.New(unknowns).WithEnsureNonEmptyBody(true).WithCompiler(e.compiler)
+		cp = transform.NewCopyPropagator(unknowns).WithEnsureNonEmptyBody(true).WithCompiler(e.compiler)
 	}
 
 	var savedQueries []ast.Body
@@ -957,11 +978,6 @@ func (e *eval) evalCall(terms []*ast.Term, iter unifyIterator) error {
 			parentID = e.parent.queryID
 		}
 
-		var capabilities *ast.Capabilities
-		if e.compiler != nil {
-			capabilities = e.compiler.Capabilities()
-		}
-
 		bctx = &BuiltinContext{
 			Context:                     e.ctx,
 			Metrics:                     e.metrics,
@@ -969,6 +985,7 @@ func (e *eval) evalCall(terms []*ast.Term, iter unifyIterator) error {
 			Time:                        e.time,
 			Cancel:                      e.cancel,
 			Runtime:                     e.runtime,
+			RequestMetadata:             e.requestMetadata,
 			Cache:                       e.builtinCache,
 			InterQueryBuiltinCache:      e.interQueryBuiltinCache,
 			InterQueryBuiltinValueCache: e.interQueryBuiltinValueCache,
@@ -979,8 +996,10 @@ func (e *eval) evalCall(terms []*ast.Term, iter unifyIterator) error {
 			QueryID:                     e.queryID,
 			ParentID:                    parentID,
 			PrintHook:                   e.printHook,
+			rule:                        e.rule,
+			ruleDepth:                   e.ruleDepth,
 			DistributedTracingOpts:      e.tracingOpts,
-			Capabilities:                capabilities,
+			Capabilities:                e.capabilities,
 			RoundTripper:                e.roundTripper,
 		}
 	}
@@ -1215,7 +1234,12 @@ func (e *eval) biunifyRef(a, b *ast.Term, b1, b2 *bindings, iter unifyIterator)
 	ref := a.Value.(ast.Ref)
 
 	if ref[0].Equal(ast.DefaultRootDocument) {
-		node := e.compiler.RuleTree.Child(ref[0].Value)
+		// Rules do not define any documents if the data is replaced by a
+		// snapshot, like with `with data as ...`.
+		var node *ast.TreeNode
+		if !e.dataSnapshot {
+			node = e.compiler.RuleTree.Child(ref[0].Value)
+		}
 		eval := evalTree{
 			e:         e,
 			ref:       ref,
@@ -1966,6 +1990,8 @@ func (e *evalBuiltin) eval(iter unifyIterator) error {
 		// Unify against the NDBCache result if present.
 		if v, ok := e.bctx.NDBuiltinCache.Get(e.bi.Name, ast.NewArray(operands[:endIndex]...)); ok {
 			switch {
+			case len(e.bi.OutputArgs) > 0:
+				return e.unifyOutputArgs(ast.NewTerm(v), iter)
 			case e.bi.Decl.Result() == nil:
 				return iter()
 			case len(operands) == numDeclArgs:
@@ -1997,9 +2023,19 @@ func (e *evalBuiltin) eval(iter unifyIterator) error {
 
 		e.e.instr.stopTimer(evalOpBuiltinCall)
 
+		// If the NDBCache is present, we can assume this builtin
+		// call was not cached earlier. Populate the NDBCache from the
+		// output term before continuing, so that the call is cached when
+		// results are produced.
+		if e.canUseNDBCache(e.bi) {
+			e.bctx.NDBuiltinCache.Put(e.bi.Name, ast.NewArray(operands[:endIndex]...), output.Value)
+		}
+
 		var err error
 
 		switch {
+		case len(e.bi.OutputArgs) > 0:
+			err = e.unifyOutputArgs(output, iter)
 		case e.bi.Decl.Result() == nil:
 			err = iter()
 		case len(operands) == numDeclArgs:
@@ -2010,13 +2046,6 @@ func (e *evalBuiltin) eval(iter unifyIterator) error {
 			err = e.e.unify(e.terms[endIndex], output, iter)
 		}
 
-		// If the NDBCache is present, we can assume this builtin
-		// call was not cached earlier.
-		if e.canUseNDBCache(e.bi) {
-			// Populate the NDBCache from the output term.
-			e.bctx.NDBuiltinCache.Put(e.bi.Name, ast.NewArray(operands[:endIndex]...), output.Value)
-		}
-
 		if err != nil {
 			// NOTE(sr): We wrap the errors here into Halt{} because we don't want to
 			// record them into builtinErrors below. The errors set here are coming from
@@ -2041,6 +2070,30 @@ func (e *evalBuiltin) eval(iter unifyIterator) error {
 	return err
 }
 
+// unifyOutputArgs unifies the output arguments of a built-in function that
+// binds its arguments with the values in the array output.
+func (e *evalBuiltin) unifyOutputArgs(output *ast.Term, iter unifyIterator) error {
+	arr, ok := output.Value.(*ast.Array)
+	if !ok || arr.Len() != len(e.bi.OutputArgs) {
+		return fmt.Errorf("%v: expected array of %d output arguments but got %v", e.bi.Name, len(e.bi.OutputArgs), output)
+	}
+	for _, i := range e.bi.OutputArgs {
+		if i < 0 || i >= len(e.terms) {
+			return fmt.Errorf("%v: output argument %d out of range", e.bi.Name, i)
+		}
+	}
+	return e.unifyOutputArgsRec(arr, 0, iter)
+}
+
+func (e *evalBuiltin) unifyOutputArgsRec(arr *ast.Array, idx int, iter unifyIterator) error {
+	if idx == arr.Len() {
+		return iter()
+	}
+	return e.e.unify(e.terms[e.bi.OutputArgs[idx]], arr.Elem(idx), func() error {
+		return e.unifyOutputArgsRec(arr, idx+1, iter)
+	})
+}
+
 type evalFunc struct {
 	e     *eval
 	ir    *ast.IndexResult
@@ -2225,7 +2278,7 @@ func (e evalFunc) evalOneRule(iter unifyIterator, rule *ast.Rule, args []*ast.Te
 	child := evalPool.Get()
 	defer evalPool.Put(child)
 
-	e.e.child(rule.Body, child)
+	e.e.ruleChild(rule, child)
 	child.findOne = findOne
 
 	var result *ast.Term
@@ -2315,7 +2368,7 @@ func (e evalFunc) partialEvalSupportRule(rule *ast.Rule, path ast.Ref) error {
 	child := evalPool.Get()
 	defer evalPool.Put(child)
 
-	e.e.child(rule.Body, child)
+	e.e.ruleChild(rule, child)
 	child.traceEnter(rule)
 
 	e.e.saveStack.PushQuery(nil)
@@ -2485,6 +2538,10 @@ func (e evalTree) enumerate(iter unifyIterator) error {
 	dc.deferred = nil
 	defer deecPool.Put(dc)
 
+	if obj, ok := doc.(ast.Object); ok && e.e.sortedIteration && e.node != nil && len(e.node.Sorted) > 0 {
+		return e.enumerateSorted(iter, obj)
+	}
+
 	if doc != nil {
 		switch doc := doc.(type) {
 		case *ast.Array:
@@ -2540,6 +2597,36 @@ func (e evalTree) enumerate(iter unifyIterator) error {
 	return nil
 }
 
+// enumerateSorted is like enumerate, for documents defined by both the base
+// document doc and rules. It visits the keys of both in sorted order.
+func (e evalTree) enumerateSorted(iter unifyIterator, doc ast.Object) error {
+	keys := doc.Keys()
+	for _, k := range e.node.Sorted {
+		keys = append(keys, ast.NewTerm(k))
+	}
+	slices.SortFunc(keys, ast.TermValueCompare)
+	keys = slices.CompactFunc(keys, ast.TermValueEqual)
+
+	dc := deecPool.Get().(*deferredEarlyExitContainer)
+	dc.deferred = nil
+	defer deecPool.Put(dc)
+
+	for _, k := range keys {
+		err := e.e.biunify(k, e.ref[e.pos], e.bindings, e.bindings, func() error {
+			return e.next(iter, k)
+		})
+		if err := dc.handleErr(err); err != nil {
+			return err
+		}
+	}
+
+	if dc.deferred != nil {
+		return dc.copyError()
+	}
+
+	return nil
+}
+
 func (e evalTree) extent() (*ast.Term, error) {
 	base, err := e.e.Resolve(e.plugged)
 	if err != nil {
@@ -2845,7 +2932,7 @@ func (e evalVirtualPartial) evalAllRulesNoCache(rules []*ast.Rule) (*ast.Term, e
 	defer evalPool.Put(child)
 
 	for _, rule := range rules {
-		e.e.child(rule.Body, child)
+		e.e.ruleChild(rule, child)
 		child.traceEnter(rule)
 		err := child.eval(func(*eval) error {
 			child.traceExit(rule)
@@ -2881,7 +2968,7 @@ func (e evalVirtualPartial) evalOneRulePreUnify(iter unifyIterator, rule *ast.Ru
 	child := evalPool.Get()
 	defer evalPool.Put(child)
 
-	e.e.child(rule.Body, child)
+	e.e.ruleChild(rule, child)
 
 	child.traceEnter(rule)
 	var defined bool
@@ -2976,7 +3063,7 @@ func (e evalVirtualPartial) evalOneRulePostUnify(iter unifyIterator, rule *ast.R
 	child := evalPool.Get()
 	defer evalPool.Put(child)
 
-	e.e.child(rule.Body, child)
+	e.e.ruleChild(rule, child)
 
 	child.traceEnter(rule)
 	var defined bool
@@ -3063,7 +3150,7 @@ func (e evalVirtualPartial) partialEvalSupportRule(rule *ast.Rule, _ ast.Ref) (b
 	child := evalPool.Get()
 	defer evalPool.Put(child)
 
-	e.e.child(rule.Body, child)
+	e.e.ruleChild(rule, child)
 	child.traceEnter(rule)
 
 	e.e.saveStack.PushQuery(nil)
@@ -3106,7 +3193,7 @@ func (e evalVirtualPartial) partialEvalSupportRule(rule *ast.Rule, _ ast.Ref) (b
 			}
 
 			if !e.e.inliningControl.shallow {
-				cp := copypropagation.New(head.Vars()).
+				cp := transform.NewCopyPropagator(head.Vars()).
 					WithEnsureNonEmptyBody(true).
 					WithCompiler(e.e.compiler)
 				plugged = applyCopyPropagation(cp, e.e.instr, plugged)
@@ -3548,7 +3635,7 @@ func (e evalVirtualComplete) evalValueRule(iter unifyIterator, rule *ast.Rule, p
 	child := evalPool.Get()
 	defer evalPool.Put(child)
 
-	e.e.child(rule.Body, child)
+	e.e.ruleChild(rule, child)
 	child.findOne = findOne
 	child.traceEnter(rule)
 	var result *ast.Term
@@ -3587,7 +3674,7 @@ func (e evalVirtualComplete) partialEval(iter unifyIterator) error {
 	defer evalPool.Put(child)
 
 	for _, rule := range e.ir.Rules {
-		e.e.child(rule.Body, child)
+		e.e.ruleChild(rule, child)
 		child.traceEnter(rule)
 
 		err := child.eval(func(child *eval) error {
@@ -3653,7 +3740,7 @@ func (e evalVirtualComplete) partialEvalSupportRule(rule *ast.Rule, path ast.Ref
 	child := evalPool.Get()
 	defer evalPool.Put(child)
 
-	e.e.child(rule.Body, child)
+	e.e.ruleChild(rule, child)
 	child.traceEnter(rule)
 
 	e.e.saveStack.PushQuery(nil)
@@ -3672,7 +3759,7 @@ func (e evalVirtualComplete) partialEvalSupportRule(rule *ast.Rule, path ast.Ref
 			head := ast.RefHead(ruleRef, child.bindings.PlugNamespaced(rule.Head.Value, e.e.caller.bindings))
 
 			if !e.e.inliningControl.shallow {
-				cp := copypropagation.New(head.Vars()).
+				cp := transform.NewCopyPropagator(head.Vars()).
 					WithEnsureNonEmptyBody(true).
 					WithCompiler(e.e.compiler)
 				plugged = applyCopyPropagation(cp, e.e.instr, plugged)
@@ -4056,7 +4143,7 @@ func getSavePairsFromTerm(x *ast.Term, b *bindings, result []savePair) []savePai
 	return result
 }
 
-func applyCopyPropagation(p *copypropagation.CopyPropagator, instr *Instrumentation, body ast.Body) ast.Body {
+func applyCopyPropagation(p *transform.CopyPropagator, instr *Instrumentation, body ast.Body) ast.Body {
 	instr.startTimer(partialOpCopyPropagation)
 	result := p.Apply(body)
 	instr.stopTimer(partialOpCopyPropagation)
@@ -258,6 +258,9 @@ func init() {
 	createCacheableHTTPStatusCodes()
 	initDefaults()
 	RegisterBuiltinFunc(ast.HTTPSend.Name, builtinHTTPSend)
+	cache.RegisterValueDecoder(ast.HTTPSend.Name, func(bs []byte) (cache.InterQueryCacheValue, error) {
+		return &interQueryCacheValue{Data: bs}, nil
+	})
 }
 
 func handleHTTPSendErr(bctx BuiltinContext, err error) error {
@@ -711,6 +714,8 @@ func createHTTPRequest(bctx BuiltinContext, obj ast.Object) (*http.Request, *htt
 		tlsConfig.ServerName = tlsServerName
 	}
 
+	client.Transport = withHTTPSendLimits(httpSendLimiter(bctx), client.Transport)
+
 	if len(bctx.DistributedTracingOpts) > 0 {
 		client.Transport = tracing.NewTransport(client.Transport, bctx.DistributedTracingOpts)
 	}
@@ -1133,6 +1138,14 @@ func (cb interQueryCacheValue) SizeInBytes() int64 {
 	return int64(len(cb.Data))
 }
 
+func (interQueryCacheValue) PersistedKind() string {
+	return ast.HTTPSend.Name
+}
+
+func (cb interQueryCacheValue) MarshalPersisted() ([]byte, error) {
+	return cb.Data, nil
+}
+
 func (cb *interQueryCacheValue) copyCacheData() (*interQueryCacheData, error) {
 	var res interQueryCacheData
 	err := util.UnmarshalJSON(cb.Data, &res)
@@ -1212,6 +1225,16 @@ func (*interQueryCacheData) SizeInBytes() int64 {
 	return 0
 }
 
+func (*interQueryCacheData) PersistedKind() string {
+	return ast.HTTPSend.Name
+}
+
+// MarshalPersisted marshals the response like interQueryCacheValue, so that
+// persisted responses are re-loaded in the default caching mode.
+func (c *interQueryCacheData) MarshalPersisted() ([]byte, error) {
+	return json.Marshal(c)
+}
+
 func (c *interQueryCacheData) Clone() (cache.InterQueryCacheValue, error) {
 	dup := make([]byte, len(c.RespBody))
 	copy(dup, c.RespBody)
@@ -0,0 +1,62 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package topdown
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/topdown/cache"
+)
+
+// httpSendLimiter returns the limiter of the http.send limits configured via
+// the `caching.http_send` configuration of the inter-query cache of bctx, or
+// nil if there is none, or no limits are configured.
+func httpSendLimiter(bctx BuiltinContext) *cache.HTTPSendLimiter {
+	p, ok := bctx.InterQueryBuiltinCache.(cache.HTTPSendLimiterProvider)
+	if !ok {
+		return nil
+	}
+	if l := p.HTTPSendLimiter(); l != nil && l.Enabled() {
+		return l
+	}
+	return nil
+}
+
+// withHTTPSendLimits returns a round tripper enforcing the limits of l around
+// rt. If l is nil, rt is returned as-is.
+func withHTTPSendLimits(l *cache.HTTPSendLimiter, rt http.RoundTripper) http.RoundTripper {
+	if l == nil {
+		return rt
+	}
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	return &limitedTransport{limiter: l, next: rt}
+}
+
+type limitedTransport struct {
+	limiter *cache.HTTPSendLimiter
+	next    http.RoundTripper
+}
+
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+
+	if err := t.limiter.Allow(host, time.Now()); err != nil {
+		return nil, err
+	}
+
+	release, err := t.limiter.Acquire(req.Context())
+	if err != nil {
+		t.limiter.Release(host)
+		return nil, err
+	}
+	defer release()
+
+	resp, err := t.next.RoundTrip(req)
+	t.limiter.Record(host, err != nil || resp.StatusCode >= http.StatusInternalServerError, time.Now())
+	return resp, err
+}
@@ -25,6 +25,8 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -1935,6 +1937,55 @@ func newQuery(qStr string, t0 time.Time) *Query {
 	return q
 }
 
+func TestHTTPSendInterQueryCachePersistence(t *testing.T) {
+	t.Parallel()
+
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Cache-Control", "max-age=290304000, public")
+		w.Header().Set("Date", time.Now().Format(time.RFC850))
+		_, _ = w.Write([]byte(`{"x": 1}`))
+	}))
+	defer ts.Close()
+
+	config, err := iCache.ParseCachingConfig([]byte(`{"inter_query_builtin_cache": {"persistence": {"directory": "` + t.TempDir() + `", "http_send": true}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	qStr := strings.ReplaceAll(`http.send({"method": "get", "url": "%URL%", "cache": true}, x)`, "%URL%", ts.URL)
+
+	// Each run uses a new cache, as a restarted OPA would.
+	for i := range 2 {
+		interQueryCache := iCache.NewInterQueryCacheWithContext(context.Background(), config)
+
+		ctx := context.Background()
+		store := inmem.New()
+		q := NewQuery(ast.MustParseBody(qStr)).
+			WithCompiler(ast.NewCompiler()).
+			WithInterQueryBuiltinCache(interQueryCache).
+			WithStore(store).
+			WithTransaction(storage.NewTransactionOrDie(ctx, store))
+
+		res, err := q.Run(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if body := res[0]["x"].Value.(ast.Object).Get(ast.StringTerm("raw_body")); body.Value.Compare(ast.String(`{"x": 1}`)) != 0 {
+			t.Fatalf("Expected response on run %d, got %v", i, body)
+		}
+
+		if err := interQueryCache.(iCache.Persister).Persist(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if act := requests.Load(); act != 1 {
+		t.Fatalf("Expected to get 1 request, got %d", act)
+	}
+}
+
 func TestInsertIntoHTTPSendInterQueryCacheError(t *testing.T) {
 	t.Parallel()
 
@@ -3430,6 +3481,94 @@ func TestHTTPSendMetrics(t *testing.T) {
 	})
 }
 
+func TestHTTPSendCircuitBreaker(t *testing.T) {
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	config, err := iCache.ParseCachingConfig([]byte(`{"http_send": {"circuit_breaker": {"failure_threshold": 2, "open_duration_seconds": 60}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	interQueryCache := iCache.NewInterQueryCache(config)
+
+	q := NewQuery(ast.MustParseBody(fmt.Sprintf(`http.send({"method": "get", "url": %q, "raise_error": false}, resp)`, ts.URL))).
+		WithInterQueryBuiltinCache(interQueryCache)
+
+	for range 2 {
+		if _, err := q.Run(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// breaker is open now: the request fails fast without reaching the server
+	rs, err := q.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected 2 calls to reach the server, got %d", calls)
+	}
+	resp := rs[0][ast.Var("resp")].Value.(ast.Object)
+	msg := resp.Get(ast.StringTerm("error")).Value.(ast.Object).Get(ast.StringTerm("message"))
+	if msg == nil || !strings.Contains(string(msg.Value.(ast.String)), "circuit breaker open") {
+		t.Fatalf("expected circuit breaker error, got %v", resp)
+	}
+
+	// a success closes the breaker
+	interQueryCache.(iCache.HTTPSendLimiterProvider).HTTPSendLimiter().Record(strings.TrimPrefix(ts.URL, "http://"), false, time.Now())
+	if _, err := q.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 calls to reach the server, got %d", calls)
+	}
+}
+
+func TestHTTPSendMaxConcurrentRequests(t *testing.T) {
+	var inflight, peak atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		n := inflight.Add(1)
+		defer inflight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	config, err := iCache.ParseCachingConfig([]byte(`{"http_send": {"max_concurrent_requests": 2}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	interQueryCache := iCache.NewInterQueryCache(config)
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q := NewQuery(ast.MustParseBody(fmt.Sprintf(`http.send({"method": "get", "url": "%s/%d"})`, ts.URL, i))).
+				WithInterQueryBuiltinCache(interQueryCache)
+			if _, err := q.Run(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if p := peak.Load(); p > 2 {
+		t.Fatalf("expected at most 2 in-flight requests, got %d", p)
+	}
+}
+
 // Warning(philipc): This test cannot be run in parallel with other tests, due
 // to the t.Setenv calls used to set up the server environment.
 func TestInitDefaults(t *testing.T) {
@@ -3665,6 +3804,22 @@ func TestHTTPGetRequestAllowNet(t *testing.T) {
 			setAllowNet([]string{"example.com"}),
 			expectedError,
 		},
+		{
+			"http.send query allow_net overrides capabilities",
+			rules,
+			func(q *Query) *Query {
+				return setAllowNet([]string{})(q).WithAllowNet([]string{serverHost})
+			},
+			resultObj.String(),
+		},
+		{
+			"http.send query allow_net empty",
+			rules,
+			func(q *Query) *Query {
+				return q.WithAllowNet([]string{})
+			},
+			expectedError,
+		},
 	}
 
 	data := loadSmallTestData()
@@ -0,0 +1,198 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package topdown
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/topdown/builtins"
+)
+
+var canonicalHashes = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha384": sha512.New384,
+	"sha512": sha512.New,
+}
+
+func builtinJSONCanonicalize(_ BuiltinContext, operands []*ast.Term, iter func(*ast.Term) error) error {
+	bs, err := canonicalJSON(operands[0].Value)
+	if err != nil {
+		return err
+	}
+	return iter(ast.StringTerm(string(bs)))
+}
+
+func builtinJSONCanonicalHash(_ BuiltinContext, operands []*ast.Term, iter func(*ast.Term) error) error {
+	alg, err := builtins.StringOperand(operands[1].Value, 2)
+	if err != nil {
+		return err
+	}
+
+	newHash, ok := canonicalHashes[string(alg)]
+	if !ok {
+		return builtins.NewOperandErr(2, "unsupported hash algorithm %v, must be one of: sha256, sha384, sha512", alg)
+	}
+
+	bs, err := canonicalJSON(operands[0].Value)
+	if err != nil {
+		return err
+	}
+
+	h := newHash()
+	h.Write(bs)
+	return iter(ast.StringTerm(hex.EncodeToString(h.Sum(nil))))
+}
+
+// canonicalJSON serializes x according to the JSON Canonicalization Scheme
+// (JCS), RFC 8785. Sets are serialized as arrays, like by json.marshal.
+func canonicalJSON(x ast.Value) ([]byte, error) {
+	v, err := ast.JSON(x)
+	if err != nil {
+		return nil, err
+	}
+
+	var sb strings.Builder
+	if err := writeCanonicalJSON(&sb, v); err != nil {
+		return nil, err
+	}
+	return []byte(sb.String()), nil
+}
+
+func writeCanonicalJSON(sb *strings.Builder, v any) error {
+	switch v := v.(type) {
+	case nil:
+		sb.WriteString("null")
+	case bool:
+		sb.WriteString(strconv.FormatBool(v))
+	case json.Number:
+		f, err := strconv.ParseFloat(string(v), 64)
+		if err != nil || math.IsInf(f, 0) {
+			return fmt.Errorf("number %v cannot be represented as an IEEE 754 double", v)
+		}
+		sb.WriteString(canonicalNumber(f))
+	case string:
+		writeCanonicalString(sb, v)
+	case []any:
+		sb.WriteByte('[')
+		for i := range v {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			if err := writeCanonicalJSON(sb, v[i]); err != nil {
+				return err
+			}
+		}
+		sb.WriteByte(']')
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		// Properties are sorted by the UTF-16 code units of their names.
+		slices.SortFunc(keys, func(a, b string) int {
+			return slices.Compare(utf16.Encode([]rune(a)), utf16.Encode([]rune(b)))
+		})
+		sb.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			writeCanonicalString(sb, k)
+			sb.WriteByte(':')
+			if err := writeCanonicalJSON(sb, v[k]); err != nil {
+				return err
+			}
+		}
+		sb.WriteByte('}')
+	default:
+		return fmt.Errorf("unexpected value type %T", v)
+	}
+	return nil
+}
+
+// writeCanonicalString writes s like ECMAScript's JSON.stringify: only quotes,
+// backslashes and control characters are escaped.
+func writeCanonicalString(sb *strings.Builder, s string) {
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			sb.WriteString(`\"`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\b':
+			sb.WriteString(`\b`)
+		case '\f':
+			sb.WriteString(`\f`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(sb, `\u%04x`, r)
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	sb.WriteByte('"')
+}
+
+// canonicalNumber formats f like ECMAScript's Number.prototype.toString, as
+// required by RFC 8785, section 3.2.2.3.
+func canonicalNumber(f float64) string {
+	if f == 0 {
+		return "0" // includes -0
+	}
+	if f < 0 {
+		return "-" + canonicalNumber(-f)
+	}
+
+	// Shortest digits d1.d2...dk and exponent, e.g., 1.2345e+02.
+	mantissa, exp, _ := strings.Cut(strconv.FormatFloat(f, 'e', -1, 64), "e")
+	digits := strings.Replace(mantissa, ".", "", 1)
+	e, _ := strconv.Atoi(exp)
+
+	k := len(digits)
+	n := e + 1
+
+	switch {
+	case k <= n && n <= 21:
+		return digits + strings.Repeat("0", n-k)
+	case 0 < n && n <= 21:
+		return digits[:n] + "." + digits[n:]
+	case -6 < n && n <= 0:
+		return "0." + strings.Repeat("0", -n) + digits
+	}
+
+	sign := "+"
+	if n-1 < 0 {
+		sign = "-"
+	}
+	exponent := "e" + sign + strconv.Itoa(int(math.Abs(float64(n-1))))
+	if k == 1 {
+		return digits + exponent
+	}
+	return digits[:1] + "." + digits[1:] + exponent
+}
+
+func init() {
+	RegisterBuiltinFunc(ast.JSONCanonicalize.Name, builtinJSONCanonicalize)
+	RegisterBuiltinFunc(ast.JSONCanonicalHash.Name, builtinJSONCanonicalHash)
+}
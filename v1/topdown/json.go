@@ -7,6 +7,7 @@ package topdown
 import (
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/open-policy-agent/opa/v1/ast"
@@ -398,8 +399,94 @@ func builtinJSONPatch(_ BuiltinContext, operands []*ast.Term, iter func(*ast.Ter
 	return iter(patched)
 }
 
+func builtinJSONPatchDiff(_ BuiltinContext, operands []*ast.Term, iter func(*ast.Term) error) error {
+	var ops []*ast.Term
+	diffPatches(nil, operands[0], operands[1], &ops)
+	return iter(ast.ArrayTerm(ops...))
+}
+
+// diffPatches appends the JSON patch operations transforming a into b, both
+// located at path, to ops.
+func diffPatches(path ast.Ref, a, b *ast.Term, ops *[]*ast.Term) {
+	switch x := a.Value.(type) {
+	case ast.Object:
+		if y, ok := b.Value.(ast.Object); ok {
+			for _, k := range x.Keys() {
+				if v := y.Get(k); v != nil {
+					diffPatches(path.Append(k), x.Get(k), v, ops)
+				} else {
+					*ops = append(*ops, patchOp("remove", path.Append(k), nil))
+				}
+			}
+			for _, k := range y.Keys() {
+				if x.Get(k) == nil {
+					*ops = append(*ops, patchOp("add", path.Append(k), y.Get(k)))
+				}
+			}
+			return
+		}
+	case *ast.Array:
+		if y, ok := b.Value.(*ast.Array); ok {
+			n := min(x.Len(), y.Len())
+			for i := range n {
+				diffPatches(path.Append(ast.StringTerm(strconv.Itoa(i))), x.Elem(i), y.Elem(i), ops)
+			}
+			// remove trailing elements back to front so that indices stay valid
+			for i := x.Len() - 1; i >= n; i-- {
+				*ops = append(*ops, patchOp("remove", path.Append(ast.StringTerm(strconv.Itoa(i))), nil))
+			}
+			for i := n; i < y.Len(); i++ {
+				*ops = append(*ops, patchOp("add", path.Append(ast.StringTerm(strconv.Itoa(i))), y.Elem(i)))
+			}
+			return
+		}
+	case ast.Set:
+		if y, ok := b.Value.(ast.Set); ok {
+			x.Diff(y).Sorted().Foreach(func(e *ast.Term) {
+				*ops = append(*ops, patchOp("remove", path.Append(e), nil))
+			})
+			y.Diff(x).Sorted().Foreach(func(e *ast.Term) {
+				*ops = append(*ops, patchOp("add", path.Append(e), e))
+			})
+			return
+		}
+	}
+
+	if !a.Equal(b) {
+		*ops = append(*ops, patchOp("replace", path, b))
+	}
+}
+
+func patchOp(op string, path ast.Ref, value *ast.Term) *ast.Term {
+	obj := ast.NewObject(
+		ast.Item(ast.InternedTerm("op"), ast.InternedTerm(op)),
+		ast.Item(ast.InternedTerm("path"), patchPath(path)),
+	)
+	if value != nil {
+		obj.Insert(ast.InternedTerm("value"), value)
+	}
+	return ast.NewTerm(obj)
+}
+
+// patchPath renders path as a JSON pointer if all its segments are strings;
+// otherwise, as an array of path segments. Array indices are string segments,
+// like in JSON pointers.
+func patchPath(path ast.Ref) *ast.Term {
+	var sb strings.Builder
+	for _, t := range path {
+		s, ok := t.Value.(ast.String)
+		if !ok {
+			return ast.ArrayTerm(path...)
+		}
+		sb.WriteByte('/')
+		sb.WriteString(strings.ReplaceAll(strings.ReplaceAll(string(s), "~", "~0"), "/", "~1"))
+	}
+	return ast.StringTerm(sb.String())
+}
+
 func init() {
 	RegisterBuiltinFunc(ast.JSONFilter.Name, builtinJSONFilter)
 	RegisterBuiltinFunc(ast.JSONRemove.Name, builtinJSONRemove)
 	RegisterBuiltinFunc(ast.JSONPatch.Name, builtinJSONPatch)
+	RegisterBuiltinFunc(ast.JSONPatchDiff.Name, builtinJSONPatchDiff)
 }
@@ -40,10 +40,20 @@ func builtinPrint(bctx BuiltinContext, operands []*ast.Term, iter func(*ast.Term
 
 	buf := make([]string, arr.Len())
 
+	var path ast.Ref
+	if bctx.rule != nil && bctx.rule.Module != nil {
+		path = bctx.rule.Path()
+	}
+
 	err = builtinPrintCrossProductOperands(bctx, buf, arr, 0, func(buf []string) error {
 		pctx := print.Context{
 			Context:  bctx.Context,
 			Location: bctx.Location,
+			RulePath: path,
+			Depth:    bctx.ruleDepth,
+		}
+		if len(buf) > 0 {
+			pctx.Level, pctx.Prefixed = print.ParseLevel(buf[0])
 		}
 		return bctx.PrintHook.Print(pctx, strings.Join(buf, " "))
 	})
@@ -0,0 +1,73 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package print
+
+import (
+	"github.com/open-policy-agent/opa/v1/logging"
+)
+
+// NewLoggingHook returns a hook that logs print statement outputs with logger
+// at the info level. Besides the fields of the request context, if any, the
+// log entries have the location of the print call ("line"), and if it is made
+// in a rule, the path of the rule ("rule") and the evaluation depth ("depth").
+func NewLoggingHook(logger logging.Logger, opts ...LoggingHookOption) Hook {
+	h := loggingHook{logger: logger}
+	for _, opt := range opts {
+		opt(&h)
+	}
+	return h
+}
+
+// LoggingHookOption configures the hook returned by NewLoggingHook.
+type LoggingHookOption func(*loggingHook)
+
+// WithLevels makes the hook log print statement outputs at the level set by
+// their first argument, e.g., print("warn:", x), without that argument.
+func WithLevels(yes bool) LoggingHookOption {
+	return func(h *loggingHook) {
+		h.levels = yes
+	}
+}
+
+type loggingHook struct {
+	logger logging.Logger
+	levels bool
+}
+
+func (h loggingHook) Print(pctx Context, msg string) error {
+	// NOTE(tsandall): if the request context is not present then do not panic,
+	// just log the print message without the additional context.
+	var fields map[string]any
+	rctx, ok := logging.FromContext(pctx.Context)
+	if ok {
+		fields = rctx.Fields()
+	} else {
+		fields = make(map[string]any, 3)
+	}
+	fields["line"] = pctx.Location.String()
+	if pctx.RulePath != nil {
+		fields["rule"] = pctx.RulePath.String()
+		fields["depth"] = pctx.Depth
+	}
+
+	logger := h.logger.WithFields(fields)
+	if !h.levels {
+		logger.Info("%s", msg)
+		return nil
+	}
+
+	msg = TrimLevelPrefix(pctx, msg)
+	switch pctx.Level {
+	case LevelDebug:
+		logger.Debug("%s", msg)
+	case LevelWarn:
+		logger.Warn("%s", msg)
+	case LevelError:
+		logger.Error("%s", msg)
+	default:
+		logger.Info("%s", msg)
+	}
+	return nil
+}
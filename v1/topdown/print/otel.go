@@ -0,0 +1,46 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package print
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// NewSpanEventHook returns a hook that records print statement outputs as
+// "print" events of the span of the request context, so that they are exported
+// with the span, e.g., over OTLP if distributed tracing is enabled. Outputs of
+// print calls made without a recording span are dropped. The level of outputs
+// is recorded as an attribute, without the prefix that set it.
+func NewSpanEventHook() Hook {
+	return spanEventHook{}
+}
+
+type spanEventHook struct{}
+
+func (spanEventHook) Print(pctx Context, msg string) error {
+	if pctx.Context == nil {
+		return nil
+	}
+
+	span := trace.SpanFromContext(pctx.Context)
+	if !span.IsRecording() {
+		return nil
+	}
+
+	attrs := []attribute.KeyValue{
+		attribute.String("message", TrimLevelPrefix(pctx, msg)),
+		attribute.String("level", pctx.Level.String()),
+		attribute.String("location", pctx.Location.String()),
+	}
+	if pctx.RulePath != nil {
+		attrs = append(attrs,
+			attribute.String("rule", pctx.RulePath.String()),
+			attribute.Int("depth", pctx.Depth))
+	}
+
+	span.AddEvent("print", trace.WithAttributes(attrs...))
+	return nil
+}
@@ -2,6 +2,7 @@ package print
 
 import (
 	"context"
+	"strings"
 
 	"github.com/open-policy-agent/opa/v1/ast"
 )
@@ -10,6 +11,10 @@ import (
 type Context struct {
 	Context  context.Context // request context passed when query executed
 	Location *ast.Location   // location of print call
+	Level    Level           // level of the output, see ParseLevel
+	Prefixed bool            // indicates if the output starts with the prefix setting its level, e.g., "warn:"
+	RulePath ast.Ref         // path of the rule containing the print call, nil in queries
+	Depth    int             // number of nested rule evaluations the print call is made in
 }
 
 // Hook defines the interface that callers can implement to receive print
@@ -19,3 +24,81 @@ type Context struct {
 type Hook interface {
 	Print(Context, string) error
 }
+
+// Level is the level of print statement outputs. Policies set it with the
+// first argument of print() calls, e.g., print("warn:", x). Outputs are at
+// LevelInfo by default. The prefix is kept in the outputs, hooks choose whether
+// to use the level, see TrimLevelPrefix.
+type Level int
+
+const (
+	LevelInfo Level = iota
+	LevelDebug
+	LevelWarn
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	default:
+		return "info"
+	}
+}
+
+// ParseLevel returns the level set by the first argument of a print() call,
+// which is one of "debug:", "info:", "warn:" and "error:", in any case.
+func ParseLevel(arg string) (Level, bool) {
+	switch strings.ToLower(arg) {
+	case "debug:":
+		return LevelDebug, true
+	case "info:":
+		return LevelInfo, true
+	case "warn:":
+		return LevelWarn, true
+	case "error:":
+		return LevelError, true
+	}
+	return LevelInfo, false
+}
+
+// TrimLevelPrefix returns msg without the prefix that set its level, if
+// pctx.Prefixed is true.
+func TrimLevelPrefix(pctx Context, msg string) string {
+	if !pctx.Prefixed {
+		return msg
+	}
+	// Level prefixes do not contain spaces, see ParseLevel.
+	_, rest, _ := strings.Cut(msg, " ")
+	return rest
+}
+
+// Multi returns a hook that passes print statement outputs to each of hooks,
+// in order, skipping nil hooks. It returns the first error returned by a hook,
+// after all of them have been called.
+func Multi(hooks ...Hook) Hook {
+	hs := make(multiHook, 0, len(hooks))
+	for _, h := range hooks {
+		if h != nil {
+			hs = append(hs, h)
+		}
+	}
+	return hs
+}
+
+type multiHook []Hook
+
+func (hs multiHook) Print(pctx Context, msg string) error {
+	var err error
+	for _, h := range hs {
+		if e := h.Print(pctx, msg); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
@@ -0,0 +1,169 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package print
+
+import (
+	"context"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/logging"
+	"github.com/open-policy-agent/opa/v1/logging/test"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		arg string
+		exp Level
+		ok  bool
+	}{
+		{arg: "debug:", exp: LevelDebug, ok: true},
+		{arg: "WARN:", exp: LevelWarn, ok: true},
+		{arg: "error:", exp: LevelError, ok: true},
+		{arg: "info:", exp: LevelInfo, ok: true},
+		{arg: "warn", exp: LevelInfo},
+		{arg: "hello", exp: LevelInfo},
+	}
+
+	for _, tc := range tests {
+		if act, ok := ParseLevel(tc.arg); act != tc.exp || ok != tc.ok {
+			t.Errorf("%q: expected %v (%v), got %v (%v)", tc.arg, tc.exp, tc.ok, act, ok)
+		}
+	}
+}
+
+type recordingHook struct {
+	msgs []string
+}
+
+func (h *recordingHook) Print(_ Context, msg string) error {
+	h.msgs = append(h.msgs, msg)
+	return nil
+}
+
+func TestTrimLevelPrefix(t *testing.T) {
+	tests := []struct {
+		pctx Context
+		msg  string
+		exp  string
+	}{
+		{pctx: Context{Level: LevelWarn, Prefixed: true}, msg: "warn: a b", exp: "a b"},
+		{pctx: Context{Level: LevelWarn, Prefixed: true}, msg: "warn:", exp: ""},
+		{pctx: Context{}, msg: "warning: a b", exp: "warning: a b"},
+	}
+
+	for _, tc := range tests {
+		if act := TrimLevelPrefix(tc.pctx, tc.msg); act != tc.exp {
+			t.Errorf("%q: expected %q, got %q", tc.msg, tc.exp, act)
+		}
+	}
+}
+
+func TestMulti(t *testing.T) {
+	h1, h2 := &recordingHook{}, &recordingHook{}
+
+	if err := Multi(h1, nil, h2).Print(Context{}, "hello"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(h1.msgs) != 1 || len(h2.msgs) != 1 {
+		t.Fatalf("expected both hooks to be called, got %v and %v", h1.msgs, h2.msgs)
+	}
+}
+
+func TestLoggingHook(t *testing.T) {
+	logger := test.New()
+	logger.SetLevel(logging.Debug)
+
+	pctx := Context{
+		Context:  logging.NewContext(context.Background(), &logging.RequestContext{ReqID: 7}),
+		Location: &ast.Location{File: "test.rego", Row: 3},
+		Level:    LevelWarn,
+		Prefixed: true,
+		RulePath: ast.MustParseRef("data.test.p"),
+		Depth:    1,
+	}
+
+	// Levels are opt-in, outputs are logged as they are by default.
+	if err := NewLoggingHook(logger).Print(pctx, "warn: hello"); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewLoggingHook(logger, WithLevels(true)).Print(pctx, "warn: hello"); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := logger.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("expected two entries, got %v", entries)
+	}
+
+	if e := entries[0]; e.Level != logging.Info || e.Message != "warn: hello" {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+
+	e := entries[1]
+	if e.Level != logging.Warn || e.Message != "hello" {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+
+	exp := map[string]any{"req_id": uint64(7), "line": "test.rego:3", "rule": "data.test.p", "depth": 1}
+	for k, v := range exp {
+		if e.Fields[k] != v {
+			t.Errorf("expected field %v to be %v, got %v", k, v, e.Fields[k])
+		}
+	}
+}
+
+func TestSpanEventHook(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	ctx, span := provider.Tracer("test").Start(context.Background(), "eval")
+
+	hook := NewSpanEventHook()
+
+	pctx := Context{
+		Context:  ctx,
+		Location: &ast.Location{File: "test.rego", Row: 3},
+		Level:    LevelDebug,
+		Prefixed: true,
+	}
+
+	if err := hook.Print(pctx, "debug: hello"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Outputs of print calls without a span are dropped.
+	if err := hook.Print(Context{Context: context.Background()}, "dropped"); err != nil {
+		t.Fatal(err)
+	}
+
+	span.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 1 || len(spans[0].Events()) != 1 {
+		t.Fatalf("expected one span with one event, got %v", spans)
+	}
+
+	event := spans[0].Events()[0]
+	if event.Name != "print" {
+		t.Fatalf("expected print event, got %v", event.Name)
+	}
+
+	attrs := map[string]string{}
+	for _, attr := range event.Attributes {
+		attrs[string(attr.Key)] = attr.Value.Emit()
+	}
+
+	exp := map[string]string{"message": "hello", "level": "debug", "location": "test.rego:3"}
+	for k, v := range exp {
+		if attrs[k] != v {
+			t.Errorf("expected attribute %v to be %v, got %v", k, v, attrs[k])
+		}
+	}
+}
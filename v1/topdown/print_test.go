@@ -236,3 +236,58 @@ func TestTopDownPrintHookErrorPropagation(t *testing.T) {
 	}
 
 }
+
+type recordingPrintHook struct {
+	ctxs []print.Context
+	msgs []string
+}
+
+func (h *recordingPrintHook) Print(pctx print.Context, msg string) error {
+	h.ctxs = append(h.ctxs, pctx)
+	h.msgs = append(h.msgs, msg)
+	return nil
+}
+
+func TestTopDownPrintHookContext(t *testing.T) {
+	c := ast.MustCompileModulesWithOpts(map[string]string{"test.rego": `package test
+
+p if {
+	print("warn:", "in p")
+	q
+}
+
+q if print("in q")`},
+		ast.CompileOpts{EnablePrintStatements: true})
+
+	hook := &recordingPrintHook{}
+	q := NewQuery(ast.MustParseBody("data.test.p = x")).
+		WithPrintHook(hook).
+		WithCompiler(c)
+
+	if _, err := q.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []struct {
+		msg      string
+		level    print.Level
+		prefixed bool
+		path     string
+		depth    int
+	}{
+		{msg: "warn: in p", level: print.LevelWarn, prefixed: true, path: "data.test.p", depth: 1},
+		{msg: "in q", level: print.LevelInfo, path: "data.test.q", depth: 2},
+	}
+
+	if len(hook.ctxs) != len(exp) {
+		t.Fatalf("expected %d prints, got %v", len(exp), hook.msgs)
+	}
+
+	for i, e := range exp {
+		pctx := hook.ctxs[i]
+		if hook.msgs[i] != e.msg || pctx.Level != e.level || pctx.Prefixed != e.prefixed || pctx.RulePath.String() != e.path || pctx.Depth != e.depth {
+			t.Errorf("expected %q (%v, %v, %d), got %q (%v, %v, %d)", e.msg, e.level, e.path, e.depth,
+				hook.msgs[i], pctx.Level, pctx.RulePath, pctx.Depth)
+		}
+	}
+}
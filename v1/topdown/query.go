@@ -8,12 +8,13 @@ import (
 	"time"
 
 	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/ast/transform"
 	"github.com/open-policy-agent/opa/v1/metrics"
 	"github.com/open-policy-agent/opa/v1/resolver"
 	"github.com/open-policy-agent/opa/v1/storage"
+	"github.com/open-policy-agent/opa/v1/storage/inmem"
 	"github.com/open-policy-agent/opa/v1/topdown/builtins"
 	"github.com/open-policy-agent/opa/v1/topdown/cache"
-	"github.com/open-policy-agent/opa/v1/topdown/copypropagation"
 	"github.com/open-policy-agent/opa/v1/topdown/print"
 	"github.com/open-policy-agent/opa/v1/tracing"
 )
@@ -30,6 +31,8 @@ type Query struct {
 	seed                        io.Reader
 	time                        time.Time
 	cancel                      Cancel
+	checkpointInterval          uint64
+	checkpointFunc              CheckpointFunc
 	query                       ast.Body
 	queryCompiler               ast.QueryCompiler
 	compiler                    *ast.Compiler
@@ -49,6 +52,7 @@ type Query struct {
 	nondeterministicBuiltins    bool
 	genvarprefix                string
 	runtime                     *ast.Term
+	requestMetadata             *ast.Term
 	builtins                    map[string]*Builtin
 	indexing                    bool
 	earlyExit                   bool
@@ -58,11 +62,16 @@ type Query struct {
 	strictBuiltinErrors         bool
 	builtinErrorList            *[]Error
 	strictObjects               bool
+	sortedIteration             bool
 	roundTripper                CustomizeRoundTripper
+	allowNet                    []string
 	printHook                   print.Hook
 	tracingOpts                 tracing.Options
 	virtualCache                VirtualCache
+	sharedVirtualCache          *SharedVirtualCache
+	sharedVirtualCacheKey       string
 	baseCache                   BaseCache
+	dataSnapshot                map[string]any
 }
 
 // Builtin represents a built-in function that queries can call.
@@ -113,6 +122,15 @@ func (q *Query) WithCancel(cancel Cancel) *Query {
 	return q
 }
 
+// WithCheckpoint sets a function to call every interval operations of the
+// evaluation, i.e., evaluated expressions. The function can pause evaluation
+// by blocking, and abort it by returning an error. This is optional.
+func (q *Query) WithCheckpoint(interval uint64, f CheckpointFunc) *Query {
+	q.checkpointInterval = interval
+	q.checkpointFunc = f
+	return q
+}
+
 // WithInput sets the input object to use for the query. References rooted at
 // input will be evaluated against this value. This is optional.
 func (q *Query) WithInput(input *ast.Term) *Query {
@@ -209,6 +227,18 @@ func (q *Query) WithRuntime(runtime *ast.Term) *Query {
 	return q
 }
 
+// WithRequestMetadata sets the metadata of the request the query is evaluated
+// for, e.g., the calling service or trace ID. The metadata can be returned by
+// the `runtime.request` built-in function.
+func (q *Query) WithRequestMetadata(metadata map[string]string) *Query {
+	obj := ast.NewObject()
+	for k, v := range metadata {
+		obj.Insert(ast.StringTerm(k), ast.StringTerm(v))
+	}
+	q.requestMetadata = ast.NewTerm(obj)
+	return q
+}
+
 // WithBuiltins adds a set of built-in functions that can be called by the
 // query.
 func (q *Query) WithBuiltins(builtins map[string]*Builtin) *Query {
@@ -290,6 +320,34 @@ func (q *Query) WithHTTPRoundTripper(t CustomizeRoundTripper) *Query {
 	return q
 }
 
+// WithAllowNet restricts the hosts that built-in functions like http.send and
+// net.lookup_ip_addr may connect to when evaluating the query, overriding the
+// allow_net capability of the compiler. If hosts is empty, no host can be
+// connected to. If hosts is nil, the capabilities of the compiler apply.
+func (q *Query) WithAllowNet(hosts []string) *Query {
+	q.allowNet = hosts
+	return q
+}
+
+// capabilities returns the capabilities enforced by built-in functions when
+// evaluating the query.
+func (q *Query) capabilities() *ast.Capabilities {
+	var c *ast.Capabilities
+	if q.compiler != nil {
+		c = q.compiler.Capabilities()
+	}
+	if q.allowNet == nil {
+		return c
+	}
+
+	var cpy ast.Capabilities
+	if c != nil {
+		cpy = *c
+	}
+	cpy.AllowNet = q.allowNet
+	return &cpy
+}
+
 func (q *Query) WithPrintHook(h print.Hook) *Query {
 	q.printHook = h
 	return q
@@ -310,6 +368,17 @@ func (q *Query) WithStrictObjects(yes bool) *Query {
 	return q
 }
 
+// WithSortedIteration tells the evaluator to iterate over the keys of documents
+// that are partly defined by base documents and partly by rules in sorted order,
+// instead of visiting the keys of the base documents first. Sets and objects
+// are always iterated in sorted order. This makes the order of results and
+// traces independent of where documents are defined, at the cost of sorting
+// the keys of such documents on every iteration.
+func (q *Query) WithSortedIteration(yes bool) *Query {
+	q.sortedIteration = yes
+	return q
+}
+
 // WithVirtualCache sets the VirtualCache to use during evaluation. This is
 // optional, and if not set, the default cache is used.
 func (q *Query) WithVirtualCache(vc VirtualCache) *Query {
@@ -317,6 +386,17 @@ func (q *Query) WithVirtualCache(vc VirtualCache) *Query {
 	return q
 }
 
+// WithSharedVirtualCache sets the SharedVirtualCache to read and store the
+// values of virtual documents that only depend on data. The key identifies
+// the policy and data the query is evaluated against, e.g., the bundle
+// revisions and a hash of the data. The shared cache is not used by partial
+// evaluation.
+func (q *Query) WithSharedVirtualCache(c *SharedVirtualCache, key string) *Query {
+	q.sharedVirtualCache = c
+	q.sharedVirtualCacheKey = key
+	return q
+}
+
 // WithBaseCache sets the BaseCache to use during evaluation. This is
 // optional, and if not set, the default cache is used.
 func (q *Query) WithBaseCache(bc BaseCache) *Query {
@@ -324,6 +404,19 @@ func (q *Query) WithBaseCache(bc BaseCache) *Query {
 	return q
 }
 
+// WithDataSnapshot makes the query evaluate as if each of its expressions
+// was evaluated `with data as <data>`: the rules of the compiler do not define
+// any documents, and the base documents are read from data instead of the
+// store. Rather than converting data into a term, data is loaded into a
+// transient in-memory store, so that only the documents the query reads are
+// converted. This makes evaluations over large replacements of the whole data
+// document feasible. The data must not be modified during evaluation. The
+// data snapshot is not supported by partial evaluation.
+func (q *Query) WithDataSnapshot(data map[string]any) *Query {
+	q.dataSnapshot = data
+	return q
+}
+
 // WithNondeterministicBuiltins causes non-deterministic builtins to be evalued
 // during partial evaluation. This is needed to pull in external data, or validate
 // a JWT, during PE, so that the result informs what queries are returned.
@@ -332,6 +425,13 @@ func (q *Query) WithNondeterministicBuiltins(yes bool) *Query {
 	return q
 }
 
+func (q *Query) checkpoints() *checkpoints {
+	if q.checkpointFunc == nil {
+		return nil
+	}
+	return newCheckpoints(q.checkpointInterval, q.checkpointFunc)
+}
+
 // PartialRun executes partial evaluation on the query with respect to unknown
 // values. Partial evaluation attempts to evaluate as much of the query as
 // possible without requiring values for the unknowns set on the query. The
@@ -340,6 +440,12 @@ func (q *Query) WithNondeterministicBuiltins(yes bool) *Query {
 // evaluation may produce additional support modules that should be used in
 // conjunction with the partially evaluated queries.
 func (q *Query) PartialRun(ctx context.Context) (partials []ast.Body, support []*ast.Module, err error) {
+	if q.dataSnapshot != nil {
+		return nil, nil, &Error{
+			Code:    InternalErr,
+			Message: "data snapshot not supported by partial evaluation",
+		}
+	}
 	if q.partialNamespace == "" {
 		q.partialNamespace = "partial" // lazily initialize partial namespace
 	}
@@ -376,12 +482,14 @@ func (q *Query) PartialRun(ctx context.Context) (partials []ast.Body, support []
 		seed:                        q.seed,
 		time:                        ast.NumberTerm(int64ToJSONNumber(q.time.UnixNano())),
 		cancel:                      q.cancel,
+		checkpoints:                 q.checkpoints(),
 		query:                       q.query,
 		queryCompiler:               q.queryCompiler,
 		queryIDFact:                 f,
 		queryID:                     f.Next(),
 		bindings:                    b,
 		compiler:                    q.compiler,
+		capabilities:                q.capabilities(),
 		store:                       q.store,
 		baseCache:                   bc,
 		txn:                         q.txn,
@@ -406,13 +514,15 @@ func (q *Query) PartialRun(ctx context.Context) (partials []ast.Body, support []
 			shallow:                  q.shallowInlining,
 			nondeterministicBuiltins: q.nondeterministicBuiltins,
 		},
-		genvarprefix:  q.genvarprefix,
-		runtime:       q.runtime,
-		indexing:      q.indexing,
-		earlyExit:     q.earlyExit,
-		builtinErrors: &builtinErrors{},
-		printHook:     q.printHook,
-		strictObjects: q.strictObjects,
+		genvarprefix:    q.genvarprefix,
+		runtime:         q.runtime,
+		requestMetadata: q.requestMetadata,
+		indexing:        q.indexing,
+		earlyExit:       q.earlyExit,
+		builtinErrors:   &builtinErrors{},
+		printHook:       q.printHook,
+		strictObjects:   q.strictObjects,
+		sortedIteration: q.sortedIteration,
 	}
 
 	if len(q.disableInlining) > 0 {
@@ -440,7 +550,7 @@ func (q *Query) PartialRun(ctx context.Context) (partials []ast.Body, support []
 		return false
 	})
 
-	p := copypropagation.New(livevars).WithCompiler(q.compiler)
+	p := transform.NewCopyPropagator(livevars).WithCompiler(q.compiler)
 
 	err = e.Run(func(e *eval) error {
 
@@ -518,6 +628,17 @@ func (q *Query) PartialRun(ctx context.Context) (partials []ast.Body, support []
 	return partials, support, err
 }
 
+// newDataSnapshot returns a transient in-memory store holding data, and a read
+// transaction on it.
+func newDataSnapshot(ctx context.Context, data map[string]any) (storage.Store, storage.Transaction, error) {
+	store := inmem.NewFromObjectWithOpts(data, inmem.OptRoundTripOnWrite(false))
+	txn, err := store.NewTransaction(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return store, txn, nil
+}
+
 // Run is a wrapper around Iter that accumulates query results and returns them
 // in one shot.
 func (q *Query) Run(ctx context.Context) (QueryResultSet, error) {
@@ -549,6 +670,17 @@ func (q *Query) Iter(ctx context.Context, iter func(QueryResult) error) error {
 		q.metrics = metrics.New()
 	}
 
+	store, txn, external := q.store, q.txn, q.external
+	if q.dataSnapshot != nil {
+		var err error
+		store, txn, err = newDataSnapshot(ctx, q.dataSnapshot)
+		if err != nil {
+			return err
+		}
+		defer store.Abort(ctx, txn)
+		external = nil
+	}
+
 	f := &queryIDFactory{}
 
 	var vc VirtualCache
@@ -558,8 +690,12 @@ func (q *Query) Iter(ctx context.Context, iter func(QueryResult) error) error {
 		vc = NewVirtualCache()
 	}
 
+	if q.sharedVirtualCache != nil && q.dataSnapshot == nil {
+		vc = newSharedVirtualCache(vc, q.sharedVirtualCache, q.sharedVirtualCacheKey, q.compiler, q.builtins)
+	}
+
 	var bc BaseCache
-	if q.baseCache != nil {
+	if q.baseCache != nil && q.dataSnapshot == nil {
 		bc = q.baseCache
 	} else {
 		bc = newBaseCache()
@@ -571,17 +707,19 @@ func (q *Query) Iter(ctx context.Context, iter func(QueryResult) error) error {
 		seed:                        q.seed,
 		time:                        ast.NumberTerm(int64ToJSONNumber(q.time.UnixNano())),
 		cancel:                      q.cancel,
+		checkpoints:                 q.checkpoints(),
 		query:                       q.query,
 		queryCompiler:               q.queryCompiler,
 		queryIDFact:                 f,
 		queryID:                     f.Next(),
 		bindings:                    newBindings(0, q.instr),
 		compiler:                    q.compiler,
-		store:                       q.store,
+		capabilities:                q.capabilities(),
+		store:                       store,
 		baseCache:                   bc,
-		txn:                         q.txn,
+		txn:                         txn,
 		input:                       q.input,
-		external:                    q.external,
+		external:                    external,
 		tracers:                     q.tracers,
 		traceEnabled:                len(q.tracers) > 0,
 		plugTraceVars:               q.plugTraceVars,
@@ -592,14 +730,17 @@ func (q *Query) Iter(ctx context.Context, iter func(QueryResult) error) error {
 		interQueryBuiltinValueCache: q.interQueryBuiltinValueCache,
 		ndBuiltinCache:              q.ndBuiltinCache,
 		virtualCache:                vc,
+		dataSnapshot:                q.dataSnapshot != nil,
 		genvarprefix:                q.genvarprefix,
 		runtime:                     q.runtime,
+		requestMetadata:             q.requestMetadata,
 		indexing:                    q.indexing,
 		earlyExit:                   q.earlyExit,
 		builtinErrors:               &builtinErrors{},
 		printHook:                   q.printHook,
 		tracingOpts:                 q.tracingOpts,
 		strictObjects:               q.strictObjects,
+		sortedIteration:             q.sortedIteration,
 		roundTripper:                q.roundTripper,
 	}
 	e.caller = e
@@ -256,6 +256,92 @@ p := data.q(42)`),
 	}
 }
 
+func TestQueryWithDataSnapshot(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	compiler := compileModules([]string{
+		`package test
+
+		p := data.a.b
+
+		q contains x if some x in data.a.c`,
+	})
+
+	store := inmem.NewFromObject(map[string]any{
+		"a": map[string]any{"b": "store"},
+	})
+	txn := storage.NewTransactionOrDie(ctx, store)
+	defer store.Abort(ctx, txn)
+
+	snapshot := map[string]any{
+		"a": map[string]any{"b": "snapshot", "c": []any{1, 2}},
+	}
+
+	tests := []struct {
+		note  string
+		query string
+		exp   []string
+	}{
+		{note: "base document", query: `x = data.a.b`, exp: []string{`"snapshot"`}},
+		{note: "root document", query: `x = data`, exp: []string{`{"a": {"b": "snapshot", "c": [1, 2]}}`}},
+		{note: "iteration", query: `x = data.a.c[_]`, exp: []string{`1`, `2`}},
+		{note: "rules disabled", query: `x = data.test.p`},
+		{note: "nested with", query: `x = data.a.b with data.a.b as "nested"`, exp: []string{`"nested"`}},
+		{note: "nested with rule", query: `x = data.test.p with data.test.p as "nested"`, exp: []string{`"nested"`}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			qrs, err := NewQuery(ast.MustParseBody(tc.query)).
+				WithCompiler(compiler).
+				WithStore(store).
+				WithTransaction(txn).
+				WithDataSnapshot(snapshot).
+				Run(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(qrs) != len(tc.exp) {
+				t.Fatalf("expected %v, got %v", tc.exp, qrs)
+			}
+			for i, exp := range tc.exp {
+				if !qrs[i][ast.Var("x")].Equal(ast.MustParseTerm(exp)) {
+					t.Fatalf("expected %v, got %v", tc.exp, qrs)
+				}
+			}
+		})
+	}
+
+	t.Run("store unchanged", func(t *testing.T) {
+		qrs, err := NewQuery(ast.MustParseBody(`x = data.test.p`)).
+			WithCompiler(compiler).
+			WithStore(store).
+			WithTransaction(txn).
+			Run(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(qrs) != 1 || !qrs[0][ast.Var("x")].Equal(ast.StringTerm("store")) {
+			t.Fatalf("expected result from store, got %v", qrs)
+		}
+	})
+
+	t.Run("partial evaluation", func(t *testing.T) {
+		_, _, err := NewQuery(ast.MustParseBody(`x = data.a.b`)).
+			WithCompiler(compiler).
+			WithStore(store).
+			WithTransaction(txn).
+			WithDataSnapshot(snapshot).
+			PartialRun(ctx)
+		if err == nil || !strings.Contains(err.Error(), "data snapshot not supported by partial evaluation") {
+			t.Fatalf("expected error, got %v", err)
+		}
+	})
+}
+
 func initTracerTestQuery() *Query {
 	ctx := context.Background()
 	store := inmem.New()
@@ -318,3 +404,56 @@ func (*testLegacyTracer) Enabled() bool {
 func (n *testLegacyTracer) Trace(e *Event) {
 	n.events = append(n.events, e)
 }
+
+func TestQueryWithSortedIteration(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	compiler := compileModules([]string{
+		`package x.a
+
+		v := 3`,
+		`package x.c
+
+		v := 4`,
+	})
+
+	store := inmem.NewFromObject(map[string]any{
+		"x": map[string]any{"b": map[string]any{"v": 1}, "d": map[string]any{"v": 2}},
+	})
+	txn := storage.NewTransactionOrDie(ctx, store)
+	defer store.Abort(ctx, txn)
+
+	tests := []struct {
+		note   string
+		sorted bool
+		exp    []string
+	}{
+		{note: "base documents first", exp: []string{`"b"`, `"d"`, `"a"`, `"c"`}},
+		{note: "sorted", sorted: true, exp: []string{`"a"`, `"b"`, `"c"`, `"d"`}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			qrs, err := NewQuery(ast.MustParseBody(`data.x[k].v`)).
+				WithCompiler(compiler).
+				WithStore(store).
+				WithTransaction(txn).
+				WithSortedIteration(tc.sorted).
+				Run(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(qrs) != len(tc.exp) {
+				t.Fatalf("expected %v, got %v", tc.exp, qrs)
+			}
+			for i, exp := range tc.exp {
+				if !qrs[i][ast.Var("k")].Equal(ast.MustParseTerm(exp)) {
+					t.Fatalf("expected %v, got %v", tc.exp, qrs)
+				}
+			}
+		})
+	}
+}
@@ -5,6 +5,8 @@
 package topdown
 
 import (
+	"slices"
+
 	"github.com/open-policy-agent/opa/v1/ast"
 	"github.com/open-policy-agent/opa/v1/topdown/builtins"
 )
@@ -145,7 +147,113 @@ func builtinReachablePaths(_ BuiltinContext, operands []*ast.Term, iter func(*as
 	return iter(ast.NewTerm(traceResult))
 }
 
+func builtinShortestPath(_ BuiltinContext, operands []*ast.Term, iter func(*ast.Term) error) error {
+	// Error on wrong types for args.
+	graph, err := builtins.ObjectOperand(operands[0].Value, 1)
+	if err != nil {
+		return err
+	}
+
+	from, to := operands[1], operands[2]
+	if from.Equal(to) {
+		return iter(ast.ArrayTerm(from))
+	}
+
+	// Breadth-first search, remembering the vertex each node was first
+	// reached from so the path can be reconstructed.
+	parents := ast.NewObject([2]*ast.Term{from, from})
+	queue := []*ast.Term{from}
+
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+
+		edges := graph.Get(node)
+		if edges == nil {
+			continue
+		}
+
+		found := false
+		foreachVertex(edges, func(neighbor *ast.Term) {
+			if found || parents.Get(neighbor) != nil {
+				return
+			}
+			parents.Insert(neighbor, node)
+			if neighbor.Equal(to) {
+				found = true
+				return
+			}
+			queue = append(queue, neighbor)
+		})
+
+		if found {
+			path := []*ast.Term{to}
+			for v := to; !v.Equal(from); {
+				v = parents.Get(v)
+				path = append(path, v)
+			}
+			slices.Reverse(path)
+			return iter(ast.ArrayTerm(path...))
+		}
+	}
+
+	return iter(ast.InternedEmptyArray)
+}
+
+func builtinTransitiveClosure(_ BuiltinContext, operands []*ast.Term, iter func(*ast.Term) error) error {
+	// Error on wrong types for args.
+	graph, err := builtins.ObjectOperand(operands[0].Value, 1)
+	if err != nil {
+		return err
+	}
+
+	switch nodes := operands[1].Value.(type) {
+	case *ast.Array, ast.Set:
+	default:
+		return builtins.NewOperandTypeErr(2, nodes, "{array, set}")
+	}
+
+	result := ast.NewObject()
+	foreachVertex(operands[1], func(node *ast.Term) {
+		if result.Get(node) != nil {
+			return
+		}
+
+		// Unlike graph.reachable, start from the neighbors of node: the node
+		// itself is only reached if it is part of a cycle.
+		reached := ast.NewSet()
+		var queue []*ast.Term
+		if edges := graph.Get(node); edges != nil {
+			foreachVertex(edges, func(neighbor *ast.Term) {
+				queue = append(queue, neighbor)
+			})
+		}
+
+		for len(queue) > 0 {
+			v := queue[0]
+			queue = queue[1:]
+			if reached.Contains(v) {
+				continue
+			}
+			reached.Add(v)
+			if edges := graph.Get(v); edges != nil {
+				foreachVertex(edges, func(neighbor *ast.Term) {
+					if !reached.Contains(neighbor) {
+						queue = append(queue, neighbor)
+					}
+				})
+			}
+		}
+
+		result.Insert(node, ast.NewTerm(reached))
+	})
+
+	return iter(ast.NewTerm(result))
+}
+
 func init() {
 	RegisterBuiltinFunc(ast.ReachableBuiltin.Name, builtinReachable)
 	RegisterBuiltinFunc(ast.ReachablePathsBuiltin.Name, builtinReachablePaths)
+	RegisterBuiltinFunc(ast.ShortestPathBuiltin.Name, builtinShortestPath)
+	RegisterBuiltinFunc(ast.TransitiveClosureBuiltin.Name, builtinTransitiveClosure)
 }
@@ -19,8 +19,13 @@ func builtinOPARuntime(bctx BuiltinContext, _ []*ast.Term, iter func(*ast.Term)
 		return iter(ast.InternedEmptyObject)
 	}
 
-	if bctx.Runtime.Get(ast.InternedTerm("config")) != nil {
-		iface, err := ast.ValueToInterface(bctx.Runtime.Value, nothingResolver)
+	runtime := bctx.Runtime
+	if bctx.Capabilities != nil && bctx.Capabilities.AllowEnv != nil {
+		runtime = allowedEnv(runtime, bctx.Capabilities.AllowEnv)
+	}
+
+	if runtime.Get(ast.InternedTerm("config")) != nil {
+		iface, err := ast.ValueToInterface(runtime.Value, nothingResolver)
 		if err != nil {
 			return err
 		}
@@ -42,11 +47,56 @@ func builtinOPARuntime(bctx BuiltinContext, _ []*ast.Term, iter func(*ast.Term)
 		}
 	}
 
-	return iter(bctx.Runtime)
+	return iter(runtime)
+}
+
+// allowedEnv returns a copy of runtime holding only the environment variables
+// named in allow.
+func allowedEnv(runtime *ast.Term, allow []string) *ast.Term {
+	obj, ok := runtime.Value.(ast.Object)
+	if !ok {
+		return runtime
+	}
+
+	envKey := ast.InternedTerm("env")
+	t := obj.Get(envKey)
+	if t == nil {
+		return runtime
+	}
+	env, ok := t.Value.(ast.Object)
+	if !ok {
+		return runtime
+	}
+
+	allowed := ast.NewObject()
+	for _, name := range allow {
+		k := ast.StringTerm(name)
+		if v := env.Get(k); v != nil {
+			allowed.Insert(k, v)
+		}
+	}
+
+	cpy := ast.NewObject()
+	obj.Foreach(func(k, v *ast.Term) {
+		if k.Equal(envKey) {
+			v = ast.NewTerm(allowed)
+		}
+		cpy.Insert(k, v)
+	})
+
+	return ast.NewTerm(cpy)
+}
+
+func builtinRuntimeRequest(bctx BuiltinContext, _ []*ast.Term, iter func(*ast.Term) error) error {
+	if bctx.RequestMetadata == nil {
+		return iter(ast.InternedEmptyObject)
+	}
+	return iter(bctx.RequestMetadata)
 }
 
 func init() {
 	RegisterBuiltinFunc(ast.OPARuntime.Name, builtinOPARuntime)
+	RegisterBuiltinFunc(ast.RuntimeRequest.Name, builtinRuntimeRequest)
 }
 
 func activeConfig(config map[string]any) (any, error) {
@@ -85,3 +85,88 @@ func TestOPARuntimeConfigMasking(t *testing.T) {
 		t.Fatalf("Expected %v but got %v", exp, term)
 	}
 }
+
+func TestOPARuntimeAllowEnv(t *testing.T) {
+	t.Parallel()
+
+	runtime := ast.MustParseTerm(`{"env": {"HOME": "/root", "SECRET": "s3cr3t"}, "version": "1.0"}`)
+
+	tests := []struct {
+		note     string
+		allowEnv []string
+		exp      string
+	}{
+		{
+			note: "omitted",
+			exp:  `{"env": {"HOME": "/root", "SECRET": "s3cr3t"}, "version": "1.0"}`,
+		},
+		{
+			note:     "empty",
+			allowEnv: []string{},
+			exp:      `{"env": {}, "version": "1.0"}`,
+		},
+		{
+			note:     "allowed",
+			allowEnv: []string{"HOME", "PATH"},
+			exp:      `{"env": {"HOME": "/root"}, "version": "1.0"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			c := ast.NewCompiler().WithCapabilities(&ast.Capabilities{AllowEnv: tc.allowEnv})
+
+			q := NewQuery(ast.MustParseBody("opa.runtime(x)")).WithCompiler(c).WithRuntime(runtime)
+			rs, err := q.Run(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			} else if len(rs) != 1 {
+				t.Fatal("Expected result set to contain exactly one result")
+			}
+
+			if exp, act := ast.MustParseTerm(tc.exp), rs[0][ast.Var("x")]; ast.Compare(act, exp) != 0 {
+				t.Fatalf("Expected %v but got %v", exp, act)
+			}
+		})
+	}
+}
+
+func TestRuntimeRequest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		note     string
+		metadata map[string]string
+		exp      string
+	}{
+		{
+			note: "omitted",
+			exp:  `{}`,
+		},
+		{
+			note:     "metadata",
+			metadata: map[string]string{"caller": "billing", "trace_id": "abc123"},
+			exp:      `{"caller": "billing", "trace_id": "abc123"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			q := NewQuery(ast.MustParseBody("runtime.request(x)")).WithCompiler(ast.NewCompiler())
+			if tc.metadata != nil {
+				q = q.WithRequestMetadata(tc.metadata)
+			}
+
+			rs, err := q.Run(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			} else if len(rs) != 1 {
+				t.Fatal("Expected result set to contain exactly one result")
+			}
+
+			if exp, act := ast.MustParseTerm(tc.exp), rs[0][ast.Var("x")]; ast.Compare(act, exp) != 0 {
+				t.Fatalf("Expected %v but got %v", exp, act)
+			}
+		})
+	}
+}
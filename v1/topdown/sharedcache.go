@@ -0,0 +1,274 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package topdown
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+)
+
+// SharedVirtualCache stores the values of virtual documents that only depend
+// on data, so that they are computed once and shared by subsequent
+// evaluations, instead of once per evaluation.
+//
+// A virtual document only depends on data if neither the rules defining it,
+// nor the rules and functions they refer to, refer to input or call
+// non-deterministic built-in functions. Values computed while a with modifier
+// is in effect are never shared.
+//
+// Entries are stored under a key that identifies the policy and data they
+// were computed from, e.g., the bundle revisions and a hash of the data. The
+// cache only holds entries for a single key: storing an entry under a new key
+// evicts all entries stored under the previous one. Within a key, the least
+// recently used entries are evicted once the number of entries exceeds the
+// configured maximum.
+//
+// A SharedVirtualCache is safe for concurrent use.
+type SharedVirtualCache struct {
+	mtx        sync.Mutex
+	maxEntries int
+	key        string
+	entries    map[string]*list.Element
+	lru        *list.List
+	compiler   *ast.Compiler
+	pure       map[*ast.Rule]bool
+}
+
+type sharedVirtualCacheEntry struct {
+	ref   string
+	value *ast.Term
+}
+
+// NewSharedVirtualCache returns a new SharedVirtualCache that holds at most
+// maxEntries values. If maxEntries is zero or negative, the number of
+// entries is unbounded.
+func NewSharedVirtualCache(maxEntries int) *SharedVirtualCache {
+	return &SharedVirtualCache{
+		maxEntries: maxEntries,
+		entries:    map[string]*list.Element{},
+		lru:        list.New(),
+	}
+}
+
+// Len returns the number of entries in the cache.
+func (c *SharedVirtualCache) Len() int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.lru.Len()
+}
+
+// Clear removes all entries from the cache.
+func (c *SharedVirtualCache) Clear() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.reset(c.key)
+}
+
+func (c *SharedVirtualCache) reset(key string) {
+	c.key = key
+	c.entries = map[string]*list.Element{}
+	c.lru.Init()
+}
+
+// get returns the cached value of ref under key. The second return value
+// indicates whether ref was found, the value is nil if ref is undefined.
+func (c *SharedVirtualCache) get(key string, ref ast.Ref) (*ast.Term, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if key != c.key {
+		return nil, false
+	}
+
+	elem, ok := c.entries[ref.String()]
+	if !ok {
+		return nil, false
+	}
+
+	c.lru.MoveToFront(elem)
+	return elem.Value.(*sharedVirtualCacheEntry).value, true
+}
+
+func (c *SharedVirtualCache) put(key string, ref ast.Ref, value *ast.Term) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if key != c.key {
+		c.reset(key)
+	}
+
+	k := ref.String()
+	if elem, ok := c.entries[k]; ok {
+		elem.Value.(*sharedVirtualCacheEntry).value = value
+		c.lru.MoveToFront(elem)
+		return
+	}
+
+	c.entries[k] = c.lru.PushFront(&sharedVirtualCacheEntry{ref: k, value: value})
+
+	if c.maxEntries > 0 && c.lru.Len() > c.maxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*sharedVirtualCacheEntry).ref)
+	}
+}
+
+// isPure returns true if the rules in the dependency closure of rule neither
+// refer to input nor call non-deterministic built-in functions. The results
+// are memoized for the most recently used compiler.
+func (c *SharedVirtualCache) isPure(compiler *ast.Compiler, builtins map[string]*Builtin, rule *ast.Rule) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if compiler != c.compiler {
+		c.compiler = compiler
+		c.pure = map[*ast.Rule]bool{}
+	}
+
+	return c.isPureRec(builtins, rule)
+}
+
+func (c *SharedVirtualCache) isPureRec(builtins map[string]*Builtin, rule *ast.Rule) bool {
+	if pure, ok := c.pure[rule]; ok {
+		return pure
+	}
+
+	// Rules cannot be recursive, but guard against cycles nonetheless.
+	c.pure[rule] = false
+
+	pure := true
+
+	ast.WalkTerms(rule, func(x *ast.Term) bool {
+		if !pure {
+			return true
+		}
+
+		switch v := x.Value.(type) {
+		case ast.Ref:
+			switch {
+			case v.HasPrefix(ast.InputRootRef):
+				pure = false
+			case v.HasPrefix(ast.DefaultRootRef):
+				for _, r := range c.compiler.GetRules(v.GroundPrefix()) {
+					if !c.isPureRec(builtins, r) {
+						pure = false
+						break
+					}
+				}
+			default:
+				if bi := lookupBuiltin(builtins, v.String()); bi != nil && bi.Nondeterministic {
+					pure = false
+				}
+			}
+		case ast.Var:
+			if bi := lookupBuiltin(builtins, string(v)); bi != nil && bi.Nondeterministic {
+				pure = false
+			}
+		}
+
+		return false
+	})
+
+	c.pure[rule] = pure
+	return pure
+}
+
+func lookupBuiltin(builtins map[string]*Builtin, name string) *ast.Builtin {
+	if bi, ok := builtins[name]; ok {
+		return bi.Decl
+	}
+	return ast.BuiltinMap[name]
+}
+
+// sharedVirtualCache is the VirtualCache used by evaluations with a
+// SharedVirtualCache. Values of virtual documents that only depend on data
+// are read from and written to the shared cache, all other values are kept
+// in the per-evaluation cache.
+type sharedVirtualCache struct {
+	VirtualCache
+	shared   *SharedVirtualCache
+	key      string
+	compiler *ast.Compiler
+	builtins map[string]*Builtin
+	depth    int
+}
+
+func newSharedVirtualCache(local VirtualCache, shared *SharedVirtualCache, key string, compiler *ast.Compiler, builtins map[string]*Builtin) *sharedVirtualCache {
+	return &sharedVirtualCache{
+		VirtualCache: local,
+		shared:       shared,
+		key:          key,
+		compiler:     compiler,
+		builtins:     builtins,
+	}
+}
+
+func (c *sharedVirtualCache) Push() {
+	c.depth++
+	c.VirtualCache.Push()
+}
+
+func (c *sharedVirtualCache) Pop() {
+	c.depth--
+	c.VirtualCache.Pop()
+}
+
+func (c *sharedVirtualCache) Get(ref ast.Ref) (*ast.Term, bool) {
+	if value, undefined := c.VirtualCache.Get(ref); value != nil || undefined {
+		return value, undefined
+	}
+
+	if !c.shareable(ref) {
+		return nil, false
+	}
+
+	value, ok := c.shared.get(c.key, ref)
+	if !ok {
+		return nil, false
+	}
+
+	return value, value == nil
+}
+
+func (c *sharedVirtualCache) Put(ref ast.Ref, value *ast.Term) {
+	c.VirtualCache.Put(ref, value)
+
+	if c.shareable(ref) {
+		c.shared.put(c.key, ref, value)
+	}
+}
+
+// shareable returns true if the value of ref only depends on data. Refs
+// are either refs to virtual documents or calls to functions, where the
+// first element is the function ref followed by the arguments.
+func (c *sharedVirtualCache) shareable(ref ast.Ref) bool {
+	if c.depth > 0 || len(ref) == 0 || !ref.IsGround() {
+		return false
+	}
+
+	path := ref
+	if fn, ok := ref[0].Value.(ast.Ref); ok {
+		path = fn
+	}
+
+	if !path.HasPrefix(ast.DefaultRootRef) {
+		return false
+	}
+
+	rules := c.compiler.GetRules(path)
+	if len(rules) == 0 {
+		return false
+	}
+
+	for _, rule := range rules {
+		if !c.shared.isPure(c.compiler, c.builtins, rule) {
+			return false
+		}
+	}
+
+	return true
+}
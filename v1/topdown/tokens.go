@@ -7,6 +7,7 @@ package topdown
 import (
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/hmac"
 	"crypto/rsa"
 	"crypto/sha256"
@@ -22,6 +23,7 @@ import (
 	"strings"
 
 	"github.com/open-policy-agent/opa/internal/jwx/jwa"
+	"github.com/open-policy-agent/opa/internal/jwx/jwe"
 	"github.com/open-policy-agent/opa/internal/jwx/jwk"
 	"github.com/open-policy-agent/opa/internal/jwx/jws"
 	"github.com/open-policy-agent/opa/v1/ast"
@@ -269,6 +271,17 @@ func verifyES(publicKey any, digest []byte, signature []byte) (err error) {
 	return errors.New("ECDSA signature verification error")
 }
 
+// Implements EdDSA JWT signature verification
+func builtinJWTVerifyEdDSA(bctx BuiltinContext, operands []*ast.Term, iter func(*ast.Term) error) error {
+	result, err := builtinJWTVerify(bctx, operands[0].Value, operands[1].Value, nil, func(publicKey any, payload []byte, signature []byte) error {
+		return verifyEdDSA(publicKey, 0, payload, signature)
+	})
+	if err == nil {
+		return iter(ast.InternedTerm(result))
+	}
+	return err
+}
+
 type verificationKey struct {
 	alg string
 	kid string
@@ -711,6 +724,7 @@ var tokenAlgorithms = map[string]tokenAlgorithm{
 	"ES256": {crypto.SHA256, verifyAsymmetric(verifyECDSA)},
 	"ES384": {crypto.SHA384, verifyAsymmetric(verifyECDSA)},
 	"ES512": {crypto.SHA512, verifyAsymmetric(verifyECDSA)},
+	"EdDSA": {0, verifyEdDSA},
 	"HS256": {crypto.SHA256, verifyHMAC},
 	"HS384": {crypto.SHA384, verifyHMAC},
 	"HS512": {crypto.SHA512, verifyHMAC},
@@ -784,6 +798,19 @@ func verifyECDSA(key any, _ crypto.Hash, digest []byte, signature []byte) (err e
 	return errSignatureNotVerified
 }
 
+// verifyEdDSA verifies an Ed25519 signature, which is computed over the
+// payload itself rather than a digest of it.
+func verifyEdDSA(key any, _ crypto.Hash, payload []byte, signature []byte) error {
+	publicKeyEd25519, ok := key.(ed25519.PublicKey)
+	if !ok || len(publicKeyEd25519) != ed25519.PublicKeySize {
+		return errors.New("incorrect public key type")
+	}
+	if ed25519.Verify(publicKeyEd25519, payload, signature) {
+		return nil
+	}
+	return errSignatureNotVerified
+}
+
 // JWT header parsing and parameters. See tokens_test.go for unit tests.
 
 // tokenHeaderType represents a recognized JWT header field
@@ -882,17 +909,76 @@ func (header *tokenHeader) valid() bool {
 	return true
 }
 
-func commonBuiltinJWTEncodeSign(bctx BuiltinContext, inputHeaders, jwsPayload, jwkSrc string, iter func(*ast.Term) error) error {
-	keys, err := jwk.ParseString(jwkSrc)
+// getKeyFromPEMOrJWK returns the key found in a PEM block (certificate, public
+// or private key) or, if keySrc isn't PEM encoded, the first key of a JWK (set).
+func getKeyFromPEMOrJWK(keySrc string) (any, error) {
+	if block, rest := pem.Decode([]byte(keySrc)); block != nil {
+		if len(rest) > 0 {
+			return nil, errors.New("extra data after a PEM key block")
+		}
+
+		switch block.Type {
+		case blockTypeCertificate:
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse a PEM certificate: %w", err)
+			}
+			return cert.PublicKey, nil
+		case "PUBLIC KEY":
+			return x509.ParsePKIXPublicKey(block.Bytes)
+		case "RSA PRIVATE KEY":
+			return x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			return x509.ParseECPrivateKey(block.Bytes)
+		case "PRIVATE KEY":
+			return x509.ParsePKCS8PrivateKey(block.Bytes)
+		default:
+			return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+		}
+	}
+
+	keys, err := jwk.ParseString(keySrc)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if len(keys.Keys) == 0 {
+		return nil, errors.New("no keys found in JWK set")
 	}
 	key, err := keys.Keys[0].Materialize()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if jwk.GetKeyTypeFromKey(key) != keys.Keys[0].GetKeyType() {
-		return errors.New("JWK derived key type and keyType parameter do not match")
+		return nil, errors.New("JWK derived key type and keyType parameter do not match")
+	}
+	return key, nil
+}
+
+// keyOperand returns the source of a key passed either as a JWK object or as a
+// string (serialized JWK or PEM).
+func keyOperand(value ast.Value, pos int) (string, error) {
+	switch v := value.(type) {
+	case ast.String:
+		return string(v), nil
+	case ast.Object:
+		keyAsJSON, err := ast.JSON(v)
+		if err != nil {
+			return "", fmt.Errorf("failed to prepare key for marshalling: %v", err)
+		}
+		bs, err := json.Marshal(keyAsJSON)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal key: %v", err)
+		}
+		return string(bs), nil
+	default:
+		return "", builtins.NewOperandTypeErr(pos, value, "object", "string")
+	}
+}
+
+func commonBuiltinJWTEncodeSign(bctx BuiltinContext, inputHeaders, jwsPayload, keySrc string, iter func(*ast.Term) error) error {
+	key, err := getKeyFromPEMOrJWK(keySrc)
+	if err != nil {
+		return err
 	}
 
 	standardHeaders := &jws.StandardHeaders{}
@@ -941,21 +1027,16 @@ func builtinJWTEncodeSign(bctx BuiltinContext, operands []*ast.Term, iter func(*
 		return fmt.Errorf("failed to marshal JWT payload: %v", err)
 	}
 
-	signatureAsJSON, err := ast.JSON(operands[2].Value)
+	keySrc, err := keyOperand(operands[2].Value, 3)
 	if err != nil {
-		return fmt.Errorf("failed to prepare JWT signature for marshalling: %v", err)
-	}
-
-	signatureBs, err := json.Marshal(signatureAsJSON)
-	if err != nil {
-		return fmt.Errorf("failed to marshal JWT signature: %v", err)
+		return err
 	}
 
 	return commonBuiltinJWTEncodeSign(
 		bctx,
 		string(inputHeadersBs),
 		string(payloadBs),
-		string(signatureBs),
+		keySrc,
 		iter,
 	)
 }
@@ -976,6 +1057,80 @@ func builtinJWTEncodeSignRaw(bctx BuiltinContext, operands []*ast.Term, iter fun
 	return commonBuiltinJWTEncodeSign(bctx, string(inputHeaders), string(jwsPayload), string(jwkSrc), iter)
 }
 
+// Implements JWE encryption in compact serialization.
+func builtinJWTEncodeEncrypt(bctx BuiltinContext, operands []*ast.Term, iter func(*ast.Term) error) error {
+	inputHeaders, err := builtins.ObjectOperand(operands[0].Value, 1)
+	if err != nil {
+		return err
+	}
+	headersAsJSON, err := ast.JSON(inputHeaders)
+	if err != nil {
+		return fmt.Errorf("failed to prepare JWE headers for marshalling: %v", err)
+	}
+	headers, ok := headersAsJSON.(map[string]any)
+	if !ok {
+		return errors.New("JWE headers must be an object")
+	}
+
+	var payload []byte
+	if s, ok := operands[1].Value.(ast.String); ok {
+		payload = []byte(s)
+	} else {
+		payloadAsJSON, err := ast.JSON(operands[1].Value)
+		if err != nil {
+			return fmt.Errorf("failed to prepare JWE payload for marshalling: %v", err)
+		}
+		if payload, err = json.Marshal(payloadAsJSON); err != nil {
+			return fmt.Errorf("failed to marshal JWE payload: %v", err)
+		}
+	}
+
+	keySrc, err := keyOperand(operands[2].Value, 3)
+	if err != nil {
+		return err
+	}
+	key, err := getKeyFromPEMOrJWK(keySrc)
+	if err != nil {
+		return err
+	}
+
+	jweCompact, err := jwe.Encrypt(payload, headers, key, bctx.Seed)
+	if err != nil {
+		return err
+	}
+
+	return iter(ast.StringTerm(string(jweCompact)))
+}
+
+// Implements JWE decryption in compact serialization.
+func builtinJWTDecodeDecrypt(_ BuiltinContext, operands []*ast.Term, iter func(*ast.Term) error) error {
+	token, err := builtins.StringOperand(operands[0].Value, 1)
+	if err != nil {
+		return err
+	}
+
+	keySrc, err := keyOperand(operands[1].Value, 2)
+	if err != nil {
+		return err
+	}
+	key, err := getKeyFromPEMOrJWK(keySrc)
+	if err != nil {
+		return err
+	}
+
+	header, payload, err := jwe.Decrypt([]byte(token), key)
+	if err != nil {
+		return err
+	}
+
+	headerObj, err := extractJSONObject(string(header))
+	if err != nil {
+		return fmt.Errorf("JWE header had invalid encoding: %w", err)
+	}
+
+	return iter(ast.ArrayTerm(ast.NewTerm(headerObj), ast.StringTerm(string(payload))))
+}
+
 // Implements full JWT decoding, validation and verification.
 func builtinJWTDecodeVerify(bctx BuiltinContext, operands []*ast.Term, iter func(*ast.Term) error) error {
 	// io.jwt.decode_verify(string, constraints, [valid, header, payload])
@@ -1246,8 +1401,12 @@ func extractJSONObject(s string) (ast.Object, error) {
 	return o, nil
 }
 
-// getInputSha returns the SHA checksum of the input
+// getInputSha returns the SHA checksum of the input, or the input itself
+// if no hash function is given.
 func getInputSHA(input []byte, h func() hash.Hash) []byte {
+	if h == nil {
+		return input
+	}
 	hasher := h()
 	hasher.Write(input)
 	return hasher.Sum(nil)
@@ -1320,10 +1479,13 @@ func init() {
 	RegisterBuiltinFunc(ast.JWTVerifyES256.Name, builtinJWTVerifyES256)
 	RegisterBuiltinFunc(ast.JWTVerifyES384.Name, builtinJWTVerifyES384)
 	RegisterBuiltinFunc(ast.JWTVerifyES512.Name, builtinJWTVerifyES512)
+	RegisterBuiltinFunc(ast.JWTVerifyEdDSA.Name, builtinJWTVerifyEdDSA)
 	RegisterBuiltinFunc(ast.JWTVerifyHS256.Name, builtinJWTVerifyHS256)
 	RegisterBuiltinFunc(ast.JWTVerifyHS384.Name, builtinJWTVerifyHS384)
 	RegisterBuiltinFunc(ast.JWTVerifyHS512.Name, builtinJWTVerifyHS512)
 	RegisterBuiltinFunc(ast.JWTDecodeVerify.Name, builtinJWTDecodeVerify)
 	RegisterBuiltinFunc(ast.JWTEncodeSignRaw.Name, builtinJWTEncodeSignRaw)
 	RegisterBuiltinFunc(ast.JWTEncodeSign.Name, builtinJWTEncodeSign)
+	RegisterBuiltinFunc(ast.JWTEncodeEncrypt.Name, builtinJWTEncodeEncrypt)
+	RegisterBuiltinFunc(ast.JWTDecodeDecrypt.Name, builtinJWTDecodeDecrypt)
 }
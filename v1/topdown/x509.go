@@ -0,0 +1,332 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package topdown
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/topdown/builtins"
+)
+
+const blockTypeCRL = "X509 CRL"
+
+// oidSCTList is the certificate extension carrying embedded signed certificate
+// timestamps, see RFC 6962, section 3.3.
+var oidSCTList = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 2}
+
+// signedCertificateTimestamp is an SCT embedded in a certificate, see RFC 6962,
+// section 3.2.
+type signedCertificateTimestamp struct {
+	Version            int
+	LogID              string // base64 encoded
+	Timestamp          uint64 // milliseconds since the Unix epoch
+	Extensions         string `json:",omitempty"` // base64 encoded
+	HashAlgorithm      int
+	SignatureAlgorithm int
+	Signature          string // base64 encoded
+}
+
+func builtinCryptoX509VerifyChain(_ BuiltinContext, operands []*ast.Term, iter func(*ast.Term) error) error {
+	input, err := builtins.StringOperand(operands[0].Value, 1)
+	if err != nil {
+		return err
+	}
+
+	options, err := builtins.ObjectOperand(operands[1].Value, 2)
+	if err != nil {
+		return err
+	}
+
+	var roots, crls, requireRevocation *ast.Term
+	rest := ast.NewObject()
+	options.Foreach(func(k, v *ast.Term) {
+		key, _ := k.Value.(ast.String)
+		switch key {
+		case "Roots":
+			roots = v
+		case "CRLs":
+			crls = v
+		case "RequireRevocationCheck":
+			requireRevocation = v
+		default:
+			rest.Insert(k, v)
+		}
+	})
+
+	vo, err := extractVerifyOpts(rest)
+	if err != nil {
+		return err
+	}
+
+	if roots == nil {
+		return builtins.NewOperandErr(2, "'Roots' is required")
+	}
+	rootsStr, ok := roots.Value.(ast.String)
+	if !ok {
+		return builtins.NewOperandErr(2, "'Roots' should be a string")
+	}
+
+	var revocationLists []*x509.RevocationList
+	if crls != nil {
+		arr, ok := crls.Value.(*ast.Array)
+		if !ok {
+			return builtins.NewOperandErr(2, "'CRLs' should be an array of strings")
+		}
+		for i := range arr.Len() {
+			s, ok := arr.Elem(i).Value.(ast.String)
+			if !ok {
+				return builtins.NewOperandErr(2, "'CRLs' should be an array of strings")
+			}
+			rl, err := getX509CRLFromString(string(s))
+			if err != nil {
+				return builtins.NewOperandErr(2, "invalid CRL at index %d: %v", i, err)
+			}
+			revocationLists = append(revocationLists, rl)
+		}
+	}
+
+	var required bool
+	if requireRevocation != nil {
+		b, ok := requireRevocation.Value.(ast.Boolean)
+		if !ok {
+			return builtins.NewOperandErr(2, "'RequireRevocationCheck' should be a boolean")
+		}
+		required = bool(b)
+	}
+
+	rootCerts, err := getX509CertsFromString(string(rootsStr))
+	if err != nil {
+		return builtins.NewOperandErr(2, "invalid 'Roots': %v", err)
+	}
+
+	chain, err := buildX509CertificateChain(string(input), rootCerts, vo, revocationLists, required)
+	if err != nil {
+		return iter(ast.ObjectTerm(
+			ast.Item(ast.InternedTerm("valid"), ast.InternedTerm(false)),
+			ast.Item(ast.InternedTerm("chain"), ast.InternedEmptyArray),
+			ast.Item(ast.InternedTerm("error"), ast.StringTerm(err.Error())),
+		))
+	}
+
+	value, err := ast.InterfaceToValue(extendCertificates(chain))
+	if err != nil {
+		return err
+	}
+
+	return iter(ast.ObjectTerm(
+		ast.Item(ast.InternedTerm("valid"), ast.InternedTerm(true)),
+		ast.Item(ast.InternedTerm("chain"), ast.NewTerm(value)),
+	))
+}
+
+// buildX509CertificateChain verifies the leaf, which is the first certificate
+// in input, against the roots, using all other certificates in input as
+// intermediates. The first chain that passes the revocation checks is
+// returned, ordered from the leaf to the root.
+func buildX509CertificateChain(input string, roots []*x509.Certificate, vo x509.VerifyOptions, crls []*x509.RevocationList, requireRevocation bool) ([]*x509.Certificate, error) {
+	certs, err := getX509CertsFromString(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no certificates found")
+	}
+
+	vo.Roots = x509.NewCertPool()
+	for _, c := range roots {
+		vo.Roots.AddCert(c)
+	}
+
+	vo.Intermediates = x509.NewCertPool()
+	for _, c := range certs[1:] {
+		vo.Intermediates.AddCert(c)
+	}
+
+	chains, err := certs[0].Verify(vo)
+	if err != nil {
+		return nil, err
+	}
+
+	now := vo.CurrentTime
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	for _, chain := range chains {
+		if err = checkX509Revocation(chain, crls, now, requireRevocation); err == nil {
+			return chain, nil
+		}
+	}
+
+	return nil, err
+}
+
+// checkX509Revocation checks all certificates of the chain but the root against
+// the CRLs issued and signed by their issuers, and current at time now. If
+// required is true, a certificate not covered by any such CRL is an error.
+func checkX509Revocation(chain []*x509.Certificate, crls []*x509.RevocationList, now time.Time, required bool) error {
+	for i := range len(chain) - 1 {
+		cert, issuer := chain[i], chain[i+1]
+
+		checked := false
+		for _, rl := range crls {
+			if !bytes.Equal(rl.RawIssuer, cert.RawIssuer) || rl.CheckSignatureFrom(issuer) != nil {
+				continue
+			}
+			if now.Before(rl.ThisUpdate) || (!rl.NextUpdate.IsZero() && now.After(rl.NextUpdate)) {
+				continue
+			}
+			checked = true
+			for _, entry := range rl.RevokedCertificateEntries {
+				if entry.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+					return fmt.Errorf("certificate %q with serial number %v was revoked at %v", cert.Subject, cert.SerialNumber, entry.RevocationTime.UTC().Format(time.RFC3339))
+				}
+			}
+		}
+
+		if required && !checked {
+			return fmt.Errorf("no current CRL found for certificate %q", cert.Subject)
+		}
+	}
+	return nil
+}
+
+func getX509CRLFromString(s string) (*x509.RevocationList, error) {
+	bs := []byte(s)
+	if !bytes.HasPrefix(bs, []byte("-----BEGIN")) {
+		var err error
+		bs, err = base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if bytes.HasPrefix(bs, []byte("-----BEGIN")) {
+		p, _ := pem.Decode(bs)
+		if p == nil {
+			return nil, errors.New("invalid PEM data")
+		}
+		if p.Type != blockTypeCRL {
+			return nil, fmt.Errorf("PEM block type is '%s', expected %s", p.Type, blockTypeCRL)
+		}
+		bs = p.Bytes
+	}
+
+	return x509.ParseRevocationList(bs)
+}
+
+// policyOIDs returns the certificate policies of cert in dotted notation.
+func policyOIDs(cert *x509.Certificate) []string {
+	var oids []string
+	for _, oid := range cert.Policies {
+		oids = append(oids, oid.String())
+	}
+	if len(oids) == 0 {
+		for _, oid := range cert.PolicyIdentifiers {
+			oids = append(oids, oid.String())
+		}
+	}
+	return oids
+}
+
+// embeddedSCTs returns the signed certificate timestamps embedded in cert.
+// Malformed SCT lists are ignored.
+func embeddedSCTs(cert *x509.Certificate) []signedCertificateTimestamp {
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(oidSCTList) {
+			continue
+		}
+
+		var list []byte
+		if _, err := asn1.Unmarshal(ext.Value, &list); err != nil {
+			return nil
+		}
+
+		list, ok := readTLSVector(list)
+		if !ok {
+			return nil
+		}
+
+		var scts []signedCertificateTimestamp
+		for len(list) > 0 {
+			var raw []byte
+			raw, list = readTLSVectorPrefix(list)
+			sct, ok := parseSCT(raw)
+			if !ok {
+				return nil
+			}
+			scts = append(scts, sct)
+		}
+		return scts
+	}
+	return nil
+}
+
+func parseSCT(bs []byte) (signedCertificateTimestamp, bool) {
+	var sct signedCertificateTimestamp
+
+	// version (1), log ID (32), timestamp (8)
+	if len(bs) < 41 {
+		return sct, false
+	}
+	sct.Version = int(bs[0])
+	sct.LogID = base64.StdEncoding.EncodeToString(bs[1:33])
+	sct.Timestamp = binary.BigEndian.Uint64(bs[33:41])
+
+	exts, rest := readTLSVectorPrefix(bs[41:])
+	if exts == nil && rest == nil {
+		return sct, false
+	}
+	if len(exts) > 0 {
+		sct.Extensions = base64.StdEncoding.EncodeToString(exts)
+	}
+
+	// hash algorithm (1), signature algorithm (1), signature
+	if len(rest) < 2 {
+		return sct, false
+	}
+	sct.HashAlgorithm = int(rest[0])
+	sct.SignatureAlgorithm = int(rest[1])
+
+	sig, ok := readTLSVector(rest[2:])
+	if !ok {
+		return sct, false
+	}
+	sct.Signature = base64.StdEncoding.EncodeToString(sig)
+
+	return sct, true
+}
+
+// readTLSVectorPrefix reads a vector with a 2 byte length prefix from bs, and
+// returns it with the remaining bytes. Both are nil if bs is too short.
+func readTLSVectorPrefix(bs []byte) ([]byte, []byte) {
+	if len(bs) < 2 {
+		return nil, nil
+	}
+	n := int(binary.BigEndian.Uint16(bs))
+	if len(bs) < 2+n {
+		return nil, nil
+	}
+	return bs[2 : 2+n], bs[2+n:]
+}
+
+// readTLSVector reads a vector with a 2 byte length prefix spanning all of bs.
+func readTLSVector(bs []byte) ([]byte, bool) {
+	v, rest := readTLSVectorPrefix(bs)
+	return v, v != nil && len(rest) == 0
+}
+
+func init() {
+	RegisterBuiltinFunc(ast.CryptoX509VerifyChain.Name, builtinCryptoX509VerifyChain)
+}
@@ -0,0 +1,262 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package topdown
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  string
+}
+
+func newTestCert(t *testing.T, tmpl *x509.Certificate, parent *testCA) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl.NotBefore = time.Now().Add(-time.Hour)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+
+	signer, parentCert := key, tmpl
+	if parent != nil {
+		signer, parentCert = parent.key, parent.cert
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parentCert, &key.PublicKey, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testCA{cert: cert, key: key, pem: string(pem.EncodeToMemory(&pem.Block{Type: blockTypeCertificate, Bytes: der}))}
+}
+
+func newTestCRL(t *testing.T, issuer *testCA, nextUpdate time.Time, revoked ...*big.Int) string {
+	t.Helper()
+
+	tmpl := &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: time.Now().Add(-time.Hour),
+		NextUpdate: nextUpdate,
+	}
+	for _, serial := range revoked {
+		tmpl.RevokedCertificateEntries = append(tmpl.RevokedCertificateEntries, x509.RevocationListEntry{
+			SerialNumber:   serial,
+			RevocationTime: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		})
+	}
+
+	der, err := x509.CreateRevocationList(rand.Reader, tmpl, issuer.cert, issuer.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: blockTypeCRL, Bytes: der}))
+}
+
+func TestCryptoX509VerifyChain(t *testing.T) {
+	t.Parallel()
+
+	ca := func(cn string, serial int64) *x509.Certificate {
+		return &x509.Certificate{
+			SerialNumber:          big.NewInt(serial),
+			Subject:               pkix.Name{CommonName: cn},
+			KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+			BasicConstraintsValid: true,
+			IsCA:                  true,
+		}
+	}
+
+	// A single SCT: version 0, log ID, timestamp, no extensions, SHA-256 with
+	// ECDSA and a 2 byte signature.
+	sct := append([]byte{0}, make([]byte, 32)...)
+	sct = append(sct, 0, 0, 0, 0, 0, 0, 0x30, 0x39, 0, 0, 4, 3, 0, 2, 0xca, 0xfe)
+	sctList := append([]byte{0, byte(len(sct) + 2), 0, byte(len(sct))}, sct...)
+	sctExt, err := asn1.Marshal(sctList)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := newTestCert(t, ca("Root", 1), nil)
+	otherRoot := newTestCert(t, ca("Other Root", 2), nil)
+	intermediate := newTestCert(t, ca("Intermediate", 3), root)
+	leaf := newTestCert(t, &x509.Certificate{
+		SerialNumber:      big.NewInt(4),
+		Subject:           pkix.Name{CommonName: "leaf"},
+		DNSNames:          []string{"example.com"},
+		ExtKeyUsage:       []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		PolicyIdentifiers: []asn1.ObjectIdentifier{{2, 23, 140, 1, 2, 1}},
+		ExtraExtensions:   []pkix.Extension{{Id: oidSCTList, Value: sctExt}},
+	}, intermediate)
+
+	currentCRL := newTestCRL(t, intermediate, time.Now().Add(time.Hour))
+	revokingCRL := newTestCRL(t, intermediate, time.Now().Add(time.Hour), big.NewInt(4))
+	staleCRL := newTestCRL(t, intermediate, time.Now().Add(-time.Minute), big.NewInt(4))
+	foreignCRL := newTestCRL(t, otherRoot, time.Now().Add(time.Hour), big.NewInt(4))
+
+	tests := []struct {
+		note    string
+		certs   string
+		options map[string]any
+		chain   []string
+		err     string
+	}{
+		{
+			note:    "chain to root",
+			certs:   leaf.pem + intermediate.pem,
+			options: map[string]any{"Roots": root.pem, "DNSName": "example.com"},
+			chain:   []string{"leaf", "Intermediate", "Root"},
+		},
+		{
+			note:    "untrusted root",
+			certs:   leaf.pem + intermediate.pem,
+			options: map[string]any{"Roots": otherRoot.pem},
+			err:     "certificate signed by unknown authority",
+		},
+		{
+			note:    "missing intermediate",
+			certs:   leaf.pem,
+			options: map[string]any{"Roots": root.pem},
+			err:     "certificate signed by unknown authority",
+		},
+		{
+			note:    "wrong DNS name",
+			certs:   leaf.pem + intermediate.pem,
+			options: map[string]any{"Roots": root.pem, "DNSName": "example.org"},
+			err:     "certificate is valid for example.com, not example.org",
+		},
+		{
+			note:    "not revoked",
+			certs:   leaf.pem + intermediate.pem,
+			options: map[string]any{"Roots": root.pem, "CRLs": []any{currentCRL}, "RequireRevocationCheck": false},
+			chain:   []string{"leaf", "Intermediate", "Root"},
+		},
+		{
+			note:    "revoked",
+			certs:   leaf.pem + intermediate.pem,
+			options: map[string]any{"Roots": root.pem, "CRLs": []any{currentCRL, revokingCRL}},
+			err:     `certificate "CN=leaf" with serial number 4 was revoked at 2026-01-02T03:04:05Z`,
+		},
+		{
+			note:    "stale and foreign CRLs are ignored",
+			certs:   leaf.pem + intermediate.pem,
+			options: map[string]any{"Roots": root.pem, "CRLs": []any{staleCRL, foreignCRL}},
+			chain:   []string{"leaf", "Intermediate", "Root"},
+		},
+		{
+			note:    "revocation check required",
+			certs:   leaf.pem + intermediate.pem,
+			options: map[string]any{"Roots": root.pem, "CRLs": []any{staleCRL, currentCRL}, "RequireRevocationCheck": true},
+			err:     `no current CRL found for certificate "CN=Intermediate"`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			t.Parallel()
+
+			result := callX509VerifyChain(t, tc.certs, tc.options)
+
+			if tc.err != "" {
+				if result.Get(ast.InternedTerm("valid")).Value.Compare(ast.Boolean(false)) != 0 {
+					t.Fatalf("expected invalid chain but got %v", result)
+				}
+				if msg := result.Get(ast.InternedTerm("error")); msg == nil || !strings.Contains(string(msg.Value.(ast.String)), tc.err) {
+					t.Fatalf("expected error %q but got %v", tc.err, msg)
+				}
+				return
+			}
+
+			if result.Get(ast.InternedTerm("valid")).Value.Compare(ast.Boolean(true)) != 0 {
+				t.Fatalf("expected valid chain but got %v", result)
+			}
+
+			chain := result.Get(ast.InternedTerm("chain")).Value.(*ast.Array)
+			if chain.Len() != len(tc.chain) {
+				t.Fatalf("expected chain %v but got %v", tc.chain, chain)
+			}
+			for i, cn := range tc.chain {
+				ref := ast.MustParseRef(`x.Subject.CommonName`)[1:]
+				if v, err := chain.Elem(i).Value.Find(ref); err != nil || v.Compare(ast.String(cn)) != 0 {
+					t.Fatalf("expected %v at index %d but got %v", cn, i, v)
+				}
+			}
+
+			cert := chain.Elem(0).Value.(ast.Object)
+			if exp := ast.MustParseTerm(`["2.23.140.1.2.1"]`); !cert.Get(ast.StringTerm("PolicyOIDs")).Equal(exp) {
+				t.Errorf("expected policy OIDs %v but got %v", exp, cert.Get(ast.StringTerm("PolicyOIDs")))
+			}
+			exp := ast.MustParseTerm(`[{"Version": 0, "LogID": "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=", "Timestamp": 12345, "HashAlgorithm": 4, "SignatureAlgorithm": 3, "Signature": "yv4="}]`)
+			if !cert.Get(ast.StringTerm("SCTs")).Equal(exp) {
+				t.Errorf("expected SCTs %v but got %v", exp, cert.Get(ast.StringTerm("SCTs")))
+			}
+		})
+	}
+}
+
+func TestCryptoX509VerifyChainInvalidOptions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		note    string
+		options string
+		err     string
+	}{
+		{note: "missing roots", options: `{}`, err: "'Roots' is required"},
+		{note: "invalid CRLs", options: `{"Roots": "", "CRLs": "x"}`, err: "'CRLs' should be an array of strings"},
+		{note: "invalid CRL", options: `{"Roots": "", "CRLs": ["-----BEGIN CERTIFICATE-----\n-----END CERTIFICATE-----\n"]}`, err: "invalid CRL at index 0: PEM block type is 'CERTIFICATE', expected X509 CRL"},
+		{note: "unknown option", options: `{"Roots": "", "Foo": 1}`, err: "invalid key option"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			t.Parallel()
+
+			err := builtinCryptoX509VerifyChain(BuiltinContext{}, []*ast.Term{ast.StringTerm(""), ast.MustParseTerm(tc.options)}, func(*ast.Term) error { return nil })
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("expected error %q but got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func callX509VerifyChain(t *testing.T, certs string, options map[string]any) ast.Object {
+	t.Helper()
+
+	opts, err := ast.InterfaceToValue(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var result ast.Object
+	err = builtinCryptoX509VerifyChain(BuiltinContext{}, []*ast.Term{ast.StringTerm(certs), ast.NewTerm(opts)}, func(x *ast.Term) error {
+		result = x.Value.(ast.Object)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}